@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/manager"
+)
+
+// CacheServerCommand is the name of the subcommand which runs the cache server
+// shared by the instances on a host.
+const CacheServerCommand = "cache-server"
+
+// runCacheServer serves the cache of Consul data for the other instances on the
+// host until an interrupt is received. It does not render any templates.
+func (cli *CLI) runCacheServer(args []string) int {
+	conf, err := cli.ParseCacheServerFlags(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return cli.handleError(err, ExitCodeParseFlagsError)
+	}
+
+	conf, err = cli.setup(conf)
+	if err != nil {
+		return cli.handleError(err, ExitCodeConfigError)
+	}
+
+	server, err := manager.NewCacheServer(conf)
+	if err != nil {
+		return cli.handleError(err, ExitCodeRunnerError)
+	}
+	if err := server.Start(); err != nil {
+		return cli.handleError(err, ExitCodeRunnerError)
+	}
+	defer server.Stop()
+
+	signal.Notify(cli.signalCh, os.Interrupt, syscall.SIGTERM, *conf.KillSignal)
+
+	select {
+	case s := <-cli.signalCh:
+		log.Printf("[DEBUG] (cli) receiving signal %q", s)
+		fmt.Fprintf(cli.errStream, "Cleaning up...\n")
+		return ExitCodeInterrupt
+	case <-cli.stopCh:
+		return ExitCodeOK
+	}
+}
+
+// ParseCacheServerFlags parses the flags of the cache-server subcommand. The
+// Consul connection settings and the path of the socket are read from the
+// given configuration files and flags. The token is ignored, since every
+// query is made with the token of the instance which asked for it.
+func (cli *CLI) ParseCacheServerFlags(args []string) (*config.Config, error) {
+	c := config.DefaultConfig()
+
+	// configPaths stores the list of configuration paths on disk
+	configPaths := make([]string, 0, 6)
+
+	flags := flag.NewFlagSet(CacheServerCommand, flag.ContinueOnError)
+	flags.SetOutput(cli.errStream)
+	flags.Usage = func() { fmt.Fprintf(cli.errStream, cacheServerUsage, Name) }
+
+	flags.Var((funcVar)(func(s string) error {
+		configPaths = append(configPaths, s)
+		return nil
+	}), "config", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Consul = config.String(s)
+		return nil
+	}), "consul", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.LogLevel = config.String(s)
+		return nil
+	}), "log-level", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.LocalCache.Path = config.String(s)
+		return nil
+	}), "path", "")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if args := flags.Args(); len(args) > 0 {
+		return nil, fmt.Errorf("cli: extra args: %q", args)
+	}
+
+	finalC := config.DefaultConfig()
+	for _, path := range configPaths {
+		c, err := config.FromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		finalC = finalC.Merge(c)
+	}
+	finalC = finalC.Merge(c)
+
+	if !config.StringPresent(finalC.LocalCache.Path) {
+		return nil, fmt.Errorf("cli: %s: -path is required", CacheServerCommand)
+	}
+
+	// Only serve the cache, and none of the other behavior.
+	finalC.Templates = &config.TemplateConfigs{}
+	finalC.Token = config.String("")
+	finalC.TokenFile = config.String("")
+
+	finalC.Finalize()
+
+	return finalC, nil
+}
+
+const cacheServerUsage = `
+Usage: %s cache-server [options]
+
+  Serves a cache of Consul data on a unix socket for the other instances on
+  the host, which use it when the "local_cache" block of their configuration
+  has the same path. Each distinct query is made to Consul once, however many
+  instances make it, with the token of the instance which made it. It runs
+  until an interrupt is received.
+
+Options:
+
+  -config=<path>
+      Sets the path to a configuration file or folder on disk to read the
+      Consul connection settings and the local_cache path from. Templates and
+      other settings are ignored. This can be specified multiple times
+
+  -consul=<address>
+      Sets the address of the Consul instance
+
+  -log-level=<level>
+      Set the logging level - values are "debug", "info", "warn", and "err"
+
+  -path=<path>
+      Sets the path of the unix socket to listen on
+`
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestCLI_ParseCacheServerFlags(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+		consul = "1.2.3.4:8500"
+		token  = "abcd1234"
+		local_cache {
+			path = "/run/a.sock"
+		}
+		template {
+			source      = "other.tpl"
+			destination = "other.out"
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		f    []string
+		e    *config.Config
+		err  bool
+	}{
+		{
+			"path",
+			[]string{"-path", "/run/b.sock"},
+			&config.Config{
+				LocalCache: &config.LocalCacheConfig{
+					Path: config.String("/run/b.sock"),
+				},
+			},
+			false,
+		},
+		{
+			"config",
+			[]string{"-config", f.Name()},
+			&config.Config{
+				Consul: config.String("1.2.3.4:8500"),
+				LocalCache: &config.LocalCacheConfig{
+					Path: config.String("/run/a.sock"),
+				},
+			},
+			false,
+		},
+		{
+			"flags_override_config",
+			[]string{"-config", f.Name(), "-consul", "5.6.7.8:8500", "-path", "/run/b.sock"},
+			&config.Config{
+				Consul: config.String("5.6.7.8:8500"),
+				LocalCache: &config.LocalCacheConfig{
+					Path: config.String("/run/b.sock"),
+				},
+			},
+			false,
+		},
+		{
+			"missing_path",
+			[]string{"-consul", "1.2.3.4:8500"},
+			nil,
+			true,
+		},
+		{
+			"extra_args",
+			[]string{"-path", "/run/b.sock", "foo"},
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var out bytes.Buffer
+			cli := NewCLI(&out, &out)
+
+			c, err := cli.ParseCacheServerFlags(tc.f)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			var e *config.Config
+			if tc.e != nil {
+				e = config.DefaultConfig().Merge(tc.e)
+				e.Templates = &config.TemplateConfigs{}
+				e.Token = config.String("")
+				e.TokenFile = config.String("")
+				e.Finalize()
+			}
+
+			if !reflect.DeepEqual(e, c) {
+				t.Errorf("\nexp: %#v\nact: %#v", e, c)
+			}
+		})
+	}
+}
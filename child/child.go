@@ -27,6 +27,31 @@ var (
 	ExitCodeError int = 127
 )
 
+// ExitError is the error returned by Start when a command with a timeout exits
+// with a non-zero exit status.
+type ExitError struct {
+	Command string
+	Code    int
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf(
+		"command exited with a non-zero exit status:\n"+
+			"\n"+
+			"    %s\n"+
+			"\n"+
+			"This is assumed to be a failure. Please ensure the command\n"+
+			"exits with a zero exit status.",
+		e.Command,
+	)
+}
+
+// ExitStatus returns the exit status of the command.
+func (e *ExitError) ExitStatus() int {
+	return e.Code
+}
+
 // Child is a wrapper around a child process which can be used to send signals
 // and manage the processes' lifecycle.
 type Child struct {
@@ -42,11 +67,16 @@ type Child struct {
 
 	reloadSignal os.Signal
 
-	killSignal  os.Signal
-	killTimeout time.Duration
+	killSignal      os.Signal
+	killTimeout     time.Duration
+	killTimeoutFunc func(pid int)
 
 	splay time.Duration
 
+	// listeners are the sockets passed to the child process. They are owned by
+	// the caller and are passed again each time the child is restarted.
+	listeners []*os.File
+
 	// cmd is the actual child process under management.
 	cmd *exec.Cmd
 
@@ -59,6 +89,10 @@ type Child struct {
 	stopLock sync.RWMutex
 	stopCh   chan struct{}
 	stopped  bool
+
+	// lastUsage is the previous resource usage sample, used to calculate the
+	// CPU percentage between samples.
+	lastUsage *Usage
 }
 
 // NewInput is input to the NewChild function.
@@ -96,12 +130,24 @@ type NewInput struct {
 	// terminate before force-killing.
 	KillTimeout time.Duration
 
+	// KillTimeoutFunc is called with the PID of the process when it does not
+	// terminate within KillTimeout after the kill signal and is force-killed.
+	// It is called while the child is locked, so it must not call its methods.
+	// This value may be nil.
+	KillTimeoutFunc func(pid int)
+
 	// Splay is the maximum random amount of time to wait before sending signals.
 	// This option helps reduce the thundering herd problem by effectively
 	// sleeping for a random amount of time before sending the signal. This
 	// prevents multiple processes from all signaling at the same time. This value
 	// may be zero (which disables the splay entirely).
 	Splay time.Duration
+
+	// Listeners are open listening sockets to pass to the child process using
+	// systemd socket activation. They are passed starting at file descriptor 3,
+	// and LISTEN_FDS and LISTEN_PID are set in the child's environment. The
+	// caller owns the sockets and must keep them open while the child runs.
+	Listeners []*os.File
 }
 
 // New creates a new child process for management with high-level APIs for
@@ -117,18 +163,20 @@ func New(i *NewInput) (*Child, error) {
 	}
 
 	child := &Child{
-		stdin:        i.Stdin,
-		stdout:       i.Stdout,
-		stderr:       i.Stderr,
-		command:      i.Command,
-		args:         i.Args,
-		env:          i.Env,
-		timeout:      i.Timeout,
-		reloadSignal: i.ReloadSignal,
-		killSignal:   i.KillSignal,
-		killTimeout:  i.KillTimeout,
-		splay:        i.Splay,
-		stopCh:       make(chan struct{}, 1),
+		stdin:           i.Stdin,
+		stdout:          i.Stdout,
+		stderr:          i.Stderr,
+		command:         i.Command,
+		args:            i.Args,
+		env:             i.Env,
+		timeout:         i.Timeout,
+		reloadSignal:    i.ReloadSignal,
+		killSignal:      i.KillSignal,
+		killTimeout:     i.KillTimeout,
+		killTimeoutFunc: i.KillTimeoutFunc,
+		splay:           i.Splay,
+		listeners:       i.Listeners,
+		stopCh:          make(chan struct{}, 1),
 	}
 
 	return child, nil
@@ -205,6 +253,32 @@ func (c *Child) Reload() error {
 	}
 }
 
+// Restart kills the child process using the same semantics as Kill and starts
+// a new process in its place, regardless of the reload signal. Callers must
+// re-read ExitCh after a restart.
+func (c *Child) Restart() error {
+	log.Printf("[INFO] (child) restarting process")
+	c.Lock()
+	defer c.Unlock()
+	c.kill()
+	return c.start()
+}
+
+// ForceRestart force-kills the child process, without sending the kill signal
+// or waiting for the splay, and starts a new process in its place. It is meant
+// for processes which are wedged and ignore signals. Callers must re-read
+// ExitCh after a restart.
+func (c *Child) ForceRestart() error {
+	log.Printf("[INFO] (child) force-restarting process")
+	c.Lock()
+	defer c.Unlock()
+	if c.running() {
+		c.cmd.Process.Kill()
+		c.cmd = nil
+	}
+	return c.start()
+}
+
 // Kill sends the kill signal to the child process and waits for successful
 // termination. If no kill signal is defined, the process is killed with the
 // most aggressive kill signal. If the process does not gracefully stop within
@@ -242,11 +316,24 @@ func (c *Child) Stop() {
 }
 
 func (c *Child) start() error {
-	cmd := exec.Command(c.command, c.args...)
+	command, args, env := c.command, c.args, c.env
+	if len(c.listeners) > 0 {
+		// LISTEN_PID must be the PID of the process which uses the sockets, which
+		// is not known until after the fork. Let a shell set it to its own PID and
+		// exec the command, which keeps the PID.
+		command = "/bin/sh"
+		args = append([]string{"-c", `export LISTEN_PID=$$; exec "$0" "$@"`,
+			c.command}, c.args...)
+		env = append(listenEnv(c.env),
+			fmt.Sprintf("LISTEN_FDS=%d", len(c.listeners)))
+	}
+
+	cmd := exec.Command(command, args...)
 	cmd.Stdin = c.stdin
 	cmd.Stdout = c.stdout
 	cmd.Stderr = c.stderr
-	cmd.Env = c.env
+	cmd.Env = env
+	cmd.ExtraFiles = c.listeners
 	if err := cmd.Start(); err != nil {
 		return err
 	}
@@ -290,15 +377,7 @@ func (c *Child) start() error {
 		select {
 		case code := <-exitCh:
 			if code != 0 {
-				return fmt.Errorf(
-					"command exited with a non-zero exit status:\n"+
-						"\n"+
-						"    %s\n"+
-						"\n"+
-						"This is assumed to be a failure. Please ensure the command\n"+
-						"exits with a zero exit status.",
-					c.Command(),
-				)
+				return &ExitError{Command: c.Command(), Code: code}
 			}
 		case <-time.After(c.timeout):
 			// Force-kill the process
@@ -325,6 +404,25 @@ func (c *Child) start() error {
 	return nil
 }
 
+// listenEnv returns the given environment without any socket activation
+// variables, which are set for the child process.
+func listenEnv(env []string) []string {
+	if env == nil {
+		env = os.Environ()
+	}
+
+	r := make([]string, 0, len(env)+1)
+	for _, e := range env {
+		if strings.HasPrefix(e, "LISTEN_PID=") ||
+			strings.HasPrefix(e, "LISTEN_FDS=") ||
+			strings.HasPrefix(e, "LISTEN_FDNAMES=") {
+			continue
+		}
+		r = append(r, e)
+	}
+	return r
+}
+
 func (c *Child) pid() int {
 	if !c.running() {
 		return 0
@@ -375,6 +473,11 @@ func (c *Child) kill() {
 			case <-killCh:
 				exited = true
 			case <-time.After(c.killTimeout):
+				log.Printf("[WARN] (child) process did not exit within %s, force-killing",
+					c.killTimeout)
+				if c.killTimeoutFunc != nil {
+					c.killTimeoutFunc(process.Pid)
+				}
 			}
 		}
 	}
@@ -1,9 +1,12 @@
 package child
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"reflect"
+	"runtime"
 	"syscall"
 	"testing"
 	"time"
@@ -203,6 +206,64 @@ func TestStart(t *testing.T) {
 	}
 }
 
+func TestStart_exitError(t *testing.T) {
+	t.Parallel()
+
+	c := testChild(t)
+	c.command = "sh"
+	c.args = []string{"-c", "exit 3"}
+	c.timeout = 5 * time.Second
+
+	err := c.Start()
+	exitErr, ok := err.(*ExitError)
+	if !ok {
+		t.Fatalf("expected *ExitError, got %#v", err)
+	}
+	if exitErr.ExitStatus() != 3 {
+		t.Errorf("expected exit status 3, got %d", exitErr.ExitStatus())
+	}
+}
+
+func TestStart_listeners(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c := testChild(t)
+	stdout := gatedio.NewByteBuffer()
+	c.stdout = stdout
+	c.env = []string{"LISTEN_FDS=5"}
+	c.command = "bash"
+	c.args = []string{"-c", `echo "$LISTEN_FDS $LISTEN_PID $$"; test -S /dev/fd/3 && echo socket`}
+	c.listeners = []*os.File{f}
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	pid := c.Pid()
+
+	select {
+	case <-c.ExitCh():
+	case <-time.After(fileWaitSleepDelay):
+		t.Fatal("process should have exited")
+	}
+
+	expected := fmt.Sprintf("1 %d %d\nsocket\n", pid, pid)
+	if stdout.String() != expected {
+		t.Errorf("expected %q to be %q", stdout.String(), expected)
+	}
+}
+
 func TestSignal(t *testing.T) {
 	t.Parallel()
 
@@ -327,6 +388,117 @@ func TestReload_noProcess(t *testing.T) {
 	}
 }
 
+func TestRestart(t *testing.T) {
+	t.Parallel()
+
+	c := testChild(t)
+	c.command = "bash"
+	c.args = []string{"-c", "while true; do sleep 0.2; done"}
+	c.killTimeout = 10 * time.Millisecond
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	opid := c.Pid()
+
+	if err := c.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	npid := c.Pid()
+
+	if opid == npid {
+		t.Error("expected new process to restart")
+	}
+}
+
+func TestForceRestart(t *testing.T) {
+	t.Parallel()
+
+	c := testChild(t)
+	c.command = "bash"
+	c.args = []string{"-c", "trap '' INT TERM; while true; do sleep 0.2; done"}
+	c.killSignal = os.Interrupt
+	c.killTimeout = 10 * time.Second
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	opid := c.Pid()
+
+	start := time.Now()
+	if err := c.ForceRestart(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected force restart not to wait for the kill timeout, took %s", d)
+	}
+
+	npid := c.Pid()
+
+	if opid == npid {
+		t.Error("expected new process to restart")
+	}
+}
+
+func TestUsage_noProcess(t *testing.T) {
+	t.Parallel()
+
+	c := testChild(t)
+	u, err := c.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != nil {
+		t.Errorf("expected %#v to be nil", u)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource usage sampling is only supported on linux")
+	}
+	t.Parallel()
+
+	c := testChild(t)
+	c.command = "bash"
+	c.args = []string{"-c", "while true; do sleep 0.2; done"}
+	c.killTimeout = 10 * time.Millisecond
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// The process may not have any resident pages immediately after exec.
+	var u *Usage
+	for i := 0; i < 50; i++ {
+		var err error
+		u, err = c.Usage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.RSS != 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if u.Pid != c.Pid() {
+		t.Errorf("expected %d to be %d", u.Pid, c.Pid())
+	}
+	if u.RSS == 0 {
+		t.Error("expected rss to be non-zero")
+	}
+	if u.OpenFDs == 0 {
+		t.Error("expected open fds to be non-zero")
+	}
+}
+
 func TestKill_signal(t *testing.T) {
 	t.Parallel()
 
@@ -394,3 +566,42 @@ func TestKill_noProcess(t *testing.T) {
 	c.killSignal = syscall.SIGUSR1
 	c.Kill()
 }
+
+func TestKill_timeout(t *testing.T) {
+	t.Parallel()
+
+	c := testChild(t)
+	c.command = "bash"
+	c.args = []string{"-c", "trap '' SIGUSR1; while true; do sleep 0.2; done"}
+	c.killSignal = syscall.SIGUSR1
+	c.killTimeout = 100 * time.Millisecond
+
+	killed := make(chan int, 1)
+	c.killTimeoutFunc = func(pid int) {
+		killed <- pid
+	}
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// For some reason bash doesn't start immediately
+	time.Sleep(fileWaitSleepDelay)
+
+	pid := c.Pid()
+	c.Kill()
+
+	select {
+	case p := <-killed:
+		if p != pid {
+			t.Errorf("expected %d to be %d", p, pid)
+		}
+	default:
+		t.Error("expected the kill timeout to be reported")
+	}
+
+	if c.cmd != nil {
+		t.Errorf("expected cmd to be nil")
+	}
+}
@@ -0,0 +1,77 @@
+package child
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUsageUnsupported is the error returned when resource usage sampling is
+// not supported on the current platform.
+var ErrUsageUnsupported = errors.New("resource usage sampling is not supported on this platform")
+
+// Usage is a point-in-time sample of the resource usage of the child process.
+// Only the direct child is sampled; processes it spawns are not included.
+type Usage struct {
+	// Pid is the pid of the sampled process.
+	Pid int
+
+	// CPUTime is the total user and system CPU time consumed by the process.
+	CPUTime time.Duration
+
+	// CPUPercent is the CPU usage, as a percentage of a single core, since the
+	// previous sample of the same process. It is 0 for the first sample.
+	CPUPercent float64
+
+	// RSS is the resident set size of the process, in bytes.
+	RSS uint64
+
+	// OpenFDs is the number of file descriptors the process has open.
+	OpenFDs int
+
+	// SampledAt is the time the sample was taken.
+	SampledAt time.Time
+}
+
+// Usage samples the current resource usage of the child process. If no
+// process is running, nil is returned. The CPU percentage is calculated
+// against the previous call to Usage.
+func (c *Child) Usage() (*Usage, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	pid := c.pid()
+	if pid == 0 {
+		return nil, nil
+	}
+
+	u, err := sampleUsage(pid)
+	if err != nil {
+		return nil, err
+	}
+	u.Pid = pid
+	u.SampledAt = time.Now()
+	u.CPUPercent = cpuPercent(c.lastUsage, u)
+
+	c.lastUsage = u
+	return u, nil
+}
+
+// cpuPercent returns the CPU usage between the two samples as a percentage of
+// a single core.
+func cpuPercent(prev, cur *Usage) float64 {
+	if prev == nil || prev.Pid != cur.Pid {
+		return 0
+	}
+
+	wall := cur.SampledAt.Sub(prev.SampledAt)
+	if wall <= 0 {
+		return 0
+	}
+
+	cpu := cur.CPUTime - prev.CPUTime
+	if cpu < 0 {
+		return 0
+	}
+
+	return float64(cpu) / float64(wall) * 100
+}
@@ -0,0 +1,62 @@
+// +build linux
+
+package child
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the number of clock ticks per second used by /proc. This is
+// USER_HZ, which is 100 on all supported architectures.
+const clockTicks = 100
+
+func sampleUsage(pid int) (*Usage, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name may contain spaces and parens, so start parsing after
+	// the last closing paren. The first field after it is the state (field 3).
+	stat := string(data)
+	idx := strings.LastIndex(stat, ")")
+	if idx == -1 {
+		return nil, fmt.Errorf("child: malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(stat[idx+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("child: malformed stat for pid %d", pid)
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	rss, err := strconv.ParseInt(fields[21], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if rss < 0 {
+		rss = 0
+	}
+
+	fds, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{
+		CPUTime: time.Duration(utime+stime) * time.Second / clockTicks,
+		RSS:     uint64(rss) * uint64(os.Getpagesize()),
+		OpenFDs: len(fds),
+	}, nil
+}
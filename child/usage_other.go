@@ -0,0 +1,7 @@
+// +build !linux
+
+package child
+
+func sampleUsage(pid int) (*Usage, error) {
+	return nil, ErrUsageUnsupported
+}
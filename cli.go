@@ -29,6 +29,7 @@ const (
 	ExitCodeParseFlagsError
 	ExitCodeRunnerError
 	ExitCodeConfigError
+	ExitCodeDriftError
 )
 
 // CLI is the main entry point.
@@ -60,8 +61,19 @@ func NewCLI(out, err io.Writer) *CLI {
 // Run accepts a slice of arguments and returns an int representing the exit
 // status from the command.
 func (cli *CLI) Run(args []string) int {
+	// Run the subcommand, if one was given
+	if len(args) > 1 && args[1] == RenderCommand {
+		return cli.runRender(args[2:])
+	}
+	if len(args) > 1 && args[1] == CtlCommand {
+		return cli.runCtl(args[2:])
+	}
+	if len(args) > 1 && args[1] == CacheServerCommand {
+		return cli.runCacheServer(args[2:])
+	}
+
 	// Parse the flags
-	config, once, dry, version, err := cli.ParseFlags(args[1:])
+	config, once, dry, checkDrift, version, err := cli.ParseFlags(args[1:])
 	if err != nil {
 		if err == flag.ErrHelp {
 			return 0
@@ -90,6 +102,12 @@ func (cli *CLI) Run(args []string) int {
 		return ExitCodeOK
 	}
 
+	// In drift check mode, compare the templates with their destinations
+	// instead of rendering them
+	if checkDrift {
+		return cli.runCheckDrift(config)
+	}
+
 	// Initial runner
 	runner, err := manager.NewRunner(config, dry, once)
 	if err != nil {
@@ -112,25 +130,24 @@ func (cli *CLI) Run(args []string) int {
 			return cli.handleError(err, code)
 		case <-runner.DoneCh:
 			return ExitCodeOK
+		case <-runner.ReloadCh:
+			fmt.Fprintf(cli.errStream, "Reload requested, reloading...\n")
+			next, code := cli.reload(runner, baseConfig, dry, once)
+			if next == nil {
+				return code
+			}
+			runner = next
 		case s := <-cli.signalCh:
 			log.Printf("[DEBUG] (cli) receiving signal %q", s)
 
 			switch s {
 			case *config.ReloadSignal:
 				fmt.Fprintf(cli.errStream, "Reloading configuration...\n")
-				runner.Stop()
-
-				// Load the new configuration from disk
-				config, err = cli.setup(baseConfig)
-				if err != nil {
-					return cli.handleError(err, ExitCodeConfigError)
-				}
-
-				runner, err = manager.NewRunner(config, dry, once)
-				if err != nil {
-					return cli.handleError(err, ExitCodeRunnerError)
+				next, code := cli.reload(runner, baseConfig, dry, once)
+				if next == nil {
+					return code
 				}
-				go runner.Start()
+				runner = next
 			case *config.KillSignal:
 				fmt.Fprintf(cli.errStream, "Cleaning up...\n")
 				runner.Stop()
@@ -152,6 +169,44 @@ func (cli *CLI) Run(args []string) int {
 	}
 }
 
+// reload applies the configuration to the given runner in place, which keeps
+// watching the dependencies still in use. If the configuration changes more
+// than the templates, the runner is stopped and replaced by a new one. It
+// returns the runner to use from now on, or nil and the exit code if the
+// configuration could not be applied.
+func (cli *CLI) reload(runner *manager.Runner, baseConfig *config.Config, dry, once bool) (*manager.Runner, int) {
+	conf, err := cli.setup(baseConfig)
+	if err != nil {
+		runner.Stop()
+		return nil, cli.handleError(err, ExitCodeConfigError)
+	}
+
+	err = runner.Reload(conf)
+	if err == nil {
+		return runner, ExitCodeOK
+	}
+	if err != manager.ErrReloadRequiresRestart {
+		runner.Stop()
+		return nil, cli.handleError(err, ExitCodeRunnerError)
+	}
+
+	log.Printf("[INFO] (cli) restarting the runner to apply the configuration")
+	runner.Stop()
+
+	next, err := manager.NewRunner(conf, dry, once)
+	if err != nil {
+		return nil, cli.handleError(err, ExitCodeRunnerError)
+	}
+	if runner.Promoted() {
+		next.Promote()
+	}
+	if err := runner.DestroyRemovedTemplates(next); err != nil {
+		log.Printf("[ERR] (cli) %s", err)
+	}
+	go next.Start()
+	return next, ExitCodeOK
+}
+
 // stop is used internally to shutdown a running CLI
 func (cli *CLI) stop() {
 	cli.Lock()
@@ -169,18 +224,26 @@ func (cli *CLI) stop() {
 // Flag library. This is extracted into a helper to keep the main function
 // small, but it also makes writing tests for parsing command line arguments
 // much easier and cleaner.
-func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, error) {
-	var dry, once, version bool
+func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, bool, error) {
+	var checkDrift, dry, once, strict, version bool
+
+	// profile is the name of the profile to apply, which defaults to the value
+	// of the environment variable
+	profile := os.Getenv(config.ProfileEnvVar)
 
 	c := config.DefaultConfig()
 
 	// configPaths stores the list of configuration paths on disk
 	configPaths := make([]string, 0, 6)
 
+	// vars and varFiles store the variables given with -var and -var-file
+	vars := make(map[string]string)
+	varFiles := make([]string, 0, 2)
+
 	// Parse the flags and options
 	flags := flag.NewFlagSet(Name, flag.ContinueOnError)
 	flags.SetOutput(cli.errStream)
-	flags.Usage = func() { fmt.Fprintf(cli.errStream, usage, Name) }
+	flags.Usage = func() { fmt.Fprintf(cli.errStream, usage, Name, Name, Name, Name) }
 
 	flags.Var((funcVar)(func(s string) error {
 		a, err := config.ParseAuthConfig(s)
@@ -191,6 +254,8 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, err
 		return nil
 	}), "auth", "")
 
+	flags.BoolVar(&checkDrift, "check-drift", false, "")
+
 	flags.Var((funcVar)(func(s string) error {
 		configPaths = append(configPaths, s)
 		return nil
@@ -261,12 +326,24 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, err
 		return nil
 	}), "max-stale", "")
 
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.Observe = config.Bool(b)
+		return nil
+	}), "observe", "")
+
 	flags.BoolVar(&once, "once", false, "")
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.OnceRetryTimeout = config.TimeDuration(d)
+		return nil
+	}), "once-retry-timeout", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		c.PidFile = config.String(s)
 		return nil
 	}), "pid-file", "")
 
+	flags.StringVar(&profile, "profile", profile, "")
+
 	flags.Var((funcVar)(func(s string) error {
 		sig, err := signals.Parse(s)
 		if err != nil {
@@ -316,6 +393,13 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, err
 		return nil
 	}), "ssl-verify", "")
 
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.Standby.Enabled = config.Bool(b)
+		return nil
+	}), "standby", "")
+
+	flags.BoolVar(&strict, "strict-config", false, "")
+
 	flags.Var((funcBoolVar)(func(b bool) error {
 		c.Syslog.Enabled = config.Bool(b)
 		return nil
@@ -335,16 +419,60 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, err
 		return nil
 	}), "template", "")
 
+	flags.Var((funcVar)(func(s string) error {
+		c.TemplateFilter = config.String(s)
+		return nil
+	}), "template-filter", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		c.Token = config.String(s)
 		return nil
 	}), "token", "")
 
+	flags.Var((funcVar)(func(s string) error {
+		c.TokenFile = config.String(s)
+		return nil
+	}), "token-file", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		k, v, err := config.ParseVar(s)
+		if err != nil {
+			return err
+		}
+		vars[k] = v
+		return nil
+	}), "var", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		varFiles = append(varFiles, s)
+		return nil
+	}), "var-file", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		c.Vault.Address = config.String(s)
 		return nil
 	}), "vault-addr", "")
 
+	flags.Var((funcVar)(func(s string) error {
+		c.VaultAgentTokenFile = config.String(s)
+		return nil
+	}), "vault-agent-token-file", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Vault.AuthMethod = config.String(s)
+		return nil
+	}), "vault-auth-method", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Vault.AuthMount = config.String(s)
+		return nil
+	}), "vault-auth-mount", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Vault.AuthRole = config.String(s)
+		return nil
+	}), "vault-auth-role", "")
+
 	flags.Var((funcBoolVar)(func(b bool) error {
 		c.Vault.RenewToken = config.Bool(b)
 		return nil
@@ -409,13 +537,18 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, err
 
 	// If there was a parser error, stop
 	if err := flags.Parse(args); err != nil {
-		return nil, false, false, false, err
+		return nil, false, false, false, false, err
 	}
 
 	// Error if extra arguments are present
 	args = flags.Args()
 	if len(args) > 0 {
-		return nil, false, false, false, fmt.Errorf("cli: extra args: %q", args)
+		return nil, false, false, false, false, fmt.Errorf("cli: extra args: %q", args)
+	}
+
+	// Add the variables from the command line
+	if err := mergeCLIVars(c, varFiles, vars); err != nil {
+		return nil, false, false, false, false, err
 	}
 
 	// Create the final configuration
@@ -423,20 +556,56 @@ func (cli *CLI) ParseFlags(args []string) (*config.Config, bool, bool, bool, err
 
 	// Merge all the provided configurations in the order supplied
 	for _, path := range configPaths {
+		if strict {
+			if err := config.ValidateStrictPath(path); err != nil {
+				return nil, false, false, false, false, err
+			}
+		}
+
 		c, err := config.FromPath(path)
 		if err != nil {
-			return nil, false, false, false, err
+			return nil, false, false, false, false, err
 		}
 		finalC = finalC.Merge(c)
 	}
 
+	// Apply the selected profile on top of the configuration files
+	finalC, err := finalC.ApplyProfile(profile)
+	if err != nil {
+		return nil, false, false, false, false, err
+	}
+
 	// Add any CLI configuration options, since that's highest precedence
 	finalC = finalC.Merge(c)
 
 	// Finalize the configuration
 	finalC.Finalize()
 
-	return finalC, once, dry, version, nil
+	return finalC, once, dry, checkDrift, version, nil
+}
+
+// mergeCLIVars sets the variables of the given configuration from the given
+// variable files, in order, and then the variables given with -var, which take
+// precedence.
+func mergeCLIVars(c *config.Config, varFiles []string, vars map[string]string) error {
+	if len(varFiles) == 0 && len(vars) == 0 {
+		return nil
+	}
+
+	c.Vars = make(map[string]string)
+	for _, path := range varFiles {
+		fileVars, err := config.ParseVarFile(path)
+		if err != nil {
+			return err
+		}
+		for k, v := range fileVars {
+			c.Vars[k] = v
+		}
+	}
+	for k, v := range vars {
+		c.Vars[k] = v
+	}
+	return nil
 }
 
 // handleError outputs the given error's Error() to the errStream and returns
@@ -467,11 +636,21 @@ Usage: %s [options]
   Consul is updated. It runs until an interrupt is received unless the -once
   flag is specified.
 
+  To render a single template to standard out and exit, run "%s render". To
+  control a running instance through its control socket, run "%s ctl". To
+  share a cache of Consul data between the instances on a host, run
+  "%s cache-server".
+
 Options:
 
   -auth=<username[:password]>
       Set the basic authentication username (and password)
 
+  -check-drift
+      Render all templates once without writing them, and report (and exit
+      non-zero for) each destination which is missing or differs from its
+      rendered contents. No commands are run
+
   -config=<path>
       Sets the path to a configuration file or folder on disk. This can be
       specified multiple times to load multiple files or folders. If multiple
@@ -515,12 +694,25 @@ Options:
       Set the maximum staleness and allow stale queries to Consul which will
       distribute work among all servers instead of just the leader
 
+  -observe
+      Render templates indefinitely and log the changes which would be written
+      and the dependency changes which caused them, without writing files or
+      running commands
+
   -once
       Do not run the process as a daemon
 
+  -once-retry-timeout=<duration>
+      The maximum amount of time once mode retries failed dependencies before
+      giving up - zero gives up on the first failure
+
   -pid-file=<path>
       Path on disk to write the PID of the process
 
+  -profile=<name>
+      Name of the profile of the configuration files to apply on top of the
+      rest of the configuration; defaults to $CONSUL_TEMPLATE_PROFILE
+
   -reload-signal=<signal>
       Signal to listen to reload configuration
 
@@ -543,6 +735,15 @@ Options:
   -ssl-verify
       Verify certificates when connecting via SSL
 
+  -standby
+      Start in standby mode - templates are rendered, but commands and child
+      reloads are held until promoted
+
+  -strict-config
+      Reject configuration files with unknown keys or invalid values, such as
+      durations, signals, file modes or log levels, naming the file and line
+      of each
+
   -syslog
       Send the output to syslog instead of standard error and standard out. The
       syslog facility defaults to LOCAL0 and can be changed using a
@@ -555,12 +756,46 @@ Options:
   -template=<template>
        Adds a new template to watch on disk in the format 'in:out(:command)'
 
+  -template-filter=<glob>
+      Only activates the templates whose id or destination matches the glob,
+      so several instances can share one configuration file
+
   -token=<token>
       Sets the Consul API token
 
+  -token-file=<path>
+      Sets the path to a file containing the Consul API token - the file is
+      watched and the new token is used when its contents change
+
+  -var=<key=value>
+      Sets a variable available to templates through the var function. This
+      can be specified multiple times and takes precedence over -var-file and
+      the vars in configuration files
+
+  -var-file=<path>
+      Sets the path to an HCL or JSON file of variables available to templates
+      through the var function. This can be specified multiple times, and
+      later files take precedence
+
   -vault-addr=<address>
       Sets the address of the Vault server
 
+  -vault-agent-token-file=<path>
+      Sets the path to a file containing the Vault API token, such as a Vault
+      agent sink - the file is watched and the new token is used when its
+      contents change
+
+  -vault-auth-method=<method>
+      Sets the method used to authenticate to Vault - values are "token" and
+      "cert", which logs in with the Vault SSL client certificate
+
+  -vault-auth-mount=<path>
+      Sets the path the Vault auth method is mounted at - this defaults to the
+      name of the auth method
+
+  -vault-auth-role=<name>
+      Sets the name of the role to log in to Vault with
+
   -vault-renew-token
       Periodically renew the provided Vault API token - this defaults to "true"
       and will renew the token at half of the lease duration
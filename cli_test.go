@@ -32,6 +32,41 @@ func TestCLI_ParseFlags(t *testing.T) {
 	}
 	defer os.Remove(f.Name())
 
+	varFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(varFile.Name())
+	if _, err := varFile.WriteString(`
+		region = "us-east-1"
+		zone   = "a"
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	strictFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(strictFile.Name())
+	if _, err := strictFile.WriteString(`log_level = "wanr"`); err != nil {
+		t.Fatal(err)
+	}
+
+	profileFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(profileFile.Name())
+	if _, err := profileFile.WriteString(`
+		log_level = "info"
+		profile "prod" {
+			log_level = "warn"
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
 	cases := []struct {
 		name string
 		f    []string
@@ -170,6 +205,22 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"observe",
+			[]string{"-observe"},
+			&config.Config{
+				Observe: config.Bool(true),
+			},
+			false,
+		},
+		{
+			"once-retry-timeout",
+			[]string{"-once-retry-timeout", "2m"},
+			&config.Config{
+				OnceRetryTimeout: config.TimeDuration(2 * time.Minute),
+			},
+			false,
+		},
 		{
 			"pid-file",
 			[]string{"-pid-file", "/var/pid/file"},
@@ -264,6 +315,56 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"standby",
+			[]string{"-standby"},
+			&config.Config{
+				Standby: &config.StandbyConfig{
+					Enabled: config.Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"profile",
+			[]string{"-config", profileFile.Name(), "-profile", "prod"},
+			&config.Config{
+				LogLevel: config.String("warn"),
+				Profiles: map[string]*config.Config{
+					"prod": &config.Config{LogLevel: config.String("warn")},
+				},
+			},
+			false,
+		},
+		{
+			"profile_none",
+			[]string{"-config", profileFile.Name()},
+			&config.Config{
+				LogLevel: config.String("info"),
+				Profiles: map[string]*config.Config{
+					"prod": &config.Config{LogLevel: config.String("warn")},
+				},
+			},
+			false,
+		},
+		{
+			"profile_unknown",
+			[]string{"-config", profileFile.Name(), "-profile", "staging"},
+			nil,
+			true,
+		},
+		{
+			"strict_config",
+			[]string{"-strict-config", "-config", f.Name()},
+			&config.Config{},
+			false,
+		},
+		{
+			"strict_config_invalid",
+			[]string{"-strict-config", "-config", strictFile.Name()},
+			nil,
+			true,
+		},
 		{
 			"syslog",
 			[]string{"-syslog"},
@@ -296,6 +397,14 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template-filter",
+			[]string{"-template-filter", "nginx-*"},
+			&config.Config{
+				TemplateFilter: config.String("nginx-*"),
+			},
+			false,
+		},
 		{
 			"token",
 			[]string{"-token", "token"},
@@ -304,6 +413,53 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"token-file",
+			[]string{"-token-file", "/tmp/token"},
+			&config.Config{
+				TokenFile: config.String("/tmp/token"),
+			},
+			false,
+		},
+		{
+			"var",
+			[]string{"-var", "region=us-east-1", "-var", "dsn=a=b"},
+			&config.Config{
+				Vars: map[string]string{
+					"region": "us-east-1",
+					"dsn":    "a=b",
+				},
+			},
+			false,
+		},
+		{
+			"var_invalid",
+			[]string{"-var", "region"},
+			nil,
+			true,
+		},
+		{
+			"var-file",
+			[]string{"-var-file", varFile.Name()},
+			&config.Config{
+				Vars: map[string]string{
+					"region": "us-east-1",
+					"zone":   "a",
+				},
+			},
+			false,
+		},
+		{
+			"var_overrides_var-file",
+			[]string{"-var", "zone=b", "-var-file", varFile.Name()},
+			&config.Config{
+				Vars: map[string]string{
+					"region": "us-east-1",
+					"zone":   "b",
+				},
+			},
+			false,
+		},
 		{
 			"vault-addr",
 			[]string{"-vault-addr", "vault_addr"},
@@ -314,6 +470,44 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault-agent-token-file",
+			[]string{"-vault-agent-token-file", "/tmp/vault-token"},
+			&config.Config{
+				VaultAgentTokenFile: config.String("/tmp/vault-token"),
+			},
+			false,
+		},
+		{
+			"vault-auth-method",
+			[]string{"-vault-auth-method", "cert"},
+			&config.Config{
+				Vault: &config.VaultConfig{
+					AuthMethod: config.String("cert"),
+				},
+			},
+			false,
+		},
+		{
+			"vault-auth-mount",
+			[]string{"-vault-auth-mount", "certs"},
+			&config.Config{
+				Vault: &config.VaultConfig{
+					AuthMount: config.String("certs"),
+				},
+			},
+			false,
+		},
+		{
+			"vault-auth-role",
+			[]string{"-vault-auth-role", "web"},
+			&config.Config{
+				Vault: &config.VaultConfig{
+					AuthRole: config.String("web"),
+				},
+			},
+			false,
+		},
 		{
 			"vault-renew-token",
 			[]string{"-vault-renew-token"},
@@ -457,7 +651,7 @@ func TestCLI_ParseFlags(t *testing.T) {
 			var out bytes.Buffer
 			cli := NewCLI(&out, &out)
 
-			a, _, _, _, err := cli.ParseFlags(tc.f)
+			a, _, _, _, _, err := cli.ParseFlags(tc.f)
 			if (err != nil) != tc.err {
 				t.Fatal(err)
 			}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AgentCacheConfig is used to configure reads from the local Consul agent's
+// cache. Cached reads are served by the agent without a round trip to the
+// Consul servers.
+type AgentCacheConfig struct {
+	// BackgroundRefresh controls if blocking queries are also served from the
+	// agent cache, which the agent keeps up to date with its own blocking query
+	// in the background. If false, only the initial read of a dependency is
+	// served from the cache.
+	BackgroundRefresh *bool `mapstructure:"background_refresh"`
+
+	// MaxAge is the maximum age of a cached result the agent may return. A
+	// value of 0 accepts any age.
+	MaxAge *time.Duration `mapstructure:"max_age"`
+
+	// UseCache controls if queries which support the agent cache are served
+	// from it.
+	UseCache *bool `mapstructure:"use_cache"`
+}
+
+// DefaultAgentCacheConfig returns a configuration that is populated with the
+// default values.
+func DefaultAgentCacheConfig() *AgentCacheConfig {
+	return &AgentCacheConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *AgentCacheConfig) Copy() *AgentCacheConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o AgentCacheConfig
+	o.BackgroundRefresh = c.BackgroundRefresh
+	o.MaxAge = c.MaxAge
+	o.UseCache = c.UseCache
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *AgentCacheConfig) Merge(o *AgentCacheConfig) *AgentCacheConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.BackgroundRefresh != nil {
+		r.BackgroundRefresh = o.BackgroundRefresh
+	}
+
+	if o.MaxAge != nil {
+		r.MaxAge = o.MaxAge
+	}
+
+	if o.UseCache != nil {
+		r.UseCache = o.UseCache
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *AgentCacheConfig) Finalize() {
+	if c.UseCache == nil {
+		c.UseCache = Bool(false ||
+			BoolPresent(c.BackgroundRefresh) ||
+			TimeDurationPresent(c.MaxAge))
+	}
+
+	if c.BackgroundRefresh == nil {
+		c.BackgroundRefresh = Bool(true)
+	}
+
+	if c.MaxAge == nil {
+		c.MaxAge = TimeDuration(0)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *AgentCacheConfig) GoString() string {
+	if c == nil {
+		return "(*AgentCacheConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&AgentCacheConfig{"+
+		"BackgroundRefresh:%s, "+
+		"MaxAge:%s, "+
+		"UseCache:%s"+
+		"}",
+		BoolGoString(c.BackgroundRefresh),
+		TimeDurationGoString(c.MaxAge),
+		BoolGoString(c.UseCache),
+	)
+}
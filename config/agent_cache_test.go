@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAgentCacheConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *AgentCacheConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&AgentCacheConfig{},
+		},
+		{
+			"copy",
+			&AgentCacheConfig{
+				BackgroundRefresh: Bool(false),
+				MaxAge:            TimeDuration(30 * time.Second),
+				UseCache:          Bool(true),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestAgentCacheConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *AgentCacheConfig
+		b    *AgentCacheConfig
+		r    *AgentCacheConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&AgentCacheConfig{},
+			&AgentCacheConfig{},
+		},
+		{
+			"nil_b",
+			&AgentCacheConfig{},
+			nil,
+			&AgentCacheConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&AgentCacheConfig{},
+			&AgentCacheConfig{},
+			&AgentCacheConfig{},
+		},
+		{
+			"background_refresh_overrides",
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+			&AgentCacheConfig{BackgroundRefresh: Bool(false)},
+			&AgentCacheConfig{BackgroundRefresh: Bool(false)},
+		},
+		{
+			"background_refresh_empty_one",
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+			&AgentCacheConfig{},
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+		},
+		{
+			"background_refresh_empty_two",
+			&AgentCacheConfig{},
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+		},
+		{
+			"background_refresh_same",
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+			&AgentCacheConfig{BackgroundRefresh: Bool(true)},
+		},
+		{
+			"max_age_overrides",
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+			&AgentCacheConfig{MaxAge: TimeDuration(0)},
+			&AgentCacheConfig{MaxAge: TimeDuration(0)},
+		},
+		{
+			"max_age_empty_one",
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+			&AgentCacheConfig{},
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+		},
+		{
+			"max_age_empty_two",
+			&AgentCacheConfig{},
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+		},
+		{
+			"max_age_same",
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+			&AgentCacheConfig{MaxAge: TimeDuration(10 * time.Second)},
+		},
+		{
+			"use_cache_overrides",
+			&AgentCacheConfig{UseCache: Bool(true)},
+			&AgentCacheConfig{UseCache: Bool(false)},
+			&AgentCacheConfig{UseCache: Bool(false)},
+		},
+		{
+			"use_cache_empty_one",
+			&AgentCacheConfig{UseCache: Bool(true)},
+			&AgentCacheConfig{},
+			&AgentCacheConfig{UseCache: Bool(true)},
+		},
+		{
+			"use_cache_empty_two",
+			&AgentCacheConfig{},
+			&AgentCacheConfig{UseCache: Bool(true)},
+			&AgentCacheConfig{UseCache: Bool(true)},
+		},
+		{
+			"use_cache_same",
+			&AgentCacheConfig{UseCache: Bool(true)},
+			&AgentCacheConfig{UseCache: Bool(true)},
+			&AgentCacheConfig{UseCache: Bool(true)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestAgentCacheConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *AgentCacheConfig
+		r    *AgentCacheConfig
+	}{
+		{
+			"empty",
+			&AgentCacheConfig{},
+			&AgentCacheConfig{
+				BackgroundRefresh: Bool(true),
+				MaxAge:            TimeDuration(0),
+				UseCache:          Bool(false),
+			},
+		},
+		{
+			"with_max_age",
+			&AgentCacheConfig{
+				MaxAge: TimeDuration(30 * time.Second),
+			},
+			&AgentCacheConfig{
+				BackgroundRefresh: Bool(true),
+				MaxAge:            TimeDuration(30 * time.Second),
+				UseCache:          Bool(true),
+			},
+		},
+		{
+			"without_background_refresh",
+			&AgentCacheConfig{
+				BackgroundRefresh: Bool(false),
+			},
+			&AgentCacheConfig{
+				BackgroundRefresh: Bool(false),
+				MaxAge:            TimeDuration(0),
+				UseCache:          Bool(true),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
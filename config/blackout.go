@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BlackoutConfig is a window during which templates are not written to disk.
+// The templates are still executed, and the latest contents are written once
+// the window ends. This allows change freezes without stopping the process.
+type BlackoutConfig struct {
+	// Duration is how long the window lasts from each of its starts.
+	Duration *time.Duration `mapstructure:"duration"`
+
+	// Schedule is the cron expression of the starts of the window, with the
+	// minute, hour, day of the month, month and day of the week fields, such as
+	// "0 18 * * 5" for every Friday at 18:00 local time.
+	Schedule *string `mapstructure:"schedule"`
+}
+
+// DefaultBlackoutConfig returns a configuration that is populated with the
+// default values.
+func DefaultBlackoutConfig() *BlackoutConfig {
+	return &BlackoutConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *BlackoutConfig) Copy() *BlackoutConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o BlackoutConfig
+	o.Duration = c.Duration
+	o.Schedule = c.Schedule
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *BlackoutConfig) Merge(o *BlackoutConfig) *BlackoutConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Duration != nil {
+		r.Duration = o.Duration
+	}
+
+	if o.Schedule != nil {
+		r.Schedule = o.Schedule
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *BlackoutConfig) Finalize() {
+	if c.Duration == nil {
+		c.Duration = TimeDuration(0)
+	}
+
+	if c.Schedule == nil {
+		c.Schedule = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *BlackoutConfig) GoString() string {
+	if c == nil {
+		return "(*BlackoutConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&BlackoutConfig{"+
+		"Duration:%s, "+
+		"Schedule:%s"+
+		"}",
+		TimeDurationGoString(c.Duration),
+		StringGoString(c.Schedule),
+	)
+}
+
+// BlackoutConfigs is a collection of BlackoutConfigs.
+type BlackoutConfigs []*BlackoutConfig
+
+// DefaultBlackoutConfigs returns a configuration that is populated with the
+// default values.
+func DefaultBlackoutConfigs() *BlackoutConfigs {
+	return &BlackoutConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *BlackoutConfigs) Copy() *BlackoutConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(BlackoutConfigs, len(*c))
+	for i, t := range *c {
+		o[i] = t.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *BlackoutConfigs) Merge(o *BlackoutConfigs) *BlackoutConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	*r = append(*r, *o.Copy()...)
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *BlackoutConfigs) Finalize() {
+	for _, t := range *c {
+		t.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *BlackoutConfigs) GoString() string {
+	if c == nil {
+		return "(*BlackoutConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, t := range *c {
+		s[i] = t.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBlackoutConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *BlackoutConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&BlackoutConfig{},
+		},
+		{
+			"copy",
+			&BlackoutConfig{
+				Duration: TimeDuration(2 * time.Hour),
+				Schedule: String("0 18 * * 5"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestBlackoutConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *BlackoutConfig
+		b    *BlackoutConfig
+		r    *BlackoutConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&BlackoutConfig{},
+			&BlackoutConfig{},
+		},
+		{
+			"nil_b",
+			&BlackoutConfig{},
+			nil,
+			&BlackoutConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&BlackoutConfig{},
+			&BlackoutConfig{},
+			&BlackoutConfig{},
+		},
+		{
+			"duration_overrides",
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+			&BlackoutConfig{Duration: TimeDuration(3 * time.Hour)},
+			&BlackoutConfig{Duration: TimeDuration(3 * time.Hour)},
+		},
+		{
+			"duration_empty_one",
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+			&BlackoutConfig{},
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+		},
+		{
+			"duration_empty_two",
+			&BlackoutConfig{},
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+		},
+		{
+			"duration_same",
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+			&BlackoutConfig{Duration: TimeDuration(2 * time.Hour)},
+		},
+		{
+			"schedule_overrides",
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+			&BlackoutConfig{Schedule: String("")},
+			&BlackoutConfig{Schedule: String("")},
+		},
+		{
+			"schedule_empty_one",
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+			&BlackoutConfig{},
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+		},
+		{
+			"schedule_empty_two",
+			&BlackoutConfig{},
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+		},
+		{
+			"schedule_same",
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+			&BlackoutConfig{Schedule: String("0 18 * * 5")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestBlackoutConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *BlackoutConfig
+		r    *BlackoutConfig
+	}{
+		{
+			"empty",
+			&BlackoutConfig{},
+			&BlackoutConfig{
+				Duration: TimeDuration(0),
+				Schedule: String(""),
+			},
+		},
+		{
+			"with_schedule",
+			&BlackoutConfig{
+				Duration: TimeDuration(3 * time.Hour),
+				Schedule: String("0 18 * * 5"),
+			},
+			&BlackoutConfig{
+				Duration: TimeDuration(3 * time.Hour),
+				Schedule: String("0 18 * * 5"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
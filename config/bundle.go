@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// BundleFormatTar, BundleFormatTarGzip and BundleFormatZip are the archive
+	// formats of a bundle.
+	BundleFormatTar     = "tar"
+	BundleFormatTarGzip = "tar.gz"
+	BundleFormatZip     = "zip"
+)
+
+// BundleConfig is an archive which packages the rendered output of several
+// templates, each as an entry at its own path. The archive is written
+// atomically whenever an entry changes.
+type BundleConfig struct {
+	// Destination is the location on disk where the archive is written.
+	Destination *string `mapstructure:"destination"`
+
+	// Format is the format of the archive, one of "tar", "tar.gz" or "zip". The
+	// default value is inferred from the extension of Destination.
+	Format *string `mapstructure:"format"`
+
+	// Name is the name templates use to add their output to the bundle.
+	Name *string `mapstructure:"name"`
+
+	// Perms are the permissions of the archive. The default value is 0644.
+	Perms *os.FileMode `mapstructure:"perms"`
+}
+
+// DefaultBundleConfig returns a configuration that is populated with the
+// default values.
+func DefaultBundleConfig() *BundleConfig {
+	return &BundleConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *BundleConfig) Copy() *BundleConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o BundleConfig
+	o.Destination = c.Destination
+	o.Format = c.Format
+	o.Name = c.Name
+	o.Perms = c.Perms
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *BundleConfig) Merge(o *BundleConfig) *BundleConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Destination != nil {
+		r.Destination = o.Destination
+	}
+
+	if o.Format != nil {
+		r.Format = o.Format
+	}
+
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
+	if o.Perms != nil {
+		r.Perms = o.Perms
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *BundleConfig) Finalize() {
+	if c.Destination == nil {
+		c.Destination = String("")
+	}
+
+	if c.Format == nil {
+		c.Format = String(bundleFormatFromPath(StringVal(c.Destination)))
+	}
+
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
+	if c.Perms == nil {
+		c.Perms = FileMode(DefaultTemplateFilePerms)
+	}
+}
+
+// bundleFormatFromPath returns the archive format matching the extension of
+// the given path, or an empty string if there is none.
+func bundleFormatFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return BundleFormatTarGzip
+	case strings.HasSuffix(path, ".tar"):
+		return BundleFormatTar
+	case strings.HasSuffix(path, ".zip"):
+		return BundleFormatZip
+	default:
+		return ""
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *BundleConfig) GoString() string {
+	if c == nil {
+		return "(*BundleConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&BundleConfig{"+
+		"Destination:%s, "+
+		"Format:%s, "+
+		"Name:%s, "+
+		"Perms:%s"+
+		"}",
+		StringGoString(c.Destination),
+		StringGoString(c.Format),
+		StringGoString(c.Name),
+		FileModeGoString(c.Perms),
+	)
+}
+
+// BundleConfigs is a collection of BundleConfigs.
+type BundleConfigs []*BundleConfig
+
+// DefaultBundleConfigs returns a configuration that is populated with the
+// default values.
+func DefaultBundleConfigs() *BundleConfigs {
+	return &BundleConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *BundleConfigs) Copy() *BundleConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(BundleConfigs, len(*c))
+	for i, t := range *c {
+		o[i] = t.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *BundleConfigs) Merge(o *BundleConfigs) *BundleConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	*r = append(*r, *o.Copy()...)
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *BundleConfigs) Finalize() {
+	for _, t := range *c {
+		t.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *BundleConfigs) GoString() string {
+	if c == nil {
+		return "(*BundleConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, t := range *c {
+		s[i] = t.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
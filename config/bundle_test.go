@@ -0,0 +1,245 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestBundleConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *BundleConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&BundleConfig{},
+		},
+		{
+			"copy",
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.tar.gz"),
+				Format:      String(BundleFormatTarGzip),
+				Name:        String("app"),
+				Perms:       FileMode(0600),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestBundleConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *BundleConfig
+		b    *BundleConfig
+		r    *BundleConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&BundleConfig{},
+			&BundleConfig{},
+		},
+		{
+			"nil_b",
+			&BundleConfig{},
+			nil,
+			&BundleConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&BundleConfig{},
+			&BundleConfig{},
+			&BundleConfig{},
+		},
+		{
+			"destination_overrides",
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+			&BundleConfig{Destination: String("")},
+			&BundleConfig{Destination: String("")},
+		},
+		{
+			"destination_empty_one",
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+			&BundleConfig{},
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+		},
+		{
+			"destination_empty_two",
+			&BundleConfig{},
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+		},
+		{
+			"destination_same",
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+			&BundleConfig{Destination: String("/etc/app/bundle.tar.gz")},
+		},
+		{
+			"format_overrides",
+			&BundleConfig{Format: String(BundleFormatZip)},
+			&BundleConfig{Format: String("")},
+			&BundleConfig{Format: String("")},
+		},
+		{
+			"format_empty_one",
+			&BundleConfig{Format: String(BundleFormatZip)},
+			&BundleConfig{},
+			&BundleConfig{Format: String(BundleFormatZip)},
+		},
+		{
+			"format_empty_two",
+			&BundleConfig{},
+			&BundleConfig{Format: String(BundleFormatZip)},
+			&BundleConfig{Format: String(BundleFormatZip)},
+		},
+		{
+			"format_same",
+			&BundleConfig{Format: String(BundleFormatZip)},
+			&BundleConfig{Format: String(BundleFormatZip)},
+			&BundleConfig{Format: String(BundleFormatZip)},
+		},
+		{
+			"name_overrides",
+			&BundleConfig{Name: String("app")},
+			&BundleConfig{Name: String("")},
+			&BundleConfig{Name: String("")},
+		},
+		{
+			"name_empty_one",
+			&BundleConfig{Name: String("app")},
+			&BundleConfig{},
+			&BundleConfig{Name: String("app")},
+		},
+		{
+			"name_empty_two",
+			&BundleConfig{},
+			&BundleConfig{Name: String("app")},
+			&BundleConfig{Name: String("app")},
+		},
+		{
+			"name_same",
+			&BundleConfig{Name: String("app")},
+			&BundleConfig{Name: String("app")},
+			&BundleConfig{Name: String("app")},
+		},
+		{
+			"perms_overrides",
+			&BundleConfig{Perms: FileMode(0600)},
+			&BundleConfig{Perms: FileMode(0644)},
+			&BundleConfig{Perms: FileMode(0644)},
+		},
+		{
+			"perms_empty_one",
+			&BundleConfig{Perms: FileMode(0600)},
+			&BundleConfig{},
+			&BundleConfig{Perms: FileMode(0600)},
+		},
+		{
+			"perms_empty_two",
+			&BundleConfig{},
+			&BundleConfig{Perms: FileMode(0600)},
+			&BundleConfig{Perms: FileMode(0600)},
+		},
+		{
+			"perms_same",
+			&BundleConfig{Perms: FileMode(0600)},
+			&BundleConfig{Perms: FileMode(0600)},
+			&BundleConfig{Perms: FileMode(0600)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestBundleConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *BundleConfig
+		r    *BundleConfig
+	}{
+		{
+			"empty",
+			&BundleConfig{},
+			&BundleConfig{
+				Destination: String(""),
+				Format:      String(""),
+				Name:        String(""),
+				Perms:       FileMode(DefaultTemplateFilePerms),
+			},
+		},
+		{
+			"tgz",
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.tgz"),
+			},
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.tgz"),
+				Format:      String(BundleFormatTarGzip),
+				Name:        String(""),
+				Perms:       FileMode(DefaultTemplateFilePerms),
+			},
+		},
+		{
+			"zip",
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.zip"),
+			},
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.zip"),
+				Format:      String(BundleFormatZip),
+				Name:        String(""),
+				Perms:       FileMode(DefaultTemplateFilePerms),
+			},
+		},
+		{
+			"explicit_format",
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.zip"),
+				Format:      String(BundleFormatTar),
+			},
+			&BundleConfig{
+				Destination: String("/etc/app/bundle.zip"),
+				Format:      String(BundleFormatTar),
+				Name:        String(""),
+				Perms:       FileMode(DefaultTemplateFilePerms),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultCanaryBake is the default amount of time hosts which are not
+	// canaries wait before writing changed contents.
+	DefaultCanaryBake = 10 * time.Minute
+)
+
+// CanaryConfig is used to configure the canary render mode. Hosts whose node
+// name matches the selector write changed contents right away, while the
+// other hosts hold them until they have baked on the canaries for the bake
+// duration.
+type CanaryConfig struct {
+	// Bake is the amount of time hosts which are not canaries hold changed
+	// contents before writing them. New contents restart the bake.
+	Bake *time.Duration `mapstructure:"bake"`
+
+	// Enabled controls if the canary render mode is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// KillSwitchKey is the Consul KV key which aborts the held changes if it
+	// exists when their bake ends. They are not written until the contents
+	// change again.
+	KillSwitchKey *string `mapstructure:"kill_switch_key"`
+
+	// NodeName is the name matched against the selector. The default value is
+	// the node name of the local Consul agent, or the hostname if Consul is
+	// unreachable.
+	NodeName *string `mapstructure:"node_name"`
+
+	// Selector is the glob pattern matching the node names of the canaries.
+	Selector *string `mapstructure:"selector"`
+}
+
+// DefaultCanaryConfig returns a configuration that is populated with the
+// default values.
+func DefaultCanaryConfig() *CanaryConfig {
+	return &CanaryConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *CanaryConfig) Copy() *CanaryConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o CanaryConfig
+	o.Bake = c.Bake
+	o.Enabled = c.Enabled
+	o.KillSwitchKey = c.KillSwitchKey
+	o.NodeName = c.NodeName
+	o.Selector = c.Selector
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *CanaryConfig) Merge(o *CanaryConfig) *CanaryConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Bake != nil {
+		r.Bake = o.Bake
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.KillSwitchKey != nil {
+		r.KillSwitchKey = o.KillSwitchKey
+	}
+
+	if o.NodeName != nil {
+		r.NodeName = o.NodeName
+	}
+
+	if o.Selector != nil {
+		r.Selector = o.Selector
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *CanaryConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Selector))
+	}
+
+	if c.Bake == nil {
+		c.Bake = TimeDuration(DefaultCanaryBake)
+	}
+
+	if c.KillSwitchKey == nil {
+		c.KillSwitchKey = String("")
+	}
+
+	if c.NodeName == nil {
+		c.NodeName = String("")
+	}
+
+	if c.Selector == nil {
+		c.Selector = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *CanaryConfig) GoString() string {
+	if c == nil {
+		return "(*CanaryConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&CanaryConfig{"+
+		"Bake:%s, "+
+		"Enabled:%s, "+
+		"KillSwitchKey:%s, "+
+		"NodeName:%s, "+
+		"Selector:%s"+
+		"}",
+		TimeDurationGoString(c.Bake),
+		BoolGoString(c.Enabled),
+		StringGoString(c.KillSwitchKey),
+		StringGoString(c.NodeName),
+		StringGoString(c.Selector),
+	)
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCanaryConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CanaryConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&CanaryConfig{},
+		},
+		{
+			"same_enabled",
+			&CanaryConfig{
+				Bake:          TimeDuration(5 * time.Minute),
+				Enabled:       Bool(true),
+				KillSwitchKey: String("canary/abort"),
+				NodeName:      String("web-1"),
+				Selector:      String("web-canary-*"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestCanaryConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CanaryConfig
+		b    *CanaryConfig
+		r    *CanaryConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&CanaryConfig{},
+			&CanaryConfig{},
+		},
+		{
+			"nil_b",
+			&CanaryConfig{},
+			nil,
+			&CanaryConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&CanaryConfig{},
+			&CanaryConfig{},
+			&CanaryConfig{},
+		},
+		{
+			"bake_overrides",
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+			&CanaryConfig{Bake: TimeDuration(0)},
+			&CanaryConfig{Bake: TimeDuration(0)},
+		},
+		{
+			"bake_empty_one",
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+			&CanaryConfig{},
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"bake_empty_two",
+			&CanaryConfig{},
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"bake_same",
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+			&CanaryConfig{Bake: TimeDuration(5 * time.Minute)},
+		},
+		{
+			"enabled_overrides",
+			&CanaryConfig{Enabled: Bool(true)},
+			&CanaryConfig{Enabled: Bool(false)},
+			&CanaryConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&CanaryConfig{Enabled: Bool(true)},
+			&CanaryConfig{},
+			&CanaryConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&CanaryConfig{},
+			&CanaryConfig{Enabled: Bool(true)},
+			&CanaryConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&CanaryConfig{Enabled: Bool(true)},
+			&CanaryConfig{Enabled: Bool(true)},
+			&CanaryConfig{Enabled: Bool(true)},
+		},
+		{
+			"kill_switch_key_overrides",
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+			&CanaryConfig{KillSwitchKey: String("")},
+			&CanaryConfig{KillSwitchKey: String("")},
+		},
+		{
+			"kill_switch_key_empty_one",
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+			&CanaryConfig{},
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+		},
+		{
+			"kill_switch_key_empty_two",
+			&CanaryConfig{},
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+		},
+		{
+			"kill_switch_key_same",
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+			&CanaryConfig{KillSwitchKey: String("canary/abort")},
+		},
+		{
+			"node_name_overrides",
+			&CanaryConfig{NodeName: String("web-1")},
+			&CanaryConfig{NodeName: String("")},
+			&CanaryConfig{NodeName: String("")},
+		},
+		{
+			"node_name_empty_one",
+			&CanaryConfig{NodeName: String("web-1")},
+			&CanaryConfig{},
+			&CanaryConfig{NodeName: String("web-1")},
+		},
+		{
+			"node_name_empty_two",
+			&CanaryConfig{},
+			&CanaryConfig{NodeName: String("web-1")},
+			&CanaryConfig{NodeName: String("web-1")},
+		},
+		{
+			"node_name_same",
+			&CanaryConfig{NodeName: String("web-1")},
+			&CanaryConfig{NodeName: String("web-1")},
+			&CanaryConfig{NodeName: String("web-1")},
+		},
+		{
+			"selector_overrides",
+			&CanaryConfig{Selector: String("web-canary-*")},
+			&CanaryConfig{Selector: String("")},
+			&CanaryConfig{Selector: String("")},
+		},
+		{
+			"selector_empty_one",
+			&CanaryConfig{Selector: String("web-canary-*")},
+			&CanaryConfig{},
+			&CanaryConfig{Selector: String("web-canary-*")},
+		},
+		{
+			"selector_empty_two",
+			&CanaryConfig{},
+			&CanaryConfig{Selector: String("web-canary-*")},
+			&CanaryConfig{Selector: String("web-canary-*")},
+		},
+		{
+			"selector_same",
+			&CanaryConfig{Selector: String("web-canary-*")},
+			&CanaryConfig{Selector: String("web-canary-*")},
+			&CanaryConfig{Selector: String("web-canary-*")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestCanaryConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *CanaryConfig
+		r    *CanaryConfig
+	}{
+		{
+			"empty",
+			&CanaryConfig{},
+			&CanaryConfig{
+				Bake:          TimeDuration(DefaultCanaryBake),
+				Enabled:       Bool(false),
+				KillSwitchKey: String(""),
+				NodeName:      String(""),
+				Selector:      String(""),
+			},
+		},
+		{
+			"with_selector",
+			&CanaryConfig{
+				Selector: String("web-canary-*"),
+			},
+			&CanaryConfig{
+				Bake:          TimeDuration(DefaultCanaryBake),
+				Enabled:       Bool(true),
+				KillSwitchKey: String(""),
+				NodeName:      String(""),
+				Selector:      String("web-canary-*"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// CompareModeBytes compares the rendered contents with the destination byte
+	// by byte.
+	CompareModeBytes = "bytes"
+
+	// CompareModeHash compares the digests of the rendered contents and of the
+	// destination.
+	CompareModeHash = "hash"
+
+	// CompareModeSemantic compares the rendered contents with the destination
+	// after normalizing both with a command, so cosmetic differences are not
+	// changes.
+	CompareModeSemantic = "semantic"
+
+	// CompareHashMD5, CompareHashSHA1, CompareHashSHA256 and CompareHashSHA512
+	// are the hashing algorithms of the hash mode.
+	CompareHashMD5    = "md5"
+	CompareHashSHA1   = "sha1"
+	CompareHashSHA256 = "sha256"
+	CompareHashSHA512 = "sha512"
+
+	// DefaultCompareHash is the default hashing algorithm of the hash mode.
+	DefaultCompareHash = CompareHashSHA256
+
+	// DefaultCompareTimeout is the default amount of time to wait for the
+	// normalization command to return.
+	DefaultCompareTimeout = 10 * time.Second
+)
+
+// CompareConfig is how the rendered contents of a template are compared with
+// its destination to decide if it changed. Only a changed template is written
+// and runs its command.
+type CompareConfig struct {
+	// Command is the normalization command of the semantic mode, such as
+	// "jq -S .". It reads the contents on stdin and writes their normalized form
+	// to stdout. If it fails, the contents are compared byte by byte.
+	Command *string `mapstructure:"command"`
+
+	// Hash is the hashing algorithm of the hash mode: "md5", "sha1", "sha256" or
+	// "sha512".
+	Hash *string `mapstructure:"hash"`
+
+	// Mode is "bytes", "hash" or "semantic". It defaults to "semantic" if
+	// Command is set, and to "bytes" otherwise.
+	Mode *string `mapstructure:"mode"`
+
+	// Timeout is the maximum amount of time to wait for the normalization
+	// command to return.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultCompareConfig returns a configuration that is populated with the
+// default values.
+func DefaultCompareConfig() *CompareConfig {
+	return &CompareConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *CompareConfig) Copy() *CompareConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o CompareConfig
+
+	o.Command = c.Command
+
+	o.Hash = c.Hash
+
+	o.Mode = c.Mode
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *CompareConfig) Merge(o *CompareConfig) *CompareConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Command != nil {
+		r.Command = o.Command
+	}
+
+	if o.Hash != nil {
+		r.Hash = o.Hash
+	}
+
+	if o.Mode != nil {
+		r.Mode = o.Mode
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *CompareConfig) Finalize() {
+	if c.Command == nil {
+		c.Command = String("")
+	}
+
+	if c.Hash == nil {
+		c.Hash = String(DefaultCompareHash)
+	}
+
+	if c.Mode == nil {
+		if StringPresent(c.Command) {
+			c.Mode = String(CompareModeSemantic)
+		} else {
+			c.Mode = String(CompareModeBytes)
+		}
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultCompareTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *CompareConfig) GoString() string {
+	if c == nil {
+		return "(*CompareConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&CompareConfig{"+
+		"Command:%s, "+
+		"Hash:%s, "+
+		"Mode:%s, "+
+		"Timeout:%s"+
+		"}",
+		StringGoString(c.Command),
+		StringGoString(c.Hash),
+		StringGoString(c.Mode),
+		TimeDurationGoString(c.Timeout),
+	)
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CompareConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&CompareConfig{},
+		},
+		{
+			"copy",
+			&CompareConfig{
+				Command: String("jq -S ."),
+				Hash:    String(CompareHashSHA1),
+				Mode:    String(CompareModeSemantic),
+				Timeout: TimeDuration(5 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestCompareConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CompareConfig
+		b    *CompareConfig
+		r    *CompareConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&CompareConfig{},
+			&CompareConfig{},
+		},
+		{
+			"nil_b",
+			&CompareConfig{},
+			nil,
+			&CompareConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&CompareConfig{},
+			&CompareConfig{},
+			&CompareConfig{},
+		},
+		{
+			"command_overrides",
+			&CompareConfig{Command: String("jq -S .")},
+			&CompareConfig{Command: String("")},
+			&CompareConfig{Command: String("")},
+		},
+		{
+			"command_empty_one",
+			&CompareConfig{Command: String("jq -S .")},
+			&CompareConfig{},
+			&CompareConfig{Command: String("jq -S .")},
+		},
+		{
+			"command_empty_two",
+			&CompareConfig{},
+			&CompareConfig{Command: String("jq -S .")},
+			&CompareConfig{Command: String("jq -S .")},
+		},
+		{
+			"command_same",
+			&CompareConfig{Command: String("jq -S .")},
+			&CompareConfig{Command: String("jq -S .")},
+			&CompareConfig{Command: String("jq -S .")},
+		},
+		{
+			"hash_overrides",
+			&CompareConfig{Hash: String(CompareHashMD5)},
+			&CompareConfig{Hash: String(CompareHashSHA512)},
+			&CompareConfig{Hash: String(CompareHashSHA512)},
+		},
+		{
+			"hash_empty_one",
+			&CompareConfig{Hash: String(CompareHashMD5)},
+			&CompareConfig{},
+			&CompareConfig{Hash: String(CompareHashMD5)},
+		},
+		{
+			"hash_empty_two",
+			&CompareConfig{},
+			&CompareConfig{Hash: String(CompareHashMD5)},
+			&CompareConfig{Hash: String(CompareHashMD5)},
+		},
+		{
+			"hash_same",
+			&CompareConfig{Hash: String(CompareHashMD5)},
+			&CompareConfig{Hash: String(CompareHashMD5)},
+			&CompareConfig{Hash: String(CompareHashMD5)},
+		},
+		{
+			"mode_overrides",
+			&CompareConfig{Mode: String(CompareModeHash)},
+			&CompareConfig{Mode: String(CompareModeBytes)},
+			&CompareConfig{Mode: String(CompareModeBytes)},
+		},
+		{
+			"mode_empty_one",
+			&CompareConfig{Mode: String(CompareModeHash)},
+			&CompareConfig{},
+			&CompareConfig{Mode: String(CompareModeHash)},
+		},
+		{
+			"mode_empty_two",
+			&CompareConfig{},
+			&CompareConfig{Mode: String(CompareModeHash)},
+			&CompareConfig{Mode: String(CompareModeHash)},
+		},
+		{
+			"mode_same",
+			&CompareConfig{Mode: String(CompareModeHash)},
+			&CompareConfig{Mode: String(CompareModeHash)},
+			&CompareConfig{Mode: String(CompareModeHash)},
+		},
+		{
+			"timeout_overrides",
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+			&CompareConfig{Timeout: TimeDuration(2 * time.Second)},
+			&CompareConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+		{
+			"timeout_empty_one",
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+			&CompareConfig{},
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&CompareConfig{},
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+			&CompareConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestCompareConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *CompareConfig
+		r    *CompareConfig
+	}{
+		{
+			"empty",
+			&CompareConfig{},
+			&CompareConfig{
+				Command: String(""),
+				Hash:    String(DefaultCompareHash),
+				Mode:    String(CompareModeBytes),
+				Timeout: TimeDuration(DefaultCompareTimeout),
+			},
+		},
+		{
+			"with_command",
+			&CompareConfig{
+				Command: String("jq -S ."),
+			},
+			&CompareConfig{
+				Command: String("jq -S ."),
+				Hash:    String(DefaultCompareHash),
+				Mode:    String(CompareModeSemantic),
+				Timeout: TimeDuration(DefaultCompareTimeout),
+			},
+		},
+		{
+			"with_mode",
+			&CompareConfig{
+				Command: String("jq -S ."),
+				Mode:    String(CompareModeHash),
+			},
+			&CompareConfig{
+				Command: String("jq -S ."),
+				Hash:    String(DefaultCompareHash),
+				Mode:    String(CompareModeHash),
+				Timeout: TimeDuration(DefaultCompareTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -26,6 +26,10 @@ const (
 	// queries by default for performance reasons.
 	DefaultMaxStale = 2 * time.Second
 
+	// DefaultOnceRetryTimeout is the default amount of time once mode retries
+	// failed dependencies before giving up.
+	DefaultOnceRetryTimeout = 1 * time.Minute
+
 	// DefaultReloadSignal is the default signal for reload.
 	DefaultReloadSignal = syscall.SIGHUP
 
@@ -38,37 +42,104 @@ const (
 
 // Config is used to configure Consul Template
 type Config struct {
+	// AgentCache is the configuration for reading from the local Consul agent's
+	// cache.
+	AgentCache *AgentCacheConfig `mapstructure:"agent_cache"`
+
 	// Auth is the HTTP basic authentication for communicating with Consul.
 	Auth *AuthConfig `mapstructure:"auth"`
 
+	// Blackout is the list of windows during which no template is written to
+	// disk.
+	Blackout *BlackoutConfigs `mapstructure:"blackout"`
+
+	// Bundles are the archives which package the rendered output of several
+	// templates.
+	Bundles *BundleConfigs `mapstructure:"bundle"`
+
+	// Canary is the configuration for the canary render mode, in which changed
+	// contents bake on the canary hosts before the other hosts write them.
+	Canary *CanaryConfig `mapstructure:"canary"`
+
 	// Consul is the location of the Consul instance to query (may be an IP
 	// address or FQDN) with port.
 	Consul *string `mapstructure:"consul"`
 
+	// Control is the configuration for the control interface.
+	Control *ControlConfig `mapstructure:"control"`
+
+	// Coordinate is the configuration for coordinated reloads.
+	Coordinate *CoordinateConfig `mapstructure:"coordinate"`
+
 	// Dedup is used to configure the dedup settings
 	Dedup *DedupConfig `mapstructure:"deduplicate"`
 
 	// Exec is the configuration for exec/supervise mode.
 	Exec *ExecConfig `mapstructure:"exec"`
 
+	// ExecCapture is the configuration of the execCapture template function.
+	ExecCapture *ExecCaptureConfig `mapstructure:"exec_capture"`
+
 	// KillSignal is the signal to listen for a graceful terminate event.
 	KillSignal *os.Signal `mapstructure:"kill_signal"`
 
+	// LocalCache is the configuration for reading Consul data through the cache
+	// server of the host.
+	LocalCache *LocalCacheConfig `mapstructure:"local_cache"`
+
 	// LogLevel is the level with which to log for this config.
 	LogLevel *string `mapstructure:"log_level"`
 
+	// MaxConcurrentCommands is the maximum number of template commands which
+	// run at the same time. Commands wait for a free slot in the order they
+	// would have run. The default value is 0, which does not limit them.
+	MaxConcurrentCommands *int `mapstructure:"max_concurrent_commands"`
+
 	// MaxStale is the maximum amount of time for staleness from Consul as given
 	// by LastContact. If supplied, Consul Template will query all servers instead
 	// of just the leader.
 	MaxStale *time.Duration `mapstructure:"max_stale"`
 
+	// Nomad is the configuration for connecting to a Nomad cluster.
+	Nomad *NomadConfig `mapstructure:"nomad"`
+
+	// Observe renders the templates indefinitely without writing them or
+	// running any commands, and logs what would change and why instead.
+	Observe *bool `mapstructure:"observe"`
+
+	// OnceBarrier is the configuration for the barrier which instances in once
+	// mode wait at before running their commands and exiting.
+	OnceBarrier *OnceBarrierConfig `mapstructure:"once_barrier"`
+
+	// OnceRetryTimeout is the maximum amount of time once mode retries failed
+	// dependencies, starting with the first failure, before giving up. A value
+	// of 0 gives up on the first failure.
+	OnceRetryTimeout *time.Duration `mapstructure:"once_retry_timeout"`
+
 	// PidFile is the path on disk where a PID file should be written containing
 	// this processes PID.
 	PidFile *string `mapstructure:"pid_file"`
 
+	// Profiles are the named overrides of this configuration, given as
+	// `profile "name" { ... }` blocks. The selected profile is merged on top of
+	// the rest of the configuration; see ApplyProfile.
+	Profiles map[string]*Config `mapstructure:"-"`
+
 	// ReloadSignal is the signal to listen for a reload event.
 	ReloadSignal *os.Signal `mapstructure:"reload_signal"`
 
+	// RemoteConfig is the configuration for loading template configuration from
+	// Consul's KV store.
+	RemoteConfig *RemoteConfigConfig `mapstructure:"remote_config"`
+
+	// Report is the configuration for the machine-readable report emitted after
+	// each run.
+	Report *ReportConfig `mapstructure:"report"`
+
+	// Resolve is the configuration for resolving the addresses returned by
+	// services and nodes to IP addresses.
+	Resolve *ResolveConfig `mapstructure:"resolve"`
+
 	// Retry is the duration of time to wait between Consul failures.
 	Retry *time.Duration `mapstructure:"retry"`
 
@@ -76,20 +147,62 @@ type Config struct {
 	// Consul. This requires Consul to be configured to serve HTTPS.
 	SSL *SSLConfig `mapstructure:"ssl"`
 
+	// Standby is the configuration for hot standby mode.
+	Standby *StandbyConfig `mapstructure:"standby"`
+
+	// Status is the configuration for the HTTP status server.
+	Status *StatusConfig `mapstructure:"status"`
+
+	// Streaming is the configuration for streaming service health from
+	// Consul's event stream.
+	Streaming *StreamingConfig `mapstructure:"streaming"`
+
 	// Syslog is the configuration for syslog.
 	Syslog *SyslogConfig `mapstructure:"syslog"`
 
+	// Systemd is the configuration for templates which render systemd units.
+	Systemd *SystemdConfig `mapstructure:"systemd"`
+
+	// Telemetry is the configuration for exporting the metrics of the runner.
+	Telemetry *TelemetryConfig `mapstructure:"telemetry"`
+
+	// TemplateFilter is a glob matched against the ID and the destination of
+	// each template. If set, only the matching templates are activated, so
+	// several runners can share one configuration file.
+	TemplateFilter *string `mapstructure:"template_filter"`
+
+	// TemplatePlugins are the external binaries which provide template
+	// functions over gRPC.
+	TemplatePlugins *TemplatePluginConfigs `mapstructure:"template_plugin"`
+
 	// Templates is the list of templates.
 	Templates *TemplateConfigs `mapstructure:"template"`
 
 	// Token is the Consul API token.
 	Token *string `mapstructure:"token"`
 
+	// TokenFile is the path to a file containing the Consul API token. The file
+	// is watched for changes and takes precedence over Token.
+	TokenFile *string `mapstructure:"token_file"`
+
+	// Vars are user-defined variables made available to templates through the
+	// var function.
+	Vars map[string]string `mapstructure:"vars"`
+
 	// Vault is the configuration for connecting to a vault server.
 	Vault *VaultConfig `mapstructure:"vault"`
 
+	// VaultAgentTokenFile is the path to a file containing the Vault token, as
+	// written by a Vault agent sink. The file is watched for changes and takes
+	// precedence over the Vault token.
+	VaultAgentTokenFile *string `mapstructure:"vault_agent_token_file"`
+
 	// Wait is the quiescence timers.
 	Wait *WaitConfig `mapstructure:"wait"`
+
+	// Watcher is the configuration for the buffer between the dependency
+	// watches and the runner.
+	Watcher *WatcherConfig `mapstructure:"watcher"`
 }
 
 // Copy returns a deep copy of the current configuration. This is useful because
@@ -97,12 +210,36 @@ type Config struct {
 func (c *Config) Copy() *Config {
 	var o Config
 
+	if c.AgentCache != nil {
+		o.AgentCache = c.AgentCache.Copy()
+	}
+
 	if c.Auth != nil {
 		o.Auth = c.Auth.Copy()
 	}
 
+	if c.Blackout != nil {
+		o.Blackout = c.Blackout.Copy()
+	}
+
+	if c.Bundles != nil {
+		o.Bundles = c.Bundles.Copy()
+	}
+
+	if c.Canary != nil {
+		o.Canary = c.Canary.Copy()
+	}
+
 	o.Consul = c.Consul
 
+	if c.Control != nil {
+		o.Control = c.Control.Copy()
+	}
+
+	if c.Coordinate != nil {
+		o.Coordinate = c.Coordinate.Copy()
+	}
+
 	if c.Dedup != nil {
 		o.Dedup = c.Dedup.Copy()
 	}
@@ -111,40 +248,122 @@ func (c *Config) Copy() *Config {
 		o.Exec = c.Exec.Copy()
 	}
 
+	if c.ExecCapture != nil {
+		o.ExecCapture = c.ExecCapture.Copy()
+	}
+
 	o.KillSignal = c.KillSignal
 
+	if c.LocalCache != nil {
+		o.LocalCache = c.LocalCache.Copy()
+	}
+
 	o.LogLevel = c.LogLevel
 
+	o.MaxConcurrentCommands = c.MaxConcurrentCommands
+
 	o.MaxStale = c.MaxStale
 
+	if c.Nomad != nil {
+		o.Nomad = c.Nomad.Copy()
+	}
+
+	o.Observe = c.Observe
+
+	if c.OnceBarrier != nil {
+		o.OnceBarrier = c.OnceBarrier.Copy()
+	}
+
+	o.OnceRetryTimeout = c.OnceRetryTimeout
+
 	o.PidFile = c.PidFile
 
+	if c.Profiles != nil {
+		o.Profiles = make(map[string]*Config, len(c.Profiles))
+		for k, v := range c.Profiles {
+			o.Profiles[k] = v.Copy()
+		}
+	}
+
 	o.ReloadSignal = c.ReloadSignal
 
+	if c.RemoteConfig != nil {
+		o.RemoteConfig = c.RemoteConfig.Copy()
+	}
+
+	if c.Report != nil {
+		o.Report = c.Report.Copy()
+	}
+
+	if c.Resolve != nil {
+		o.Resolve = c.Resolve.Copy()
+	}
+
 	o.Retry = c.Retry
 
 	if c.SSL != nil {
 		o.SSL = c.SSL.Copy()
 	}
 
+	if c.Standby != nil {
+		o.Standby = c.Standby.Copy()
+	}
+
+	if c.Status != nil {
+		o.Status = c.Status.Copy()
+	}
+
+	if c.Streaming != nil {
+		o.Streaming = c.Streaming.Copy()
+	}
+
 	if c.Syslog != nil {
 		o.Syslog = c.Syslog.Copy()
 	}
 
+	if c.Systemd != nil {
+		o.Systemd = c.Systemd.Copy()
+	}
+
+	if c.Telemetry != nil {
+		o.Telemetry = c.Telemetry.Copy()
+	}
+
+	o.TemplateFilter = c.TemplateFilter
+
+	if c.TemplatePlugins != nil {
+		o.TemplatePlugins = c.TemplatePlugins.Copy()
+	}
+
 	if c.Templates != nil {
 		o.Templates = c.Templates.Copy()
 	}
 
 	o.Token = c.Token
 
+	o.TokenFile = c.TokenFile
+
+	if c.Vars != nil {
+		o.Vars = make(map[string]string, len(c.Vars))
+		for k, v := range c.Vars {
+			o.Vars[k] = v
+		}
+	}
+
 	if c.Vault != nil {
 		o.Vault = c.Vault.Copy()
 	}
 
+	o.VaultAgentTokenFile = c.VaultAgentTokenFile
+
 	if c.Wait != nil {
 		o.Wait = c.Wait.Copy()
 	}
 
+	if c.Watcher != nil {
+		o.Watcher = c.Watcher.Copy()
+	}
+
 	return &o
 }
 
@@ -164,14 +383,38 @@ func (c *Config) Merge(o *Config) *Config {
 
 	r := c.Copy()
 
+	if o.AgentCache != nil {
+		r.AgentCache = r.AgentCache.Merge(o.AgentCache)
+	}
+
 	if o.Auth != nil {
 		r.Auth = r.Auth.Merge(o.Auth)
 	}
 
+	if o.Blackout != nil {
+		r.Blackout = r.Blackout.Merge(o.Blackout)
+	}
+
+	if o.Bundles != nil {
+		r.Bundles = r.Bundles.Merge(o.Bundles)
+	}
+
+	if o.Canary != nil {
+		r.Canary = r.Canary.Merge(o.Canary)
+	}
+
 	if o.Consul != nil {
 		r.Consul = o.Consul
 	}
 
+	if o.Control != nil {
+		r.Control = r.Control.Merge(o.Control)
+	}
+
+	if o.Coordinate != nil {
+		r.Coordinate = r.Coordinate.Merge(o.Coordinate)
+	}
+
 	if o.Dedup != nil {
 		r.Dedup = r.Dedup.Merge(o.Dedup)
 	}
@@ -180,26 +423,75 @@ func (c *Config) Merge(o *Config) *Config {
 		r.Exec = r.Exec.Merge(o.Exec)
 	}
 
+	if o.ExecCapture != nil {
+		r.ExecCapture = r.ExecCapture.Merge(o.ExecCapture)
+	}
+
 	if o.KillSignal != nil {
 		r.KillSignal = o.KillSignal
 	}
 
+	if o.LocalCache != nil {
+		r.LocalCache = r.LocalCache.Merge(o.LocalCache)
+	}
+
 	if o.LogLevel != nil {
 		r.LogLevel = o.LogLevel
 	}
 
+	if o.MaxConcurrentCommands != nil {
+		r.MaxConcurrentCommands = o.MaxConcurrentCommands
+	}
+
 	if o.MaxStale != nil {
 		r.MaxStale = o.MaxStale
 	}
 
+	if o.Nomad != nil {
+		r.Nomad = r.Nomad.Merge(o.Nomad)
+	}
+
+	if o.Observe != nil {
+		r.Observe = o.Observe
+	}
+
+	if o.OnceBarrier != nil {
+		r.OnceBarrier = r.OnceBarrier.Merge(o.OnceBarrier)
+	}
+
+	if o.OnceRetryTimeout != nil {
+		r.OnceRetryTimeout = o.OnceRetryTimeout
+	}
+
 	if o.PidFile != nil {
 		r.PidFile = o.PidFile
 	}
 
+	if o.Profiles != nil {
+		if r.Profiles == nil {
+			r.Profiles = make(map[string]*Config, len(o.Profiles))
+		}
+		for k, v := range o.Profiles {
+			r.Profiles[k] = r.Profiles[k].Merge(v)
+		}
+	}
+
 	if o.ReloadSignal != nil {
 		r.ReloadSignal = o.ReloadSignal
 	}
 
+	if o.RemoteConfig != nil {
+		r.RemoteConfig = r.RemoteConfig.Merge(o.RemoteConfig)
+	}
+
+	if o.Report != nil {
+		r.Report = r.Report.Merge(o.Report)
+	}
+
+	if o.Resolve != nil {
+		r.Resolve = r.Resolve.Merge(o.Resolve)
+	}
+
 	if o.Retry != nil {
 		r.Retry = o.Retry
 	}
@@ -208,10 +500,38 @@ func (c *Config) Merge(o *Config) *Config {
 		r.SSL = r.SSL.Merge(o.SSL)
 	}
 
+	if o.Standby != nil {
+		r.Standby = r.Standby.Merge(o.Standby)
+	}
+
+	if o.Status != nil {
+		r.Status = r.Status.Merge(o.Status)
+	}
+
+	if o.Streaming != nil {
+		r.Streaming = r.Streaming.Merge(o.Streaming)
+	}
+
 	if o.Syslog != nil {
 		r.Syslog = r.Syslog.Merge(o.Syslog)
 	}
 
+	if o.Systemd != nil {
+		r.Systemd = r.Systemd.Merge(o.Systemd)
+	}
+
+	if o.Telemetry != nil {
+		r.Telemetry = r.Telemetry.Merge(o.Telemetry)
+	}
+
+	if o.TemplateFilter != nil {
+		r.TemplateFilter = o.TemplateFilter
+	}
+
+	if o.TemplatePlugins != nil {
+		r.TemplatePlugins = r.TemplatePlugins.Merge(o.TemplatePlugins)
+	}
+
 	if o.Templates != nil {
 		r.Templates = r.Templates.Merge(o.Templates)
 	}
@@ -220,14 +540,35 @@ func (c *Config) Merge(o *Config) *Config {
 		r.Token = o.Token
 	}
 
+	if o.TokenFile != nil {
+		r.TokenFile = o.TokenFile
+	}
+
+	if o.Vars != nil {
+		if r.Vars == nil {
+			r.Vars = make(map[string]string, len(o.Vars))
+		}
+		for k, v := range o.Vars {
+			r.Vars[k] = v
+		}
+	}
+
 	if o.Vault != nil {
 		r.Vault = r.Vault.Merge(o.Vault)
 	}
 
+	if o.VaultAgentTokenFile != nil {
+		r.VaultAgentTokenFile = o.VaultAgentTokenFile
+	}
+
 	if o.Wait != nil {
 		r.Wait = r.Wait.Merge(o.Wait)
 	}
 
+	if o.Watcher != nil {
+		r.Watcher = r.Watcher.Merge(o.Watcher)
+	}
+
 	return r
 }
 
@@ -238,23 +579,68 @@ func Parse(s string) (*Config, error) {
 		return nil, errors.Wrap(err, "error decoding config")
 	}
 
-	// Convert to a map and flatten the keys we want to flatten
+	// Convert to a map
 	parsed, ok := shadow.(map[string]interface{})
 	if !ok {
 		return nil, errors.New("error converting config")
 	}
 
+	// The profiles are decoded on their own, since each holds a configuration
+	profiles, err := parseProfiles(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := decodeConfig(parsed)
+	if err != nil {
+		return nil, err
+	}
+	c.Profiles = profiles
+	return c, nil
+}
+
+// decodeConfig decodes the given parsed contents of a configuration file, or of
+// a profile, into a config.
+func decodeConfig(parsed map[string]interface{}) (*Config, error) {
+	// Give the labeled template plugin blocks their name
+	nameTemplatePlugins(parsed)
+
+	// Flatten the keys we want to flatten
 	flattenKeys(parsed, []string{
+		"agent_cache",
 		"auth",
+		"canary",
+		"control",
+		"coordinate",
 		"deduplicate",
 		"env",
 		"exec",
 		"exec.env",
+		"exec.escalation",
+		"exec.monitor",
+		"exec.reload_pacing",
+		"exec_capture",
+		"local_cache",
+		"nomad",
+		"nomad.ssl",
+		"once_barrier",
+		"remote_config",
+		"report",
+		"resolve",
 		"ssl",
+		"standby",
+		"status",
+		"status.live",
+		"status.ready",
+		"streaming",
 		"syslog",
+		"systemd",
+		"telemetry",
+		"vars",
 		"vault",
 		"vault.ssl",
 		"wait",
+		"watcher",
 	})
 
 	// FlattenFlatten keys belonging to the templates. We cannot do this above
@@ -262,11 +648,23 @@ func Parse(s string) (*Config, error) {
 	if templates, ok := parsed["template"].([]map[string]interface{}); ok {
 		for _, template := range templates {
 			flattenKeys(template, []string{
+				"compare",
 				"env",
 				"exec",
 				"exec.env",
+				"ready_check",
 				"wait",
 			})
+
+			// perms = "preserve" is kept apart from the permissions, and any other
+			// value of perms overrides it when configurations are merged.
+			if perms, ok := template["perms"]; ok {
+				preserve := perms == "preserve"
+				template["preserve_perms"] = preserve
+				if preserve {
+					delete(template, "perms")
+				}
+			}
 		}
 	}
 
@@ -401,39 +799,95 @@ func (c *Config) GoString() string {
 	}
 
 	return fmt.Sprintf("&Config{"+
+		"AgentCache:%#v, "+
 		"Auth:%#v, "+
+		"Blackout:%#v, "+
+		"Bundles:%#v, "+
+		"Canary:%#v, "+
 		"Consul:%s, "+
+		"Control:%#v, "+
+		"Coordinate:%#v, "+
 		"Dedup:%#v, "+
 		"Exec:%#v, "+
+		"ExecCapture:%#v, "+
 		"KillSignal:%s, "+
+		"LocalCache:%#v, "+
 		"LogLevel:%s, "+
+		"MaxConcurrentCommands:%s, "+
 		"MaxStale:%s, "+
+		"Nomad:%#v, "+
+		"Observe:%s, "+
+		"OnceBarrier:%#v, "+
+		"OnceRetryTimeout:%s, "+
 		"PidFile:%s, "+
+		"Profiles:%#v, "+
 		"ReloadSignal:%s, "+
+		"RemoteConfig:%#v, "+
+		"Report:%#v, "+
+		"Resolve:%#v, "+
 		"Retry:%s, "+
 		"SSL:%#v, "+
+		"Standby:%#v, "+
+		"Status:%#v, "+
+		"Streaming:%#v, "+
 		"Syslog:%#v, "+
+		"Systemd:%#v, "+
+		"Telemetry:%#v, "+
+		"TemplateFilter:%s, "+
+		"TemplatePlugins:%#v, "+
 		"Templates:%#v, "+
 		"Token:%s, "+
+		"TokenFile:%s, "+
+		"Vars:%#v, "+
 		"Vault:%#v, "+
-		"Wait:%#v"+
+		"VaultAgentTokenFile:%s, "+
+		"Wait:%#v, "+
+		"Watcher:%#v"+
 		"}",
+		c.AgentCache,
 		c.Auth,
+		c.Blackout,
+		c.Bundles,
+		c.Canary,
 		StringGoString(c.Consul),
+		c.Control,
+		c.Coordinate,
 		c.Dedup,
 		c.Exec,
+		c.ExecCapture,
 		SignalGoString(c.KillSignal),
+		c.LocalCache,
 		StringGoString(c.LogLevel),
+		IntGoString(c.MaxConcurrentCommands),
 		TimeDurationGoString(c.MaxStale),
+		c.Nomad,
+		BoolGoString(c.Observe),
+		c.OnceBarrier,
+		TimeDurationGoString(c.OnceRetryTimeout),
 		StringGoString(c.PidFile),
+		c.Profiles,
 		SignalGoString(c.ReloadSignal),
+		c.RemoteConfig,
+		c.Report,
+		c.Resolve,
 		TimeDurationGoString(c.Retry),
 		c.SSL,
+		c.Standby,
+		c.Status,
+		c.Streaming,
 		c.Syslog,
+		c.Systemd,
+		c.Telemetry,
+		StringGoString(c.TemplateFilter),
+		c.TemplatePlugins,
 		c.Templates,
 		StringGoString(c.Token),
+		StringGoString(c.TokenFile),
+		c.Vars,
 		c.Vault,
+		StringGoString(c.VaultAgentTokenFile),
 		c.Wait,
+		c.Watcher,
 	)
 }
 
@@ -441,22 +895,43 @@ func (c *Config) GoString() string {
 // variables may be set which control the values for the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
-		Auth:         DefaultAuthConfig(),
-		Consul:       stringFromEnv("CONSUL_HTTP_ADDR"),
-		Dedup:        DefaultDedupConfig(),
-		Exec:         DefaultExecConfig(),
-		KillSignal:   Signal(DefaultKillSignal),
-		LogLevel:     stringFromEnv("CT_LOG", "CONSUL_TEMPLATE_LOG"),
-		MaxStale:     TimeDuration(DefaultMaxStale),
-		PidFile:      String(""),
-		ReloadSignal: Signal(DefaultReloadSignal),
-		Retry:        TimeDuration(DefaultRetry),
-		SSL:          DefaultSSLConfig(),
-		Syslog:       DefaultSyslogConfig(),
-		Templates:    DefaultTemplateConfigs(),
-		Token:        stringFromEnv("CONSUL_TOKEN", "CONSUL_HTTP_TOKEN"),
-		Vault:        DefaultVaultConfig(),
-		Wait:         DefaultWaitConfig(),
+		AgentCache:       DefaultAgentCacheConfig(),
+		Auth:             DefaultAuthConfig(),
+		Blackout:         DefaultBlackoutConfigs(),
+		Bundles:          DefaultBundleConfigs(),
+		Canary:           DefaultCanaryConfig(),
+		Consul:           stringFromEnv("CONSUL_HTTP_ADDR"),
+		Control:          DefaultControlConfig(),
+		Coordinate:       DefaultCoordinateConfig(),
+		Dedup:            DefaultDedupConfig(),
+		Exec:             DefaultExecConfig(),
+		ExecCapture:      DefaultExecCaptureConfig(),
+		KillSignal:       Signal(DefaultKillSignal),
+		LocalCache:       DefaultLocalCacheConfig(),
+		LogLevel:         stringFromEnv("CT_LOG", "CONSUL_TEMPLATE_LOG"),
+		MaxStale:         TimeDuration(DefaultMaxStale),
+		Nomad:            DefaultNomadConfig(),
+		OnceBarrier:      DefaultOnceBarrierConfig(),
+		OnceRetryTimeout: TimeDuration(DefaultOnceRetryTimeout),
+		PidFile:          String(""),
+		ReloadSignal:     Signal(DefaultReloadSignal),
+		RemoteConfig:     DefaultRemoteConfigConfig(),
+		Report:           DefaultReportConfig(),
+		Resolve:          DefaultResolveConfig(),
+		Retry:            TimeDuration(DefaultRetry),
+		SSL:              DefaultSSLConfig(),
+		Standby:          DefaultStandbyConfig(),
+		Status:           DefaultStatusConfig(),
+		Streaming:        DefaultStreamingConfig(),
+		Syslog:           DefaultSyslogConfig(),
+		Systemd:          DefaultSystemdConfig(),
+		Telemetry:        DefaultTelemetryConfig(),
+		TemplatePlugins:  DefaultTemplatePluginConfigs(),
+		Templates:        DefaultTemplateConfigs(),
+		Token:            stringFromEnv("CONSUL_TOKEN", "CONSUL_HTTP_TOKEN"),
+		Vault:            DefaultVaultConfig(),
+		Wait:             DefaultWaitConfig(),
+		Watcher:          DefaultWatcherConfig(),
 	}
 }
 
@@ -466,15 +941,45 @@ func DefaultConfig() *Config {
 // data was given, but the user did not explicitly add "Enabled: true" to the
 // configuration.
 func (c *Config) Finalize() {
+	if c.AgentCache == nil {
+		c.AgentCache = DefaultAgentCacheConfig()
+	}
+	c.AgentCache.Finalize()
+
 	if c.Auth == nil {
 		c.Auth = DefaultAuthConfig()
 	}
 	c.Auth.Finalize()
 
+	if c.Blackout == nil {
+		c.Blackout = DefaultBlackoutConfigs()
+	}
+	c.Blackout.Finalize()
+
+	if c.Bundles == nil {
+		c.Bundles = DefaultBundleConfigs()
+	}
+	c.Bundles.Finalize()
+
+	if c.Canary == nil {
+		c.Canary = DefaultCanaryConfig()
+	}
+	c.Canary.Finalize()
+
 	if c.Consul == nil {
 		c.Consul = String("")
 	}
 
+	if c.Control == nil {
+		c.Control = DefaultControlConfig()
+	}
+	c.Control.Finalize()
+
+	if c.Coordinate == nil {
+		c.Coordinate = DefaultCoordinateConfig()
+	}
+	c.Coordinate.Finalize()
+
 	if c.Dedup == nil {
 		c.Dedup = DefaultDedupConfig()
 	}
@@ -485,18 +990,50 @@ func (c *Config) Finalize() {
 	}
 	c.Exec.Finalize()
 
+	if c.ExecCapture == nil {
+		c.ExecCapture = DefaultExecCaptureConfig()
+	}
+	c.ExecCapture.Finalize()
+
 	if c.KillSignal == nil {
 		c.KillSignal = Signal(DefaultKillSignal)
 	}
 
+	if c.LocalCache == nil {
+		c.LocalCache = DefaultLocalCacheConfig()
+	}
+	c.LocalCache.Finalize()
+
 	if c.LogLevel == nil {
 		c.LogLevel = String(DefaultLogLevel)
 	}
 
+	if c.MaxConcurrentCommands == nil {
+		c.MaxConcurrentCommands = Int(0)
+	}
+
 	if c.MaxStale == nil {
 		c.MaxStale = TimeDuration(DefaultMaxStale)
 	}
 
+	if c.Nomad == nil {
+		c.Nomad = DefaultNomadConfig()
+	}
+	c.Nomad.Finalize()
+
+	if c.Observe == nil {
+		c.Observe = Bool(false)
+	}
+
+	if c.OnceBarrier == nil {
+		c.OnceBarrier = DefaultOnceBarrierConfig()
+	}
+	c.OnceBarrier.Finalize()
+
+	if c.OnceRetryTimeout == nil {
+		c.OnceRetryTimeout = TimeDuration(DefaultOnceRetryTimeout)
+	}
+
 	if c.PidFile == nil {
 		c.PidFile = String("")
 	}
@@ -505,6 +1042,21 @@ func (c *Config) Finalize() {
 		c.ReloadSignal = Signal(DefaultReloadSignal)
 	}
 
+	if c.RemoteConfig == nil {
+		c.RemoteConfig = DefaultRemoteConfigConfig()
+	}
+	c.RemoteConfig.Finalize()
+
+	if c.Report == nil {
+		c.Report = DefaultReportConfig()
+	}
+	c.Report.Finalize()
+
+	if c.Resolve == nil {
+		c.Resolve = DefaultResolveConfig()
+	}
+	c.Resolve.Finalize()
+
 	if c.Retry == nil {
 		c.Retry = TimeDuration(DefaultRetry)
 	}
@@ -514,11 +1066,45 @@ func (c *Config) Finalize() {
 	}
 	c.SSL.Finalize()
 
+	if c.Standby == nil {
+		c.Standby = DefaultStandbyConfig()
+	}
+	c.Standby.Finalize()
+
+	if c.Status == nil {
+		c.Status = DefaultStatusConfig()
+	}
+	c.Status.Finalize()
+
+	if c.Streaming == nil {
+		c.Streaming = DefaultStreamingConfig()
+	}
+	c.Streaming.Finalize()
+
 	if c.Syslog == nil {
 		c.Syslog = DefaultSyslogConfig()
 	}
 	c.Syslog.Finalize()
 
+	if c.Systemd == nil {
+		c.Systemd = DefaultSystemdConfig()
+	}
+	c.Systemd.Finalize()
+
+	if c.Telemetry == nil {
+		c.Telemetry = DefaultTelemetryConfig()
+	}
+	c.Telemetry.Finalize()
+
+	if c.TemplateFilter == nil {
+		c.TemplateFilter = String("")
+	}
+
+	if c.TemplatePlugins == nil {
+		c.TemplatePlugins = DefaultTemplatePluginConfigs()
+	}
+	c.TemplatePlugins.Finalize()
+
 	if c.Templates == nil {
 		c.Templates = DefaultTemplateConfigs()
 	}
@@ -528,15 +1114,32 @@ func (c *Config) Finalize() {
 		c.Token = String("")
 	}
 
+	if c.TokenFile == nil {
+		c.TokenFile = String("")
+	}
+
+	if c.Vars == nil {
+		c.Vars = make(map[string]string)
+	}
+
 	if c.Vault == nil {
 		c.Vault = DefaultVaultConfig()
 	}
 	c.Vault.Finalize()
 
+	if c.VaultAgentTokenFile == nil {
+		c.VaultAgentTokenFile = String("")
+	}
+
 	if c.Wait == nil {
 		c.Wait = DefaultWaitConfig()
 	}
 	c.Wait.Finalize()
+
+	if c.Watcher == nil {
+		c.Watcher = DefaultWatcherConfig()
+	}
+	c.Watcher.Finalize()
 }
 
 func stringFromEnv(list ...string) *string {
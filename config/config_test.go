@@ -17,6 +17,50 @@ func TestParse(t *testing.T) {
 		e    *Config
 		err  bool
 	}{
+		{
+			"agent_cache",
+			`agent_cache {}`,
+			&Config{
+				AgentCache: &AgentCacheConfig{},
+			},
+			false,
+		},
+		{
+			"agent_cache_background_refresh",
+			`agent_cache {
+				background_refresh = false
+			}`,
+			&Config{
+				AgentCache: &AgentCacheConfig{
+					BackgroundRefresh: Bool(false),
+				},
+			},
+			false,
+		},
+		{
+			"agent_cache_max_age",
+			`agent_cache {
+				max_age = "30s"
+			}`,
+			&Config{
+				AgentCache: &AgentCacheConfig{
+					MaxAge: TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"agent_cache_use_cache",
+			`agent_cache {
+				use_cache = true
+			}`,
+			&Config{
+				AgentCache: &AgentCacheConfig{
+					UseCache: Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"auth",
 			`auth {
@@ -31,6 +75,60 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"blackout",
+			`blackout {
+				schedule = "0 18 * * 5"
+				duration = "62h"
+			}
+			blackout {
+				schedule = "0 0 24 12 *"
+				duration = "48h"
+			}`,
+			&Config{
+				Blackout: &BlackoutConfigs{
+					&BlackoutConfig{
+						Duration: TimeDuration(62 * time.Hour),
+						Schedule: String("0 18 * * 5"),
+					},
+					&BlackoutConfig{
+						Duration: TimeDuration(48 * time.Hour),
+						Schedule: String("0 0 24 12 *"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"bundle",
+			`bundle {
+				name        = "app"
+				destination = "/etc/app/bundle.tar.gz"
+				perms       = "0600"
+			}
+			template {
+				contents    = "{{ key \"foo\" }}"
+				bundle      = "app"
+				bundle_path = "conf.d/app.conf"
+			}`,
+			&Config{
+				Bundles: &BundleConfigs{
+					&BundleConfig{
+						Destination: String("/etc/app/bundle.tar.gz"),
+						Name:        String("app"),
+						Perms:       FileMode(0600),
+					},
+				},
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Bundle:     String("app"),
+						BundlePath: String("conf.d/app.conf"),
+						Contents:   String(`{{ key "foo" }}`),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"consul",
 			`consul = "1.2.3.4"`,
@@ -39,6 +137,88 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"control",
+			`control {
+				address = "127.0.0.1:8559"
+				enabled = true
+				path    = "/run/consul-template.sock"
+				token   = "abcd1234"
+			}`,
+			&Config{
+				Control: &ControlConfig{
+					Address: String("127.0.0.1:8559"),
+					Enabled: Bool(true),
+					Path:    String("/run/consul-template.sock"),
+					Token:   String("abcd1234"),
+				},
+			},
+			false,
+		},
+		{
+			"canary",
+			`canary {
+				bake            = "30m"
+				kill_switch_key = "canary/abort"
+				node_name       = "web-1"
+				selector        = "web-canary-*"
+			}`,
+			&Config{
+				Canary: &CanaryConfig{
+					Bake:          TimeDuration(30 * time.Minute),
+					KillSwitchKey: String("canary/abort"),
+					NodeName:      String("web-1"),
+					Selector:      String("web-canary-*"),
+				},
+			},
+			false,
+		},
+		{
+			"coordinate",
+			`coordinate {
+				enabled      = true
+				max_parallel = "10%"
+				prefix       = "foo/"
+				timeout      = "1m"
+				ttl          = "30s"
+			}`,
+			&Config{
+				Coordinate: &CoordinateConfig{
+					Enabled:     Bool(true),
+					MaxParallel: String("10%"),
+					Prefix:      String("foo/"),
+					Timeout:     TimeDuration(1 * time.Minute),
+					TTL:         TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"observe",
+			`observe = true`,
+			&Config{
+				Observe: Bool(true),
+			},
+			false,
+		},
+		{
+			"once_barrier",
+			`once_barrier {
+				enabled   = true
+				instances = 12
+				prefix    = "migrations/42/"
+				timeout   = "10m"
+			}`,
+			&Config{
+				OnceBarrier: &OnceBarrierConfig{
+					Enabled:   Bool(true),
+					Instances: Int(12),
+					Prefix:    String("migrations/42/"),
+					Timeout:   TimeDuration(10 * time.Minute),
+				},
+			},
+			false,
+		},
 		{
 			"deduplicate",
 			`deduplicate {
@@ -53,6 +233,44 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"deduplicate_batch",
+			`deduplicate {
+				batch          = true
+				flush_interval = "10s"
+			}`,
+			&Config{
+				Dedup: &DedupConfig{
+					Batch:         Bool(true),
+					FlushInterval: TimeDuration(10 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"deduplicate_lock_wait",
+			`deduplicate {
+				lock_wait = "5s"
+			}`,
+			&Config{
+				Dedup: &DedupConfig{
+					LockWait: TimeDuration(5 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"deduplicate_sharded",
+			`deduplicate {
+				sharded = true
+			}`,
+			&Config{
+				Dedup: &DedupConfig{
+					Sharded: Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"exec",
 			`exec {}`,
@@ -161,6 +379,28 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"exec_escalation",
+			`exec {
+				escalation {
+					check    = "curl -sf http://localhost:8080/health"
+					interval = "2s"
+					steps    = ["kill"]
+					timeout  = "10s"
+				}
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					Escalation: &ExecEscalationConfig{
+						Check:    String("curl -sf http://localhost:8080/health"),
+						Interval: TimeDuration(2 * time.Second),
+						Steps:    []string{"kill"},
+						Timeout:  TimeDuration(10 * time.Second),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"exec_kill_signal",
 			`exec {
@@ -185,6 +425,76 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"exec_listeners",
+			`exec {
+				listeners = ["tcp://0.0.0.0:8080", "unix:///run/app.sock"]
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					Listeners: []string{"tcp://0.0.0.0:8080", "unix:///run/app.sock"},
+				},
+			},
+			false,
+		},
+		{
+			"exec_monitor",
+			`exec {
+				monitor {
+					action          = "restart"
+					interval        = "5s"
+					max_cpu_percent = 80
+					max_open_files  = 1024
+					max_rss         = 536870912
+					signal          = "SIGUSR1"
+				}
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					Monitor: &ExecMonitorConfig{
+						Action:        String("restart"),
+						Interval:      TimeDuration(5 * time.Second),
+						MaxCPUPercent: Float64(80),
+						MaxOpenFiles:  Int(1024),
+						MaxRSS:        Uint64(536870912),
+						Signal:        Signal(syscall.SIGUSR1),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"exec_reload_pacing",
+			`exec {
+				reload_pacing {
+					max_cpu_percent = 80
+					max_delay       = "30s"
+					max_rss         = 536870912
+				}
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					ReloadPacing: &ExecReloadPacingConfig{
+						MaxCPUPercent: Float64(80),
+						MaxDelay:      TimeDuration(30 * time.Second),
+						MaxRSS:        Uint64(536870912),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"exec_prewarm_timeout",
+			`exec {
+				prewarm_timeout = "30s"
+			 }`,
+			&Config{
+				Exec: &ExecConfig{
+					PrewarmTimeout: TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
 		{
 			"exec_reload_signal",
 			`exec {
@@ -222,313 +532,1146 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"kill_signal",
-			`kill_signal = "SIGUSR1"`,
+			"exec_wait_for",
+			`exec {
+				wait_for_file    = "/run/app/ready"
+				wait_for_key     = "locks/deploy-ready"
+				wait_for_service = "db"
+			 }`,
 			&Config{
-				KillSignal: Signal(syscall.SIGUSR1),
+				Exec: &ExecConfig{
+					WaitForFile:    String("/run/app/ready"),
+					WaitForKey:     String("locks/deploy-ready"),
+					WaitForService: String("db"),
+				},
 			},
 			false,
 		},
 		{
-			"log_level",
-			`log_level = "WARN"`,
+			"exec_capture",
+			`exec_capture {
+				allowed   = ["hostname"]
+				cache_ttl = "1m"
+				timeout   = "2s"
+			}`,
 			&Config{
-				LogLevel: String("WARN"),
+				ExecCapture: &ExecCaptureConfig{
+					Allowed:  []string{"hostname"},
+					CacheTTL: TimeDuration(1 * time.Minute),
+					Timeout:  TimeDuration(2 * time.Second),
+				},
 			},
 			false,
 		},
 		{
-			"max_stale",
-			`max_stale = "10s"`,
+			"kill_signal",
+			`kill_signal = "SIGUSR1"`,
 			&Config{
-				MaxStale: TimeDuration(10 * time.Second),
+				KillSignal: Signal(syscall.SIGUSR1),
 			},
 			false,
 		},
 		{
-			"pid_file",
-			`pid_file = "/var/pid"`,
+			"local_cache",
+			`local_cache {
+				path = "/run/consul-template-cache.sock"
+			}`,
 			&Config{
-				PidFile: String("/var/pid"),
+				LocalCache: &LocalCacheConfig{
+					Path: String("/run/consul-template-cache.sock"),
+				},
 			},
 			false,
 		},
 		{
-			"reload_signal",
-			`reload_signal = "SIGUSR1"`,
+			"log_level",
+			`log_level = "WARN"`,
 			&Config{
-				ReloadSignal: Signal(syscall.SIGUSR1),
+				LogLevel: String("WARN"),
 			},
 			false,
 		},
 		{
-			"retry",
-			`retry = "10s"`,
+			"max_concurrent_commands",
+			`max_concurrent_commands = 2`,
 			&Config{
-				Retry: TimeDuration(10 * time.Second),
+				MaxConcurrentCommands: Int(2),
 			},
 			false,
 		},
 		{
-			"ssl",
-			`ssl {}`,
+			"max_stale",
+			`max_stale = "10s"`,
 			&Config{
-				SSL: &SSLConfig{},
+				MaxStale: TimeDuration(10 * time.Second),
 			},
 			false,
 		},
 		{
-			"ssl_enabled",
-			`ssl {
-				enabled = true
-			}`,
+			"nomad",
+			`nomad {}`,
 			&Config{
-				SSL: &SSLConfig{
-					Enabled: Bool(true),
-				},
+				Nomad: &NomadConfig{},
 			},
 			false,
 		},
 		{
-			"ssl_verify",
-			`ssl {
-				verify = true
+			"nomad_address",
+			`nomad {
+				address = "http://127.0.0.1:4646"
 			}`,
 			&Config{
-				SSL: &SSLConfig{
-					Verify: Bool(true),
+				Nomad: &NomadConfig{
+					Address: String("http://127.0.0.1:4646"),
 				},
 			},
 			false,
 		},
 		{
-			"ssl_cert",
-			`ssl {
-				cert = "cert"
+			"nomad_namespace",
+			`nomad {
+				namespace = "web"
 			}`,
 			&Config{
-				SSL: &SSLConfig{
-					Cert: String("cert"),
+				Nomad: &NomadConfig{
+					Namespace: String("web"),
 				},
 			},
 			false,
 		},
 		{
-			"ssl_key",
-			`ssl {
-				key = "key"
+			"nomad_region",
+			`nomad {
+				region = "eu"
 			}`,
 			&Config{
-				SSL: &SSLConfig{
-					Key: String("key"),
+				Nomad: &NomadConfig{
+					Region: String("eu"),
 				},
 			},
 			false,
 		},
 		{
-			"ssl_ca_cert",
-			`ssl {
-				ca_cert = "ca_cert"
+			"nomad_ssl",
+			`nomad {
+				ssl {
+					enabled = true
+					ca_cert = "ca.pem"
+				}
 			}`,
 			&Config{
-				SSL: &SSLConfig{
-					CaCert: String("ca_cert"),
+				Nomad: &NomadConfig{
+					SSL: &SSLConfig{
+						Enabled: Bool(true),
+						CaCert:  String("ca.pem"),
+					},
 				},
 			},
 			false,
 		},
 		{
-			"ssl_ca_path",
-			`ssl {
-				ca_path = "ca_path"
+			"nomad_token",
+			`nomad {
+				token = "token"
 			}`,
 			&Config{
-				SSL: &SSLConfig{
-					CaPath: String("ca_path"),
+				Nomad: &NomadConfig{
+					Token: String("token"),
 				},
 			},
 			false,
 		},
 		{
-			"ssl_server_name",
-			`ssl {
-				server_name = "server_name"
-			}`,
+			"once_retry_timeout",
+			`once_retry_timeout = "2m"`,
 			&Config{
-				SSL: &SSLConfig{
-					ServerName: String("server_name"),
-				},
+				OnceRetryTimeout: TimeDuration(2 * time.Minute),
 			},
 			false,
 		},
 		{
-			"syslog",
-			`syslog {}`,
+			"pid_file",
+			`pid_file = "/var/pid"`,
 			&Config{
-				Syslog: &SyslogConfig{},
+				PidFile: String("/var/pid"),
 			},
 			false,
 		},
 		{
-			"syslog_enabled",
-			`syslog {
+			"reload_signal",
+			`reload_signal = "SIGUSR1"`,
+			&Config{
+				ReloadSignal: Signal(syscall.SIGUSR1),
+			},
+			false,
+		},
+		{
+			"remote_config",
+			`remote_config {
+				prefix = "consul-template/configs/web"
+			}`,
+			&Config{
+				RemoteConfig: &RemoteConfigConfig{
+					Prefix: String("consul-template/configs/web"),
+				},
+			},
+			false,
+		},
+		{
+			"report",
+			`report {
+				path = "/var/run/consul-template/report.json"
+			}`,
+			&Config{
+				Report: &ReportConfig{
+					Path: String("/var/run/consul-template/report.json"),
+				},
+			},
+			false,
+		},
+		{
+			"resolve",
+			`resolve {
+				prefer_family = "v6"
+				timeout       = "2s"
+				ttl           = "10s"
+			}`,
+			&Config{
+				Resolve: &ResolveConfig{
+					PreferFamily: String("v6"),
+					Timeout:      TimeDuration(2 * time.Second),
+					TTL:          TimeDuration(10 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"retry",
+			`retry = "10s"`,
+			&Config{
+				Retry: TimeDuration(10 * time.Second),
+			},
+			false,
+		},
+		{
+			"ssl",
+			`ssl {}`,
+			&Config{
+				SSL: &SSLConfig{},
+			},
+			false,
+		},
+		{
+			"ssl_enabled",
+			`ssl {
+				enabled = true
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					Enabled: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"ssl_verify",
+			`ssl {
+				verify = true
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					Verify: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"ssl_cert",
+			`ssl {
+				cert = "cert"
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					Cert: String("cert"),
+				},
+			},
+			false,
+		},
+		{
+			"ssl_key",
+			`ssl {
+				key = "key"
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					Key: String("key"),
+				},
+			},
+			false,
+		},
+		{
+			"ssl_ca_cert",
+			`ssl {
+				ca_cert = "ca_cert"
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					CaCert: String("ca_cert"),
+				},
+			},
+			false,
+		},
+		{
+			"ssl_ca_path",
+			`ssl {
+				ca_path = "ca_path"
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					CaPath: String("ca_path"),
+				},
+			},
+			false,
+		},
+		{
+			"ssl_server_name",
+			`ssl {
+				server_name = "server_name"
+			}`,
+			&Config{
+				SSL: &SSLConfig{
+					ServerName: String("server_name"),
+				},
+			},
+			false,
+		},
+		{
+			"standby",
+			`standby {
+				enabled = true
+				key     = "service/web/active"
+				signal  = "SIGUSR1"
+			}`,
+			&Config{
+				Standby: &StandbyConfig{
+					Enabled: Bool(true),
+					Key:     String("service/web/active"),
+					Signal:  Signal(syscall.SIGUSR1),
+				},
+			},
+			false,
+		},
+		{
+			"status",
+			`status {}`,
+			&Config{
+				Status: &StatusConfig{},
+			},
+			false,
+		},
+		{
+			"status_address",
+			`status {
+				address = "0.0.0.0:8558"
+			}`,
+			&Config{
+				Status: &StatusConfig{
+					Address: String("0.0.0.0:8558"),
+				},
+			},
+			false,
+		},
+		{
+			"status_enabled",
+			`status {
+				enabled = true
+			}`,
+			&Config{
+				Status: &StatusConfig{
+					Enabled: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"status_debug",
+			`status {
+				expvar = true
+				pprof  = true
+			}`,
+			&Config{
+				Status: &StatusConfig{
+					Expvar: Bool(true),
+					Pprof:  Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"status_live",
+			`status {
+				live {
+					loop_timeout    = "30s"
+					watcher_timeout = "2m"
+				}
+			}`,
+			&Config{
+				Status: &StatusConfig{
+					Live: &StatusLiveConfig{
+						LoopTimeout:    TimeDuration(30 * time.Second),
+						WatcherTimeout: TimeDuration(2 * time.Minute),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"status_ready",
+			`status {
+				ready {
+					child     = false
+					commands  = false
+					templates = false
+				}
+			}`,
+			&Config{
+				Status: &StatusConfig{
+					Ready: &StatusReadyConfig{
+						Child:     Bool(false),
+						Commands:  Bool(false),
+						Templates: Bool(false),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"streaming",
+			`streaming {
+				address = "consul.service.consul:8300"
+				enabled = true
+			}`,
+			&Config{
+				Streaming: &StreamingConfig{
+					Address: String("consul.service.consul:8300"),
+					Enabled: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"syslog",
+			`syslog {}`,
+			&Config{
+				Syslog: &SyslogConfig{},
+			},
+			false,
+		},
+		{
+			"syslog_enabled",
+			`syslog {
 				enabled = true
 			}`,
 			&Config{
-				Syslog: &SyslogConfig{
-					Enabled: Bool(true),
+				Syslog: &SyslogConfig{
+					Enabled: Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"syslog_facility",
+			`syslog {
+				facility = "facility"
+			}`,
+			&Config{
+				Syslog: &SyslogConfig{
+					Facility: String("facility"),
+				},
+			},
+			false,
+		},
+		{
+			"systemd",
+			`systemd {
+				enabled               = true
+				daemon_reload_command = "systemctl --user daemon-reload"
+				unit_dirs             = ["/etc/systemd/user"]
+			}`,
+			&Config{
+				Systemd: &SystemdConfig{
+					DaemonReloadCommand: String("systemctl --user daemon-reload"),
+					Enabled:             Bool(true),
+					UnitDirs:            []string{"/etc/systemd/user"},
+				},
+			},
+			false,
+		},
+		{
+			"telemetry",
+			`telemetry {
+				prometheus_port = 9311
+			}`,
+			&Config{
+				Telemetry: &TelemetryConfig{
+					PrometheusPort: Int(9311),
+				},
+			},
+			false,
+		},
+		{
+			"template",
+			`template {}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{},
+				},
+			},
+			false,
+		},
+		{
+			"template_multi",
+			`template {}
+			template {}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{},
+					&TemplateConfig{},
+				},
+			},
+			false,
+		},
+		{
+			"template_acl",
+			`template {
+				acl = "D:P(A;;FA;;;SY)(A;;FR;;;BU)"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						ACL: String("D:P(A;;FA;;;SY)(A;;FR;;;BU)"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_add_header",
+			`template {
+				add_header = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						AddHeader: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_backup",
+			`template {
+				backup = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Backup: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_blackout",
+			`template {
+				blackout {
+					schedule = "0 9 * * 1-5"
+					duration = "1h"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Blackout: &BlackoutConfigs{
+							&BlackoutConfig{
+								Duration: TimeDuration(1 * time.Hour),
+								Schedule: String("0 9 * * 1-5"),
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_command",
+			`template {
+				command = "command"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Command: String("command"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_command_timeout",
+			`template {
+				command_timeout = "10s"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						CommandTimeout: TimeDuration(10 * time.Second),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_compare",
+			`template {
+				compare {
+					command = "jq -S ."
+					timeout = "5s"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Compare: &CompareConfig{
+							Command: String("jq -S ."),
+							Timeout: TimeDuration(5 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_contents",
+			`template {
+				contents = "contents"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Contents: String("contents"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_create_dest_dirs",
+			`template {
+				create_dest_dirs = false
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						CreateDestDirs: Bool(false),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_critical",
+			`template {
+				critical = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Critical: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_delete_on_destroy",
+			`template {
+				delete_on_destroy = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DeleteOnDestroy: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_dest_dir_group",
+			`template {
+				dest_dir_group = "group"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestDirGroup: String("group"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_dest_dir_perms",
+			`template {
+				dest_dir_perms = "0750"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestDirPerms: FileMode(0750),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_dest_dir_user",
+			`template {
+				dest_dir_user = "user"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestDirUser: String("user"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_destination",
+			`template {
+				destination = "destination"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Destination: String("destination"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_destroy_command",
+			`template {
+				destroy_command = "destroy"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestroyCommand: String("destroy"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_encoding",
+			`template {
+				encoding = "gzip"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Encoding: String("gzip"),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec",
+			`template {
+				exec {}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_command",
+			`template {
+				exec {
+					command = "command"
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Command: String("command"),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_enabled",
+			`template {
+				exec {
+					enabled = true
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Enabled: Bool(true),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_env",
+			`template {
+				exec {
+					env {}
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Env: &EnvConfig{},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_env_blacklist",
+			`template {
+				exec {
+					env {
+						blacklist = ["a", "b"]
+					}
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Env: &EnvConfig{
+								Blacklist: []string{"a", "b"},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_env_custom",
+			`template {
+				exec {
+					env {
+						custom = ["a=b", "c=d"]
+					}
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Env: &EnvConfig{
+								Custom: []string{"a=b", "c=d"},
+							},
+						},
+					},
 				},
 			},
 			false,
 		},
 		{
-			"syslog_facility",
-			`syslog {
-				facility = "facility"
-			}`,
+			"template_exec_env_pristine",
+			`template {
+				exec {
+					env {
+						pristine = true
+					}
+				}
+			 }`,
 			&Config{
-				Syslog: &SyslogConfig{
-					Facility: String("facility"),
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Env: &EnvConfig{
+								Pristine: Bool(true),
+							},
+						},
+					},
 				},
 			},
 			false,
 		},
 		{
-			"template",
-			`template {}`,
+			"template_exec_env_whitelist",
+			`template {
+				exec {
+					env {
+						whitelist = ["a", "b"]
+					}
+				}
+			 }`,
 			&Config{
 				Templates: &TemplateConfigs{
-					&TemplateConfig{},
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Env: &EnvConfig{
+								Whitelist: []string{"a", "b"},
+							},
+						},
+					},
 				},
 			},
 			false,
 		},
 		{
-			"template_multi",
-			`template {}
-			template {}`,
+			"template_exec_kill_signal",
+			`template {
+				exec {
+					kill_signal = "SIGUSR1"
+				}
+			 }`,
 			&Config{
 				Templates: &TemplateConfigs{
-					&TemplateConfig{},
-					&TemplateConfig{},
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							KillSignal: Signal(syscall.SIGUSR1),
+						},
+					},
 				},
 			},
 			false,
 		},
 		{
-			"template_backup",
+			"template_exec_kill_timeout",
 			`template {
-				backup = true
+				exec {
+					kill_timeout = "30s"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							KillTimeout: TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_reload_signal",
+			`template {
+				exec {
+					reload_signal = "SIGUSR1"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							ReloadSignal: Signal(syscall.SIGUSR1),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_splay",
+			`template {
+				exec {
+					splay = "30s"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Splay: TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_supervise",
+			`template {
+				exec {
+					supervise = true
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Supervise: Bool(true),
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_timeout",
+			`template {
+				exec {
+					timeout = "30s"
+				}
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Exec: &ExecConfig{
+							Timeout: TimeDuration(30 * time.Second),
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"template_header_template",
+			`template {
+				header_template = "// {{ .Name }}"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Backup: Bool(true),
+						HeaderTemplate: String("// {{ .Name }}"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_command",
+			"template_id",
 			`template {
-				command = "command"
+				id = "model"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Command: String("command"),
+						ID: String("model"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_command_timeout",
+			"template_input_template",
 			`template {
-				command_timeout = "10s"
+				input_template = "model"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						CommandTimeout: TimeDuration(10 * time.Second),
+						InputTemplate: String("model"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_contents",
+			"template_log_level",
 			`template {
-				contents = "contents"
+				log_level = "debug"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Contents: String("contents"),
+						LogLevel: String("debug"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_destination",
+			"template_log_tag",
 			`template {
-				destination = "destination"
+				log_tag = "web"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Destination: String("destination"),
+						LogTag: String("web"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec",
+			"template_max_output_size",
 			`template {
-				exec {}
+				max_output_size = 1048576
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{},
+						MaxOutputSize: Int(1048576),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_command",
+			"template_max_range_iterations",
 			`template {
-				exec {
-					command = "command"
-				}
+				max_range_iterations = 10000
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Command: String("command"),
-						},
+						MaxRangeIterations: Int(10000),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_enabled",
+			"template_min_instances",
 			`template {
-				exec {
-					enabled = true
+				min_instances {
+					service = "web"
+					count   = 2
 				}
-			 }`,
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Enabled: Bool(true),
+						MinInstances: &MinInstancesConfigs{
+							&MinInstancesConfig{
+								Count:   Int(2),
+								Service: String("web"),
+							},
 						},
 					},
 				},
@@ -536,105 +1679,78 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"template_exec_env",
+			"template_name",
 			`template {
-				exec {
-					env {}
-				}
-			 }`,
+				name = "nginx-upstreams"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Env: &EnvConfig{},
-						},
+						Name: String("nginx-upstreams"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_env_blacklist",
+			"template_perms",
 			`template {
-				exec {
-					env {
-						blacklist = ["a", "b"]
-					}
-				}
-			 }`,
+				perms = "0600"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Env: &EnvConfig{
-								Blacklist: []string{"a", "b"},
-							},
-						},
+						Perms:         FileMode(0600),
+						PreservePerms: Bool(false),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_env_custom",
+			"template_priority",
 			`template {
-				exec {
-					env {
-						custom = ["a=b", "c=d"]
-					}
-				}
-			 }`,
+				priority = "high"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Env: &EnvConfig{
-								Custom: []string{"a=b", "c=d"},
-							},
-						},
+						Priority: String(TemplatePriorityHigh),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_env_pristine",
+			"template_perms_preserve",
 			`template {
-				exec {
-					env {
-						pristine = true
-					}
-				}
-			 }`,
+				perms = "preserve"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Env: &EnvConfig{
-								Pristine: Bool(true),
-							},
-						},
+						PreservePerms: Bool(true),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_env_whitelist",
+			"template_ready_check",
 			`template {
-				exec {
-					env {
-						whitelist = ["a", "b"]
-					}
+				ready_check {
+					grpc         = "127.0.0.1:9000"
+					grpc_service = "app"
+					interval     = "2s"
 				}
-			 }`,
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Env: &EnvConfig{
-								Whitelist: []string{"a", "b"},
-							},
+						ReadyCheck: &ReadyCheckConfig{
+							GRPC:        String("127.0.0.1:9000"),
+							GRPCService: String("app"),
+							Interval:    TimeDuration(2 * time.Second),
 						},
 					},
 				},
@@ -642,17 +1758,37 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"template_exec_kill_signal",
+			"template_render_strategy",
+			`template {
+				render_strategy = "symlink"
+				render_versions = 10
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						RenderStrategy: String(TemplateRenderStrategySymlink),
+						RenderVersions: Int(10),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_secret_rotation",
 			`template {
-				exec {
-					kill_signal = "SIGUSR1"
+				secret_rotation {
+					path          = "database/creds/*"
+					rotate_within = "02:00-04:00"
 				}
-			 }`,
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							KillSignal: Signal(syscall.SIGUSR1),
+						SecretRotation: &SecretRotationConfigs{
+							&SecretRotationConfig{
+								Path:         String("database/creds/*"),
+								RotateWithin: String("02:00-04:00"),
+							},
 						},
 					},
 				},
@@ -660,101 +1796,84 @@ func TestParse(t *testing.T) {
 			false,
 		},
 		{
-			"template_exec_kill_timeout",
+			"template_seed_file",
 			`template {
-				exec {
-					kill_timeout = "30s"
-				}
-			 }`,
+				seed_file = "/var/lib/ct/seed"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							KillTimeout: TimeDuration(30 * time.Second),
-						},
+						SeedFile: String("/var/lib/ct/seed"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_reload_signal",
+			"template_serial",
 			`template {
-				exec {
-					reload_signal = "SIGUSR1"
-				}
-			 }`,
+				serial = true
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							ReloadSignal: Signal(syscall.SIGUSR1),
-						},
+						Serial: Bool(true),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_splay",
+			"template_source",
 			`template {
-				exec {
-					splay = "30s"
-				}
-			 }`,
+				source = "source"
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Splay: TimeDuration(30 * time.Second),
-						},
+						Source: String("source"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_exec_timeout",
+			"template_split_destination",
 			`template {
-				exec {
-					timeout = "30s"
-				}
-			 }`,
+				split_destination = true
+			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Exec: &ExecConfig{
-							Timeout: TimeDuration(30 * time.Second),
-						},
+						SplitDestination: Bool(true),
 					},
 				},
 			},
 			false,
 		},
-
 		{
-			"template_perms",
+			"template_sandbox_path",
 			`template {
-				perms = "0600"
+				sandbox_path = "/etc/templates"
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Perms: FileMode(0600),
+						SandboxPath: String("/etc/templates"),
 					},
 				},
 			},
 			false,
 		},
 		{
-			"template_source",
+			"template_strict",
 			`template {
-				source = "source"
+				strict = true
 			}`,
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Source: String("source"),
+						Strict: Bool(true),
 					},
 				},
 			},
@@ -825,6 +1944,40 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template_filter",
+			`template_filter = "nginx-*"`,
+			&Config{
+				TemplateFilter: String("nginx-*"),
+			},
+			false,
+		},
+		{
+			"template_plugin",
+			`template_plugin "mysql" {
+				path    = "/usr/local/bin/ct-plugin-mysql"
+				args    = ["-dsn", "/etc/mysql.dsn"]
+				timeout = "10s"
+			}
+			template_plugin "ldap" {
+				path = "/usr/local/bin/ct-plugin-ldap"
+			}`,
+			&Config{
+				TemplatePlugins: &TemplatePluginConfigs{
+					&TemplatePluginConfig{
+						Args:    []string{"-dsn", "/etc/mysql.dsn"},
+						Name:    String("mysql"),
+						Path:    String("/usr/local/bin/ct-plugin-mysql"),
+						Timeout: TimeDuration(10 * time.Second),
+					},
+					&TemplatePluginConfig{
+						Name: String("ldap"),
+						Path: String("/usr/local/bin/ct-plugin-ldap"),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"token",
 			`token = "token"`,
@@ -833,6 +1986,26 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"token_file",
+			`token_file = "/tmp/token"`,
+			&Config{
+				TokenFile: String("/tmp/token"),
+			},
+			false,
+		},
+		{
+			"vars",
+			`vars {
+				region = "us-east-1"
+			}`,
+			&Config{
+				Vars: map[string]string{
+					"region": "us-east-1",
+				},
+			},
+			false,
+		},
 		{
 			"vault",
 			`vault {}`,
@@ -865,6 +2038,66 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault_addresses",
+			`vault {
+				addresses = ["address1", "address2"]
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					Addresses: []string{"address1", "address2"},
+				},
+			},
+			false,
+		},
+		{
+			"vault_auth_method",
+			`vault {
+				auth_method = "cert"
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					AuthMethod: String("cert"),
+				},
+			},
+			false,
+		},
+		{
+			"vault_auth_mount",
+			`vault {
+				auth_mount = "certs"
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					AuthMount: String("certs"),
+				},
+			},
+			false,
+		},
+		{
+			"vault_auth_role",
+			`vault {
+				auth_role = "web"
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					AuthRole: String("web"),
+				},
+			},
+			false,
+		},
+		{
+			"vault_check_capabilities",
+			`vault {
+				check_capabilities = true
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					CheckCapabilities: Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"vault_token",
 			`vault {
@@ -901,6 +2134,42 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault_health_check_interval",
+			`vault {
+				health_check_interval = "30s"
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					HealthCheckInterval: TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
+		{
+			"vault_pki_renew_fraction",
+			`vault {
+				pki_renew_fraction = 0.75
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					PKIRenewFraction: Float64(0.75),
+				},
+			},
+			false,
+		},
+		{
+			"vault_performance_standby_ok",
+			`vault {
+				performance_standby_ok = true
+			}`,
+			&Config{
+				Vault: &VaultConfig{
+					PerformanceStandbyOK: Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"vault_renew_token",
 			`vault {
@@ -1037,6 +2306,14 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault_agent_token_file",
+			`vault_agent_token_file = "/tmp/vault-token"`,
+			&Config{
+				VaultAgentTokenFile: String("/tmp/vault-token"),
+			},
+			false,
+		},
 		{
 			"wait",
 			`wait {
@@ -1064,6 +2341,24 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"watcher",
+			`watcher {
+				backpressure           = "coalesce"
+				buffer_size            = 512
+				max_concurrent_fetches = 8
+				stale_grace_period     = "30s"
+			}`,
+			&Config{
+				Watcher: &WatcherConfig{
+					Backpressure:         String(WatcherBackpressureCoalesce),
+					BufferSize:           Int(512),
+					MaxConcurrentFetches: Int(8),
+					StaleGracePeriod:     TimeDuration(30 * time.Second),
+				},
+			},
+			false,
+		},
 
 		// Parse JSON file permissions as a string. There is a mapstructure
 		// function for testing this, but this is double-tested because it has
@@ -1078,7 +2373,8 @@ func TestParse(t *testing.T) {
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Perms: FileMode(0600),
+						Perms:         FileMode(0600),
+						PreservePerms: Bool(false),
 					},
 				},
 			},
@@ -1096,10 +2392,12 @@ func TestParse(t *testing.T) {
 			&Config{
 				Templates: &TemplateConfigs{
 					&TemplateConfig{
-						Perms: FileMode(0600),
+						Perms:         FileMode(0600),
+						PreservePerms: Bool(false),
 					},
 					&TemplateConfig{
-						Perms: FileMode(0600),
+						Perms:         FileMode(0600),
+						PreservePerms: Bool(false),
 					},
 				},
 			},
@@ -1173,6 +2471,24 @@ func TestConfig_Merge(t *testing.T) {
 			&Config{},
 			&Config{},
 		},
+		{
+			"agent_cache",
+			&Config{
+				AgentCache: &AgentCacheConfig{
+					UseCache: Bool(true),
+				},
+			},
+			&Config{
+				AgentCache: &AgentCacheConfig{
+					UseCache: Bool(false),
+				},
+			},
+			&Config{
+				AgentCache: &AgentCacheConfig{
+					UseCache: Bool(false),
+				},
+			},
+		},
 		{
 			"auth",
 			&Config{
@@ -1183,30 +2499,152 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 			&Config{
-				Auth: &AuthConfig{
-					Enabled:  Bool(true),
-					Username: String("username-diff"),
-					Password: String("password-diff"),
+				Auth: &AuthConfig{
+					Enabled:  Bool(true),
+					Username: String("username-diff"),
+					Password: String("password-diff"),
+				},
+			},
+			&Config{
+				Auth: &AuthConfig{
+					Enabled:  Bool(true),
+					Username: String("username-diff"),
+					Password: String("password-diff"),
+				},
+			},
+		},
+		{
+			"blackout",
+			&Config{
+				Blackout: &BlackoutConfigs{
+					&BlackoutConfig{Schedule: String("0 18 * * 5")},
+				},
+			},
+			&Config{
+				Blackout: &BlackoutConfigs{
+					&BlackoutConfig{Schedule: String("0 0 24 12 *")},
+				},
+			},
+			&Config{
+				Blackout: &BlackoutConfigs{
+					&BlackoutConfig{Schedule: String("0 18 * * 5")},
+					&BlackoutConfig{Schedule: String("0 0 24 12 *")},
+				},
+			},
+		},
+		{
+			"bundle",
+			&Config{
+				Bundles: &BundleConfigs{
+					&BundleConfig{Name: String("one")},
+				},
+			},
+			&Config{
+				Bundles: &BundleConfigs{
+					&BundleConfig{Name: String("two")},
+				},
+			},
+			&Config{
+				Bundles: &BundleConfigs{
+					&BundleConfig{Name: String("one")},
+					&BundleConfig{Name: String("two")},
+				},
+			},
+		},
+		{
+			"consul",
+			&Config{
+				Consul: String("consul"),
+			},
+			&Config{
+				Consul: String("consul-diff"),
+			},
+			&Config{
+				Consul: String("consul-diff"),
+			},
+		},
+		{
+			"control",
+			&Config{
+				Control: &ControlConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Control: &ControlConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Control: &ControlConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
+		{
+			"canary",
+			&Config{
+				Canary: &CanaryConfig{
+					Selector: String("web-canary-*"),
+				},
+			},
+			&Config{
+				Canary: &CanaryConfig{
+					Selector: String("db-canary-*"),
+				},
+			},
+			&Config{
+				Canary: &CanaryConfig{
+					Selector: String("db-canary-*"),
+				},
+			},
+		},
+		{
+			"coordinate",
+			&Config{
+				Coordinate: &CoordinateConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Coordinate: &CoordinateConfig{
+					Enabled: Bool(false),
 				},
 			},
 			&Config{
-				Auth: &AuthConfig{
-					Enabled:  Bool(true),
-					Username: String("username-diff"),
-					Password: String("password-diff"),
+				Coordinate: &CoordinateConfig{
+					Enabled: Bool(false),
 				},
 			},
 		},
 		{
-			"consul",
+			"observe",
 			&Config{
-				Consul: String("consul"),
+				Observe: Bool(true),
 			},
 			&Config{
-				Consul: String("consul-diff"),
+				Observe: Bool(false),
 			},
 			&Config{
-				Consul: String("consul-diff"),
+				Observe: Bool(false),
+			},
+		},
+		{
+			"once_barrier",
+			&Config{
+				OnceBarrier: &OnceBarrierConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				OnceBarrier: &OnceBarrierConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				OnceBarrier: &OnceBarrierConfig{
+					Enabled: Bool(false),
+				},
 			},
 		},
 		{
@@ -1245,6 +2683,24 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"exec_capture",
+			&Config{
+				ExecCapture: &ExecCaptureConfig{
+					Allowed: []string{"hostname"},
+				},
+			},
+			&Config{
+				ExecCapture: &ExecCaptureConfig{
+					Allowed: []string{"uname"},
+				},
+			},
+			&Config{
+				ExecCapture: &ExecCaptureConfig{
+					Allowed: []string{"hostname", "uname"},
+				},
+			},
+		},
 		{
 			"kill_signal",
 			&Config{
@@ -1257,6 +2713,24 @@ func TestConfig_Merge(t *testing.T) {
 				KillSignal: Signal(syscall.SIGUSR2),
 			},
 		},
+		{
+			"local_cache",
+			&Config{
+				LocalCache: &LocalCacheConfig{
+					Path: String("/run/a.sock"),
+				},
+			},
+			&Config{
+				LocalCache: &LocalCacheConfig{
+					Path: String("/run/b.sock"),
+				},
+			},
+			&Config{
+				LocalCache: &LocalCacheConfig{
+					Path: String("/run/b.sock"),
+				},
+			},
+		},
 		{
 			"log_level",
 			&Config{
@@ -1269,6 +2743,18 @@ func TestConfig_Merge(t *testing.T) {
 				LogLevel: String("log_level-diff"),
 			},
 		},
+		{
+			"max_concurrent_commands",
+			&Config{
+				MaxConcurrentCommands: Int(1),
+			},
+			&Config{
+				MaxConcurrentCommands: Int(2),
+			},
+			&Config{
+				MaxConcurrentCommands: Int(2),
+			},
+		},
 		{
 			"max_stale",
 			&Config{
@@ -1281,6 +2767,36 @@ func TestConfig_Merge(t *testing.T) {
 				MaxStale: TimeDuration(20 * time.Second),
 			},
 		},
+		{
+			"nomad",
+			&Config{
+				Nomad: &NomadConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Nomad: &NomadConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Nomad: &NomadConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
+		{
+			"once_retry_timeout",
+			&Config{
+				OnceRetryTimeout: TimeDuration(1 * time.Minute),
+			},
+			&Config{
+				OnceRetryTimeout: TimeDuration(2 * time.Minute),
+			},
+			&Config{
+				OnceRetryTimeout: TimeDuration(2 * time.Minute),
+			},
+		},
 		{
 			"pid_file",
 			&Config{
@@ -1305,6 +2821,60 @@ func TestConfig_Merge(t *testing.T) {
 				ReloadSignal: Signal(syscall.SIGUSR2),
 			},
 		},
+		{
+			"remote_config",
+			&Config{
+				RemoteConfig: &RemoteConfigConfig{
+					Prefix: String("foo/"),
+				},
+			},
+			&Config{
+				RemoteConfig: &RemoteConfigConfig{
+					Prefix: String("bar/"),
+				},
+			},
+			&Config{
+				RemoteConfig: &RemoteConfigConfig{
+					Prefix: String("bar/"),
+				},
+			},
+		},
+		{
+			"report",
+			&Config{
+				Report: &ReportConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Report: &ReportConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Report: &ReportConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
+		{
+			"resolve",
+			&Config{
+				Resolve: &ResolveConfig{
+					TTL: TimeDuration(10 * time.Second),
+				},
+			},
+			&Config{
+				Resolve: &ResolveConfig{
+					TTL: TimeDuration(20 * time.Second),
+				},
+			},
+			&Config{
+				Resolve: &ResolveConfig{
+					TTL: TimeDuration(20 * time.Second),
+				},
+			},
+		},
 		{
 			"retry",
 			&Config{
@@ -1335,6 +2905,60 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"standby",
+			&Config{
+				Standby: &StandbyConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Standby: &StandbyConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Standby: &StandbyConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
+		{
+			"status",
+			&Config{
+				Status: &StatusConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Status: &StatusConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Status: &StatusConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
+		{
+			"streaming",
+			&Config{
+				Streaming: &StreamingConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Streaming: &StreamingConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Streaming: &StreamingConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
 		{
 			"syslog",
 			&Config{
@@ -1353,6 +2977,42 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"systemd",
+			&Config{
+				Systemd: &SystemdConfig{
+					Enabled: Bool(true),
+				},
+			},
+			&Config{
+				Systemd: &SystemdConfig{
+					Enabled: Bool(false),
+				},
+			},
+			&Config{
+				Systemd: &SystemdConfig{
+					Enabled: Bool(false),
+				},
+			},
+		},
+		{
+			"telemetry",
+			&Config{
+				Telemetry: &TelemetryConfig{
+					PrometheusPort: Int(9311),
+				},
+			},
+			&Config{
+				Telemetry: &TelemetryConfig{
+					PrometheusPort: Int(9312),
+				},
+			},
+			&Config{
+				Telemetry: &TelemetryConfig{
+					PrometheusPort: Int(9312),
+				},
+			},
+		},
 		{
 			"template_configs",
 			&Config{
@@ -1380,6 +3040,37 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"template_filter",
+			&Config{
+				TemplateFilter: String("nginx-*"),
+			},
+			&Config{
+				TemplateFilter: String("app-*"),
+			},
+			&Config{
+				TemplateFilter: String("app-*"),
+			},
+		},
+		{
+			"template_plugin",
+			&Config{
+				TemplatePlugins: &TemplatePluginConfigs{
+					&TemplatePluginConfig{Name: String("mysql")},
+				},
+			},
+			&Config{
+				TemplatePlugins: &TemplatePluginConfigs{
+					&TemplatePluginConfig{Name: String("ldap")},
+				},
+			},
+			&Config{
+				TemplatePlugins: &TemplatePluginConfigs{
+					&TemplatePluginConfig{Name: String("mysql")},
+					&TemplatePluginConfig{Name: String("ldap")},
+				},
+			},
+		},
 		{
 			"token",
 			&Config{
@@ -1392,6 +3083,38 @@ func TestConfig_Merge(t *testing.T) {
 				Token: String("token-diff"),
 			},
 		},
+		{
+			"token_file",
+			&Config{
+				TokenFile: String("/tmp/token"),
+			},
+			&Config{
+				TokenFile: String("/tmp/token-diff"),
+			},
+			&Config{
+				TokenFile: String("/tmp/token-diff"),
+			},
+		},
+		{
+			"vars",
+			&Config{
+				Vars: map[string]string{
+					"region": "us-east-1",
+					"zone":   "a",
+				},
+			},
+			&Config{
+				Vars: map[string]string{
+					"zone": "b",
+				},
+			},
+			&Config{
+				Vars: map[string]string{
+					"region": "us-east-1",
+					"zone":   "b",
+				},
+			},
+		},
 		{
 			"vault",
 			&Config{
@@ -1410,6 +3133,18 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"vault_agent_token_file",
+			&Config{
+				VaultAgentTokenFile: String("/tmp/vault-token"),
+			},
+			&Config{
+				VaultAgentTokenFile: String("/tmp/vault-token-diff"),
+			},
+			&Config{
+				VaultAgentTokenFile: String("/tmp/vault-token-diff"),
+			},
+		},
 		{
 			"wait",
 			&Config{
@@ -1431,6 +3166,24 @@ func TestConfig_Merge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"watcher",
+			&Config{
+				Watcher: &WatcherConfig{
+					Backpressure: String(WatcherBackpressureBlock),
+				},
+			},
+			&Config{
+				Watcher: &WatcherConfig{
+					Backpressure: String(WatcherBackpressureDrop),
+				},
+			},
+			&Config{
+				Watcher: &WatcherConfig{
+					Backpressure: String(WatcherBackpressureDrop),
+				},
+			},
+		},
 	}
 
 	for i, tc := range cases {
@@ -1555,6 +3308,26 @@ func TestDefaultConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"NOMAD_ADDR",
+			"http://1.2.3.4:4646",
+			&Config{
+				Nomad: &NomadConfig{
+					Address: String("http://1.2.3.4:4646"),
+				},
+			},
+			false,
+		},
+		{
+			"NOMAD_TOKEN",
+			"abcd1234",
+			&Config{
+				Nomad: &NomadConfig{
+					Token: String("abcd1234"),
+				},
+			},
+			false,
+		},
 		{
 			"VAULT_ADDR",
 			"http://1.2.3.4:8200",
@@ -0,0 +1,118 @@
+package config
+
+import "fmt"
+
+// ControlConfig is the configuration for the control interface, which exposes
+// operations on the runner, such as reloading or pausing it, over a local unix
+// socket and optionally a TCP address.
+type ControlConfig struct {
+	// Address is the TCP address to listen on, such as "127.0.0.1:8559". A
+	// token is required to listen on a TCP address.
+	Address *string `mapstructure:"address"`
+
+	// Enabled controls if the control interface is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Path is the path of the unix socket to listen on. The socket is only
+	// accessible by the user consul-template runs as.
+	Path *string `mapstructure:"path"`
+
+	// Token is the token clients must present. It is optional on the unix
+	// socket, which is protected by its file permissions.
+	Token *string `mapstructure:"token" json:"-"`
+}
+
+// DefaultControlConfig returns a configuration that is populated with the
+// default values.
+func DefaultControlConfig() *ControlConfig {
+	return &ControlConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ControlConfig) Copy() *ControlConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ControlConfig
+	o.Address = c.Address
+	o.Enabled = c.Enabled
+	o.Path = c.Path
+	o.Token = c.Token
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ControlConfig) Merge(o *ControlConfig) *ControlConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Address != nil {
+		r.Address = o.Address
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	if o.Token != nil {
+		r.Token = o.Token
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ControlConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Path) || StringPresent(c.Address))
+	}
+
+	if c.Address == nil {
+		c.Address = String("")
+	}
+
+	if c.Path == nil {
+		c.Path = String("")
+	}
+
+	if c.Token == nil {
+		c.Token = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ControlConfig) GoString() string {
+	if c == nil {
+		return "(*ControlConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ControlConfig{"+
+		"Address:%s, "+
+		"Enabled:%s, "+
+		"Path:%s, "+
+		"Token:%s"+
+		"}",
+		StringGoString(c.Address),
+		BoolGoString(c.Enabled),
+		StringGoString(c.Path),
+		StringGoString(c.Token),
+	)
+}
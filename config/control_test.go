@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestControlConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ControlConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ControlConfig{},
+		},
+		{
+			"same_enabled",
+			&ControlConfig{
+				Address: String("127.0.0.1:8559"),
+				Enabled: Bool(true),
+				Path:    String("/run/consul-template.sock"),
+				Token:   String("abcd1234"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestControlConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ControlConfig
+		b    *ControlConfig
+		r    *ControlConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ControlConfig{},
+			&ControlConfig{},
+		},
+		{
+			"nil_b",
+			&ControlConfig{},
+			nil,
+			&ControlConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ControlConfig{},
+			&ControlConfig{},
+			&ControlConfig{},
+		},
+		{
+			"address_overrides",
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+			&ControlConfig{Address: String("")},
+			&ControlConfig{Address: String("")},
+		},
+		{
+			"address_empty_one",
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+			&ControlConfig{},
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+		},
+		{
+			"address_empty_two",
+			&ControlConfig{},
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+		},
+		{
+			"address_same",
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+			&ControlConfig{Address: String("127.0.0.1:8559")},
+		},
+		{
+			"enabled_overrides",
+			&ControlConfig{Enabled: Bool(true)},
+			&ControlConfig{Enabled: Bool(false)},
+			&ControlConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&ControlConfig{Enabled: Bool(true)},
+			&ControlConfig{},
+			&ControlConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&ControlConfig{},
+			&ControlConfig{Enabled: Bool(true)},
+			&ControlConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&ControlConfig{Enabled: Bool(true)},
+			&ControlConfig{Enabled: Bool(true)},
+			&ControlConfig{Enabled: Bool(true)},
+		},
+		{
+			"path_overrides",
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+			&ControlConfig{Path: String("")},
+			&ControlConfig{Path: String("")},
+		},
+		{
+			"path_empty_one",
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+			&ControlConfig{},
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+		},
+		{
+			"path_empty_two",
+			&ControlConfig{},
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+		},
+		{
+			"path_same",
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+			&ControlConfig{Path: String("/run/consul-template.sock")},
+		},
+		{
+			"token_overrides",
+			&ControlConfig{Token: String("abcd1234")},
+			&ControlConfig{Token: String("")},
+			&ControlConfig{Token: String("")},
+		},
+		{
+			"token_empty_one",
+			&ControlConfig{Token: String("abcd1234")},
+			&ControlConfig{},
+			&ControlConfig{Token: String("abcd1234")},
+		},
+		{
+			"token_empty_two",
+			&ControlConfig{},
+			&ControlConfig{Token: String("abcd1234")},
+			&ControlConfig{Token: String("abcd1234")},
+		},
+		{
+			"token_same",
+			&ControlConfig{Token: String("abcd1234")},
+			&ControlConfig{Token: String("abcd1234")},
+			&ControlConfig{Token: String("abcd1234")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestControlConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ControlConfig
+		r    *ControlConfig
+	}{
+		{
+			"empty",
+			&ControlConfig{},
+			&ControlConfig{
+				Address: String(""),
+				Enabled: Bool(false),
+				Path:    String(""),
+				Token:   String(""),
+			},
+		},
+		{
+			"with_path",
+			&ControlConfig{
+				Path: String("/run/consul-template.sock"),
+			},
+			&ControlConfig{
+				Address: String(""),
+				Enabled: Bool(true),
+				Path:    String("/run/consul-template.sock"),
+				Token:   String(""),
+			},
+		},
+		{
+			"with_address",
+			&ControlConfig{
+				Address: String("127.0.0.1:8559"),
+				Token:   String("abcd1234"),
+			},
+			&ControlConfig{
+				Address: String("127.0.0.1:8559"),
+				Enabled: Bool(true),
+				Path:    String(""),
+				Token:   String("abcd1234"),
+			},
+		},
+		{
+			"disabled",
+			&ControlConfig{
+				Enabled: Bool(false),
+				Path:    String("/run/consul-template.sock"),
+			},
+			&ControlConfig{
+				Address: String(""),
+				Enabled: Bool(false),
+				Path:    String("/run/consul-template.sock"),
+				Token:   String(""),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
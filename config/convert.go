@@ -56,6 +56,56 @@ func FileModePresent(o *os.FileMode) bool {
 	return *o != 0
 }
 
+func Float64(f float64) *float64 {
+	return &f
+}
+
+func Float64Val(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+func Float64GoString(f *float64) string {
+	if f == nil {
+		return "(*float64)(nil)"
+	}
+	return fmt.Sprintf("%g", *f)
+}
+
+func Float64Present(f *float64) bool {
+	if f == nil {
+		return false
+	}
+	return *f != 0
+}
+
+func Int(i int) *int {
+	return &i
+}
+
+func IntVal(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func IntGoString(i *int) string {
+	if i == nil {
+		return "(*int)(nil)"
+	}
+	return fmt.Sprintf("%d", *i)
+}
+
+func IntPresent(i *int) bool {
+	if i == nil {
+		return false
+	}
+	return *i != 0
+}
+
 func Signal(s os.Signal) *os.Signal {
 	return &s
 }
@@ -127,3 +177,28 @@ func TimeDurationPresent(t *time.Duration) bool {
 	}
 	return *t != 0
 }
+
+func Uint64(u uint64) *uint64 {
+	return &u
+}
+
+func Uint64Val(u *uint64) uint64 {
+	if u == nil {
+		return 0
+	}
+	return *u
+}
+
+func Uint64GoString(u *uint64) string {
+	if u == nil {
+		return "(*uint64)(nil)"
+	}
+	return fmt.Sprintf("%d", *u)
+}
+
+func Uint64Present(u *uint64) bool {
+	if u == nil {
+		return false
+	}
+	return *u != 0
+}
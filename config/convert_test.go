@@ -139,3 +139,87 @@ func TestTimeDurationVal(t *testing.T) {
 		})
 	}
 }
+
+func TestFloat64Val(t *testing.T) {
+	cases := []struct {
+		name string
+		f    *float64
+		e    float64
+	}{
+		{
+			"nil",
+			nil,
+			0,
+		},
+		{
+			"input",
+			Float64(12.5),
+			12.5,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			f := Float64Val(tc.f)
+			if f != tc.e {
+				t.Errorf("\nexp: %g\nact: %g", tc.e, f)
+			}
+		})
+	}
+}
+
+func TestIntVal(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *int
+		e    int
+	}{
+		{
+			"nil",
+			nil,
+			0,
+		},
+		{
+			"input",
+			Int(12),
+			12,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			v := IntVal(tc.i)
+			if v != tc.e {
+				t.Errorf("\nexp: %d\nact: %d", tc.e, v)
+			}
+		})
+	}
+}
+
+func TestUint64Val(t *testing.T) {
+	cases := []struct {
+		name string
+		u    *uint64
+		e    uint64
+	}{
+		{
+			"nil",
+			nil,
+			0,
+		},
+		{
+			"input",
+			Uint64(12),
+			12,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			u := Uint64Val(tc.u)
+			if u != tc.e {
+				t.Errorf("\nexp: %d\nact: %d", tc.e, u)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCoordinatePrefix is the default prefix used for coordinated
+	// reloads.
+	DefaultCoordinatePrefix = "consul-template/coordinate/"
+
+	// DefaultCoordinateMaxParallel is the default number of instances which may
+	// reload at the same time.
+	DefaultCoordinateMaxParallel = "1"
+
+	// DefaultCoordinateTTL is the default session TTL for coordinated reloads.
+	DefaultCoordinateTTL = 15 * time.Second
+
+	// DefaultCoordinateTimeout is the default maximum amount of time to wait for
+	// a turn to reload.
+	DefaultCoordinateTimeout = 5 * time.Minute
+)
+
+// CoordinateConfig is used to configure coordinated reloads. Instances sharing
+// the same prefix take turns running their commands and reloading their child
+// process after a render, so only a limited number of them reload at a time.
+type CoordinateConfig struct {
+	// Enabled controls if coordinated reloads are enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// MaxParallel is the maximum number of instances which may reload at the
+	// same time. It is either a number, such as "2", or a percentage of the
+	// registered instances, such as "10%". At least one instance may always
+	// reload.
+	MaxParallel *string `mapstructure:"max_parallel"`
+
+	// Prefix is the KV prefix under which instances register and queue.
+	Prefix *string `mapstructure:"prefix"`
+
+	// Timeout is the maximum amount of time to wait for a turn. If it expires,
+	// the instance reloads anyway. A value of 0 waits forever.
+	Timeout *time.Duration `mapstructure:"timeout"`
+
+	// TTL is the session TTL. Instances whose session expires are removed from
+	// the queue.
+	TTL *time.Duration `mapstructure:"ttl"`
+}
+
+// DefaultCoordinateConfig returns a configuration that is populated with the
+// default values.
+func DefaultCoordinateConfig() *CoordinateConfig {
+	return &CoordinateConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *CoordinateConfig) Copy() *CoordinateConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o CoordinateConfig
+	o.Enabled = c.Enabled
+	o.MaxParallel = c.MaxParallel
+	o.Prefix = c.Prefix
+	o.Timeout = c.Timeout
+	o.TTL = c.TTL
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *CoordinateConfig) Merge(o *CoordinateConfig) *CoordinateConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.MaxParallel != nil {
+		r.MaxParallel = o.MaxParallel
+	}
+
+	if o.Prefix != nil {
+		r.Prefix = o.Prefix
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	if o.TTL != nil {
+		r.TTL = o.TTL
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *CoordinateConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			StringPresent(c.MaxParallel) ||
+			StringPresent(c.Prefix) ||
+			TimeDurationPresent(c.Timeout) ||
+			TimeDurationPresent(c.TTL))
+	}
+
+	if c.MaxParallel == nil {
+		c.MaxParallel = String(DefaultCoordinateMaxParallel)
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = String(DefaultCoordinatePrefix)
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultCoordinateTimeout)
+	}
+
+	if c.TTL == nil {
+		c.TTL = TimeDuration(DefaultCoordinateTTL)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *CoordinateConfig) GoString() string {
+	if c == nil {
+		return "(*CoordinateConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&CoordinateConfig{"+
+		"Enabled:%s, "+
+		"MaxParallel:%s, "+
+		"Prefix:%s, "+
+		"Timeout:%s, "+
+		"TTL:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.MaxParallel),
+		StringGoString(c.Prefix),
+		TimeDurationGoString(c.Timeout),
+		TimeDurationGoString(c.TTL),
+	)
+}
+
+// ParseMaxParallel returns the number of instances which may reload at the
+// same time, given the number of registered instances. The result is always at
+// least 1.
+func ParseMaxParallel(s string, instances int) (int, error) {
+	var n int
+	if strings.HasSuffix(s, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || pct <= 0 || pct > 100 {
+			return 0, fmt.Errorf("invalid max_parallel percentage %q", s)
+		}
+		n = int(float64(instances) * pct / 100)
+	} else {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 1 {
+			return 0, fmt.Errorf("invalid max_parallel %q", s)
+		}
+		n = i
+	}
+
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoordinateConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CoordinateConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&CoordinateConfig{},
+		},
+		{
+			"same_enabled",
+			&CoordinateConfig{
+				Enabled:     Bool(true),
+				MaxParallel: String("10%"),
+				Prefix:      String("prefix/"),
+				Timeout:     TimeDuration(10 * time.Second),
+				TTL:         TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestCoordinateConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *CoordinateConfig
+		b    *CoordinateConfig
+		r    *CoordinateConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&CoordinateConfig{},
+			&CoordinateConfig{},
+		},
+		{
+			"nil_b",
+			&CoordinateConfig{},
+			nil,
+			&CoordinateConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&CoordinateConfig{},
+			&CoordinateConfig{},
+			&CoordinateConfig{},
+		},
+		{
+			"enabled_overrides",
+			&CoordinateConfig{Enabled: Bool(true)},
+			&CoordinateConfig{Enabled: Bool(false)},
+			&CoordinateConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&CoordinateConfig{Enabled: Bool(true)},
+			&CoordinateConfig{},
+			&CoordinateConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&CoordinateConfig{},
+			&CoordinateConfig{Enabled: Bool(true)},
+			&CoordinateConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&CoordinateConfig{Enabled: Bool(true)},
+			&CoordinateConfig{Enabled: Bool(true)},
+			&CoordinateConfig{Enabled: Bool(true)},
+		},
+		{
+			"max_parallel_overrides",
+			&CoordinateConfig{MaxParallel: String("10%")},
+			&CoordinateConfig{MaxParallel: String("2")},
+			&CoordinateConfig{MaxParallel: String("2")},
+		},
+		{
+			"max_parallel_empty_one",
+			&CoordinateConfig{MaxParallel: String("10%")},
+			&CoordinateConfig{},
+			&CoordinateConfig{MaxParallel: String("10%")},
+		},
+		{
+			"max_parallel_empty_two",
+			&CoordinateConfig{},
+			&CoordinateConfig{MaxParallel: String("10%")},
+			&CoordinateConfig{MaxParallel: String("10%")},
+		},
+		{
+			"max_parallel_same",
+			&CoordinateConfig{MaxParallel: String("10%")},
+			&CoordinateConfig{MaxParallel: String("10%")},
+			&CoordinateConfig{MaxParallel: String("10%")},
+		},
+		{
+			"prefix_overrides",
+			&CoordinateConfig{Prefix: String("prefix/")},
+			&CoordinateConfig{Prefix: String("")},
+			&CoordinateConfig{Prefix: String("")},
+		},
+		{
+			"prefix_empty_one",
+			&CoordinateConfig{Prefix: String("prefix/")},
+			&CoordinateConfig{},
+			&CoordinateConfig{Prefix: String("prefix/")},
+		},
+		{
+			"prefix_empty_two",
+			&CoordinateConfig{},
+			&CoordinateConfig{Prefix: String("prefix/")},
+			&CoordinateConfig{Prefix: String("prefix/")},
+		},
+		{
+			"prefix_same",
+			&CoordinateConfig{Prefix: String("prefix/")},
+			&CoordinateConfig{Prefix: String("prefix/")},
+			&CoordinateConfig{Prefix: String("prefix/")},
+		},
+		{
+			"timeout_overrides",
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{Timeout: TimeDuration(0)},
+			&CoordinateConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{},
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&CoordinateConfig{},
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"ttl_overrides",
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{TTL: TimeDuration(0)},
+			&CoordinateConfig{TTL: TimeDuration(0)},
+		},
+		{
+			"ttl_empty_one",
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{},
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+		{
+			"ttl_empty_two",
+			&CoordinateConfig{},
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+		{
+			"ttl_same",
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+			&CoordinateConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestCoordinateConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *CoordinateConfig
+		r    *CoordinateConfig
+	}{
+		{
+			"empty",
+			&CoordinateConfig{},
+			&CoordinateConfig{
+				Enabled:     Bool(false),
+				MaxParallel: String(DefaultCoordinateMaxParallel),
+				Prefix:      String(DefaultCoordinatePrefix),
+				Timeout:     TimeDuration(DefaultCoordinateTimeout),
+				TTL:         TimeDuration(DefaultCoordinateTTL),
+			},
+		},
+		{
+			"with_max_parallel",
+			&CoordinateConfig{
+				MaxParallel: String("10%"),
+			},
+			&CoordinateConfig{
+				Enabled:     Bool(true),
+				MaxParallel: String("10%"),
+				Prefix:      String(DefaultCoordinatePrefix),
+				Timeout:     TimeDuration(DefaultCoordinateTimeout),
+				TTL:         TimeDuration(DefaultCoordinateTTL),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
+
+func TestParseMaxParallel(t *testing.T) {
+	cases := []struct {
+		name      string
+		s         string
+		instances int
+		exp       int
+		err       bool
+	}{
+		{"number", "2", 10, 2, false},
+		{"percent", "10%", 50, 5, false},
+		{"percent_rounds_down", "10%", 19, 1, false},
+		{"percent_at_least_one", "10%", 3, 1, false},
+		{"percent_no_instances", "50%", 0, 1, false},
+		{"zero", "0", 10, 0, true},
+		{"invalid", "lots", 10, 0, true},
+		{"invalid_percent", "200%", 10, 0, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := ParseMaxParallel(tc.s, tc.instances)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
@@ -12,6 +12,14 @@ const (
 	// DefaultDedupTTL is the default TTL for deduplicate mode.
 	DefaultDedupTTL = 15 * time.Second
 
+	// DefaultDedupLockWait is the default amount of time a single lock
+	// acquisition attempt blocks in Consul before retrying.
+	DefaultDedupLockWait = 15 * time.Second
+
+	// DefaultDedupFlushInterval is the default interval at which batched
+	// dependency data is published in batch mode.
+	DefaultDedupFlushInterval = 5 * time.Second
+
 	// DefaultDedupMaxStale is the default max staleness for the deduplication
 	// manager.
 	DefaultDedupMaxStale = DefaultMaxStale
@@ -21,15 +29,33 @@ const (
 // on electing a leader per-template and watching of a key. This is used
 // to reduce the cost of many instances of CT running the same template.
 type DedupConfig struct {
+	// Batch publishes the dependency data of all the templates an instance
+	// leads in a few compressed writes every FlushInterval, instead of one
+	// write per template whenever it renders.
+	Batch *bool `mapstructure:"batch"`
+
 	// Controls if deduplication mode is enabled
 	Enabled *bool `mapstructure:"enabled"`
 
+	// FlushInterval is the interval at which dependency data is published in
+	// batch mode, defaults to 5 seconds.
+	FlushInterval *time.Duration `mapstructure:"flush_interval"`
+
+	// LockWait is the amount of time a single lock acquisition attempt blocks
+	// in Consul before retrying, defaults to 15 seconds.
+	LockWait *time.Duration `mapstructure:"lock_wait"`
+
 	// MaxStale is the maximum amount of time to allow for stale queries.
 	MaxStale *time.Duration `mapstructure:"max_stale"`
 
 	// Controls the KV prefix used. Defaults to defaultDedupPrefix
 	Prefix *string `mapstructure:"prefix"`
 
+	// Sharded spreads leadership of the templates across all instances
+	// sharing the prefix, instead of letting the first instance to start lead
+	// every template.
+	Sharded *bool `mapstructure:"sharded"`
+
 	// TTL is the Session TTL used for lock acquisition, defaults to 15 seconds.
 	TTL *time.Duration `mapstructure:"ttl"`
 }
@@ -47,9 +73,13 @@ func (c *DedupConfig) Copy() *DedupConfig {
 	}
 
 	var o DedupConfig
+	o.Batch = c.Batch
 	o.Enabled = c.Enabled
+	o.FlushInterval = c.FlushInterval
+	o.LockWait = c.LockWait
 	o.MaxStale = c.MaxStale
 	o.Prefix = c.Prefix
+	o.Sharded = c.Sharded
 	o.TTL = c.TTL
 	return &o
 }
@@ -72,10 +102,22 @@ func (c *DedupConfig) Merge(o *DedupConfig) *DedupConfig {
 
 	r := c.Copy()
 
+	if o.Batch != nil {
+		r.Batch = o.Batch
+	}
+
 	if o.Enabled != nil {
 		r.Enabled = o.Enabled
 	}
 
+	if o.FlushInterval != nil {
+		r.FlushInterval = o.FlushInterval
+	}
+
+	if o.LockWait != nil {
+		r.LockWait = o.LockWait
+	}
+
 	if o.MaxStale != nil {
 		r.MaxStale = o.MaxStale
 	}
@@ -84,6 +126,10 @@ func (c *DedupConfig) Merge(o *DedupConfig) *DedupConfig {
 		r.Prefix = o.Prefix
 	}
 
+	if o.Sharded != nil {
+		r.Sharded = o.Sharded
+	}
+
 	if o.TTL != nil {
 		r.TTL = o.TTL
 	}
@@ -95,11 +141,27 @@ func (c *DedupConfig) Merge(o *DedupConfig) *DedupConfig {
 func (c *DedupConfig) Finalize() {
 	if c.Enabled == nil {
 		c.Enabled = Bool(false ||
+			BoolPresent(c.Batch) ||
+			TimeDurationPresent(c.FlushInterval) ||
+			TimeDurationPresent(c.LockWait) ||
 			TimeDurationPresent(c.MaxStale) ||
 			StringPresent(c.Prefix) ||
+			BoolPresent(c.Sharded) ||
 			TimeDurationPresent(c.TTL))
 	}
 
+	if c.Batch == nil {
+		c.Batch = Bool(false)
+	}
+
+	if c.FlushInterval == nil {
+		c.FlushInterval = TimeDuration(DefaultDedupFlushInterval)
+	}
+
+	if c.LockWait == nil {
+		c.LockWait = TimeDuration(DefaultDedupLockWait)
+	}
+
 	if c.MaxStale == nil {
 		c.MaxStale = TimeDuration(DefaultDedupMaxStale)
 	}
@@ -108,6 +170,10 @@ func (c *DedupConfig) Finalize() {
 		c.Prefix = String(DefaultDedupPrefix)
 	}
 
+	if c.Sharded == nil {
+		c.Sharded = Bool(false)
+	}
+
 	if c.TTL == nil {
 		c.TTL = TimeDuration(DefaultDedupTTL)
 	}
@@ -119,14 +185,22 @@ func (c *DedupConfig) GoString() string {
 		return "(*DedupConfig)(nil)"
 	}
 	return fmt.Sprintf("&DedupConfig{"+
+		"Batch:%s, "+
 		"Enabled:%s, "+
+		"FlushInterval:%s, "+
+		"LockWait:%s, "+
 		"MaxStale:%s, "+
 		"Prefix:%s, "+
+		"Sharded:%s, "+
 		"TTL:%s"+
 		"}",
+		BoolGoString(c.Batch),
 		BoolGoString(c.Enabled),
+		TimeDurationGoString(c.FlushInterval),
+		TimeDurationGoString(c.LockWait),
 		TimeDurationGoString(c.MaxStale),
 		StringGoString(c.Prefix),
+		BoolGoString(c.Sharded),
 		TimeDurationGoString(c.TTL),
 	)
 }
@@ -23,10 +23,14 @@ func TestDedupConfig_Copy(t *testing.T) {
 		{
 			"copy",
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(30 * time.Second),
-				Prefix:   String("prefix"),
-				TTL:      TimeDuration(10 * time.Second),
+				Batch:         Bool(true),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(time.Second),
+				LockWait:      TimeDuration(5 * time.Second),
+				MaxStale:      TimeDuration(30 * time.Second),
+				Prefix:        String("prefix"),
+				Sharded:       Bool(true),
+				TTL:           TimeDuration(10 * time.Second),
 			},
 		},
 	}
@@ -96,6 +100,78 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{Enabled: Bool(true)},
 			&DedupConfig{Enabled: Bool(true)},
 		},
+		{
+			"batch_overrides",
+			&DedupConfig{Batch: Bool(true)},
+			&DedupConfig{Batch: Bool(false)},
+			&DedupConfig{Batch: Bool(false)},
+		},
+		{
+			"batch_empty_one",
+			&DedupConfig{Batch: Bool(true)},
+			&DedupConfig{},
+			&DedupConfig{Batch: Bool(true)},
+		},
+		{
+			"batch_empty_two",
+			&DedupConfig{},
+			&DedupConfig{Batch: Bool(true)},
+			&DedupConfig{Batch: Bool(true)},
+		},
+		{
+			"batch_same",
+			&DedupConfig{Batch: Bool(true)},
+			&DedupConfig{Batch: Bool(true)},
+			&DedupConfig{Batch: Bool(true)},
+		},
+		{
+			"flush_interval_overrides",
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+			&DedupConfig{FlushInterval: TimeDuration(10 * time.Second)},
+			&DedupConfig{FlushInterval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"flush_interval_empty_one",
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+			&DedupConfig{},
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+		},
+		{
+			"flush_interval_empty_two",
+			&DedupConfig{},
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+		},
+		{
+			"flush_interval_same",
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+			&DedupConfig{FlushInterval: TimeDuration(5 * time.Second)},
+		},
+		{
+			"lock_wait_overrides",
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+			&DedupConfig{LockWait: TimeDuration(20 * time.Second)},
+			&DedupConfig{LockWait: TimeDuration(20 * time.Second)},
+		},
+		{
+			"lock_wait_empty_one",
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+			&DedupConfig{},
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+		},
+		{
+			"lock_wait_empty_two",
+			&DedupConfig{},
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+		},
+		{
+			"lock_wait_same",
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+			&DedupConfig{LockWait: TimeDuration(10 * time.Second)},
+		},
 		{
 			"max_stale_overrides",
 			&DedupConfig{MaxStale: TimeDuration(10 * time.Second)},
@@ -144,6 +220,30 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{Prefix: String("prefix")},
 			&DedupConfig{Prefix: String("prefix")},
 		},
+		{
+			"sharded_overrides",
+			&DedupConfig{Sharded: Bool(true)},
+			&DedupConfig{Sharded: Bool(false)},
+			&DedupConfig{Sharded: Bool(false)},
+		},
+		{
+			"sharded_empty_one",
+			&DedupConfig{Sharded: Bool(true)},
+			&DedupConfig{},
+			&DedupConfig{Sharded: Bool(true)},
+		},
+		{
+			"sharded_empty_two",
+			&DedupConfig{},
+			&DedupConfig{Sharded: Bool(true)},
+			&DedupConfig{Sharded: Bool(true)},
+		},
+		{
+			"sharded_same",
+			&DedupConfig{Sharded: Bool(true)},
+			&DedupConfig{Sharded: Bool(true)},
+			&DedupConfig{Sharded: Bool(true)},
+		},
 		{
 			"ttl_overrides",
 			&DedupConfig{TTL: TimeDuration(10 * time.Second)},
@@ -190,10 +290,62 @@ func TestDedupConfig_Finalize(t *testing.T) {
 			"empty",
 			&DedupConfig{},
 			&DedupConfig{
-				Enabled:  Bool(false),
-				MaxStale: TimeDuration(DefaultDedupMaxStale),
-				Prefix:   String(DefaultDedupPrefix),
-				TTL:      TimeDuration(DefaultDedupTTL),
+				Batch:         Bool(false),
+				Enabled:       Bool(false),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(DefaultDedupTTL),
+			},
+		},
+		{
+			"with_batch",
+			&DedupConfig{
+				Batch: Bool(true),
+			},
+			&DedupConfig{
+				Batch:         Bool(true),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(DefaultDedupTTL),
+			},
+		},
+		{
+			"with_flush_interval",
+			&DedupConfig{
+				FlushInterval: TimeDuration(10 * time.Second),
+			},
+			&DedupConfig{
+				Batch:         Bool(false),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(10 * time.Second),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(DefaultDedupTTL),
+			},
+		},
+		{
+			"with_lock_wait",
+			&DedupConfig{
+				LockWait: TimeDuration(10 * time.Second),
+			},
+			&DedupConfig{
+				Batch:         Bool(false),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(10 * time.Second),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(DefaultDedupTTL),
 			},
 		},
 		{
@@ -202,10 +354,14 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				MaxStale: TimeDuration(10 * time.Second),
 			},
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(10 * time.Second),
-				Prefix:   String(DefaultDedupPrefix),
-				TTL:      TimeDuration(DefaultDedupTTL),
+				Batch:         Bool(false),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(10 * time.Second),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(DefaultDedupTTL),
 			},
 		},
 		{
@@ -214,10 +370,30 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				Prefix: String("prefix"),
 			},
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(DefaultDedupMaxStale),
-				Prefix:   String("prefix"),
-				TTL:      TimeDuration(DefaultDedupTTL),
+				Batch:         Bool(false),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String("prefix"),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(DefaultDedupTTL),
+			},
+		},
+		{
+			"with_sharded",
+			&DedupConfig{
+				Sharded: Bool(true),
+			},
+			&DedupConfig{
+				Batch:         Bool(false),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(true),
+				TTL:           TimeDuration(DefaultDedupTTL),
 			},
 		},
 		{
@@ -226,10 +402,14 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				TTL: TimeDuration(10 * time.Second),
 			},
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(DefaultDedupMaxStale),
-				Prefix:   String(DefaultDedupPrefix),
-				TTL:      TimeDuration(10 * time.Second),
+				Batch:         Bool(false),
+				Enabled:       Bool(true),
+				FlushInterval: TimeDuration(DefaultDedupFlushInterval),
+				LockWait:      TimeDuration(DefaultDedupLockWait),
+				MaxStale:      TimeDuration(DefaultDedupMaxStale),
+				Prefix:        String(DefaultDedupPrefix),
+				Sharded:       Bool(false),
+				TTL:           TimeDuration(10 * time.Second),
 			},
 		},
 	}
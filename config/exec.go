@@ -38,6 +38,10 @@ type ExecConfig struct {
 	// EnvConfig is the environmental customizations.
 	Env *EnvConfig `mapstructure:"env"`
 
+	// Escalation is the configuration for verifying the child process is ready
+	// after a reload, and escalating to a restart or kill if it is not.
+	Escalation *ExecEscalationConfig `mapstructure:"escalation"`
+
 	// KillSignal is the signal to send to the command to kill it gracefully. The
 	// default value is "SIGTERM".
 	KillSignal *os.Signal `mapstructure:"kill_signal"`
@@ -46,6 +50,28 @@ type ExecConfig struct {
 	// hard-killing it.
 	KillTimeout *time.Duration `mapstructure:"kill_timeout"`
 
+	// Listeners is the list of addresses, such as "tcp://0.0.0.0:8080" or
+	// "unix:///run/app.sock", to listen on and pass to the child process using
+	// systemd socket activation. The sockets stay open across child restarts
+	// and configuration reloads.
+	Listeners []string `mapstructure:"listeners"`
+
+	// Monitor is the configuration for sampling the resource usage of the
+	// child process.
+	Monitor *ExecMonitorConfig `mapstructure:"monitor"`
+
+	// PrewarmTimeout is the maximum amount of time to wait for all templates,
+	// including those which read Vault secrets, to render before the child
+	// process is started. The child process is never started before they have
+	// rendered; if they do not render in time, the runner exits with an error
+	// naming the templates and the data they are missing. The default value is
+	// 0, which waits forever.
+	PrewarmTimeout *time.Duration `mapstructure:"prewarm_timeout"`
+
+	// ReloadPacing is the configuration for delaying reloads of the child
+	// process while it is busy. It is ignored by template exec blocks.
+	ReloadPacing *ExecReloadPacingConfig `mapstructure:"reload_pacing"`
+
 	// ReloadSignal is the signal to send to the child process when a template
 	// changes. This tells the child process that templates have
 	ReloadSignal *os.Signal `mapstructure:"reload_signal"`
@@ -55,16 +81,37 @@ type ExecConfig struct {
 	// reduce the "thundering herd" problem where all tasks are restarted at once.
 	Splay *time.Duration `mapstructure:"splay"`
 
+	// Supervise runs Command of a template exec block as a long-running process
+	// instead of a one-shot command. The process is started once the template
+	// renders, sent ReloadSignal when it renders again and restarted when there
+	// is no reload signal. Timeout does not apply to it, and Consul Template
+	// exits when the process dies. The default value is false. It is ignored by
+	// the top-level exec block, which always supervises its command.
+	Supervise *bool `mapstructure:"supervise"`
+
 	// Timeout is the maximum amount of time to wait for a command to complete.
 	// By default, this is 0, which means "wait forever".
 	Timeout *time.Duration `mapstructure:"timeout"`
+
+	// WaitForFile, WaitForKey and WaitForService gate the start of the child
+	// process of the top-level exec block: even once all the templates have
+	// rendered, the child is only started when the file exists, the Consul key
+	// is "true" and the Consul service has a passing instance. Only the
+	// configured conditions are checked. They are ignored by template exec
+	// blocks.
+	WaitForFile    *string `mapstructure:"wait_for_file"`
+	WaitForKey     *string `mapstructure:"wait_for_key"`
+	WaitForService *string `mapstructure:"wait_for_service"`
 }
 
 // DefaultExecConfig returns a configuration that is populated with the
 // default values.
 func DefaultExecConfig() *ExecConfig {
 	return &ExecConfig{
-		Env: DefaultEnvConfig(),
+		Env:          DefaultEnvConfig(),
+		Escalation:   DefaultExecEscalationConfig(),
+		Monitor:      DefaultExecMonitorConfig(),
+		ReloadPacing: DefaultExecReloadPacingConfig(),
 	}
 }
 
@@ -84,16 +131,42 @@ func (c *ExecConfig) Copy() *ExecConfig {
 		o.Env = c.Env.Copy()
 	}
 
+	if c.Escalation != nil {
+		o.Escalation = c.Escalation.Copy()
+	}
+
 	o.KillSignal = c.KillSignal
 
 	o.KillTimeout = c.KillTimeout
 
+	if c.Listeners != nil {
+		o.Listeners = append([]string{}, c.Listeners...)
+	}
+
+	if c.Monitor != nil {
+		o.Monitor = c.Monitor.Copy()
+	}
+
+	o.PrewarmTimeout = c.PrewarmTimeout
+
+	if c.ReloadPacing != nil {
+		o.ReloadPacing = c.ReloadPacing.Copy()
+	}
+
 	o.ReloadSignal = c.ReloadSignal
 
 	o.Splay = c.Splay
 
+	o.Supervise = c.Supervise
+
 	o.Timeout = c.Timeout
 
+	o.WaitForFile = c.WaitForFile
+
+	o.WaitForKey = c.WaitForKey
+
+	o.WaitForService = c.WaitForService
+
 	return &o
 }
 
@@ -127,6 +200,10 @@ func (c *ExecConfig) Merge(o *ExecConfig) *ExecConfig {
 		r.Env = r.Env.Merge(o.Env)
 	}
 
+	if o.Escalation != nil {
+		r.Escalation = r.Escalation.Merge(o.Escalation)
+	}
+
 	if o.KillSignal != nil {
 		r.KillSignal = o.KillSignal
 	}
@@ -135,6 +212,22 @@ func (c *ExecConfig) Merge(o *ExecConfig) *ExecConfig {
 		r.KillTimeout = o.KillTimeout
 	}
 
+	if o.Listeners != nil {
+		r.Listeners = append(r.Listeners, o.Listeners...)
+	}
+
+	if o.Monitor != nil {
+		r.Monitor = r.Monitor.Merge(o.Monitor)
+	}
+
+	if o.PrewarmTimeout != nil {
+		r.PrewarmTimeout = o.PrewarmTimeout
+	}
+
+	if o.ReloadPacing != nil {
+		r.ReloadPacing = r.ReloadPacing.Merge(o.ReloadPacing)
+	}
+
 	if o.ReloadSignal != nil {
 		r.ReloadSignal = o.ReloadSignal
 	}
@@ -143,10 +236,26 @@ func (c *ExecConfig) Merge(o *ExecConfig) *ExecConfig {
 		r.Splay = o.Splay
 	}
 
+	if o.Supervise != nil {
+		r.Supervise = o.Supervise
+	}
+
 	if o.Timeout != nil {
 		r.Timeout = o.Timeout
 	}
 
+	if o.WaitForFile != nil {
+		r.WaitForFile = o.WaitForFile
+	}
+
+	if o.WaitForKey != nil {
+		r.WaitForKey = o.WaitForKey
+	}
+
+	if o.WaitForService != nil {
+		r.WaitForService = o.WaitForService
+	}
+
 	return r
 }
 
@@ -165,6 +274,11 @@ func (c *ExecConfig) Finalize() {
 	}
 	c.Env.Finalize()
 
+	if c.Escalation == nil {
+		c.Escalation = DefaultExecEscalationConfig()
+	}
+	c.Escalation.Finalize()
+
 	if c.KillSignal == nil {
 		c.KillSignal = Signal(DefaultExecKillSignal)
 	}
@@ -173,6 +287,24 @@ func (c *ExecConfig) Finalize() {
 		c.KillTimeout = TimeDuration(DefaultExecKillTimeout)
 	}
 
+	if c.Listeners == nil {
+		c.Listeners = []string{}
+	}
+
+	if c.Monitor == nil {
+		c.Monitor = DefaultExecMonitorConfig()
+	}
+	c.Monitor.Finalize()
+
+	if c.PrewarmTimeout == nil {
+		c.PrewarmTimeout = TimeDuration(0 * time.Second)
+	}
+
+	if c.ReloadPacing == nil {
+		c.ReloadPacing = DefaultExecReloadPacingConfig()
+	}
+	c.ReloadPacing.Finalize()
+
 	if c.ReloadSignal == nil {
 		c.ReloadSignal = Signal(DefaultExecReloadSignal)
 	}
@@ -181,9 +313,25 @@ func (c *ExecConfig) Finalize() {
 		c.Splay = TimeDuration(0 * time.Second)
 	}
 
+	if c.Supervise == nil {
+		c.Supervise = Bool(false)
+	}
+
 	if c.Timeout == nil {
 		c.Timeout = TimeDuration(DefaultExecTimeout)
 	}
+
+	if c.WaitForFile == nil {
+		c.WaitForFile = String("")
+	}
+
+	if c.WaitForKey == nil {
+		c.WaitForKey = String("")
+	}
+
+	if c.WaitForService == nil {
+		c.WaitForService = String("")
+	}
 }
 
 // GoString defines the printable version of this struct.
@@ -196,19 +344,37 @@ func (c *ExecConfig) GoString() string {
 		"Command:%s, "+
 		"Enabled:%s, "+
 		"Env:%#v, "+
+		"Escalation:%#v, "+
 		"KillSignal:%s, "+
 		"KillTimeout:%s, "+
+		"Listeners:%v, "+
+		"Monitor:%#v, "+
+		"PrewarmTimeout:%s, "+
+		"ReloadPacing:%#v, "+
 		"ReloadSignal:%s, "+
 		"Splay:%s, "+
-		"Timeout:%s"+
+		"Supervise:%s, "+
+		"Timeout:%s, "+
+		"WaitForFile:%s, "+
+		"WaitForKey:%s, "+
+		"WaitForService:%s"+
 		"}",
 		StringGoString(c.Command),
 		BoolGoString(c.Enabled),
 		c.Env,
+		c.Escalation,
 		SignalGoString(c.KillSignal),
 		TimeDurationGoString(c.KillTimeout),
+		c.Listeners,
+		c.Monitor,
+		TimeDurationGoString(c.PrewarmTimeout),
+		c.ReloadPacing,
 		SignalGoString(c.ReloadSignal),
 		TimeDurationGoString(c.Splay),
+		BoolGoString(c.Supervise),
 		TimeDurationGoString(c.Timeout),
+		StringGoString(c.WaitForFile),
+		StringGoString(c.WaitForKey),
+		StringGoString(c.WaitForService),
 	)
 }
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultExecCaptureTimeout is the default amount of time to wait for a
+	// command run by execCapture to return.
+	DefaultExecCaptureTimeout = 5 * time.Second
+)
+
+// ExecCaptureConfig is the configuration of the execCapture template function,
+// which runs a short command at render time and returns its output. It is
+// opt-in: only the commands in Allowed can be run.
+type ExecCaptureConfig struct {
+	// Allowed are the commands execCapture can run, such as "hostname" or
+	// "/usr/bin/uname". A command must be given to execCapture exactly as it is
+	// listed. The default value is empty, which allows no commands.
+	Allowed []string `mapstructure:"allowed"`
+
+	// CacheTTL is how long the output of a command is reused for the same
+	// arguments instead of running it again. The default value is 0, which
+	// runs the command on every render.
+	CacheTTL *time.Duration `mapstructure:"cache_ttl"`
+
+	// Timeout is the amount of time to wait for a command to return before it
+	// is killed and rendering fails.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultExecCaptureConfig returns a configuration that is populated with the
+// default values.
+func DefaultExecCaptureConfig() *ExecCaptureConfig {
+	return &ExecCaptureConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ExecCaptureConfig) Copy() *ExecCaptureConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ExecCaptureConfig
+	if c.Allowed != nil {
+		o.Allowed = append([]string{}, c.Allowed...)
+	}
+	o.CacheTTL = c.CacheTTL
+	o.Timeout = c.Timeout
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ExecCaptureConfig) Merge(o *ExecCaptureConfig) *ExecCaptureConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Allowed != nil {
+		r.Allowed = append(r.Allowed, o.Allowed...)
+	}
+
+	if o.CacheTTL != nil {
+		r.CacheTTL = o.CacheTTL
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ExecCaptureConfig) Finalize() {
+	if c.Allowed == nil {
+		c.Allowed = []string{}
+	}
+
+	if c.CacheTTL == nil {
+		c.CacheTTL = TimeDuration(0)
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultExecCaptureTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ExecCaptureConfig) GoString() string {
+	if c == nil {
+		return "(*ExecCaptureConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ExecCaptureConfig{"+
+		"Allowed:%v, "+
+		"CacheTTL:%s, "+
+		"Timeout:%s"+
+		"}",
+		c.Allowed,
+		TimeDurationGoString(c.CacheTTL),
+		TimeDurationGoString(c.Timeout),
+	)
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecCaptureConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecCaptureConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ExecCaptureConfig{},
+		},
+		{
+			"same_enabled",
+			&ExecCaptureConfig{
+				Allowed:  []string{"hostname"},
+				CacheTTL: TimeDuration(1 * time.Minute),
+				Timeout:  TimeDuration(2 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestExecCaptureConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecCaptureConfig
+		b    *ExecCaptureConfig
+		r    *ExecCaptureConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{},
+		},
+		{
+			"nil_b",
+			&ExecCaptureConfig{},
+			nil,
+			&ExecCaptureConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{},
+		},
+		{
+			"allowed_merges",
+			&ExecCaptureConfig{Allowed: []string{"hostname"}},
+			&ExecCaptureConfig{Allowed: []string{"uname"}},
+			&ExecCaptureConfig{Allowed: []string{"hostname", "uname"}},
+		},
+		{
+			"allowed_empty_one",
+			&ExecCaptureConfig{Allowed: []string{"hostname"}},
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{Allowed: []string{"hostname"}},
+		},
+		{
+			"allowed_empty_two",
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{Allowed: []string{"hostname"}},
+			&ExecCaptureConfig{Allowed: []string{"hostname"}},
+		},
+		{
+			"cache_ttl_overrides",
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(0)},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(0)},
+		},
+		{
+			"cache_ttl_empty_one",
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+		},
+		{
+			"cache_ttl_empty_two",
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+		},
+		{
+			"cache_ttl_same",
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+			&ExecCaptureConfig{CacheTTL: TimeDuration(1 * time.Minute)},
+		},
+		{
+			"timeout_overrides",
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ExecCaptureConfig{Timeout: TimeDuration(0)},
+			&ExecCaptureConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ExecCaptureConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestExecCaptureConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ExecCaptureConfig
+		r    *ExecCaptureConfig
+	}{
+		{
+			"empty",
+			&ExecCaptureConfig{},
+			&ExecCaptureConfig{
+				Allowed:  []string{},
+				CacheTTL: TimeDuration(0),
+				Timeout:  TimeDuration(DefaultExecCaptureTimeout),
+			},
+		},
+		{
+			"with_allowed",
+			&ExecCaptureConfig{
+				Allowed: []string{"hostname"},
+			},
+			&ExecCaptureConfig{
+				Allowed:  []string{"hostname"},
+				CacheTTL: TimeDuration(0),
+				Timeout:  TimeDuration(DefaultExecCaptureTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultExecEscalationInterval is the default amount of time between
+	// readiness checks of the child process.
+	DefaultExecEscalationInterval = 1 * time.Second
+
+	// DefaultExecEscalationTimeout is the default amount of time the child
+	// process has to become ready after each step before escalating.
+	DefaultExecEscalationTimeout = 30 * time.Second
+
+	// ExecEscalationStepRestart gracefully restarts the child process, using
+	// the kill signal and kill timeout.
+	ExecEscalationStepRestart = "restart"
+
+	// ExecEscalationStepKill force-kills the child process and starts a new
+	// one.
+	ExecEscalationStepKill = "kill"
+)
+
+// ExecEscalationConfig is used to configure the escalation chain of child
+// reloads. After the reload signal is sent, the readiness check is run until
+// it succeeds. If the child process does not become ready within the timeout,
+// the next step is taken, until the steps are exhausted.
+type ExecEscalationConfig struct {
+	// Check is the command which verifies the child process is ready. The
+	// child is ready when the command exits zero.
+	Check *string `mapstructure:"check"`
+
+	// Enabled controls if reloads are verified and escalated.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Interval is the amount of time between readiness checks. It is also the
+	// maximum amount of time a single check may take.
+	Interval *time.Duration `mapstructure:"interval"`
+
+	// Steps is the list of steps taken in order after the reload signal, while
+	// the child process is not ready. Valid steps are "restart" and "kill". The
+	// steps are replaced, not appended, when merged.
+	Steps []string `mapstructure:"steps"`
+
+	// Timeout is the amount of time the child process has to become ready after
+	// each step.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultExecEscalationConfig returns a configuration that is populated with
+// the default values.
+func DefaultExecEscalationConfig() *ExecEscalationConfig {
+	return &ExecEscalationConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ExecEscalationConfig) Copy() *ExecEscalationConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ExecEscalationConfig
+
+	o.Check = c.Check
+
+	o.Enabled = c.Enabled
+
+	o.Interval = c.Interval
+
+	if c.Steps != nil {
+		o.Steps = append([]string{}, c.Steps...)
+	}
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ExecEscalationConfig) Merge(o *ExecEscalationConfig) *ExecEscalationConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Check != nil {
+		r.Check = o.Check
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Interval != nil {
+		r.Interval = o.Interval
+	}
+
+	if o.Steps != nil {
+		r.Steps = append([]string{}, o.Steps...)
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ExecEscalationConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Check))
+	}
+
+	if c.Check == nil {
+		c.Check = String("")
+	}
+
+	if c.Interval == nil {
+		c.Interval = TimeDuration(DefaultExecEscalationInterval)
+	}
+
+	if c.Steps == nil {
+		c.Steps = []string{ExecEscalationStepRestart, ExecEscalationStepKill}
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultExecEscalationTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ExecEscalationConfig) GoString() string {
+	if c == nil {
+		return "(*ExecEscalationConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ExecEscalationConfig{"+
+		"Check:%s, "+
+		"Enabled:%s, "+
+		"Interval:%s, "+
+		"Steps:%v, "+
+		"Timeout:%s"+
+		"}",
+		StringGoString(c.Check),
+		BoolGoString(c.Enabled),
+		TimeDurationGoString(c.Interval),
+		c.Steps,
+		TimeDurationGoString(c.Timeout),
+	)
+}
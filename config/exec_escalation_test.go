@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecEscalationConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecEscalationConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ExecEscalationConfig{},
+		},
+		{
+			"copy",
+			&ExecEscalationConfig{
+				Check:    String("check"),
+				Enabled:  Bool(true),
+				Interval: TimeDuration(2 * time.Second),
+				Steps:    []string{"restart"},
+				Timeout:  TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestExecEscalationConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecEscalationConfig
+		b    *ExecEscalationConfig
+		r    *ExecEscalationConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{},
+		},
+		{
+			"nil_b",
+			&ExecEscalationConfig{},
+			nil,
+			&ExecEscalationConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{},
+		},
+		{
+			"check_overrides",
+			&ExecEscalationConfig{Check: String("check")},
+			&ExecEscalationConfig{Check: String("")},
+			&ExecEscalationConfig{Check: String("")},
+		},
+		{
+			"check_empty_one",
+			&ExecEscalationConfig{Check: String("check")},
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Check: String("check")},
+		},
+		{
+			"check_empty_two",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Check: String("check")},
+			&ExecEscalationConfig{Check: String("check")},
+		},
+		{
+			"check_same",
+			&ExecEscalationConfig{Check: String("check")},
+			&ExecEscalationConfig{Check: String("check")},
+			&ExecEscalationConfig{Check: String("check")},
+		},
+		{
+			"enabled_overrides",
+			&ExecEscalationConfig{Enabled: Bool(true)},
+			&ExecEscalationConfig{Enabled: Bool(false)},
+			&ExecEscalationConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&ExecEscalationConfig{Enabled: Bool(true)},
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Enabled: Bool(true)},
+			&ExecEscalationConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&ExecEscalationConfig{Enabled: Bool(true)},
+			&ExecEscalationConfig{Enabled: Bool(true)},
+			&ExecEscalationConfig{Enabled: Bool(true)},
+		},
+		{
+			"interval_overrides",
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+			&ExecEscalationConfig{Interval: TimeDuration(0)},
+			&ExecEscalationConfig{Interval: TimeDuration(0)},
+		},
+		{
+			"interval_empty_one",
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+		},
+		{
+			"interval_empty_two",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+		},
+		{
+			"interval_same",
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+			&ExecEscalationConfig{Interval: TimeDuration(2 * time.Second)},
+		},
+		{
+			"steps_overrides",
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+			&ExecEscalationConfig{Steps: []string{}},
+			&ExecEscalationConfig{Steps: []string{}},
+		},
+		{
+			"steps_empty_one",
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+		},
+		{
+			"steps_empty_two",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+		},
+		{
+			"steps_same",
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+			&ExecEscalationConfig{Steps: []string{"kill"}},
+		},
+		{
+			"timeout_overrides",
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&ExecEscalationConfig{Timeout: TimeDuration(0)},
+			&ExecEscalationConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+			&ExecEscalationConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestExecEscalationConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ExecEscalationConfig
+		r    *ExecEscalationConfig
+	}{
+		{
+			"empty",
+			&ExecEscalationConfig{},
+			&ExecEscalationConfig{
+				Check:    String(""),
+				Enabled:  Bool(false),
+				Interval: TimeDuration(DefaultExecEscalationInterval),
+				Steps:    []string{ExecEscalationStepRestart, ExecEscalationStepKill},
+				Timeout:  TimeDuration(DefaultExecEscalationTimeout),
+			},
+		},
+		{
+			"with_check",
+			&ExecEscalationConfig{
+				Check: String("check"),
+			},
+			&ExecEscalationConfig{
+				Check:    String("check"),
+				Enabled:  Bool(true),
+				Interval: TimeDuration(DefaultExecEscalationInterval),
+				Steps:    []string{ExecEscalationStepRestart, ExecEscalationStepKill},
+				Timeout:  TimeDuration(DefaultExecEscalationTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultExecMonitorInterval is the default amount of time between
+	// resource usage samples of the child process.
+	DefaultExecMonitorInterval = 10 * time.Second
+
+	// DefaultExecMonitorSignal is the default signal to send to the child
+	// process when a threshold is exceeded and the action is "signal".
+	DefaultExecMonitorSignal = syscall.SIGHUP
+
+	// ExecMonitorActionLog only logs a warning when a threshold is exceeded.
+	ExecMonitorActionLog = "log"
+
+	// ExecMonitorActionSignal sends the configured signal to the child process
+	// when a threshold is exceeded.
+	ExecMonitorActionSignal = "signal"
+
+	// ExecMonitorActionRestart restarts the child process when a threshold is
+	// exceeded.
+	ExecMonitorActionRestart = "restart"
+)
+
+// ExecMonitorConfig is used to configure the periodic sampling of the resource
+// usage of the child process and the optional thresholds applied to it.
+type ExecMonitorConfig struct {
+	// Action is the action to take when a threshold is exceeded. Valid values
+	// are "log", "signal", and "restart".
+	Action *string `mapstructure:"action"`
+
+	// Enabled controls if resource usage is sampled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Interval is the amount of time between samples.
+	Interval *time.Duration `mapstructure:"interval"`
+
+	// MaxCPUPercent is the maximum CPU usage, as a percentage of a single core,
+	// before the action is taken. A value of 0 disables the threshold.
+	MaxCPUPercent *float64 `mapstructure:"max_cpu_percent"`
+
+	// MaxOpenFiles is the maximum number of open file descriptors before the
+	// action is taken. A value of 0 disables the threshold.
+	MaxOpenFiles *int `mapstructure:"max_open_files"`
+
+	// MaxRSS is the maximum resident set size, in bytes, before the action is
+	// taken. A value of 0 disables the threshold.
+	MaxRSS *uint64 `mapstructure:"max_rss"`
+
+	// Signal is the signal to send when the action is "signal".
+	Signal *os.Signal `mapstructure:"signal"`
+}
+
+// DefaultExecMonitorConfig returns a configuration that is populated with the
+// default values.
+func DefaultExecMonitorConfig() *ExecMonitorConfig {
+	return &ExecMonitorConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ExecMonitorConfig) Copy() *ExecMonitorConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ExecMonitorConfig
+
+	o.Action = c.Action
+
+	o.Enabled = c.Enabled
+
+	o.Interval = c.Interval
+
+	o.MaxCPUPercent = c.MaxCPUPercent
+
+	o.MaxOpenFiles = c.MaxOpenFiles
+
+	o.MaxRSS = c.MaxRSS
+
+	o.Signal = c.Signal
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ExecMonitorConfig) Merge(o *ExecMonitorConfig) *ExecMonitorConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Action != nil {
+		r.Action = o.Action
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Interval != nil {
+		r.Interval = o.Interval
+	}
+
+	if o.MaxCPUPercent != nil {
+		r.MaxCPUPercent = o.MaxCPUPercent
+	}
+
+	if o.MaxOpenFiles != nil {
+		r.MaxOpenFiles = o.MaxOpenFiles
+	}
+
+	if o.MaxRSS != nil {
+		r.MaxRSS = o.MaxRSS
+	}
+
+	if o.Signal != nil {
+		r.Signal = o.Signal
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ExecMonitorConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			StringPresent(c.Action) ||
+			TimeDurationPresent(c.Interval) ||
+			Float64Present(c.MaxCPUPercent) ||
+			IntPresent(c.MaxOpenFiles) ||
+			Uint64Present(c.MaxRSS) ||
+			SignalPresent(c.Signal))
+	}
+
+	if c.Action == nil {
+		c.Action = String(ExecMonitorActionLog)
+	}
+
+	if c.Interval == nil {
+		c.Interval = TimeDuration(DefaultExecMonitorInterval)
+	}
+
+	if c.MaxCPUPercent == nil {
+		c.MaxCPUPercent = Float64(0)
+	}
+
+	if c.MaxOpenFiles == nil {
+		c.MaxOpenFiles = Int(0)
+	}
+
+	if c.MaxRSS == nil {
+		c.MaxRSS = Uint64(0)
+	}
+
+	if c.Signal == nil {
+		c.Signal = Signal(DefaultExecMonitorSignal)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ExecMonitorConfig) GoString() string {
+	if c == nil {
+		return "(*ExecMonitorConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ExecMonitorConfig{"+
+		"Action:%s, "+
+		"Enabled:%s, "+
+		"Interval:%s, "+
+		"MaxCPUPercent:%s, "+
+		"MaxOpenFiles:%s, "+
+		"MaxRSS:%s, "+
+		"Signal:%s"+
+		"}",
+		StringGoString(c.Action),
+		BoolGoString(c.Enabled),
+		TimeDurationGoString(c.Interval),
+		Float64GoString(c.MaxCPUPercent),
+		IntGoString(c.MaxOpenFiles),
+		Uint64GoString(c.MaxRSS),
+		SignalGoString(c.Signal),
+	)
+}
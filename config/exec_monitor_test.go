@@ -0,0 +1,303 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecMonitorConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecMonitorConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ExecMonitorConfig{},
+		},
+		{
+			"copy",
+			&ExecMonitorConfig{
+				Action:        String("signal"),
+				Enabled:       Bool(true),
+				Interval:      TimeDuration(10 * time.Second),
+				MaxCPUPercent: Float64(80),
+				MaxOpenFiles:  Int(1024),
+				MaxRSS:        Uint64(1024),
+				Signal:        Signal(syscall.SIGUSR1),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestExecMonitorConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecMonitorConfig
+		b    *ExecMonitorConfig
+		r    *ExecMonitorConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{},
+		},
+		{
+			"nil_b",
+			&ExecMonitorConfig{},
+			nil,
+			&ExecMonitorConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{},
+		},
+		{
+			"action_overrides",
+			&ExecMonitorConfig{Action: String("log")},
+			&ExecMonitorConfig{Action: String("restart")},
+			&ExecMonitorConfig{Action: String("restart")},
+		},
+		{
+			"action_empty_one",
+			&ExecMonitorConfig{Action: String("log")},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Action: String("log")},
+		},
+		{
+			"action_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Action: String("log")},
+			&ExecMonitorConfig{Action: String("log")},
+		},
+		{
+			"action_same",
+			&ExecMonitorConfig{Action: String("log")},
+			&ExecMonitorConfig{Action: String("log")},
+			&ExecMonitorConfig{Action: String("log")},
+		},
+		{
+			"enabled_overrides",
+			&ExecMonitorConfig{Enabled: Bool(true)},
+			&ExecMonitorConfig{Enabled: Bool(false)},
+			&ExecMonitorConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&ExecMonitorConfig{Enabled: Bool(true)},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Enabled: Bool(true)},
+			&ExecMonitorConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&ExecMonitorConfig{Enabled: Bool(true)},
+			&ExecMonitorConfig{Enabled: Bool(true)},
+			&ExecMonitorConfig{Enabled: Bool(true)},
+		},
+		{
+			"interval_overrides",
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+			&ExecMonitorConfig{Interval: TimeDuration(0 * time.Second)},
+			&ExecMonitorConfig{Interval: TimeDuration(0 * time.Second)},
+		},
+		{
+			"interval_empty_one",
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"interval_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"interval_same",
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+			&ExecMonitorConfig{Interval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"max_cpu_percent_overrides",
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(0)},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(0)},
+		},
+		{
+			"max_cpu_percent_empty_one",
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+		},
+		{
+			"max_cpu_percent_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+		},
+		{
+			"max_cpu_percent_same",
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+			&ExecMonitorConfig{MaxCPUPercent: Float64(80)},
+		},
+		{
+			"max_open_files_overrides",
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+			&ExecMonitorConfig{MaxOpenFiles: Int(0)},
+			&ExecMonitorConfig{MaxOpenFiles: Int(0)},
+		},
+		{
+			"max_open_files_empty_one",
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+		},
+		{
+			"max_open_files_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+		},
+		{
+			"max_open_files_same",
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+			&ExecMonitorConfig{MaxOpenFiles: Int(1024)},
+		},
+		{
+			"max_rss_overrides",
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+			&ExecMonitorConfig{MaxRSS: Uint64(0)},
+			&ExecMonitorConfig{MaxRSS: Uint64(0)},
+		},
+		{
+			"max_rss_empty_one",
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+		},
+		{
+			"max_rss_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+		},
+		{
+			"max_rss_same",
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+			&ExecMonitorConfig{MaxRSS: Uint64(1024)},
+		},
+		{
+			"signal_overrides",
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGINT)},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGINT)},
+		},
+		{
+			"signal_empty_one",
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+		},
+		{
+			"signal_empty_two",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+		},
+		{
+			"signal_same",
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+			&ExecMonitorConfig{Signal: Signal(syscall.SIGUSR1)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestExecMonitorConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ExecMonitorConfig
+		r    *ExecMonitorConfig
+	}{
+		{
+			"empty",
+			&ExecMonitorConfig{},
+			&ExecMonitorConfig{
+				Action:        String(ExecMonitorActionLog),
+				Enabled:       Bool(false),
+				Interval:      TimeDuration(DefaultExecMonitorInterval),
+				MaxCPUPercent: Float64(0),
+				MaxOpenFiles:  Int(0),
+				MaxRSS:        Uint64(0),
+				Signal:        Signal(DefaultExecMonitorSignal),
+			},
+		},
+		{
+			"with_threshold",
+			&ExecMonitorConfig{
+				MaxRSS: Uint64(1024),
+			},
+			&ExecMonitorConfig{
+				Action:        String(ExecMonitorActionLog),
+				Enabled:       Bool(true),
+				Interval:      TimeDuration(DefaultExecMonitorInterval),
+				MaxCPUPercent: Float64(0),
+				MaxOpenFiles:  Int(0),
+				MaxRSS:        Uint64(1024),
+				Signal:        Signal(DefaultExecMonitorSignal),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultExecReloadPacingMaxDelay is the default maximum amount of time a
+// reload of the child process is delayed while the child is busy.
+const DefaultExecReloadPacingMaxDelay = 60 * time.Second
+
+// ExecReloadPacingConfig is used to configure the pacing of child reloads. A
+// reload is delayed while the last resource usage sample of the child process
+// exceeds a threshold, until the child is quieter or the maximum delay passes.
+// The resource usage is sampled by the monitor of the child process, at its
+// interval.
+type ExecReloadPacingConfig struct {
+	// Enabled controls if reloads are paced.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// MaxCPUPercent is the CPU usage, as a percentage of a single core, above
+	// which reloads are delayed. A value of 0 disables the threshold.
+	MaxCPUPercent *float64 `mapstructure:"max_cpu_percent"`
+
+	// MaxDelay is the maximum amount of time a reload is delayed. Once it
+	// passes, the child process is reloaded even if it is still busy.
+	MaxDelay *time.Duration `mapstructure:"max_delay"`
+
+	// MaxRSS is the resident set size, in bytes, above which reloads are
+	// delayed. A value of 0 disables the threshold.
+	MaxRSS *uint64 `mapstructure:"max_rss"`
+}
+
+// DefaultExecReloadPacingConfig returns a configuration that is populated with
+// the default values.
+func DefaultExecReloadPacingConfig() *ExecReloadPacingConfig {
+	return &ExecReloadPacingConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ExecReloadPacingConfig) Copy() *ExecReloadPacingConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ExecReloadPacingConfig
+
+	o.Enabled = c.Enabled
+
+	o.MaxCPUPercent = c.MaxCPUPercent
+
+	o.MaxDelay = c.MaxDelay
+
+	o.MaxRSS = c.MaxRSS
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ExecReloadPacingConfig) Merge(o *ExecReloadPacingConfig) *ExecReloadPacingConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.MaxCPUPercent != nil {
+		r.MaxCPUPercent = o.MaxCPUPercent
+	}
+
+	if o.MaxDelay != nil {
+		r.MaxDelay = o.MaxDelay
+	}
+
+	if o.MaxRSS != nil {
+		r.MaxRSS = o.MaxRSS
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ExecReloadPacingConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			Float64Present(c.MaxCPUPercent) ||
+			Uint64Present(c.MaxRSS))
+	}
+
+	if c.MaxCPUPercent == nil {
+		c.MaxCPUPercent = Float64(0)
+	}
+
+	if c.MaxDelay == nil {
+		c.MaxDelay = TimeDuration(DefaultExecReloadPacingMaxDelay)
+	}
+
+	if c.MaxRSS == nil {
+		c.MaxRSS = Uint64(0)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ExecReloadPacingConfig) GoString() string {
+	if c == nil {
+		return "(*ExecReloadPacingConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ExecReloadPacingConfig{"+
+		"Enabled:%s, "+
+		"MaxCPUPercent:%s, "+
+		"MaxDelay:%s, "+
+		"MaxRSS:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		Float64GoString(c.MaxCPUPercent),
+		TimeDurationGoString(c.MaxDelay),
+		Uint64GoString(c.MaxRSS),
+	)
+}
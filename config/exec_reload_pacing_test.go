@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecReloadPacingConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecReloadPacingConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ExecReloadPacingConfig{},
+		},
+		{
+			"copy",
+			&ExecReloadPacingConfig{
+				Enabled:       Bool(true),
+				MaxCPUPercent: Float64(80),
+				MaxDelay:      TimeDuration(30 * time.Second),
+				MaxRSS:        Uint64(1024),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestExecReloadPacingConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecReloadPacingConfig
+		b    *ExecReloadPacingConfig
+		r    *ExecReloadPacingConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{},
+		},
+		{
+			"nil_b",
+			&ExecReloadPacingConfig{},
+			nil,
+			&ExecReloadPacingConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{},
+		},
+		{
+			"enabled_overrides",
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+			&ExecReloadPacingConfig{Enabled: Bool(false)},
+			&ExecReloadPacingConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+			&ExecReloadPacingConfig{Enabled: Bool(true)},
+		},
+		{
+			"max_cpu_percent_overrides",
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(50)},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(50)},
+		},
+		{
+			"max_cpu_percent_empty_one",
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+		},
+		{
+			"max_cpu_percent_empty_two",
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+		},
+		{
+			"max_cpu_percent_same",
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+			&ExecReloadPacingConfig{MaxCPUPercent: Float64(80)},
+		},
+		{
+			"max_delay_overrides",
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(60 * time.Second)},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(60 * time.Second)},
+		},
+		{
+			"max_delay_empty_one",
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+		},
+		{
+			"max_delay_empty_two",
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+		},
+		{
+			"max_delay_same",
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+			&ExecReloadPacingConfig{MaxDelay: TimeDuration(30 * time.Second)},
+		},
+		{
+			"max_rss_overrides",
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(2048)},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(2048)},
+		},
+		{
+			"max_rss_empty_one",
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+		},
+		{
+			"max_rss_empty_two",
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+		},
+		{
+			"max_rss_same",
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+			&ExecReloadPacingConfig{MaxRSS: Uint64(1024)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestExecReloadPacingConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ExecReloadPacingConfig
+		r    *ExecReloadPacingConfig
+	}{
+		{
+			"empty",
+			&ExecReloadPacingConfig{},
+			&ExecReloadPacingConfig{
+				Enabled:       Bool(false),
+				MaxCPUPercent: Float64(0),
+				MaxDelay:      TimeDuration(DefaultExecReloadPacingMaxDelay),
+				MaxRSS:        Uint64(0),
+			},
+		},
+		{
+			"with_threshold",
+			&ExecReloadPacingConfig{
+				MaxCPUPercent: Float64(80),
+			},
+			&ExecReloadPacingConfig{
+				Enabled:       Bool(true),
+				MaxCPUPercent: Float64(80),
+				MaxDelay:      TimeDuration(DefaultExecReloadPacingMaxDelay),
+				MaxRSS:        Uint64(0),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
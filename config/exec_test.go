@@ -24,14 +24,22 @@ func TestExecConfig_Copy(t *testing.T) {
 		{
 			"copy",
 			&ExecConfig{
-				Command:      String("command"),
-				Enabled:      Bool(true),
-				Env:          &EnvConfig{Pristine: Bool(true)},
-				KillSignal:   Signal(syscall.SIGINT),
-				KillTimeout:  TimeDuration(10 * time.Second),
-				ReloadSignal: Signal(syscall.SIGINT),
-				Splay:        TimeDuration(10 * time.Second),
-				Timeout:      TimeDuration(10 * time.Second),
+				Command:        String("command"),
+				Enabled:        Bool(true),
+				Env:            &EnvConfig{Pristine: Bool(true)},
+				Escalation:     &ExecEscalationConfig{Enabled: Bool(true)},
+				KillSignal:     Signal(syscall.SIGINT),
+				KillTimeout:    TimeDuration(10 * time.Second),
+				Listeners:      []string{"tcp://127.0.0.1:8080"},
+				Monitor:        &ExecMonitorConfig{Enabled: Bool(true)},
+				PrewarmTimeout: TimeDuration(10 * time.Second),
+				ReloadPacing:   &ExecReloadPacingConfig{Enabled: Bool(true)},
+				ReloadSignal:   Signal(syscall.SIGINT),
+				Splay:          TimeDuration(10 * time.Second),
+				Timeout:        TimeDuration(10 * time.Second),
+				WaitForFile:    String("/run/app/ready"),
+				WaitForKey:     String("locks/deploy-ready"),
+				WaitForService: String("db"),
 			},
 		},
 	}
@@ -149,6 +157,30 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{Env: &EnvConfig{Pristine: Bool(true)}},
 			&ExecConfig{Env: &EnvConfig{Pristine: Bool(true)}},
 		},
+		{
+			"escalation_overrides",
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(false)}},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(false)}},
+		},
+		{
+			"escalation_empty_one",
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+			&ExecConfig{},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+		},
+		{
+			"escalation_empty_two",
+			&ExecConfig{},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+		},
+		{
+			"escalation_same",
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+			&ExecConfig{Escalation: &ExecEscalationConfig{Enabled: Bool(true)}},
+		},
 		{
 			"kill_signal_overrides",
 			&ExecConfig{KillSignal: Signal(syscall.SIGINT)},
@@ -197,6 +229,96 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{KillTimeout: TimeDuration(10 * time.Second)},
 			&ExecConfig{KillTimeout: TimeDuration(10 * time.Second)},
 		},
+		{
+			"listeners_merges",
+			&ExecConfig{Listeners: []string{"tcp://:1"}},
+			&ExecConfig{Listeners: []string{"tcp://:2"}},
+			&ExecConfig{Listeners: []string{"tcp://:1", "tcp://:2"}},
+		},
+		{
+			"listeners_empty_one",
+			&ExecConfig{Listeners: []string{"tcp://:1"}},
+			&ExecConfig{},
+			&ExecConfig{Listeners: []string{"tcp://:1"}},
+		},
+		{
+			"listeners_empty_two",
+			&ExecConfig{},
+			&ExecConfig{Listeners: []string{"tcp://:1"}},
+			&ExecConfig{Listeners: []string{"tcp://:1"}},
+		},
+		{
+			"monitor_overrides",
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(false)}},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(false)}},
+		},
+		{
+			"monitor_empty_one",
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+			&ExecConfig{},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+		},
+		{
+			"monitor_empty_two",
+			&ExecConfig{},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+		},
+		{
+			"monitor_same",
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+			&ExecConfig{Monitor: &ExecMonitorConfig{Enabled: Bool(true)}},
+		},
+		{
+			"prewarm_timeout_overrides",
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+			&ExecConfig{PrewarmTimeout: TimeDuration(0 * time.Second)},
+			&ExecConfig{PrewarmTimeout: TimeDuration(0 * time.Second)},
+		},
+		{
+			"prewarm_timeout_empty_one",
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+			&ExecConfig{},
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"prewarm_timeout_empty_two",
+			&ExecConfig{},
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"prewarm_timeout_same",
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+			&ExecConfig{PrewarmTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"reload_pacing_overrides",
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(false)}},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(false)}},
+		},
+		{
+			"reload_pacing_empty_one",
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+			&ExecConfig{},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+		},
+		{
+			"reload_pacing_empty_two",
+			&ExecConfig{},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+		},
+		{
+			"reload_pacing_same",
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+			&ExecConfig{ReloadPacing: &ExecReloadPacingConfig{Enabled: Bool(true)}},
+		},
 		{
 			"reload_signal_overrides",
 			&ExecConfig{ReloadSignal: Signal(syscall.SIGINT)},
@@ -245,6 +367,30 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{Splay: TimeDuration(10 * time.Second)},
 			&ExecConfig{Splay: TimeDuration(10 * time.Second)},
 		},
+		{
+			"supervise_overrides",
+			&ExecConfig{Supervise: Bool(true)},
+			&ExecConfig{Supervise: Bool(false)},
+			&ExecConfig{Supervise: Bool(false)},
+		},
+		{
+			"supervise_empty_one",
+			&ExecConfig{Supervise: Bool(true)},
+			&ExecConfig{},
+			&ExecConfig{Supervise: Bool(true)},
+		},
+		{
+			"supervise_empty_two",
+			&ExecConfig{},
+			&ExecConfig{Supervise: Bool(true)},
+			&ExecConfig{Supervise: Bool(true)},
+		},
+		{
+			"supervise_same",
+			&ExecConfig{Supervise: Bool(true)},
+			&ExecConfig{Supervise: Bool(true)},
+			&ExecConfig{Supervise: Bool(true)},
+		},
 		{
 			"timeout_overrides",
 			&ExecConfig{Timeout: TimeDuration(10 * time.Second)},
@@ -269,6 +415,30 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{Timeout: TimeDuration(10 * time.Second)},
 			&ExecConfig{Timeout: TimeDuration(10 * time.Second)},
 		},
+		{
+			"wait_for_key_overrides",
+			&ExecConfig{WaitForKey: String("a")},
+			&ExecConfig{WaitForKey: String("")},
+			&ExecConfig{WaitForKey: String("")},
+		},
+		{
+			"wait_for_key_empty_one",
+			&ExecConfig{WaitForKey: String("a")},
+			&ExecConfig{},
+			&ExecConfig{WaitForKey: String("a")},
+		},
+		{
+			"wait_for_key_empty_two",
+			&ExecConfig{},
+			&ExecConfig{WaitForKey: String("a")},
+			&ExecConfig{WaitForKey: String("a")},
+		},
+		{
+			"wait_for_key_same",
+			&ExecConfig{WaitForKey: String("a")},
+			&ExecConfig{WaitForKey: String("a")},
+			&ExecConfig{WaitForKey: String("a")},
+		},
 	}
 
 	for i, tc := range cases {
@@ -299,11 +469,39 @@ func TestExecConfig_Finalize(t *testing.T) {
 					Pristine:  Bool(false),
 					Whitelist: []string{},
 				},
-				KillSignal:   Signal(DefaultExecKillSignal),
-				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
-				ReloadSignal: Signal(DefaultExecReloadSignal),
-				Splay:        TimeDuration(0 * time.Second),
-				Timeout:      TimeDuration(DefaultExecTimeout),
+				Escalation: &ExecEscalationConfig{
+					Check:    String(""),
+					Enabled:  Bool(false),
+					Interval: TimeDuration(DefaultExecEscalationInterval),
+					Steps:    []string{ExecEscalationStepRestart, ExecEscalationStepKill},
+					Timeout:  TimeDuration(DefaultExecEscalationTimeout),
+				},
+				KillSignal:  Signal(DefaultExecKillSignal),
+				KillTimeout: TimeDuration(DefaultExecKillTimeout),
+				Listeners:   []string{},
+				Monitor: &ExecMonitorConfig{
+					Action:        String(ExecMonitorActionLog),
+					Enabled:       Bool(false),
+					Interval:      TimeDuration(DefaultExecMonitorInterval),
+					MaxCPUPercent: Float64(0),
+					MaxOpenFiles:  Int(0),
+					MaxRSS:        Uint64(0),
+					Signal:        Signal(DefaultExecMonitorSignal),
+				},
+				PrewarmTimeout: TimeDuration(0 * time.Second),
+				ReloadPacing: &ExecReloadPacingConfig{
+					Enabled:       Bool(false),
+					MaxCPUPercent: Float64(0),
+					MaxDelay:      TimeDuration(DefaultExecReloadPacingMaxDelay),
+					MaxRSS:        Uint64(0),
+				},
+				ReloadSignal:   Signal(DefaultExecReloadSignal),
+				Splay:          TimeDuration(0 * time.Second),
+				Supervise:      Bool(false),
+				Timeout:        TimeDuration(DefaultExecTimeout),
+				WaitForFile:    String(""),
+				WaitForKey:     String(""),
+				WaitForService: String(""),
 			},
 		},
 		{
@@ -320,11 +518,39 @@ func TestExecConfig_Finalize(t *testing.T) {
 					Pristine:  Bool(false),
 					Whitelist: []string{},
 				},
-				KillSignal:   Signal(DefaultExecKillSignal),
-				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
-				ReloadSignal: Signal(DefaultExecReloadSignal),
-				Splay:        TimeDuration(0 * time.Second),
-				Timeout:      TimeDuration(DefaultExecTimeout),
+				Escalation: &ExecEscalationConfig{
+					Check:    String(""),
+					Enabled:  Bool(false),
+					Interval: TimeDuration(DefaultExecEscalationInterval),
+					Steps:    []string{ExecEscalationStepRestart, ExecEscalationStepKill},
+					Timeout:  TimeDuration(DefaultExecEscalationTimeout),
+				},
+				KillSignal:  Signal(DefaultExecKillSignal),
+				KillTimeout: TimeDuration(DefaultExecKillTimeout),
+				Listeners:   []string{},
+				Monitor: &ExecMonitorConfig{
+					Action:        String(ExecMonitorActionLog),
+					Enabled:       Bool(false),
+					Interval:      TimeDuration(DefaultExecMonitorInterval),
+					MaxCPUPercent: Float64(0),
+					MaxOpenFiles:  Int(0),
+					MaxRSS:        Uint64(0),
+					Signal:        Signal(DefaultExecMonitorSignal),
+				},
+				PrewarmTimeout: TimeDuration(0 * time.Second),
+				ReloadPacing: &ExecReloadPacingConfig{
+					Enabled:       Bool(false),
+					MaxCPUPercent: Float64(0),
+					MaxDelay:      TimeDuration(DefaultExecReloadPacingMaxDelay),
+					MaxRSS:        Uint64(0),
+				},
+				ReloadSignal:   Signal(DefaultExecReloadSignal),
+				Splay:          TimeDuration(0 * time.Second),
+				Supervise:      Bool(false),
+				Timeout:        TimeDuration(DefaultExecTimeout),
+				WaitForFile:    String(""),
+				WaitForKey:     String(""),
+				WaitForService: String(""),
 			},
 		},
 	}
@@ -0,0 +1,88 @@
+package config
+
+import "fmt"
+
+// LocalCacheConfig is the configuration for reading Consul data through the
+// cache server of the host, which shares a single query for each piece of data
+// between all the instances on the host.
+type LocalCacheConfig struct {
+	// Enabled controls if Consul is queried through the cache server.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Path is the path of the unix socket of the cache server. The cache server
+	// listens on it, and the other instances connect to it.
+	Path *string `mapstructure:"path"`
+}
+
+// DefaultLocalCacheConfig returns a configuration that is populated with the
+// default values.
+func DefaultLocalCacheConfig() *LocalCacheConfig {
+	return &LocalCacheConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *LocalCacheConfig) Copy() *LocalCacheConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o LocalCacheConfig
+	o.Enabled = c.Enabled
+	o.Path = c.Path
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *LocalCacheConfig) Merge(o *LocalCacheConfig) *LocalCacheConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *LocalCacheConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Path))
+	}
+
+	if c.Path == nil {
+		c.Path = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *LocalCacheConfig) GoString() string {
+	if c == nil {
+		return "(*LocalCacheConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&LocalCacheConfig{"+
+		"Enabled:%s, "+
+		"Path:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.Path),
+	)
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLocalCacheConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *LocalCacheConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&LocalCacheConfig{},
+		},
+		{
+			"same_enabled",
+			&LocalCacheConfig{
+				Enabled: Bool(true),
+				Path:    String("/run/consul-template-cache.sock"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestLocalCacheConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *LocalCacheConfig
+		b    *LocalCacheConfig
+		r    *LocalCacheConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&LocalCacheConfig{},
+			&LocalCacheConfig{},
+		},
+		{
+			"nil_b",
+			&LocalCacheConfig{},
+			nil,
+			&LocalCacheConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&LocalCacheConfig{},
+			&LocalCacheConfig{},
+			&LocalCacheConfig{},
+		},
+		{
+			"enabled_overrides",
+			&LocalCacheConfig{Enabled: Bool(true)},
+			&LocalCacheConfig{Enabled: Bool(false)},
+			&LocalCacheConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&LocalCacheConfig{Enabled: Bool(true)},
+			&LocalCacheConfig{},
+			&LocalCacheConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&LocalCacheConfig{},
+			&LocalCacheConfig{Enabled: Bool(true)},
+			&LocalCacheConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&LocalCacheConfig{Enabled: Bool(true)},
+			&LocalCacheConfig{Enabled: Bool(true)},
+			&LocalCacheConfig{Enabled: Bool(true)},
+		},
+		{
+			"path_overrides",
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+			&LocalCacheConfig{Path: String("")},
+			&LocalCacheConfig{Path: String("")},
+		},
+		{
+			"path_empty_one",
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+			&LocalCacheConfig{},
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+		},
+		{
+			"path_empty_two",
+			&LocalCacheConfig{},
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+		},
+		{
+			"path_same",
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+			&LocalCacheConfig{Path: String("/run/consul-template-cache.sock")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestLocalCacheConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *LocalCacheConfig
+		r    *LocalCacheConfig
+	}{
+		{
+			"empty",
+			&LocalCacheConfig{},
+			&LocalCacheConfig{
+				Enabled: Bool(false),
+				Path:    String(""),
+			},
+		},
+		{
+			"with_path",
+			&LocalCacheConfig{
+				Path: String("/run/consul-template-cache.sock"),
+			},
+			&LocalCacheConfig{
+				Enabled: Bool(true),
+				Path:    String("/run/consul-template-cache.sock"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultMinInstancesCount is the default minimum number of healthy
+	// instances required by a min_instances guard.
+	DefaultMinInstancesCount = 1
+)
+
+// MinInstancesConfig is a guard which refuses to render a template if the data
+// it uses includes fewer than Count healthy instances of the named service.
+// The last good file is kept instead.
+type MinInstancesConfig struct {
+	// Count is the minimum number of healthy instances.
+	Count *int `mapstructure:"count"`
+
+	// Service is the name of the service, as queried by the template.
+	Service *string `mapstructure:"service"`
+}
+
+// DefaultMinInstancesConfig returns a configuration that is populated with the
+// default values.
+func DefaultMinInstancesConfig() *MinInstancesConfig {
+	return &MinInstancesConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *MinInstancesConfig) Copy() *MinInstancesConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o MinInstancesConfig
+	o.Count = c.Count
+	o.Service = c.Service
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *MinInstancesConfig) Merge(o *MinInstancesConfig) *MinInstancesConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Count != nil {
+		r.Count = o.Count
+	}
+
+	if o.Service != nil {
+		r.Service = o.Service
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *MinInstancesConfig) Finalize() {
+	if c.Count == nil {
+		c.Count = Int(DefaultMinInstancesCount)
+	}
+
+	if c.Service == nil {
+		c.Service = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *MinInstancesConfig) GoString() string {
+	if c == nil {
+		return "(*MinInstancesConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&MinInstancesConfig{"+
+		"Count:%s, "+
+		"Service:%s"+
+		"}",
+		IntGoString(c.Count),
+		StringGoString(c.Service),
+	)
+}
+
+// MinInstancesConfigs is a collection of MinInstancesConfigs.
+type MinInstancesConfigs []*MinInstancesConfig
+
+// DefaultMinInstancesConfigs returns a configuration that is populated with
+// the default values.
+func DefaultMinInstancesConfigs() *MinInstancesConfigs {
+	return &MinInstancesConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *MinInstancesConfigs) Copy() *MinInstancesConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(MinInstancesConfigs, len(*c))
+	for i, t := range *c {
+		o[i] = t.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *MinInstancesConfigs) Merge(o *MinInstancesConfigs) *MinInstancesConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	*r = append(*r, *o.Copy()...)
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *MinInstancesConfigs) Finalize() {
+	for _, t := range *c {
+		t.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *MinInstancesConfigs) GoString() string {
+	if c == nil {
+		return "(*MinInstancesConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, t := range *c {
+		s[i] = t.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMinInstancesConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *MinInstancesConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&MinInstancesConfig{},
+		},
+		{
+			"same_enabled",
+			&MinInstancesConfig{
+				Count:   Int(2),
+				Service: String("web"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestMinInstancesConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *MinInstancesConfig
+		b    *MinInstancesConfig
+		r    *MinInstancesConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&MinInstancesConfig{},
+			&MinInstancesConfig{},
+		},
+		{
+			"nil_b",
+			&MinInstancesConfig{},
+			nil,
+			&MinInstancesConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&MinInstancesConfig{},
+			&MinInstancesConfig{},
+			&MinInstancesConfig{},
+		},
+		{
+			"count_overrides",
+			&MinInstancesConfig{Count: Int(2)},
+			&MinInstancesConfig{Count: Int(3)},
+			&MinInstancesConfig{Count: Int(3)},
+		},
+		{
+			"count_empty_one",
+			&MinInstancesConfig{Count: Int(2)},
+			&MinInstancesConfig{},
+			&MinInstancesConfig{Count: Int(2)},
+		},
+		{
+			"count_empty_two",
+			&MinInstancesConfig{},
+			&MinInstancesConfig{Count: Int(2)},
+			&MinInstancesConfig{Count: Int(2)},
+		},
+		{
+			"count_same",
+			&MinInstancesConfig{Count: Int(2)},
+			&MinInstancesConfig{Count: Int(2)},
+			&MinInstancesConfig{Count: Int(2)},
+		},
+		{
+			"service_overrides",
+			&MinInstancesConfig{Service: String("web")},
+			&MinInstancesConfig{Service: String("")},
+			&MinInstancesConfig{Service: String("")},
+		},
+		{
+			"service_empty_one",
+			&MinInstancesConfig{Service: String("web")},
+			&MinInstancesConfig{},
+			&MinInstancesConfig{Service: String("web")},
+		},
+		{
+			"service_empty_two",
+			&MinInstancesConfig{},
+			&MinInstancesConfig{Service: String("web")},
+			&MinInstancesConfig{Service: String("web")},
+		},
+		{
+			"service_same",
+			&MinInstancesConfig{Service: String("web")},
+			&MinInstancesConfig{Service: String("web")},
+			&MinInstancesConfig{Service: String("web")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestMinInstancesConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *MinInstancesConfig
+		r    *MinInstancesConfig
+	}{
+		{
+			"empty",
+			&MinInstancesConfig{},
+			&MinInstancesConfig{
+				Count:   Int(DefaultMinInstancesCount),
+				Service: String(""),
+			},
+		},
+		{
+			"with_service",
+			&MinInstancesConfig{
+				Count:   Int(3),
+				Service: String("web"),
+			},
+			&MinInstancesConfig{
+				Count:   Int(3),
+				Service: String("web"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import "fmt"
+
+// NomadConfig is the configuration for connecting to a Nomad cluster, which is
+// queried for the services registered with Nomad's native service discovery.
+type NomadConfig struct {
+	// Address is the URI to the Nomad agent. This can also be set via the
+	// NOMAD_ADDR environment variable.
+	Address *string `mapstructure:"address"`
+
+	// Enabled controls whether the Nomad integration is active.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Namespace is the Nomad namespace to query. This can also be set via the
+	// NOMAD_NAMESPACE environment variable. The default value is empty, which
+	// queries the namespace of the token.
+	Namespace *string `mapstructure:"namespace"`
+
+	// Region is the Nomad region to query. This can also be set via the
+	// NOMAD_REGION environment variable. The default value is empty, which
+	// queries the region of the agent.
+	Region *string `mapstructure:"region"`
+
+	// SSL indicates we should use a secure connection while talking to Nomad.
+	SSL *SSLConfig `mapstructure:"ssl"`
+
+	// Token is the Nomad ACL token to communicate with for requests. This can
+	// also be set via the NOMAD_TOKEN environment variable.
+	Token *string `mapstructure:"token" json:"-"`
+}
+
+// DefaultNomadConfig returns a configuration that is populated with the
+// default values.
+func DefaultNomadConfig() *NomadConfig {
+	return &NomadConfig{
+		Address:   stringFromEnv("NOMAD_ADDR"),
+		Namespace: stringFromEnv("NOMAD_NAMESPACE"),
+		Region:    stringFromEnv("NOMAD_REGION"),
+		SSL: &SSLConfig{
+			CaCert:     stringFromEnv("NOMAD_CACERT"),
+			CaPath:     stringFromEnv("NOMAD_CAPATH"),
+			Cert:       stringFromEnv("NOMAD_CLIENT_CERT"),
+			Key:        stringFromEnv("NOMAD_CLIENT_KEY"),
+			ServerName: stringFromEnv("NOMAD_TLS_SERVER_NAME"),
+			Verify:     antiboolFromEnv("NOMAD_SKIP_VERIFY"),
+		},
+		Token: stringFromEnv("NOMAD_TOKEN"),
+	}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *NomadConfig) Copy() *NomadConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o NomadConfig
+	o.Address = c.Address
+
+	o.Enabled = c.Enabled
+
+	o.Namespace = c.Namespace
+
+	o.Region = c.Region
+
+	if c.SSL != nil {
+		o.SSL = c.SSL.Copy()
+	}
+
+	o.Token = c.Token
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *NomadConfig) Merge(o *NomadConfig) *NomadConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Address != nil {
+		r.Address = o.Address
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Namespace != nil {
+		r.Namespace = o.Namespace
+	}
+
+	if o.Region != nil {
+		r.Region = o.Region
+	}
+
+	if o.SSL != nil {
+		r.SSL = r.SSL.Merge(o.SSL)
+	}
+
+	if o.Token != nil {
+		r.Token = o.Token
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *NomadConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Address))
+	}
+
+	if c.Address == nil {
+		c.Address = String("")
+	}
+
+	if c.Namespace == nil {
+		c.Namespace = String("")
+	}
+
+	if c.Region == nil {
+		c.Region = String("")
+	}
+
+	if c.SSL == nil {
+		c.SSL = DefaultSSLConfig()
+	}
+	c.SSL.Finalize()
+
+	if c.Token == nil {
+		c.Token = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *NomadConfig) GoString() string {
+	if c == nil {
+		return "(*NomadConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&NomadConfig{"+
+		"Address:%s, "+
+		"Enabled:%s, "+
+		"Namespace:%s, "+
+		"Region:%s, "+
+		"SSL:%#v, "+
+		"Token:%s"+
+		"}",
+		StringGoString(c.Address),
+		BoolGoString(c.Enabled),
+		StringGoString(c.Namespace),
+		StringGoString(c.Region),
+		c.SSL,
+		StringGoString(c.Token),
+	)
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNomadConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *NomadConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&NomadConfig{},
+		},
+		{
+			"same_enabled",
+			&NomadConfig{
+				Address:   String("address"),
+				Enabled:   Bool(true),
+				Namespace: String("namespace"),
+				Region:    String("region"),
+				SSL:       &SSLConfig{Enabled: Bool(true)},
+				Token:     String("token"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestNomadConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *NomadConfig
+		b    *NomadConfig
+		r    *NomadConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&NomadConfig{},
+			&NomadConfig{},
+		},
+		{
+			"nil_b",
+			&NomadConfig{},
+			nil,
+			&NomadConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&NomadConfig{},
+			&NomadConfig{},
+			&NomadConfig{},
+		},
+		{
+			"address_overrides",
+			&NomadConfig{Address: String("address")},
+			&NomadConfig{Address: String("")},
+			&NomadConfig{Address: String("")},
+		},
+		{
+			"address_empty_one",
+			&NomadConfig{Address: String("address")},
+			&NomadConfig{},
+			&NomadConfig{Address: String("address")},
+		},
+		{
+			"address_empty_two",
+			&NomadConfig{},
+			&NomadConfig{Address: String("address")},
+			&NomadConfig{Address: String("address")},
+		},
+		{
+			"address_same",
+			&NomadConfig{Address: String("address")},
+			&NomadConfig{Address: String("address")},
+			&NomadConfig{Address: String("address")},
+		},
+		{
+			"enabled_overrides",
+			&NomadConfig{Enabled: Bool(true)},
+			&NomadConfig{Enabled: Bool(false)},
+			&NomadConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&NomadConfig{Enabled: Bool(true)},
+			&NomadConfig{},
+			&NomadConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&NomadConfig{},
+			&NomadConfig{Enabled: Bool(true)},
+			&NomadConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&NomadConfig{Enabled: Bool(true)},
+			&NomadConfig{Enabled: Bool(true)},
+			&NomadConfig{Enabled: Bool(true)},
+		},
+		{
+			"namespace_overrides",
+			&NomadConfig{Namespace: String("namespace")},
+			&NomadConfig{Namespace: String("")},
+			&NomadConfig{Namespace: String("")},
+		},
+		{
+			"namespace_empty_one",
+			&NomadConfig{Namespace: String("namespace")},
+			&NomadConfig{},
+			&NomadConfig{Namespace: String("namespace")},
+		},
+		{
+			"namespace_empty_two",
+			&NomadConfig{},
+			&NomadConfig{Namespace: String("namespace")},
+			&NomadConfig{Namespace: String("namespace")},
+		},
+		{
+			"namespace_same",
+			&NomadConfig{Namespace: String("namespace")},
+			&NomadConfig{Namespace: String("namespace")},
+			&NomadConfig{Namespace: String("namespace")},
+		},
+		{
+			"region_overrides",
+			&NomadConfig{Region: String("region")},
+			&NomadConfig{Region: String("")},
+			&NomadConfig{Region: String("")},
+		},
+		{
+			"region_empty_one",
+			&NomadConfig{Region: String("region")},
+			&NomadConfig{},
+			&NomadConfig{Region: String("region")},
+		},
+		{
+			"region_empty_two",
+			&NomadConfig{},
+			&NomadConfig{Region: String("region")},
+			&NomadConfig{Region: String("region")},
+		},
+		{
+			"region_same",
+			&NomadConfig{Region: String("region")},
+			&NomadConfig{Region: String("region")},
+			&NomadConfig{Region: String("region")},
+		},
+		{
+			"token_overrides",
+			&NomadConfig{Token: String("token")},
+			&NomadConfig{Token: String("")},
+			&NomadConfig{Token: String("")},
+		},
+		{
+			"token_empty_one",
+			&NomadConfig{Token: String("token")},
+			&NomadConfig{},
+			&NomadConfig{Token: String("token")},
+		},
+		{
+			"token_empty_two",
+			&NomadConfig{},
+			&NomadConfig{Token: String("token")},
+			&NomadConfig{Token: String("token")},
+		},
+		{
+			"token_same",
+			&NomadConfig{Token: String("token")},
+			&NomadConfig{Token: String("token")},
+			&NomadConfig{Token: String("token")},
+		},
+		{
+			"ssl_overrides",
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(false)}},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(false)}},
+		},
+		{
+			"ssl_empty_one",
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+			&NomadConfig{},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+		},
+		{
+			"ssl_empty_two",
+			&NomadConfig{},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+		},
+		{
+			"ssl_same",
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+			&NomadConfig{SSL: &SSLConfig{Enabled: Bool(true)}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestNomadConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *NomadConfig
+		r    *NomadConfig
+	}{
+		{
+			"empty",
+			&NomadConfig{},
+			&NomadConfig{
+				Address:   String(""),
+				Enabled:   Bool(false),
+				Namespace: String(""),
+				Region:    String(""),
+				SSL: &SSLConfig{
+					CaCert:     String(""),
+					CaPath:     String(""),
+					Cert:       String(""),
+					Enabled:    Bool(false),
+					Key:        String(""),
+					ServerName: String(""),
+					Verify:     Bool(true),
+				},
+				Token: String(""),
+			},
+		},
+		{
+			"with_address",
+			&NomadConfig{
+				Address: String("http://127.0.0.1:4646"),
+			},
+			&NomadConfig{
+				Address:   String("http://127.0.0.1:4646"),
+				Enabled:   Bool(true),
+				Namespace: String(""),
+				Region:    String(""),
+				SSL: &SSLConfig{
+					CaCert:     String(""),
+					CaPath:     String(""),
+					Cert:       String(""),
+					Enabled:    Bool(false),
+					Key:        String(""),
+					ServerName: String(""),
+					Verify:     Bool(true),
+				},
+				Token: String(""),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
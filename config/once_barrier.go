@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultOnceBarrierPrefix is the default prefix used for the once mode
+	// barrier.
+	DefaultOnceBarrierPrefix = "consul-template/barrier/"
+
+	// DefaultOnceBarrierTimeout is the default maximum amount of time to wait
+	// for the barrier to open.
+	DefaultOnceBarrierTimeout = 5 * time.Minute
+)
+
+// OnceBarrierConfig is used to configure the barrier of once mode. Instances
+// sharing the same prefix render their templates, then wait until the given
+// number of instances have rendered before running their commands and
+// exiting, so they all do it at the same time.
+type OnceBarrierConfig struct {
+	// Enabled controls if the barrier is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Instances is the number of instances which must reach the barrier
+	// before it opens.
+	Instances *int `mapstructure:"instances"`
+
+	// Prefix is the KV prefix under which instances register at the barrier.
+	// Once the barrier opens it stays open, so each rollout should use its own
+	// prefix.
+	Prefix *string `mapstructure:"prefix"`
+
+	// Timeout is the maximum amount of time to wait for the barrier to open.
+	// If it expires, the instance exits with an error without running its
+	// commands. A value of 0 waits forever.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultOnceBarrierConfig returns a configuration that is populated with the
+// default values.
+func DefaultOnceBarrierConfig() *OnceBarrierConfig {
+	return &OnceBarrierConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *OnceBarrierConfig) Copy() *OnceBarrierConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o OnceBarrierConfig
+	o.Enabled = c.Enabled
+	o.Instances = c.Instances
+	o.Prefix = c.Prefix
+	o.Timeout = c.Timeout
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *OnceBarrierConfig) Merge(o *OnceBarrierConfig) *OnceBarrierConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Instances != nil {
+		r.Instances = o.Instances
+	}
+
+	if o.Prefix != nil {
+		r.Prefix = o.Prefix
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *OnceBarrierConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			IntPresent(c.Instances) ||
+			StringPresent(c.Prefix) ||
+			TimeDurationPresent(c.Timeout))
+	}
+
+	if c.Instances == nil {
+		c.Instances = Int(0)
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = String(DefaultOnceBarrierPrefix)
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultOnceBarrierTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *OnceBarrierConfig) GoString() string {
+	if c == nil {
+		return "(*OnceBarrierConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&OnceBarrierConfig{"+
+		"Enabled:%s, "+
+		"Instances:%s, "+
+		"Prefix:%s, "+
+		"Timeout:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		IntGoString(c.Instances),
+		StringGoString(c.Prefix),
+		TimeDurationGoString(c.Timeout),
+	)
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOnceBarrierConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *OnceBarrierConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&OnceBarrierConfig{},
+		},
+		{
+			"same_enabled",
+			&OnceBarrierConfig{
+				Enabled:   Bool(true),
+				Instances: Int(12),
+				Prefix:    String("prefix/"),
+				Timeout:   TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestOnceBarrierConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *OnceBarrierConfig
+		b    *OnceBarrierConfig
+		r    *OnceBarrierConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{},
+		},
+		{
+			"nil_b",
+			&OnceBarrierConfig{},
+			nil,
+			&OnceBarrierConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{},
+		},
+		{
+			"enabled_overrides",
+			&OnceBarrierConfig{Enabled: Bool(true)},
+			&OnceBarrierConfig{Enabled: Bool(false)},
+			&OnceBarrierConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&OnceBarrierConfig{Enabled: Bool(true)},
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Enabled: Bool(true)},
+			&OnceBarrierConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&OnceBarrierConfig{Enabled: Bool(true)},
+			&OnceBarrierConfig{Enabled: Bool(true)},
+			&OnceBarrierConfig{Enabled: Bool(true)},
+		},
+		{
+			"instances_overrides",
+			&OnceBarrierConfig{Instances: Int(12)},
+			&OnceBarrierConfig{Instances: Int(0)},
+			&OnceBarrierConfig{Instances: Int(0)},
+		},
+		{
+			"instances_empty_one",
+			&OnceBarrierConfig{Instances: Int(12)},
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Instances: Int(12)},
+		},
+		{
+			"instances_empty_two",
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Instances: Int(12)},
+			&OnceBarrierConfig{Instances: Int(12)},
+		},
+		{
+			"instances_same",
+			&OnceBarrierConfig{Instances: Int(12)},
+			&OnceBarrierConfig{Instances: Int(12)},
+			&OnceBarrierConfig{Instances: Int(12)},
+		},
+		{
+			"prefix_overrides",
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+			&OnceBarrierConfig{Prefix: String("")},
+			&OnceBarrierConfig{Prefix: String("")},
+		},
+		{
+			"prefix_empty_one",
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+		},
+		{
+			"prefix_empty_two",
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+		},
+		{
+			"prefix_same",
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+			&OnceBarrierConfig{Prefix: String("prefix/")},
+		},
+		{
+			"timeout_overrides",
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+			&OnceBarrierConfig{Timeout: TimeDuration(0)},
+			&OnceBarrierConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+			&OnceBarrierConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestOnceBarrierConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *OnceBarrierConfig
+		r    *OnceBarrierConfig
+	}{
+		{
+			"empty",
+			&OnceBarrierConfig{},
+			&OnceBarrierConfig{
+				Enabled:   Bool(false),
+				Instances: Int(0),
+				Prefix:    String(DefaultOnceBarrierPrefix),
+				Timeout:   TimeDuration(DefaultOnceBarrierTimeout),
+			},
+		},
+		{
+			"with_instances",
+			&OnceBarrierConfig{
+				Instances: Int(12),
+			},
+			&OnceBarrierConfig{
+				Enabled:   Bool(true),
+				Instances: Int(12),
+				Prefix:    String(DefaultOnceBarrierPrefix),
+				Timeout:   TimeDuration(DefaultOnceBarrierTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
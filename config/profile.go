@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProfileEnvVar is the environment variable which selects the profile when
+	// none is given on the command line.
+	ProfileEnvVar = "CONSUL_TEMPLATE_PROFILE"
+)
+
+// parseProfiles removes the `profile "name" { ... }` blocks from the given
+// parsed contents of a configuration file and decodes each into a config, by
+// name. A profile given more than once in the same file is merged in order.
+func parseProfiles(parsed map[string]interface{}) (map[string]*Config, error) {
+	raw, ok := parsed["profile"]
+	if !ok {
+		return nil, nil
+	}
+	delete(parsed, "profile")
+
+	blocks, ok := raw.([]map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile: expected named blocks, got %T", raw)
+	}
+
+	profiles := make(map[string]*Config)
+	for _, block := range blocks {
+		for name, body := range block {
+			bodies, err := profileBodies(body)
+			if err != nil {
+				return nil, errors.Wrapf(err, "profile %q", name)
+			}
+			for _, b := range bodies {
+				if _, ok := b["profile"]; ok {
+					return nil, fmt.Errorf("profile %q: profiles cannot be nested", name)
+				}
+				c, err := decodeConfig(b)
+				if err != nil {
+					return nil, errors.Wrapf(err, "profile %q", name)
+				}
+				profiles[name] = profiles[name].Merge(c)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// profileBodies returns the contents of a named profile block, which an
+// unnamed block would have as fields instead.
+func profileBodies(body interface{}) ([]map[string]interface{}, error) {
+	switch b := body.(type) {
+	case []map[string]interface{}:
+		return b, nil
+	case map[string]interface{}:
+		return []map[string]interface{}{b}, nil
+	default:
+		return nil, fmt.Errorf("expected a block, got %T", body)
+	}
+}
+
+// ApplyProfile returns a copy of this configuration with the profile of the
+// given name merged on top, so its values take precedence. An empty name
+// returns a copy of this configuration. It returns an error if the profile is
+// not defined.
+func (c *Config) ApplyProfile(name string) (*Config, error) {
+	if name == "" {
+		return c.Copy(), nil
+	}
+
+	var p *Config
+	if c != nil {
+		p = c.Profiles[name]
+	}
+	if p == nil {
+		return nil, fmt.Errorf("profile %q is not defined, known profiles are %q",
+			name, c.ProfileNames())
+	}
+	return c.Merge(p), nil
+}
+
+// ProfileNames returns the names of the profiles of this configuration, in
+// order.
+func (c *Config) ProfileNames() []string {
+	if c == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
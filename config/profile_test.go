@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_profiles(t *testing.T) {
+	cases := []struct {
+		name string
+		i    string
+		e    map[string]*Config
+		err  string
+	}{
+		{
+			"none",
+			`log_level = "info"`,
+			nil,
+			"",
+		},
+		{
+			"profiles",
+			`
+			profile "dev" {
+				consul = "127.0.0.1:8500"
+			}
+			profile "prod" {
+				consul = "consul.service:8500"
+				wait {
+					min = "5s"
+				}
+			}`,
+			map[string]*Config{
+				"dev": &Config{
+					Consul: String("127.0.0.1:8500"),
+				},
+				"prod": &Config{
+					Consul: String("consul.service:8500"),
+					Wait:   &WaitConfig{Min: TimeDuration(5 * time.Second)},
+				},
+			},
+			"",
+		},
+		{
+			"same_name",
+			`
+			profile "prod" {
+				log_level = "info"
+				pid_file  = "/run/ct.pid"
+			}
+			profile "prod" {
+				log_level = "warn"
+			}`,
+			map[string]*Config{
+				"prod": &Config{
+					LogLevel: String("warn"),
+					PidFile:  String("/run/ct.pid"),
+				},
+			},
+			"",
+		},
+		{
+			"nested",
+			`
+			profile "prod" {
+				profile "dev" {}
+			}`,
+			nil,
+			"profiles cannot be nested",
+		},
+		{
+			"unknown_key",
+			`
+			profile "prod" {
+				nonsense = true
+			}`,
+			nil,
+			`profile "prod"`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c, err := Parse(tc.i)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.e, c.Profiles) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, c.Profiles)
+			}
+		})
+	}
+}
+
+func TestConfig_ApplyProfile(t *testing.T) {
+	base := &Config{
+		Consul:   String("127.0.0.1:8500"),
+		LogLevel: String("info"),
+		Profiles: map[string]*Config{
+			"prod": &Config{
+				Consul: String("consul.service:8500"),
+			},
+		},
+	}
+
+	// A profile defined in a later file is merged with the same profile of an
+	// earlier file.
+	c := base.Merge(&Config{
+		Profiles: map[string]*Config{
+			"prod": &Config{LogLevel: String("warn")},
+		},
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		r, err := c.ApplyProfile("prod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := StringVal(r.Consul); act != "consul.service:8500" {
+			t.Errorf("expected the address of the profile, got %q", act)
+		}
+		if act := StringVal(r.LogLevel); act != "warn" {
+			t.Errorf("expected the log level of the profile, got %q", act)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		r, err := c.ApplyProfile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c, r) {
+			t.Errorf("\nexp: %#v\nact: %#v", c, r)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := c.ApplyProfile("staging")
+		if err == nil || !strings.Contains(err.Error(), `known profiles are ["prod"]`) {
+			t.Fatalf("expected an unknown profile, got %v", err)
+		}
+	})
+
+	if act := StringVal(base.LogLevel); act != "info" {
+		t.Errorf("expected the base configuration to be unchanged, got %q", act)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultReadyCheckInterval is the default amount of time between
+	// readiness checks of the process started by the command of a template.
+	DefaultReadyCheckInterval = 1 * time.Second
+)
+
+// ReadyCheckConfig is the readiness check of the long-lived process started by
+// the command of a template, such as a sidecar. The check runs until it
+// succeeds, and again each time the command runs. The readiness probe of the
+// status server waits for the checks of all templates, so the host is only
+// reported ready once all their processes are.
+type ReadyCheckConfig struct {
+	// Enabled controls if the check runs. It is enabled by default if GRPC or
+	// HTTP is set.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// GRPC is the address, as "host:port", of a gRPC server implementing the
+	// standard health checking protocol. The process is ready when the server
+	// reports it is serving.
+	GRPC *string `mapstructure:"grpc"`
+
+	// GRPCService is the name of the service whose health is checked with
+	// GRPC. The default value is empty, which checks the overall health of the
+	// server.
+	GRPCService *string `mapstructure:"grpc_service"`
+
+	// HTTP is the URL of an HTTP endpoint. The process is ready when a GET
+	// request to it returns a 2xx status.
+	HTTP *string `mapstructure:"http"`
+
+	// Interval is the amount of time between checks. It is also the maximum
+	// amount of time a single check may take.
+	Interval *time.Duration `mapstructure:"interval"`
+}
+
+// DefaultReadyCheckConfig returns a configuration that is populated with the
+// default values.
+func DefaultReadyCheckConfig() *ReadyCheckConfig {
+	return &ReadyCheckConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ReadyCheckConfig) Copy() *ReadyCheckConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ReadyCheckConfig
+
+	o.Enabled = c.Enabled
+
+	o.GRPC = c.GRPC
+
+	o.GRPCService = c.GRPCService
+
+	o.HTTP = c.HTTP
+
+	o.Interval = c.Interval
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ReadyCheckConfig) Merge(o *ReadyCheckConfig) *ReadyCheckConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.GRPC != nil {
+		r.GRPC = o.GRPC
+	}
+
+	if o.GRPCService != nil {
+		r.GRPCService = o.GRPCService
+	}
+
+	if o.HTTP != nil {
+		r.HTTP = o.HTTP
+	}
+
+	if o.Interval != nil {
+		r.Interval = o.Interval
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ReadyCheckConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.GRPC) || StringPresent(c.HTTP))
+	}
+
+	if c.GRPC == nil {
+		c.GRPC = String("")
+	}
+
+	if c.GRPCService == nil {
+		c.GRPCService = String("")
+	}
+
+	if c.HTTP == nil {
+		c.HTTP = String("")
+	}
+
+	if c.Interval == nil {
+		c.Interval = TimeDuration(DefaultReadyCheckInterval)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ReadyCheckConfig) GoString() string {
+	if c == nil {
+		return "(*ReadyCheckConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ReadyCheckConfig{"+
+		"Enabled:%s, "+
+		"GRPC:%s, "+
+		"GRPCService:%s, "+
+		"HTTP:%s, "+
+		"Interval:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.GRPC),
+		StringGoString(c.GRPCService),
+		StringGoString(c.HTTP),
+		TimeDurationGoString(c.Interval),
+	)
+}
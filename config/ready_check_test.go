@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadyCheckConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ReadyCheckConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ReadyCheckConfig{},
+		},
+		{
+			"copy",
+			&ReadyCheckConfig{
+				Enabled:     Bool(true),
+				GRPC:        String("127.0.0.1:9000"),
+				GRPCService: String("app"),
+				HTTP:        String("http://127.0.0.1:8080/ready"),
+				Interval:    TimeDuration(2 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestReadyCheckConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ReadyCheckConfig
+		b    *ReadyCheckConfig
+		r    *ReadyCheckConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{},
+		},
+		{
+			"nil_b",
+			&ReadyCheckConfig{},
+			nil,
+			&ReadyCheckConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{},
+		},
+		{
+			"enabled_overrides",
+			&ReadyCheckConfig{Enabled: Bool(true)},
+			&ReadyCheckConfig{Enabled: Bool(false)},
+			&ReadyCheckConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&ReadyCheckConfig{Enabled: Bool(true)},
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{Enabled: Bool(true)},
+			&ReadyCheckConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&ReadyCheckConfig{Enabled: Bool(true)},
+			&ReadyCheckConfig{Enabled: Bool(true)},
+			&ReadyCheckConfig{Enabled: Bool(true)},
+		},
+		{
+			"grpc_overrides",
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+			&ReadyCheckConfig{GRPC: String("")},
+			&ReadyCheckConfig{GRPC: String("")},
+		},
+		{
+			"grpc_empty_one",
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+		},
+		{
+			"grpc_empty_two",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+		},
+		{
+			"grpc_same",
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+			&ReadyCheckConfig{GRPC: String("127.0.0.1:9000")},
+		},
+		{
+			"grpc_service_overrides",
+			&ReadyCheckConfig{GRPCService: String("app")},
+			&ReadyCheckConfig{GRPCService: String("")},
+			&ReadyCheckConfig{GRPCService: String("")},
+		},
+		{
+			"grpc_service_empty_one",
+			&ReadyCheckConfig{GRPCService: String("app")},
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{GRPCService: String("app")},
+		},
+		{
+			"grpc_service_empty_two",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{GRPCService: String("app")},
+			&ReadyCheckConfig{GRPCService: String("app")},
+		},
+		{
+			"grpc_service_same",
+			&ReadyCheckConfig{GRPCService: String("app")},
+			&ReadyCheckConfig{GRPCService: String("app")},
+			&ReadyCheckConfig{GRPCService: String("app")},
+		},
+		{
+			"http_overrides",
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+			&ReadyCheckConfig{HTTP: String("")},
+			&ReadyCheckConfig{HTTP: String("")},
+		},
+		{
+			"http_empty_one",
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+		},
+		{
+			"http_empty_two",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+		},
+		{
+			"http_same",
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+			&ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080/ready")},
+		},
+		{
+			"interval_overrides",
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+			&ReadyCheckConfig{Interval: TimeDuration(5 * time.Second)},
+			&ReadyCheckConfig{Interval: TimeDuration(5 * time.Second)},
+		},
+		{
+			"interval_empty_one",
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+		},
+		{
+			"interval_empty_two",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+		},
+		{
+			"interval_same",
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+			&ReadyCheckConfig{Interval: TimeDuration(2 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestReadyCheckConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ReadyCheckConfig
+		r    *ReadyCheckConfig
+	}{
+		{
+			"empty",
+			&ReadyCheckConfig{},
+			&ReadyCheckConfig{
+				Enabled:     Bool(false),
+				GRPC:        String(""),
+				GRPCService: String(""),
+				HTTP:        String(""),
+				Interval:    TimeDuration(DefaultReadyCheckInterval),
+			},
+		},
+		{
+			"with_grpc",
+			&ReadyCheckConfig{
+				GRPC: String("127.0.0.1:9000"),
+			},
+			&ReadyCheckConfig{
+				Enabled:     Bool(true),
+				GRPC:        String("127.0.0.1:9000"),
+				GRPCService: String(""),
+				HTTP:        String(""),
+				Interval:    TimeDuration(DefaultReadyCheckInterval),
+			},
+		},
+		{
+			"with_http",
+			&ReadyCheckConfig{
+				HTTP: String("http://127.0.0.1:8080/ready"),
+			},
+			&ReadyCheckConfig{
+				Enabled:     Bool(true),
+				GRPC:        String(""),
+				GRPCService: String(""),
+				HTTP:        String("http://127.0.0.1:8080/ready"),
+				Interval:    TimeDuration(DefaultReadyCheckInterval),
+			},
+		},
+		{
+			"disabled",
+			&ReadyCheckConfig{
+				Enabled: Bool(false),
+				HTTP:    String("http://127.0.0.1:8080/ready"),
+			},
+			&ReadyCheckConfig{
+				Enabled:     Bool(false),
+				GRPC:        String(""),
+				GRPCService: String(""),
+				HTTP:        String("http://127.0.0.1:8080/ready"),
+				Interval:    TimeDuration(DefaultReadyCheckInterval),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import "fmt"
+
+// RemoteConfigConfig is used to load template configuration from Consul's KV
+// store. Each key under the prefix holds HCL or JSON containing one or more
+// template blocks, which are added to the templates from the local
+// configuration. A change to any key under the prefix reloads the
+// configuration.
+type RemoteConfigConfig struct {
+	// Enabled controls if remote configuration is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Prefix is the KV prefix to load template configuration from.
+	Prefix *string `mapstructure:"prefix"`
+}
+
+// DefaultRemoteConfigConfig returns a configuration that is populated with the
+// default values.
+func DefaultRemoteConfigConfig() *RemoteConfigConfig {
+	return &RemoteConfigConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *RemoteConfigConfig) Copy() *RemoteConfigConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o RemoteConfigConfig
+	o.Enabled = c.Enabled
+	o.Prefix = c.Prefix
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *RemoteConfigConfig) Merge(o *RemoteConfigConfig) *RemoteConfigConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Prefix != nil {
+		r.Prefix = o.Prefix
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *RemoteConfigConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Prefix))
+	}
+
+	if c.Prefix == nil {
+		c.Prefix = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *RemoteConfigConfig) GoString() string {
+	if c == nil {
+		return "(*RemoteConfigConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&RemoteConfigConfig{"+
+		"Enabled:%s, "+
+		"Prefix:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.Prefix),
+	)
+}
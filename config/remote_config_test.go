@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRemoteConfigConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RemoteConfigConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&RemoteConfigConfig{},
+		},
+		{
+			"same_enabled",
+			&RemoteConfigConfig{
+				Enabled: Bool(true),
+				Prefix:  String("foo/"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestRemoteConfigConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *RemoteConfigConfig
+		b    *RemoteConfigConfig
+		r    *RemoteConfigConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{},
+		},
+		{
+			"nil_b",
+			&RemoteConfigConfig{},
+			nil,
+			&RemoteConfigConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{},
+		},
+		{
+			"enabled_overrides",
+			&RemoteConfigConfig{Enabled: Bool(true)},
+			&RemoteConfigConfig{Enabled: Bool(false)},
+			&RemoteConfigConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&RemoteConfigConfig{Enabled: Bool(true)},
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{Enabled: Bool(true)},
+			&RemoteConfigConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&RemoteConfigConfig{Enabled: Bool(true)},
+			&RemoteConfigConfig{Enabled: Bool(true)},
+			&RemoteConfigConfig{Enabled: Bool(true)},
+		},
+		{
+			"prefix_overrides",
+			&RemoteConfigConfig{Prefix: String("foo/")},
+			&RemoteConfigConfig{Prefix: String("")},
+			&RemoteConfigConfig{Prefix: String("")},
+		},
+		{
+			"prefix_empty_one",
+			&RemoteConfigConfig{Prefix: String("foo/")},
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{Prefix: String("foo/")},
+		},
+		{
+			"prefix_empty_two",
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{Prefix: String("foo/")},
+			&RemoteConfigConfig{Prefix: String("foo/")},
+		},
+		{
+			"prefix_same",
+			&RemoteConfigConfig{Prefix: String("foo/")},
+			&RemoteConfigConfig{Prefix: String("foo/")},
+			&RemoteConfigConfig{Prefix: String("foo/")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestRemoteConfigConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *RemoteConfigConfig
+		r    *RemoteConfigConfig
+	}{
+		{
+			"empty",
+			&RemoteConfigConfig{},
+			&RemoteConfigConfig{
+				Enabled: Bool(false),
+				Prefix:  String(""),
+			},
+		},
+		{
+			"with_prefix",
+			&RemoteConfigConfig{
+				Prefix: String("foo/"),
+			},
+			&RemoteConfigConfig{
+				Enabled: Bool(true),
+				Prefix:  String("foo/"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import "fmt"
+
+// ReportConfig is the configuration for the machine-readable report emitted
+// after each run.
+type ReportConfig struct {
+	// Enabled controls if a report is emitted after each run. The latest report
+	// is always available from the status server, if it is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Path is the file where each report is written as JSON. If the path is a
+	// named pipe (FIFO), each report is written to it as a single line.
+	// Otherwise the file is atomically replaced with the latest report.
+	Path *string `mapstructure:"path"`
+}
+
+// DefaultReportConfig returns a configuration that is populated with the
+// default values.
+func DefaultReportConfig() *ReportConfig {
+	return &ReportConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ReportConfig) Copy() *ReportConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ReportConfig
+	o.Enabled = c.Enabled
+	o.Path = c.Path
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ReportConfig) Merge(o *ReportConfig) *ReportConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ReportConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Path))
+	}
+
+	if c.Path == nil {
+		c.Path = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ReportConfig) GoString() string {
+	if c == nil {
+		return "(*ReportConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ReportConfig{"+
+		"Enabled:%s, "+
+		"Path:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.Path),
+	)
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestReportConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ReportConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ReportConfig{},
+		},
+		{
+			"same_enabled",
+			&ReportConfig{
+				Enabled: Bool(true),
+				Path:    String("/var/run/report.json"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestReportConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ReportConfig
+		b    *ReportConfig
+		r    *ReportConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ReportConfig{},
+			&ReportConfig{},
+		},
+		{
+			"nil_b",
+			&ReportConfig{},
+			nil,
+			&ReportConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ReportConfig{},
+			&ReportConfig{},
+			&ReportConfig{},
+		},
+		{
+			"enabled_overrides",
+			&ReportConfig{Enabled: Bool(true)},
+			&ReportConfig{Enabled: Bool(false)},
+			&ReportConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&ReportConfig{Enabled: Bool(true)},
+			&ReportConfig{},
+			&ReportConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&ReportConfig{},
+			&ReportConfig{Enabled: Bool(true)},
+			&ReportConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&ReportConfig{Enabled: Bool(true)},
+			&ReportConfig{Enabled: Bool(true)},
+			&ReportConfig{Enabled: Bool(true)},
+		},
+		{
+			"path_overrides",
+			&ReportConfig{Path: String("/var/run/report.json")},
+			&ReportConfig{Path: String("")},
+			&ReportConfig{Path: String("")},
+		},
+		{
+			"path_empty_one",
+			&ReportConfig{Path: String("/var/run/report.json")},
+			&ReportConfig{},
+			&ReportConfig{Path: String("/var/run/report.json")},
+		},
+		{
+			"path_empty_two",
+			&ReportConfig{},
+			&ReportConfig{Path: String("/var/run/report.json")},
+			&ReportConfig{Path: String("/var/run/report.json")},
+		},
+		{
+			"path_same",
+			&ReportConfig{Path: String("/var/run/report.json")},
+			&ReportConfig{Path: String("/var/run/report.json")},
+			&ReportConfig{Path: String("/var/run/report.json")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestReportConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ReportConfig
+		r    *ReportConfig
+	}{
+		{
+			"empty",
+			&ReportConfig{},
+			&ReportConfig{
+				Enabled: Bool(false),
+				Path:    String(""),
+			},
+		},
+		{
+			"with_path",
+			&ReportConfig{
+				Path: String("/var/run/report.json"),
+			},
+			&ReportConfig{
+				Enabled: Bool(true),
+				Path:    String("/var/run/report.json"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
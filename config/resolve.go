@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultResolveTTL is the default amount of time resolved addresses are
+	// cached.
+	DefaultResolveTTL = 30 * time.Second
+
+	// DefaultResolveTimeout is the default amount of time to wait for a lookup.
+	DefaultResolveTimeout = 5 * time.Second
+
+	// ResolveFamilyV4 and ResolveFamilyV6 are the address families which can
+	// be preferred.
+	ResolveFamilyV4 = "v4"
+	ResolveFamilyV6 = "v6"
+)
+
+// ResolveConfig is the configuration for resolving the addresses returned by
+// the queries which ask for them to be resolved, such as "service.web?resolve",
+// and for choosing among the addresses of dual-stack nodes and services.
+type ResolveConfig struct {
+	// PreferFamily is the address family, "v4" or "v6", which the
+	// preferAddress template function chooses when there are addresses of
+	// both. The default value is empty, which chooses the first address.
+	PreferFamily *string `mapstructure:"prefer_family"`
+
+	// Timeout is the amount of time to wait for a lookup before giving up.
+	Timeout *time.Duration `mapstructure:"timeout"`
+
+	// TTL is the amount of time resolved addresses are cached. The queries
+	// which resolve addresses are repeated at least this often, so changes to
+	// the records are picked up even if nothing changes in Consul.
+	TTL *time.Duration `mapstructure:"ttl"`
+}
+
+// DefaultResolveConfig returns a configuration that is populated with the
+// default values.
+func DefaultResolveConfig() *ResolveConfig {
+	return &ResolveConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ResolveConfig) Copy() *ResolveConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ResolveConfig
+	o.PreferFamily = c.PreferFamily
+	o.Timeout = c.Timeout
+	o.TTL = c.TTL
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ResolveConfig) Merge(o *ResolveConfig) *ResolveConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.PreferFamily != nil {
+		r.PreferFamily = o.PreferFamily
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	if o.TTL != nil {
+		r.TTL = o.TTL
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ResolveConfig) Finalize() {
+	if c.PreferFamily == nil {
+		c.PreferFamily = String("")
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultResolveTimeout)
+	}
+
+	if c.TTL == nil {
+		c.TTL = TimeDuration(DefaultResolveTTL)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ResolveConfig) GoString() string {
+	if c == nil {
+		return "(*ResolveConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ResolveConfig{"+
+		"PreferFamily:%s, "+
+		"Timeout:%s, "+
+		"TTL:%s"+
+		"}",
+		StringGoString(c.PreferFamily),
+		TimeDurationGoString(c.Timeout),
+		TimeDurationGoString(c.TTL),
+	)
+}
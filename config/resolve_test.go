@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ResolveConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ResolveConfig{},
+		},
+		{
+			"full",
+			&ResolveConfig{
+				PreferFamily: String("v6"),
+				Timeout:      TimeDuration(2 * time.Second),
+				TTL:          TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestResolveConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ResolveConfig
+		b    *ResolveConfig
+		r    *ResolveConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ResolveConfig{},
+			&ResolveConfig{},
+		},
+		{
+			"nil_b",
+			&ResolveConfig{},
+			nil,
+			&ResolveConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ResolveConfig{},
+			&ResolveConfig{},
+			&ResolveConfig{},
+		},
+		{
+			"prefer_family_overrides",
+			&ResolveConfig{PreferFamily: String("v6")},
+			&ResolveConfig{PreferFamily: String("")},
+			&ResolveConfig{PreferFamily: String("")},
+		},
+		{
+			"prefer_family_empty_one",
+			&ResolveConfig{PreferFamily: String("v6")},
+			&ResolveConfig{},
+			&ResolveConfig{PreferFamily: String("v6")},
+		},
+		{
+			"prefer_family_empty_two",
+			&ResolveConfig{},
+			&ResolveConfig{PreferFamily: String("v6")},
+			&ResolveConfig{PreferFamily: String("v6")},
+		},
+		{
+			"prefer_family_same",
+			&ResolveConfig{PreferFamily: String("v6")},
+			&ResolveConfig{PreferFamily: String("v6")},
+			&ResolveConfig{PreferFamily: String("v6")},
+		},
+		{
+			"timeout_overrides",
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ResolveConfig{Timeout: TimeDuration(0)},
+			&ResolveConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ResolveConfig{},
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&ResolveConfig{},
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+			&ResolveConfig{Timeout: TimeDuration(2 * time.Second)},
+		},
+		{
+			"ttl_overrides",
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+			&ResolveConfig{TTL: TimeDuration(0)},
+			&ResolveConfig{TTL: TimeDuration(0)},
+		},
+		{
+			"ttl_empty_one",
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+			&ResolveConfig{},
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+		{
+			"ttl_empty_two",
+			&ResolveConfig{},
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+		{
+			"ttl_same",
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+			&ResolveConfig{TTL: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestResolveConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *ResolveConfig
+		r    *ResolveConfig
+	}{
+		{
+			"empty",
+			&ResolveConfig{},
+			&ResolveConfig{
+				PreferFamily: String(""),
+				Timeout:      TimeDuration(DefaultResolveTimeout),
+				TTL:          TimeDuration(DefaultResolveTTL),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretRotationConfig restricts when new versions of the secrets of a
+// template are written to disk. A new version of a matching secret, such as
+// new dynamic database credentials, is held back and the template keeps the
+// version it was rendered with until the rotation window, unless the held
+// version's lease is about to expire. This keeps credential changes away from
+// peak hours.
+type SecretRotationConfig struct {
+	// Path is the path of the secrets read by the secret and kv2 functions
+	// this applies to, such as "database/creds/app". It may contain the
+	// wildcards of path.Match, such as "database/creds/*". The default value
+	// is empty, which applies to all the secrets of the template.
+	Path *string `mapstructure:"path"`
+
+	// RotateWithin is the daily window during which new versions of the
+	// secrets are written, as "HH:MM-HH:MM" in local time, such as
+	// "02:00-04:00". A window may wrap around midnight.
+	RotateWithin *string `mapstructure:"rotate_within"`
+}
+
+// DefaultSecretRotationConfig returns a configuration that is populated with the
+// default values.
+func DefaultSecretRotationConfig() *SecretRotationConfig {
+	return &SecretRotationConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *SecretRotationConfig) Copy() *SecretRotationConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o SecretRotationConfig
+	o.Path = c.Path
+	o.RotateWithin = c.RotateWithin
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *SecretRotationConfig) Merge(o *SecretRotationConfig) *SecretRotationConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	if o.RotateWithin != nil {
+		r.RotateWithin = o.RotateWithin
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *SecretRotationConfig) Finalize() {
+	if c.Path == nil {
+		c.Path = String("")
+	}
+
+	if c.RotateWithin == nil {
+		c.RotateWithin = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *SecretRotationConfig) GoString() string {
+	if c == nil {
+		return "(*SecretRotationConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&SecretRotationConfig{"+
+		"Path:%s, "+
+		"RotateWithin:%s"+
+		"}",
+		StringGoString(c.Path),
+		StringGoString(c.RotateWithin),
+	)
+}
+
+// SecretRotationConfigs is a collection of SecretRotationConfigs.
+type SecretRotationConfigs []*SecretRotationConfig
+
+// DefaultSecretRotationConfigs returns a configuration that is populated with the
+// default values.
+func DefaultSecretRotationConfigs() *SecretRotationConfigs {
+	return &SecretRotationConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *SecretRotationConfigs) Copy() *SecretRotationConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(SecretRotationConfigs, len(*c))
+	for i, t := range *c {
+		o[i] = t.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *SecretRotationConfigs) Merge(o *SecretRotationConfigs) *SecretRotationConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	*r = append(*r, *o.Copy()...)
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *SecretRotationConfigs) Finalize() {
+	for _, t := range *c {
+		t.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *SecretRotationConfigs) GoString() string {
+	if c == nil {
+		return "(*SecretRotationConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, t := range *c {
+		s[i] = t.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
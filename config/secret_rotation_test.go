@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSecretRotationConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SecretRotationConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&SecretRotationConfig{},
+		},
+		{
+			"copy",
+			&SecretRotationConfig{
+				Path:         String("database/creds/app"),
+				RotateWithin: String("02:00-04:00"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestSecretRotationConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SecretRotationConfig
+		b    *SecretRotationConfig
+		r    *SecretRotationConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&SecretRotationConfig{},
+			&SecretRotationConfig{},
+		},
+		{
+			"nil_b",
+			&SecretRotationConfig{},
+			nil,
+			&SecretRotationConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&SecretRotationConfig{},
+			&SecretRotationConfig{},
+			&SecretRotationConfig{},
+		},
+		{
+			"path_overrides",
+			&SecretRotationConfig{Path: String("database/creds/app")},
+			&SecretRotationConfig{Path: String("database/creds/*")},
+			&SecretRotationConfig{Path: String("database/creds/*")},
+		},
+		{
+			"path_empty_one",
+			&SecretRotationConfig{Path: String("database/creds/app")},
+			&SecretRotationConfig{},
+			&SecretRotationConfig{Path: String("database/creds/app")},
+		},
+		{
+			"path_empty_two",
+			&SecretRotationConfig{},
+			&SecretRotationConfig{Path: String("database/creds/app")},
+			&SecretRotationConfig{Path: String("database/creds/app")},
+		},
+		{
+			"path_same",
+			&SecretRotationConfig{Path: String("database/creds/app")},
+			&SecretRotationConfig{Path: String("database/creds/app")},
+			&SecretRotationConfig{Path: String("database/creds/app")},
+		},
+		{
+			"rotate_within_overrides",
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+			&SecretRotationConfig{RotateWithin: String("")},
+			&SecretRotationConfig{RotateWithin: String("")},
+		},
+		{
+			"rotate_within_empty_one",
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+			&SecretRotationConfig{},
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+		},
+		{
+			"rotate_within_empty_two",
+			&SecretRotationConfig{},
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+		},
+		{
+			"rotate_within_same",
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+			&SecretRotationConfig{RotateWithin: String("02:00-04:00")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestSecretRotationConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *SecretRotationConfig
+		r    *SecretRotationConfig
+	}{
+		{
+			"empty",
+			&SecretRotationConfig{},
+			&SecretRotationConfig{
+				Path:         String(""),
+				RotateWithin: String(""),
+			},
+		},
+		{
+			"with_rotate_within",
+			&SecretRotationConfig{
+				Path:         String("database/creds/*"),
+				RotateWithin: String("02:00-04:00"),
+			},
+			&SecretRotationConfig{
+				Path:         String("database/creds/*"),
+				RotateWithin: String("02:00-04:00"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
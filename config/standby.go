@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// StandbyConfig is the configuration for hot standby mode. A runner in standby
+// renders its templates but holds their commands and the reload of the child
+// process until it is promoted, so files are kept warm on passive members of
+// a cluster without touching the services which read them.
+type StandbyConfig struct {
+	// Enabled controls if the runner starts in standby.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Key is the Consul KV key which promotes the runner once its value is
+	// "true".
+	Key *string `mapstructure:"key"`
+
+	// Signal is the signal which promotes the runner.
+	Signal *os.Signal `mapstructure:"signal"`
+}
+
+// DefaultStandbyConfig returns a configuration that is populated with the
+// default values.
+func DefaultStandbyConfig() *StandbyConfig {
+	return &StandbyConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *StandbyConfig) Copy() *StandbyConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o StandbyConfig
+	o.Enabled = c.Enabled
+	o.Key = c.Key
+	o.Signal = c.Signal
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *StandbyConfig) Merge(o *StandbyConfig) *StandbyConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Key != nil {
+		r.Key = o.Key
+	}
+
+	if o.Signal != nil {
+		r.Signal = o.Signal
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers. The signal is left nil when it is
+// not set, since there is no default promotion signal.
+func (c *StandbyConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Key) || c.Signal != nil)
+	}
+
+	if c.Key == nil {
+		c.Key = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *StandbyConfig) GoString() string {
+	if c == nil {
+		return "(*StandbyConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&StandbyConfig{"+
+		"Enabled:%s, "+
+		"Key:%s, "+
+		"Signal:%s"+
+		"}",
+		BoolGoString(c.Enabled),
+		StringGoString(c.Key),
+		SignalGoString(c.Signal),
+	)
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestStandbyConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StandbyConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&StandbyConfig{},
+		},
+		{
+			"same_enabled",
+			&StandbyConfig{
+				Enabled: Bool(true),
+				Key:     String("service/web/active"),
+				Signal:  Signal(syscall.SIGUSR1),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestStandbyConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StandbyConfig
+		b    *StandbyConfig
+		r    *StandbyConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&StandbyConfig{},
+			&StandbyConfig{},
+		},
+		{
+			"nil_b",
+			&StandbyConfig{},
+			nil,
+			&StandbyConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&StandbyConfig{},
+			&StandbyConfig{},
+			&StandbyConfig{},
+		},
+		{
+			"enabled_overrides",
+			&StandbyConfig{Enabled: Bool(true)},
+			&StandbyConfig{Enabled: Bool(false)},
+			&StandbyConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&StandbyConfig{Enabled: Bool(true)},
+			&StandbyConfig{},
+			&StandbyConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&StandbyConfig{},
+			&StandbyConfig{Enabled: Bool(true)},
+			&StandbyConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&StandbyConfig{Enabled: Bool(true)},
+			&StandbyConfig{Enabled: Bool(true)},
+			&StandbyConfig{Enabled: Bool(true)},
+		},
+		{
+			"key_overrides",
+			&StandbyConfig{Key: String("service/web/active")},
+			&StandbyConfig{Key: String("")},
+			&StandbyConfig{Key: String("")},
+		},
+		{
+			"key_empty_one",
+			&StandbyConfig{Key: String("service/web/active")},
+			&StandbyConfig{},
+			&StandbyConfig{Key: String("service/web/active")},
+		},
+		{
+			"key_empty_two",
+			&StandbyConfig{},
+			&StandbyConfig{Key: String("service/web/active")},
+			&StandbyConfig{Key: String("service/web/active")},
+		},
+		{
+			"key_same",
+			&StandbyConfig{Key: String("service/web/active")},
+			&StandbyConfig{Key: String("service/web/active")},
+			&StandbyConfig{Key: String("service/web/active")},
+		},
+		{
+			"signal_overrides",
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR2)},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR2)},
+		},
+		{
+			"signal_empty_one",
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+			&StandbyConfig{},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+		},
+		{
+			"signal_empty_two",
+			&StandbyConfig{},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+		},
+		{
+			"signal_same",
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+			&StandbyConfig{Signal: Signal(syscall.SIGUSR1)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestStandbyConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *StandbyConfig
+		r    *StandbyConfig
+	}{
+		{
+			"empty",
+			&StandbyConfig{},
+			&StandbyConfig{
+				Enabled: Bool(false),
+				Key:     String(""),
+			},
+		},
+		{
+			"with_key",
+			&StandbyConfig{
+				Key: String("service/web/active"),
+			},
+			&StandbyConfig{
+				Enabled: Bool(true),
+				Key:     String("service/web/active"),
+			},
+		},
+		{
+			"with_signal",
+			&StandbyConfig{
+				Signal: Signal(syscall.SIGUSR1),
+			},
+			&StandbyConfig{
+				Enabled: Bool(true),
+				Key:     String(""),
+				Signal:  Signal(syscall.SIGUSR1),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultStatusAddress is the default address the status server listens on.
+	DefaultStatusAddress = "127.0.0.1:8558"
+
+	// DefaultStatusLiveLoopTimeout is the default maximum amount of time the
+	// runner's event loop may go without making progress before the liveness
+	// probe fails.
+	DefaultStatusLiveLoopTimeout = 1 * time.Minute
+
+	// DefaultStatusLiveWatcherTimeout is the default maximum amount of time the
+	// watcher may report errors without returning any data before the liveness
+	// probe fails.
+	DefaultStatusLiveWatcherTimeout = 5 * time.Minute
+)
+
+// StatusConfig is used to configure the HTTP status server, which serves the
+// readiness and liveness probes of the runner.
+type StatusConfig struct {
+	// Address is the address the status server listens on.
+	Address *string `mapstructure:"address"`
+
+	// Enabled controls if the status server is started.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// Expvar controls if the exported variables of the process, such as its
+	// memory statistics, are served at /debug/vars.
+	Expvar *bool `mapstructure:"expvar"`
+
+	// Live is the criteria for the liveness probe.
+	Live *StatusLiveConfig `mapstructure:"live"`
+
+	// Pprof controls if the runtime profiles of the process, such as the heap,
+	// goroutine and CPU profiles, are served at /debug/pprof/.
+	Pprof *bool `mapstructure:"pprof"`
+
+	// Ready is the criteria for the readiness probe.
+	Ready *StatusReadyConfig `mapstructure:"ready"`
+}
+
+// DefaultStatusConfig returns a configuration that is populated with the
+// default values.
+func DefaultStatusConfig() *StatusConfig {
+	return &StatusConfig{
+		Live:  DefaultStatusLiveConfig(),
+		Ready: DefaultStatusReadyConfig(),
+	}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *StatusConfig) Copy() *StatusConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o StatusConfig
+	o.Address = c.Address
+	o.Enabled = c.Enabled
+	o.Expvar = c.Expvar
+
+	if c.Live != nil {
+		o.Live = c.Live.Copy()
+	}
+
+	o.Pprof = c.Pprof
+
+	if c.Ready != nil {
+		o.Ready = c.Ready.Copy()
+	}
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *StatusConfig) Merge(o *StatusConfig) *StatusConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Address != nil {
+		r.Address = o.Address
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.Expvar != nil {
+		r.Expvar = o.Expvar
+	}
+
+	if o.Live != nil {
+		r.Live = r.Live.Merge(o.Live)
+	}
+
+	if o.Pprof != nil {
+		r.Pprof = o.Pprof
+	}
+
+	if o.Ready != nil {
+		r.Ready = r.Ready.Merge(o.Ready)
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *StatusConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(false ||
+			StringPresent(c.Address))
+	}
+
+	if c.Address == nil {
+		c.Address = String(DefaultStatusAddress)
+	}
+
+	if c.Expvar == nil {
+		c.Expvar = Bool(false)
+	}
+
+	if c.Live == nil {
+		c.Live = DefaultStatusLiveConfig()
+	}
+	c.Live.Finalize()
+
+	if c.Pprof == nil {
+		c.Pprof = Bool(false)
+	}
+
+	if c.Ready == nil {
+		c.Ready = DefaultStatusReadyConfig()
+	}
+	c.Ready.Finalize()
+}
+
+// GoString defines the printable version of this struct.
+func (c *StatusConfig) GoString() string {
+	if c == nil {
+		return "(*StatusConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&StatusConfig{"+
+		"Address:%s, "+
+		"Enabled:%s, "+
+		"Expvar:%s, "+
+		"Live:%#v, "+
+		"Pprof:%s, "+
+		"Ready:%#v"+
+		"}",
+		StringGoString(c.Address),
+		BoolGoString(c.Enabled),
+		BoolGoString(c.Expvar),
+		c.Live,
+		BoolGoString(c.Pprof),
+		c.Ready,
+	)
+}
+
+// StatusLiveConfig is the criteria for the liveness probe. The probe fails
+// when the runner is stuck and should be restarted.
+type StatusLiveConfig struct {
+	// LoopTimeout is the maximum amount of time the runner's event loop may go
+	// without making progress. A value of 0 disables this check.
+	LoopTimeout *time.Duration `mapstructure:"loop_timeout"`
+
+	// WatcherTimeout is the maximum amount of time the watcher may report
+	// errors without returning any data. A value of 0 disables this check.
+	WatcherTimeout *time.Duration `mapstructure:"watcher_timeout"`
+}
+
+// DefaultStatusLiveConfig returns a configuration that is populated with the
+// default values.
+func DefaultStatusLiveConfig() *StatusLiveConfig {
+	return &StatusLiveConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *StatusLiveConfig) Copy() *StatusLiveConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o StatusLiveConfig
+	o.LoopTimeout = c.LoopTimeout
+	o.WatcherTimeout = c.WatcherTimeout
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *StatusLiveConfig) Merge(o *StatusLiveConfig) *StatusLiveConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.LoopTimeout != nil {
+		r.LoopTimeout = o.LoopTimeout
+	}
+
+	if o.WatcherTimeout != nil {
+		r.WatcherTimeout = o.WatcherTimeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *StatusLiveConfig) Finalize() {
+	if c.LoopTimeout == nil {
+		c.LoopTimeout = TimeDuration(DefaultStatusLiveLoopTimeout)
+	}
+
+	if c.WatcherTimeout == nil {
+		c.WatcherTimeout = TimeDuration(DefaultStatusLiveWatcherTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *StatusLiveConfig) GoString() string {
+	if c == nil {
+		return "(*StatusLiveConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&StatusLiveConfig{"+
+		"LoopTimeout:%s, "+
+		"WatcherTimeout:%s"+
+		"}",
+		TimeDurationGoString(c.LoopTimeout),
+		TimeDurationGoString(c.WatcherTimeout),
+	)
+}
+
+// StatusReadyConfig is the criteria for the readiness probe. The probe fails
+// until the runner is able to serve its rendered output.
+type StatusReadyConfig struct {
+	// Child requires the child process to be running, if exec mode is
+	// configured.
+	Child *bool `mapstructure:"child"`
+
+	// Commands requires the ready checks of the processes started by the
+	// commands of templates to succeed since the commands last ran.
+	Commands *bool `mapstructure:"commands"`
+
+	// Templates requires all templates to have rendered at least once.
+	Templates *bool `mapstructure:"templates"`
+}
+
+// DefaultStatusReadyConfig returns a configuration that is populated with the
+// default values.
+func DefaultStatusReadyConfig() *StatusReadyConfig {
+	return &StatusReadyConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *StatusReadyConfig) Copy() *StatusReadyConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o StatusReadyConfig
+	o.Child = c.Child
+	o.Commands = c.Commands
+	o.Templates = c.Templates
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *StatusReadyConfig) Merge(o *StatusReadyConfig) *StatusReadyConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Child != nil {
+		r.Child = o.Child
+	}
+
+	if o.Commands != nil {
+		r.Commands = o.Commands
+	}
+
+	if o.Templates != nil {
+		r.Templates = o.Templates
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *StatusReadyConfig) Finalize() {
+	if c.Child == nil {
+		c.Child = Bool(true)
+	}
+
+	if c.Commands == nil {
+		c.Commands = Bool(true)
+	}
+
+	if c.Templates == nil {
+		c.Templates = Bool(true)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *StatusReadyConfig) GoString() string {
+	if c == nil {
+		return "(*StatusReadyConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&StatusReadyConfig{"+
+		"Child:%s, "+
+		"Commands:%s, "+
+		"Templates:%s"+
+		"}",
+		BoolGoString(c.Child),
+		BoolGoString(c.Commands),
+		BoolGoString(c.Templates),
+	)
+}
@@ -0,0 +1,495 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatusConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StatusConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&StatusConfig{},
+		},
+		{
+			"copy",
+			&StatusConfig{
+				Address: String("0.0.0.0:8558"),
+				Enabled: Bool(true),
+				Expvar:  Bool(true),
+				Live: &StatusLiveConfig{
+					LoopTimeout: TimeDuration(30 * time.Second),
+				},
+				Pprof: Bool(true),
+				Ready: &StatusReadyConfig{
+					Child: Bool(false),
+				},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestStatusConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StatusConfig
+		b    *StatusConfig
+		r    *StatusConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&StatusConfig{},
+			&StatusConfig{},
+		},
+		{
+			"nil_b",
+			&StatusConfig{},
+			nil,
+			&StatusConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&StatusConfig{},
+			&StatusConfig{},
+			&StatusConfig{},
+		},
+		{
+			"address_overrides",
+			&StatusConfig{Address: String("a:1")},
+			&StatusConfig{Address: String("b:2")},
+			&StatusConfig{Address: String("b:2")},
+		},
+		{
+			"address_empty_one",
+			&StatusConfig{Address: String("a:1")},
+			&StatusConfig{},
+			&StatusConfig{Address: String("a:1")},
+		},
+		{
+			"address_empty_two",
+			&StatusConfig{},
+			&StatusConfig{Address: String("a:1")},
+			&StatusConfig{Address: String("a:1")},
+		},
+		{
+			"address_same",
+			&StatusConfig{Address: String("a:1")},
+			&StatusConfig{Address: String("a:1")},
+			&StatusConfig{Address: String("a:1")},
+		},
+		{
+			"enabled_overrides",
+			&StatusConfig{Enabled: Bool(true)},
+			&StatusConfig{Enabled: Bool(false)},
+			&StatusConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&StatusConfig{Enabled: Bool(true)},
+			&StatusConfig{},
+			&StatusConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&StatusConfig{},
+			&StatusConfig{Enabled: Bool(true)},
+			&StatusConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&StatusConfig{Enabled: Bool(true)},
+			&StatusConfig{Enabled: Bool(true)},
+			&StatusConfig{Enabled: Bool(true)},
+		},
+		{
+			"expvar_overrides",
+			&StatusConfig{Expvar: Bool(true)},
+			&StatusConfig{Expvar: Bool(false)},
+			&StatusConfig{Expvar: Bool(false)},
+		},
+		{
+			"expvar_empty_one",
+			&StatusConfig{Expvar: Bool(true)},
+			&StatusConfig{},
+			&StatusConfig{Expvar: Bool(true)},
+		},
+		{
+			"expvar_empty_two",
+			&StatusConfig{},
+			&StatusConfig{Expvar: Bool(true)},
+			&StatusConfig{Expvar: Bool(true)},
+		},
+		{
+			"expvar_same",
+			&StatusConfig{Expvar: Bool(true)},
+			&StatusConfig{Expvar: Bool(true)},
+			&StatusConfig{Expvar: Bool(true)},
+		},
+		{
+			"live_merges",
+			&StatusConfig{Live: &StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)}},
+			&StatusConfig{Live: &StatusLiveConfig{WatcherTimeout: TimeDuration(20 * time.Second)}},
+			&StatusConfig{Live: &StatusLiveConfig{
+				LoopTimeout:    TimeDuration(10 * time.Second),
+				WatcherTimeout: TimeDuration(20 * time.Second),
+			}},
+		},
+		{
+			"pprof_overrides",
+			&StatusConfig{Pprof: Bool(true)},
+			&StatusConfig{Pprof: Bool(false)},
+			&StatusConfig{Pprof: Bool(false)},
+		},
+		{
+			"pprof_empty_one",
+			&StatusConfig{Pprof: Bool(true)},
+			&StatusConfig{},
+			&StatusConfig{Pprof: Bool(true)},
+		},
+		{
+			"pprof_empty_two",
+			&StatusConfig{},
+			&StatusConfig{Pprof: Bool(true)},
+			&StatusConfig{Pprof: Bool(true)},
+		},
+		{
+			"pprof_same",
+			&StatusConfig{Pprof: Bool(true)},
+			&StatusConfig{Pprof: Bool(true)},
+			&StatusConfig{Pprof: Bool(true)},
+		},
+		{
+			"ready_merges",
+			&StatusConfig{Ready: &StatusReadyConfig{Child: Bool(false)}},
+			&StatusConfig{Ready: &StatusReadyConfig{Templates: Bool(false)}},
+			&StatusConfig{Ready: &StatusReadyConfig{
+				Child:     Bool(false),
+				Templates: Bool(false),
+			}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestStatusConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *StatusConfig
+		r    *StatusConfig
+	}{
+		{
+			"empty",
+			&StatusConfig{},
+			&StatusConfig{
+				Address: String(DefaultStatusAddress),
+				Enabled: Bool(false),
+				Expvar:  Bool(false),
+				Live: &StatusLiveConfig{
+					LoopTimeout:    TimeDuration(DefaultStatusLiveLoopTimeout),
+					WatcherTimeout: TimeDuration(DefaultStatusLiveWatcherTimeout),
+				},
+				Pprof: Bool(false),
+				Ready: &StatusReadyConfig{
+					Child:     Bool(true),
+					Commands:  Bool(true),
+					Templates: Bool(true),
+				},
+			},
+		},
+		{
+			"with_address",
+			&StatusConfig{
+				Address: String("0.0.0.0:8558"),
+			},
+			&StatusConfig{
+				Address: String("0.0.0.0:8558"),
+				Enabled: Bool(true),
+				Expvar:  Bool(false),
+				Live: &StatusLiveConfig{
+					LoopTimeout:    TimeDuration(DefaultStatusLiveLoopTimeout),
+					WatcherTimeout: TimeDuration(DefaultStatusLiveWatcherTimeout),
+				},
+				Pprof: Bool(false),
+				Ready: &StatusReadyConfig{
+					Child:     Bool(true),
+					Commands:  Bool(true),
+					Templates: Bool(true),
+				},
+			},
+		},
+		{
+			"with_probes",
+			&StatusConfig{
+				Live: &StatusLiveConfig{
+					LoopTimeout: TimeDuration(0),
+				},
+				Ready: &StatusReadyConfig{
+					Child: Bool(false),
+				},
+			},
+			&StatusConfig{
+				Address: String(DefaultStatusAddress),
+				Enabled: Bool(false),
+				Expvar:  Bool(false),
+				Live: &StatusLiveConfig{
+					LoopTimeout:    TimeDuration(0),
+					WatcherTimeout: TimeDuration(DefaultStatusLiveWatcherTimeout),
+				},
+				Pprof: Bool(false),
+				Ready: &StatusReadyConfig{
+					Child:     Bool(false),
+					Commands:  Bool(true),
+					Templates: Bool(true),
+				},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
+
+func TestStatusLiveConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StatusLiveConfig
+		b    *StatusLiveConfig
+		r    *StatusLiveConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&StatusLiveConfig{},
+			&StatusLiveConfig{},
+		},
+		{
+			"nil_b",
+			&StatusLiveConfig{},
+			nil,
+			&StatusLiveConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&StatusLiveConfig{},
+			&StatusLiveConfig{},
+			&StatusLiveConfig{},
+		},
+		{
+			"loop_timeout_overrides",
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(20 * time.Second)},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(20 * time.Second)},
+		},
+		{
+			"loop_timeout_empty_one",
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"loop_timeout_empty_two",
+			&StatusLiveConfig{},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"loop_timeout_same",
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{LoopTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"watcher_timeout_overrides",
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(20 * time.Second)},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(20 * time.Second)},
+		},
+		{
+			"watcher_timeout_empty_one",
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"watcher_timeout_empty_two",
+			&StatusLiveConfig{},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"watcher_timeout_same",
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+			&StatusLiveConfig{WatcherTimeout: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestStatusReadyConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StatusReadyConfig
+		b    *StatusReadyConfig
+		r    *StatusReadyConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&StatusReadyConfig{},
+			&StatusReadyConfig{},
+		},
+		{
+			"nil_b",
+			&StatusReadyConfig{},
+			nil,
+			&StatusReadyConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&StatusReadyConfig{},
+			&StatusReadyConfig{},
+			&StatusReadyConfig{},
+		},
+		{
+			"child_overrides",
+			&StatusReadyConfig{Child: Bool(true)},
+			&StatusReadyConfig{Child: Bool(false)},
+			&StatusReadyConfig{Child: Bool(false)},
+		},
+		{
+			"child_empty_one",
+			&StatusReadyConfig{Child: Bool(true)},
+			&StatusReadyConfig{},
+			&StatusReadyConfig{Child: Bool(true)},
+		},
+		{
+			"child_empty_two",
+			&StatusReadyConfig{},
+			&StatusReadyConfig{Child: Bool(true)},
+			&StatusReadyConfig{Child: Bool(true)},
+		},
+		{
+			"child_same",
+			&StatusReadyConfig{Child: Bool(true)},
+			&StatusReadyConfig{Child: Bool(true)},
+			&StatusReadyConfig{Child: Bool(true)},
+		},
+		{
+			"commands_overrides",
+			&StatusReadyConfig{Commands: Bool(true)},
+			&StatusReadyConfig{Commands: Bool(false)},
+			&StatusReadyConfig{Commands: Bool(false)},
+		},
+		{
+			"commands_empty_one",
+			&StatusReadyConfig{Commands: Bool(true)},
+			&StatusReadyConfig{},
+			&StatusReadyConfig{Commands: Bool(true)},
+		},
+		{
+			"commands_empty_two",
+			&StatusReadyConfig{},
+			&StatusReadyConfig{Commands: Bool(true)},
+			&StatusReadyConfig{Commands: Bool(true)},
+		},
+		{
+			"commands_same",
+			&StatusReadyConfig{Commands: Bool(true)},
+			&StatusReadyConfig{Commands: Bool(true)},
+			&StatusReadyConfig{Commands: Bool(true)},
+		},
+		{
+			"templates_overrides",
+			&StatusReadyConfig{Templates: Bool(true)},
+			&StatusReadyConfig{Templates: Bool(false)},
+			&StatusReadyConfig{Templates: Bool(false)},
+		},
+		{
+			"templates_empty_one",
+			&StatusReadyConfig{Templates: Bool(true)},
+			&StatusReadyConfig{},
+			&StatusReadyConfig{Templates: Bool(true)},
+		},
+		{
+			"templates_empty_two",
+			&StatusReadyConfig{},
+			&StatusReadyConfig{Templates: Bool(true)},
+			&StatusReadyConfig{Templates: Bool(true)},
+		},
+		{
+			"templates_same",
+			&StatusReadyConfig{Templates: Bool(true)},
+			&StatusReadyConfig{Templates: Bool(true)},
+			&StatusReadyConfig{Templates: Bool(true)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
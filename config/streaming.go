@@ -0,0 +1,94 @@
+package config
+
+import "fmt"
+
+// DefaultStreamingServerPort is the RPC port of a Consul server, which is used
+// with the host of the Consul address when no streaming address is given.
+const DefaultStreamingServerPort = "8300"
+
+// StreamingConfig is the configuration for streaming the health of services
+// from Consul's event stream instead of holding a blocking query open for each
+// service. Queries streaming does not support, and queries made while the
+// stream is unavailable, fall back to blocking queries.
+type StreamingConfig struct {
+	// Address is the address of the RPC port of a Consul server, which serves
+	// the event stream. If empty, the host of the Consul address is used with
+	// the default server RPC port.
+	Address *string `mapstructure:"address"`
+
+	// Enabled controls if service health is streamed.
+	Enabled *bool `mapstructure:"enabled"`
+}
+
+// DefaultStreamingConfig returns a configuration that is populated with the
+// default values.
+func DefaultStreamingConfig() *StreamingConfig {
+	return &StreamingConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *StreamingConfig) Copy() *StreamingConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o StreamingConfig
+	o.Address = c.Address
+	o.Enabled = c.Enabled
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *StreamingConfig) Merge(o *StreamingConfig) *StreamingConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Address != nil {
+		r.Address = o.Address
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *StreamingConfig) Finalize() {
+	if c.Enabled == nil {
+		c.Enabled = Bool(StringPresent(c.Address))
+	}
+
+	if c.Address == nil {
+		c.Address = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *StreamingConfig) GoString() string {
+	if c == nil {
+		return "(*StreamingConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&StreamingConfig{"+
+		"Address:%s, "+
+		"Enabled:%s"+
+		"}",
+		StringGoString(c.Address),
+		BoolGoString(c.Enabled),
+	)
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestStreamingConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StreamingConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&StreamingConfig{},
+		},
+		{
+			"same_enabled",
+			&StreamingConfig{
+				Address: String("consul.service.consul:8300"),
+				Enabled: Bool(true),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestStreamingConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *StreamingConfig
+		b    *StreamingConfig
+		r    *StreamingConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&StreamingConfig{},
+			&StreamingConfig{},
+		},
+		{
+			"nil_b",
+			&StreamingConfig{},
+			nil,
+			&StreamingConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&StreamingConfig{},
+			&StreamingConfig{},
+			&StreamingConfig{},
+		},
+		{
+			"address_overrides",
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+			&StreamingConfig{Address: String("")},
+			&StreamingConfig{Address: String("")},
+		},
+		{
+			"address_empty_one",
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+			&StreamingConfig{},
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+		},
+		{
+			"address_empty_two",
+			&StreamingConfig{},
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+		},
+		{
+			"address_same",
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+			&StreamingConfig{Address: String("consul.service.consul:8300")},
+		},
+		{
+			"enabled_overrides",
+			&StreamingConfig{Enabled: Bool(true)},
+			&StreamingConfig{Enabled: Bool(false)},
+			&StreamingConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&StreamingConfig{Enabled: Bool(true)},
+			&StreamingConfig{},
+			&StreamingConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&StreamingConfig{},
+			&StreamingConfig{Enabled: Bool(true)},
+			&StreamingConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&StreamingConfig{Enabled: Bool(true)},
+			&StreamingConfig{Enabled: Bool(true)},
+			&StreamingConfig{Enabled: Bool(true)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestStreamingConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *StreamingConfig
+		r    *StreamingConfig
+	}{
+		{
+			"empty",
+			&StreamingConfig{},
+			&StreamingConfig{
+				Address: String(""),
+				Enabled: Bool(false),
+			},
+		},
+		{
+			"with_address",
+			&StreamingConfig{
+				Address: String("consul.service.consul:8300"),
+			},
+			&StreamingConfig{
+				Address: String("consul.service.consul:8300"),
+				Enabled: Bool(true),
+			},
+		},
+		{
+			"enabled",
+			&StreamingConfig{
+				Enabled: Bool(true),
+			},
+			&StreamingConfig{
+				Address: String(""),
+				Enabled: Bool(true),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/logging"
+	"github.com/hashicorp/consul-template/signals"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/pkg/errors"
+)
+
+// strictEnums are the values allowed for the keys which only accept a fixed set
+// of values, by the path of the key.
+var strictEnums = map[string][]string{
+	"exec.escalation.steps":          {ExecEscalationStepRestart, ExecEscalationStepKill},
+	"exec.monitor.action":            {ExecMonitorActionLog, ExecMonitorActionSignal, ExecMonitorActionRestart},
+	"resolve.prefer_family":          {"", ResolveFamilyV4, ResolveFamilyV6},
+	"template.compare.hash":          {CompareHashMD5, CompareHashSHA1, CompareHashSHA256, CompareHashSHA512},
+	"template.compare.mode":          {CompareModeBytes, CompareModeHash, CompareModeSemantic},
+	"template.encoding":              {"", TemplateEncodingGzip, TemplateEncodingBase64},
+	"template.exec.escalation.steps": {ExecEscalationStepRestart, ExecEscalationStepKill},
+	"template.exec.monitor.action":   {ExecMonitorActionLog, ExecMonitorActionSignal, ExecMonitorActionRestart},
+	"template.priority":              {TemplatePriorityHigh, TemplatePriorityNormal, TemplatePriorityLow},
+	"template.render_strategy":       {TemplateRenderStrategyAtomic, TemplateRenderStrategySymlink},
+	"vault.auth_method":              {VaultAuthMethodToken, VaultAuthMethodCert},
+}
+
+// strictAliases are the keys which are accepted in addition to the fields of
+// the configuration, such as deprecated names, by the path of their block.
+var strictAliases = map[string][]string{
+	"vault": {"renew"},
+}
+
+// StrictError is a problem found in a configuration file by strict validation,
+// along with its position.
+type StrictError struct {
+	Pos token.Pos
+	Msg string
+}
+
+// Error returns the position and the description of the problem.
+func (e *StrictError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// StrictErrors are all the problems found in a configuration file by strict
+// validation.
+type StrictErrors []*StrictError
+
+// Error returns each problem on its own line.
+func (e StrictErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateStrict checks the given configuration contents more strictly than
+// Parse: the error names the file, line and column of every unknown key and
+// of every value which is not a valid duration, signal, file mode, log level
+// or one of the values a key accepts. Unknown keys come with the closest known
+// key, if there is one. The name is the file name used in the positions.
+func ValidateStrict(name, s string) error {
+	file, err := hcl.ParseString(s)
+	if err != nil {
+		return errors.Wrap(err, "error decoding config "+name)
+	}
+
+	list, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return fmt.Errorf("error converting config %s", name)
+	}
+
+	v := &strictValidator{name: name}
+	v.checkList(list, reflect.TypeOf(Config{}), "")
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// ValidateStrictPath checks the configuration file at the given path, or every
+// file in the directory at the given path, with ValidateStrict.
+func ValidateStrictPath(path string) error {
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("from file %s", path))
+		}
+		return ValidateStrict(path, string(b))
+	})
+}
+
+// strictValidator walks the syntax tree of a configuration file along with the
+// type of the configuration, collecting the problems it finds.
+type strictValidator struct {
+	name string
+	errs StrictErrors
+
+	// profile is the name of the profile being checked, if any.
+	profile string
+}
+
+// errorf records a problem at the given position.
+func (v *strictValidator) errorf(pos token.Pos, format string, a ...interface{}) {
+	pos.Filename = v.name
+	v.errs = append(v.errs, &StrictError{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+// checkList checks the items of a block against the fields of the given struct
+// type. The path is the path of the block, such as "exec.env".
+func (v *strictValidator) checkList(list *ast.ObjectList, t reflect.Type, path string) {
+	fields := strictFields(t)
+
+	for _, item := range list.Items {
+		if len(item.Keys) == 0 {
+			continue
+		}
+		key := item.Keys[0].Token.Value().(string)
+		keyPath := strings.TrimPrefix(path+"."+key, ".")
+
+		if path == "" && key == "profile" {
+			v.checkProfile(item)
+			continue
+		}
+
+		ft, ok := fields[key]
+		if !ok {
+			if stringInSlice(key, strictAliases[path]) {
+				continue
+			}
+			msg := fmt.Sprintf("unknown key %q", keyPath)
+			if s := strictSuggest(key, fields); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			v.errorf(item.Keys[0].Pos(), "%s", msg)
+			continue
+		}
+
+		v.checkValue(item.Val, ft, keyPath)
+	}
+}
+
+// checkProfile checks a `profile "name" { ... }` block, whose contents are a
+// whole configuration.
+func (v *strictValidator) checkProfile(item *ast.ObjectItem) {
+	if len(item.Keys) != 2 {
+		v.errorf(item.Keys[0].Pos(), "profile blocks must have a name")
+		return
+	}
+	if v.profile != "" {
+		v.errorf(item.Keys[0].Pos(), "profile %q: profiles cannot be nested", v.profile)
+		return
+	}
+	body, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		v.errorf(item.Keys[0].Pos(), "profile blocks must have a body")
+		return
+	}
+
+	v.profile = item.Keys[1].Token.Value().(string)
+	v.checkList(body.List, reflect.TypeOf(Config{}), "")
+	v.profile = ""
+}
+
+// checkValue checks a single value against the type of its field.
+func (v *strictValidator) checkValue(node ast.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch n := node.(type) {
+	case *ast.ObjectType:
+		switch {
+		case t.Kind() == reflect.Struct:
+			v.checkList(n.List, t, path)
+		case t.Kind() == reflect.Slice && strictStruct(t.Elem()):
+			v.checkList(n.List, strictElem(t), path)
+		}
+	case *ast.ListType:
+		for _, elem := range n.List {
+			switch {
+			case t.Kind() == reflect.Slice && strictStruct(t.Elem()):
+				v.checkValue(elem, strictElem(t), path)
+			case t.Kind() == reflect.Slice:
+				v.checkValue(elem, t.Elem(), path)
+			}
+		}
+	case *ast.LiteralType:
+		v.checkLiteral(n, t, path)
+	}
+}
+
+// checkLiteral checks a literal value against the type of its field and the
+// values the key accepts.
+func (v *strictValidator) checkLiteral(n *ast.LiteralType, t reflect.Type, path string) {
+	s, ok := n.Token.Value().(string)
+	if !ok {
+		return
+	}
+
+	if allowed, ok := strictEnums[path]; ok && !stringInSlice(s, allowed) {
+		v.errorf(n.Pos(), "invalid value %q for %q: must be one of %q", s, path, allowed)
+		return
+	}
+
+	var err error
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		_, err = time.ParseDuration(s)
+	case t == reflect.TypeOf(os.FileMode(0)):
+		if s != "preserve" || path != "template.perms" {
+			_, err = strconv.ParseUint(s, 8, 12)
+		}
+	case t == reflect.TypeOf((*os.Signal)(nil)).Elem():
+		_, err = signals.Parse(s)
+	case t == reflect.TypeOf(WaitConfig{}):
+		_, err = ParseWaitConfig(s)
+	case path == "log_level" || strings.HasSuffix(path, ".log_level"):
+		if s != "" && !strictLogLevel(s) {
+			err = fmt.Errorf("must be one of %q", logging.Levels)
+		}
+	}
+	if err != nil {
+		v.errorf(n.Pos(), "invalid value %q for %q: %s", s, path, err)
+	}
+}
+
+// strictFields returns the type of each field of the given struct type, by the
+// key it is decoded from.
+func strictFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[tag] = f.Type
+	}
+	return fields
+}
+
+// strictStruct returns true if the given type is a struct or a pointer to one.
+func strictStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// strictElem returns the struct type of the elements of the given slice type.
+func strictElem(t reflect.Type) reflect.Type {
+	t = t.Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// strictLogLevel returns true if the given log level is known, in any case.
+func strictLogLevel(s string) bool {
+	for _, l := range logging.Levels {
+		if strings.EqualFold(string(l), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// strictSuggest returns the known key closest to the given unknown key, or the
+// empty string if none is close enough to be a likely typo.
+func strictSuggest(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for f := range fields {
+		if d := levenshtein(key, f); d < bestDist || (d == bestDist && f < best) {
+			best, bestDist = f, d
+		}
+	}
+	if bestDist > 2 {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between the given strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// min3 returns the smallest of the given ints.
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// stringInSlice returns true if the slice contains the string.
+func stringInSlice(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
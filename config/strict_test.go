@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateStrict(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		e    []string
+	}{
+		{
+			"valid",
+			`
+			log_level = "warn"
+			kill_signal = "SIGTERM"
+			template {
+				source      = "in"
+				destination = "out"
+				perms       = "0644"
+				wait        = "5s:10s"
+				encoding    = "gzip"
+			}
+			template {
+				contents = "x"
+				perms    = "preserve"
+				exec {
+					command = "true"
+					monitor {
+						action = "restart"
+					}
+				}
+			}
+			vault {
+				auth_method = "cert"
+				renew       = true
+			}
+			vars {
+				anything = "goes"
+			}
+			`,
+			nil,
+		},
+		{
+			"unknown_key",
+			`
+			template {
+				destinaton = "out"
+			}`,
+			[]string{
+				`test.hcl:3:5: unknown key "template.destinaton" (did you mean "destination"?)`,
+			},
+		},
+		{
+			"unknown_key_no_suggestion",
+			`nonsense = true`,
+			[]string{
+				`test.hcl:1:1: unknown key "nonsense"`,
+			},
+		},
+		{
+			"invalid_values",
+			`
+			log_level = "wanr"
+			exec {
+				kill_timeout = "5q"
+			}
+			template {
+				encoding = "gzp"
+				perms    = "999"
+			}`,
+			[]string{
+				`test.hcl:2:16: invalid value "wanr" for "log_level": must be one of ["TRACE" "DEBUG" "INFO" "WARN" "ERR"]`,
+				`test.hcl:4:20: invalid value "5q" for "exec.kill_timeout": time: unknown unit "q" in duration "5q"`,
+				`test.hcl:7:16: invalid value "gzp" for "template.encoding": must be one of ["" "gzip" "base64"]`,
+				`test.hcl:8:16: invalid value "999" for "template.perms": strconv.ParseUint: parsing "999": invalid syntax`,
+			},
+		},
+		{
+			"bundle_perms_preserve",
+			`
+			bundle {
+				perms = "preserve"
+			}`,
+			[]string{
+				`test.hcl:3:13: invalid value "preserve" for "bundle.perms": strconv.ParseUint: parsing "preserve": invalid syntax`,
+			},
+		},
+		{
+			"profile",
+			`
+			profile "prod" {
+				log_level = "warn"
+				template {
+					encoding = "gzp"
+				}
+			}`,
+			[]string{
+				`test.hcl:5:17: invalid value "gzp" for "template.encoding": must be one of ["" "gzip" "base64"]`,
+			},
+		},
+		{
+			"profile_nested",
+			`
+			profile "prod" {
+				profile "dev" {}
+			}`,
+			[]string{
+				`test.hcl:3:5: profile "prod": profiles cannot be nested`,
+			},
+		},
+		{
+			"profile_unnamed",
+			`profile {}`,
+			[]string{
+				`test.hcl:1:1: profile blocks must have a name`,
+			},
+		},
+		{
+			"invalid_signal",
+			`kill_signal = "SIGBOGUS"`,
+			[]string{
+				`test.hcl:1:15: invalid value "SIGBOGUS" for "kill_signal": ` + `invalid signal "SIGBOGUS"`,
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			err := ValidateStrict("test.hcl", tc.i)
+			if tc.e == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			errs, ok := err.(StrictErrors)
+			if !ok {
+				t.Fatalf("expected StrictErrors, got %#v", err)
+			}
+			act := make([]string, len(errs))
+			for i, e := range errs {
+				act[i] = e.Error()
+			}
+			if tc.name == "invalid_signal" {
+				// The list of valid signals depends on the platform.
+				act[0] = act[0][:len(tc.e[0])]
+			}
+			if !reflect.DeepEqual(tc.e, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, act)
+			}
+		})
+	}
+}
+
+func TestValidateStrictPath(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.hcl"), []byte(`consul = "x"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateStrictPath(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := filepath.Join(dir, "b.hcl")
+	if err := ioutil.WriteFile(bad, []byte(`consull = "x"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exp := bad + `:1:1: unknown key "consull" (did you mean "consul"?)`
+	if err := ValidateStrictPath(dir); err == nil || err.Error() != exp {
+		t.Errorf("\nexp: %q\nact: %v", exp, err)
+	}
+}
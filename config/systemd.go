@@ -0,0 +1,120 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultSystemdDaemonReloadCommand is the default command which makes
+	// systemd reload its unit files.
+	DefaultSystemdDaemonReloadCommand = "systemctl daemon-reload"
+)
+
+// DefaultSystemdUnitDirs are the default directories of the systemd units and
+// their drop-ins managed by the system administrator.
+var DefaultSystemdUnitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+}
+
+// SystemdConfig is the configuration for templates which render systemd units
+// or their drop-ins. systemd only notices the changes to these files after a
+// daemon-reload, which has to happen before the services are restarted.
+type SystemdConfig struct {
+	// DaemonReloadCommand is the command which makes systemd reload its unit
+	// files. It runs once for each run in which a template rendered a file in
+	// one of UnitDirs, before the commands of the templates.
+	DaemonReloadCommand *string `mapstructure:"daemon_reload_command"`
+
+	// Enabled controls if the daemon-reload command is run.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// UnitDirs are the directories in which rendering a file requires a
+	// daemon-reload. Files in their subdirectories, such as drop-ins, are
+	// included.
+	UnitDirs []string `mapstructure:"unit_dirs"`
+}
+
+// DefaultSystemdConfig returns a configuration that is populated with the
+// default values.
+func DefaultSystemdConfig() *SystemdConfig {
+	return &SystemdConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *SystemdConfig) Copy() *SystemdConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o SystemdConfig
+	o.DaemonReloadCommand = c.DaemonReloadCommand
+	o.Enabled = c.Enabled
+	if c.UnitDirs != nil {
+		o.UnitDirs = append([]string{}, c.UnitDirs...)
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *SystemdConfig) Merge(o *SystemdConfig) *SystemdConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.DaemonReloadCommand != nil {
+		r.DaemonReloadCommand = o.DaemonReloadCommand
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.UnitDirs != nil {
+		r.UnitDirs = append(r.UnitDirs, o.UnitDirs...)
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *SystemdConfig) Finalize() {
+	if c.DaemonReloadCommand == nil {
+		c.DaemonReloadCommand = String(DefaultSystemdDaemonReloadCommand)
+	}
+
+	if c.Enabled == nil {
+		c.Enabled = Bool(false)
+	}
+
+	if c.UnitDirs == nil {
+		c.UnitDirs = append([]string{}, DefaultSystemdUnitDirs...)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *SystemdConfig) GoString() string {
+	if c == nil {
+		return "(*SystemdConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&SystemdConfig{"+
+		"DaemonReloadCommand:%s, "+
+		"Enabled:%s, "+
+		"UnitDirs:%v"+
+		"}",
+		StringGoString(c.DaemonReloadCommand),
+		BoolGoString(c.Enabled),
+		c.UnitDirs,
+	)
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSystemdConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SystemdConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&SystemdConfig{},
+		},
+		{
+			"same_enabled",
+			&SystemdConfig{
+				DaemonReloadCommand: String("systemctl --user daemon-reload"),
+				Enabled:             Bool(true),
+				UnitDirs:            []string{"/etc/systemd/user"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestSystemdConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *SystemdConfig
+		b    *SystemdConfig
+		r    *SystemdConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&SystemdConfig{},
+			&SystemdConfig{},
+		},
+		{
+			"nil_b",
+			&SystemdConfig{},
+			nil,
+			&SystemdConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&SystemdConfig{},
+			&SystemdConfig{},
+			&SystemdConfig{},
+		},
+		{
+			"daemon_reload_command_overrides",
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+			&SystemdConfig{DaemonReloadCommand: String("")},
+			&SystemdConfig{DaemonReloadCommand: String("")},
+		},
+		{
+			"daemon_reload_command_empty_one",
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+			&SystemdConfig{},
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+		},
+		{
+			"daemon_reload_command_empty_two",
+			&SystemdConfig{},
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+		},
+		{
+			"daemon_reload_command_same",
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+			&SystemdConfig{DaemonReloadCommand: String("systemctl daemon-reload")},
+		},
+		{
+			"enabled_overrides",
+			&SystemdConfig{Enabled: Bool(true)},
+			&SystemdConfig{Enabled: Bool(false)},
+			&SystemdConfig{Enabled: Bool(false)},
+		},
+		{
+			"enabled_empty_one",
+			&SystemdConfig{Enabled: Bool(true)},
+			&SystemdConfig{},
+			&SystemdConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_empty_two",
+			&SystemdConfig{},
+			&SystemdConfig{Enabled: Bool(true)},
+			&SystemdConfig{Enabled: Bool(true)},
+		},
+		{
+			"enabled_same",
+			&SystemdConfig{Enabled: Bool(true)},
+			&SystemdConfig{Enabled: Bool(true)},
+			&SystemdConfig{Enabled: Bool(true)},
+		},
+		{
+			"unit_dirs_merges",
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/system"}},
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/user"}},
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/system", "/etc/systemd/user"}},
+		},
+		{
+			"unit_dirs_empty_one",
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/system"}},
+			&SystemdConfig{},
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/system"}},
+		},
+		{
+			"unit_dirs_empty_two",
+			&SystemdConfig{},
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/system"}},
+			&SystemdConfig{UnitDirs: []string{"/etc/systemd/system"}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestSystemdConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *SystemdConfig
+		r    *SystemdConfig
+	}{
+		{
+			"empty",
+			&SystemdConfig{},
+			&SystemdConfig{
+				DaemonReloadCommand: String(DefaultSystemdDaemonReloadCommand),
+				Enabled:             Bool(false),
+				UnitDirs:            []string{"/etc/systemd/system", "/run/systemd/system"},
+			},
+		},
+		{
+			"with_unit_dirs",
+			&SystemdConfig{
+				UnitDirs: []string{"/etc/systemd/user"},
+			},
+			&SystemdConfig{
+				DaemonReloadCommand: String(DefaultSystemdDaemonReloadCommand),
+				Enabled:             Bool(false),
+				UnitDirs:            []string{"/etc/systemd/user"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
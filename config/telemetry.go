@@ -0,0 +1,74 @@
+package config
+
+import "fmt"
+
+// TelemetryConfig is the configuration for exporting the metrics of the
+// runner.
+type TelemetryConfig struct {
+	// PrometheusPort is the port of the HTTP listener which serves the metrics
+	// in the Prometheus text format at /metrics. The default value is 0, which
+	// disables the listener.
+	PrometheusPort *int `mapstructure:"prometheus_port"`
+}
+
+// DefaultTelemetryConfig returns a configuration that is populated with the
+// default values.
+func DefaultTelemetryConfig() *TelemetryConfig {
+	return &TelemetryConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *TelemetryConfig) Copy() *TelemetryConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o TelemetryConfig
+	o.PrometheusPort = c.PrometheusPort
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *TelemetryConfig) Merge(o *TelemetryConfig) *TelemetryConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.PrometheusPort != nil {
+		r.PrometheusPort = o.PrometheusPort
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *TelemetryConfig) Finalize() {
+	if c.PrometheusPort == nil {
+		c.PrometheusPort = Int(0)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *TelemetryConfig) GoString() string {
+	if c == nil {
+		return "(*TelemetryConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&TelemetryConfig{"+
+		"PrometheusPort:%s"+
+		"}",
+		IntGoString(c.PrometheusPort),
+	)
+}
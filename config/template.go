@@ -15,9 +15,48 @@ const (
 	// specified.
 	DefaultTemplateFilePerms = 0644
 
+	// DefaultTemplateDestDirPerms are the default permissions of the
+	// directories created for a template destination.
+	DefaultTemplateDestDirPerms = 0755
+
 	// DefaultTemplateCommandTimeout is the amount of time to wait for a command
 	// to return.
 	DefaultTemplateCommandTimeout = 30 * time.Second
+
+	// TemplateEncodingGzip gzip compresses the rendered output before it is
+	// written to the destination.
+	TemplateEncodingGzip = "gzip"
+
+	// TemplateEncodingBase64 base64 encodes the rendered output before it is
+	// written to the destination.
+	TemplateEncodingBase64 = "base64"
+
+	// TemplateRenderStrategyAtomic replaces the destination with the rendered
+	// output in a single rename.
+	TemplateRenderStrategyAtomic = "atomic"
+
+	// TemplateRenderStrategySymlink writes each rendered output to a versioned
+	// file next to the destination, and atomically points the destination,
+	// which is a symlink, to the newest version.
+	TemplateRenderStrategySymlink = "symlink"
+
+	// DefaultTemplateRenderVersions is the default number of versions kept by
+	// the symlink render strategy.
+	DefaultTemplateRenderVersions = 5
+
+	// DefaultTemplateHeader is the default template of the header added to
+	// the rendered output when AddHeader is set.
+	DefaultTemplateHeader = `# Managed by Consul Template. DO NOT EDIT: changes will be overwritten.
+# Template: {{ .Name }}
+# Source: {{ .Source }}
+# Rendered: {{ .Time }}
+`
+
+	// TemplatePriorityHigh, TemplatePriorityNormal and TemplatePriorityLow are
+	// the priorities of the dependencies of a template.
+	TemplatePriorityHigh   = "high"
+	TemplatePriorityNormal = "normal"
+	TemplatePriorityLow    = "low"
 )
 
 var (
@@ -29,10 +68,31 @@ var (
 )
 
 type TemplateConfig struct {
+	// ACL is the NTFS access control list to apply to the file on disk instead
+	// of Perms, as an SDDL string such as "D:P(A;;FA;;;SY)(A;;FR;;;BU)". Only
+	// the DACL of the string is applied. It is only supported on Windows.
+	ACL *string `mapstructure:"acl"`
+
+	// AddHeader prepends a header, rendered from HeaderTemplate, to the output
+	// written to Destination. The header is left out when the output is
+	// compared with the destination, so a new render time alone does not
+	// rewrite the file. The default value is true if HeaderTemplate is set.
+	AddHeader *bool `mapstructure:"add_header"`
+
 	// Backup determines if this template should retain a backup. The default
 	// value is false.
 	Backup *bool `mapstructure:"backup"`
 
+	// Blackout is the list of windows during which this template is not
+	// written to disk, in addition to the global ones.
+	Blackout *BlackoutConfigs `mapstructure:"blackout"`
+
+	// Bundle is the name of the bundle this template is packaged into, instead
+	// of being written to Destination, which must be empty. BundlePath is the
+	// path of its entry in the archive, whose mode is Perms.
+	Bundle     *string `mapstructure:"bundle"`
+	BundlePath *string `mapstructure:"bundle_path"`
+
 	// Command is the arbitrary command to execute after a template has
 	// successfully rendered. This is DEPRECATED. Use Exec instead.
 	Command *string `mapstructure:"command"`
@@ -41,27 +101,178 @@ type TemplateConfig struct {
 	// before force-killing it. This is DEPRECATED. Use Exec instead.
 	CommandTimeout *time.Duration `mapstructure:"command_timeout"`
 
+	// Compare is how the rendered contents are compared with the destination
+	// to decide if the template changed.
+	Compare *CompareConfig `mapstructure:"compare"`
+
 	// Contents are the raw template contents to evaluate. Either this or Source
 	// must be specified, but not both.
 	Contents *string `mapstructure:"contents"`
 
+	// CreateDestDirs creates the missing parent directories of Destination
+	// when the template renders, with DestDirPerms and the owner given by
+	// DestDirUser and DestDirGroup. If false, rendering fails while the
+	// directory is missing. The default value is true.
+	CreateDestDirs *bool `mapstructure:"create_dest_dirs"`
+
+	// Critical makes this template bypass quiescence, so it is rendered as soon
+	// as its data changes even if a wait is configured. Critical templates are
+	// also rendered before other templates in each run. The default value is
+	// false.
+	Critical *bool `mapstructure:"critical"`
+
+	// DeleteOnDestroy deletes the destination when this template is removed
+	// from the configuration on reload, so consumers do not keep reading a
+	// stale file. The default value is false.
+	DeleteOnDestroy *bool `mapstructure:"delete_on_destroy"`
+
+	// DestDirGroup is the group, by name or id, which owns the directories
+	// created for Destination. The default value is empty, which keeps the
+	// group of the process. It is not supported on Windows.
+	DestDirGroup *string `mapstructure:"dest_dir_group"`
+
+	// DestDirPerms are the permissions of the directories created for
+	// Destination. They are applied as given, regardless of the umask. The
+	// default value is 0755.
+	DestDirPerms *os.FileMode `mapstructure:"dest_dir_perms"`
+
+	// DestDirUser is the user, by name or id, which owns the directories
+	// created for Destination. The default value is empty, which keeps the
+	// user of the process. It is not supported on Windows.
+	DestDirUser *string `mapstructure:"dest_dir_user"`
+
 	// Destination is the location on disk where the template should be rendered.
 	// This is required unless running in debug/dry mode.
 	Destination *string `mapstructure:"destination"`
 
+	// DestroyCommand is the command to execute when this template is removed
+	// from the configuration on reload. It runs with the environment and timeout
+	// of Exec. The default value is empty, which runs no command.
+	DestroyCommand *string `mapstructure:"destroy_command"`
+
+	// Encoding is applied to the rendered output before it is written to the
+	// destination, for consumers which read compressed or encoded files. It is
+	// one of "gzip" or "base64". The default value is empty, which writes the
+	// output as-is.
+	Encoding *string `mapstructure:"encoding"`
+
 	// Exec is the configuration for the command to run when the template renders
 	// successfully.
 	Exec *ExecConfig `mapstructure:"exec"`
 
+	// HeaderTemplate is the Go template of the header added by AddHeader. It
+	// is executed with the fields .Name, .Source, .Destination and .Time, the
+	// render time in RFC 3339 format. The default value is
+	// DefaultTemplateHeader, whose lines are "#" comments.
+	HeaderTemplate *string `mapstructure:"header_template"`
+
+	// ID is an optional, user-defined identifier for this template. It is used
+	// to refer to this template from other templates.
+	ID *string `mapstructure:"id"`
+
+	// InputTemplate is the ID of another template whose rendered output is made
+	// available to this template as .Input. This template is always rendered
+	// after the input template.
+	InputTemplate *string `mapstructure:"input_template"`
+
+	// LogLevel is the minimum level of the log lines about this template, which
+	// overrides the global log level for them. The lines are tagged with LogTag,
+	// or with the destination if no tag is given. The default value is empty,
+	// which uses the global log level.
+	LogLevel *string `mapstructure:"log_level"`
+
+	// LogTag is the tag added to the log lines about this template, so they can
+	// be told apart in the output. The default value is empty.
+	LogTag *string `mapstructure:"log_tag"`
+
+	// MaxOutputSize is the maximum size in bytes of the rendered output. A
+	// template which exceeds it is stopped and fails to render. The default
+	// value is 0, which does not limit the output.
+	MaxOutputSize *int `mapstructure:"max_output_size"`
+
+	// MaxRangeIterations is the maximum number of range iterations during a
+	// single render, summed over all range actions. A template which exceeds it
+	// is stopped and fails to render. The default value is 0, which does not
+	// limit the iterations.
+	MaxRangeIterations *int `mapstructure:"max_range_iterations"`
+
+	// MinInstances is the list of guards which refuse to render this template
+	// if it would include too few healthy instances of a service.
+	MinInstances *MinInstancesConfigs `mapstructure:"min_instances"`
+
+	// Name is a human-readable name for this template, shown alongside its ID
+	// in logs, events, reports and the control interface. The default value is
+	// empty, which only shows the ID.
+	Name *string `mapstructure:"name"`
+
 	// Perms are the file system permissions to use when creating the file on
 	// disk. This is useful for when files contain sensitive information, such as
-	// secrets from Vault.
+	// secrets from Vault. If PreservePerms is true, they only apply to new files.
 	Perms *os.FileMode `mapstructure:"perms"`
 
+	// PreservePerms keeps the mode and owner of an existing destination instead
+	// of applying Perms. It is set by perms = "preserve", and cleared by any
+	// other value of perms.
+	PreservePerms *bool `mapstructure:"preserve_perms"`
+
+	// Priority is the priority of the dependencies of this template, one of
+	// "high", "normal" or "low". When the watcher limits the requests which
+	// establish watches, such as after a restart, the dependencies with the
+	// highest priority are fetched and retried first. A dependency shared by
+	// several templates has the highest of their priorities. The default value
+	// is "normal".
+	Priority *string `mapstructure:"priority"`
+
+	// ReadyCheck is the readiness check of the long-lived process started by
+	// the command of this template.
+	ReadyCheck *ReadyCheckConfig `mapstructure:"ready_check"`
+
+	// RenderStrategy is how the rendered output replaces the destination. It
+	// is "atomic", which renames a temporary file over the destination, or
+	// "symlink", which writes versioned files named after the destination and
+	// the time they were rendered, and points the destination to the newest
+	// one. The default value is "atomic".
+	RenderStrategy *string `mapstructure:"render_strategy"`
+
+	// RenderVersions is the number of versioned files kept by the symlink
+	// render strategy, including the current one. The default value is 5.
+	RenderVersions *int `mapstructure:"render_versions"`
+
+	// SandboxPath is the directory tree the file function may read from and
+	// the plugin function may execute from. Paths are resolved, including
+	// symlinks, before they are checked. The default value is empty, which does
+	// not restrict them.
+	SandboxPath *string `mapstructure:"sandbox_path"`
+
+	// SecretRotation is the list of windows during which new versions of the
+	// secrets of this template are written to disk.
+	SecretRotation *SecretRotationConfigs `mapstructure:"secret_rotation"`
+
+	// SeedFile is the path on disk where the seed of the random template
+	// functions is persisted, so generated values survive restarts. The file is
+	// created if it does not exist; removing it rotates the generated values.
+	// The default value is empty, which uses a new seed for each process.
+	SeedFile *string `mapstructure:"seed_file"`
+
+	// Serial makes the command of this template wait for its previous run to
+	// exit before it runs again. This only matters for commands without a
+	// timeout, which are not waited for. The default value is false.
+	Serial *bool `mapstructure:"serial"`
+
 	// Source is the path on disk to the template contents to evaluate. Either
 	// this or Contents should be specified, but not both.
 	Source *string `mapstructure:"source"`
 
+	// SplitDestination makes Destination a directory in which each key of the
+	// output, which must be a JSON object, is written to its own file. The
+	// files are replaced together, and the files of removed keys are deleted.
+	// The default value is false.
+	SplitDestination *bool `mapstructure:"split_destination"`
+
+	// Strict makes references to missing map keys fail the template instead of
+	// rendering "<no value>". The default value is false.
+	Strict *bool `mapstructure:"strict"`
+
 	// Wait configures per-template quiescence timers.
 	Wait *WaitConfig `mapstructure:"wait"`
 
@@ -88,24 +299,102 @@ func (c *TemplateConfig) Copy() *TemplateConfig {
 
 	var o TemplateConfig
 
+	o.ACL = c.ACL
+
+	o.AddHeader = c.AddHeader
+
 	o.Backup = c.Backup
 
+	if c.Blackout != nil {
+		o.Blackout = c.Blackout.Copy()
+	}
+
+	o.Bundle = c.Bundle
+
+	o.BundlePath = c.BundlePath
+
 	o.Command = c.Command
 
 	o.CommandTimeout = c.CommandTimeout
 
+	if c.Compare != nil {
+		o.Compare = c.Compare.Copy()
+	}
+
 	o.Contents = c.Contents
 
+	o.CreateDestDirs = c.CreateDestDirs
+
+	o.Critical = c.Critical
+
+	o.DeleteOnDestroy = c.DeleteOnDestroy
+
+	o.DestDirGroup = c.DestDirGroup
+
+	o.DestDirPerms = c.DestDirPerms
+
+	o.DestDirUser = c.DestDirUser
+
 	o.Destination = c.Destination
 
+	o.DestroyCommand = c.DestroyCommand
+
+	o.Encoding = c.Encoding
+
 	if c.Exec != nil {
 		o.Exec = c.Exec.Copy()
 	}
 
+	o.HeaderTemplate = c.HeaderTemplate
+
+	o.ID = c.ID
+
+	o.InputTemplate = c.InputTemplate
+
+	o.LogLevel = c.LogLevel
+
+	o.LogTag = c.LogTag
+
+	o.MaxOutputSize = c.MaxOutputSize
+
+	o.MaxRangeIterations = c.MaxRangeIterations
+
+	if c.MinInstances != nil {
+		o.MinInstances = c.MinInstances.Copy()
+	}
+
+	o.Name = c.Name
+
 	o.Perms = c.Perms
 
+	o.PreservePerms = c.PreservePerms
+
+	o.Priority = c.Priority
+
+	if c.ReadyCheck != nil {
+		o.ReadyCheck = c.ReadyCheck.Copy()
+	}
+
+	o.RenderStrategy = c.RenderStrategy
+
+	o.RenderVersions = c.RenderVersions
+
+	o.SandboxPath = c.SandboxPath
+
+	if c.SecretRotation != nil {
+		o.SecretRotation = c.SecretRotation.Copy()
+	}
+
+	o.SeedFile = c.SeedFile
+
+	o.Serial = c.Serial
+
 	o.Source = c.Source
 
+	o.SplitDestination = c.SplitDestination
+
+	o.Strict = c.Strict
+
 	if c.Wait != nil {
 		o.Wait = c.Wait.Copy()
 	}
@@ -134,10 +423,30 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 
 	r := c.Copy()
 
+	if o.ACL != nil {
+		r.ACL = o.ACL
+	}
+
+	if o.AddHeader != nil {
+		r.AddHeader = o.AddHeader
+	}
+
 	if o.Backup != nil {
 		r.Backup = o.Backup
 	}
 
+	if o.Blackout != nil {
+		r.Blackout = r.Blackout.Merge(o.Blackout)
+	}
+
+	if o.Bundle != nil {
+		r.Bundle = o.Bundle
+	}
+
+	if o.BundlePath != nil {
+		r.BundlePath = o.BundlePath
+	}
+
 	if o.Command != nil {
 		r.Command = o.Command
 	}
@@ -146,26 +455,142 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 		r.CommandTimeout = o.CommandTimeout
 	}
 
+	if o.Compare != nil {
+		r.Compare = r.Compare.Merge(o.Compare)
+	}
+
 	if o.Contents != nil {
 		r.Contents = o.Contents
 	}
 
+	if o.CreateDestDirs != nil {
+		r.CreateDestDirs = o.CreateDestDirs
+	}
+
+	if o.Critical != nil {
+		r.Critical = o.Critical
+	}
+
+	if o.DeleteOnDestroy != nil {
+		r.DeleteOnDestroy = o.DeleteOnDestroy
+	}
+
+	if o.DestDirGroup != nil {
+		r.DestDirGroup = o.DestDirGroup
+	}
+
+	if o.DestDirPerms != nil {
+		r.DestDirPerms = o.DestDirPerms
+	}
+
+	if o.DestDirUser != nil {
+		r.DestDirUser = o.DestDirUser
+	}
+
 	if o.Destination != nil {
 		r.Destination = o.Destination
 	}
 
+	if o.DestroyCommand != nil {
+		r.DestroyCommand = o.DestroyCommand
+	}
+
+	if o.Encoding != nil {
+		r.Encoding = o.Encoding
+	}
+
 	if o.Exec != nil {
 		r.Exec = r.Exec.Merge(o.Exec)
 	}
 
+	if o.HeaderTemplate != nil {
+		r.HeaderTemplate = o.HeaderTemplate
+	}
+
+	if o.ID != nil {
+		r.ID = o.ID
+	}
+
+	if o.InputTemplate != nil {
+		r.InputTemplate = o.InputTemplate
+	}
+
+	if o.LogLevel != nil {
+		r.LogLevel = o.LogLevel
+	}
+
+	if o.LogTag != nil {
+		r.LogTag = o.LogTag
+	}
+
+	if o.MaxOutputSize != nil {
+		r.MaxOutputSize = o.MaxOutputSize
+	}
+
+	if o.MaxRangeIterations != nil {
+		r.MaxRangeIterations = o.MaxRangeIterations
+	}
+
+	if o.MinInstances != nil {
+		r.MinInstances = r.MinInstances.Merge(o.MinInstances)
+	}
+
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
 	if o.Perms != nil {
 		r.Perms = o.Perms
 	}
 
+	if o.PreservePerms != nil {
+		r.PreservePerms = o.PreservePerms
+	}
+
+	if o.Priority != nil {
+		r.Priority = o.Priority
+	}
+
+	if o.ReadyCheck != nil {
+		r.ReadyCheck = r.ReadyCheck.Merge(o.ReadyCheck)
+	}
+
+	if o.RenderStrategy != nil {
+		r.RenderStrategy = o.RenderStrategy
+	}
+
+	if o.RenderVersions != nil {
+		r.RenderVersions = o.RenderVersions
+	}
+
+	if o.SandboxPath != nil {
+		r.SandboxPath = o.SandboxPath
+	}
+
+	if o.SecretRotation != nil {
+		r.SecretRotation = r.SecretRotation.Merge(o.SecretRotation)
+	}
+
+	if o.SeedFile != nil {
+		r.SeedFile = o.SeedFile
+	}
+
+	if o.Serial != nil {
+		r.Serial = o.Serial
+	}
+
 	if o.Source != nil {
 		r.Source = o.Source
 	}
 
+	if o.SplitDestination != nil {
+		r.SplitDestination = o.SplitDestination
+	}
+
+	if o.Strict != nil {
+		r.Strict = o.Strict
+	}
+
 	if o.Wait != nil {
 		r.Wait = r.Wait.Merge(o.Wait)
 	}
@@ -184,10 +609,31 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 // Finalize ensures the configuration has no nil pointers and sets default
 // values.
 func (c *TemplateConfig) Finalize() {
+	if c.ACL == nil {
+		c.ACL = String("")
+	}
+
+	if c.AddHeader == nil {
+		c.AddHeader = Bool(StringPresent(c.HeaderTemplate))
+	}
+
 	if c.Backup == nil {
 		c.Backup = Bool(false)
 	}
 
+	if c.Blackout == nil {
+		c.Blackout = DefaultBlackoutConfigs()
+	}
+	c.Blackout.Finalize()
+
+	if c.Bundle == nil {
+		c.Bundle = String("")
+	}
+
+	if c.BundlePath == nil {
+		c.BundlePath = String("")
+	}
+
 	if c.Command == nil {
 		c.Command = String("")
 	}
@@ -196,14 +642,51 @@ func (c *TemplateConfig) Finalize() {
 		c.CommandTimeout = TimeDuration(DefaultTemplateCommandTimeout)
 	}
 
+	if c.Compare == nil {
+		c.Compare = DefaultCompareConfig()
+	}
+	c.Compare.Finalize()
+
 	if c.Contents == nil {
 		c.Contents = String("")
 	}
 
+	if c.CreateDestDirs == nil {
+		c.CreateDestDirs = Bool(true)
+	}
+
+	if c.Critical == nil {
+		c.Critical = Bool(false)
+	}
+
+	if c.DeleteOnDestroy == nil {
+		c.DeleteOnDestroy = Bool(false)
+	}
+
+	if c.DestDirGroup == nil {
+		c.DestDirGroup = String("")
+	}
+
+	if c.DestDirPerms == nil {
+		c.DestDirPerms = FileMode(DefaultTemplateDestDirPerms)
+	}
+
+	if c.DestDirUser == nil {
+		c.DestDirUser = String("")
+	}
+
 	if c.Destination == nil {
 		c.Destination = String("")
 	}
 
+	if c.DestroyCommand == nil {
+		c.DestroyCommand = String("")
+	}
+
+	if c.Encoding == nil {
+		c.Encoding = String("")
+	}
+
 	if c.Exec == nil {
 		c.Exec = DefaultExecConfig()
 	}
@@ -217,14 +700,97 @@ func (c *TemplateConfig) Finalize() {
 	}
 	c.Exec.Finalize()
 
+	if c.HeaderTemplate == nil {
+		c.HeaderTemplate = String(DefaultTemplateHeader)
+	}
+
+	if c.ID == nil {
+		c.ID = String("")
+	}
+
+	if c.InputTemplate == nil {
+		c.InputTemplate = String("")
+	}
+
+	if c.LogLevel == nil {
+		c.LogLevel = String("")
+	}
+
+	if c.LogTag == nil {
+		c.LogTag = String("")
+	}
+
+	if c.MaxOutputSize == nil {
+		c.MaxOutputSize = Int(0)
+	}
+
+	if c.MaxRangeIterations == nil {
+		c.MaxRangeIterations = Int(0)
+	}
+
+	if c.MinInstances == nil {
+		c.MinInstances = DefaultMinInstancesConfigs()
+	}
+	c.MinInstances.Finalize()
+
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
 	if c.Perms == nil {
 		c.Perms = FileMode(DefaultTemplateFilePerms)
 	}
 
+	if c.PreservePerms == nil {
+		c.PreservePerms = Bool(false)
+	}
+
+	if c.Priority == nil {
+		c.Priority = String(TemplatePriorityNormal)
+	}
+
+	if c.ReadyCheck == nil {
+		c.ReadyCheck = DefaultReadyCheckConfig()
+	}
+	c.ReadyCheck.Finalize()
+
+	if c.RenderStrategy == nil {
+		c.RenderStrategy = String(TemplateRenderStrategyAtomic)
+	}
+
+	if c.RenderVersions == nil {
+		c.RenderVersions = Int(DefaultTemplateRenderVersions)
+	}
+
+	if c.SandboxPath == nil {
+		c.SandboxPath = String("")
+	}
+
+	if c.SecretRotation == nil {
+		c.SecretRotation = DefaultSecretRotationConfigs()
+	}
+	c.SecretRotation.Finalize()
+
+	if c.SeedFile == nil {
+		c.SeedFile = String("")
+	}
+
+	if c.Serial == nil {
+		c.Serial = Bool(false)
+	}
+
 	if c.Source == nil {
 		c.Source = String("")
 	}
 
+	if c.SplitDestination == nil {
+		c.SplitDestination = Bool(false)
+	}
+
+	if c.Strict == nil {
+		c.Strict = Bool(false)
+	}
+
 	if c.Wait == nil {
 		c.Wait = DefaultWaitConfig()
 	}
@@ -246,26 +812,94 @@ func (c *TemplateConfig) GoString() string {
 	}
 
 	return fmt.Sprintf("&TemplateConfig{"+
+		"ACL:%s, "+
+		"AddHeader:%s, "+
 		"Backup:%s, "+
+		"Blackout:%#v, "+
+		"Bundle:%s, "+
+		"BundlePath:%s, "+
 		"Command:%s, "+
 		"CommandTimeout:%s, "+
+		"Compare:%#v, "+
 		"Contents:%s, "+
+		"CreateDestDirs:%s, "+
+		"Critical:%s, "+
+		"DeleteOnDestroy:%s, "+
+		"DestDirGroup:%s, "+
+		"DestDirPerms:%s, "+
+		"DestDirUser:%s, "+
 		"Destination:%s, "+
+		"DestroyCommand:%s, "+
+		"Encoding:%s, "+
 		"Exec:%#v, "+
+		"HeaderTemplate:%s, "+
+		"ID:%s, "+
+		"InputTemplate:%s, "+
+		"LogLevel:%s, "+
+		"LogTag:%s, "+
+		"MaxOutputSize:%s, "+
+		"MaxRangeIterations:%s, "+
+		"MinInstances:%#v, "+
+		"Name:%s, "+
 		"Perms:%s, "+
+		"PreservePerms:%s, "+
+		"Priority:%s, "+
+		"ReadyCheck:%#v, "+
+		"RenderStrategy:%s, "+
+		"RenderVersions:%s, "+
+		"SandboxPath:%s, "+
+		"SecretRotation:%#v, "+
+		"SeedFile:%s, "+
+		"Serial:%s, "+
 		"Source:%s, "+
+		"SplitDestination:%s, "+
+		"Strict:%s, "+
 		"Wait:%#v, "+
 		"LeftDelim:%s, "+
 		"RightDelim:%s"+
 		"}",
+		StringGoString(c.ACL),
+		BoolGoString(c.AddHeader),
 		BoolGoString(c.Backup),
+		c.Blackout,
+		StringGoString(c.Bundle),
+		StringGoString(c.BundlePath),
 		StringGoString(c.Command),
 		TimeDurationGoString(c.CommandTimeout),
+		c.Compare,
 		StringGoString(c.Contents),
+		BoolGoString(c.CreateDestDirs),
+		BoolGoString(c.Critical),
+		BoolGoString(c.DeleteOnDestroy),
+		StringGoString(c.DestDirGroup),
+		FileModeGoString(c.DestDirPerms),
+		StringGoString(c.DestDirUser),
 		StringGoString(c.Destination),
+		StringGoString(c.DestroyCommand),
+		StringGoString(c.Encoding),
 		c.Exec,
+		StringGoString(c.HeaderTemplate),
+		StringGoString(c.ID),
+		StringGoString(c.InputTemplate),
+		StringGoString(c.LogLevel),
+		StringGoString(c.LogTag),
+		IntGoString(c.MaxOutputSize),
+		IntGoString(c.MaxRangeIterations),
+		c.MinInstances,
+		StringGoString(c.Name),
 		FileModeGoString(c.Perms),
+		BoolGoString(c.PreservePerms),
+		StringGoString(c.Priority),
+		c.ReadyCheck,
+		StringGoString(c.RenderStrategy),
+		IntGoString(c.RenderVersions),
+		StringGoString(c.SandboxPath),
+		c.SecretRotation,
+		StringGoString(c.SeedFile),
+		BoolGoString(c.Serial),
 		StringGoString(c.Source),
+		BoolGoString(c.SplitDestination),
+		BoolGoString(c.Strict),
 		c.Wait,
 		StringGoString(c.LeftDelim),
 		StringGoString(c.RightDelim),
@@ -282,10 +916,19 @@ func (c *TemplateConfig) Display() string {
 		source = String("(dynamic)")
 	}
 
-	return fmt.Sprintf("%q => %q",
+	destination := StringVal(c.Destination)
+	if bundle := StringVal(c.Bundle); bundle != "" {
+		destination = bundle + ":" + StringVal(c.BundlePath)
+	}
+
+	display := fmt.Sprintf("%q => %q",
 		StringVal(source),
-		StringVal(c.Destination),
+		destination,
 	)
+	if name := StringVal(c.Name); name != "" {
+		display = fmt.Sprintf("%s (%s)", name, display)
+	}
+	return display
 }
 
 // TemplateConfigs is a collection of TemplateConfigs
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTemplatePluginTimeout is the default amount of time a call to a
+	// function of a template plugin may take.
+	DefaultTemplatePluginTimeout = 5 * time.Second
+)
+
+// TemplatePluginConfig is an external binary which provides template
+// functions. It is started once, when the runner starts, and serves the calls
+// to its functions over gRPC.
+type TemplatePluginConfig struct {
+	// Args are the arguments the plugin is started with.
+	Args []string `mapstructure:"args"`
+
+	// Name is the name of the plugin, given as the label of the
+	// `template_plugin "name" { ... }` block.
+	Name *string `mapstructure:"name"`
+
+	// Path is the path to the plugin binary.
+	Path *string `mapstructure:"path"`
+
+	// Timeout is the maximum amount of time a call to a function of the plugin
+	// may take, after which the render fails.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultTemplatePluginConfig returns a configuration that is populated with
+// the default values.
+func DefaultTemplatePluginConfig() *TemplatePluginConfig {
+	return &TemplatePluginConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *TemplatePluginConfig) Copy() *TemplatePluginConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o TemplatePluginConfig
+	if c.Args != nil {
+		o.Args = append([]string{}, c.Args...)
+	}
+
+	o.Name = c.Name
+
+	o.Path = c.Path
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *TemplatePluginConfig) Merge(o *TemplatePluginConfig) *TemplatePluginConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Args != nil {
+		r.Args = append(r.Args, o.Args...)
+	}
+
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *TemplatePluginConfig) Finalize() {
+	if c.Args == nil {
+		c.Args = []string{}
+	}
+
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
+	if c.Path == nil {
+		c.Path = String("")
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultTemplatePluginTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *TemplatePluginConfig) GoString() string {
+	if c == nil {
+		return "(*TemplatePluginConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&TemplatePluginConfig{"+
+		"Args:%v, "+
+		"Name:%s, "+
+		"Path:%s, "+
+		"Timeout:%s"+
+		"}",
+		c.Args,
+		StringGoString(c.Name),
+		StringGoString(c.Path),
+		TimeDurationGoString(c.Timeout),
+	)
+}
+
+// TemplatePluginConfigs is a collection of TemplatePluginConfigs.
+type TemplatePluginConfigs []*TemplatePluginConfig
+
+// DefaultTemplatePluginConfigs returns a configuration that is populated with
+// the default values.
+func DefaultTemplatePluginConfigs() *TemplatePluginConfigs {
+	return &TemplatePluginConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *TemplatePluginConfigs) Copy() *TemplatePluginConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(TemplatePluginConfigs, len(*c))
+	for i, t := range *c {
+		o[i] = t.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *TemplatePluginConfigs) Merge(o *TemplatePluginConfigs) *TemplatePluginConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	*r = append(*r, *o.Copy()...)
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *TemplatePluginConfigs) Finalize() {
+	for _, t := range *c {
+		t.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *TemplatePluginConfigs) GoString() string {
+	if c == nil {
+		return "(*TemplatePluginConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, t := range *c {
+		s[i] = t.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+// nameTemplatePlugins rewrites the `template_plugin "name" { ... }` blocks of
+// the given parsed contents of a configuration file into blocks with a name
+// field, so they decode into TemplatePluginConfigs. Blocks without a label are
+// kept as they are.
+func nameTemplatePlugins(parsed map[string]interface{}) {
+	blocks, ok := parsed["template_plugin"].([]map[string]interface{})
+	if !ok {
+		return
+	}
+
+	named := make([]map[string]interface{}, 0, len(blocks))
+	for _, block := range blocks {
+		if len(block) != 1 {
+			named = append(named, block)
+			continue
+		}
+		for name, body := range block {
+			bodies, err := profileBodies(body)
+			if err != nil {
+				named = append(named, block)
+				continue
+			}
+			for _, b := range bodies {
+				b["name"] = name
+				named = append(named, b)
+			}
+		}
+	}
+	parsed["template_plugin"] = named
+}
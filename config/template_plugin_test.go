@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTemplatePluginConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *TemplatePluginConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&TemplatePluginConfig{},
+		},
+		{
+			"copy",
+			&TemplatePluginConfig{
+				Args:    []string{"-dsn", "/etc/mysql.dsn"},
+				Name:    String("mysql"),
+				Path:    String("/usr/local/bin/ct-plugin-mysql"),
+				Timeout: TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestTemplatePluginConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *TemplatePluginConfig
+		b    *TemplatePluginConfig
+		r    *TemplatePluginConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{},
+		},
+		{
+			"nil_b",
+			&TemplatePluginConfig{},
+			nil,
+			&TemplatePluginConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{},
+		},
+		{
+			"args_merges",
+			&TemplatePluginConfig{Args: []string{"a"}},
+			&TemplatePluginConfig{Args: []string{"b"}},
+			&TemplatePluginConfig{Args: []string{"a", "b"}},
+		},
+		{
+			"args_empty_one",
+			&TemplatePluginConfig{Args: []string{"a"}},
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Args: []string{"a"}},
+		},
+		{
+			"args_empty_two",
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Args: []string{"a"}},
+			&TemplatePluginConfig{Args: []string{"a"}},
+		},
+		{
+			"name_overrides",
+			&TemplatePluginConfig{Name: String("mysql")},
+			&TemplatePluginConfig{Name: String("")},
+			&TemplatePluginConfig{Name: String("")},
+		},
+		{
+			"name_empty_one",
+			&TemplatePluginConfig{Name: String("mysql")},
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Name: String("mysql")},
+		},
+		{
+			"name_empty_two",
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Name: String("mysql")},
+			&TemplatePluginConfig{Name: String("mysql")},
+		},
+		{
+			"name_same",
+			&TemplatePluginConfig{Name: String("mysql")},
+			&TemplatePluginConfig{Name: String("mysql")},
+			&TemplatePluginConfig{Name: String("mysql")},
+		},
+		{
+			"path_overrides",
+			&TemplatePluginConfig{Path: String("/bin/a")},
+			&TemplatePluginConfig{Path: String("")},
+			&TemplatePluginConfig{Path: String("")},
+		},
+		{
+			"path_empty_one",
+			&TemplatePluginConfig{Path: String("/bin/a")},
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Path: String("/bin/a")},
+		},
+		{
+			"path_empty_two",
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Path: String("/bin/a")},
+			&TemplatePluginConfig{Path: String("/bin/a")},
+		},
+		{
+			"path_same",
+			&TemplatePluginConfig{Path: String("/bin/a")},
+			&TemplatePluginConfig{Path: String("/bin/a")},
+			&TemplatePluginConfig{Path: String("/bin/a")},
+		},
+		{
+			"timeout_overrides",
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+			&TemplatePluginConfig{Timeout: TimeDuration(0)},
+			&TemplatePluginConfig{Timeout: TimeDuration(0)},
+		},
+		{
+			"timeout_empty_one",
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_empty_two",
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+		{
+			"timeout_same",
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+			&TemplatePluginConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestTemplatePluginConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *TemplatePluginConfig
+		r    *TemplatePluginConfig
+	}{
+		{
+			"empty",
+			&TemplatePluginConfig{},
+			&TemplatePluginConfig{
+				Args:    []string{},
+				Name:    String(""),
+				Path:    String(""),
+				Timeout: TimeDuration(DefaultTemplatePluginTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -23,17 +23,42 @@ func TestTemplateConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&TemplateConfig{
-				Backup:         Bool(true),
-				Command:        String("command"),
-				CommandTimeout: TimeDuration(10 * time.Second),
-				Contents:       String("contents"),
-				Destination:    String("destination"),
-				Exec:           &ExecConfig{Command: String("command")},
-				Perms:          FileMode(0600),
-				Source:         String("source"),
-				Wait:           &WaitConfig{Min: TimeDuration(10)},
-				LeftDelim:      String("left_delim"),
-				RightDelim:     String("right_delim"),
+				ACL:                String("D:P(A;;FA;;;SY)"),
+				AddHeader:          Bool(true),
+				Backup:             Bool(true),
+				Command:            String("command"),
+				CommandTimeout:     TimeDuration(10 * time.Second),
+				Contents:           String("contents"),
+				CreateDestDirs:     Bool(false),
+				Critical:           Bool(true),
+				DeleteOnDestroy:    Bool(true),
+				DestDirGroup:       String("group"),
+				DestDirPerms:       FileMode(0700),
+				DestDirUser:        String("user"),
+				Destination:        String("destination"),
+				DestroyCommand:     String("destroy"),
+				Encoding:           String("gzip"),
+				Exec:               &ExecConfig{Command: String("command")},
+				HeaderTemplate:     String("// {{ .Name }}\n"),
+				ID:                 String("id"),
+				InputTemplate:      String("input"),
+				LogLevel:           String("debug"),
+				LogTag:             String("tag"),
+				MaxOutputSize:      Int(1024),
+				MaxRangeIterations: Int(100),
+				MinInstances: &MinInstancesConfigs{
+					&MinInstancesConfig{Count: Int(2), Service: String("web")},
+				},
+				Name:             String("name"),
+				Perms:            FileMode(0600),
+				PreservePerms:    Bool(true),
+				SeedFile:         String("seed_file"),
+				Serial:           Bool(true),
+				Source:           String("source"),
+				SplitDestination: Bool(true),
+				Wait:             &WaitConfig{Min: TimeDuration(10)},
+				LeftDelim:        String("left_delim"),
+				RightDelim:       String("right_delim"),
 			},
 		},
 	}
@@ -79,6 +104,54 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{},
 			&TemplateConfig{},
 		},
+		{
+			"acl_overrides",
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{ACL: String("")},
+			&TemplateConfig{ACL: String("")},
+		},
+		{
+			"acl_empty_one",
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{},
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+		},
+		{
+			"acl_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+		},
+		{
+			"acl_same",
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+			&TemplateConfig{ACL: String("D:P(A;;FA;;;SY)")},
+		},
+		{
+			"add_header_overrides",
+			&TemplateConfig{AddHeader: Bool(true)},
+			&TemplateConfig{AddHeader: Bool(false)},
+			&TemplateConfig{AddHeader: Bool(false)},
+		},
+		{
+			"add_header_empty_one",
+			&TemplateConfig{AddHeader: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{AddHeader: Bool(true)},
+		},
+		{
+			"add_header_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{AddHeader: Bool(true)},
+			&TemplateConfig{AddHeader: Bool(true)},
+		},
+		{
+			"add_header_same",
+			&TemplateConfig{AddHeader: Bool(true)},
+			&TemplateConfig{AddHeader: Bool(true)},
+			&TemplateConfig{AddHeader: Bool(true)},
+		},
 		{
 			"backup_overrides",
 			&TemplateConfig{Backup: Bool(true)},
@@ -103,6 +176,54 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Backup: Bool(true)},
 			&TemplateConfig{Backup: Bool(true)},
 		},
+		{
+			"bundle_overrides",
+			&TemplateConfig{Bundle: String("nginx")},
+			&TemplateConfig{Bundle: String("")},
+			&TemplateConfig{Bundle: String("")},
+		},
+		{
+			"bundle_empty_one",
+			&TemplateConfig{Bundle: String("nginx")},
+			&TemplateConfig{},
+			&TemplateConfig{Bundle: String("nginx")},
+		},
+		{
+			"bundle_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Bundle: String("nginx")},
+			&TemplateConfig{Bundle: String("nginx")},
+		},
+		{
+			"bundle_same",
+			&TemplateConfig{Bundle: String("nginx")},
+			&TemplateConfig{Bundle: String("nginx")},
+			&TemplateConfig{Bundle: String("nginx")},
+		},
+		{
+			"bundle_path_overrides",
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+			&TemplateConfig{BundlePath: String("")},
+			&TemplateConfig{BundlePath: String("")},
+		},
+		{
+			"bundle_path_empty_one",
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+			&TemplateConfig{},
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+		},
+		{
+			"bundle_path_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+		},
+		{
+			"bundle_path_same",
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+			&TemplateConfig{BundlePath: String("conf.d/app.conf")},
+		},
 		{
 			"command_overrides",
 			&TemplateConfig{Command: String("command")},
@@ -151,6 +272,30 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{CommandTimeout: TimeDuration(10 * time.Second)},
 			&TemplateConfig{CommandTimeout: TimeDuration(10 * time.Second)},
 		},
+		{
+			"compare_overrides",
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeBytes)}},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeBytes)}},
+		},
+		{
+			"compare_empty_one",
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+			&TemplateConfig{},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+		},
+		{
+			"compare_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+		},
+		{
+			"compare_same",
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+			&TemplateConfig{Compare: &CompareConfig{Mode: String(CompareModeHash)}},
+		},
 		{
 			"contents_overrides",
 			&TemplateConfig{Contents: String("contents")},
@@ -175,6 +320,150 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Contents: String("contents")},
 			&TemplateConfig{Contents: String("contents")},
 		},
+		{
+			"create_dest_dirs_overrides",
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+			&TemplateConfig{CreateDestDirs: Bool(true)},
+		},
+		{
+			"create_dest_dirs_empty_one",
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+			&TemplateConfig{},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+		},
+		{
+			"create_dest_dirs_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+		},
+		{
+			"create_dest_dirs_same",
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+			&TemplateConfig{CreateDestDirs: Bool(false)},
+		},
+		{
+			"critical_overrides",
+			&TemplateConfig{Critical: Bool(true)},
+			&TemplateConfig{Critical: Bool(false)},
+			&TemplateConfig{Critical: Bool(false)},
+		},
+		{
+			"critical_empty_one",
+			&TemplateConfig{Critical: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{Critical: Bool(true)},
+		},
+		{
+			"critical_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Critical: Bool(true)},
+			&TemplateConfig{Critical: Bool(true)},
+		},
+		{
+			"critical_same",
+			&TemplateConfig{Critical: Bool(true)},
+			&TemplateConfig{Critical: Bool(true)},
+			&TemplateConfig{Critical: Bool(true)},
+		},
+		{
+			"delete_on_destroy_overrides",
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+			&TemplateConfig{DeleteOnDestroy: Bool(false)},
+			&TemplateConfig{DeleteOnDestroy: Bool(false)},
+		},
+		{
+			"delete_on_destroy_empty_one",
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+		},
+		{
+			"delete_on_destroy_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+		},
+		{
+			"delete_on_destroy_same",
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+			&TemplateConfig{DeleteOnDestroy: Bool(true)},
+		},
+		{
+			"dest_dir_group_overrides",
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("")},
+			&TemplateConfig{DestDirGroup: String("")},
+		},
+		{
+			"dest_dir_group_empty_one",
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{},
+			&TemplateConfig{DestDirGroup: String("group")},
+		},
+		{
+			"dest_dir_group_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("group")},
+		},
+		{
+			"dest_dir_group_same",
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("group")},
+			&TemplateConfig{DestDirGroup: String("group")},
+		},
+		{
+			"dest_dir_perms_overrides",
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+			&TemplateConfig{DestDirPerms: FileMode(0755)},
+			&TemplateConfig{DestDirPerms: FileMode(0755)},
+		},
+		{
+			"dest_dir_perms_empty_one",
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+			&TemplateConfig{},
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+		},
+		{
+			"dest_dir_perms_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+		},
+		{
+			"dest_dir_perms_same",
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+			&TemplateConfig{DestDirPerms: FileMode(0700)},
+		},
+		{
+			"dest_dir_user_overrides",
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("")},
+			&TemplateConfig{DestDirUser: String("")},
+		},
+		{
+			"dest_dir_user_empty_one",
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{},
+			&TemplateConfig{DestDirUser: String("user")},
+		},
+		{
+			"dest_dir_user_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("user")},
+		},
+		{
+			"dest_dir_user_same",
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("user")},
+			&TemplateConfig{DestDirUser: String("user")},
+		},
 		{
 			"destination_overrides",
 			&TemplateConfig{Destination: String("destination")},
@@ -199,6 +488,54 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Destination: String("destination")},
 			&TemplateConfig{Destination: String("destination")},
 		},
+		{
+			"destroy_command_overrides",
+			&TemplateConfig{DestroyCommand: String("destroy")},
+			&TemplateConfig{DestroyCommand: String("")},
+			&TemplateConfig{DestroyCommand: String("")},
+		},
+		{
+			"destroy_command_empty_one",
+			&TemplateConfig{DestroyCommand: String("destroy")},
+			&TemplateConfig{},
+			&TemplateConfig{DestroyCommand: String("destroy")},
+		},
+		{
+			"destroy_command_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{DestroyCommand: String("destroy")},
+			&TemplateConfig{DestroyCommand: String("destroy")},
+		},
+		{
+			"destroy_command_same",
+			&TemplateConfig{DestroyCommand: String("destroy")},
+			&TemplateConfig{DestroyCommand: String("destroy")},
+			&TemplateConfig{DestroyCommand: String("destroy")},
+		},
+		{
+			"encoding_overrides",
+			&TemplateConfig{Encoding: String("gzip")},
+			&TemplateConfig{Encoding: String("")},
+			&TemplateConfig{Encoding: String("")},
+		},
+		{
+			"encoding_empty_one",
+			&TemplateConfig{Encoding: String("gzip")},
+			&TemplateConfig{},
+			&TemplateConfig{Encoding: String("gzip")},
+		},
+		{
+			"encoding_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Encoding: String("gzip")},
+			&TemplateConfig{Encoding: String("gzip")},
+		},
+		{
+			"encoding_same",
+			&TemplateConfig{Encoding: String("gzip")},
+			&TemplateConfig{Encoding: String("gzip")},
+			&TemplateConfig{Encoding: String("gzip")},
+		},
 		{
 			"exec_overrides",
 			&TemplateConfig{Exec: &ExecConfig{Command: String("command")}},
@@ -223,6 +560,231 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Exec: &ExecConfig{Command: String("command")}},
 			&TemplateConfig{Exec: &ExecConfig{Command: String("command")}},
 		},
+		{
+			"header_template_overrides",
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+			&TemplateConfig{HeaderTemplate: String("")},
+			&TemplateConfig{HeaderTemplate: String("")},
+		},
+		{
+			"header_template_empty_one",
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+			&TemplateConfig{},
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+		},
+		{
+			"header_template_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+		},
+		{
+			"header_template_same",
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+			&TemplateConfig{HeaderTemplate: String("// {{ .Name }}\n")},
+		},
+		{
+			"id_overrides",
+			&TemplateConfig{ID: String("one")},
+			&TemplateConfig{ID: String("")},
+			&TemplateConfig{ID: String("")},
+		},
+		{
+			"id_empty_one",
+			&TemplateConfig{ID: String("one")},
+			&TemplateConfig{},
+			&TemplateConfig{ID: String("one")},
+		},
+		{
+			"id_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{ID: String("one")},
+			&TemplateConfig{ID: String("one")},
+		},
+		{
+			"id_same",
+			&TemplateConfig{ID: String("one")},
+			&TemplateConfig{ID: String("one")},
+			&TemplateConfig{ID: String("one")},
+		},
+		{
+			"input_template_overrides",
+			&TemplateConfig{InputTemplate: String("one")},
+			&TemplateConfig{InputTemplate: String("")},
+			&TemplateConfig{InputTemplate: String("")},
+		},
+		{
+			"input_template_empty_one",
+			&TemplateConfig{InputTemplate: String("one")},
+			&TemplateConfig{},
+			&TemplateConfig{InputTemplate: String("one")},
+		},
+		{
+			"input_template_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{InputTemplate: String("one")},
+			&TemplateConfig{InputTemplate: String("one")},
+		},
+		{
+			"input_template_same",
+			&TemplateConfig{InputTemplate: String("one")},
+			&TemplateConfig{InputTemplate: String("one")},
+			&TemplateConfig{InputTemplate: String("one")},
+		},
+		{
+			"log_level_overrides",
+			&TemplateConfig{LogLevel: String("debug")},
+			&TemplateConfig{LogLevel: String("")},
+			&TemplateConfig{LogLevel: String("")},
+		},
+		{
+			"log_level_empty_one",
+			&TemplateConfig{LogLevel: String("debug")},
+			&TemplateConfig{},
+			&TemplateConfig{LogLevel: String("debug")},
+		},
+		{
+			"log_level_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{LogLevel: String("debug")},
+			&TemplateConfig{LogLevel: String("debug")},
+		},
+		{
+			"log_level_same",
+			&TemplateConfig{LogLevel: String("debug")},
+			&TemplateConfig{LogLevel: String("debug")},
+			&TemplateConfig{LogLevel: String("debug")},
+		},
+		{
+			"log_tag_overrides",
+			&TemplateConfig{LogTag: String("tag")},
+			&TemplateConfig{LogTag: String("")},
+			&TemplateConfig{LogTag: String("")},
+		},
+		{
+			"log_tag_empty_one",
+			&TemplateConfig{LogTag: String("tag")},
+			&TemplateConfig{},
+			&TemplateConfig{LogTag: String("tag")},
+		},
+		{
+			"log_tag_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{LogTag: String("tag")},
+			&TemplateConfig{LogTag: String("tag")},
+		},
+		{
+			"log_tag_same",
+			&TemplateConfig{LogTag: String("tag")},
+			&TemplateConfig{LogTag: String("tag")},
+			&TemplateConfig{LogTag: String("tag")},
+		},
+		{
+			"max_output_size_overrides",
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+			&TemplateConfig{MaxOutputSize: Int(0)},
+			&TemplateConfig{MaxOutputSize: Int(0)},
+		},
+		{
+			"max_output_size_empty_one",
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+			&TemplateConfig{},
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+		},
+		{
+			"max_output_size_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+		},
+		{
+			"max_output_size_same",
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+			&TemplateConfig{MaxOutputSize: Int(1024)},
+		},
+		{
+			"max_range_iterations_overrides",
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+			&TemplateConfig{MaxRangeIterations: Int(0)},
+			&TemplateConfig{MaxRangeIterations: Int(0)},
+		},
+		{
+			"max_range_iterations_empty_one",
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+			&TemplateConfig{},
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+		},
+		{
+			"max_range_iterations_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+		},
+		{
+			"max_range_iterations_same",
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+			&TemplateConfig{MaxRangeIterations: Int(100)},
+		},
+		{
+			"min_instances_merges",
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("one")},
+			}},
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("two")},
+			}},
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("one")},
+				&MinInstancesConfig{Service: String("two")},
+			}},
+		},
+		{
+			"min_instances_empty_one",
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("one")},
+			}},
+			&TemplateConfig{},
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("one")},
+			}},
+		},
+		{
+			"min_instances_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("one")},
+			}},
+			&TemplateConfig{MinInstances: &MinInstancesConfigs{
+				&MinInstancesConfig{Service: String("one")},
+			}},
+		},
+		{
+			"name_overrides",
+			&TemplateConfig{Name: String("name")},
+			&TemplateConfig{Name: String("")},
+			&TemplateConfig{Name: String("")},
+		},
+		{
+			"name_empty_one",
+			&TemplateConfig{Name: String("name")},
+			&TemplateConfig{},
+			&TemplateConfig{Name: String("name")},
+		},
+		{
+			"name_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Name: String("name")},
+			&TemplateConfig{Name: String("name")},
+		},
+		{
+			"name_same",
+			&TemplateConfig{Name: String("name")},
+			&TemplateConfig{Name: String("name")},
+			&TemplateConfig{Name: String("name")},
+		},
 		{
 			"perms_overrides",
 			&TemplateConfig{Perms: FileMode(0600)},
@@ -247,6 +809,225 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Perms: FileMode(0600)},
 			&TemplateConfig{Perms: FileMode(0600)},
 		},
+		{
+			"preserve_perms_overrides",
+			&TemplateConfig{PreservePerms: Bool(true)},
+			&TemplateConfig{PreservePerms: Bool(false)},
+			&TemplateConfig{PreservePerms: Bool(false)},
+		},
+		{
+			"preserve_perms_empty_one",
+			&TemplateConfig{PreservePerms: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{PreservePerms: Bool(true)},
+		},
+		{
+			"preserve_perms_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{PreservePerms: Bool(true)},
+			&TemplateConfig{PreservePerms: Bool(true)},
+		},
+		{
+			"preserve_perms_same",
+			&TemplateConfig{PreservePerms: Bool(true)},
+			&TemplateConfig{PreservePerms: Bool(true)},
+			&TemplateConfig{PreservePerms: Bool(true)},
+		},
+		{
+			"priority_overrides",
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+			&TemplateConfig{Priority: String(TemplatePriorityLow)},
+			&TemplateConfig{Priority: String(TemplatePriorityLow)},
+		},
+		{
+			"priority_empty_one",
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+			&TemplateConfig{},
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+		},
+		{
+			"priority_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+		},
+		{
+			"priority_same",
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+			&TemplateConfig{Priority: String(TemplatePriorityHigh)},
+		},
+		{
+			"ready_check_overrides",
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080")}},
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("")}},
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("")}},
+		},
+		{
+			"ready_check_empty_one",
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080")}},
+			&TemplateConfig{},
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080")}},
+		},
+		{
+			"ready_check_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080")}},
+			&TemplateConfig{ReadyCheck: &ReadyCheckConfig{HTTP: String("http://127.0.0.1:8080")}},
+		},
+		{
+			"render_strategy_overrides",
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategyAtomic)},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategyAtomic)},
+		},
+		{
+			"render_strategy_empty_one",
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+			&TemplateConfig{},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+		},
+		{
+			"render_strategy_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+		},
+		{
+			"render_strategy_same",
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+			&TemplateConfig{RenderStrategy: String(TemplateRenderStrategySymlink)},
+		},
+		{
+			"render_versions_overrides",
+			&TemplateConfig{RenderVersions: Int(10)},
+			&TemplateConfig{RenderVersions: Int(2)},
+			&TemplateConfig{RenderVersions: Int(2)},
+		},
+		{
+			"render_versions_empty_one",
+			&TemplateConfig{RenderVersions: Int(10)},
+			&TemplateConfig{},
+			&TemplateConfig{RenderVersions: Int(10)},
+		},
+		{
+			"render_versions_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{RenderVersions: Int(10)},
+			&TemplateConfig{RenderVersions: Int(10)},
+		},
+		{
+			"render_versions_same",
+			&TemplateConfig{RenderVersions: Int(10)},
+			&TemplateConfig{RenderVersions: Int(10)},
+			&TemplateConfig{RenderVersions: Int(10)},
+		},
+		{
+			"sandbox_path_overrides",
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+			&TemplateConfig{SandboxPath: String("")},
+			&TemplateConfig{SandboxPath: String("")},
+		},
+		{
+			"sandbox_path_empty_one",
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+			&TemplateConfig{},
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+		},
+		{
+			"sandbox_path_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+		},
+		{
+			"sandbox_path_same",
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+			&TemplateConfig{SandboxPath: String("/etc/templates")},
+		},
+		{
+			"secret_rotation_merges",
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("one")},
+			}},
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("two")},
+			}},
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("one")},
+				&SecretRotationConfig{Path: String("two")},
+			}},
+		},
+		{
+			"secret_rotation_empty_one",
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("one")},
+			}},
+			&TemplateConfig{},
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("one")},
+			}},
+		},
+		{
+			"secret_rotation_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("one")},
+			}},
+			&TemplateConfig{SecretRotation: &SecretRotationConfigs{
+				&SecretRotationConfig{Path: String("one")},
+			}},
+		},
+		{
+			"seed_file_overrides",
+			&TemplateConfig{SeedFile: String("seed_file")},
+			&TemplateConfig{SeedFile: String("")},
+			&TemplateConfig{SeedFile: String("")},
+		},
+		{
+			"seed_file_empty_one",
+			&TemplateConfig{SeedFile: String("seed_file")},
+			&TemplateConfig{},
+			&TemplateConfig{SeedFile: String("seed_file")},
+		},
+		{
+			"seed_file_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{SeedFile: String("seed_file")},
+			&TemplateConfig{SeedFile: String("seed_file")},
+		},
+		{
+			"seed_file_same",
+			&TemplateConfig{SeedFile: String("seed_file")},
+			&TemplateConfig{SeedFile: String("seed_file")},
+			&TemplateConfig{SeedFile: String("seed_file")},
+		},
+		{
+			"serial_overrides",
+			&TemplateConfig{Serial: Bool(true)},
+			&TemplateConfig{Serial: Bool(false)},
+			&TemplateConfig{Serial: Bool(false)},
+		},
+		{
+			"serial_empty_one",
+			&TemplateConfig{Serial: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{Serial: Bool(true)},
+		},
+		{
+			"serial_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Serial: Bool(true)},
+			&TemplateConfig{Serial: Bool(true)},
+		},
+		{
+			"serial_same",
+			&TemplateConfig{Serial: Bool(true)},
+			&TemplateConfig{Serial: Bool(true)},
+			&TemplateConfig{Serial: Bool(true)},
+		},
 		{
 			"source_overrides",
 			&TemplateConfig{Source: String("source")},
@@ -271,6 +1052,54 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Source: String("source")},
 			&TemplateConfig{Source: String("source")},
 		},
+		{
+			"split_destination_overrides",
+			&TemplateConfig{SplitDestination: Bool(true)},
+			&TemplateConfig{SplitDestination: Bool(false)},
+			&TemplateConfig{SplitDestination: Bool(false)},
+		},
+		{
+			"split_destination_empty_one",
+			&TemplateConfig{SplitDestination: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{SplitDestination: Bool(true)},
+		},
+		{
+			"split_destination_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{SplitDestination: Bool(true)},
+			&TemplateConfig{SplitDestination: Bool(true)},
+		},
+		{
+			"split_destination_same",
+			&TemplateConfig{SplitDestination: Bool(true)},
+			&TemplateConfig{SplitDestination: Bool(true)},
+			&TemplateConfig{SplitDestination: Bool(true)},
+		},
+		{
+			"strict_overrides",
+			&TemplateConfig{Strict: Bool(true)},
+			&TemplateConfig{Strict: Bool(false)},
+			&TemplateConfig{Strict: Bool(false)},
+		},
+		{
+			"strict_empty_one",
+			&TemplateConfig{Strict: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{Strict: Bool(true)},
+		},
+		{
+			"strict_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{Strict: Bool(true)},
+			&TemplateConfig{Strict: Bool(true)},
+		},
+		{
+			"strict_same",
+			&TemplateConfig{Strict: Bool(true)},
+			&TemplateConfig{Strict: Bool(true)},
+			&TemplateConfig{Strict: Bool(true)},
+		},
 		{
 			"wait_overrides",
 			&TemplateConfig{Wait: &WaitConfig{Min: TimeDuration(10)}},
@@ -365,11 +1194,30 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 			"empty",
 			&TemplateConfig{},
 			&TemplateConfig{
+				ACL:            String(""),
+				AddHeader:      Bool(false),
 				Backup:         Bool(false),
+				Blackout:       &BlackoutConfigs{},
+				Bundle:         String(""),
+				BundlePath:     String(""),
 				Command:        String(""),
 				CommandTimeout: TimeDuration(DefaultTemplateCommandTimeout),
-				Contents:       String(""),
-				Destination:    String(""),
+				Compare: &CompareConfig{
+					Command: String(""),
+					Hash:    String(DefaultCompareHash),
+					Mode:    String(CompareModeBytes),
+					Timeout: TimeDuration(DefaultCompareTimeout),
+				},
+				Contents:        String(""),
+				CreateDestDirs:  Bool(true),
+				Critical:        Bool(false),
+				DeleteOnDestroy: Bool(false),
+				DestDirGroup:    String(""),
+				DestDirPerms:    FileMode(DefaultTemplateDestDirPerms),
+				DestDirUser:     String(""),
+				Destination:     String(""),
+				DestroyCommand:  String(""),
+				Encoding:        String(""),
 				Exec: &ExecConfig{
 					Command: String(""),
 					Enabled: Bool(false),
@@ -379,14 +1227,68 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 						Pristine:  Bool(false),
 						Whitelist: []string{},
 					},
-					KillSignal:   Signal(DefaultExecKillSignal),
-					KillTimeout:  TimeDuration(DefaultExecKillTimeout),
-					ReloadSignal: Signal(DefaultExecReloadSignal),
-					Splay:        TimeDuration(0 * time.Second),
-					Timeout:      TimeDuration(DefaultTemplateCommandTimeout),
+					Escalation: &ExecEscalationConfig{
+						Check:    String(""),
+						Enabled:  Bool(false),
+						Interval: TimeDuration(DefaultExecEscalationInterval),
+						Steps:    []string{ExecEscalationStepRestart, ExecEscalationStepKill},
+						Timeout:  TimeDuration(DefaultExecEscalationTimeout),
+					},
+					KillSignal:  Signal(DefaultExecKillSignal),
+					KillTimeout: TimeDuration(DefaultExecKillTimeout),
+					Listeners:   []string{},
+					Monitor: &ExecMonitorConfig{
+						Action:        String(ExecMonitorActionLog),
+						Enabled:       Bool(false),
+						Interval:      TimeDuration(DefaultExecMonitorInterval),
+						MaxCPUPercent: Float64(0),
+						MaxOpenFiles:  Int(0),
+						MaxRSS:        Uint64(0),
+						Signal:        Signal(DefaultExecMonitorSignal),
+					},
+					PrewarmTimeout: TimeDuration(0 * time.Second),
+					ReloadPacing: &ExecReloadPacingConfig{
+						Enabled:       Bool(false),
+						MaxCPUPercent: Float64(0),
+						MaxDelay:      TimeDuration(DefaultExecReloadPacingMaxDelay),
+						MaxRSS:        Uint64(0),
+					},
+					ReloadSignal:   Signal(DefaultExecReloadSignal),
+					Splay:          TimeDuration(0 * time.Second),
+					Supervise:      Bool(false),
+					Timeout:        TimeDuration(DefaultTemplateCommandTimeout),
+					WaitForFile:    String(""),
+					WaitForKey:     String(""),
+					WaitForService: String(""),
+				},
+				HeaderTemplate:     String(DefaultTemplateHeader),
+				ID:                 String(""),
+				InputTemplate:      String(""),
+				LogLevel:           String(""),
+				LogTag:             String(""),
+				MaxOutputSize:      Int(0),
+				MaxRangeIterations: Int(0),
+				MinInstances:       &MinInstancesConfigs{},
+				Name:               String(""),
+				Perms:              FileMode(DefaultTemplateFilePerms),
+				PreservePerms:      Bool(false),
+				Priority:           String(TemplatePriorityNormal),
+				ReadyCheck: &ReadyCheckConfig{
+					Enabled:     Bool(false),
+					GRPC:        String(""),
+					GRPCService: String(""),
+					HTTP:        String(""),
+					Interval:    TimeDuration(DefaultReadyCheckInterval),
 				},
-				Perms:  FileMode(DefaultTemplateFilePerms),
-				Source: String(""),
+				RenderStrategy:   String(TemplateRenderStrategyAtomic),
+				RenderVersions:   Int(DefaultTemplateRenderVersions),
+				SandboxPath:      String(""),
+				SecretRotation:   &SecretRotationConfigs{},
+				SeedFile:         String(""),
+				Serial:           Bool(false),
+				Source:           String(""),
+				SplitDestination: Bool(false),
+				Strict:           Bool(false),
 				Wait: &WaitConfig{
 					Enabled: Bool(false),
 					Max:     TimeDuration(0 * time.Second),
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/pkg/errors"
+)
+
+// ParseVar parses a variable in the format "key=value".
+func ParseVar(s string) (string, string, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("var: invalid format %q, expected key=value", s)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ParseVarFile reads the variables in the HCL or JSON file at the given path.
+// The file contains top-level "key = value" pairs, and all values must be
+// strings.
+func ParseVarFile(path string) (map[string]string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "var file")
+	}
+
+	var parsed map[string]interface{}
+	if err := hcl.Decode(&parsed, string(contents)); err != nil {
+		return nil, errors.Wrapf(err, "var file %s", path)
+	}
+
+	vars := make(map[string]string, len(parsed))
+	for k, v := range parsed {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("var file %s: %q must be a string", path, k)
+		}
+		vars[k] = s
+	}
+	return vars, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseVar(t *testing.T) {
+	cases := []struct {
+		name string
+		s    string
+		k    string
+		v    string
+		err  bool
+	}{
+		{
+			"key_value",
+			"region=us-east-1",
+			"region",
+			"us-east-1",
+			false,
+		},
+		{
+			"value_with_equals",
+			"dsn=a=b",
+			"dsn",
+			"a=b",
+			false,
+		},
+		{
+			"empty_value",
+			"region=",
+			"region",
+			"",
+			false,
+		},
+		{
+			"missing_equals",
+			"region",
+			"",
+			"",
+			true,
+		},
+		{
+			"missing_key",
+			"=us-east-1",
+			"",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			k, v, err := ParseVar(tc.s)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if k != tc.k || v != tc.v {
+				t.Errorf("\nexp: %q=%q\nact: %q=%q", tc.k, tc.v, k, v)
+			}
+		})
+	}
+}
+
+func TestParseVarFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		contents string
+		e        map[string]string
+		err      bool
+	}{
+		{
+			"hcl",
+			`region = "us-east-1"`,
+			map[string]string{"region": "us-east-1"},
+			false,
+		},
+		{
+			"json",
+			`{"region": "us-east-1", "zone": "a"}`,
+			map[string]string{"region": "us-east-1", "zone": "a"},
+			false,
+		},
+		{
+			"not_a_string",
+			`count = 3`,
+			nil,
+			true,
+		},
+		{
+			"invalid",
+			`region {`,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			f, err := ioutil.TempFile("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(tc.contents); err != nil {
+				t.Fatal(err)
+			}
+
+			vars, err := ParseVarFile(f.Name())
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.e, vars) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, vars)
+			}
+		})
+	}
+}
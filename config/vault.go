@@ -2,11 +2,23 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 )
 
 const (
+	// VaultAuthMethodToken authenticates to Vault with the configured token.
+	VaultAuthMethodToken = "token"
+
+	// VaultAuthMethodCert authenticates to Vault with the configured TLS client
+	// certificate, using Vault's cert auth method.
+	VaultAuthMethodCert = "cert"
+
+	// DefaultVaultAuthMethod is the default method used to authenticate to
+	// Vault.
+	DefaultVaultAuthMethod = VaultAuthMethodToken
+
 	// DefaultVaultRenewToken is the default value for it the Vault token should
 	// be renewed.
 	DefaultVaultRenewToken = true
@@ -14,6 +26,14 @@ const (
 	// DefaultVaultUnwrapToken is the default value for it the Vault token should
 	// be unwrapped.
 	DefaultVaultUnwrapToken = false
+
+	// DefaultVaultHealthCheckInterval is the default interval at which the
+	// Vault addresses are probed when more than one is configured.
+	DefaultVaultHealthCheckInterval = 10 * time.Second
+
+	// DefaultVaultPKIRenewFraction is the default fraction of the validity of
+	// a PKI certificate after which a new certificate is issued.
+	DefaultVaultPKIRenewFraction = 0.5
 )
 
 // VaultConfig is the configuration for connecting to a vault server.
@@ -21,9 +41,48 @@ type VaultConfig struct {
 	// Address is the URI to the Vault server.
 	Address *string `mapstructure:"address"`
 
+	// Addresses are the URIs of other Vault servers, which are failed over to,
+	// in order, if the servers before them are unhealthy. Requests go to the
+	// first healthy active node, or to a standby if no active node is
+	// reachable, and move back once an earlier server is healthy again.
+	Addresses []string `mapstructure:"addresses"`
+
+	// AuthMethod is the method used to authenticate to Vault, either "token"
+	// or "cert". With "cert", Consul Template logs in with the TLS client
+	// certificate from the SSL configuration and logs in again before the
+	// returned token expires.
+	AuthMethod *string `mapstructure:"auth_method"`
+
+	// AuthMount is the path the auth method is mounted at. It defaults to the
+	// name of the auth method.
+	AuthMount *string `mapstructure:"auth_mount"`
+
+	// AuthRole is the name of the role to log in with. If empty, Vault picks a
+	// role which matches the client certificate.
+	AuthRole *string `mapstructure:"auth_role"`
+
+	// CheckCapabilities checks the capabilities of the token on the paths of
+	// the Vault dependencies before they are watched, failing with a report of
+	// every missing permission instead of retrying the denied requests.
+	CheckCapabilities *bool `mapstructure:"check_capabilities"`
+
 	// Enabled controls whether the Vault integration is active.
 	Enabled *bool `mapstructure:"enabled"`
 
+	// HealthCheckInterval is the interval at which the health of each Vault
+	// server is probed, when Addresses are configured.
+	HealthCheckInterval *time.Duration `mapstructure:"health_check_interval"`
+
+	// PKIRenewFraction is the fraction of the validity of a certificate
+	// issued by the pkiCert function after which a new certificate is issued.
+	// It must be between 0 and 1.
+	PKIRenewFraction *float64 `mapstructure:"pki_renew_fraction"`
+
+	// PerformanceStandbyOK allows failing over to performance standby nodes,
+	// which serve reads themselves and may lag behind the active node. They
+	// are not used by default. Disaster recovery secondaries are never used.
+	PerformanceStandbyOK *bool `mapstructure:"performance_standby_ok"`
+
 	// RenewToken renews the Vault token.
 	RenewToken *bool `mapstructure:"renew_token"`
 
@@ -71,8 +130,26 @@ func (c *VaultConfig) Copy() *VaultConfig {
 	var o VaultConfig
 	o.Address = c.Address
 
+	if c.Addresses != nil {
+		o.Addresses = append([]string{}, c.Addresses...)
+	}
+
+	o.AuthMethod = c.AuthMethod
+
+	o.AuthMount = c.AuthMount
+
+	o.AuthRole = c.AuthRole
+
+	o.CheckCapabilities = c.CheckCapabilities
+
 	o.Enabled = c.Enabled
 
+	o.HealthCheckInterval = c.HealthCheckInterval
+
+	o.PKIRenewFraction = c.PKIRenewFraction
+
+	o.PerformanceStandbyOK = c.PerformanceStandbyOK
+
 	o.RenewToken = c.RenewToken
 
 	if c.SSL != nil {
@@ -108,10 +185,42 @@ func (c *VaultConfig) Merge(o *VaultConfig) *VaultConfig {
 		r.Address = o.Address
 	}
 
+	if o.Addresses != nil {
+		r.Addresses = append(r.Addresses, o.Addresses...)
+	}
+
+	if o.AuthMethod != nil {
+		r.AuthMethod = o.AuthMethod
+	}
+
+	if o.AuthMount != nil {
+		r.AuthMount = o.AuthMount
+	}
+
+	if o.AuthRole != nil {
+		r.AuthRole = o.AuthRole
+	}
+
+	if o.CheckCapabilities != nil {
+		r.CheckCapabilities = o.CheckCapabilities
+	}
+
 	if o.Enabled != nil {
 		r.Enabled = o.Enabled
 	}
 
+	if o.HealthCheckInterval != nil {
+		r.HealthCheckInterval = o.HealthCheckInterval
+	}
+
+	if o.PKIRenewFraction != nil {
+		r.PKIRenewFraction = o.PKIRenewFraction
+	}
+
+	if o.PerformanceStandbyOK != nil {
+		r.PerformanceStandbyOK = o.PerformanceStandbyOK
+	}
+
 	if o.RenewToken != nil {
 		r.RenewToken = o.RenewToken
 	}
@@ -134,13 +243,45 @@ func (c *VaultConfig) Merge(o *VaultConfig) *VaultConfig {
 // Finalize ensures there no nil pointers.
 func (c *VaultConfig) Finalize() {
 	if c.Enabled == nil {
-		c.Enabled = Bool(StringPresent(c.Address))
+		c.Enabled = Bool(StringPresent(c.Address) || len(c.Addresses) > 0)
 	}
 
 	if c.Address == nil {
 		c.Address = String("")
 	}
 
+	if c.Addresses == nil {
+		c.Addresses = []string{}
+	}
+
+	if c.AuthMethod == nil {
+		c.AuthMethod = String(DefaultVaultAuthMethod)
+	}
+
+	if c.AuthMount == nil {
+		c.AuthMount = String(StringVal(c.AuthMethod))
+	}
+
+	if c.AuthRole == nil {
+		c.AuthRole = String("")
+	}
+
+	if c.CheckCapabilities == nil {
+		c.CheckCapabilities = Bool(false)
+	}
+
+	if c.HealthCheckInterval == nil {
+		c.HealthCheckInterval = TimeDuration(DefaultVaultHealthCheckInterval)
+	}
+
+	if c.PKIRenewFraction == nil {
+		c.PKIRenewFraction = Float64(DefaultVaultPKIRenewFraction)
+	}
+
+	if c.PerformanceStandbyOK == nil {
+		c.PerformanceStandbyOK = Bool(false)
+	}
+
 	if c.RenewToken == nil {
 		c.RenewToken = Bool(DefaultVaultRenewToken)
 	}
@@ -168,6 +309,14 @@ func (c *VaultConfig) GoString() string {
 	return fmt.Sprintf("&VaultConfig{"+
 		"Enabled:%s, "+
 		"Address:%s, "+
+		"Addresses:%v, "+
+		"AuthMethod:%s, "+
+		"AuthMount:%s, "+
+		"AuthRole:%s, "+
+		"CheckCapabilities:%s, "+
+		"HealthCheckInterval:%s, "+
+		"PKIRenewFraction:%s, "+
+		"PerformanceStandbyOK:%s, "+
 		"Token:%s, "+
 		"UnwrapToken:%s, "+
 		"RenewToken:%s, "+
@@ -175,6 +324,14 @@ func (c *VaultConfig) GoString() string {
 		"}",
 		BoolGoString(c.Enabled),
 		StringGoString(c.Address),
+		c.Addresses,
+		StringGoString(c.AuthMethod),
+		StringGoString(c.AuthMount),
+		StringGoString(c.AuthRole),
+		BoolGoString(c.CheckCapabilities),
+		TimeDurationGoString(c.HealthCheckInterval),
+		Float64GoString(c.PKIRenewFraction),
+		BoolGoString(c.PerformanceStandbyOK),
 		StringGoString(c.Token),
 		BoolGoString(c.UnwrapToken),
 		BoolGoString(c.RenewToken),
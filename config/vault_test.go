@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestVaultConfig_Copy(t *testing.T) {
@@ -22,12 +23,20 @@ func TestVaultConfig_Copy(t *testing.T) {
 		{
 			"same_enabled",
 			&VaultConfig{
-				Address:     String("address"),
-				Enabled:     Bool(true),
-				RenewToken:  Bool(true),
-				SSL:         &SSLConfig{Enabled: Bool(true)},
-				Token:       String("token"),
-				UnwrapToken: Bool(true),
+				Address:              String("address"),
+				Addresses:            []string{"address2"},
+				AuthMethod:           String("cert"),
+				AuthMount:            String("cert"),
+				AuthRole:             String("web"),
+				CheckCapabilities:    Bool(true),
+				Enabled:              Bool(true),
+				HealthCheckInterval:  TimeDuration(5 * time.Second),
+				PKIRenewFraction:     Float64(0.75),
+				PerformanceStandbyOK: Bool(true),
+				RenewToken:           Bool(true),
+				SSL:                  &SSLConfig{Enabled: Bool(true)},
+				Token:                String("token"),
+				UnwrapToken:          Bool(true),
 			},
 		},
 	}
@@ -121,6 +130,192 @@ func TestVaultConfig_Merge(t *testing.T) {
 			&VaultConfig{Address: String("address")},
 			&VaultConfig{Address: String("address")},
 		},
+		{
+			"addresses_merges",
+			&VaultConfig{Addresses: []string{"a"}},
+			&VaultConfig{Addresses: []string{"b"}},
+			&VaultConfig{Addresses: []string{"a", "b"}},
+		},
+		{
+			"addresses_empty_one",
+			&VaultConfig{Addresses: []string{"a"}},
+			&VaultConfig{},
+			&VaultConfig{Addresses: []string{"a"}},
+		},
+		{
+			"addresses_empty_two",
+			&VaultConfig{},
+			&VaultConfig{Addresses: []string{"a"}},
+			&VaultConfig{Addresses: []string{"a"}},
+		},
+		{
+			"health_check_interval_overrides",
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+			&VaultConfig{HealthCheckInterval: TimeDuration(0)},
+			&VaultConfig{HealthCheckInterval: TimeDuration(0)},
+		},
+		{
+			"health_check_interval_empty_one",
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+			&VaultConfig{},
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"health_check_interval_empty_two",
+			&VaultConfig{},
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"health_check_interval_same",
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+			&VaultConfig{HealthCheckInterval: TimeDuration(10 * time.Second)},
+		},
+		{
+			"pki_renew_fraction_overrides",
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+			&VaultConfig{PKIRenewFraction: Float64(0.75)},
+			&VaultConfig{PKIRenewFraction: Float64(0.75)},
+		},
+		{
+			"pki_renew_fraction_empty_one",
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+			&VaultConfig{},
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+		},
+		{
+			"pki_renew_fraction_empty_two",
+			&VaultConfig{},
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+		},
+		{
+			"pki_renew_fraction_same",
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+			&VaultConfig{PKIRenewFraction: Float64(0.5)},
+		},
+		{
+			"performance_standby_ok_overrides",
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+			&VaultConfig{PerformanceStandbyOK: Bool(false)},
+			&VaultConfig{PerformanceStandbyOK: Bool(false)},
+		},
+		{
+			"performance_standby_ok_empty_one",
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+			&VaultConfig{},
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+		},
+		{
+			"performance_standby_ok_empty_two",
+			&VaultConfig{},
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+		},
+		{
+			"performance_standby_ok_same",
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+			&VaultConfig{PerformanceStandbyOK: Bool(true)},
+		},
+		{
+			"auth_method_overrides",
+			&VaultConfig{AuthMethod: String("cert")},
+			&VaultConfig{AuthMethod: String("token")},
+			&VaultConfig{AuthMethod: String("token")},
+		},
+		{
+			"auth_method_empty_one",
+			&VaultConfig{AuthMethod: String("cert")},
+			&VaultConfig{},
+			&VaultConfig{AuthMethod: String("cert")},
+		},
+		{
+			"auth_method_empty_two",
+			&VaultConfig{},
+			&VaultConfig{AuthMethod: String("cert")},
+			&VaultConfig{AuthMethod: String("cert")},
+		},
+		{
+			"auth_method_same",
+			&VaultConfig{AuthMethod: String("cert")},
+			&VaultConfig{AuthMethod: String("cert")},
+			&VaultConfig{AuthMethod: String("cert")},
+		},
+		{
+			"auth_mount_overrides",
+			&VaultConfig{AuthMount: String("cert")},
+			&VaultConfig{AuthMount: String("certs")},
+			&VaultConfig{AuthMount: String("certs")},
+		},
+		{
+			"auth_mount_empty_one",
+			&VaultConfig{AuthMount: String("cert")},
+			&VaultConfig{},
+			&VaultConfig{AuthMount: String("cert")},
+		},
+		{
+			"auth_mount_empty_two",
+			&VaultConfig{},
+			&VaultConfig{AuthMount: String("cert")},
+			&VaultConfig{AuthMount: String("cert")},
+		},
+		{
+			"auth_mount_same",
+			&VaultConfig{AuthMount: String("cert")},
+			&VaultConfig{AuthMount: String("cert")},
+			&VaultConfig{AuthMount: String("cert")},
+		},
+		{
+			"auth_role_overrides",
+			&VaultConfig{AuthRole: String("web")},
+			&VaultConfig{AuthRole: String("db")},
+			&VaultConfig{AuthRole: String("db")},
+		},
+		{
+			"auth_role_empty_one",
+			&VaultConfig{AuthRole: String("web")},
+			&VaultConfig{},
+			&VaultConfig{AuthRole: String("web")},
+		},
+		{
+			"auth_role_empty_two",
+			&VaultConfig{},
+			&VaultConfig{AuthRole: String("web")},
+			&VaultConfig{AuthRole: String("web")},
+		},
+		{
+			"auth_role_same",
+			&VaultConfig{AuthRole: String("web")},
+			&VaultConfig{AuthRole: String("web")},
+			&VaultConfig{AuthRole: String("web")},
+		},
+		{
+			"check_capabilities_overrides",
+			&VaultConfig{CheckCapabilities: Bool(true)},
+			&VaultConfig{CheckCapabilities: Bool(false)},
+			&VaultConfig{CheckCapabilities: Bool(false)},
+		},
+		{
+			"check_capabilities_empty_one",
+			&VaultConfig{CheckCapabilities: Bool(true)},
+			&VaultConfig{},
+			&VaultConfig{CheckCapabilities: Bool(true)},
+		},
+		{
+			"check_capabilities_empty_two",
+			&VaultConfig{},
+			&VaultConfig{CheckCapabilities: Bool(true)},
+			&VaultConfig{CheckCapabilities: Bool(true)},
+		},
+		{
+			"check_capabilities_same",
+			&VaultConfig{CheckCapabilities: Bool(true)},
+			&VaultConfig{CheckCapabilities: Bool(true)},
+			&VaultConfig{CheckCapabilities: Bool(true)},
+		},
 		{
 			"token_overrides",
 			&VaultConfig{Token: String("token")},
@@ -239,9 +434,17 @@ func TestVaultConfig_Finalize(t *testing.T) {
 			"empty",
 			&VaultConfig{},
 			&VaultConfig{
-				Address:    String(""),
-				Enabled:    Bool(false),
-				RenewToken: Bool(DefaultVaultRenewToken),
+				Address:              String(""),
+				Addresses:            []string{},
+				AuthMethod:           String(DefaultVaultAuthMethod),
+				AuthMount:            String(DefaultVaultAuthMethod),
+				AuthRole:             String(""),
+				CheckCapabilities:    Bool(false),
+				Enabled:              Bool(false),
+				HealthCheckInterval:  TimeDuration(DefaultVaultHealthCheckInterval),
+				PKIRenewFraction:     Float64(DefaultVaultPKIRenewFraction),
+				PerformanceStandbyOK: Bool(false),
+				RenewToken:           Bool(DefaultVaultRenewToken),
 				SSL: &SSLConfig{
 					CaCert:     String(""),
 					CaPath:     String(""),
@@ -261,9 +464,47 @@ func TestVaultConfig_Finalize(t *testing.T) {
 				Address: String("address"),
 			},
 			&VaultConfig{
-				Address:    String("address"),
-				Enabled:    Bool(true),
-				RenewToken: Bool(DefaultVaultRenewToken),
+				Address:              String("address"),
+				Addresses:            []string{},
+				AuthMethod:           String(DefaultVaultAuthMethod),
+				AuthMount:            String(DefaultVaultAuthMethod),
+				AuthRole:             String(""),
+				CheckCapabilities:    Bool(false),
+				Enabled:              Bool(true),
+				HealthCheckInterval:  TimeDuration(DefaultVaultHealthCheckInterval),
+				PKIRenewFraction:     Float64(DefaultVaultPKIRenewFraction),
+				PerformanceStandbyOK: Bool(false),
+				RenewToken:           Bool(DefaultVaultRenewToken),
+				SSL: &SSLConfig{
+					CaCert:     String(""),
+					CaPath:     String(""),
+					Cert:       String(""),
+					Enabled:    Bool(false),
+					Key:        String(""),
+					ServerName: String(""),
+					Verify:     Bool(true),
+				},
+				Token:       String(""),
+				UnwrapToken: Bool(DefaultVaultUnwrapToken),
+			},
+		},
+		{
+			"with_addresses",
+			&VaultConfig{
+				Addresses: []string{"address"},
+			},
+			&VaultConfig{
+				Address:              String(""),
+				Addresses:            []string{"address"},
+				AuthMethod:           String(DefaultVaultAuthMethod),
+				AuthMount:            String(DefaultVaultAuthMethod),
+				AuthRole:             String(""),
+				CheckCapabilities:    Bool(false),
+				Enabled:              Bool(true),
+				HealthCheckInterval:  TimeDuration(DefaultVaultHealthCheckInterval),
+				PKIRenewFraction:     Float64(DefaultVaultPKIRenewFraction),
+				PerformanceStandbyOK: Bool(false),
+				RenewToken:           Bool(DefaultVaultRenewToken),
 				SSL: &SSLConfig{
 					CaCert:     String(""),
 					CaPath:     String(""),
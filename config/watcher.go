@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultWatcherBufferSize is the default number of updates the watcher
+	// buffers before the backpressure policy applies.
+	DefaultWatcherBufferSize = 2048
+
+	// WatcherBackpressureBlock makes a dependency wait for room in the buffer
+	// before publishing its update.
+	WatcherBackpressureBlock = "block"
+
+	// WatcherBackpressureCoalesce keeps at most one pending update per
+	// dependency, which always carries its latest data.
+	WatcherBackpressureCoalesce = "coalesce"
+
+	// WatcherBackpressureDrop drops the update of a dependency when the buffer
+	// is full.
+	WatcherBackpressureDrop = "drop"
+)
+
+// WatcherConfig is the configuration for the buffer between the dependency
+// watches and the runner.
+type WatcherConfig struct {
+	// Backpressure is the policy applied when dependencies update faster than
+	// the runner processes them. It is one of "block", "coalesce" or "drop".
+	Backpressure *string `mapstructure:"backpressure"`
+
+	// BufferSize is the number of updates which are buffered before the
+	// backpressure policy applies.
+	BufferSize *int `mapstructure:"buffer_size"`
+
+	// MaxConcurrentFetches is the maximum number of requests which establish
+	// the watches of dependencies, made before a dependency has data or while
+	// it retries after an error, that run at the same time. Waiting requests
+	// run in the order of the priority of their dependency, which is set by the
+	// templates. Zero does not limit them.
+	MaxConcurrentFetches *int `mapstructure:"max_concurrent_fetches"`
+
+	// StaleGracePeriod is how long templates keep rendering with the last data
+	// of a dependency which starts failing after having returned data. Its
+	// errors are logged as warnings until then. If it still fails once the
+	// period ends, the runner exits with an error. Zero disables the period,
+	// so failing dependencies are retried forever.
+	StaleGracePeriod *time.Duration `mapstructure:"stale_grace_period"`
+}
+
+// DefaultWatcherConfig returns a configuration that is populated with the
+// default values.
+func DefaultWatcherConfig() *WatcherConfig {
+	return &WatcherConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *WatcherConfig) Copy() *WatcherConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o WatcherConfig
+	o.Backpressure = c.Backpressure
+	o.BufferSize = c.BufferSize
+	o.MaxConcurrentFetches = c.MaxConcurrentFetches
+	o.StaleGracePeriod = c.StaleGracePeriod
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *WatcherConfig) Merge(o *WatcherConfig) *WatcherConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Backpressure != nil {
+		r.Backpressure = o.Backpressure
+	}
+
+	if o.BufferSize != nil {
+		r.BufferSize = o.BufferSize
+	}
+
+	if o.MaxConcurrentFetches != nil {
+		r.MaxConcurrentFetches = o.MaxConcurrentFetches
+	}
+
+	if o.StaleGracePeriod != nil {
+		r.StaleGracePeriod = o.StaleGracePeriod
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *WatcherConfig) Finalize() {
+	if c.Backpressure == nil {
+		c.Backpressure = String(WatcherBackpressureBlock)
+	}
+
+	if c.BufferSize == nil {
+		c.BufferSize = Int(DefaultWatcherBufferSize)
+	}
+
+	if c.MaxConcurrentFetches == nil {
+		c.MaxConcurrentFetches = Int(0)
+	}
+
+	if c.StaleGracePeriod == nil {
+		c.StaleGracePeriod = TimeDuration(0)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *WatcherConfig) GoString() string {
+	if c == nil {
+		return "(*WatcherConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&WatcherConfig{"+
+		"Backpressure:%s, "+
+		"BufferSize:%s, "+
+		"MaxConcurrentFetches:%s, "+
+		"StaleGracePeriod:%s"+
+		"}",
+		StringGoString(c.Backpressure),
+		IntGoString(c.BufferSize),
+		IntGoString(c.MaxConcurrentFetches),
+		TimeDurationGoString(c.StaleGracePeriod),
+	)
+}
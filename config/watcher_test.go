@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcherConfig_Copy(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *WatcherConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&WatcherConfig{},
+		},
+		{
+			"full",
+			&WatcherConfig{
+				Backpressure:         String(WatcherBackpressureCoalesce),
+				BufferSize:           Int(512),
+				MaxConcurrentFetches: Int(8),
+				StaleGracePeriod:     TimeDuration(30 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestWatcherConfig_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *WatcherConfig
+		b    *WatcherConfig
+		r    *WatcherConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&WatcherConfig{},
+			&WatcherConfig{},
+		},
+		{
+			"nil_b",
+			&WatcherConfig{},
+			nil,
+			&WatcherConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&WatcherConfig{},
+			&WatcherConfig{},
+			&WatcherConfig{},
+		},
+		{
+			"backpressure_overrides",
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureBlock)},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureBlock)},
+		},
+		{
+			"backpressure_empty_one",
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+			&WatcherConfig{},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+		},
+		{
+			"backpressure_empty_two",
+			&WatcherConfig{},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+		},
+		{
+			"backpressure_same",
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+			&WatcherConfig{Backpressure: String(WatcherBackpressureDrop)},
+		},
+		{
+			"buffer_size_overrides",
+			&WatcherConfig{BufferSize: Int(512)},
+			&WatcherConfig{BufferSize: Int(0)},
+			&WatcherConfig{BufferSize: Int(0)},
+		},
+		{
+			"buffer_size_empty_one",
+			&WatcherConfig{BufferSize: Int(512)},
+			&WatcherConfig{},
+			&WatcherConfig{BufferSize: Int(512)},
+		},
+		{
+			"buffer_size_empty_two",
+			&WatcherConfig{},
+			&WatcherConfig{BufferSize: Int(512)},
+			&WatcherConfig{BufferSize: Int(512)},
+		},
+		{
+			"buffer_size_same",
+			&WatcherConfig{BufferSize: Int(512)},
+			&WatcherConfig{BufferSize: Int(512)},
+			&WatcherConfig{BufferSize: Int(512)},
+		},
+		{
+			"max_concurrent_fetches_overrides",
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+			&WatcherConfig{MaxConcurrentFetches: Int(0)},
+			&WatcherConfig{MaxConcurrentFetches: Int(0)},
+		},
+		{
+			"max_concurrent_fetches_empty_one",
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+			&WatcherConfig{},
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+		},
+		{
+			"max_concurrent_fetches_empty_two",
+			&WatcherConfig{},
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+		},
+		{
+			"max_concurrent_fetches_same",
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+			&WatcherConfig{MaxConcurrentFetches: Int(8)},
+		},
+		{
+			"stale_grace_period_overrides",
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(0)},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(0)},
+		},
+		{
+			"stale_grace_period_empty_one",
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+			&WatcherConfig{},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+		},
+		{
+			"stale_grace_period_empty_two",
+			&WatcherConfig{},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+		},
+		{
+			"stale_grace_period_same",
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+			&WatcherConfig{StaleGracePeriod: TimeDuration(30 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestWatcherConfig_Finalize(t *testing.T) {
+	cases := []struct {
+		name string
+		i    *WatcherConfig
+		r    *WatcherConfig
+	}{
+		{
+			"empty",
+			&WatcherConfig{},
+			&WatcherConfig{
+				Backpressure:         String(WatcherBackpressureBlock),
+				BufferSize:           Int(DefaultWatcherBufferSize),
+				MaxConcurrentFetches: Int(0),
+				StaleGracePeriod:     TimeDuration(0),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// CtlCommand is the name of the subcommand which controls a running instance
+// through its control socket.
+const CtlCommand = "ctl"
+
+// ctlMethods is the HTTP method of the request made for each ctl command.
+var ctlMethods = map[string]string{
+	"events":            http.MethodGet,
+	"pause":             http.MethodPost,
+	"pause-dependency":  http.MethodPost,
+	"promote":           http.MethodPost,
+	"reload":            http.MethodPost,
+	"render":            http.MethodPost,
+	"resume":            http.MethodPost,
+	"resume-dependency": http.MethodPost,
+	"state":             http.MethodGet,
+}
+
+// ctlParams is the query parameter the argument of each ctl command which takes
+// one is sent as.
+var ctlParams = map[string]string{
+	"pause-dependency":  "dependency",
+	"resume-dependency": "dependency",
+}
+
+// runCtl sends a single command to the control interface of a running instance
+// and writes any response to the output stream. The events command streams
+// events until the connection is closed or the CLI is stopped.
+func (cli *CLI) runCtl(args []string) int {
+	c, command, arg, err := cli.ParseCtlFlags(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return cli.handleError(err, ExitCodeParseFlagsError)
+	}
+
+	client, base := ctlClient(c)
+	u := base + "/v1/" + command
+	if param, ok := ctlParams[command]; ok {
+		u += "?" + url.Values{param: {arg}}.Encode()
+	}
+	req, err := http.NewRequest(ctlMethods[command], u, nil)
+	if err != nil {
+		return cli.handleError(err, ExitCodeError)
+	}
+	if token := config.StringVal(c.Token); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// Abort a streaming request when the CLI is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-cli.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req = req.WithContext(ctx)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return cli.handleError(fmt.Errorf("ctl: %s", err), ExitCodeError)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return cli.handleError(fmt.Errorf("ctl: %s failed: %s: %s",
+			command, resp.Status, strings.TrimSpace(string(body))), ExitCodeError)
+	}
+
+	if _, err := io.Copy(cli.outStream, resp.Body); err != nil {
+		select {
+		case <-cli.stopCh:
+			return ExitCodeOK
+		default:
+		}
+		return cli.handleError(fmt.Errorf("ctl: %s", err), ExitCodeError)
+	}
+
+	return ExitCodeOK
+}
+
+// ctlClient returns the HTTP client and base URL for the control interface. The
+// unix socket is used if a path is configured, and the TCP address otherwise.
+func ctlClient(c *config.ControlConfig) (*http.Client, string) {
+	transport := &http.Transport{}
+	base := "http://" + config.StringVal(c.Address)
+
+	if path := config.StringVal(c.Path); path != "" {
+		transport.Dial = func(_, _ string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		}
+		base = "http://unix"
+	}
+
+	return &http.Client{Transport: transport}, base
+}
+
+// ParseCtlFlags parses the flags of the ctl subcommand and returns the control
+// configuration, the command to send and its argument, if it takes one. The
+// configuration is read from the given configuration files, with the flags
+// taking precedence.
+func (cli *CLI) ParseCtlFlags(args []string) (*config.ControlConfig, string, string, error) {
+	c := config.DefaultControlConfig()
+
+	// configPaths stores the list of configuration paths on disk
+	configPaths := make([]string, 0, 6)
+
+	flags := flag.NewFlagSet(CtlCommand, flag.ContinueOnError)
+	flags.SetOutput(cli.errStream)
+	flags.Usage = func() { fmt.Fprintf(cli.errStream, ctlUsage, Name) }
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Address = config.String(s)
+		return nil
+	}), "address", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		configPaths = append(configPaths, s)
+		return nil
+	}), "config", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Path = config.String(s)
+		return nil
+	}), "path", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Token = config.String(s)
+		return nil
+	}), "token", "")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, "", "", err
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		return nil, "", "", fmt.Errorf("cli: %s: expected exactly one command, got %q",
+			CtlCommand, args)
+	}
+	command := args[0]
+	if _, ok := ctlMethods[command]; !ok {
+		return nil, "", "", fmt.Errorf("cli: %s: unknown command %q", CtlCommand, command)
+	}
+
+	var arg string
+	if param, ok := ctlParams[command]; ok {
+		if len(args) != 2 {
+			return nil, "", "", fmt.Errorf("cli: %s: %s expects exactly one %s, got %q",
+				CtlCommand, command, param, args[1:])
+		}
+		arg = args[1]
+	} else if len(args) != 1 {
+		return nil, "", "", fmt.Errorf("cli: %s: expected exactly one command, got %q",
+			CtlCommand, args)
+	}
+
+	finalC := config.DefaultControlConfig()
+	for _, path := range configPaths {
+		fc, err := config.FromPath(path)
+		if err != nil {
+			return nil, "", "", err
+		}
+		finalC = finalC.Merge(fc.Control)
+	}
+
+	// A path or address given as a flag replaces both from the configuration.
+	if c.Path != nil || c.Address != nil {
+		finalC.Path, finalC.Address = nil, nil
+	}
+	finalC = finalC.Merge(c)
+	finalC.Finalize()
+
+	if !config.StringPresent(finalC.Path) && !config.StringPresent(finalC.Address) {
+		return nil, "", "", fmt.Errorf("cli: %s: -path or -address is required", CtlCommand)
+	}
+
+	return finalC, command, arg, nil
+}
+
+const ctlUsage = `
+Usage: %s ctl [options] <command> [<dependency>]
+
+  Sends a command to the control interface of a running instance. The control
+  interface is enabled with the "control" block of the configuration.
+
+Commands:
+
+  events     Stream the events of the runner as newline-delimited JSON
+  pause      Stop rendering templates and running commands
+  promote    Promote the instance from standby
+  reload     Reload the configuration
+  render     Render all templates now, even if paused or waiting to quiesce
+  resume     Resume a paused instance
+  state      Print the state of the runner, its templates and dependencies
+
+  pause-dependency <dependency>
+             Stop polling a dependency, as named by the state command
+  resume-dependency <dependency>
+             Resume polling a paused dependency
+
+Options:
+
+  -address=<address>
+      Sets the TCP address of the control interface
+
+  -config=<path>
+      Sets the path to a configuration file or folder on disk to read the
+      control settings from. This can be specified multiple times
+
+  -path=<path>
+      Sets the path of the control socket. It takes precedence over the
+      address
+
+  -token=<token>
+      Sets the token of the control interface
+`
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/manager"
+)
+
+func TestCLI_ParseCtlFlags(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+		control {
+			address = "127.0.0.1:8559"
+			token   = "abcd1234"
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		f       []string
+		e       *config.ControlConfig
+		command string
+		arg     string
+		err     bool
+	}{
+		{
+			"path",
+			[]string{"-path", "/run/ct.sock", "pause"},
+			&config.ControlConfig{
+				Path: config.String("/run/ct.sock"),
+			},
+			"pause",
+			"",
+			false,
+		},
+		{
+			"config",
+			[]string{"-config", f.Name(), "state"},
+			&config.ControlConfig{
+				Address: config.String("127.0.0.1:8559"),
+				Token:   config.String("abcd1234"),
+			},
+			"state",
+			"",
+			false,
+		},
+		{
+			"flags_override_config",
+			[]string{"-config", f.Name(), "-path", "/run/ct.sock", "reload"},
+			&config.ControlConfig{
+				Path:  config.String("/run/ct.sock"),
+				Token: config.String("abcd1234"),
+			},
+			"reload",
+			"",
+			false,
+		},
+		{
+			"dependency",
+			[]string{"-path", "/run/ct.sock", "pause-dependency", "vault.read(secret/foo)"},
+			&config.ControlConfig{
+				Path: config.String("/run/ct.sock"),
+			},
+			"pause-dependency",
+			"vault.read(secret/foo)",
+			false,
+		},
+		{
+			"missing_dependency",
+			[]string{"-path", "/run/ct.sock", "resume-dependency"},
+			nil,
+			"",
+			"",
+			true,
+		},
+		{
+			"unexpected_argument",
+			[]string{"-path", "/run/ct.sock", "pause", "vault.read(secret/foo)"},
+			nil,
+			"",
+			"",
+			true,
+		},
+		{
+			"missing_path",
+			[]string{"pause"},
+			nil,
+			"",
+			"",
+			true,
+		},
+		{
+			"missing_command",
+			[]string{"-path", "/run/ct.sock"},
+			nil,
+			"",
+			"",
+			true,
+		},
+		{
+			"unknown_command",
+			[]string{"-path", "/run/ct.sock", "restart"},
+			nil,
+			"",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var out bytes.Buffer
+			cli := NewCLI(&out, &out)
+
+			c, command, arg, err := cli.ParseCtlFlags(tc.f)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if tc.e != nil {
+				tc.e.Finalize()
+			}
+
+			if !reflect.DeepEqual(tc.e, c) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, c)
+			}
+			if command != tc.command {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.command, command)
+			}
+			if arg != tc.arg {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.arg, arg)
+			}
+		})
+	}
+}
+
+func TestCLI_Run_ctl(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ct.sock")
+	c := config.TestConfig(&config.Config{
+		Control: &config.ControlConfig{
+			Path: config.String(path),
+		},
+	})
+
+	runner, err := manager.NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go runner.Start()
+	defer runner.Stop()
+
+	// Wait for the control socket to come up.
+	for i := 0; ; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("control socket was not created")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	var out bytes.Buffer
+	cli := NewCLI(&out, &out)
+
+	if exit := cli.Run([]string{"consul-template", "ctl", "-path", path, "pause"}); exit != 0 {
+		t.Fatalf("expected 0 exit, got %d: %s", exit, out.String())
+	}
+	if !runner.Paused() {
+		t.Fatal("expected runner to be paused")
+	}
+
+	out.Reset()
+	if exit := cli.Run([]string{"consul-template", "ctl", "-path", path, "state"}); exit != 0 {
+		t.Fatalf("expected 0 exit, got %d: %s", exit, out.String())
+	}
+	if act := out.String(); !strings.Contains(act, `"paused":true`) {
+		t.Errorf("\nexp: %q\nact: %q", `"paused":true`, act)
+	}
+}
@@ -0,0 +1,494 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/pkg/errors"
+)
+
+const (
+	// aclGlobalManagementPolicyID is the ID of the builtin policy which grants
+	// all privileges.
+	aclGlobalManagementPolicyID = "00000000-0000-0000-0000-000000000001"
+
+	// ACLAccessDeny, ACLAccessRead, ACLAccessList and ACLAccessWrite are the
+	// levels of access of an ACL rule.
+	ACLAccessDeny  = "deny"
+	ACLAccessRead  = "read"
+	ACLAccessList  = "list"
+	ACLAccessWrite = "write"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*ACLTokenSelfQuery)(nil)
+
+	// ACLTokenSelfQueryRe is the regular expression to use.
+	ACLTokenSelfQueryRe = regexp.MustCompile(`\A` + dcRe + `\z`)
+
+	// ACLTokenSelfQuerySleepTime is the amount of time to sleep between
+	// queries, since the endpoint does not support blocking queries.
+	ACLTokenSelfQuerySleepTime = 1 * time.Minute
+
+	// aclSegmentedResources are the resources whose rules apply to a segment,
+	// such as the name of a service or a key, and which also have prefix rules.
+	// The other resources, such as "operator", have a single rule.
+	aclSegmentedResources = []string{
+		"agent", "event", "identity", "key", "node", "query", "service", "session",
+	}
+
+	// aclAccessLevels are the privileges of the levels of access, where a
+	// higher level includes the lower ones.
+	aclAccessLevels = map[string]int{
+		ACLAccessDeny:  0,
+		ACLAccessRead:  1,
+		ACLAccessList:  2,
+		ACLAccessWrite: 3,
+	}
+)
+
+func init() {
+	gob.Register(&ACLToken{})
+}
+
+// ACLToken is the ACL token of Consul Template, along with the rules it grants.
+type ACLToken struct {
+	// Disabled is true if ACLs are disabled in the datacenter, so everything is
+	// allowed.
+	Disabled bool
+
+	AccessorID  string
+	Description string
+	Local       bool
+
+	// Policies are the policies of the token and of its roles.
+	Policies []*ACLPolicy
+
+	// Roles are the names of the roles of the token.
+	Roles []string
+
+	// ServiceIdentities and NodeIdentities are the names of the service and
+	// node identities of the token and of its roles.
+	ServiceIdentities []string
+	NodeIdentities    []string
+
+	// Management is true if the token has the global management policy, which
+	// grants all privileges.
+	Management bool
+
+	// Rules are the effective rules of the token, merged from its policies and
+	// identities. The rules of a policy the token cannot read are missing.
+	Rules []*ACLRule
+}
+
+// ACLPolicy is an ACL policy of a token. Rules is empty and Readable is false
+// if the token is not allowed to read the policy.
+type ACLPolicy struct {
+	ID       string
+	Name     string
+	Rules    string
+	Readable bool
+}
+
+// ACLRule is a rule of an ACL policy. Resource is the kind of resource, such as
+// "key" or "operator". Segment is the name of the resource, or its prefix if
+// Prefix is true; it is empty for resources without segments. Access is
+// "deny", "read", "list" or "write".
+type ACLRule struct {
+	Resource string
+	Segment  string
+	Prefix   bool
+	Access   string
+}
+
+// Allows returns true if the effective rules of the token grant the given
+// access, "read", "list" or "write", to the given resource and segment, such
+// as ("key", "app/config", "read") or ("operator", "", "read"). An exact rule
+// takes precedence over prefix rules, and the longest prefix takes precedence
+// over shorter ones. Access which is not granted by a rule is denied, since
+// the default policy of the datacenter is not known.
+func (t *ACLToken) Allows(resource, segment, access string) bool {
+	if t == nil {
+		return false
+	}
+	if t.Disabled || t.Management {
+		return true
+	}
+
+	want, ok := aclAccessLevels[access]
+	if !ok || want == 0 {
+		return false
+	}
+
+	var match *ACLRule
+	for _, r := range t.Rules {
+		if r.Resource != resource {
+			continue
+		}
+		switch {
+		case !r.Prefix && r.Segment == segment:
+			match = r
+		case r.Prefix && strings.HasPrefix(segment, r.Segment):
+			if match == nil || (match.Prefix && len(r.Segment) > len(match.Segment)) {
+				match = r
+			}
+		}
+		if match != nil && !match.Prefix {
+			break
+		}
+	}
+	if match == nil {
+		return false
+	}
+
+	// List access is only meaningful for keys; write access includes it.
+	if access == ACLAccessList && match.Access == ACLAccessRead {
+		return false
+	}
+	return aclAccessLevels[match.Access] >= want
+}
+
+// aclLink is a reference to a policy or role in a response.
+type aclLink struct {
+	ID   string
+	Name string
+}
+
+// aclIdentities are the identities of a token or role in a response.
+type aclIdentities struct {
+	ServiceIdentities []struct {
+		ServiceName string
+	}
+	NodeIdentities []struct {
+		NodeName string
+	}
+}
+
+// aclTokenResponse is the response of the token self endpoint.
+type aclTokenResponse struct {
+	aclIdentities
+
+	AccessorID  string
+	Description string
+	Local       bool
+	Policies    []aclLink
+	Roles       []aclLink
+
+	// Rules are the rules of a legacy token.
+	Rules string
+}
+
+// aclRoleResponse is the response of the role endpoint.
+type aclRoleResponse struct {
+	aclIdentities
+
+	Policies []aclLink
+}
+
+// aclPolicyResponse is the response of the policy endpoint.
+type aclPolicyResponse struct {
+	Rules string
+}
+
+// ACLTokenSelfQuery is the dependency to query the ACL token of Consul Template
+// and the rules it grants.
+type ACLTokenSelfQuery struct {
+	stopCh chan struct{}
+
+	dc string
+}
+
+// NewACLTokenSelfQuery parses the given string into a dependency. If no
+// datacenter is given, the datacenter of the local agent is used.
+func NewACLTokenSelfQuery(s string) (*ACLTokenSelfQuery, error) {
+	if !ACLTokenSelfQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("acl.self: invalid format: %q", s)
+	}
+
+	m := regexpMatch(ACLTokenSelfQueryRe, s)
+	return &ACLTokenSelfQuery{
+		dc:     m["dc"],
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// ACLToken of the client. The policies and roles of the token are read too,
+// which only succeeds if the token is allowed to read them.
+func (d *ACLTokenSelfQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	params := url.Values{}
+	if opts.Datacenter != "" {
+		params.Set("dc", opts.Datacenter)
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/acl/token/self",
+		RawQuery: params.Encode(),
+	})
+
+	// The token endpoints do not support blocking queries, so sleep between
+	// queries once we have returned data.
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, ACLTokenSelfQuerySleepTime)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(ACLTokenSelfQuerySleepTime):
+		}
+	}
+
+	// The path is escaped as the URL path of the request, so the IDs of roles
+	// and policies are joined to it as they are.
+	query := func(path string, out interface{}) error {
+		_, err := clients.consulQuery(path, &QueryOptions{
+			Datacenter: opts.Datacenter,
+		}, nil, out)
+		return err
+	}
+
+	var r aclTokenResponse
+	if err := query("/v1/acl/token/self", &r); err != nil {
+		if strings.Contains(err.Error(), "ACL support disabled") {
+			log.Printf("[TRACE] %s: ACLs are disabled", d)
+			return respWithMetadata(&ACLToken{Disabled: true})
+		}
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	token := &ACLToken{
+		AccessorID:  r.AccessorID,
+		Description: r.Description,
+		Local:       r.Local,
+	}
+	if r.Rules != "" {
+		rules, err := parseACLRules(r.Rules)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+		token.Rules = append(token.Rules, rules...)
+	}
+	addIdentities := func(ids aclIdentities) {
+		for _, s := range ids.ServiceIdentities {
+			token.ServiceIdentities = append(token.ServiceIdentities, s.ServiceName)
+		}
+		for _, n := range ids.NodeIdentities {
+			token.NodeIdentities = append(token.NodeIdentities, n.NodeName)
+		}
+	}
+	addIdentities(r.aclIdentities)
+
+	// The policies of the roles apply to the token too. A role the token cannot
+	// read only contributes its name.
+	links := append([]aclLink{}, r.Policies...)
+	for _, role := range r.Roles {
+		token.Roles = append(token.Roles, role.Name)
+
+		var rr aclRoleResponse
+		if err := query("/v1/acl/role/"+role.ID, &rr); err != nil {
+			log.Printf("[TRACE] %s: cannot read role %s: %s", d, role.Name, err)
+			continue
+		}
+		links = append(links, rr.Policies...)
+		addIdentities(rr.aclIdentities)
+	}
+
+	seen := make(map[string]bool, len(links))
+	for _, link := range links {
+		if seen[link.ID] {
+			continue
+		}
+		seen[link.ID] = true
+
+		policy := &ACLPolicy{ID: link.ID, Name: link.Name}
+		token.Policies = append(token.Policies, policy)
+		if link.ID == aclGlobalManagementPolicyID {
+			token.Management = true
+			continue
+		}
+
+		var pr aclPolicyResponse
+		if err := query("/v1/acl/policy/"+link.ID, &pr); err != nil {
+			log.Printf("[TRACE] %s: cannot read policy %s: %s", d, link.Name, err)
+			continue
+		}
+		rules, err := parseACLRules(pr.Rules)
+		if err != nil {
+			log.Printf("[WARN] %s: cannot parse the rules of policy %s: %s", d, link.Name, err)
+			continue
+		}
+		policy.Rules, policy.Readable = pr.Rules, true
+		token.Rules = append(token.Rules, rules...)
+	}
+
+	for _, name := range token.ServiceIdentities {
+		token.Rules = append(token.Rules,
+			&ACLRule{Resource: "service", Segment: name, Access: ACLAccessWrite},
+			&ACLRule{Resource: "service", Segment: name + "-sidecar-proxy", Access: ACLAccessWrite},
+			&ACLRule{Resource: "service", Prefix: true, Access: ACLAccessRead},
+			&ACLRule{Resource: "node", Prefix: true, Access: ACLAccessRead},
+		)
+	}
+	for _, name := range token.NodeIdentities {
+		token.Rules = append(token.Rules,
+			&ACLRule{Resource: "node", Segment: name, Access: ACLAccessWrite},
+			&ACLRule{Resource: "service", Prefix: true, Access: ACLAccessRead},
+		)
+	}
+	token.Rules = mergeACLRules(token.Rules)
+
+	log.Printf("[TRACE] %s: returned %d policies and %d rules", d,
+		len(token.Policies), len(token.Rules))
+
+	return respWithMetadata(token)
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *ACLTokenSelfQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *ACLTokenSelfQuery) String() string {
+	if d.dc != "" {
+		return fmt.Sprintf("acl.self(@%s)", d.dc)
+	}
+	return "acl.self"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *ACLTokenSelfQuery) Stop() {
+	close(d.stopCh)
+}
+
+// parseACLRules parses the rules of an ACL policy, given in HCL or JSON.
+func parseACLRules(s string) ([]*ACLRule, error) {
+	var parsed map[string]interface{}
+	if err := hcl.Decode(&parsed, s); err != nil {
+		return nil, err
+	}
+
+	var rules []*ACLRule
+	for key, value := range parsed {
+		resource, prefix := strings.TrimSuffix(key, "_prefix"), strings.HasSuffix(key, "_prefix")
+		if !aclSegmented(resource) {
+			// A resource without segments, such as operator = "read"
+			if access, ok := value.(string); ok {
+				rules = append(rules, &ACLRule{Resource: key, Access: access})
+			}
+			continue
+		}
+
+		for _, block := range aclBlocks(value) {
+			for segment, body := range block {
+				for _, b := range aclBlocks(body) {
+					access, ok := b["policy"].(string)
+					if !ok {
+						continue
+					}
+					rules = append(rules, &ACLRule{
+						Resource: resource,
+						Segment:  segment,
+						Prefix:   prefix,
+						Access:   access,
+					})
+				}
+			}
+		}
+	}
+	return rules, nil
+}
+
+// aclSegmented returns true if the given resource has segments.
+func aclSegmented(resource string) bool {
+	for _, r := range aclSegmentedResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// aclBlocks returns the blocks of a decoded value, which is a list of blocks
+// when decoded from HCL and a single block when decoded from JSON.
+func aclBlocks(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		return v
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var blocks []map[string]interface{}
+		for _, elem := range v {
+			blocks = append(blocks, aclBlocks(elem)...)
+		}
+		return blocks
+	}
+	return nil
+}
+
+// mergeACLRules merges the rules for the same resource and segment the way
+// Consul merges policies: deny takes precedence, then write, list and read.
+// The rules are sorted.
+func mergeACLRules(rules []*ACLRule) []*ACLRule {
+	type ruleKey struct {
+		resource, segment string
+		prefix            bool
+	}
+
+	merged := make(map[ruleKey]*ACLRule, len(rules))
+	for _, r := range rules {
+		k := ruleKey{r.Resource, r.Segment, r.Prefix}
+		current, ok := merged[k]
+		if !ok || aclAccessPrecedes(r.Access, current.Access) {
+			merged[k] = &ACLRule{Resource: r.Resource, Segment: r.Segment, Prefix: r.Prefix, Access: r.Access}
+		}
+	}
+
+	result := make([]*ACLRule, 0, len(merged))
+	for _, r := range merged {
+		result = append(result, r)
+	}
+	sort.Sort(ByACLRuleResource(result))
+	return result
+}
+
+// ByACLRuleResource is a sortable slice of ACLRule structs. Exact rules sort
+// before the prefix rules of the same resource.
+type ByACLRuleResource []*ACLRule
+
+func (s ByACLRuleResource) Len() int      { return len(s) }
+func (s ByACLRuleResource) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByACLRuleResource) Less(i, j int) bool {
+	if s[i].Resource != s[j].Resource {
+		return s[i].Resource < s[j].Resource
+	}
+	if s[i].Prefix != s[j].Prefix {
+		return !s[i].Prefix
+	}
+	return s[i].Segment < s[j].Segment
+}
+
+// aclAccessPrecedes returns true if access a takes precedence over access b
+// when merging policies.
+func aclAccessPrecedes(a, b string) bool {
+	if b == ACLAccessDeny {
+		return false
+	}
+	if a == ACLAccessDeny {
+		return true
+	}
+	return aclAccessLevels[a] > aclAccessLevels[b]
+}
@@ -0,0 +1,267 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	ACLTokenSelfQuerySleepTime = 50 * time.Millisecond
+}
+
+func TestNewACLTokenSelfQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *ACLTokenSelfQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&ACLTokenSelfQuery{},
+			false,
+		},
+		{
+			"dc",
+			"@dc1",
+			&ACLTokenSelfQuery{
+				dc: "dc1",
+			},
+			false,
+		},
+		{
+			"name",
+			"token",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewACLTokenSelfQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestACLTokenSelfQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		responses map[string]string
+		exp       *ACLToken
+		err       bool
+	}{
+		{
+			"token",
+			map[string]string{
+				"/v1/acl/token/self": `{
+					"AccessorID": "accessor",
+					"Description": "app",
+					"Policies": [{"ID": "p1", "Name": "app"}, {"ID": "p2", "Name": "secret"}],
+					"Roles": [{"ID": "r1", "Name": "web"}],
+					"ServiceIdentities": [{"ServiceName": "api"}]
+				}`,
+				"/v1/acl/role/r1": `{
+					"Policies": [{"ID": "p3", "Name": "web"}]
+				}`,
+				"/v1/acl/policy/p1": `{
+					"Rules": "key_prefix \"app/\" { policy = \"read\" }\noperator = \"read\""
+				}`,
+				"/v1/acl/policy/p3": `{
+					"Rules": "{\"key\": {\"app/secret\": {\"policy\": \"deny\"}}}"
+				}`,
+			},
+			&ACLToken{
+				AccessorID:  "accessor",
+				Description: "app",
+				Policies: []*ACLPolicy{
+					&ACLPolicy{
+						ID:       "p1",
+						Name:     "app",
+						Rules:    "key_prefix \"app/\" { policy = \"read\" }\noperator = \"read\"",
+						Readable: true,
+					},
+					&ACLPolicy{ID: "p2", Name: "secret"},
+					&ACLPolicy{
+						ID:       "p3",
+						Name:     "web",
+						Rules:    "{\"key\": {\"app/secret\": {\"policy\": \"deny\"}}}",
+						Readable: true,
+					},
+				},
+				Roles:             []string{"web"},
+				ServiceIdentities: []string{"api"},
+				Rules: []*ACLRule{
+					&ACLRule{Resource: "key", Segment: "app/secret", Access: "deny"},
+					&ACLRule{Resource: "key", Segment: "app/", Prefix: true, Access: "read"},
+					&ACLRule{Resource: "node", Prefix: true, Access: "read"},
+					&ACLRule{Resource: "operator", Access: "read"},
+					&ACLRule{Resource: "service", Segment: "api", Access: "write"},
+					&ACLRule{Resource: "service", Segment: "api-sidecar-proxy", Access: "write"},
+					&ACLRule{Resource: "service", Prefix: true, Access: "read"},
+				},
+			},
+			false,
+		},
+		{
+			"management",
+			map[string]string{
+				"/v1/acl/token/self": `{
+					"AccessorID": "root",
+					"Policies": [{"ID": "00000000-0000-0000-0000-000000000001", "Name": "global-management"}]
+				}`,
+			},
+			&ACLToken{
+				AccessorID: "root",
+				Policies: []*ACLPolicy{
+					&ACLPolicy{ID: "00000000-0000-0000-0000-000000000001", Name: "global-management"},
+				},
+				Management: true,
+				Rules:      []*ACLRule{},
+			},
+			false,
+		},
+		{
+			"disabled",
+			map[string]string{},
+			&ACLToken{Disabled: true},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(tc.responses) == 0 {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte("ACL support disabled"))
+					return
+				}
+				body, ok := tc.responses[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte("Permission denied"))
+					return
+				}
+				w.Write([]byte(body))
+			}))
+			defer s.Close()
+
+			clients := NewClientSet()
+			if err := clients.CreateConsulClient(&CreateConsulClientInput{
+				Address: strings.TrimPrefix(s.URL, "http://"),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewACLTokenSelfQuery("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if tc.exp != nil {
+				assert.Equal(t, tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestACLToken_Allows(t *testing.T) {
+	t.Parallel()
+
+	token := &ACLToken{
+		Rules: []*ACLRule{
+			&ACLRule{Resource: "key", Segment: "app/secret", Access: "deny"},
+			&ACLRule{Resource: "key", Segment: "app/", Prefix: true, Access: "read"},
+			&ACLRule{Resource: "key", Segment: "app/db/", Prefix: true, Access: "write"},
+			&ACLRule{Resource: "operator", Access: "read"},
+			&ACLRule{Resource: "service", Segment: "", Prefix: true, Access: "read"},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		token    *ACLToken
+		resource string
+		segment  string
+		access   string
+		exp      bool
+	}{
+		{"prefix", token, "key", "app/config", "read", true},
+		{"prefix_write", token, "key", "app/config", "write", false},
+		{"prefix_list", token, "key", "app/config", "list", false},
+		{"longest_prefix", token, "key", "app/db/password", "write", true},
+		{"exact_deny", token, "key", "app/secret", "read", false},
+		{"no_rule", token, "key", "other/config", "read", false},
+		{"global", token, "operator", "", "read", true},
+		{"global_write", token, "operator", "", "write", false},
+		{"empty_prefix", token, "service", "web", "read", true},
+		{"unknown_access", token, "service", "web", "admin", false},
+		{"disabled", &ACLToken{Disabled: true}, "key", "app/secret", "write", true},
+		{"management", &ACLToken{Management: true}, "acl", "", "write", true},
+		{"nil", nil, "key", "app/config", "read", false},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := tc.token.Allows(tc.resource, tc.segment, tc.access)
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestACLTokenSelfQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"empty",
+			"",
+			"acl.self",
+		},
+		{
+			"datacenter",
+			"@dc1",
+			"acl.self(@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewACLTokenSelfQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
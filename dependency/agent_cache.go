@@ -0,0 +1,87 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// agentCacheEndpoints are the prefixes of the Consul HTTP API endpoints which
+// can be served from the agent cache.
+var agentCacheEndpoints = []string{
+	"/v1/catalog/services",
+	"/v1/catalog/service/",
+	"/v1/health/service/",
+}
+
+// agentCacheTransport is an http.RoundTripper which asks the local Consul
+// agent to serve supported queries from its cache.
+type agentCacheTransport struct {
+	*http.Transport
+
+	// backgroundRefresh controls if blocking queries are served from the cache.
+	// If false, only queries without an index are.
+	backgroundRefresh bool
+
+	// maxAge is the maximum age of a cached result, or 0 for any age.
+	maxAge time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *agentCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cacheable(req) {
+		return t.Transport.RoundTrip(req)
+	}
+
+	// RoundTrip must not modify the given request, so change a copy of it.
+	req = cloneRequest(req)
+
+	q := req.URL.Query()
+	q.Set("cached", "")
+	req.URL.RawQuery = q.Encode()
+
+	if t.maxAge > 0 {
+		req.Header.Set("Cache-Control",
+			fmt.Sprintf("max-age=%d", int64(t.maxAge/time.Second)))
+	}
+
+	return t.Transport.RoundTrip(req)
+}
+
+// cloneRequest returns a copy of the given request with its own URL and
+// header, which can be changed without affecting the original.
+func cloneRequest(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+
+	u := *req.URL
+	r.URL = &u
+
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	return r
+}
+
+// cacheable returns true if the given request may be served from the cache.
+func (t *agentCacheTransport) cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+
+	if !t.backgroundRefresh {
+		if index := req.URL.Query().Get("index"); index != "" && index != "0" {
+			return false
+		}
+	}
+
+	for _, prefix := range agentCacheEndpoints {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
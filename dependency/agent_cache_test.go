@@ -0,0 +1,103 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+func TestAgentCacheTransport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		cached       bool
+		cacheControl string
+	}
+
+	var last request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, cached := r.URL.Query()["cached"]
+		last = request{
+			cached:       cached,
+			cacheControl: r.Header.Get("Cache-Control"),
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name              string
+		path              string
+		backgroundRefresh bool
+		maxAge            time.Duration
+		exp               request
+	}{
+		{
+			"health_service",
+			"/v1/health/service/web",
+			true,
+			0,
+			request{cached: true},
+		},
+		{
+			"health_service_blocking",
+			"/v1/health/service/web?index=10",
+			true,
+			0,
+			request{cached: true},
+		},
+		{
+			"health_service_blocking_no_background_refresh",
+			"/v1/health/service/web?index=10",
+			false,
+			0,
+			request{},
+		},
+		{
+			"health_service_initial_no_background_refresh",
+			"/v1/health/service/web",
+			false,
+			0,
+			request{cached: true},
+		},
+		{
+			"max_age",
+			"/v1/catalog/services",
+			true,
+			30 * time.Second,
+			request{cached: true, cacheControl: "max-age=30"},
+		},
+		{
+			"unsupported",
+			"/v1/kv/foo",
+			true,
+			30 * time.Second,
+			request{},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			client := &http.Client{
+				Transport: &agentCacheTransport{
+					Transport:         cleanhttp.DefaultTransport(),
+					backgroundRefresh: tc.backgroundRefresh,
+					maxAge:            tc.maxAge,
+				},
+			}
+
+			resp, err := client.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if last != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, last)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"sort"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/pkg/errors"
 )
 
@@ -16,7 +17,7 @@ var (
 	_ Dependency = (*CatalogNodesQuery)(nil)
 
 	// CatalogNodesQueryRe is the regular expression to use.
-	CatalogNodesQueryRe = regexp.MustCompile(`\A` + dcRe + nearRe + `\z`)
+	CatalogNodesQueryRe = regexp.MustCompile(`\A` + segmentRe + resolveRe + dcRe + nearRe + `\z`)
 )
 
 func init() {
@@ -28,18 +29,28 @@ type Node struct {
 	Node            string
 	Address         string
 	TaggedAddresses map[string]string
+
+	// AddressIPs are the IP addresses Address resolves to. They are only set
+	// if the query asked for the addresses to be resolved, and are empty if
+	// the lookup failed.
+	AddressIPs []string
 }
 
 // CatalogNodesQuery is the representation of all registered nodes in Consul.
 type CatalogNodesQuery struct {
 	stopCh chan struct{}
 
-	dc   string
-	near string
+	dc      string
+	near    string
+	resolve bool
+	segment string
 }
 
 // NewCatalogNodesQuery parses the given string into a dependency. If the name is
-// empty then the name of the local agent is used.
+// empty then the name of the local agent is used. If a network segment is
+// given, such as "?segment=alpha", only the nodes of that segment are returned.
+// If "?resolve" is given, the addresses of the nodes are resolved to IP
+// addresses.
 func NewCatalogNodesQuery(s string) (*CatalogNodesQuery, error) {
 	if !CatalogNodesQueryRe.MatchString(s) {
 		return nil, fmt.Errorf("catalog.nodes: invalid format: %q", s)
@@ -47,9 +58,11 @@ func NewCatalogNodesQuery(s string) (*CatalogNodesQuery, error) {
 
 	m := regexpMatch(CatalogNodesQueryRe, s)
 	return &CatalogNodesQuery{
-		dc:     m["dc"],
-		near:   m["near"],
-		stopCh: make(chan struct{}, 1),
+		dc:      m["dc"],
+		near:    m["near"],
+		resolve: m["resolve"] != "",
+		segment: m["segment"],
+		stopCh:  make(chan struct{}, 1),
 	}, nil
 }
 
@@ -66,14 +79,41 @@ func (d *CatalogNodesQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inter
 		Datacenter: d.dc,
 		Near:       d.near,
 	})
+	if d.resolve {
+		opts.WaitTime = resolveWaitTime(clients.Resolver(), opts.WaitTime)
+	}
 
-	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+	u := &url.URL{
 		Path:     "/v1/catalog/nodes",
 		RawQuery: opts.String(),
-	})
-	n, qm, err := clients.Consul().Catalog().Nodes(opts.ToConsulOpts())
-	if err != nil {
-		return nil, nil, errors.Wrap(err, d.String())
+	}
+	if d.segment != "" {
+		q := u.Query()
+		q.Set("segment", d.segment)
+		u.RawQuery = q.Encode()
+	}
+	log.Printf("[TRACE] %s: GET %s", d, u)
+
+	// The API client does not support network segments, so query them directly.
+	var n []*consulapi.Node
+	var rm *ResponseMetadata
+	if d.segment != "" {
+		var err error
+		rm, err = clients.consulQuery(u.Path, opts, url.Values{"segment": {d.segment}}, &n)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+	} else {
+		var qm *consulapi.QueryMeta
+		var err error
+		n, qm, err = clients.Consul().Catalog().Nodes(opts.ToConsulOpts())
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+		rm = &ResponseMetadata{
+			LastIndex:   qm.LastIndex,
+			LastContact: qm.LastContact,
+		}
 	}
 
 	log.Printf("[TRACE] %s: returned %d results", d, len(n))
@@ -88,9 +128,12 @@ func (d *CatalogNodesQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inter
 	}
 	sort.Stable(ByNode(nodes))
 
-	rm := &ResponseMetadata{
-		LastIndex:   qm.LastIndex,
-		LastContact: qm.LastContact,
+	if d.resolve {
+		resolver := clients.Resolver()
+		for _, node := range nodes {
+			node.AddressIPs = resolveAddresses(resolver, node.Address)[0]
+		}
+		rm.Derived = true
 	}
 
 	return nodes, rm, nil
@@ -104,6 +147,12 @@ func (d *CatalogNodesQuery) CanShare() bool {
 // String returns the human-friendly version of this dependency.
 func (d *CatalogNodesQuery) String() string {
 	name := ""
+	if d.segment != "" {
+		name = name + "?segment=" + d.segment
+	}
+	if d.resolve {
+		name = name + "?resolve"
+	}
 	if d.dc != "" {
 		name = name + "@" + d.dc
 	}
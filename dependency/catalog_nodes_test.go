@@ -2,7 +2,11 @@ package dependency
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -53,6 +57,32 @@ func TestNewCatalogNodesQuery(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"segment",
+			"?segment=alpha",
+			&CatalogNodesQuery{
+				segment: "alpha",
+			},
+			false,
+		},
+		{
+			"segment_dc_near",
+			"?segment=alpha@dc1~node1",
+			&CatalogNodesQuery{
+				dc:      "dc1",
+				near:    "node1",
+				segment: "alpha",
+			},
+			false,
+		},
+		{
+			"resolve",
+			"?resolve",
+			&CatalogNodesQuery{
+				resolve: true,
+			},
+			false,
+		},
 	}
 
 	for i, tc := range cases {
@@ -145,6 +175,16 @@ func TestCatalogNodesQuery_String(t *testing.T) {
 			"@dc1~node1",
 			"catalog.nodes(@dc1~node1)",
 		},
+		{
+			"segment_datacenter",
+			"?segment=alpha@dc1",
+			"catalog.nodes(?segment=alpha@dc1)",
+		},
+		{
+			"resolve_datacenter",
+			"?resolve@dc1",
+			"catalog.nodes(?resolve@dc1)",
+		},
 	}
 
 	for i, tc := range cases {
@@ -157,3 +197,51 @@ func TestCatalogNodesQuery_String(t *testing.T) {
 		})
 	}
 }
+
+func TestCatalogNodesQuery_FetchSegment(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/catalog/nodes", r.URL.Path)
+		assert.Equal(t, "alpha", r.URL.Query().Get("segment"))
+		assert.Equal(t, "dc1", r.URL.Query().Get("dc"))
+		w.Header().Set("X-Consul-Index", "12")
+		w.Header().Set("X-Consul-LastContact", "5")
+		w.Write([]byte(`[
+			{"Node": "node2", "Address": "127.0.0.2"},
+			{"Node": "node1", "Address": "127.0.0.1", "TaggedAddresses": {"lan": "127.0.0.1"}}
+		]`))
+	}))
+	defer s.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(s.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewCatalogNodesQuery("?segment=alpha@dc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, rm, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []*Node{
+		&Node{
+			Node:            "node1",
+			Address:         "127.0.0.1",
+			TaggedAddresses: map[string]string{"lan": "127.0.0.1"},
+		},
+		&Node{
+			Node:    "node2",
+			Address: "127.0.0.2",
+		},
+	}, act)
+	assert.Equal(t, uint64(12), rm.LastIndex)
+	assert.Equal(t, 5*time.Millisecond, rm.LastContact)
+}
@@ -2,10 +2,16 @@ package dependency
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/go-cleanhttp"
@@ -13,6 +19,10 @@ import (
 	vaultapi "github.com/hashicorp/vault/api"
 )
 
+// DefaultNomadAddress is the address of the Nomad agent which is used when no
+// address is configured.
+const DefaultNomadAddress = "http://127.0.0.1:4646"
+
 // ClientSet is a collection of clients that dependencies use to communicate
 // with remote services like Consul or Vault.
 type ClientSet struct {
@@ -20,18 +30,54 @@ type ClientSet struct {
 
 	vault  *vaultClient
 	consul *consulClient
+	nomad  *nomadClient
+
+	// resolver resolves the addresses returned by the dependencies which are
+	// asked to resolve them.
+	resolver *Resolver
 }
 
 // consulClient is a wrapper around a real Consul API client.
 type consulClient struct {
 	client     *consulapi.Client
 	httpClient *http.Client
+
+	// config is the configuration the client was created with. It is used for
+	// requests to endpoints the API client does not support.
+	config *consulapi.Config
+
+	// stream is the stream of service health from Consul's event stream, or
+	// nil if streaming is disabled.
+	stream *healthStream
 }
 
 // vaultClient is a wrapper around a real Vault API client.
 type vaultClient struct {
 	client     *vaultapi.Client
 	httpClient *http.Client
+	transport  *http.Transport
+
+	// failover sends the requests of the client to one of several addresses,
+	// or is nil if only one address is configured.
+	failover *vaultFailover
+
+	// loginLeaseDuration is the lease duration of the token the client logged
+	// in with, if it logged in with an auth method.
+	loginLeaseDuration time.Duration
+}
+
+// nomadClient sends requests to the HTTP API of Nomad. There is no Nomad API
+// client, so requests are built directly.
+type nomadClient struct {
+	httpClient *http.Client
+
+	// address is the scheme and host of the Nomad agent.
+	address *url.URL
+
+	// token, namespace and region are sent with each request, unless empty.
+	token     string
+	namespace string
+	region    string
 }
 
 // CreateConsulClientInput is used as input to the CreateConsulClient function.
@@ -48,6 +94,18 @@ type CreateConsulClientInput struct {
 	SSLCACert    string
 	SSLCAPath    string
 	ServerName   string
+
+	// AgentCacheEnabled controls if supported queries are served from the local
+	// agent's cache. See agentCacheTransport.
+	AgentCacheEnabled           bool
+	AgentCacheBackgroundRefresh bool
+	AgentCacheMaxAge            time.Duration
+
+	// StreamingEnabled controls if service health is streamed from the Consul
+	// server at StreamingAddress, falling back to blocking queries. See
+	// healthStream.
+	StreamingEnabled bool
+	StreamingAddress string
 }
 
 // CreateVaultClientInput is used as input to the CreateVaultClient function.
@@ -55,6 +113,9 @@ type CreateVaultClientInput struct {
 	Address     string
 	Token       string
 	UnwrapToken bool
+	AuthMethod  string
+	AuthMount   string
+	AuthRole    string
 	SSLEnabled  bool
 	SSLVerify   bool
 	SSLCert     string
@@ -62,6 +123,27 @@ type CreateVaultClientInput struct {
 	SSLCACert   string
 	SSLCAPath   string
 	ServerName  string
+
+	// Addresses are more addresses to fail over to, after Address. See
+	// vaultFailover.
+	Addresses            []string
+	HealthCheckInterval  time.Duration
+	PerformanceStandbyOK bool
+}
+
+// CreateNomadClientInput is used as input to the CreateNomadClient function.
+type CreateNomadClientInput struct {
+	Address    string
+	Token      string
+	Namespace  string
+	Region     string
+	SSLEnabled bool
+	SSLVerify  bool
+	SSLCert    string
+	SSLKey     string
+	SSLCACert  string
+	SSLCAPath  string
+	ServerName string
 }
 
 // NewClientSet creates a new client set that is ready to accept clients.
@@ -69,7 +151,8 @@ func NewClientSet() *ClientSet {
 	return &ClientSet{}
 }
 
-// CreateConsulClient creates a new Consul API client from the given input.
+// CreateConsulClient creates a new Consul API client from the given input. Any
+// existing Consul client in the set is replaced.
 func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 	consulConfig := consulapi.DefaultConfig()
 
@@ -92,6 +175,7 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 	transport := cleanhttp.DefaultPooledTransport()
 
 	// Configure SSL
+	var streamTLSConfig *tls.Config
 	if i.SSLEnabled {
 		consulConfig.Scheme = "https"
 
@@ -138,10 +222,18 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 
 		// Save the TLS config on our transport
 		transport.TLSClientConfig = &tlsConfig
+		streamTLSConfig = &tlsConfig
 	}
 
 	// Setup the new transport
 	consulConfig.HttpClient.Transport = transport
+	if i.AgentCacheEnabled {
+		consulConfig.HttpClient.Transport = &agentCacheTransport{
+			Transport:         transport,
+			backgroundRefresh: i.AgentCacheBackgroundRefresh,
+			maxAge:            i.AgentCacheMaxAge,
+		}
+	}
 
 	// Create the API client
 	client, err := consulapi.NewClient(consulConfig)
@@ -149,16 +241,39 @@ func (c *ClientSet) CreateConsulClient(i *CreateConsulClientInput) error {
 		return fmt.Errorf("client set: consul: %s", err)
 	}
 
-	// Save the data on ourselves
+	// Create the health stream
+	var stream *healthStream
+	if i.StreamingEnabled {
+		stream, err = newHealthStream(i.StreamingAddress, consulConfig.Token, streamTLSConfig)
+		if err != nil {
+			return fmt.Errorf("client set: consul streaming: %s", err)
+		}
+	}
+
+	// Save the data on ourselves, replacing any existing client
+	c.Lock()
+	defer c.Unlock()
+
+	if c.consul != nil {
+		c.consul.httpClient.CloseIdleConnections()
+		if c.consul.stream != nil {
+			c.consul.stream.close()
+		}
+	}
+
 	c.consul = &consulClient{
 		client:     client,
 		httpClient: consulConfig.HttpClient,
+		config:     consulConfig,
+		stream:     stream,
 	}
 
 	return nil
 }
 
-func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
+// CreateVaultClient creates a new Vault API client from the given input. Any
+// existing Vault client in the set is replaced.
+func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) (err error) {
 	vaultConfig := vaultapi.DefaultConfig()
 
 	if i.Address != "" {
@@ -218,6 +333,23 @@ func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
 	// Setup the new transport
 	vaultConfig.HttpClient.Transport = transport
 
+	// Fail over between the addresses, if more than one is given
+	var failover *vaultFailover
+	if addresses := vaultAddresses(vaultConfig.Address, i.Addresses); len(addresses) > 1 {
+		failover, err = newVaultFailover(addresses, transport, i.HealthCheckInterval,
+			i.PerformanceStandbyOK)
+		if err != nil {
+			return fmt.Errorf("client set: vault: %s", err)
+		}
+		defer func() {
+			if err != nil {
+				failover.Stop()
+			}
+		}()
+		vaultConfig.Address = failover.clientAddress()
+		vaultConfig.HttpClient.Transport = failover
+	}
+
 	// Create the client
 	client, err := vaultapi.NewClient(vaultConfig)
 	if err != nil {
@@ -251,10 +383,138 @@ func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
 		client.SetToken(secret.Auth.ClientToken)
 	}
 
-	// Save the data on ourselves
+	// Log in with the auth method, if one other than a token is configured
+	var loginLeaseDuration time.Duration
+	switch i.AuthMethod {
+	case "", "token":
+	case "cert":
+		mount := i.AuthMount
+		if mount == "" {
+			mount = i.AuthMethod
+		}
+
+		data := make(map[string]interface{})
+		if i.AuthRole != "" {
+			data["name"] = i.AuthRole
+		}
+
+		secret, err := client.Logical().Write(fmt.Sprintf("auth/%s/login", mount), data)
+		if err != nil {
+			return fmt.Errorf("client set: vault login: %s", err)
+		}
+
+		if secret == nil || secret.Auth == nil {
+			return fmt.Errorf("client set: vault login: no secret auth")
+		}
+
+		if secret.Auth.ClientToken == "" {
+			return fmt.Errorf("client set: vault login: no token returned")
+		}
+
+		client.SetToken(secret.Auth.ClientToken)
+		loginLeaseDuration = time.Duration(secret.Auth.LeaseDuration) * time.Second
+	default:
+		return fmt.Errorf("client set: vault: unknown auth method %q", i.AuthMethod)
+	}
+
+	// Save the data on ourselves, replacing any existing client
+	c.Lock()
+	defer c.Unlock()
+
+	if c.vault != nil {
+		c.vault.stop()
+	}
+
 	c.vault = &vaultClient{
-		client:     client,
-		httpClient: vaultConfig.HttpClient,
+		client:             client,
+		httpClient:         vaultConfig.HttpClient,
+		transport:          transport,
+		failover:           failover,
+		loginLeaseDuration: loginLeaseDuration,
+	}
+
+	return nil
+}
+
+// CreateNomadClient creates a new Nomad client from the given input. Any
+// existing Nomad client in the set is replaced.
+func (c *ClientSet) CreateNomadClient(i *CreateNomadClientInput) error {
+	address := i.Address
+	if address == "" {
+		address = DefaultNomadAddress
+	}
+	if !strings.Contains(address, "://") {
+		if i.SSLEnabled {
+			address = "https://" + address
+		} else {
+			address = "http://" + address
+		}
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("client set: nomad: %s", err)
+	}
+
+	// This transport will attempt to keep connections open to the Nomad agent.
+	transport := cleanhttp.DefaultPooledTransport()
+
+	// Configure SSL
+	if i.SSLEnabled || u.Scheme == "https" {
+		var tlsConfig tls.Config
+
+		// Custom certificate or certificate and key
+		if i.SSLCert != "" && i.SSLKey != "" {
+			cert, err := tls.LoadX509KeyPair(i.SSLCert, i.SSLKey)
+			if err != nil {
+				return fmt.Errorf("client set: nomad: %s", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		} else if i.SSLCert != "" {
+			cert, err := tls.LoadX509KeyPair(i.SSLCert, i.SSLCert)
+			if err != nil {
+				return fmt.Errorf("client set: nomad: %s", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		// Custom CA certificate
+		if i.SSLCACert != "" || i.SSLCAPath != "" {
+			rootConfig := &rootcerts.Config{
+				CAFile: i.SSLCACert,
+				CAPath: i.SSLCAPath,
+			}
+			if err := rootcerts.ConfigureTLS(&tlsConfig, rootConfig); err != nil {
+				return fmt.Errorf("client set: nomad configuring TLS failed: %s", err)
+			}
+		}
+
+		// SSL verification
+		if i.ServerName != "" {
+			tlsConfig.ServerName = i.ServerName
+			tlsConfig.InsecureSkipVerify = false
+		}
+		if !i.SSLVerify {
+			log.Printf("[WARN] (clients) disabling nomad SSL verification")
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		transport.TLSClientConfig = &tlsConfig
+	}
+
+	// Save the data on ourselves, replacing any existing client
+	c.Lock()
+	defer c.Unlock()
+
+	if c.nomad != nil {
+		c.nomad.httpClient.CloseIdleConnections()
+	}
+
+	c.nomad = &nomadClient{
+		httpClient: &http.Client{Transport: transport},
+		address:    &url.URL{Scheme: u.Scheme, Host: u.Host},
+		token:      i.Token,
+		namespace:  i.Namespace,
+		region:     i.Region,
 	}
 
 	return nil
@@ -267,6 +527,163 @@ func (c *ClientSet) Consul() *consulapi.Client {
 	return c.consul.client
 }
 
+// consulGet issues a GET request for the given path and query parameters to
+// Consul, using the address, credentials and transport of the Consul client.
+// It is used for endpoints the API client does not support. The response is
+// returned whatever its status code.
+func (c *ClientSet) consulGet(path string, params url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("GET", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Path = path
+	req.URL.RawQuery = params.Encode()
+
+	return c.ConsulDo(req)
+}
+
+// ConsulDo sends the given request to Consul, using the address, credentials
+// and transport of the Consul client. Only the path and query of the request
+// URL are used. The token of the client is added unless the request already
+// has one.
+func (c *ClientSet) ConsulDo(req *http.Request) (*http.Response, error) {
+	c.RLock()
+	conf := c.consul.config
+	c.RUnlock()
+
+	req.URL.Scheme = conf.Scheme
+	req.URL.Host = conf.Address
+	req.Host = ""
+
+	if conf.Token != "" && req.Header.Get("X-Consul-Token") == "" {
+		req.Header.Set("X-Consul-Token", conf.Token)
+	}
+
+	if conf.HttpAuth != nil {
+		req.SetBasicAuth(conf.HttpAuth.Username, conf.HttpAuth.Password)
+	}
+
+	return conf.HttpClient.Do(req)
+}
+
+// consulQuery issues a (possibly blocking) GET request for the given path to
+// Consul with the given query options and extra query parameters, and decodes
+// the JSON response into out. It is used for query parameters the API client
+// does not support, such as network segments.
+func (c *ClientSet) consulQuery(path string, opts *QueryOptions, extra url.Values, out interface{}) (*ResponseMetadata, error) {
+	params, err := url.ParseQuery(opts.String())
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range extra {
+		for _, v := range vs {
+			params.Add(k, v)
+		}
+	}
+
+	resp, err := c.consulGet(path, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected response code: %d (%s)", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, err
+	}
+
+	rm := &ResponseMetadata{}
+	if index := resp.Header.Get("X-Consul-Index"); index != "" {
+		rm.LastIndex, err = strconv.ParseUint(index, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse X-Consul-Index: %s", err)
+		}
+	}
+	if last := resp.Header.Get("X-Consul-LastContact"); last != "" {
+		ms, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse X-Consul-LastContact: %s", err)
+		}
+		rm.LastContact = time.Duration(ms) * time.Millisecond
+	}
+	return rm, nil
+}
+
+// nomadQuery issues a (possibly blocking) GET request for the given path to
+// Nomad with the given query options, and decodes the JSON response into out.
+func (c *ClientSet) nomadQuery(path string, opts *QueryOptions, out interface{}) (*ResponseMetadata, error) {
+	c.RLock()
+	client := c.nomad
+	c.RUnlock()
+
+	if client == nil {
+		return nil, fmt.Errorf("nomad is not enabled")
+	}
+
+	params := url.Values{}
+	if opts.AllowStale {
+		params.Set("stale", "true")
+	}
+	if opts.WaitIndex != 0 {
+		params.Set("index", strconv.FormatUint(opts.WaitIndex, 10))
+	}
+	if opts.WaitTime != 0 {
+		params.Set("wait", opts.WaitTime.String())
+	}
+	if client.namespace != "" {
+		params.Set("namespace", client.namespace)
+	}
+	if client.region != "" {
+		params.Set("region", client.region)
+	}
+
+	u := *client.address
+	u.Path = path
+	u.RawQuery = params.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.token != "" {
+		req.Header.Set("X-Nomad-Token", client.token)
+	}
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected response code: %d (%s)", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, err
+	}
+
+	rm := &ResponseMetadata{}
+	if index := resp.Header.Get("X-Nomad-Index"); index != "" {
+		rm.LastIndex, err = strconv.ParseUint(index, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse X-Nomad-Index: %s", err)
+		}
+	}
+	if last := resp.Header.Get("X-Nomad-LastContact"); last != "" {
+		ms, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse X-Nomad-LastContact: %s", err)
+		}
+		rm.LastContact = time.Duration(ms) * time.Millisecond
+	}
+	return rm, nil
+}
+
 // Vault returns the Consul client for this set.
 func (c *ClientSet) Vault() *vaultapi.Client {
 	c.RLock()
@@ -274,16 +691,72 @@ func (c *ClientSet) Vault() *vaultapi.Client {
 	return c.vault.client
 }
 
+// VaultAddress returns the address the requests of the Vault client are sent
+// to, which changes when it fails over to another address.
+func (c *ClientSet) VaultAddress() string {
+	c.RLock()
+	defer c.RUnlock()
+	if c.vault.failover != nil {
+		return c.vault.failover.Address()
+	}
+	return c.vault.client.Address()
+}
+
+// VaultLoginLeaseDuration returns the lease duration of the token the Vault
+// client logged in with, or zero if the client did not log in with an auth
+// method or the token does not expire.
+func (c *ClientSet) VaultLoginLeaseDuration() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.vault.loginLeaseDuration
+}
+
+// SetResolver sets the resolver of this set.
+func (c *ClientSet) SetResolver(r *Resolver) {
+	c.Lock()
+	defer c.Unlock()
+	c.resolver = r
+}
+
+// healthStream returns the health stream of the Consul client, or nil if
+// streaming is disabled.
+func (c *ClientSet) healthStream() *healthStream {
+	c.RLock()
+	defer c.RUnlock()
+	if c.consul == nil {
+		return nil
+	}
+	return c.consul.stream
+}
+
+// Resolver returns the resolver of this set. If none was set, a resolver with
+// the default TTL and timeout is created.
+func (c *ClientSet) Resolver() *Resolver {
+	c.Lock()
+	defer c.Unlock()
+	if c.resolver == nil {
+		c.resolver = NewResolver(defaultResolverTTL, defaultResolverTimeout)
+	}
+	return c.resolver
+}
+
 // Stop closes all idle connections for any attached clients.
 func (c *ClientSet) Stop() {
 	c.Lock()
 	defer c.Unlock()
 
 	if c.consul != nil {
-		c.consul.httpClient.Transport.(*http.Transport).CloseIdleConnections()
+		c.consul.httpClient.CloseIdleConnections()
+		if c.consul.stream != nil {
+			c.consul.stream.close()
+		}
 	}
 
 	if c.vault != nil {
-		c.vault.httpClient.Transport.(*http.Transport).CloseIdleConnections()
+		c.vault.stop()
+	}
+
+	if c.nomad != nil {
+		c.nomad.httpClient.CloseIdleConnections()
 	}
 }
@@ -13,13 +13,16 @@ import (
 )
 
 const (
-	dcRe     = `(@(?P<dc>[[:word:]\.\-\_]+))?`
-	keyRe    = `/?(?P<key>[^@]+)`
-	filterRe = `(\|(?P<filter>[[:word:]\,]+))?`
-	nameRe   = `(?P<name>[[:word:]\-\_]+)`
-	nearRe   = `(~(?P<near>[[:word:]\.\-\_]+))?`
-	prefixRe = `/?(?P<prefix>[^@]+)`
-	tagRe    = `((?P<tag>[[:word:]\.\-\_]+)\.)?`
+	dcRe      = `(@(?P<dc>[[:word:]\.\-\_]+))?`
+	keyRe     = `/?(?P<key>[^@]+)`
+	filterRe  = `(\|(?P<filter>[[:word:]\,]+))?`
+	nameRe    = `(?P<name>[[:word:]\-\_]+)`
+	nearRe    = `(~(?P<near>[[:word:]\.\-\_]+))?`
+	peerRe    = `(\?peer=(?P<peer>[[:word:]\.\-\_]+))?`
+	prefixRe  = `/?(?P<prefix>[^@]+)`
+	resolveRe = `(?P<resolve>\?resolve)?`
+	segmentRe = `(\?segment=(?P<segment>[[:word:]\.\-\_]+))?`
+	tagRe     = `((?P<tag>[[:word:]\.\-\_]+)\.)?`
 )
 
 // Dependency is an interface for a dependency that Consul Template is capable
@@ -182,6 +185,11 @@ type ResponseMetadata struct {
 	LastIndex   uint64
 	LastContact time.Duration
 	Block       bool
+
+	// Derived is true if the data includes values which do not come from
+	// Consul, such as resolved addresses, so it may change while the index
+	// stays the same.
+	Derived bool
 }
 
 // deepCopyAndSortTags deep copies the tags in the given string slice and then
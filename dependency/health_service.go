@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/pkg/errors"
@@ -29,7 +30,7 @@ var (
 	_ Dependency = (*HealthServiceQuery)(nil)
 
 	// HealthServiceQueryRe is the regular expression to use.
-	HealthServiceQueryRe = regexp.MustCompile(`\A` + tagRe + nameRe + dcRe + nearRe + filterRe + `\z`)
+	HealthServiceQueryRe = regexp.MustCompile(`\A` + tagRe + nameRe + segmentRe + peerRe + resolveRe + dcRe + nearRe + filterRe + `\z`)
 )
 
 func init() {
@@ -47,20 +48,44 @@ type HealthService struct {
 	Checks      []*api.HealthCheck
 	Status      string
 	Port        int
+
+	// Peer is the name of the cluster peer the service was imported from, or
+	// empty for a local service.
+	Peer string
+
+	// AddressIPs and NodeAddressIPs are the IP addresses Address and
+	// NodeAddress resolve to. They are only set if the query asked for the
+	// addresses to be resolved, and are empty if the lookup failed.
+	AddressIPs     []string
+	NodeAddressIPs []string
 }
 
 // HealthServiceQuery is the representation of all a service query in Consul.
 type HealthServiceQuery struct {
 	stopCh chan struct{}
 
+	// stream and sub are the health stream and subscription the query is
+	// streamed from, if any.
+	streamLock sync.Mutex
+	stream     *healthStream
+	sub        *healthSubscription
+
 	dc      string
 	filters []string
 	name    string
 	near    string
+	peer    string
+	resolve bool
+	segment string
 	tag     string
 }
 
 // NewHealthServiceQuery processes the strings to build a service dependency.
+// If a network segment is given, such as "web?segment=alpha", only the
+// instances on nodes of that segment are returned. If a cluster peer is given,
+// such as "web?peer=partner", the instances imported from that peer are
+// returned instead of the local ones. If "?resolve" is given, such as
+// "web?resolve", the addresses of the instances are resolved to IP addresses.
 func NewHealthServiceQuery(s string) (*HealthServiceQuery, error) {
 	if !HealthServiceQueryRe.MatchString(s) {
 		return nil, fmt.Errorf("health.service: invalid format: %q", s)
@@ -96,6 +121,9 @@ func NewHealthServiceQuery(s string) (*HealthServiceQuery, error) {
 		filters: filters,
 		name:    m["name"],
 		near:    m["near"],
+		peer:    m["peer"],
+		resolve: m["resolve"] != "",
+		segment: m["segment"],
 		tag:     m["tag"],
 	}, nil
 }
@@ -113,6 +141,9 @@ func (d *HealthServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inte
 		Datacenter: d.dc,
 		Near:       d.near,
 	})
+	if d.resolve {
+		opts.WaitTime = resolveWaitTime(clients.Resolver(), opts.WaitTime)
+	}
 
 	u := &url.URL{
 		Path:     "/v1/health/service/" + d.name,
@@ -123,15 +154,73 @@ func (d *HealthServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inte
 		q.Set("tag", d.tag)
 		u.RawQuery = q.Encode()
 	}
+	if d.segment != "" {
+		q := u.Query()
+		q.Set("segment", d.segment)
+		u.RawQuery = q.Encode()
+	}
+	if d.peer != "" {
+		q := u.Query()
+		q.Set("peer", d.peer)
+		u.RawQuery = q.Encode()
+	}
 	log.Printf("[TRACE] %s: GET %s", d, u)
 
 	// Check if a user-supplied filter was given. If so, we may be querying for
 	// more than healthy services, so we need to implement client-side filtering.
 	passingOnly := len(d.filters) == 1 && d.filters[0] == HealthPassing
 
-	entries, qm, err := clients.Consul().Health().Service(d.name, d.tag, passingOnly, opts.ToConsulOpts())
-	if err != nil {
-		return nil, nil, errors.Wrap(err, d.String())
+	// Stream the service health if streaming is enabled and supports the query,
+	// falling back to a blocking query if the stream is not available.
+	var entries []*api.ServiceEntry
+	var rm *ResponseMetadata
+	if stream := clients.healthStream(); stream != nil && d.canStream() {
+		var err error
+		entries, rm, err = d.fetchStream(stream, opts)
+		if err == ErrStopped {
+			return nil, nil, err
+		}
+		if err != nil {
+			log.Printf("[DEBUG] %s: falling back to blocking query: %s", d, err)
+		}
+	}
+
+	// The API client does not support network segments or cluster peers, so
+	// query them directly.
+	switch {
+	case rm != nil:
+		// The service health was streamed.
+	case d.segment != "" || d.peer != "":
+		params := url.Values{}
+		if d.segment != "" {
+			params.Set("segment", d.segment)
+		}
+		if d.peer != "" {
+			params.Set("peer", d.peer)
+		}
+		if d.tag != "" {
+			params.Set("tag", d.tag)
+		}
+		if passingOnly {
+			params.Set("passing", "1")
+		}
+
+		var err error
+		rm, err = clients.consulQuery(u.Path, opts, params, &entries)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+	default:
+		var qm *api.QueryMeta
+		var err error
+		entries, qm, err = clients.Consul().Health().Service(d.name, d.tag, passingOnly, opts.ToConsulOpts())
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+		rm = &ResponseMetadata{
+			LastIndex:   qm.LastIndex,
+			LastContact: qm.LastContact,
+		}
 	}
 
 	log.Printf("[TRACE] %s: returned %d results", d, len(entries))
@@ -163,19 +252,58 @@ func (d *HealthServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (inte
 			Status:      status,
 			Checks:      entry.Checks,
 			Port:        entry.Service.Port,
+			Peer:        d.peer,
 		})
 	}
 
 	log.Printf("[TRACE] %s: returned %d results after filtering", d, len(list))
 
+	if d.resolve {
+		resolver := clients.Resolver()
+		for _, s := range list {
+			ips := resolveAddresses(resolver, s.Address, s.NodeAddress)
+			s.AddressIPs, s.NodeAddressIPs = ips[0], ips[1]
+		}
+		rm.Derived = true
+	}
+
 	sort.Stable(ByNodeThenID(list))
 
-	rm := &ResponseMetadata{
-		LastIndex:   qm.LastIndex,
-		LastContact: qm.LastContact,
+	return list, rm, nil
+}
+
+// canStream returns true if the query can be streamed. The event stream has no
+// equivalent of network segments, cluster peers or sorting by distance.
+func (d *HealthServiceQuery) canStream() bool {
+	return d.segment == "" && d.peer == "" && d.near == ""
+}
+
+// fetchStream returns the instances of the service from the subscription of
+// the given stream, subscribing if the query is not subscribed yet or the
+// stream was replaced.
+func (d *HealthServiceQuery) fetchStream(stream *healthStream, opts *QueryOptions) ([]*api.ServiceEntry, *ResponseMetadata, error) {
+	d.streamLock.Lock()
+	select {
+	case <-d.stopCh:
+		d.streamLock.Unlock()
+		return nil, nil, ErrStopped
+	default:
+	}
+	if d.stream != stream {
+		if d.sub != nil {
+			d.stream.release(d.sub)
+		}
+		d.stream, d.sub = stream, stream.subscribe(d.dc, d.name)
+	}
+	sub := d.sub
+	d.streamLock.Unlock()
+
+	entries, index, err := sub.fetch(opts.WaitIndex, opts.WaitTime, d.stopCh)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return list, rm, nil
+	return filterTag(entries, d.tag), &ResponseMetadata{LastIndex: index}, nil
 }
 
 // CanShare returns a boolean if this dependency is shareable.
@@ -183,9 +311,21 @@ func (d *HealthServiceQuery) CanShare() bool {
 	return true
 }
 
+// Name returns the name of the service this query is for.
+func (d *HealthServiceQuery) Name() string {
+	return d.name
+}
+
 // Stop halts the dependency's fetch function.
 func (d *HealthServiceQuery) Stop() {
 	close(d.stopCh)
+
+	d.streamLock.Lock()
+	defer d.streamLock.Unlock()
+	if d.sub != nil {
+		d.stream.release(d.sub)
+		d.stream, d.sub = nil, nil
+	}
 }
 
 // String returns the human-friendly version of this dependency.
@@ -194,6 +334,15 @@ func (d *HealthServiceQuery) String() string {
 	if d.tag != "" {
 		name = d.tag + "." + name
 	}
+	if d.segment != "" {
+		name = name + "?segment=" + d.segment
+	}
+	if d.peer != "" {
+		name = name + "?peer=" + d.peer
+	}
+	if d.resolve {
+		name = name + "?resolve"
+	}
 	if d.dc != "" {
 		name = name + "@" + d.dc
 	}
@@ -2,7 +2,11 @@ package dependency
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -124,6 +128,38 @@ func TestNewHealthServiceQuery(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"tag_name_segment_dc",
+			"tag.name?segment=alpha@dc",
+			&HealthServiceQuery{
+				dc:      "dc",
+				filters: []string{"passing"},
+				name:    "name",
+				segment: "alpha",
+				tag:     "tag",
+			},
+			false,
+		},
+		{
+			"name_peer",
+			"name?peer=partner",
+			&HealthServiceQuery{
+				filters: []string{"passing"},
+				name:    "name",
+				peer:    "partner",
+			},
+			false,
+		},
+		{
+			"name_resolve",
+			"name?resolve",
+			&HealthServiceQuery{
+				filters: []string{"passing"},
+				name:    "name",
+				resolve: true,
+			},
+			false,
+		},
 	}
 
 	for i, tc := range cases {
@@ -283,6 +319,21 @@ func TestHealthServiceQuery_String(t *testing.T) {
 			"tag.name@dc~near",
 			"health.service(tag.name@dc~near|passing)",
 		},
+		{
+			"tag_name_segment_dc",
+			"tag.name?segment=alpha@dc",
+			"health.service(tag.name?segment=alpha@dc|passing)",
+		},
+		{
+			"name_peer",
+			"name?peer=partner",
+			"health.service(name?peer=partner|passing)",
+		},
+		{
+			"name_resolve_dc",
+			"name?resolve@dc",
+			"health.service(name?resolve@dc|passing)",
+		},
 	}
 
 	for i, tc := range cases {
@@ -295,3 +346,134 @@ func TestHealthServiceQuery_String(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthServiceQuery_FetchSegment(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/web", r.URL.Path)
+		assert.Equal(t, "alpha", r.URL.Query().Get("segment"))
+		assert.Equal(t, "tag", r.URL.Query().Get("tag"))
+		assert.Equal(t, "1", r.URL.Query().Get("passing"))
+		w.Header().Set("X-Consul-Index", "7")
+		w.Write([]byte(`[{
+			"Node": {"Node": "node1", "Address": "127.0.0.1"},
+			"Service": {"ID": "web1", "Service": "web", "Tags": ["tag"], "Port": 80},
+			"Checks": [{"Status": "passing"}]
+		}]`))
+	}))
+	defer s.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(s.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewHealthServiceQuery("tag.web?segment=alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, rm, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := act.([]*HealthService)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "node1", list[0].Node)
+		assert.Equal(t, "127.0.0.1", list[0].Address)
+		assert.Equal(t, "web1", list[0].ID)
+		assert.Equal(t, HealthPassing, list[0].Status)
+		assert.Equal(t, 80, list[0].Port)
+	}
+	assert.Equal(t, uint64(7), rm.LastIndex)
+}
+
+func TestHealthServiceQuery_FetchPeer(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/web", r.URL.Path)
+		assert.Equal(t, "partner", r.URL.Query().Get("peer"))
+		assert.Equal(t, "", r.URL.Query().Get("segment"))
+		w.Header().Set("X-Consul-Index", "7")
+		w.Write([]byte(`[{
+			"Node": {"Node": "node1", "Address": "10.0.0.1"},
+			"Service": {"ID": "web1", "Service": "web", "Port": 80},
+			"Checks": [{"Status": "passing"}]
+		}]`))
+	}))
+	defer s.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(s.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewHealthServiceQuery("web?peer=partner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, rm, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := act.([]*HealthService)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "node1", list[0].Node)
+		assert.Equal(t, "10.0.0.1", list[0].Address)
+		assert.Equal(t, "partner", list[0].Peer)
+	}
+	assert.Equal(t, uint64(7), rm.LastIndex)
+}
+
+func TestHealthServiceQuery_FetchResolve(t *testing.T) {
+	t.Parallel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/web", r.URL.Path)
+		assert.Equal(t, "10000ms", r.URL.Query().Get("wait"))
+		w.Header().Set("X-Consul-Index", "7")
+		w.Write([]byte(`[{
+			"Node": {"Node": "node1", "Address": "10.0.0.1"},
+			"Service": {"ID": "web1", "Service": "web", "Address": "web.example", "Port": 80},
+			"Checks": [{"Status": "passing"}]
+		}]`))
+	}))
+	defer s.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Address: strings.TrimPrefix(s.URL, "http://"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	clients.SetResolver(testResolver(map[string][]string{
+		"web.example": []string{"10.1.0.1"},
+	}, make(map[string]int)))
+
+	d, err := NewHealthServiceQuery("web?resolve")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, rm, err := d.Fetch(clients, &QueryOptions{WaitTime: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := act.([]*HealthService)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "web.example", list[0].Address)
+		assert.Equal(t, []string{"10.1.0.1"}, list[0].AddressIPs)
+		assert.Equal(t, []string{"10.0.0.1"}, list[0].NodeAddressIPs)
+	}
+	assert.True(t, rm.Derived)
+}
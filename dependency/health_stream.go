@@ -0,0 +1,577 @@
+package dependency
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/consul/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// rpcTLS and rpcGRPC are the first bytes a client sends to the RPC port of
+	// a Consul server to select TLS and the gRPC protocol.
+	rpcTLS  byte = 3
+	rpcGRPC byte = 8
+
+	// subscribeMethod is the gRPC method of Consul's event stream.
+	subscribeMethod = "/subscribe.StateChangeSubscription/Subscribe"
+
+	// topicServiceHealth is the topic of the service health events.
+	topicServiceHealth int32 = 1
+
+	// catalogOpDeregister is the operation of an event which removes an
+	// instance of the service.
+	catalogOpDeregister int32 = 1
+
+	// defaultStreamWaitTime is how long a fetch without a wait time waits for
+	// the service health to change, like the default wait time of a blocking
+	// query.
+	defaultStreamWaitTime = 5 * time.Minute
+
+	// streamReadyTimeout is how long a fetch waits for the snapshot of a
+	// subscription before it falls back to a blocking query.
+	streamReadyTimeout = 10 * time.Second
+
+	// streamRetryMin and streamRetryMax bound the back-off between attempts to
+	// subscribe after a stream failed.
+	streamRetryMin = 1 * time.Second
+	streamRetryMax = 1 * time.Minute
+)
+
+var (
+	// errStreamClosed is returned by subscriptions of a stream which was closed
+	// because the Consul client was replaced.
+	errStreamClosed = errors.New("health stream closed")
+
+	// errStreamNotReady is returned when the snapshot of a subscription was not
+	// received in time.
+	errStreamNotReady = errors.New("health stream not ready")
+
+	// subscribeStreamDesc describes the server-streaming Subscribe method.
+	subscribeStreamDesc = &grpc.StreamDesc{
+		StreamName:    "Subscribe",
+		ServerStreams: true,
+	}
+)
+
+// healthStream streams the health of services from Consul's event stream,
+// which Consul servers serve over gRPC on their RPC port. All subscriptions
+// share the one connection, and each subscription is shared by the queries
+// for the same service, instead of every query holding open its own blocking
+// HTTP request.
+type healthStream struct {
+	conn  *grpc.ClientConn
+	token string
+
+	sync.Mutex
+	closed bool
+	subs   map[string]*healthSubscription
+}
+
+// newHealthStream creates a stream to the Consul server at the given RPC
+// address. The connection is established in the background, so no error is
+// returned for an unreachable server. If tlsConfig is non-nil, the connection
+// is upgraded to TLS like a Consul agent's.
+func newHealthStream(address, token string, tlsConfig *tls.Config) (*healthStream, error) {
+	conn, err := grpc.Dial(address,
+		grpc.WithInsecure(),
+		grpc.WithDialer(streamDialer(tlsConfig)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &healthStream{
+		conn:  conn,
+		token: token,
+		subs:  make(map[string]*healthSubscription),
+	}, nil
+}
+
+// streamDialer returns a dialer which selects the gRPC protocol on the RPC
+// port of a Consul server, switching to TLS first if tlsConfig is non-nil.
+func streamDialer(tlsConfig *tls.Config) func(string, time.Duration) (net.Conn, error) {
+	return func(address string, timeout time.Duration) (net.Conn, error) {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if tlsConfig != nil {
+			if _, err := conn.Write([]byte{rpcTLS}); err != nil {
+				conn.Close()
+				return nil, err
+			}
+
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+
+		if _, err := conn.Write([]byte{rpcGRPC}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// subscribe returns the subscription to the health of the given service in
+// the given datacenter, starting it if no query holds it yet. Each call must
+// be matched by a call to release.
+func (s *healthStream) subscribe(dc, name string) *healthSubscription {
+	s.Lock()
+	defer s.Unlock()
+
+	key := name
+	if dc != "" {
+		key = name + "@" + dc
+	}
+	sub, ok := s.subs[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		sub = &healthSubscription{
+			stream: s,
+			key:    key,
+			req: &subscribeRequest{
+				Topic:      topicServiceHealth,
+				Key:        name,
+				Token:      s.token,
+				Datacenter: dc,
+			},
+			cancel:   cancel,
+			entries:  make(map[string]*api.ServiceEntry),
+			updateCh: make(chan struct{}),
+		}
+		if s.closed {
+			sub.fail(errStreamClosed)
+		} else {
+			go sub.run(ctx)
+		}
+		s.subs[key] = sub
+	}
+
+	sub.refs++
+	return sub
+}
+
+// release gives up a reference to the given subscription, which is stopped
+// once no query holds it anymore.
+func (s *healthStream) release(sub *healthSubscription) {
+	s.Lock()
+	defer s.Unlock()
+
+	sub.refs--
+	if sub.refs > 0 {
+		return
+	}
+
+	sub.cancel()
+	if s.subs[sub.key] == sub {
+		delete(s.subs, sub.key)
+	}
+}
+
+// close stops all subscriptions and closes the connection.
+func (s *healthStream) close() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for _, sub := range s.subs {
+		sub.cancel()
+		sub.fail(errStreamClosed)
+	}
+	s.conn.Close()
+}
+
+// healthSubscription is a view of the health of one service, which is kept up
+// to date by the events of a subscription.
+type healthSubscription struct {
+	stream *healthStream
+	key    string
+	req    *subscribeRequest
+	cancel context.CancelFunc
+
+	// refs is the number of queries holding the subscription. It is guarded by
+	// the lock of the stream.
+	refs int
+
+	sync.Mutex
+
+	// entries are the instances of the service by node and service ID, and
+	// index is the index of the last event applied to them.
+	entries map[string]*api.ServiceEntry
+	index   uint64
+
+	// ready is true once the snapshot of the service was received, and err is
+	// the reason the subscription cannot currently be used, if any.
+	ready bool
+	err   error
+
+	// updateCh is closed and replaced whenever the view changes.
+	updateCh chan struct{}
+}
+
+// run subscribes to the service health until the context is canceled,
+// subscribing again with back-off whenever the stream fails. A server which
+// does not support streaming fails the subscription for good.
+func (sub *healthSubscription) run(ctx context.Context) {
+	retry := streamRetryMin
+	for {
+		err := sub.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if grpc.Code(err) == codes.Unimplemented {
+			log.Printf("[WARN] (health stream) %s: streaming is not supported by "+
+				"the Consul server: %s", sub.key, err)
+			sub.fail(err)
+			return
+		}
+
+		// Start over with the shortest back-off if the stream was working.
+		sub.Lock()
+		if sub.ready {
+			retry = streamRetryMin
+		}
+		sub.Unlock()
+
+		log.Printf("[WARN] (health stream) %s: %s (retry in %s)", sub.key, err, retry)
+		sub.fail(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+
+		if retry *= 2; retry > streamRetryMax {
+			retry = streamRetryMax
+		}
+	}
+}
+
+// subscribe runs one stream, applying its events until it fails.
+func (sub *healthSubscription) subscribe(ctx context.Context) error {
+	stream, err := grpc.NewClientStream(ctx, subscribeStreamDesc,
+		sub.stream.conn, subscribeMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(sub.req); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	// Every stream starts with a snapshot of the service, which replaces the
+	// view once it is complete.
+	snapshot := make(map[string]*api.ServiceEntry)
+	for {
+		var event subscribeEvent
+		if err := stream.RecvMsg(&event); err != nil {
+			if err == io.EOF {
+				err = errors.New("stream ended")
+			}
+			return err
+		}
+
+		switch {
+		case event.NewSnapshotToFollow:
+			snapshot = make(map[string]*api.ServiceEntry)
+		case event.EndOfSnapshot:
+			sub.replace(snapshot, event.Index)
+			snapshot = nil
+		case snapshot != nil:
+			applyHealthEvent(snapshot, &event)
+		default:
+			sub.apply(&event)
+		}
+	}
+}
+
+// replace replaces the view with the given snapshot.
+func (sub *healthSubscription) replace(entries map[string]*api.ServiceEntry, index uint64) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	sub.entries = entries
+	sub.index = index
+	sub.ready = true
+	sub.err = nil
+	sub.notify()
+}
+
+// apply applies the given event to the view.
+func (sub *healthSubscription) apply(event *subscribeEvent) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	applyHealthEvent(sub.entries, event)
+	if event.Index > sub.index {
+		sub.index = event.Index
+	}
+	sub.notify()
+}
+
+// fail marks the view as unusable with the given error until the next
+// snapshot is received.
+func (sub *healthSubscription) fail(err error) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	sub.ready = false
+	sub.err = err
+	sub.notify()
+}
+
+// notify wakes up the fetches waiting for the view to change. The lock must be
+// held.
+func (sub *healthSubscription) notify() {
+	close(sub.updateCh)
+	sub.updateCh = make(chan struct{})
+}
+
+// fetch returns the instances of the service and the index of the view once
+// it is past the given index or the wait time elapsed, like a blocking query.
+// It returns an error if the view is not usable or does not become ready in
+// time, so the caller can fall back to a blocking query.
+func (sub *healthSubscription) fetch(index uint64, wait time.Duration, stopCh <-chan struct{}) ([]*api.ServiceEntry, uint64, error) {
+	if wait <= 0 {
+		wait = defaultStreamWaitTime
+	}
+	waitCh := time.After(wait)
+	readyCh := time.After(streamReadyTimeout)
+
+	for {
+		sub.Lock()
+		ready, err, updateCh := sub.ready, sub.err, sub.updateCh
+		if ready && sub.index > index {
+			entries, current := sub.list(), sub.index
+			sub.Unlock()
+			return entries, current, nil
+		}
+		sub.Unlock()
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		select {
+		case <-stopCh:
+			return nil, 0, ErrStopped
+		case <-updateCh:
+		case <-readyCh:
+			if !ready {
+				return nil, 0, errStreamNotReady
+			}
+		case <-waitCh:
+			sub.Lock()
+			defer sub.Unlock()
+			if !sub.ready {
+				return nil, 0, errStreamNotReady
+			}
+			return sub.list(), sub.index, nil
+		}
+	}
+}
+
+// list returns a copy of the instances in the view. The lock must be held.
+func (sub *healthSubscription) list() []*api.ServiceEntry {
+	entries := make([]*api.ServiceEntry, 0, len(sub.entries))
+	for _, entry := range sub.entries {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// applyHealthEvent applies the service health updates of the given event,
+// which may be a batch of events, to the given instances.
+func applyHealthEvent(entries map[string]*api.ServiceEntry, event *subscribeEvent) {
+	if event.EventBatch != nil {
+		for _, e := range event.EventBatch.Events {
+			applyHealthEvent(entries, e)
+		}
+	}
+
+	update := event.ServiceHealth
+	if update == nil || update.CheckServiceNode == nil {
+		return
+	}
+
+	entry := update.CheckServiceNode.serviceEntry()
+	key := entry.Node.Node + "/" + entry.Service.ID
+	if update.Op == catalogOpDeregister {
+		delete(entries, key)
+		return
+	}
+	entries[key] = entry
+}
+
+// filterTag returns the entries which have the given tag. Tags are compared
+// case-insensitively, like the tag filter of the health endpoint.
+func filterTag(entries []*api.ServiceEntry, tag string) []*api.ServiceEntry {
+	if tag == "" {
+		return entries
+	}
+
+	filtered := make([]*api.ServiceEntry, 0, len(entries))
+	for _, entry := range entries {
+		for _, t := range entry.Service.Tags {
+			if strings.EqualFold(t, tag) {
+				filtered = append(filtered, entry)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// The following are the messages of Consul's event stream which are used for
+// service health. Only the fields consul-template needs are declared; the
+// others are skipped when decoding. The field numbers must match the
+// subscribe and service protobuf definitions of Consul.
+
+type subscribeRequest struct {
+	Topic      int32  `protobuf:"varint,1,opt,name=Topic,proto3"`
+	Key        string `protobuf:"bytes,2,opt,name=Key,proto3"`
+	Token      string `protobuf:"bytes,3,opt,name=Token,proto3"`
+	Index      uint64 `protobuf:"varint,4,opt,name=Index,proto3"`
+	Datacenter string `protobuf:"bytes,5,opt,name=Datacenter,proto3"`
+}
+
+func (m *subscribeRequest) Reset()         { *m = subscribeRequest{} }
+func (m *subscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*subscribeRequest) ProtoMessage()    {}
+
+type subscribeEvent struct {
+	Index               uint64               `protobuf:"varint,1,opt,name=Index,proto3"`
+	EndOfSnapshot       bool                 `protobuf:"varint,2,opt,name=EndOfSnapshot,proto3"`
+	NewSnapshotToFollow bool                 `protobuf:"varint,3,opt,name=NewSnapshotToFollow,proto3"`
+	EventBatch          *subscribeEventBatch `protobuf:"bytes,4,opt,name=EventBatch"`
+	ServiceHealth       *serviceHealthUpdate `protobuf:"bytes,10,opt,name=ServiceHealth"`
+}
+
+func (m *subscribeEvent) Reset()         { *m = subscribeEvent{} }
+func (m *subscribeEvent) String() string { return proto.CompactTextString(m) }
+func (*subscribeEvent) ProtoMessage()    {}
+
+type subscribeEventBatch struct {
+	Events []*subscribeEvent `protobuf:"bytes,1,rep,name=Events"`
+}
+
+func (m *subscribeEventBatch) Reset()         { *m = subscribeEventBatch{} }
+func (m *subscribeEventBatch) String() string { return proto.CompactTextString(m) }
+func (*subscribeEventBatch) ProtoMessage()    {}
+
+type serviceHealthUpdate struct {
+	Op               int32               `protobuf:"varint,1,opt,name=Op,proto3"`
+	CheckServiceNode *pbCheckServiceNode `protobuf:"bytes,2,opt,name=CheckServiceNode"`
+}
+
+func (m *serviceHealthUpdate) Reset()         { *m = serviceHealthUpdate{} }
+func (m *serviceHealthUpdate) String() string { return proto.CompactTextString(m) }
+func (*serviceHealthUpdate) ProtoMessage()    {}
+
+type pbCheckServiceNode struct {
+	Node    *pbNode          `protobuf:"bytes,1,opt,name=Node"`
+	Service *pbNodeService   `protobuf:"bytes,2,opt,name=Service"`
+	Checks  []*pbHealthCheck `protobuf:"bytes,3,rep,name=Checks"`
+}
+
+func (m *pbCheckServiceNode) Reset()         { *m = pbCheckServiceNode{} }
+func (m *pbCheckServiceNode) String() string { return proto.CompactTextString(m) }
+func (*pbCheckServiceNode) ProtoMessage()    {}
+
+// serviceEntry converts the node to the entry the health endpoint returns.
+func (m *pbCheckServiceNode) serviceEntry() *api.ServiceEntry {
+	entry := &api.ServiceEntry{
+		Node:    &api.Node{},
+		Service: &api.AgentService{},
+	}
+
+	if n := m.Node; n != nil {
+		entry.Node.Node = n.Node
+		entry.Node.Address = n.Address
+		entry.Node.TaggedAddresses = n.TaggedAddresses
+	}
+
+	if s := m.Service; s != nil {
+		entry.Service.ID = s.ID
+		entry.Service.Service = s.Service
+		entry.Service.Tags = s.Tags
+		entry.Service.Address = s.Address
+		entry.Service.Port = int(s.Port)
+	}
+
+	for _, c := range m.Checks {
+		entry.Checks = append(entry.Checks, &api.HealthCheck{
+			Node:        c.Node,
+			CheckID:     c.CheckID,
+			Name:        c.Name,
+			Status:      c.Status,
+			Notes:       c.Notes,
+			Output:      c.Output,
+			ServiceID:   c.ServiceID,
+			ServiceName: c.ServiceName,
+		})
+	}
+
+	return entry
+}
+
+type pbNode struct {
+	Node            string            `protobuf:"bytes,2,opt,name=Node,proto3"`
+	Address         string            `protobuf:"bytes,3,opt,name=Address,proto3"`
+	TaggedAddresses map[string]string `protobuf:"bytes,5,rep,name=TaggedAddresses" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *pbNode) Reset()         { *m = pbNode{} }
+func (m *pbNode) String() string { return proto.CompactTextString(m) }
+func (*pbNode) ProtoMessage()    {}
+
+type pbNodeService struct {
+	ID      string   `protobuf:"bytes,2,opt,name=ID,proto3"`
+	Service string   `protobuf:"bytes,3,opt,name=Service,proto3"`
+	Tags    []string `protobuf:"bytes,4,rep,name=Tags"`
+	Address string   `protobuf:"bytes,5,opt,name=Address,proto3"`
+	Port    int32    `protobuf:"varint,7,opt,name=Port,proto3"`
+}
+
+func (m *pbNodeService) Reset()         { *m = pbNodeService{} }
+func (m *pbNodeService) String() string { return proto.CompactTextString(m) }
+func (*pbNodeService) ProtoMessage()    {}
+
+type pbHealthCheck struct {
+	Node        string `protobuf:"bytes,1,opt,name=Node,proto3"`
+	CheckID     string `protobuf:"bytes,2,opt,name=CheckID,proto3"`
+	Name        string `protobuf:"bytes,3,opt,name=Name,proto3"`
+	Status      string `protobuf:"bytes,4,opt,name=Status,proto3"`
+	Notes       string `protobuf:"bytes,5,opt,name=Notes,proto3"`
+	Output      string `protobuf:"bytes,6,opt,name=Output,proto3"`
+	ServiceID   string `protobuf:"bytes,7,opt,name=ServiceID,proto3"`
+	ServiceName string `protobuf:"bytes,8,opt,name=ServiceName,proto3"`
+}
+
+func (m *pbHealthCheck) Reset()         { *m = pbHealthCheck{} }
+func (m *pbHealthCheck) String() string { return proto.CompactTextString(m) }
+func (*pbHealthCheck) ProtoMessage()    {}
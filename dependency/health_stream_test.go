@@ -0,0 +1,243 @@
+package dependency
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// testStreamListener is a listener which checks that connections select the
+// gRPC protocol, like the RPC port of a Consul server.
+type testStreamListener struct {
+	net.Listener
+}
+
+func (l *testStreamListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, 1)
+		if _, err := conn.Read(b); err != nil || b[0] != rpcGRPC {
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
+
+// testStreamServer starts a gRPC server which streams the given events for
+// every subscription, and then the events sent on the returned channel.
+func testStreamServer(t *testing.T, events ...*subscribeEvent) (string, chan<- *subscribeEvent, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateCh := make(chan *subscribeEvent, 1)
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		var req subscribeRequest
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		if req.Topic != topicServiceHealth || req.Key != "web" || req.Token != "token" {
+			return grpc.Errorf(codes.InvalidArgument, "unexpected request %s", &req)
+		}
+
+		for _, event := range events {
+			if err := stream.SendMsg(event); err != nil {
+				return err
+			}
+		}
+		for event := range updateCh {
+			if err := stream.SendMsg(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "subscribe.StateChangeSubscription",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Subscribe",
+				Handler:       handler,
+				ServerStreams: true,
+			},
+		},
+	}, struct{}{})
+	go s.Serve(&testStreamListener{ln})
+
+	return ln.Addr().String(), updateCh, s.Stop
+}
+
+// testServiceHealthEvent returns an event which registers or deregisters an
+// instance of the web service on the given node.
+func testServiceHealthEvent(op int32, node, status string, tags ...string) *subscribeEvent {
+	return &subscribeEvent{
+		ServiceHealth: &serviceHealthUpdate{
+			Op: op,
+			CheckServiceNode: &pbCheckServiceNode{
+				Node: &pbNode{
+					Node:    node,
+					Address: "10.0.0.1",
+				},
+				Service: &pbNodeService{
+					ID:      "web",
+					Service: "web",
+					Tags:    tags,
+					Port:    8080,
+				},
+				Checks: []*pbHealthCheck{
+					{
+						Node:    node,
+						CheckID: "web-check",
+						Status:  status,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestHealthServiceQuery_FetchStream(t *testing.T) {
+	t.Parallel()
+
+	address, updateCh, stop := testStreamServer(t,
+		testServiceHealthEvent(0, "node1", "passing", "release"),
+		&subscribeEvent{
+			EventBatch: &subscribeEventBatch{
+				Events: []*subscribeEvent{
+					testServiceHealthEvent(0, "node2", "passing"),
+					testServiceHealthEvent(0, "node3", "critical"),
+				},
+			},
+		},
+		&subscribeEvent{Index: 10, EndOfSnapshot: true},
+	)
+	defer stop()
+
+	clients := NewClientSet()
+	if err := clients.CreateConsulClient(&CreateConsulClientInput{
+		Token:            "token",
+		StreamingEnabled: true,
+		StreamingAddress: address,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Stop()
+
+	d, err := NewHealthServiceQuery("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	act, rm, err := d.Fetch(clients, &QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rm.LastIndex != 10 {
+		t.Errorf("\nexp: %#v\nact: %#v", 10, rm.LastIndex)
+	}
+
+	var nodes []string
+	for _, s := range act.([]*HealthService) {
+		nodes = append(nodes, s.Node)
+	}
+	if exp := []string{"node1", "node2"}; !reflect.DeepEqual(exp, nodes) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, nodes)
+	}
+
+	// The tag is filtered on the client, from the same subscription.
+	tagged, err := NewHealthServiceQuery("release.web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tagged.Stop()
+
+	act, _, err = tagged.Fetch(clients, &QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(act.([]*HealthService)); l != 1 {
+		t.Errorf("\nexp: %#v\nact: %#v", 1, l)
+	}
+
+	// A blocking fetch returns once the service changes.
+	time.AfterFunc(50*time.Millisecond, func() {
+		event := testServiceHealthEvent(catalogOpDeregister, "node2", "passing")
+		event.Index = 11
+		updateCh <- event
+	})
+
+	act, rm, err = d.Fetch(clients, &QueryOptions{WaitIndex: 10, WaitTime: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rm.LastIndex != 11 {
+		t.Errorf("\nexp: %#v\nact: %#v", 11, rm.LastIndex)
+	}
+	if l := len(act.([]*HealthService)); l != 1 {
+		t.Errorf("\nexp: %#v\nact: %#v", 1, l)
+	}
+}
+
+func TestHealthServiceQuery_FetchStreamUnsupported(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	go s.Serve(&testStreamListener{ln})
+	defer s.Stop()
+
+	stream, err := newHealthStream(ln.Addr().String(), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.close()
+
+	d, err := NewHealthServiceQuery("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	if _, _, err := d.fetchStream(stream, &QueryOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestHealthServiceQuery_canStream(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"web":               true,
+		"release.web@dc1":   true,
+		"web?segment=alpha": false,
+		"web?peer=partner":  false,
+		"web~node1":         false,
+	}
+
+	for s, exp := range cases {
+		d, err := NewHealthServiceQuery(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := d.canStream(); act != exp {
+			t.Errorf("%s\nexp: %#v\nact: %#v", s, exp, act)
+		}
+	}
+}
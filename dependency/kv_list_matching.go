@@ -0,0 +1,77 @@
+package dependency
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*KVListMatchingQuery)(nil)
+)
+
+// KVListMatchingQuery queries the KV store for the keys under a prefix which
+// match a regular expression. Only the matching keys are returned, so changes
+// to other keys under the prefix do not change the data.
+type KVListMatchingQuery struct {
+	list *KVListQuery
+	re   *regexp.Regexp
+}
+
+// NewKVListMatchingQuery parses a prefix string and a regular expression into
+// a dependency. The regular expression is matched against each key relative
+// to the prefix.
+func NewKVListMatchingQuery(s, pattern string) (*KVListMatchingQuery, error) {
+	list, err := NewKVListQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("kv.list.matching: invalid pattern: %s", err)
+	}
+
+	return &KVListMatchingQuery{
+		list: list,
+		re:   re,
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// matching keys.
+func (d *KVListMatchingQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	result, rm, err := d.list.Fetch(clients, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	list := result.([]*KeyPair)
+	pairs := make([]*KeyPair, 0, len(list))
+	for _, pair := range list {
+		if d.re.MatchString(pair.Key) {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *KVListMatchingQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *KVListMatchingQuery) String() string {
+	prefix := d.list.prefix
+	if d.list.dc != "" {
+		prefix = prefix + "@" + d.list.dc
+	}
+	return fmt.Sprintf("kv.list.matching(%s|%s)", prefix, d.re)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *KVListMatchingQuery) Stop() {
+	d.list.Stop()
+}
@@ -0,0 +1,137 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKVListMatchingQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		i       string
+		pattern string
+		exp     string
+		err     bool
+	}{
+		{
+			"prefix",
+			"prefix",
+			"^foo",
+			"kv.list.matching(prefix|^foo)",
+			false,
+		},
+		{
+			"dc",
+			"prefix@dc1",
+			"^foo",
+			"kv.list.matching(prefix@dc1|^foo)",
+			false,
+		},
+		{
+			"invalid_prefix",
+			"@dc1",
+			"^foo",
+			"",
+			true,
+		},
+		{
+			"invalid_pattern",
+			"prefix",
+			"(",
+			"",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewKVListMatchingQuery(tc.i, tc.pattern)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				assert.Equal(t, tc.exp, act.String())
+			}
+		})
+	}
+}
+
+func TestKVListMatchingQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	clients, consul := testConsulServer(t)
+	defer consul.Stop()
+
+	consul.SetKV("prefix/foo", []byte("bar"))
+	consul.SetKV("prefix/zip", []byte("zap"))
+	consul.SetKV("prefix/wave/ocean", []byte("sleek"))
+
+	cases := []struct {
+		name    string
+		i       string
+		pattern string
+		exp     []*KeyPair
+	}{
+		{
+			"matching",
+			"prefix",
+			"^(foo|zip)$",
+			[]*KeyPair{
+				&KeyPair{
+					Path:  "prefix/foo",
+					Key:   "foo",
+					Value: "bar",
+				},
+				&KeyPair{
+					Path:  "prefix/zip",
+					Key:   "zip",
+					Value: "zap",
+				},
+			},
+		},
+		{
+			"nested",
+			"prefix/",
+			"^wave/",
+			[]*KeyPair{
+				&KeyPair{
+					Path:  "prefix/wave/ocean",
+					Key:   "wave/ocean",
+					Value: "sleek",
+				},
+			},
+		},
+		{
+			"none",
+			"prefix",
+			"^nope$",
+			[]*KeyPair{},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewKVListMatchingQuery(tc.i, tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range act.([]*KeyPair) {
+				p.CreateIndex = 0
+				p.ModifyIndex = 0
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
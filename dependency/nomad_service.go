@@ -0,0 +1,166 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*NomadServiceQuery)(nil)
+
+	// NomadServiceQueryRe is the regular expression to use for
+	// NomadServiceQuery.
+	NomadServiceQueryRe = regexp.MustCompile(`\A` + tagRe + nameRe + `\z`)
+)
+
+func init() {
+	gob.Register([]*NomadService{})
+}
+
+// NomadService is an instance of a service registered with Nomad's native
+// service discovery.
+type NomadService struct {
+	ID         string
+	Name       string
+	Namespace  string
+	NodeID     string
+	Datacenter string
+	JobID      string
+	AllocID    string
+	Tags       ServiceTags
+	Address    string
+	Port       int
+}
+
+// nomadServiceRegistration is a service instance as returned by the
+// /v1/service/:name endpoint of Nomad.
+type nomadServiceRegistration struct {
+	ID          string
+	ServiceName string
+	Namespace   string
+	NodeID      string
+	Datacenter  string
+	JobID       string
+	AllocID     string
+	Tags        []string
+	Address     string
+	Port        int
+}
+
+// NomadServiceQuery is the representation of a requested Nomad service
+// dependency from inside a template.
+type NomadServiceQuery struct {
+	stopCh chan struct{}
+
+	name string
+	tag  string
+}
+
+// NewNomadServiceQuery parses a string of the format [tag.]name. If a tag is
+// given, only the instances with that tag are returned.
+func NewNomadServiceQuery(s string) (*NomadServiceQuery, error) {
+	if !NomadServiceQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("nomad.service: invalid format: %q", s)
+	}
+
+	m := regexpMatch(NomadServiceQueryRe, s)
+	return &NomadServiceQuery{
+		stopCh: make(chan struct{}, 1),
+		name:   m["name"],
+		tag:    m["tag"],
+	}, nil
+}
+
+// Fetch queries the Nomad API defined by the given client and returns a slice
+// of NomadService objects, sorted by address and port.
+func (d *NomadServiceQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	// The path is escaped as the URL path of the request.
+	path := "/v1/service/" + d.name
+	log.Printf("[TRACE] %s: GET %s", d, path)
+
+	var registrations []*nomadServiceRegistration
+	rm, err := clients.nomadQuery(path, opts, &registrations)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	services := make([]*NomadService, 0, len(registrations))
+	for _, s := range registrations {
+		if d.tag != "" && !contains(s.Tags, d.tag) {
+			continue
+		}
+		services = append(services, &NomadService{
+			ID:         s.ID,
+			Name:       s.ServiceName,
+			Namespace:  s.Namespace,
+			NodeID:     s.NodeID,
+			Datacenter: s.Datacenter,
+			JobID:      s.JobID,
+			AllocID:    s.AllocID,
+			Tags:       ServiceTags(deepCopyAndSortTags(s.Tags)),
+			Address:    s.Address,
+			Port:       s.Port,
+		})
+	}
+	sort.Stable(ByNomadAddressThenPort(services))
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(services))
+
+	return services, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *NomadServiceQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *NomadServiceQuery) String() string {
+	name := d.name
+	if d.tag != "" {
+		name = d.tag + "." + name
+	}
+	return fmt.Sprintf("nomad.service(%s)", name)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *NomadServiceQuery) Stop() {
+	close(d.stopCh)
+}
+
+// contains returns true if the given tags include the given tag.
+func contains(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ByNomadAddressThenPort is a sortable slice of NomadService structs.
+type ByNomadAddressThenPort []*NomadService
+
+func (s ByNomadAddressThenPort) Len() int      { return len(s) }
+func (s ByNomadAddressThenPort) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByNomadAddressThenPort) Less(i, j int) bool {
+	if s[i].Address == s[j].Address {
+		if s[i].Port == s[j].Port {
+			return s[i].ID < s[j].ID
+		}
+		return s[i].Port < s[j].Port
+	}
+	return s[i].Address < s[j].Address
+}
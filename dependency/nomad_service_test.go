@@ -0,0 +1,230 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testNomadServer starts an HTTP server which serves the given responses of
+// the Nomad API, keyed by path, and returns a client set for it.
+func testNomadServer(t *testing.T, responses map[string]string) (*ClientSet, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Nomad-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("namespace") != "web" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Nomad-Index", "12")
+		fmt.Fprint(w, body)
+	}))
+
+	clients := NewClientSet()
+	if err := clients.CreateNomadClient(&CreateNomadClientInput{
+		Address:   srv.URL,
+		Token:     "token",
+		Namespace: "web",
+	}); err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return clients, srv
+}
+
+func TestNewNomadServiceQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *NomadServiceQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"name",
+			"api",
+			&NomadServiceQuery{
+				name: "api",
+			},
+			false,
+		},
+		{
+			"tag_name",
+			"v2.api",
+			&NomadServiceQuery{
+				name: "api",
+				tag:  "v2",
+			},
+			false,
+		},
+		{
+			"dc",
+			"api@dc1",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewNomadServiceQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestNomadServiceQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	clients, srv := testNomadServer(t, map[string]string{
+		"/v1/service/api": `[
+			{"ID": "b", "ServiceName": "api", "Namespace": "web", "NodeID": "n2",
+				"Datacenter": "dc1", "JobID": "api", "AllocID": "a2",
+				"Tags": ["v1"], "Address": "10.0.0.2", "Port": 8080},
+			{"ID": "a", "ServiceName": "api", "Namespace": "web", "NodeID": "n1",
+				"Datacenter": "dc1", "JobID": "api", "AllocID": "a1",
+				"Tags": ["v2", "v1"], "Address": "10.0.0.1", "Port": 8080}
+		]`,
+	})
+	defer srv.Close()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  []*NomadService
+	}{
+		{
+			"all",
+			"api",
+			[]*NomadService{
+				&NomadService{
+					ID:         "a",
+					Name:       "api",
+					Namespace:  "web",
+					NodeID:     "n1",
+					Datacenter: "dc1",
+					JobID:      "api",
+					AllocID:    "a1",
+					Tags:       ServiceTags([]string{"v1", "v2"}),
+					Address:    "10.0.0.1",
+					Port:       8080,
+				},
+				&NomadService{
+					ID:         "b",
+					Name:       "api",
+					Namespace:  "web",
+					NodeID:     "n2",
+					Datacenter: "dc1",
+					JobID:      "api",
+					AllocID:    "a2",
+					Tags:       ServiceTags([]string{"v1"}),
+					Address:    "10.0.0.2",
+					Port:       8080,
+				},
+			},
+		},
+		{
+			"tag",
+			"v2.api",
+			[]*NomadService{
+				&NomadService{
+					ID:         "a",
+					Name:       "api",
+					Namespace:  "web",
+					NodeID:     "n1",
+					Datacenter: "dc1",
+					JobID:      "api",
+					AllocID:    "a1",
+					Tags:       ServiceTags([]string{"v1", "v2"}),
+					Address:    "10.0.0.1",
+					Port:       8080,
+				},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewNomadServiceQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, rm, err := d.Fetch(clients, &QueryOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rm.LastIndex != 12 {
+				t.Errorf("expected index 12, got %d", rm.LastIndex)
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+
+	t.Run("not_enabled", func(t *testing.T) {
+		d, err := NewNomadServiceQuery("api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(NewClientSet(), &QueryOptions{}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestNomadServiceQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"name",
+			"api",
+			"nomad.service(api)",
+		},
+		{
+			"tag_name",
+			"v2.api",
+			"nomad.service(v2.api)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewNomadServiceQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*NomadServicesQuery)(nil)
+
+	// NomadServicesQueryRe is the regular expression to use for
+	// NomadServicesQuery.
+	NomadServicesQueryRe = regexp.MustCompile(`\A\z`)
+)
+
+func init() {
+	gob.Register([]*NomadServicesSnippet{})
+}
+
+// NomadServicesSnippet is a service registered with Nomad's native service
+// discovery.
+type NomadServicesSnippet struct {
+	Name string
+	Tags ServiceTags
+}
+
+// nomadServiceListStub is a namespace and its services as returned by the
+// /v1/services endpoint of Nomad.
+type nomadServiceListStub struct {
+	Namespace string
+	Services  []struct {
+		ServiceName string
+		Tags        []string
+	}
+}
+
+// NomadServicesQuery is the representation of a requested list of the services
+// registered with Nomad from inside a template.
+type NomadServicesQuery struct {
+	stopCh chan struct{}
+}
+
+// NewNomadServicesQuery parses a string of the services to list, which must be
+// empty, since the namespace and region come from the Nomad configuration.
+func NewNomadServicesQuery(s string) (*NomadServicesQuery, error) {
+	if !NomadServicesQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("nomad.services: invalid format: %q", s)
+	}
+
+	return &NomadServicesQuery{
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Nomad API defined by the given client and returns a slice
+// of NomadServicesSnippet objects, sorted by name.
+func (d *NomadServicesQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	log.Printf("[TRACE] %s: GET /v1/services", d)
+
+	var stubs []*nomadServiceListStub
+	rm, err := clients.nomadQuery("/v1/services", opts, &stubs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	// Services with the same name in several namespaces are listed once, with
+	// the tags of all of them.
+	tags := make(map[string][]string)
+	for _, stub := range stubs {
+		for _, s := range stub.Services {
+			tags[s.ServiceName] = append(tags[s.ServiceName], s.Tags...)
+		}
+	}
+
+	services := make([]*NomadServicesSnippet, 0, len(tags))
+	for name, t := range tags {
+		services = append(services, &NomadServicesSnippet{
+			Name: name,
+			Tags: ServiceTags(deepCopyAndSortTags(uniqueTags(t))),
+		})
+	}
+	sort.Sort(ByNomadServiceName(services))
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(services))
+
+	return services, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *NomadServicesQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *NomadServicesQuery) String() string {
+	return "nomad.services"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *NomadServicesQuery) Stop() {
+	close(d.stopCh)
+}
+
+// uniqueTags returns the given tags without duplicates.
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	unique := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		unique = append(unique, t)
+	}
+	return unique
+}
+
+// ByNomadServiceName is a sortable slice of NomadServicesSnippet structs.
+type ByNomadServiceName []*NomadServicesSnippet
+
+func (s ByNomadServiceName) Len() int           { return len(s) }
+func (s ByNomadServiceName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s ByNomadServiceName) Less(i, j int) bool { return s[i].Name < s[j].Name }
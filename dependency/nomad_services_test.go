@@ -0,0 +1,86 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNomadServicesQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *NomadServicesQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&NomadServicesQuery{},
+			false,
+		},
+		{
+			"dc",
+			"@dc1",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewNomadServicesQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestNomadServicesQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	clients, srv := testNomadServer(t, map[string]string{
+		"/v1/services": `[
+			{"Namespace": "web", "Services": [
+				{"ServiceName": "web", "Tags": ["b", "a"]},
+				{"ServiceName": "api", "Tags": []}
+			]},
+			{"Namespace": "other", "Services": [
+				{"ServiceName": "web", "Tags": ["c", "a"]}
+			]}
+		]`,
+	})
+	defer srv.Close()
+
+	d, err := NewNomadServicesQuery("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, _, err := d.Fetch(clients, &QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []*NomadServicesSnippet{
+		&NomadServicesSnippet{
+			Name: "api",
+			Tags: ServiceTags([]string{}),
+		},
+		&NomadServicesSnippet{
+			Name: "web",
+			Tags: ServiceTags([]string{"a", "b", "c"}),
+		},
+	}
+	assert.Equal(t, exp, act)
+}
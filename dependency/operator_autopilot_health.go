@@ -0,0 +1,220 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*OperatorAutopilotHealthQuery)(nil)
+
+	// OperatorAutopilotHealthQueryRe is the regular expression to use.
+	OperatorAutopilotHealthQueryRe = regexp.MustCompile(`\A` + dcRe + `\z`)
+
+	// OperatorAutopilotHealthQuerySleepTime is the amount of time to sleep
+	// between queries, since the endpoint does not support blocking queries.
+	OperatorAutopilotHealthQuerySleepTime = 10 * time.Second
+)
+
+func init() {
+	gob.Register(&AutopilotHealth{})
+}
+
+// AutopilotHealth is the health of the Consul servers, as reported by
+// autopilot.
+type AutopilotHealth struct {
+	// Healthy is true if all the servers are healthy.
+	Healthy bool
+
+	// FailureTolerance is the number of servers which could fail without the
+	// cluster losing quorum.
+	FailureTolerance int
+
+	// Servers is the health of each server, sorted by name.
+	Servers []*AutopilotServerHealth
+}
+
+// AutopilotServerHealth is the health of a single Consul server.
+type AutopilotServerHealth struct {
+	ID          string
+	Name        string
+	Address     string
+	SerfStatus  string
+	Version     string
+	Leader      bool
+	LastContact time.Duration
+	LastTerm    uint64
+	LastIndex   uint64
+	Healthy     bool
+	Voter       bool
+	StableSince time.Time
+}
+
+// autopilotHealthResponse is the response of the autopilot health endpoint.
+// LastContact is encoded as a duration string, such as "10ms".
+type autopilotHealthResponse struct {
+	Healthy          bool
+	FailureTolerance int
+	Servers          []struct {
+		ID          string
+		Name        string
+		Address     string
+		SerfStatus  string
+		Version     string
+		Leader      bool
+		LastContact string
+		LastTerm    uint64
+		LastIndex   uint64
+		Healthy     bool
+		Voter       bool
+		StableSince time.Time
+	}
+}
+
+// OperatorAutopilotHealthQuery is the dependency to query the health of the
+// Consul servers from autopilot.
+type OperatorAutopilotHealthQuery struct {
+	stopCh chan struct{}
+
+	dc string
+}
+
+// NewOperatorAutopilotHealthQuery parses the given string into a dependency.
+// If no datacenter is given, the datacenter of the local agent is used.
+func NewOperatorAutopilotHealthQuery(s string) (*OperatorAutopilotHealthQuery, error) {
+	if !OperatorAutopilotHealthQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("operator.autopilot: invalid format: %q", s)
+	}
+
+	m := regexpMatch(OperatorAutopilotHealthQueryRe, s)
+	return &OperatorAutopilotHealthQuery{
+		dc:     m["dc"],
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// AutopilotHealth of the servers.
+func (d *OperatorAutopilotHealthQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	params := url.Values{}
+	if opts.Datacenter != "" {
+		params.Set("dc", opts.Datacenter)
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/operator/autopilot/health",
+		RawQuery: params.Encode(),
+	})
+
+	// The autopilot health endpoint does not support blocking queries, so
+	// sleep between queries once we have returned data.
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, OperatorAutopilotHealthQuerySleepTime)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(OperatorAutopilotHealthQuerySleepTime):
+		}
+	}
+
+	resp, err := clients.consulGet("/v1/operator/autopilot/health", params)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+	defer resp.Body.Close()
+
+	// Consul answers with 429 when the servers are unhealthy, which is still a
+	// valid health report.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusTooManyRequests {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, nil, errors.Wrap(fmt.Errorf("unexpected response code: %d (%s)",
+			resp.StatusCode, body), d.String())
+	}
+
+	var r autopilotHealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(r.Servers))
+
+	health := &AutopilotHealth{
+		Healthy:          r.Healthy,
+		FailureTolerance: r.FailureTolerance,
+		Servers:          make([]*AutopilotServerHealth, 0, len(r.Servers)),
+	}
+	for _, s := range r.Servers {
+		var lastContact time.Duration
+		if s.LastContact != "" {
+			lastContact, err = time.ParseDuration(s.LastContact)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, d.String())
+			}
+		}
+
+		health.Servers = append(health.Servers, &AutopilotServerHealth{
+			ID:          s.ID,
+			Name:        s.Name,
+			Address:     s.Address,
+			SerfStatus:  s.SerfStatus,
+			Version:     s.Version,
+			Leader:      s.Leader,
+			LastContact: lastContact,
+			LastTerm:    s.LastTerm,
+			LastIndex:   s.LastIndex,
+			Healthy:     s.Healthy,
+			Voter:       s.Voter,
+			StableSince: s.StableSince,
+		})
+	}
+	sort.Stable(ByAutopilotServerName(health.Servers))
+
+	return respWithMetadata(health)
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *OperatorAutopilotHealthQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *OperatorAutopilotHealthQuery) String() string {
+	if d.dc != "" {
+		return fmt.Sprintf("operator.autopilot(@%s)", d.dc)
+	}
+	return "operator.autopilot"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *OperatorAutopilotHealthQuery) Stop() {
+	close(d.stopCh)
+}
+
+// ByAutopilotServerName is a sortable slice of AutopilotServerHealth.
+type ByAutopilotServerName []*AutopilotServerHealth
+
+// Len, Swap, and Less are used to implement the sort.Sort interface.
+func (s ByAutopilotServerName) Len() int      { return len(s) }
+func (s ByAutopilotServerName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByAutopilotServerName) Less(i, j int) bool {
+	if s[i].Name == s[j].Name {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].Name < s[j].Name
+}
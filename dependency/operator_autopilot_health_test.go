@@ -0,0 +1,197 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	OperatorAutopilotHealthQuerySleepTime = 50 * time.Millisecond
+}
+
+func TestNewOperatorAutopilotHealthQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *OperatorAutopilotHealthQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&OperatorAutopilotHealthQuery{},
+			false,
+		},
+		{
+			"dc",
+			"@dc1",
+			&OperatorAutopilotHealthQuery{
+				dc: "dc1",
+			},
+			false,
+		},
+		{
+			"name",
+			"server1",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewOperatorAutopilotHealthQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestOperatorAutopilotHealthQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		code int
+		body string
+		exp  *AutopilotHealth
+		err  bool
+	}{
+		{
+			"healthy",
+			http.StatusOK,
+			`{"Healthy": true, "FailureTolerance": 1, "Servers": [
+				{"ID": "b", "Name": "server2", "LastContact": "10ms", "Healthy": true, "Voter": true},
+				{"ID": "a", "Name": "server1", "LastContact": "0s", "Leader": true, "Healthy": true, "Voter": true}
+			]}`,
+			&AutopilotHealth{
+				Healthy:          true,
+				FailureTolerance: 1,
+				Servers: []*AutopilotServerHealth{
+					&AutopilotServerHealth{
+						ID:      "a",
+						Name:    "server1",
+						Leader:  true,
+						Healthy: true,
+						Voter:   true,
+					},
+					&AutopilotServerHealth{
+						ID:          "b",
+						Name:        "server2",
+						LastContact: 10 * time.Millisecond,
+						Healthy:     true,
+						Voter:       true,
+					},
+				},
+			},
+			false,
+		},
+		{
+			"unhealthy",
+			http.StatusTooManyRequests,
+			`{"Healthy": false, "FailureTolerance": 0, "Servers": [
+				{"ID": "a", "Name": "server1", "LastContact": "1m0s", "Healthy": false, "Voter": true}
+			]}`,
+			&AutopilotHealth{
+				Healthy:          false,
+				FailureTolerance: 0,
+				Servers: []*AutopilotServerHealth{
+					&AutopilotServerHealth{
+						ID:          "a",
+						Name:        "server1",
+						LastContact: time.Minute,
+						Voter:       true,
+					},
+				},
+			},
+			false,
+		},
+		{
+			"error",
+			http.StatusForbidden,
+			`Permission denied`,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/operator/autopilot/health", r.URL.Path)
+				assert.Equal(t, "token", r.Header.Get("X-Consul-Token"))
+				w.WriteHeader(tc.code)
+				w.Write([]byte(tc.body))
+			}))
+			defer s.Close()
+
+			clients := NewClientSet()
+			if err := clients.CreateConsulClient(&CreateConsulClientInput{
+				Address: strings.TrimPrefix(s.URL, "http://"),
+				Token:   "token",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewOperatorAutopilotHealthQuery("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if tc.exp != nil {
+				assert.Equal(t, tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestOperatorAutopilotHealthQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"empty",
+			"",
+			"operator.autopilot",
+		},
+		{
+			"datacenter",
+			"@dc1",
+			"operator.autopilot(@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewOperatorAutopilotHealthQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
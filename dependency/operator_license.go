@@ -0,0 +1,160 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*OperatorLicenseQuery)(nil)
+
+	// OperatorLicenseQueryRe is the regular expression to use.
+	OperatorLicenseQueryRe = regexp.MustCompile(`\A` + dcRe + `\z`)
+
+	// OperatorLicenseQuerySleepTime is the amount of time to sleep between
+	// queries, since the endpoint does not support blocking queries.
+	OperatorLicenseQuerySleepTime = 1 * time.Minute
+)
+
+func init() {
+	gob.Register(&License{})
+}
+
+// License is the Consul Enterprise license of a datacenter.
+type License struct {
+	// Valid is true if the license is currently valid.
+	Valid bool
+
+	LicenseID      string
+	CustomerID     string
+	InstallationID string
+	Product        string
+	IssueTime      time.Time
+	StartTime      time.Time
+	ExpirationTime time.Time
+
+	// Features are the sorted names of the licensed features.
+	Features []string
+
+	// Warnings are any warnings about the license, such as its upcoming
+	// expiration.
+	Warnings []string
+}
+
+// licenseResponse is the response of the license endpoint.
+type licenseResponse struct {
+	Valid   bool
+	License struct {
+		LicenseID      string    `json:"license_id"`
+		CustomerID     string    `json:"customer_id"`
+		InstallationID string    `json:"installation_id"`
+		Product        string    `json:"product"`
+		IssueTime      time.Time `json:"issue_time"`
+		StartTime      time.Time `json:"start_time"`
+		ExpirationTime time.Time `json:"expiration_time"`
+		Features       []string  `json:"features"`
+	}
+	Warnings []string
+}
+
+// OperatorLicenseQuery is the dependency to query the Consul Enterprise license.
+type OperatorLicenseQuery struct {
+	stopCh chan struct{}
+
+	dc string
+}
+
+// NewOperatorLicenseQuery parses the given string into a dependency. If no
+// datacenter is given, the datacenter of the local agent is used.
+func NewOperatorLicenseQuery(s string) (*OperatorLicenseQuery, error) {
+	if !OperatorLicenseQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("operator.license: invalid format: %q", s)
+	}
+
+	m := regexpMatch(OperatorLicenseQueryRe, s)
+	return &OperatorLicenseQuery{
+		dc:     m["dc"],
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// License of the datacenter.
+func (d *OperatorLicenseQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	params := url.Values{}
+	if opts.Datacenter != "" {
+		params.Set("dc", opts.Datacenter)
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/operator/license",
+		RawQuery: params.Encode(),
+	})
+
+	// The license endpoint does not support blocking queries, so sleep between
+	// queries once we have returned data.
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, OperatorLicenseQuerySleepTime)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(OperatorLicenseQuerySleepTime):
+		}
+	}
+
+	var r licenseResponse
+	if _, err := clients.consulQuery("/v1/operator/license", &QueryOptions{
+		Datacenter: opts.Datacenter,
+	}, nil, &r); err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned license %s", d, r.License.LicenseID)
+
+	features := append([]string{}, r.License.Features...)
+	sort.Strings(features)
+
+	return respWithMetadata(&License{
+		Valid:          r.Valid,
+		LicenseID:      r.License.LicenseID,
+		CustomerID:     r.License.CustomerID,
+		InstallationID: r.License.InstallationID,
+		Product:        r.License.Product,
+		IssueTime:      r.License.IssueTime,
+		StartTime:      r.License.StartTime,
+		ExpirationTime: r.License.ExpirationTime,
+		Features:       features,
+		Warnings:       append([]string{}, r.Warnings...),
+	})
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *OperatorLicenseQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *OperatorLicenseQuery) String() string {
+	if d.dc != "" {
+		return fmt.Sprintf("operator.license(@%s)", d.dc)
+	}
+	return "operator.license"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *OperatorLicenseQuery) Stop() {
+	close(d.stopCh)
+}
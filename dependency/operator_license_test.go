@@ -0,0 +1,171 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	OperatorLicenseQuerySleepTime = 50 * time.Millisecond
+}
+
+func TestNewOperatorLicenseQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *OperatorLicenseQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&OperatorLicenseQuery{},
+			false,
+		},
+		{
+			"dc",
+			"@dc1",
+			&OperatorLicenseQuery{
+				dc: "dc1",
+			},
+			false,
+		},
+		{
+			"name",
+			"license",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewOperatorLicenseQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestOperatorLicenseQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	expiration := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		code int
+		body string
+		exp  *License
+		err  bool
+	}{
+		{
+			"license",
+			http.StatusOK,
+			`{"Valid": true, "License": {
+				"license_id": "id",
+				"customer_id": "customer",
+				"installation_id": "*",
+				"product": "consul",
+				"expiration_time": "2018-01-01T00:00:00Z",
+				"features": ["Redundancy Zones", "Network Segments"]
+			}, "Warnings": ["expires soon"]}`,
+			&License{
+				Valid:          true,
+				LicenseID:      "id",
+				CustomerID:     "customer",
+				InstallationID: "*",
+				Product:        "consul",
+				ExpirationTime: expiration,
+				Features:       []string{"Network Segments", "Redundancy Zones"},
+				Warnings:       []string{"expires soon"},
+			},
+			false,
+		},
+		{
+			"not_enterprise",
+			http.StatusNotFound,
+			``,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/operator/license", r.URL.Path)
+				w.WriteHeader(tc.code)
+				w.Write([]byte(tc.body))
+			}))
+			defer s.Close()
+
+			clients := NewClientSet()
+			if err := clients.CreateConsulClient(&CreateConsulClientInput{
+				Address: strings.TrimPrefix(s.URL, "http://"),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewOperatorLicenseQuery("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if tc.exp != nil {
+				assert.Equal(t, tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestOperatorLicenseQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"empty",
+			"",
+			"operator.license",
+		},
+		{
+			"datacenter",
+			"@dc1",
+			"operator.license(@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewOperatorLicenseQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
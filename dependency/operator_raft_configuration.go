@@ -0,0 +1,137 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*OperatorRaftConfigurationQuery)(nil)
+
+	// OperatorRaftConfigurationQueryRe is the regular expression to use.
+	OperatorRaftConfigurationQueryRe = regexp.MustCompile(`\A` + dcRe + `\z`)
+
+	// OperatorRaftConfigurationQuerySleepTime is the amount of time to sleep
+	// between queries, since the endpoint does not support blocking queries.
+	OperatorRaftConfigurationQuerySleepTime = 15 * time.Second
+)
+
+func init() {
+	gob.Register([]*RaftServer{})
+}
+
+// RaftServer is a server in the Raft configuration of a Consul datacenter.
+type RaftServer struct {
+	ID      string
+	Node    string
+	Address string
+	Leader  bool
+	Voter   bool
+}
+
+// OperatorRaftConfigurationQuery is the dependency to query the Raft
+// configuration of the Consul servers.
+type OperatorRaftConfigurationQuery struct {
+	stopCh chan struct{}
+
+	dc string
+}
+
+// NewOperatorRaftConfigurationQuery parses the given string into a dependency.
+// If no datacenter is given, the datacenter of the local agent is used.
+func NewOperatorRaftConfigurationQuery(s string) (*OperatorRaftConfigurationQuery, error) {
+	if !OperatorRaftConfigurationQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("operator.raft: invalid format: %q", s)
+	}
+
+	m := regexpMatch(OperatorRaftConfigurationQueryRe, s)
+	return &OperatorRaftConfigurationQuery{
+		dc:     m["dc"],
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns a slice
+// of RaftServer objects, sorted by node name.
+func (d *OperatorRaftConfigurationQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/operator/raft/configuration",
+		RawQuery: opts.String(),
+	})
+
+	// The Raft configuration endpoint does not support blocking queries, so
+	// sleep between queries once we have returned data.
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, OperatorRaftConfigurationQuerySleepTime)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(OperatorRaftConfigurationQuerySleepTime):
+		}
+	}
+
+	conf, err := clients.Consul().Operator().RaftGetConfiguration(opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(conf.Servers))
+
+	servers := make([]*RaftServer, 0, len(conf.Servers))
+	for _, s := range conf.Servers {
+		servers = append(servers, &RaftServer{
+			ID:      s.ID,
+			Node:    s.Node,
+			Address: s.Address,
+			Leader:  s.Leader,
+			Voter:   s.Voter,
+		})
+	}
+	sort.Stable(ByRaftServerNode(servers))
+
+	return respWithMetadata(servers)
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *OperatorRaftConfigurationQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *OperatorRaftConfigurationQuery) String() string {
+	if d.dc != "" {
+		return fmt.Sprintf("operator.raft(@%s)", d.dc)
+	}
+	return "operator.raft"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *OperatorRaftConfigurationQuery) Stop() {
+	close(d.stopCh)
+}
+
+// ByRaftServerNode is a sortable slice of RaftServer.
+type ByRaftServerNode []*RaftServer
+
+// Len, Swap, and Less are used to implement the sort.Sort interface.
+func (s ByRaftServerNode) Len() int      { return len(s) }
+func (s ByRaftServerNode) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByRaftServerNode) Less(i, j int) bool {
+	if s[i].Node == s[j].Node {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].Node < s[j].Node
+}
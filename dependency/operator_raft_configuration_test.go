@@ -0,0 +1,116 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	OperatorRaftConfigurationQuerySleepTime = 50 * time.Millisecond
+}
+
+func TestNewOperatorRaftConfigurationQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *OperatorRaftConfigurationQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&OperatorRaftConfigurationQuery{},
+			false,
+		},
+		{
+			"dc",
+			"@dc1",
+			&OperatorRaftConfigurationQuery{
+				dc: "dc1",
+			},
+			false,
+		},
+		{
+			"name",
+			"server1",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewOperatorRaftConfigurationQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestOperatorRaftConfigurationQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	clients, consul := testConsulServer(t)
+	defer consul.Stop()
+
+	d, err := NewOperatorRaftConfigurationQuery("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, _, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := act.([]*RaftServer)
+	if len(servers) != 1 {
+		t.Fatalf("expected 1 server, got %d", len(servers))
+	}
+	assert.Equal(t, consul.Config.NodeName, servers[0].Node)
+	assert.True(t, servers[0].Leader)
+	assert.True(t, servers[0].Voter)
+}
+
+func TestOperatorRaftConfigurationQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"empty",
+			"",
+			"operator.raft",
+		},
+		{
+			"datacenter",
+			"@dc1",
+			"operator.raft(@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewOperatorRaftConfigurationQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*OperatorSegmentsQuery)(nil)
+
+	// OperatorSegmentsQueryRe is the regular expression to use.
+	OperatorSegmentsQueryRe = regexp.MustCompile(`\A` + dcRe + `\z`)
+
+	// OperatorSegmentsQuerySleepTime is the amount of time to sleep between
+	// queries, since the endpoint does not support blocking queries.
+	OperatorSegmentsQuerySleepTime = 1 * time.Minute
+)
+
+// OperatorSegmentsQuery is the dependency to query the network segments of a
+// Consul Enterprise datacenter.
+type OperatorSegmentsQuery struct {
+	stopCh chan struct{}
+
+	dc string
+}
+
+// NewOperatorSegmentsQuery parses the given string into a dependency. If no
+// datacenter is given, the datacenter of the local agent is used.
+func NewOperatorSegmentsQuery(s string) (*OperatorSegmentsQuery, error) {
+	if !OperatorSegmentsQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("operator.segments: invalid format: %q", s)
+	}
+
+	m := regexpMatch(OperatorSegmentsQueryRe, s)
+	return &OperatorSegmentsQuery{
+		dc:     m["dc"],
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// sorted names of the network segments. The default segment is named "".
+func (d *OperatorSegmentsQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	params := url.Values{}
+	if opts.Datacenter != "" {
+		params.Set("dc", opts.Datacenter)
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/operator/segment",
+		RawQuery: params.Encode(),
+	})
+
+	// The segment endpoint does not support blocking queries, so sleep between
+	// queries once we have returned data.
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: long polling for %s", d, OperatorSegmentsQuerySleepTime)
+
+		select {
+		case <-d.stopCh:
+			return nil, nil, ErrStopped
+		case <-time.After(OperatorSegmentsQuerySleepTime):
+		}
+	}
+
+	var segments []string
+	if _, err := clients.consulQuery("/v1/operator/segment", &QueryOptions{
+		Datacenter: opts.Datacenter,
+	}, nil, &segments); err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(segments))
+
+	sort.Strings(segments)
+
+	return respWithMetadata(segments)
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *OperatorSegmentsQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *OperatorSegmentsQuery) String() string {
+	if d.dc != "" {
+		return fmt.Sprintf("operator.segments(@%s)", d.dc)
+	}
+	return "operator.segments"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *OperatorSegmentsQuery) Stop() {
+	close(d.stopCh)
+}
@@ -0,0 +1,154 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	OperatorSegmentsQuerySleepTime = 50 * time.Millisecond
+}
+
+func TestNewOperatorSegmentsQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *OperatorSegmentsQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&OperatorSegmentsQuery{},
+			false,
+		},
+		{
+			"dc",
+			"@dc1",
+			&OperatorSegmentsQuery{
+				dc: "dc1",
+			},
+			false,
+		},
+		{
+			"name",
+			"alpha",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewOperatorSegmentsQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestOperatorSegmentsQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		code int
+		body string
+		exp  []string
+		err  bool
+	}{
+		{
+			"segments",
+			http.StatusOK,
+			`["beta", "", "alpha"]`,
+			[]string{"", "alpha", "beta"},
+			false,
+		},
+		{
+			"not_enterprise",
+			http.StatusNotFound,
+			``,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/operator/segment", r.URL.Path)
+				assert.Equal(t, "dc1", r.URL.Query().Get("dc"))
+				w.WriteHeader(tc.code)
+				w.Write([]byte(tc.body))
+			}))
+			defer s.Close()
+
+			clients := NewClientSet()
+			if err := clients.CreateConsulClient(&CreateConsulClientInput{
+				Address: strings.TrimPrefix(s.URL, "http://"),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewOperatorSegmentsQuery("@dc1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(clients, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if tc.exp != nil {
+				assert.Equal(t, tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestOperatorSegmentsQuery_String(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"empty",
+			"",
+			"operator.segments",
+		},
+		{
+			"datacenter",
+			"@dc1",
+			"operator.segments(@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewOperatorSegmentsQuery(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
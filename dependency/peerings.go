@@ -0,0 +1,160 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*PeeringsQuery)(nil)
+
+	// PeeringsQueryRe is the regular expression to use.
+	PeeringsQueryRe = regexp.MustCompile(`\A\z`)
+)
+
+func init() {
+	gob.Register([]*Peering{})
+}
+
+// Peering is a cluster peering of the local Consul cluster.
+type Peering struct {
+	ID        string
+	Name      string
+	Partition string
+	Meta      map[string]string
+
+	// State is the state of the peering, such as "ACTIVE" or "FAILING".
+	State string
+
+	PeerID              string
+	PeerServerName      string
+	PeerServerAddresses []string
+
+	// ImportedServices and ExportedServices are the sorted names of the
+	// services imported from and exported to the peer.
+	ImportedServices []string
+	ExportedServices []string
+
+	// RemoteDatacenter and RemotePartition are the location of the peer.
+	RemoteDatacenter string
+	RemotePartition  string
+}
+
+// peeringResponse is an entry of the response of the peerings endpoint.
+type peeringResponse struct {
+	ID                  string
+	Name                string
+	Partition           string
+	Meta                map[string]string
+	State               string
+	PeerID              string
+	PeerServerName      string
+	PeerServerAddresses []string
+	StreamStatus        struct {
+		ImportedServices []string
+		ExportedServices []string
+	}
+	Remote struct {
+		Datacenter string
+		Partition  string
+	}
+}
+
+// PeeringsQuery is the dependency to query the cluster peerings of the local
+// Consul cluster.
+type PeeringsQuery struct {
+	stopCh chan struct{}
+}
+
+// NewPeeringsQuery parses the given string into a dependency.
+func NewPeeringsQuery(s string) (*PeeringsQuery, error) {
+	if !PeeringsQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("peerings: invalid format: %q", s)
+	}
+
+	return &PeeringsQuery{
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client and returns the
+// peerings sorted by name.
+func (d *PeeringsQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{})
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/peerings",
+		RawQuery: opts.String(),
+	})
+
+	// The API client does not support peerings, so query them directly.
+	var entries []*peeringResponse
+	rm, err := clients.consulQuery("/v1/peerings", opts, nil, &entries)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	log.Printf("[TRACE] %s: returned %d results", d, len(entries))
+
+	list := make([]*Peering, 0, len(entries))
+	for _, entry := range entries {
+		imported := append([]string{}, entry.StreamStatus.ImportedServices...)
+		sort.Strings(imported)
+		exported := append([]string{}, entry.StreamStatus.ExportedServices...)
+		sort.Strings(exported)
+
+		list = append(list, &Peering{
+			ID:                  entry.ID,
+			Name:                entry.Name,
+			Partition:           entry.Partition,
+			Meta:                entry.Meta,
+			State:               entry.State,
+			PeerID:              entry.PeerID,
+			PeerServerName:      entry.PeerServerName,
+			PeerServerAddresses: append([]string{}, entry.PeerServerAddresses...),
+			ImportedServices:    imported,
+			ExportedServices:    exported,
+			RemoteDatacenter:    entry.Remote.Datacenter,
+			RemotePartition:     entry.Remote.Partition,
+		})
+	}
+
+	sort.Sort(ByPeeringName(list))
+
+	return list, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *PeeringsQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *PeeringsQuery) String() string {
+	return "peerings"
+}
+
+// Stop halts the dependency's fetch function.
+func (d *PeeringsQuery) Stop() {
+	close(d.stopCh)
+}
+
+// ByPeeringName is a sortable slice of Peering structs.
+type ByPeeringName []*Peering
+
+func (s ByPeeringName) Len() int           { return len(s) }
+func (s ByPeeringName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s ByPeeringName) Less(i, j int) bool { return s[i].Name < s[j].Name }
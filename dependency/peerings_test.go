@@ -0,0 +1,156 @@
+package dependency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPeeringsQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *PeeringsQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			&PeeringsQuery{},
+			false,
+		},
+		{
+			"name",
+			"partner",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewPeeringsQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestPeeringsQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		code int
+		body string
+		exp  []*Peering
+		err  bool
+	}{
+		{
+			"peerings",
+			http.StatusOK,
+			`[{
+				"ID": "2",
+				"Name": "west",
+				"State": "PENDING",
+				"Remote": {"Datacenter": "dc3"}
+			}, {
+				"ID": "1",
+				"Name": "east",
+				"State": "ACTIVE",
+				"PeerServerAddresses": ["10.0.0.1:8502"],
+				"StreamStatus": {
+					"ImportedServices": ["web", "db"],
+					"ExportedServices": ["api"]
+				},
+				"Remote": {"Datacenter": "dc2", "Partition": "default"}
+			}]`,
+			[]*Peering{
+				&Peering{
+					ID:                  "1",
+					Name:                "east",
+					State:               "ACTIVE",
+					PeerServerAddresses: []string{"10.0.0.1:8502"},
+					ImportedServices:    []string{"db", "web"},
+					ExportedServices:    []string{"api"},
+					RemoteDatacenter:    "dc2",
+					RemotePartition:     "default",
+				},
+				&Peering{
+					ID:                  "2",
+					Name:                "west",
+					State:               "PENDING",
+					PeerServerAddresses: []string{},
+					ImportedServices:    []string{},
+					ExportedServices:    []string{},
+					RemoteDatacenter:    "dc3",
+				},
+			},
+			false,
+		},
+		{
+			"not_supported",
+			http.StatusNotFound,
+			``,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/peerings", r.URL.Path)
+				w.Header().Set("X-Consul-Index", "3")
+				w.WriteHeader(tc.code)
+				w.Write([]byte(tc.body))
+			}))
+			defer s.Close()
+
+			clients := NewClientSet()
+			if err := clients.CreateConsulClient(&CreateConsulClientInput{
+				Address: strings.TrimPrefix(s.URL, "http://"),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := NewPeeringsQuery("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, rm, err := d.Fetch(clients, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if tc.exp != nil {
+				assert.Equal(t, tc.exp, act)
+				assert.Equal(t, uint64(3), rm.LastIndex)
+			}
+		})
+	}
+}
+
+func TestPeeringsQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewPeeringsQuery("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "peerings", d.String())
+}
@@ -0,0 +1,138 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultResolverTTL and defaultResolverTimeout are used by the resolver of
+	// a client set which was not given one.
+	defaultResolverTTL     = 30 * time.Second
+	defaultResolverTimeout = 5 * time.Second
+)
+
+// Resolver resolves host names to IP addresses for the dependencies which are
+// asked to resolve the addresses they return. Results are cached for the TTL,
+// since the system resolver does not expose the TTL of the records it returns.
+// Failed lookups are cached as well, so an unresolvable name does not cause a
+// lookup on every fetch.
+type Resolver struct {
+	ttl     time.Duration
+	timeout time.Duration
+
+	// lookup and now can be replaced in tests.
+	lookup func(host string) ([]string, error)
+	now    func() time.Time
+
+	sync.Mutex
+	cache map[string]*resolverEntry
+}
+
+// resolverEntry is a cached lookup.
+type resolverEntry struct {
+	ips     []string
+	err     error
+	expires time.Time
+}
+
+// NewResolver creates a new resolver which caches results for the given TTL
+// and gives up on lookups after the given timeout.
+func NewResolver(ttl, timeout time.Duration) *Resolver {
+	return &Resolver{
+		ttl:     ttl,
+		timeout: timeout,
+		lookup:  net.LookupHost,
+		now:     time.Now,
+		cache:   make(map[string]*resolverEntry),
+	}
+}
+
+// TTL returns the amount of time results are cached.
+func (r *Resolver) TTL() time.Duration {
+	return r.ttl
+}
+
+// Resolve returns the sorted IP addresses of the given host. An IP address is
+// returned as is, and an empty host resolves to no addresses.
+func (r *Resolver) Resolve(host string) ([]string, error) {
+	if host == "" {
+		return nil, nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	now := r.now()
+	if entry, ok := r.cache[host]; ok && now.Before(entry.expires) {
+		return entry.ips, entry.err
+	}
+
+	log.Printf("[TRACE] (resolver) looking up %q", host)
+	ips, err := r.lookupHost(host)
+	if err == nil {
+		sort.Strings(ips)
+	}
+
+	r.cache[host] = &resolverEntry{
+		ips:     ips,
+		err:     err,
+		expires: now.Add(r.ttl),
+	}
+	return ips, err
+}
+
+// lookupHost looks up the given host, giving up after the timeout. A lookup
+// which times out is left to finish in the background, since the system
+// resolver cannot be cancelled.
+func (r *Resolver) lookupHost(host string) ([]string, error) {
+	type lookupResult struct {
+		ips []string
+		err error
+	}
+
+	resultCh := make(chan lookupResult, 1)
+	go func() {
+		ips, err := r.lookup(host)
+		resultCh <- lookupResult{ips, err}
+	}()
+
+	select {
+	case result := <-resultCh:
+		return result.ips, result.err
+	case <-time.After(r.timeout):
+		return nil, fmt.Errorf("lookup %s: timed out after %s", host, r.timeout)
+	}
+}
+
+// resolveAddresses resolves each of the given addresses, logging the lookups
+// which fail. The result of a failed lookup is an empty list, so templates can
+// fall back to the host name.
+func resolveAddresses(r *Resolver, addresses ...string) [][]string {
+	result := make([][]string, len(addresses))
+	for i, address := range addresses {
+		ips, err := r.Resolve(address)
+		if err != nil {
+			log.Printf("[WARN] (resolver) failed to resolve %q: %s", address, err)
+		}
+		result[i] = append([]string{}, ips...)
+	}
+	return result
+}
+
+// resolveWaitTime returns the wait time of a blocking query which resolves
+// addresses, which is capped at the TTL of the resolver so the addresses are
+// resolved again once they expire.
+func resolveWaitTime(r *Resolver, wait time.Duration) time.Duration {
+	if ttl := r.TTL(); ttl > 0 && (wait == 0 || ttl < wait) {
+		return ttl
+	}
+	return wait
+}
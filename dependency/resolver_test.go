@@ -0,0 +1,87 @@
+package dependency
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testResolver returns a resolver whose lookups are answered from the given
+// map and counted in lookups.
+func testResolver(hosts map[string][]string, lookups map[string]int) *Resolver {
+	r := NewResolver(10*time.Second, time.Second)
+	r.lookup = func(host string) ([]string, error) {
+		lookups[host]++
+		if ips, ok := hosts[host]; ok {
+			return append([]string{}, ips...), nil
+		}
+		return nil, errors.New("no such host")
+	}
+	return r
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	t.Parallel()
+
+	hosts := map[string][]string{
+		"web.example": []string{"10.0.0.2", "10.0.0.1"},
+	}
+	lookups := make(map[string]int)
+	r := testResolver(hosts, lookups)
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	ips, err := r.Resolve("web.example")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, ips)
+
+	// Addresses which are already IPs are not looked up.
+	ips, err = r.Resolve("10.0.0.9")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.9"}, ips)
+
+	// Failed lookups are cached too.
+	_, err = r.Resolve("missing.example")
+	assert.Error(t, err)
+	_, err = r.Resolve("missing.example")
+	assert.Error(t, err)
+
+	hosts["web.example"] = []string{"10.0.0.3"}
+	ips, _ = r.Resolve("web.example")
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, ips)
+
+	// Once the TTL expired, the host is looked up again.
+	now = now.Add(11 * time.Second)
+	ips, _ = r.Resolve("web.example")
+	assert.Equal(t, []string{"10.0.0.3"}, ips)
+
+	assert.Equal(t, map[string]int{"web.example": 2, "missing.example": 1}, lookups)
+}
+
+func TestResolver_Resolve_timeout(t *testing.T) {
+	t.Parallel()
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	r := NewResolver(10*time.Second, 10*time.Millisecond)
+	r.lookup = func(host string) ([]string, error) {
+		<-doneCh
+		return []string{"10.0.0.1"}, nil
+	}
+
+	_, err := r.Resolve("slow.example")
+	assert.EqualError(t, err, "lookup slow.example: timed out after 10ms")
+}
+
+func TestResolveWaitTime(t *testing.T) {
+	t.Parallel()
+
+	r := NewResolver(30*time.Second, time.Second)
+	assert.Equal(t, 30*time.Second, resolveWaitTime(r, 0))
+	assert.Equal(t, 30*time.Second, resolveWaitTime(r, 5*time.Minute))
+	assert.Equal(t, 10*time.Second, resolveWaitTime(r, 10*time.Second))
+}
@@ -12,6 +12,9 @@ type Set struct {
 	sync.RWMutex
 	list []string
 	set  map[string]Dependency
+
+	// last is the dependency most recently given to Add.
+	last string
 }
 
 // Add adds a new element to the set if it does not already exist.
@@ -19,6 +22,7 @@ func (s *Set) Add(d Dependency) bool {
 	s.init()
 	s.Lock()
 	defer s.Unlock()
+	s.last = d.String()
 	if _, ok := s.set[d.String()]; !ok {
 		s.list = append(s.list, d.String())
 		s.set[d.String()] = d
@@ -27,6 +31,34 @@ func (s *Set) Add(d Dependency) bool {
 	return false
 }
 
+// Remove removes an element from the set, if it exists.
+func (s *Set) Remove(d Dependency) bool {
+	s.init()
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.set[d.String()]; !ok {
+		return false
+	}
+
+	delete(s.set, d.String())
+	for i, k := range s.list {
+		if k == d.String() {
+			s.list = append(s.list[:i], s.list[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Last returns the dependency most recently given to Add, even if it was
+// already in the set, or nil if Add has not been called.
+func (s *Set) Last() Dependency {
+	s.init()
+	s.RLock()
+	defer s.RUnlock()
+	return s.set[s.last]
+}
+
 // Get retrieves a single element from the set by name.
 func (s *Set) Get(v string) Dependency {
 	s.RLock()
@@ -0,0 +1,226 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// vaultCapabilityDependency is a Vault dependency whose token needs a
+// capability on a path. Any one of the returned capabilities is enough.
+type vaultCapabilityDependency interface {
+	Dependency
+	vaultCapabilities(clients *ClientSet) (path string, capabilities []string, err error)
+}
+
+// VaultCapabilityChecker checks that the Vault token has the capabilities the
+// Vault dependencies need before they are fetched. The capabilities of each
+// path are looked up once per token and cached, so a path shared by many
+// dependencies or templates costs a single lookup.
+type VaultCapabilityChecker struct {
+	sync.Mutex
+
+	token string
+	cache map[string][]string
+}
+
+// NewVaultCapabilityChecker returns a new VaultCapabilityChecker.
+func NewVaultCapabilityChecker() *VaultCapabilityChecker {
+	return &VaultCapabilityChecker{
+		cache: make(map[string][]string),
+	}
+}
+
+// VaultMissingCapability is a capability a Vault dependency needs but the
+// token does not have.
+type VaultMissingCapability struct {
+	// Dependency is the dependency which needs the capability.
+	Dependency string
+
+	// Path is the path it needs the capability on.
+	Path string
+
+	// Capabilities are the capabilities of which it needs any one, and
+	// Granted are the ones the token has.
+	Capabilities []string
+	Granted      []string
+}
+
+// VaultCapabilityError is the error returned by Check when the token is
+// missing capabilities. It reports all of them at once.
+type VaultCapabilityError struct {
+	Missing []*VaultMissingCapability
+}
+
+// Error implements the error interface.
+func (e *VaultCapabilityError) Error() string {
+	lines := make([]string, 0, len(e.Missing)+1)
+	lines = append(lines, fmt.Sprintf("vault token is missing %d capabilities:", len(e.Missing)))
+	for _, m := range e.Missing {
+		granted := "none"
+		if len(m.Granted) > 0 {
+			granted = strings.Join(m.Granted, ", ")
+		}
+		lines = append(lines, fmt.Sprintf("  %s needs %s on %q (granted: %s)",
+			m.Dependency, strings.Join(m.Capabilities, " or "), m.Path, granted))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check checks that the token has the capabilities the given dependencies
+// need, ignoring the dependencies which are not Vault dependencies. It returns
+// a *VaultCapabilityError listing every missing capability. The capabilities
+// of the paths which are not cached are looked up in a single request.
+func (c *VaultCapabilityChecker) Check(clients *ClientSet, deps []Dependency) error {
+	type need struct {
+		d            Dependency
+		path         string
+		capabilities []string
+	}
+
+	var needs []*need
+	for _, d := range deps {
+		vd, ok := d.(vaultCapabilityDependency)
+		if !ok {
+			continue
+		}
+		path, capabilities, err := vd.vaultCapabilities(clients)
+		if err != nil {
+			return errors.Wrap(err, d.String())
+		}
+		needs = append(needs, &need{d: d, path: path, capabilities: capabilities})
+	}
+	if len(needs) == 0 {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// The cache belongs to a token, which may be renewed by a new login.
+	if token := clients.Vault().Token(); token != c.token {
+		c.token = token
+		c.cache = make(map[string][]string)
+	}
+
+	var paths []string
+	for _, n := range needs {
+		if _, ok := c.cache[n.path]; !ok {
+			paths = appendPath(paths, n.path)
+		}
+	}
+	if len(paths) > 0 {
+		granted, err := c.lookup(clients, paths)
+		if err != nil {
+			return err
+		}
+		for path, capabilities := range granted {
+			c.cache[path] = capabilities
+		}
+	}
+
+	var missing []*VaultMissingCapability
+	for _, n := range needs {
+		granted := c.cache[n.path]
+		if !hasVaultCapability(granted, n.capabilities) {
+			missing = append(missing, &VaultMissingCapability{
+				Dependency:   n.d.String(),
+				Path:         n.path,
+				Capabilities: n.capabilities,
+				Granted:      granted,
+			})
+		}
+	}
+	if len(missing) > 0 {
+		sort.Sort(ByVaultMissingDependency(missing))
+		return &VaultCapabilityError{Missing: missing}
+	}
+	return nil
+}
+
+// lookup returns the capabilities of the token on each of the given paths.
+func (c *VaultCapabilityChecker) lookup(clients *ClientSet, paths []string) (map[string][]string, error) {
+	log.Printf("[TRACE] vault.capabilities: POST %s", &url.URL{
+		Path: "/v1/sys/capabilities-self",
+	})
+
+	secret, err := clients.Vault().Logical().Write("sys/capabilities-self",
+		map[string]interface{}{"paths": paths})
+	if err != nil {
+		return nil, errors.Wrap(err, "vault.capabilities")
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("vault.capabilities: no capabilities returned")
+	}
+	return parseVaultCapabilities(secret.Data, paths), nil
+}
+
+// parseVaultCapabilities parses the response of sys/capabilities-self for the
+// given paths. The capabilities are keyed by path, and older servers, which
+// only take a single path, return them under "capabilities".
+func parseVaultCapabilities(data map[string]interface{}, paths []string) map[string][]string {
+	r := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		raw, ok := data[path]
+		if !ok && len(paths) == 1 {
+			raw = data["capabilities"]
+		}
+		list, _ := raw.([]interface{})
+		capabilities := make([]string, 0, len(list))
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				capabilities = append(capabilities, s)
+			}
+		}
+		r[path] = capabilities
+	}
+	return r
+}
+
+// hasVaultCapability returns true if the granted capabilities include any of
+// the needed ones. The root capability grants everything and deny overrides
+// everything.
+func hasVaultCapability(granted, needed []string) bool {
+	for _, g := range granted {
+		if g == "deny" {
+			return false
+		}
+	}
+	for _, g := range granted {
+		if g == "root" {
+			return true
+		}
+		for _, n := range needed {
+			if g == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appendPath appends the given path to the paths if it is not already in
+// them.
+func appendPath(paths []string, path string) []string {
+	for _, p := range paths {
+		if p == path {
+			return paths
+		}
+	}
+	return append(paths, path)
+}
+
+// ByVaultMissingDependency is a sortable slice of VaultMissingCapability
+// structs.
+type ByVaultMissingDependency []*VaultMissingCapability
+
+func (s ByVaultMissingDependency) Len() int      { return len(s) }
+func (s ByVaultMissingDependency) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByVaultMissingDependency) Less(i, j int) bool {
+	return s[i].Dependency < s[j].Dependency
+}
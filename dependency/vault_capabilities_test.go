@@ -0,0 +1,107 @@
+package dependency
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseVaultCapabilities(t *testing.T) {
+	cases := []struct {
+		name  string
+		data  map[string]interface{}
+		paths []string
+		exp   map[string][]string
+	}{
+		{
+			"by_path",
+			map[string]interface{}{
+				"secret/foo":   []interface{}{"read", "list"},
+				"secret/bar":   []interface{}{"deny"},
+				"capabilities": []interface{}{"deny"},
+			},
+			[]string{"secret/foo", "secret/bar"},
+			map[string][]string{
+				"secret/foo": {"read", "list"},
+				"secret/bar": {"deny"},
+			},
+		},
+		{
+			"single_path",
+			map[string]interface{}{
+				"capabilities": []interface{}{"update"},
+			},
+			[]string{"pki/issue/web"},
+			map[string][]string{
+				"pki/issue/web": {"update"},
+			},
+		},
+		{
+			"missing",
+			map[string]interface{}{},
+			[]string{"secret/foo", "secret/bar"},
+			map[string][]string{
+				"secret/foo": {},
+				"secret/bar": {},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := parseVaultCapabilities(tc.data, tc.paths)
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestHasVaultCapability(t *testing.T) {
+	cases := []struct {
+		name    string
+		granted []string
+		needed  []string
+		exp     bool
+	}{
+		{"granted", []string{"read", "list"}, []string{"read"}, true},
+		{"any", []string{"update"}, []string{"create", "update"}, true},
+		{"missing", []string{"read"}, []string{"update"}, false},
+		{"none", nil, []string{"read"}, false},
+		{"root", []string{"root"}, []string{"update"}, true},
+		{"deny", []string{"deny"}, []string{"read"}, false},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := hasVaultCapability(tc.granted, tc.needed); act != tc.exp {
+				t.Errorf("expected %t, got %t", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestVaultCapabilityError_Error(t *testing.T) {
+	err := &VaultCapabilityError{
+		Missing: []*VaultMissingCapability{
+			{
+				Dependency:   "vault.read(secret/foo)",
+				Path:         "secret/foo",
+				Capabilities: []string{"read"},
+			},
+			{
+				Dependency:   "vault.write(secret/bar -> da39a3ee)",
+				Path:         "secret/bar",
+				Capabilities: []string{"create", "update"},
+				Granted:      []string{"read"},
+			},
+		},
+	}
+
+	exp := "vault token is missing 2 capabilities:\n" +
+		`  vault.read(secret/foo) needs read on "secret/foo" (granted: none)` + "\n" +
+		`  vault.write(secret/bar -> da39a3ee) needs create or update on "secret/bar" (granted: read)`
+	if act := err.Error(); act != exp {
+		t.Errorf("\nexp: %s\nact: %s", exp, act)
+	}
+}
@@ -1,5 +1,11 @@
 package dependency
 
+import (
+	"log"
+	"net/url"
+	"strings"
+)
+
 var (
 	// VaultDefaultLeaseDuration is the default lease duration in seconds.
 	VaultDefaultLeaseDuration = 5 * 60
@@ -24,3 +30,60 @@ func leaseDurationOrDefault(d int) int {
 	}
 	return d
 }
+
+// vaultKVMount returns the mount path backing the given secret path and
+// whether that mount is a version 2 KV secrets engine. Vault servers that
+// predate the sys/internal/ui/mounts endpoint, or tokens that cannot query it,
+// are treated as version 1 so existing templates keep working.
+func vaultKVMount(clients *ClientSet, path string) (string, bool, error) {
+	log.Printf("[TRACE] vault.kv: GET %s", &url.URL{
+		Path: "/v1/sys/internal/ui/mounts/" + path,
+	})
+
+	secret, err := clients.Vault().Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		log.Printf("[WARN] vault.kv: failed to detect mount for %q, "+
+			"assuming version 1: %s", path, err)
+		return "", false, nil
+	}
+	if secret == nil || secret.Data == nil {
+		return "", false, nil
+	}
+
+	mount, _ := secret.Data["path"].(string)
+	if mount == "" {
+		return "", false, nil
+	}
+
+	options, _ := secret.Data["options"].(map[string]interface{})
+	if options == nil {
+		return mount, false, nil
+	}
+
+	version, _ := options["version"].(string)
+	return mount, version == "2", nil
+}
+
+// vaultKVPath rewrites the given path so it is addressed beneath the given
+// API prefix ("data" or "metadata") of a version 2 KV mount. Paths which
+// already include the prefix are returned unchanged.
+func vaultKVPath(path, mount, prefix string) string {
+	mount = strings.Trim(mount, "/")
+	if mount == "" {
+		return path
+	}
+
+	if path != mount && !strings.HasPrefix(path, mount+"/") {
+		return path
+	}
+	rel := strings.Trim(strings.TrimPrefix(path, mount), "/")
+
+	if rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+		return path
+	}
+
+	if rel == "" {
+		return mount + "/" + prefix
+	}
+	return mount + "/" + prefix + "/" + rel
+}
@@ -1,5 +1,81 @@
 package dependency
 
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
 func init() {
 	VaultDefaultLeaseDuration = 0
 }
+
+func TestVaultKVPath(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		path   string
+		mount  string
+		prefix string
+		exp    string
+	}{
+		{
+			"data",
+			"secret/foo/bar",
+			"secret/",
+			"data",
+			"secret/data/foo/bar",
+		},
+		{
+			"metadata",
+			"secret/foo",
+			"secret/",
+			"metadata",
+			"secret/metadata/foo",
+		},
+		{
+			"mount_root",
+			"secret",
+			"secret/",
+			"metadata",
+			"secret/metadata",
+		},
+		{
+			"nested_mount",
+			"team/kv/foo",
+			"team/kv/",
+			"data",
+			"team/kv/data/foo",
+		},
+		{
+			"already_prefixed",
+			"secret/data/foo",
+			"secret/",
+			"data",
+			"secret/data/foo",
+		},
+		{
+			"different_mount",
+			"secretive/foo",
+			"secret/",
+			"data",
+			"secretive/foo",
+		},
+		{
+			"no_mount",
+			"secret/foo",
+			"",
+			"data",
+			"secret/foo",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := vaultKVPath(tc.path, tc.mount, tc.prefix)
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
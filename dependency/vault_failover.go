@@ -0,0 +1,367 @@
+package dependency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// vaultFailoverHost is the host of the address the Vault API client is
+	// created with when it fails over between several addresses. Requests to
+	// it are sent to the current address. It cannot resolve, so a request
+	// which is not sent through the failover transport fails instead of going
+	// to an unexpected server. Requests to other hosts, such as the active node
+	// a standby redirects to, are sent as-is.
+	vaultFailoverHost = "vault-failover.invalid"
+
+	// defaultVaultHealthCheckInterval is the interval at which the addresses
+	// are probed if none is given.
+	defaultVaultHealthCheckInterval = 10 * time.Second
+
+	// vaultHealthCheckTimeout is the maximum amount of time a single probe of
+	// an address may take.
+	vaultHealthCheckTimeout = 5 * time.Second
+)
+
+// These are the states of a Vault server as reported by its health endpoint,
+// from the most to the least preferred.
+const (
+	vaultHealthActive = iota
+	vaultHealthStandby
+	vaultHealthUnusable
+)
+
+// vaultHealth is the part of the response of the health endpoint of Vault
+// which is used to pick an address.
+type vaultHealth struct {
+	ClusterID string `json:"cluster_id"`
+}
+
+// vaultAddress is an address the Vault client may fail over to, with the
+// result of its last probe.
+type vaultAddress struct {
+	url       *url.URL
+	state     int
+	status    string
+	clusterID string
+}
+
+// vaultFailover is a transport which sends the requests of the Vault client to
+// the healthiest of several addresses. The addresses are probed periodically:
+// an active node is preferred to a standby, which forwards or redirects
+// requests to the active node, and an earlier address to a later one in the
+// same state, so requests move back to the first addresses once they recover.
+// Performance standbys are only used if allowed, and disaster recovery
+// secondaries, sealed and uninitialized servers are never used.
+type vaultFailover struct {
+	transport     http.RoundTripper
+	interval      time.Duration
+	perfStandbyOK bool
+
+	// addresses are the configured addresses and current is the index of the
+	// one requests are sent to. They are protected by lock.
+	addresses []*vaultAddress
+	current   int
+	lock      sync.RWMutex
+
+	// probeCh requests a probe before the next interval, after a request
+	// failed.
+	probeCh chan struct{}
+	stopCh  chan struct{}
+	stop    sync.Once
+}
+
+// newVaultFailover creates a transport which fails over between the given
+// addresses, sending requests with the given transport. The addresses are
+// probed once before it returns; probing continues in the background until it
+// is stopped.
+func newVaultFailover(addresses []string, transport http.RoundTripper,
+	interval time.Duration, perfStandbyOK bool) (*vaultFailover, error) {
+	if interval <= 0 {
+		interval = defaultVaultHealthCheckInterval
+	}
+
+	f := &vaultFailover{
+		transport:     transport,
+		interval:      interval,
+		perfStandbyOK: perfStandbyOK,
+		probeCh:       make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+	}
+
+	for _, address := range addresses {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("vault address %q: %s", address, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("vault address %q: missing scheme or host", address)
+		}
+		f.addresses = append(f.addresses, &vaultAddress{
+			url:   u,
+			state: vaultHealthUnusable,
+		})
+	}
+	if len(f.addresses) == 0 {
+		return nil, fmt.Errorf("no vault addresses")
+	}
+
+	f.probe()
+	go f.run()
+	return f, nil
+}
+
+// clientAddress returns the address the Vault API client is created with, so
+// its requests are sent to the current address.
+func (f *vaultFailover) clientAddress() string {
+	return (&url.URL{
+		Scheme: f.addresses[0].url.Scheme,
+		Host:   vaultFailoverHost,
+	}).String()
+}
+
+// Address returns the address requests are currently sent to.
+func (f *vaultFailover) Address() string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.addresses[f.current].url.String()
+}
+
+// RoundTrip implements http.RoundTripper. Requests to the failover host are
+// sent to the current address. If a request without a body cannot be sent,
+// it is retried once on the next usable address.
+func (f *vaultFailover) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != vaultFailoverHost {
+		return f.transport.RoundTrip(req)
+	}
+
+	f.lock.RLock()
+	current := f.current
+	f.lock.RUnlock()
+
+	resp, err := f.transport.RoundTrip(f.rewrite(req, current))
+	if err == nil {
+		return resp, nil
+	}
+
+	next, ok := f.fail(current, err)
+	if !ok || req.Body != nil {
+		return resp, err
+	}
+	return f.transport.RoundTrip(f.rewrite(req, next))
+}
+
+// rewrite returns a copy of the request to the address with the given index.
+func (f *vaultFailover) rewrite(req *http.Request, index int) *http.Request {
+	address := f.addresses[index].url
+
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Scheme = address.Scheme
+	u.Host = address.Host
+	r.URL = &u
+	r.Host = address.Host
+	return r
+}
+
+// fail marks the address with the given index as unusable after a request to
+// it failed, switches to the next usable address, if any, and requests a new
+// probe. It returns the index of the address switched to.
+func (f *vaultFailover) fail(index int, err error) (int, bool) {
+	select {
+	case f.probeCh <- struct{}{}:
+	default:
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	address := f.addresses[index]
+	address.state = vaultHealthUnusable
+	address.status = err.Error()
+
+	if f.current != index {
+		// Another request already switched.
+		return f.current, true
+	}
+	next, ok := f.best()
+	if !ok {
+		return 0, false
+	}
+	f.switchTo(next)
+	return next, true
+}
+
+// run probes the addresses at the configured interval, or when a request
+// failed, until the transport is stopped.
+func (f *vaultFailover) run() {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-f.probeCh:
+		case <-f.stopCh:
+			return
+		}
+		f.probe()
+	}
+}
+
+// probe checks the health of all the addresses and switches to the best one.
+// If none is usable, the current address is kept.
+func (f *vaultFailover) probe() {
+	type result struct {
+		state     int
+		status    string
+		clusterID string
+	}
+	results := make([]result, len(f.addresses))
+
+	var wg sync.WaitGroup
+	for i, address := range f.addresses {
+		wg.Add(1)
+		go func(i int, u *url.URL) {
+			defer wg.Done()
+			state, status, clusterID := f.check(u)
+			results[i] = result{state, status, clusterID}
+		}(i, address.url)
+	}
+	wg.Wait()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for i, address := range f.addresses {
+		r := results[i]
+		if address.state != r.state {
+			log.Printf("[DEBUG] (clients) vault %s is %s", address.url, r.status)
+		}
+		address.state, address.status, address.clusterID = r.state, r.status, r.clusterID
+	}
+
+	next, ok := f.best()
+	if !ok {
+		log.Printf("[WARN] (clients) no healthy vault address, still using %s (%s)",
+			f.addresses[f.current].url, f.addresses[f.current].status)
+		return
+	}
+	if next != f.current {
+		f.switchTo(next)
+	}
+}
+
+// check probes the health endpoint of the given address and returns its state,
+// a description of it and the ID of its cluster, if known.
+func (f *vaultFailover) check(u *url.URL) (int, string, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultHealthCheckTimeout)
+	defer cancel()
+
+	health := *u
+	health.Path = "/v1/sys/health"
+	req, err := http.NewRequest("GET", health.String(), nil)
+	if err != nil {
+		return vaultHealthUnusable, err.Error(), ""
+	}
+
+	resp, err := f.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return vaultHealthUnusable, err.Error(), ""
+	}
+	defer resp.Body.Close()
+
+	var body vaultHealth
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	// These are the status codes of the health endpoint without any of its
+	// options.
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return vaultHealthActive, "active", body.ClusterID
+	case 429:
+		return vaultHealthStandby, "standby", body.ClusterID
+	case 473:
+		if f.perfStandbyOK {
+			return vaultHealthStandby, "performance standby", body.ClusterID
+		}
+		return vaultHealthUnusable, "performance standby (not allowed)", body.ClusterID
+	case 472:
+		return vaultHealthUnusable, "disaster recovery secondary", body.ClusterID
+	case 501:
+		return vaultHealthUnusable, "not initialized", body.ClusterID
+	case 503:
+		return vaultHealthUnusable, "sealed", body.ClusterID
+	default:
+		return vaultHealthUnusable, fmt.Sprintf("unexpected status %d", resp.StatusCode), body.ClusterID
+	}
+}
+
+// best returns the index of the most preferred usable address. It must be
+// called with the lock held.
+func (f *vaultFailover) best() (int, bool) {
+	best := -1
+	for i, address := range f.addresses {
+		if address.state == vaultHealthUnusable {
+			continue
+		}
+		if best == -1 || address.state < f.addresses[best].state {
+			best = i
+		}
+	}
+	return best, best != -1
+}
+
+// switchTo sends the following requests to the address with the given index.
+// It must be called with the lock held.
+func (f *vaultFailover) switchTo(index int) {
+	from, to := f.addresses[f.current], f.addresses[index]
+	log.Printf("[INFO] (clients) vault failing over from %s (%s) to %s (%s)",
+		from.url, from.status, to.url, to.status)
+
+	// Leases are not shared between clusters, so renewing them on the new
+	// cluster fails and the secrets are read again.
+	if from.clusterID != "" && to.clusterID != "" && from.clusterID != to.clusterID {
+		log.Printf("[WARN] (clients) vault %s is in another cluster, secrets will be "+
+			"read again when their leases are renewed", to.url)
+	}
+	f.current = index
+}
+
+// Stop stops probing the addresses.
+func (f *vaultFailover) Stop() {
+	f.stop.Do(func() {
+		close(f.stopCh)
+	})
+}
+
+// vaultAddresses returns the given address followed by the other addresses,
+// without empty or repeated ones.
+func vaultAddresses(address string, others []string) []string {
+	var result []string
+	seen := make(map[string]struct{})
+	for _, a := range append([]string{address}, others...) {
+		if _, ok := seen[a]; ok || a == "" {
+			continue
+		}
+		seen[a] = struct{}{}
+		result = append(result, a)
+	}
+	return result
+}
+
+// stop stops the failover of the client, if any, and closes its idle
+// connections.
+func (v *vaultClient) stop() {
+	if v.failover != nil {
+		v.failover.Stop()
+	}
+	v.transport.CloseIdleConnections()
+}
@@ -0,0 +1,230 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// testVaultNode is a fake Vault server whose health status can be changed.
+type testVaultNode struct {
+	*httptest.Server
+	status  int32
+	handler http.HandlerFunc
+}
+
+func newTestVaultNode(t *testing.T, status int, handler http.HandlerFunc) *testVaultNode {
+	n := &testVaultNode{status: int32(status), handler: handler}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/health" {
+			w.WriteHeader(int(atomic.LoadInt32(&n.status)))
+			fmt.Fprintf(w, `{"cluster_id":"cluster"}`)
+			return
+		}
+		if n.handler != nil {
+			n.handler(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"node":%q}}`, n.URL)
+	}))
+	return n
+}
+
+func (n *testVaultNode) setStatus(status int) {
+	atomic.StoreInt32(&n.status, int32(status))
+}
+
+func TestVaultFailover_probe(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		statuses      []int
+		perfStandbyOK bool
+		exp           int
+	}{
+		{
+			"first_active",
+			[]int{200, 200},
+			false,
+			0,
+		},
+		{
+			"active_over_standby",
+			[]int{429, 200},
+			false,
+			1,
+		},
+		{
+			"standby_over_sealed",
+			[]int{503, 429},
+			false,
+			1,
+		},
+		{
+			"performance_standby_not_allowed",
+			[]int{473, 429},
+			false,
+			1,
+		},
+		{
+			"performance_standby_allowed",
+			[]int{473, 429},
+			true,
+			0,
+		},
+		{
+			"dr_secondary",
+			[]int{472, 501, 429},
+			false,
+			2,
+		},
+		{
+			"none_usable",
+			[]int{503, 501},
+			false,
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var addresses []string
+			for _, status := range tc.statuses {
+				n := newTestVaultNode(t, status, nil)
+				defer n.Close()
+				addresses = append(addresses, n.URL)
+			}
+
+			f, err := newVaultFailover(addresses, cleanhttp.DefaultTransport(),
+				time.Hour, tc.perfStandbyOK)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Stop()
+
+			if a := f.Address(); a != addresses[tc.exp] {
+				t.Errorf("expected %s, got %s", addresses[tc.exp], a)
+			}
+		})
+	}
+}
+
+func TestVaultFailover_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	first := newTestVaultNode(t, 503, nil)
+	defer first.Close()
+	second := newTestVaultNode(t, 200, nil)
+	defer second.Close()
+
+	f, err := newVaultFailover([]string{first.URL, second.URL},
+		cleanhttp.DefaultTransport(), time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	get := func(url string) string {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := f.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var body struct {
+			Data struct {
+				Node string `json:"node"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Data.Node
+	}
+
+	// Requests to the failover host go to the active node.
+	if node := get(f.clientAddress() + "/v1/secret/foo"); node != second.URL {
+		t.Errorf("expected %s, got %s", second.URL, node)
+	}
+
+	// Other requests, such as redirects, are sent as-is.
+	if node := get(first.URL + "/v1/secret/foo"); node != first.URL {
+		t.Errorf("expected %s, got %s", first.URL, node)
+	}
+
+	// Requests move back to the first node once it is active again.
+	first.setStatus(200)
+	f.probe()
+	if node := get(f.clientAddress() + "/v1/secret/foo"); node != first.URL {
+		t.Errorf("expected %s, got %s", first.URL, node)
+	}
+
+	// A request which cannot be sent is retried on the next node.
+	first.Close()
+	if node := get(f.clientAddress() + "/v1/secret/foo"); node != second.URL {
+		t.Errorf("expected %s, got %s", second.URL, node)
+	}
+	if a := f.Address(); a != second.URL {
+		t.Errorf("expected %s, got %s", second.URL, a)
+	}
+}
+
+func TestClientSet_vaultFailover(t *testing.T) {
+	t.Parallel()
+
+	active := newTestVaultNode(t, 200, nil)
+	defer active.Close()
+
+	// The standby redirects requests to the active node, which is not one of
+	// the configured addresses.
+	standby := newTestVaultNode(t, 429, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, active.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+	defer standby.Close()
+
+	sealed := newTestVaultNode(t, 503, nil)
+	defer sealed.Close()
+
+	clients := NewClientSet()
+	if err := clients.CreateVaultClient(&CreateVaultClientInput{
+		Address:             sealed.URL,
+		Addresses:           []string{standby.URL, sealed.URL},
+		HealthCheckInterval: time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer clients.Stop()
+
+	if a := clients.VaultAddress(); a != standby.URL {
+		t.Errorf("expected %s, got %s", standby.URL, a)
+	}
+
+	secret, err := clients.Vault().Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node := secret.Data["node"]; node != active.URL {
+		t.Errorf("expected %s, got %v", active.URL, node)
+	}
+}
+
+func TestVaultAddresses(t *testing.T) {
+	t.Parallel()
+
+	act := vaultAddresses("a", []string{"b", "", "a", "c", "b"})
+	if exp := []string{"a", "b", "c"}; !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
@@ -22,6 +22,10 @@ type VaultListQuery struct {
 
 	path   string
 	secret *Secret
+
+	// listPath is the path actually listed in Vault after any KV version 2
+	// rewriting. It is resolved on the first fetch.
+	listPath string
 }
 
 // NewVaultListQuery creates a new datacenter dependency.
@@ -64,13 +68,17 @@ func (d *VaultListQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 		}
 	}
 
+	if err := d.resolvePath(clients); err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
 	// If we got this far, we either didn't have a secret to renew, the secret was
 	// not renewable, or the renewal failed, so attempt a fresh list.
 	log.Printf("[TRACE] %s: LIST %s", d, &url.URL{
-		Path:     "/v1/" + d.path,
+		Path:     "/v1/" + d.listPath,
 		RawQuery: opts.String(),
 	})
-	secret, err := clients.Vault().Logical().List(d.path)
+	secret, err := clients.Vault().Logical().List(d.listPath)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, d.String())
 	}
@@ -114,6 +122,33 @@ func (d *VaultListQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 	return respWithMetadata(result)
 }
 
+// resolvePath resolves the path to list, if it was not resolved yet. Paths on
+// a version 2 KV mount are listed beneath "metadata/".
+func (d *VaultListQuery) resolvePath(clients *ClientSet) error {
+	if d.listPath != "" {
+		return nil
+	}
+
+	mount, v2, err := vaultKVMount(clients, d.path)
+	if err != nil {
+		return err
+	}
+	d.listPath = d.path
+	if v2 {
+		d.listPath = vaultKVPath(d.path, mount, "metadata")
+	}
+	return nil
+}
+
+// vaultCapabilities returns the path which is listed and the capability
+// listing it needs.
+func (d *VaultListQuery) vaultCapabilities(clients *ClientSet) (string, []string, error) {
+	if err := d.resolvePath(clients); err != nil {
+		return "", nil, err
+	}
+	return d.listPath, []string{"list"}, nil
+}
+
 // CanShare returns if this dependency is shareable.
 func (d *VaultListQuery) CanShare() bool {
 	return false
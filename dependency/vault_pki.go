@@ -0,0 +1,197 @@
+package dependency
+
+import (
+	"crypto/x509"
+	"encoding/gob"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// VaultPKIDefaultRenewFraction is the fraction of the validity of a
+	// certificate after which a new one is issued, when none is given.
+	VaultPKIDefaultRenewFraction = 0.5
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*VaultPKIQuery)(nil)
+)
+
+func init() {
+	gob.Register(&VaultPKI{})
+}
+
+// VaultPKI is a certificate issued by a Vault PKI role.
+type VaultPKI struct {
+	Cert         string
+	Key          string
+	KeyType      string
+	CA           string
+	CAChain      []string
+	SerialNumber string
+	NotBefore    time.Time
+	NotAfter     time.Time
+}
+
+// VaultPKIQuery is the dependency to Vault for a certificate issued by a PKI
+// role. Unlike writing to the issue endpoint with the secret function, a new
+// certificate is only issued once the given fraction of the validity of the
+// current one has passed.
+type VaultPKIQuery struct {
+	stopCh chan struct{}
+
+	path     string
+	data     map[string]interface{}
+	dataHash string
+	fraction float64
+	pki      *VaultPKI
+}
+
+// NewVaultPKIQuery creates a new dependency which issues certificates from the
+// PKI role at the given path, such as "pki/issue/web", with the given data. A
+// fraction outside of (0, 1) is replaced by VaultPKIDefaultRenewFraction.
+func NewVaultPKIQuery(s string, d map[string]interface{}, fraction float64) (*VaultPKIQuery, error) {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil, fmt.Errorf("vault.pki: invalid format: %q", s)
+	}
+
+	if fraction <= 0 || fraction >= 1 {
+		fraction = VaultPKIDefaultRenewFraction
+	}
+
+	return &VaultPKIQuery{
+		stopCh:   make(chan struct{}, 1),
+		path:     s,
+		data:     d,
+		dataHash: sha1Map(d),
+		fraction: fraction,
+	}, nil
+}
+
+// Fetch queries the Vault API
+func (d *VaultPKIQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{})
+
+	// If this is not the first query, sleep until the certificate is due to be
+	// reissued.
+	if opts.WaitIndex != 0 && d.pki != nil {
+		if dur := d.pki.renewAt(d.fraction).Sub(time.Now()); dur > 0 {
+			log.Printf("[TRACE] %s: reissuing in %s", d, dur)
+
+			select {
+			case <-d.stopCh:
+				return nil, nil, ErrStopped
+			case <-time.After(dur):
+			}
+		}
+	}
+
+	log.Printf("[TRACE] %s: PUT %s", d, &url.URL{
+		Path:     "/v1/" + d.path,
+		RawQuery: opts.String(),
+	})
+
+	vaultSecret, err := clients.Vault().Logical().Write(d.path, d.data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+	if vaultSecret == nil {
+		return nil, nil, fmt.Errorf("%s: no certificate returned", d)
+	}
+
+	for _, w := range vaultSecret.Warnings {
+		log.Printf("[WARN] %s: %s", d, w)
+	}
+
+	pki, err := parseVaultPKI(vaultSecret.Data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+	d.pki = pki
+
+	log.Printf("[DEBUG] %s: issued certificate %s, valid until %s",
+		d, pki.SerialNumber, pki.NotAfter.Format(time.RFC3339))
+
+	return respWithMetadata(pki)
+}
+
+// vaultCapabilities returns the path certificates are issued from and the
+// capability issuing them needs.
+func (d *VaultPKIQuery) vaultCapabilities(*ClientSet) (string, []string, error) {
+	return d.path, []string{"update"}, nil
+}
+
+// CanShare returns if this dependency is shareable.
+func (d *VaultPKIQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the given dependency's fetch.
+func (d *VaultPKIQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *VaultPKIQuery) String() string {
+	return fmt.Sprintf("vault.pki(%s -> %s)", d.path, d.dataHash)
+}
+
+// renewAt returns the time at which the given fraction of the validity of
+// this certificate has passed.
+func (p *VaultPKI) renewAt(fraction float64) time.Time {
+	validity := p.NotAfter.Sub(p.NotBefore)
+	return p.NotBefore.Add(time.Duration(float64(validity) * fraction))
+}
+
+// parseVaultPKI parses the data returned by the issue endpoint of a Vault PKI
+// secrets engine. The validity of the certificate is read from the
+// certificate itself.
+func parseVaultPKI(data map[string]interface{}) (*VaultPKI, error) {
+	str := func(k string) string {
+		s, _ := data[k].(string)
+		return s
+	}
+
+	pki := &VaultPKI{
+		Cert:         str("certificate"),
+		Key:          str("private_key"),
+		KeyType:      str("private_key_type"),
+		CA:           str("issuing_ca"),
+		SerialNumber: str("serial_number"),
+	}
+	if chain, ok := data["ca_chain"].([]interface{}); ok {
+		for _, c := range chain {
+			if s, ok := c.(string); ok {
+				pki.CAChain = append(pki.CAChain, s)
+			}
+		}
+	}
+
+	block, _ := pem.Decode([]byte(pki.Cert))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM certificate in response")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse certificate")
+	}
+	pki.NotBefore = cert.NotBefore
+	pki.NotAfter = cert.NotAfter
+
+	return pki, nil
+}
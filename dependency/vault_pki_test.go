@@ -0,0 +1,199 @@
+package dependency
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVaultPKIQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		d    map[string]interface{}
+		f    float64
+		exp  *VaultPKIQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			0,
+			nil,
+			true,
+		},
+		{
+			"path",
+			"/pki/issue/web/",
+			map[string]interface{}{"common_name": "web.example.com"},
+			0.75,
+			&VaultPKIQuery{
+				path:     "pki/issue/web",
+				data:     map[string]interface{}{"common_name": "web.example.com"},
+				dataHash: "4924da34",
+				fraction: 0.75,
+			},
+			false,
+		},
+		{
+			"default_fraction",
+			"pki/issue/web",
+			nil,
+			1.5,
+			&VaultPKIQuery{
+				path:     "pki/issue/web",
+				dataHash: "da39a3ee",
+				fraction: VaultPKIDefaultRenewFraction,
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewVaultPKIQuery(tc.i, tc.d, tc.f)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestVaultPKIQuery_Fetch(t *testing.T) {
+	t.Parallel()
+
+	clients, vault := testVaultServer(t)
+	defer vault.Stop()
+
+	if err := clients.Vault().Sys().Mount("pki", &api.MountInput{
+		Type: "pki",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clients.Vault().Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "example.com",
+		"ttl":         "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clients.Vault().Logical().Write("pki/roles/web", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"max_ttl":          "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewVaultPKIQuery("pki/issue/web", map[string]interface{}{
+		"common_name": "web.example.com",
+		"ttl":         "10m",
+	}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, _, err := d.Fetch(clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pki := act.(*VaultPKI)
+	if pki.Cert == "" || pki.Key == "" || pki.CA == "" {
+		t.Errorf("expected a certificate, key and CA, got %#v", pki)
+	}
+	if !pki.NotAfter.After(pki.NotBefore) {
+		t.Errorf("expected a validity period, got %s to %s", pki.NotBefore, pki.NotAfter)
+	}
+}
+
+func TestVaultPKIQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewVaultPKIQuery("pki/issue/web", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "vault.pki(pki/issue/web -> da39a3ee)", d.String())
+}
+
+func TestVaultPKI_renewAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pki := &VaultPKI{
+		NotBefore: now,
+		NotAfter:  now.Add(72 * time.Hour),
+	}
+
+	assert.Equal(t, now.Add(36*time.Hour), pki.renewAt(0.5))
+	assert.Equal(t, now.Add(54*time.Hour), pki.renewAt(0.75))
+}
+
+func TestParseVaultPKI(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(72 * time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "web.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "web.example.com"},
+	}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	act, err := parseVaultPKI(map[string]interface{}{
+		"certificate":      cert,
+		"private_key":      "key",
+		"private_key_type": "ec",
+		"issuing_ca":       "ca",
+		"ca_chain":         []interface{}{"ca"},
+		"serial_number":    "01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &VaultPKI{
+		Cert:         cert,
+		Key:          "key",
+		KeyType:      "ec",
+		CA:           "ca",
+		CAChain:      []string{"ca"},
+		SerialNumber: "01",
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}, act)
+
+	if _, err := parseVaultPKI(map[string]interface{}{}); err == nil {
+		t.Error("expected an error without a certificate")
+	}
+}
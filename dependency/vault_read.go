@@ -21,6 +21,14 @@ type VaultReadQuery struct {
 
 	path   string
 	secret *Secret
+
+	// kv2 forces the path to be read from a version 2 KV secrets engine
+	// mounted at the first path segment, skipping mount auto-detection.
+	kv2 bool
+
+	// readPath is the path actually read from Vault after any KV version 2
+	// rewriting. It is resolved on the first fetch.
+	readPath string
 }
 
 // NewVaultReadQuery creates a new datacenter dependency.
@@ -37,6 +45,22 @@ func NewVaultReadQuery(s string) (*VaultReadQuery, error) {
 	}, nil
 }
 
+// NewVaultKV2ReadQuery creates a new dependency which reads the given path
+// from a version 2 KV secrets engine mounted at the first path segment.
+func NewVaultKV2ReadQuery(s string) (*VaultReadQuery, error) {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil, fmt.Errorf("vault.kv2: invalid format: %q", s)
+	}
+
+	return &VaultReadQuery{
+		path:   s,
+		kv2:    true,
+		stopCh: make(chan struct{}, 1),
+	}, nil
+}
+
 // Fetch queries the Vault API
 func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
 	select {
@@ -92,13 +116,22 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 		log.Printf("[WARN] %s: failed to renew %s: %s", d, d.secret.LeaseID, err)
 	}
 
+	// Resolve the path to read, accounting for version 2 KV mounts.
+	if d.readPath == "" {
+		readPath, err := d.resolvePath(clients)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+		d.readPath = readPath
+	}
+
 	// If we got this far, we either didn't have a secret to renew, the secret was
 	// not renewable, or the renewal failed, so attempt a fresh read.
 	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
-		Path:     "/v1/" + d.path,
+		Path:     "/v1/" + d.readPath,
 		RawQuery: opts.String(),
 	})
-	vaultSecret, err := clients.Vault().Logical().Read(d.path)
+	vaultSecret, err := clients.Vault().Logical().Read(d.readPath)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, d.String())
 	}
@@ -126,6 +159,41 @@ func (d *VaultReadQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfac
 	return respWithMetadata(secret)
 }
 
+// resolvePath returns the path to read from Vault. Paths on a version 2 KV
+// mount are rewritten to include the "data/" segment.
+func (d *VaultReadQuery) resolvePath(clients *ClientSet) (string, error) {
+	if d.kv2 {
+		mount := strings.SplitN(d.path, "/", 2)[0]
+		return vaultKVPath(d.path, mount, "data"), nil
+	}
+
+	mount, v2, err := vaultKVMount(clients, d.path)
+	if err != nil {
+		return "", err
+	}
+	if !v2 {
+		return d.path, nil
+	}
+
+	readPath := vaultKVPath(d.path, mount, "data")
+	log.Printf("[DEBUG] %s: detected KV version 2 mount %q, reading %s",
+		d, mount, readPath)
+	return readPath, nil
+}
+
+// vaultCapabilities returns the path which is read and the capability reading
+// it needs. The path is resolved here if it was not fetched yet.
+func (d *VaultReadQuery) vaultCapabilities(clients *ClientSet) (string, []string, error) {
+	if d.readPath == "" {
+		readPath, err := d.resolvePath(clients)
+		if err != nil {
+			return "", nil, err
+		}
+		d.readPath = readPath
+	}
+	return d.readPath, []string{"read"}, nil
+}
+
 // CanShare returns if this dependency is shareable.
 func (d *VaultReadQuery) CanShare() bool {
 	return false
@@ -138,5 +206,8 @@ func (d *VaultReadQuery) Stop() {
 
 // String returns the human-friendly version of this dependency.
 func (d *VaultReadQuery) String() string {
+	if d.kv2 {
+		return fmt.Sprintf("vault.kv2(%s)", d.path)
+	}
 	return fmt.Sprintf("vault.read(%s)", d.path)
 }
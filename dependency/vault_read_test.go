@@ -65,6 +65,48 @@ func TestNewVaultReadQuery(t *testing.T) {
 	}
 }
 
+func TestNewVaultKV2ReadQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *VaultReadQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"path",
+			"/secret/foo/",
+			&VaultReadQuery{
+				path: "secret/foo",
+				kv2:  true,
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewVaultKV2ReadQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
 func TestVaultReadQuery_Fetch(t *testing.T) {
 	t.Parallel()
 
@@ -215,3 +257,13 @@ func TestVaultReadQuery_String(t *testing.T) {
 		})
 	}
 }
+
+func TestVaultKV2ReadQuery_String(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewVaultKV2ReadQuery("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "vault.kv2(secret/foo)", d.String())
+}
@@ -134,6 +134,12 @@ func (d *VaultWriteQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interfa
 	return respWithMetadata(secret)
 }
 
+// vaultCapabilities returns the path which is written and the capabilities
+// of which writing it needs one.
+func (d *VaultWriteQuery) vaultCapabilities(*ClientSet) (string, []string, error) {
+	return d.path, []string{"create", "update"}, nil
+}
+
 // CanShare returns if this dependency is shareable.
 func (d *VaultWriteQuery) CanShare() bool {
 	return false
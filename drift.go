@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/manager"
+)
+
+// runCheckDrift renders every template once in memory and compares the result
+// with its destination on disk. Nothing is written to disk, no commands are run
+// and no child process is started. Each destination which is missing or whose
+// contents differ is reported on the output stream, and ExitCodeDriftError is
+// returned if there are any.
+func (cli *CLI) runCheckDrift(conf *config.Config) int {
+	// Turn off everything which acts on the host or on Consul
+	conf = conf.Copy()
+	conf.Exec.Enabled = config.Bool(false)
+	conf.Exec.Command = config.String("")
+	conf.PidFile = config.String("")
+	conf.Status.Enabled = config.Bool(false)
+
+	var lock sync.Mutex
+	drifted := make(map[string]string)
+
+	runner, err := manager.NewRunner(conf, false, true)
+	if err != nil {
+		return cli.handleError(err, ExitCodeRunnerError)
+	}
+	runner.SetRenderer(manager.RendererFunc(func(i *manager.RenderInput) (*manager.RenderResult, error) {
+		if i.Split {
+			if err := checkSplitDrift(i, &lock, drifted); err != nil {
+				return nil, err
+			}
+			return &manager.RenderResult{
+				DidRender:   false,
+				WouldRender: true,
+			}, nil
+		}
+
+		var reason string
+		existing, err := ioutil.ReadFile(i.Path)
+		switch {
+		case os.IsNotExist(err):
+			reason = "missing"
+		case err != nil:
+			return nil, err
+		case !bytes.Equal(existing, i.Contents):
+			reason = "changed"
+		}
+
+		if reason != "" {
+			lock.Lock()
+			drifted[i.Path] = reason
+			lock.Unlock()
+		}
+
+		return &manager.RenderResult{
+			DidRender:   false,
+			WouldRender: true,
+		}, nil
+	}))
+	go runner.Start()
+	defer runner.Stop()
+
+	select {
+	case err := <-runner.ErrCh:
+		return cli.handleError(err, ExitCodeRunnerError)
+	case <-runner.DoneCh:
+	case <-cli.stopCh:
+		return ExitCodeOK
+	}
+
+	if len(drifted) == 0 {
+		return ExitCodeOK
+	}
+
+	paths := make([]string, 0, len(drifted))
+	for path := range drifted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		fmt.Fprintf(cli.outStream, "drifted: %s (%s)\n", path, drifted[path])
+	}
+	return ExitCodeDriftError
+}
+
+// checkSplitDrift compares the files of a split destination with the rendered
+// output, recording each file which is missing, changed or no longer rendered.
+func checkSplitDrift(i *manager.RenderInput, lock *sync.Mutex, drifted map[string]string) error {
+	files, err := manager.SplitContents(i.Contents)
+	if err != nil {
+		return err
+	}
+	existing, err := manager.ReadSplit(i.Path)
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for key, contents := range files {
+		path := filepath.Join(i.Path, key)
+		current, ok := existing[key]
+		switch {
+		case !ok:
+			drifted[path] = "missing"
+		case !bytes.Equal(current, contents):
+			drifted[path] = "changed"
+		}
+	}
+	for key := range existing {
+		if _, ok := files[key]; !ok {
+			drifted[filepath.Join(i.Path, key)] = "removed"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCLI_Run_checkDrift(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "in.tpl")
+	if err := ioutil.WriteFile(src, []byte(`hello {{ "world" }}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	current := filepath.Join(dir, "current")
+	if err := ioutil.WriteFile(current, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed := filepath.Join(dir, "changed")
+	if err := ioutil.WriteFile(changed, []byte("hello there"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	cases := []struct {
+		name string
+		dsts []string
+		exit int
+		exp  string
+	}{
+		{
+			"current",
+			[]string{current},
+			ExitCodeOK,
+			"",
+		},
+		{
+			"drifted",
+			[]string{current, missing, changed},
+			ExitCodeDriftError,
+			fmt.Sprintf("drifted: %s (changed)\ndrifted: %s (missing)\n", changed, missing),
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			cli := NewCLI(&out, &errOut)
+
+			args := []string{"consul-template", "-check-drift"}
+			for _, dst := range tc.dsts {
+				args = append(args, "-template", src+":"+dst)
+			}
+
+			if exit := cli.Run(args); exit != tc.exit {
+				t.Fatalf("expected %d exit, got %d: %s", tc.exit, exit, errOut.String())
+			}
+			if act := out.String(); act != tc.exp {
+				t.Errorf("\nexp: %q\nact: %q", tc.exp, act)
+			}
+
+			// Nothing must be written
+			if _, err := os.Stat(missing); !os.IsNotExist(err) {
+				t.Errorf("expected %s to not exist: %v", missing, err)
+			}
+			contents, err := ioutil.ReadFile(changed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp, act := "hello there", string(contents); exp != act {
+				t.Errorf("\nexp: %q\nact: %q", exp, act)
+			}
+		})
+	}
+}
@@ -35,10 +35,10 @@ func Setup(config *Config) error {
 	var logOutput io.Writer
 
 	// Setup the default logging
-	logFilter := NewLogFilter()
+	logFilter := NewTagFilter()
 	logFilter.MinLevel = logutils.LogLevel(strings.ToUpper(config.Level))
 	logFilter.Writer = config.Writer
-	if !ValidateLevelFilter(logFilter.MinLevel, logFilter) {
+	if !ValidateLevelFilter(logFilter.MinLevel, logFilter.LevelFilter) {
 		levels := make([]string, 0, len(logFilter.Levels))
 		for _, level := range logFilter.Levels {
 			levels = append(levels, string(level))
@@ -64,6 +64,10 @@ func Setup(config *Config) error {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.LUTC)
 	log.SetOutput(logOutput)
 
+	filterLock.Lock()
+	filter = logFilter
+	filterLock.Unlock()
+
 	return nil
 }
 
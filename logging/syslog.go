@@ -4,7 +4,6 @@ import (
 	"bytes"
 
 	"github.com/hashicorp/go-syslog"
-	"github.com/hashicorp/logutils"
 )
 
 // syslogPriorityMap is used to map a log level to a syslog priority level.
@@ -19,7 +18,13 @@ var syslogPriorityMap = map[string]gsyslog.Priority{
 // Syslogger. Implements the io.Writer interface.
 type SyslogWrapper struct {
 	l    gsyslog.Syslogger
-	filt *logutils.LevelFilter
+	filt levelChecker
+}
+
+// levelChecker is implemented by the filters which decide if a log line is
+// written.
+type levelChecker interface {
+	Check(line []byte) bool
 }
 
 // Write is used to implement io.Writer.
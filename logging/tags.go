@@ -0,0 +1,145 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/logutils"
+)
+
+// filter is the filter installed by Setup, if any. Tag levels are applied to it.
+var (
+	filter     *TagFilter
+	filterLock sync.RWMutex
+)
+
+// TagFilter is a LevelFilter which applies a different minimum level to tagged
+// lines. A line is tagged when its message is prefixed with the tag in
+// brackets, after the level and component, such as "[DEBUG] (runner) [web]".
+// Untagged lines, and lines with a tag that has no level, use MinLevel.
+type TagFilter struct {
+	*logutils.LevelFilter
+
+	lock sync.RWMutex
+	tags map[string]*logutils.LevelFilter
+}
+
+// NewTagFilter returns a TagFilter that is configured with the log levels that
+// we use.
+func NewTagFilter() *TagFilter {
+	return &TagFilter{
+		LevelFilter: NewLogFilter(),
+		tags:        make(map[string]*logutils.LevelFilter),
+	}
+}
+
+// Check will check a given line if it would be included in the filter.
+func (f *TagFilter) Check(line []byte) bool {
+	if tag := lineTag(line); tag != "" {
+		f.lock.RLock()
+		tf, ok := f.tags[tag]
+		f.lock.RUnlock()
+		if ok {
+			return tf.Check(line)
+		}
+	}
+	return f.LevelFilter.Check(line)
+}
+
+// Write is used to implement io.Writer.
+func (f *TagFilter) Write(p []byte) (int, error) {
+	if !f.Check(p) {
+		return len(p), nil
+	}
+	return f.Writer.Write(p)
+}
+
+// SetTagLevels replaces the minimum level of each tag with the given levels.
+func (f *TagFilter) SetTagLevels(levels map[string]logutils.LogLevel) {
+	tags := make(map[string]*logutils.LevelFilter, len(levels))
+	for tag, level := range levels {
+		tags[tag] = &logutils.LevelFilter{
+			Levels:   f.Levels,
+			MinLevel: level,
+		}
+	}
+
+	f.lock.Lock()
+	f.tags = tags
+	f.lock.Unlock()
+}
+
+// SetTagLevels validates the given levels and applies them to the logging
+// set up by Setup, replacing any previous tag levels. It does nothing else if
+// logging was not set up.
+func SetTagLevels(levels map[string]string) error {
+	parsed, err := parseTagLevels(levels)
+	if err != nil {
+		return err
+	}
+
+	filterLock.RLock()
+	defer filterLock.RUnlock()
+	if filter != nil {
+		filter.SetTagLevels(parsed)
+	}
+	return nil
+}
+
+// ValidateTagLevels returns an error if any of the given levels is invalid,
+// without applying them.
+func ValidateTagLevels(levels map[string]string) error {
+	_, err := parseTagLevels(levels)
+	return err
+}
+
+// parseTagLevels parses the given level of each tag.
+func parseTagLevels(levels map[string]string) (map[string]logutils.LogLevel, error) {
+	parsed := make(map[string]logutils.LogLevel, len(levels))
+	for tag, level := range levels {
+		l := logutils.LogLevel(strings.ToUpper(level))
+		if !ValidateLevelFilter(l, NewLogFilter()) {
+			return nil, fmt.Errorf("invalid log level %q for tag %q", level, tag)
+		}
+		parsed[tag] = l
+	}
+	return parsed, nil
+}
+
+// lineTag returns the tag of the given log line, or the empty string if it is
+// not tagged.
+func lineTag(line []byte) string {
+	// Skip past the level
+	x := bytes.IndexByte(line, '[')
+	if x < 0 {
+		return ""
+	}
+	y := bytes.IndexByte(line[x:], ']')
+	if y < 0 {
+		return ""
+	}
+	rest := line[x+y+1:]
+
+	// Skip past the component
+	rest = bytes.TrimPrefix(rest, []byte(" "))
+	if !bytes.HasPrefix(rest, []byte("(")) {
+		return ""
+	}
+	y = bytes.IndexByte(rest, ')')
+	if y < 0 {
+		return ""
+	}
+	rest = bytes.TrimPrefix(rest[y+1:], []byte(" "))
+
+	// Read the tag
+	if !bytes.HasPrefix(rest, []byte("[")) {
+		return ""
+	}
+	y = bytes.IndexByte(rest, ']')
+	if y < 0 {
+		return ""
+	}
+	return string(rest[1:y])
+}
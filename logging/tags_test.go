@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/logutils"
+)
+
+func TestTagFilter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	filt := NewTagFilter()
+	filt.MinLevel = logutils.LogLevel("INFO")
+	filt.Writer = &buf
+	filt.SetTagLevels(map[string]logutils.LogLevel{
+		"noisy": "WARN",
+		"debug": "TRACE",
+	})
+
+	cases := []struct {
+		name string
+		line string
+		pass bool
+	}{
+		{
+			"untagged_above",
+			"[INFO] (runner) hello",
+			true,
+		},
+		{
+			"untagged_below",
+			"[DEBUG] (runner) hello",
+			false,
+		},
+		{
+			"tag_without_level",
+			"[DEBUG] (runner) [other] hello",
+			false,
+		},
+		{
+			"tag_silenced",
+			"[INFO] (runner) [noisy] hello",
+			false,
+		},
+		{
+			"tag_silenced_above",
+			"[ERR] (runner) [noisy] hello",
+			true,
+		},
+		{
+			"tag_verbose",
+			"[TRACE] (runner) [debug] hello",
+			true,
+		},
+		{
+			"timestamp",
+			"2017/01/01 00:00:00.000000 [TRACE] (runner) [debug] hello",
+			true,
+		},
+		{
+			"tag_not_after_component",
+			"[DEBUG] (runner) hello [debug]",
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := filt.Check([]byte(tc.line)); act != tc.pass {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.pass, act)
+			}
+		})
+	}
+
+	if _, err := filt.Write([]byte("[DEBUG] (runner) [noisy] hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := filt.Write([]byte("[DEBUG] (runner) [debug] hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "[DEBUG] (runner) [debug] hello\n", buf.String(); exp != act {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
+
+func TestSetTagLevels(t *testing.T) {
+	if err := SetTagLevels(map[string]string{"web": "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTagLevels(map[string]string{"web": "nope"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestValidateTagLevels(t *testing.T) {
+	if err := ValidateTagLevels(map[string]string{"web": "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateTagLevels(map[string]string{"web": "nope"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
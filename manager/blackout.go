@@ -0,0 +1,207 @@
+package manager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// cronSchedule is a parsed cron expression with the minute, hour, day of the
+// month, month and day of the week fields. Each field is the set of values it
+// matches, as a bit set.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny are true if the day fields are "*". Like cron, a day
+	// matches either day field if both are restricted.
+	domAny, dowAny bool
+}
+
+// parseCronSchedule parses the given cron expression. Each field is "*", a
+// value, a range such as "1-5", or a list of those, and "*" and ranges can
+// have a step such as "*/15". Sunday is 0 or 7.
+func parseCronSchedule(s string) (*cronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields, got %d", s, len(fields))
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("schedule %q: minute: %s", s, err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("schedule %q: hour: %s", s, err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("schedule %q: day of month: %s", s, err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("schedule %q: month: %s", s, err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("schedule %q: day of week: %s", s, err)
+	}
+
+	// Sunday is both 0 and 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return &c, nil
+}
+
+// parseCronField parses a field of a cron expression with values between min
+// and max into a bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rng)
+			}
+			lo, hi = v, v
+			if step > 1 {
+				// A single value with a step, such as "5/10", runs to the end.
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", rng, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// dayMatches returns true if the day of the given time matches the day fields.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// prev returns the latest time at or before the given time which matches the
+// schedule and is after the given earliest time. It returns false if there is
+// none.
+func (c *cronSchedule) prev(t, earliest time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+
+	for t.After(earliest) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			// Go to the last minute of the previous month.
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// blackoutMaxJoins is the maximum number of windows joined to the end of the
+// current blackout, so a window which never ends does not loop forever. The
+// blackout is evaluated again when the returned end is reached.
+const blackoutMaxJoins = 100
+
+// blackoutWindow is a parsed blackout window.
+type blackoutWindow struct {
+	schedule *cronSchedule
+	duration time.Duration
+}
+
+// newBlackoutWindows parses the given blackout windows.
+func newBlackoutWindows(c *config.BlackoutConfigs) ([]*blackoutWindow, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	windows := make([]*blackoutWindow, 0, len(*c))
+	for _, b := range *c {
+		schedule, err := parseCronSchedule(config.StringVal(b.Schedule))
+		if err != nil {
+			return nil, fmt.Errorf("blackout: %s", err)
+		}
+		duration := config.TimeDurationVal(b.Duration)
+		if duration <= 0 {
+			return nil, fmt.Errorf("blackout: %q requires a positive duration",
+				config.StringVal(b.Schedule))
+		}
+		windows = append(windows, &blackoutWindow{
+			schedule: schedule,
+			duration: duration,
+		})
+	}
+	return windows, nil
+}
+
+// blackoutUntil returns the end of the blackout at the given time, in the
+// time zone of the given time, and true if any of the given windows is
+// active. Windows which overlap or follow each other directly are joined.
+func blackoutUntil(windows []*blackoutWindow, t time.Time) (time.Time, bool) {
+	var until time.Time
+	at := t
+	for i := 0; i < blackoutMaxJoins; i++ {
+		extended := false
+		for _, w := range windows {
+			start, ok := w.schedule.prev(at, at.Add(-w.duration))
+			if !ok {
+				continue
+			}
+			if end := start.Add(w.duration); end.After(until) {
+				until = end
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+		at = until
+	}
+	return until, !until.IsZero()
+}
@@ -0,0 +1,270 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		s    string
+		err  bool
+	}{
+		{
+			"every_minute",
+			"* * * * *",
+			false,
+		},
+		{
+			"lists_ranges_steps",
+			"0,30 9-17/2 1-15 */3 1-5",
+			false,
+		},
+		{
+			"sunday_seven",
+			"0 0 * * 7",
+			false,
+		},
+		{
+			"too_few_fields",
+			"0 18 * *",
+			true,
+		},
+		{
+			"out_of_range",
+			"60 * * * *",
+			true,
+		},
+		{
+			"bad_range",
+			"* 5-1 * * *",
+			true,
+		},
+		{
+			"bad_step",
+			"*/0 * * * *",
+			true,
+		},
+		{
+			"bad_value",
+			"* * * jan *",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			_, err := parseCronSchedule(tc.s)
+			if (err != nil) != tc.err {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestBlackoutUntil(t *testing.T) {
+	t.Parallel()
+
+	windows := func(c ...*config.BlackoutConfig) []*blackoutWindow {
+		b := config.BlackoutConfigs(c)
+		w, err := newBlackoutWindows(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	// 2026-10-16 is a Friday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	weekend := windows(&config.BlackoutConfig{
+		Schedule: config.String("0 18 * * 5"),
+		Duration: config.TimeDuration(62 * time.Hour),
+	})
+	joined := windows(
+		&config.BlackoutConfig{
+			Schedule: config.String("0 9 * * *"),
+			Duration: config.TimeDuration(2 * time.Hour),
+		},
+		&config.BlackoutConfig{
+			Schedule: config.String("0 11 * * *"),
+			Duration: config.TimeDuration(1 * time.Hour),
+		},
+	)
+	// Either the first of the month or a Monday.
+	days := windows(&config.BlackoutConfig{
+		Schedule: config.String("0 0 1 * 1"),
+		Duration: config.TimeDuration(24 * time.Hour),
+	})
+
+	cases := []struct {
+		name    string
+		windows []*blackoutWindow
+		t       time.Time
+		until   time.Time
+	}{
+		{
+			"none",
+			nil,
+			at(16, 18, 0),
+			time.Time{},
+		},
+		{
+			"before",
+			weekend,
+			at(16, 17, 59),
+			time.Time{},
+		},
+		{
+			"start",
+			weekend,
+			at(16, 18, 0),
+			at(19, 8, 0),
+		},
+		{
+			"during",
+			weekend,
+			at(18, 12, 30),
+			at(19, 8, 0),
+		},
+		{
+			"end",
+			weekend,
+			at(19, 8, 0),
+			time.Time{},
+		},
+		{
+			"joined",
+			joined,
+			at(16, 9, 30),
+			at(16, 12, 0),
+		},
+		{
+			"day_of_week",
+			days,
+			at(19, 10, 0),
+			at(20, 0, 0),
+		},
+		{
+			"day_of_month",
+			days,
+			time.Date(2026, time.December, 1, 10, 0, 0, 0, time.UTC),
+			time.Date(2026, time.December, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// November 1st is a Sunday, so the window runs into Monday's.
+			"day_of_month_joined",
+			days,
+			time.Date(2026, time.November, 1, 10, 0, 0, 0, time.UTC),
+			time.Date(2026, time.November, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"neither_day",
+			days,
+			at(16, 10, 0),
+			time.Time{},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			until, ok := blackoutUntil(tc.windows, tc.t)
+			if ok != !tc.until.IsZero() || !until.Equal(tc.until) {
+				t.Errorf("\nexp: %s\nact: %s (%t)", tc.until, until, ok)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_blackout(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	c := config.TestConfig(&config.Config{
+		Blackout: &config.BlackoutConfigs{
+			&config.BlackoutConfig{
+				Schedule: config.String("* * * * *"),
+				Duration: config.TimeDuration(1 * time.Hour),
+			},
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The template is held during the blackout.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatalf("expected the template to be held, got %v", err)
+	}
+	if r.blackoutEnd.IsZero() {
+		t.Error("expected the end of the blackout")
+	}
+	for _, e := range r.RenderEvents() {
+		if !strings.HasPrefix(e.BlockedReason, "blackout until ") {
+			t.Errorf("unexpected reason: %q", e.BlockedReason)
+		}
+	}
+
+	// Once the blackout is over, the template is written.
+	r.blackouts = nil
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(out); err != nil || string(b) != "test" {
+		t.Errorf("expected the template to render, got %q (%v)", b, err)
+	}
+	if !r.blackoutEnd.IsZero() {
+		t.Errorf("expected no blackout, got %s", r.blackoutEnd)
+	}
+}
+
+func TestNewRunner_blackoutInvalid(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String("/tmp/out"),
+				Blackout: &config.BlackoutConfigs{
+					&config.BlackoutConfig{
+						Schedule: config.String("0 18 * * 5"),
+					},
+				},
+			},
+		},
+	})
+
+	_, err := NewRunner(c, false, false)
+	if err == nil || !strings.Contains(err.Error(), "requires a positive duration") {
+		t.Errorf("expected a duration error, got %v", err)
+	}
+}
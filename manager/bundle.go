@@ -0,0 +1,293 @@
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/pkg/errors"
+)
+
+// bundleModTime is the modification time of every entry of an archive, so the
+// archive only changes when the contents of an entry change.
+var bundleModTime = time.Unix(0, 0).UTC()
+
+// bundle packages the rendered output of the template configurations which
+// name it into an archive.
+type bundle struct {
+	config *config.BundleConfig
+
+	// configs are the template configurations of the entries, in the order
+	// of the configuration, and entries are their latest rendered output.
+	configs []*config.TemplateConfig
+	entries map[*config.TemplateConfig]*bundleEntry
+
+	// changed is true if an entry was rendered since the archive was last
+	// written.
+	changed bool
+
+	// observed is the last observed archive in observe mode.
+	observed *observation
+}
+
+// bundleEntry is the latest rendered output of a template configuration of a
+// bundle, with what the run which rendered it records once the archive is
+// written.
+type bundleEntry struct {
+	tmpl      *template.Template
+	contents  []byte
+	used      []dep.Dependency
+	changed   []string
+	revisions map[string]uint64
+}
+
+// newBundles returns the bundles of the given configuration, keyed by the
+// template configurations packaged into them. It is an error if a bundle or a
+// template which names one is invalid.
+func newBundles(bundles *config.BundleConfigs, templates config.TemplateConfigs) ([]*bundle, map[*config.TemplateConfig]*bundle, error) {
+	byName := make(map[string]*bundle)
+	var list []*bundle
+	for _, bc := range *bundles {
+		name := config.StringVal(bc.Name)
+		if name == "" {
+			return nil, nil, fmt.Errorf("bundle: name is required")
+		}
+		if _, ok := byName[name]; ok {
+			return nil, nil, fmt.Errorf("bundle %q: defined more than once", name)
+		}
+		if config.StringVal(bc.Destination) == "" {
+			return nil, nil, fmt.Errorf("bundle %q: destination is required", name)
+		}
+		switch format := config.StringVal(bc.Format); format {
+		case config.BundleFormatTar, config.BundleFormatTarGzip, config.BundleFormatZip:
+		default:
+			return nil, nil, fmt.Errorf("bundle %q: invalid format %q, must be %q, %q or %q",
+				name, format, config.BundleFormatTar, config.BundleFormatTarGzip, config.BundleFormatZip)
+		}
+
+		b := &bundle{
+			config:  bc,
+			entries: make(map[*config.TemplateConfig]*bundleEntry),
+		}
+		byName[name] = b
+		list = append(list, b)
+	}
+
+	byTemplate := make(map[*config.TemplateConfig]*bundle)
+	paths := make(map[*bundle]map[string]struct{})
+	for _, tc := range templates {
+		name, entry := config.StringVal(tc.Bundle), config.StringVal(tc.BundlePath)
+		if name == "" {
+			if entry != "" {
+				return nil, nil, fmt.Errorf("%s: bundle_path requires a bundle", tc.Display())
+			}
+			continue
+		}
+
+		b, ok := byName[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: unknown bundle %q", tc.Display(), name)
+		}
+		if config.StringVal(tc.Destination) != "" {
+			return nil, nil, fmt.Errorf("%s: bundle cannot be used with destination", tc.Display())
+		}
+		if config.BoolVal(tc.SplitDestination) {
+			return nil, nil, fmt.Errorf("%s: bundle cannot be used with split_destination", tc.Display())
+		}
+		if err := validateBundlePath(entry); err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", tc.Display(), err)
+		}
+		if paths[b] == nil {
+			paths[b] = make(map[string]struct{})
+		}
+		if _, ok := paths[b][entry]; ok {
+			return nil, nil, fmt.Errorf("%s: bundle_path %q is used more than once in bundle %q",
+				tc.Display(), entry, name)
+		}
+		paths[b][entry] = struct{}{}
+
+		b.configs = append(b.configs, tc)
+		byTemplate[tc] = b
+	}
+
+	for _, b := range list {
+		if len(b.configs) == 0 {
+			return nil, nil, fmt.Errorf("bundle %q: no template uses it", b.name())
+		}
+	}
+	return list, byTemplate, nil
+}
+
+// validateBundlePath returns an error if the given path of an entry is not a
+// clean relative path inside the archive.
+func validateBundlePath(p string) error {
+	switch {
+	case p == "":
+		return fmt.Errorf("bundle_path is required")
+	case path.IsAbs(p) || strings.Contains(p, `\`):
+		return fmt.Errorf("bundle_path %q must be a relative path using slashes", p)
+	case path.Clean(p) != p || p == "." || p == ".." || strings.HasPrefix(p, "../"):
+		return fmt.Errorf("bundle_path %q must be a clean path inside the archive", p)
+	}
+	return nil
+}
+
+// name returns the name of this bundle.
+func (b *bundle) name() string {
+	return config.StringVal(b.config.Name)
+}
+
+// set records the latest rendered output of the given template configuration.
+func (b *bundle) set(tc *config.TemplateConfig, e *bundleEntry) {
+	b.entries[tc] = e
+	b.changed = true
+}
+
+// missing returns the number of entries which have not rendered yet.
+func (b *bundle) missing() int {
+	var n int
+	for _, tc := range b.configs {
+		if _, ok := b.entries[tc]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// configsByBundlePath is a sortable list of template configurations by their
+// path in the bundle.
+type configsByBundlePath []*config.TemplateConfig
+
+func (s configsByBundlePath) Len() int      { return len(s) }
+func (s configsByBundlePath) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s configsByBundlePath) Less(i, j int) bool {
+	return config.StringVal(s[i].BundlePath) < config.StringVal(s[j].BundlePath)
+}
+
+// archive returns the archive of all the entries, sorted by path. Entries have
+// the permissions of their template configuration and a fixed modification
+// time, so the same entries always produce the same archive.
+func (b *bundle) archive() ([]byte, error) {
+	configs := make(configsByBundlePath, len(b.configs))
+	copy(configs, b.configs)
+	sort.Sort(configs)
+
+	var buf bytes.Buffer
+	var err error
+	switch config.StringVal(b.config.Format) {
+	case config.BundleFormatTar:
+		err = b.writeTar(&buf, configs)
+	case config.BundleFormatTarGzip:
+		gz := gzip.NewWriter(&buf)
+		if err = b.writeTar(gz, configs); err == nil {
+			err = gz.Close()
+		}
+	case config.BundleFormatZip:
+		err = b.writeZip(&buf, configs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTar writes the entries of the given template configurations as a tar
+// archive.
+func (b *bundle) writeTar(w io.Writer, configs []*config.TemplateConfig) error {
+	tw := tar.NewWriter(w)
+	for _, tc := range configs {
+		contents := b.entries[tc].contents
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     config.StringVal(tc.BundlePath),
+			Mode:     int64(bundleEntryPerms(tc)),
+			Size:     int64(len(contents)),
+			ModTime:  bundleModTime,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(contents); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeZip writes the entries of the given template configurations as a zip
+// archive.
+func (b *bundle) writeZip(w io.Writer, configs []*config.TemplateConfig) error {
+	zw := zip.NewWriter(w)
+	for _, tc := range configs {
+		h := &zip.FileHeader{
+			Name:     config.StringVal(tc.BundlePath),
+			Method:   zip.Deflate,
+			Modified: bundleModTime,
+		}
+		h.SetMode(bundleEntryPerms(tc))
+		f, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(b.entries[tc].contents); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// bundleEntryPerms returns the permissions of the entry of the given template
+// configuration.
+func bundleEntryPerms(tc *config.TemplateConfig) os.FileMode {
+	return config.FileModeVal(tc.Perms).Perm()
+}
+
+// renderBundle writes the archive of the given bundle, taking dry mode into
+// account. It returns a nil result if an entry has not rendered yet.
+func (r *Runner) renderBundle(b *bundle) (*RenderResult, error) {
+	if n := b.missing(); n > 0 {
+		log.Printf("[DEBUG] (runner) bundle %q is waiting for %d template(s)", b.name(), n)
+		return nil, nil
+	}
+	b.changed = false
+
+	dest := config.StringVal(b.config.Destination)
+	if r.dry {
+		// The archive is binary, so show its entries instead.
+		for _, tc := range b.configs {
+			fmt.Fprintf(r.outStream, "> %s:%s\n%s", dest, config.StringVal(tc.BundlePath),
+				b.entries[tc].contents)
+		}
+		return &RenderResult{DidRender: true, WouldRender: true}, nil
+	}
+
+	contents, err := b.archive()
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error packaging bundle %q", b.name()))
+	}
+	if r.observe {
+		return r.observeBundle(b, dest, contents)
+	}
+	result, err := renderRecovered(r.renderer, &RenderInput{
+		Contents:       contents,
+		CreateDestDirs: true,
+		DestDirPerms:   config.DefaultTemplateDestDirPerms,
+		Path:           dest,
+		Perms:          config.FileModeVal(b.config.Perms),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error rendering bundle %q", b.name()))
+	}
+	return result, nil
+}
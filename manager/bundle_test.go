@@ -0,0 +1,311 @@
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewBundles(t *testing.T) {
+	cases := []struct {
+		name      string
+		bundles   *config.BundleConfigs
+		templates config.TemplateConfigs
+		err       string
+	}{
+		{
+			"valid",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/app.zip")},
+			},
+			config.TemplateConfigs{
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("a.conf")},
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("conf.d/b.conf")},
+				&config.TemplateConfig{Destination: config.String("/tmp/c.conf")},
+			},
+			"",
+		},
+		{
+			"no_format",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/app")},
+			},
+			config.TemplateConfigs{
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("a.conf")},
+			},
+			"invalid format",
+		},
+		{
+			"duplicate_name",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/a.tar")},
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/b.tar")},
+			},
+			nil,
+			"defined more than once",
+		},
+		{
+			"unused",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/app.tar")},
+			},
+			nil,
+			"no template uses it",
+		},
+		{
+			"unknown_bundle",
+			&config.BundleConfigs{},
+			config.TemplateConfigs{
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("a.conf")},
+			},
+			`unknown bundle "app"`,
+		},
+		{
+			"with_destination",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/app.tar")},
+			},
+			config.TemplateConfigs{
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("a.conf"),
+					Destination: config.String("/tmp/a.conf")},
+			},
+			"cannot be used with destination",
+		},
+		{
+			"escaping_path",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/app.tar")},
+			},
+			config.TemplateConfigs{
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("../a.conf")},
+			},
+			"must be a clean path",
+		},
+		{
+			"duplicate_path",
+			&config.BundleConfigs{
+				&config.BundleConfig{Name: config.String("app"), Destination: config.String("/tmp/app.tar")},
+			},
+			config.TemplateConfigs{
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("a.conf")},
+				&config.TemplateConfig{Bundle: config.String("app"), BundlePath: config.String("a.conf")},
+			},
+			"used more than once",
+		},
+		{
+			"path_without_bundle",
+			&config.BundleConfigs{},
+			config.TemplateConfigs{
+				&config.TemplateConfig{BundlePath: config.String("a.conf")},
+			},
+			"bundle_path requires a bundle",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.bundles.Finalize()
+			for _, tmpl := range tc.templates {
+				tmpl.Finalize()
+			}
+
+			bundles, byTemplate, err := newBundles(tc.bundles, tc.templates)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(bundles) != 1 || len(byTemplate) != 2 {
+				t.Errorf("expected 1 bundle of 2 templates, got %d and %d", len(bundles), len(byTemplate))
+			}
+		})
+	}
+}
+
+// readBundle returns the contents and permissions of the entries of the given
+// archive.
+func readBundle(t *testing.T, format string, archive []byte) (map[string]string, map[string]os.FileMode) {
+	contents := make(map[string]string)
+	modes := make(map[string]os.FileMode)
+
+	switch format {
+	case config.BundleFormatZip:
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents[f.Name] = string(b)
+			modes[f.Name] = f.Mode().Perm()
+		}
+	default:
+		var r io.Reader = bytes.NewReader(archive)
+		if format == config.BundleFormatTarGzip {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents[h.Name] = string(b)
+			modes[h.Name] = os.FileMode(h.Mode).Perm()
+		}
+	}
+	return contents, modes
+}
+
+func TestBundle_archive(t *testing.T) {
+	formats := []string{config.BundleFormatTar, config.BundleFormatTarGzip, config.BundleFormatZip}
+
+	for i, format := range formats {
+		t.Run(fmt.Sprintf("%d_%s", i, format), func(t *testing.T) {
+			b := &bundle{
+				config:  &config.BundleConfig{Format: config.String(format)},
+				entries: make(map[*config.TemplateConfig]*bundleEntry),
+			}
+			for _, tc := range []*config.TemplateConfig{
+				{BundlePath: config.String("z.conf"), Perms: config.FileMode(0600)},
+				{BundlePath: config.String("conf.d/a.conf"), Perms: config.FileMode(0644)},
+			} {
+				b.configs = append(b.configs, tc)
+				b.set(tc, &bundleEntry{contents: []byte("contents of " + config.StringVal(tc.BundlePath))})
+			}
+
+			archive, err := b.archive()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			contents, modes := readBundle(t, format, archive)
+			expContents := map[string]string{
+				"z.conf":        "contents of z.conf",
+				"conf.d/a.conf": "contents of conf.d/a.conf",
+			}
+			if !reflect.DeepEqual(expContents, contents) {
+				t.Errorf("\nexp: %#v\nact: %#v", expContents, contents)
+			}
+			expModes := map[string]os.FileMode{
+				"z.conf":        0600,
+				"conf.d/a.conf": 0644,
+			}
+			if !reflect.DeepEqual(expModes, modes) {
+				t.Errorf("\nexp: %#v\nact: %#v", expModes, modes)
+			}
+
+			// The same entries produce the same archive.
+			again, err := b.archive()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(archive, again) {
+				t.Error("expected the archive to be the same")
+			}
+		})
+	}
+}
+
+func TestRunner_bundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "bundle.tar.gz")
+
+	c := config.TestConfig(&config.Config{
+		Bundles: &config.BundleConfigs{
+			&config.BundleConfig{
+				Name:        config.String("app"),
+				Destination: config.String(dest),
+			},
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:   config.String(`{{ key "foo" }}`),
+				Bundle:     config.String("app"),
+				BundlePath: config.String("foo.conf"),
+			},
+			&config.TemplateConfig{
+				Contents:   config.String(`static`),
+				Bundle:     config.String("app"),
+				BundlePath: config.String("static.conf"),
+				Perms:      config.FileMode(0600),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	// The archive is not written until every entry rendered.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected no archive yet, got %v", err)
+	}
+
+	r.Receive(r.dependencies["kv.block(foo)"], "bar")
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, modes := readBundle(t, config.BundleFormatTarGzip, archive)
+	exp := map[string]string{"foo.conf": "bar", "static.conf": "static"}
+	if !reflect.DeepEqual(exp, contents) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, contents)
+	}
+	if modes["static.conf"] != 0600 {
+		t.Errorf("expected static.conf to have mode 0600, got %s", modes["static.conf"])
+	}
+
+	for _, tmpl := range r.templates {
+		if event := r.RenderEvents()[tmpl.ID()]; event == nil || event.LastDidRender.IsZero() {
+			t.Errorf("expected %s to be rendered", r.templateLabel(tmpl.ID()))
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+const (
+	// cacheUpstreamWait is the wait time of the blocking queries the cache
+	// server makes to Consul.
+	cacheUpstreamWait = 5 * time.Minute
+
+	// cacheDefaultWait and cacheMaxWait are the default and maximum wait time of
+	// the blocking queries of the clients, like Consul.
+	cacheDefaultWait = 5 * time.Minute
+	cacheMaxWait     = 10 * time.Minute
+
+	// cacheIdleTimeout is how long a query keeps being watched after its last
+	// client went away, so clients which re-issue their blocking query find it.
+	cacheIdleTimeout = time.Minute
+
+	// cacheMinInterval is the minimum time between two queries to Consul for the
+	// same data when the index does not change, so endpoints which return early
+	// are not queried in a tight loop.
+	cacheMinInterval = time.Second
+
+	// cacheSocketPerms are the permissions of the socket of the cache server.
+	// Every request is made with the token of the client, so any instance of
+	// the same user or group may share the cache.
+	cacheSocketPerms = 0660
+)
+
+// cacheHopHeaders are the headers of a response which are not passed on to the
+// clients of the cache server.
+var cacheHopHeaders = []string{
+	"Connection",
+	"Content-Length",
+	"Keep-Alive",
+	"Transfer-Encoding",
+}
+
+// CacheServer is a cache of Consul data shared by the instances on a host. It
+// serves the Consul HTTP API on a unix socket, and holds a single blocking
+// query to Consul for each distinct query of its clients, however many clients
+// make it. Queries are made with the token of the client, and clients with
+// different tokens never share data.
+type CacheServer struct {
+	config   *config.Config
+	clients  *dep.ClientSet
+	listener net.Listener
+	server   *httpServer
+
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+	stopCh  chan struct{}
+}
+
+// cacheResponse is a response of Consul served to the clients.
+type cacheResponse struct {
+	index  uint64
+	status int
+	header http.Header
+	body   []byte
+}
+
+// cacheEntry is a query watched by the cache server. response is the latest
+// response of Consul, and updateCh is closed and replaced whenever it changes.
+// Once done, the query is no longer watched and response is the final
+// response. clients is the number of clients waiting on the entry, and
+// lastUsed the last time a client went away.
+type cacheEntry struct {
+	path  string
+	query url.Values
+	token string
+
+	lock     sync.Mutex
+	response *cacheResponse
+	updateCh chan struct{}
+	done     bool
+	clients  int
+	lastUsed time.Time
+
+	cancel func()
+}
+
+// NewCacheServer creates a new cache server from the given configuration. It
+// listens on the path of the local cache configuration, and connects to Consul
+// with the Consul settings, without a token of its own.
+func NewCacheServer(c *config.Config) (*CacheServer, error) {
+	if !config.StringPresent(c.LocalCache.Path) {
+		return nil, fmt.Errorf("cache server: a path is required")
+	}
+
+	// The cache server itself always talks to Consul directly.
+	upstream := c.Copy()
+	upstream.LocalCache = config.DefaultLocalCacheConfig()
+	upstream.Streaming = config.DefaultStreamingConfig()
+	upstream.Finalize()
+
+	clients := dep.NewClientSet()
+	if err := createConsulClient(clients, upstream, ""); err != nil {
+		return nil, err
+	}
+
+	s := &CacheServer{
+		config:  c,
+		clients: clients,
+		entries: make(map[string]*cacheEntry),
+		stopCh:  make(chan struct{}),
+	}
+	s.server = newHTTPServer(http.HandlerFunc(s.handle))
+
+	return s, nil
+}
+
+// Start begins listening on the configured socket. Requests are served in the
+// background until Stop is called.
+func (s *CacheServer) Start() error {
+	path := config.StringVal(s.config.LocalCache.Path)
+
+	// Remove the socket of a previous process which did not clean up.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cache server: failed to remove %s: %s", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("cache server: failed to listen on %s: %s", path, err)
+	}
+	if err := os.Chmod(path, cacheSocketPerms); err != nil {
+		ln.Close()
+		return fmt.Errorf("cache server: failed to set permissions on %s: %s", path, err)
+	}
+	s.listener = ln
+
+	log.Printf("[INFO] (cache) listening on %s", path)
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil {
+			log.Printf("[ERR] (cache) server stopped: %s", err)
+		}
+	}()
+	go s.expire()
+
+	return nil
+}
+
+// Stop closes the listener, all active connections and the queries to Consul.
+func (s *CacheServer) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.stopCh:
+		return
+	default:
+	}
+
+	log.Printf("[INFO] (cache) stopping")
+	close(s.stopCh)
+	if s.listener != nil {
+		s.server.Close()
+	}
+	for key, e := range s.entries {
+		e.cancel()
+		delete(s.entries, key)
+	}
+	s.clients.Stop()
+}
+
+// Size returns the number of queries the cache server is watching.
+func (s *CacheServer) Size() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.entries)
+}
+
+// handle serves a request of a client. GET requests are served from the cache,
+// and any other request is passed on to Consul.
+func (s *CacheServer) handle(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		s.proxy(w, req)
+		return
+	}
+
+	query := req.URL.Query()
+
+	var index uint64
+	if v := query.Get("index"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid index %q", v), http.StatusBadRequest)
+			return
+		}
+		index = i
+	}
+
+	wait := cacheDefaultWait
+	if v := query.Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid wait %q", v), http.StatusBadRequest)
+			return
+		}
+		wait = d
+	}
+	if wait > cacheMaxWait {
+		wait = cacheMaxWait
+	}
+
+	query.Del("index")
+	query.Del("wait")
+
+	e := s.acquire(req.URL.Path, query, req.Header.Get("X-Consul-Token"))
+	defer s.release(e)
+
+	resp := e.wait(index, wait, req.Context().Done())
+	if resp == nil {
+		return
+	}
+
+	for k, v := range resp.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// proxy passes the request on to Consul and copies the response back.
+func (s *CacheServer) proxy(w http.ResponseWriter, req *http.Request) {
+	upstream, err := http.NewRequest(req.Method, "", req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	upstream.URL.Path = req.URL.Path
+	upstream.URL.RawQuery = req.URL.RawQuery
+	upstream.Header = cloneHeader(req.Header)
+	upstream.ContentLength = req.ContentLength
+
+	resp, err := s.clients.ConsulDo(upstream.WithContext(req.Context()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, v := range cacheHeader(resp.Header) {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// acquire returns the entry for the given query, and starts watching it if it
+// is not watched yet. The entry must be released once the client is done.
+func (s *CacheServer) acquire(path string, query url.Values, token string) *cacheEntry {
+	key := token + "\x00" + path + "?" + query.Encode()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, ok := s.entries[key]
+	if ok {
+		e.lock.Lock()
+		if e.done {
+			ok = false
+		} else {
+			e.clients++
+		}
+		e.lock.Unlock()
+	}
+
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		e = &cacheEntry{
+			path:     path,
+			query:    query,
+			token:    token,
+			updateCh: make(chan struct{}),
+			clients:  1,
+			cancel:   cancel,
+		}
+		s.entries[key] = e
+
+		log.Printf("[DEBUG] (cache) watching %s", path)
+		go s.watch(ctx, key, e)
+	}
+
+	return e
+}
+
+// release records that a client is done with the entry.
+func (s *CacheServer) release(e *cacheEntry) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.clients--
+	e.lastUsed = time.Now()
+}
+
+// remove stops watching the entry with the given key, if it is still the given
+// entry.
+func (s *CacheServer) remove(key string, e *cacheEntry) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.entries[key] == e {
+		delete(s.entries, key)
+	}
+	e.cancel()
+}
+
+// expire periodically stops watching the entries without clients.
+func (s *CacheServer) expire() {
+	ticker := time.NewTicker(cacheIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.stopCh:
+			return
+		}
+
+		s.lock.Lock()
+		for key, e := range s.entries {
+			e.lock.Lock()
+			idle := e.clients == 0 && time.Since(e.lastUsed) > cacheIdleTimeout
+			e.lock.Unlock()
+
+			if idle {
+				log.Printf("[DEBUG] (cache) no longer watching %s", e.path)
+				e.cancel()
+				delete(s.entries, key)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+// watch queries Consul for the entry until it is cancelled. Each response with
+// a new index is served to the clients. A response without an index, from an
+// endpoint which does not support blocking queries, or an error ends the
+// watch, and is the final response of the entry.
+func (s *CacheServer) watch(ctx context.Context, key string, e *cacheEntry) {
+	var index uint64
+	for {
+		start := time.Now()
+
+		resp, err := s.fetch(ctx, e, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("[WARN] (cache) %s: %s", e.path, err)
+			resp = &cacheResponse{
+				status: http.StatusBadGateway,
+				header: http.Header{"Content-Type": []string{"text/plain"}},
+				body:   []byte(err.Error()),
+			}
+		}
+
+		if err != nil || resp.header.Get("X-Consul-Index") == "" {
+			e.finish(resp)
+			s.remove(key, e)
+			return
+		}
+
+		if index == 0 || resp.index != index {
+			e.update(resp)
+			index = resp.index
+			continue
+		}
+
+		if d := cacheMinInterval - time.Since(start); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// fetch queries Consul for the entry, blocking until the data changes from the
+// given index, if any.
+func (s *CacheServer) fetch(ctx context.Context, e *cacheEntry, index uint64) (*cacheResponse, error) {
+	query := make(url.Values, len(e.query)+2)
+	for k, v := range e.query {
+		query[k] = v
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%dms", cacheUpstreamWait/time.Millisecond))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Path = e.path
+	req.URL.RawQuery = query.Encode()
+	if e.token != "" {
+		req.Header.Set("X-Consul-Token", e.token)
+	}
+
+	resp, err := s.clients.ConsulDo(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &cacheResponse{
+		status: resp.StatusCode,
+		header: cacheHeader(resp.Header),
+		body:   body,
+	}
+	if v := resp.Header.Get("X-Consul-Index"); v != "" {
+		if result.index, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid index %q", v)
+		}
+	}
+
+	return result, nil
+}
+
+// update replaces the response of the entry and wakes up the waiting clients.
+func (e *cacheEntry) update(resp *cacheResponse) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.response = resp
+	close(e.updateCh)
+	e.updateCh = make(chan struct{})
+}
+
+// finish sets the final response of the entry and wakes up the waiting
+// clients.
+func (e *cacheEntry) finish(resp *cacheResponse) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.response = resp
+	e.done = true
+	close(e.updateCh)
+	e.updateCh = make(chan struct{})
+}
+
+// wait returns the response for a client which has seen the given index. Like
+// a blocking query, it waits until the index changes or the wait time passes,
+// and always waits for the first response. It returns nil if stopCh is closed
+// first.
+func (e *cacheEntry) wait(index uint64, wait time.Duration, stopCh <-chan struct{}) *cacheResponse {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	var expired bool
+	for {
+		e.lock.Lock()
+		resp, done, updateCh := e.response, e.done, e.updateCh
+		e.lock.Unlock()
+
+		if resp != nil && (done || expired || index == 0 || resp.index != index) {
+			return resp
+		}
+
+		select {
+		case <-updateCh:
+		case <-timer.C:
+			expired = true
+		case <-stopCh:
+			return nil
+		}
+	}
+}
+
+// cacheHeader returns a copy of the given response header without the
+// hop-by-hop headers.
+func cacheHeader(h http.Header) http.Header {
+	header := cloneHeader(h)
+	for _, k := range cacheHopHeaders {
+		header.Del(k)
+	}
+	return header
+}
+
+// cloneHeader returns a deep copy of the given header.
+func cloneHeader(h http.Header) http.Header {
+	header := make(http.Header, len(h))
+	for k, v := range h {
+		header[k] = append([]string(nil), v...)
+	}
+	return header
+}
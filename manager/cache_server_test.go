@@ -0,0 +1,252 @@
+package manager
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// testCacheConsul is a fake Consul which serves a single KV key with blocking
+// queries, and counts the queries it receives for each token.
+type testCacheConsul struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	index    uint64
+	value    string
+	changeCh chan struct{}
+	queries  map[string]int
+}
+
+func newTestCacheConsul() *testCacheConsul {
+	c := &testCacheConsul{
+		index:    1,
+		value:    "a",
+		changeCh: make(chan struct{}),
+		queries:  make(map[string]int),
+	}
+
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/foo":
+		case "/v1/agent/self":
+			fmt.Fprint(w, `{"Config": {"NodeName": "node1"}}`)
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		c.lock.Lock()
+		c.queries[r.Header.Get("X-Consul-Token")]++
+		c.lock.Unlock()
+
+		if r.Method == http.MethodPut {
+			body, _ := ioutil.ReadAll(r.Body)
+			c.set(string(body))
+			fmt.Fprint(w, "true")
+			return
+		}
+
+		index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+		c.lock.Lock()
+		changeCh := c.changeCh
+		current := c.index
+		c.lock.Unlock()
+
+		if index == current {
+			select {
+			case <-changeCh:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+		fmt.Fprintf(w, `[{"Key": "foo", "Value": "%s"}]`,
+			base64.StdEncoding.EncodeToString([]byte(c.value)))
+	}))
+
+	return c
+}
+
+func (c *testCacheConsul) set(value string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.index++
+	c.value = value
+	close(c.changeCh)
+	c.changeCh = make(chan struct{})
+}
+
+func (c *testCacheConsul) queryCount(token string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.queries[token]
+}
+
+func testCacheServer(t *testing.T, address string) (*CacheServer, *config.Config, func()) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.TestConfig(&config.Config{
+		Consul: config.String(strings.TrimPrefix(address, "http://")),
+		LocalCache: &config.LocalCacheConfig{
+			Path: config.String(filepath.Join(dir, "cache.sock")),
+		},
+	})
+
+	s, err := NewCacheServer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s, c, func() {
+		s.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func testCacheClient(t *testing.T, c *config.Config, token string) *consulapi.Client {
+	clients := dep.NewClientSet()
+	if err := createConsulClient(clients, c, token); err != nil {
+		t.Fatal(err)
+	}
+	return clients.Consul()
+}
+
+func TestCacheServer_shared(t *testing.T) {
+	t.Parallel()
+
+	consul := newTestCacheConsul()
+	defer consul.Close()
+
+	s, c, stop := testCacheServer(t, consul.URL)
+	defer stop()
+
+	fi, err := os.Stat(config.StringVal(c.LocalCache.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != cacheSocketPerms {
+		t.Errorf("\nexp: %#o\nact: %#o", cacheSocketPerms, mode)
+	}
+
+	// Every client gets the data, and then blocks on the same query.
+	var wg sync.WaitGroup
+	values := make([]string, 3)
+	for i := range values {
+		client := testCacheClient(t, c, "token")
+
+		_, meta, err := client.KV().List("foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.LastIndex != 1 {
+			t.Errorf("\nexp: %#v\nact: %#v", 1, meta.LastIndex)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			pairs, _, err := client.KV().List("foo", &consulapi.QueryOptions{
+				WaitIndex: meta.LastIndex,
+				WaitTime:  5 * time.Second,
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(pairs) == 1 {
+				values[i] = string(pairs[0].Value)
+			}
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	consul.set("b")
+	wg.Wait()
+
+	for _, v := range values {
+		if v != "b" {
+			t.Errorf("\nexp: %#v\nact: %#v", "b", v)
+		}
+	}
+
+	// One query for the data, one which returned with the change, and the one
+	// which may be blocking now, however many clients there are.
+	if n := consul.queryCount("token"); n > 3 {
+		t.Errorf("\nexp: at most %d queries\nact: %d", 3, n)
+	}
+
+	// Another token does not share the cached data.
+	if _, _, err := testCacheClient(t, c, "other").KV().List("foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := consul.queryCount("other"); n == 0 {
+		t.Errorf("expected a query with the other token")
+	}
+	if n := s.Size(); n != 2 {
+		t.Errorf("\nexp: %d entries\nact: %d", 2, n)
+	}
+}
+
+func TestCacheServer_passthrough(t *testing.T) {
+	t.Parallel()
+
+	consul := newTestCacheConsul()
+	defer consul.Close()
+
+	s, c, stop := testCacheServer(t, consul.URL)
+	defer stop()
+
+	client := testCacheClient(t, c, "token")
+
+	// Endpoints without blocking queries are not watched.
+	self, err := client.Agent().Self()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := self["Config"]["NodeName"]; name != "node1" {
+		t.Errorf("\nexp: %#v\nact: %#v", "node1", name)
+	}
+	if n := s.Size(); n != 0 {
+		t.Errorf("\nexp: %d entries\nact: %d", 0, n)
+	}
+
+	// Writes are passed on to Consul.
+	if _, err := client.KV().Put(&consulapi.KVPair{Key: "foo", Value: []byte("c")}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, _, err := client.KV().List("foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 1 || string(pairs[0].Value) != "c" {
+		t.Errorf("\nexp: %#v\nact: %#v", "c", pairs)
+	}
+}
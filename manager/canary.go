@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// canaryRetryInterval is how long to wait before checking the kill switch
+// again when Consul cannot be reached at the end of a bake.
+const canaryRetryInterval = 30 * time.Second
+
+// canaryHold is a change to the contents of a destination which is held
+// while it bakes on the canaries.
+type canaryHold struct {
+	sum   [sha256.Size]byte
+	since time.Time
+
+	// aborted is true if the kill switch existed when the bake ended. The
+	// change is not written until the contents change again.
+	aborted bool
+}
+
+// canary holds the changed contents of the templates on hosts which are not
+// canaries, until they have baked on the canaries for the bake duration. The
+// contents are keyed by destination, so holds survive a reload.
+type canary struct {
+	config  *config.CanaryConfig
+	clients *dep.ClientSet
+
+	// isCanary is true if the node name of this host matches the selector. It
+	// is resolved on the first render.
+	resolved bool
+	isCanary bool
+
+	// committed is the checksum of the contents last written to each
+	// destination, and held are the changes which are baking.
+	committed map[string][sha256.Size]byte
+	held      map[string]*canaryHold
+
+	// end is the earliest time a change held in the last run must be checked
+	// again, or zero if none was held.
+	end time.Time
+}
+
+// newCanary returns the canary render mode of the given configuration. It is
+// an error if the selector is not a valid glob.
+func newCanary(c *config.CanaryConfig, clients *dep.ClientSet) (*canary, error) {
+	selector := config.StringVal(c.Selector)
+	if _, err := filepath.Match(selector, ""); err != nil {
+		return nil, fmt.Errorf("canary: selector %q: %s", selector, err)
+	}
+
+	return &canary{
+		config:    c,
+		clients:   clients,
+		committed: make(map[string][sha256.Size]byte),
+		held:      make(map[string]*canaryHold),
+	}, nil
+}
+
+// nodeName returns the name matched against the selector: the configured
+// one, else the node name of the local Consul agent, else the hostname.
+func (c *canary) nodeName() string {
+	if name := config.StringVal(c.config.NodeName); name != "" {
+		return name
+	}
+	if c.clients != nil && c.clients.Consul() != nil {
+		name, err := c.clients.Consul().Agent().NodeName()
+		if err == nil && name != "" {
+			return name
+		}
+		log.Printf("[WARN] (runner) canary: failed to get the Consul node name, "+
+			"using the hostname: %v", err)
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// canary returns true if this host is a canary.
+func (c *canary) canary() bool {
+	if !c.resolved {
+		name := c.nodeName()
+		c.isCanary, _ = filepath.Match(config.StringVal(c.config.Selector), name)
+		c.resolved = true
+		if c.isCanary {
+			log.Printf("[INFO] (runner) canary: %q is a canary, writing changes right away", name)
+		} else {
+			log.Printf("[INFO] (runner) canary: %q is not a canary, baking changes for %s",
+				name, config.TimeDurationVal(c.config.Bake))
+		}
+	}
+	return c.isCanary
+}
+
+// hold returns the reason the given contents of the destination are held, or
+// false if they can be written at the given time. A change whose destination
+// was never written is written right away, so new hosts get their files.
+func (c *canary) hold(dest string, contents []byte, now time.Time) (string, bool) {
+	if c.canary() {
+		return "", false
+	}
+
+	sum := sha256.Sum256(contents)
+	committed, ok := c.committed[dest]
+	if !ok {
+		existing, err := ioutil.ReadFile(dest)
+		if err != nil {
+			return "", false
+		}
+		committed = sha256.Sum256(existing)
+		c.committed[dest] = committed
+	}
+	if sum == committed {
+		delete(c.held, dest)
+		return "", false
+	}
+
+	h, ok := c.held[dest]
+	if !ok || h.sum != sum {
+		h = &canaryHold{sum: sum, since: now}
+		c.held[dest] = h
+	}
+
+	killSwitch := config.StringVal(c.config.KillSwitchKey)
+	if h.aborted {
+		return fmt.Sprintf("canary change aborted by kill switch %q", killSwitch), true
+	}
+
+	if until := h.since.Add(config.TimeDurationVal(c.config.Bake)); now.Before(until) {
+		c.wait(until)
+		return fmt.Sprintf("canary bake until %s", until.Local().Format(time.RFC3339)), true
+	}
+
+	if killSwitch != "" {
+		pair, _, err := c.clients.Consul().KV().Get(killSwitch, nil)
+		if err != nil {
+			c.wait(now.Add(canaryRetryInterval))
+			return fmt.Sprintf("canary kill switch %q: %s", killSwitch, err), true
+		}
+		if pair != nil {
+			h.aborted = true
+			log.Printf("[WARN] (runner) canary: kill switch %q exists, aborting the change to %s",
+				killSwitch, dest)
+			return fmt.Sprintf("canary change aborted by kill switch %q", killSwitch), true
+		}
+	}
+
+	delete(c.held, dest)
+	return "", false
+}
+
+// commit records that the given contents were written to the destination.
+func (c *canary) commit(dest string, contents []byte) {
+	c.committed[dest] = sha256.Sum256(contents)
+	delete(c.held, dest)
+}
+
+// wait makes the runner check the held changes again at the given time, if
+// it is earlier than the current end.
+func (c *canary) wait(until time.Time) {
+	if c.end.IsZero() || until.Before(c.end) {
+		c.end = until
+	}
+}
@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestNewRunner_canary(t *testing.T) {
+	cases := []struct {
+		name     string
+		once     bool
+		selector string
+		enabled  bool
+		err      string
+	}{
+		{"enabled", false, "web-canary-*", true, ""},
+		{"once", true, "web-canary-*", false, ""},
+		{"bad_selector", false, "web-[", false, "canary: selector"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.TestConfig(&config.Config{
+				Canary: &config.CanaryConfig{
+					Selector: config.String(tc.selector),
+				},
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("test"),
+						Destination: config.String("/tmp/out"),
+					},
+				},
+			})
+
+			r, err := NewRunner(c, false, tc.once)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (r.canary != nil) != tc.enabled {
+				t.Errorf("expected the canary render mode to be enabled %t", tc.enabled)
+			}
+		})
+	}
+}
+
+func testCanary(t *testing.T, nodeName string) *canary {
+	c := &config.CanaryConfig{
+		Bake:     config.TimeDuration(10 * time.Minute),
+		NodeName: config.String(nodeName),
+		Selector: config.String("web-canary-*"),
+	}
+	c.Finalize()
+
+	ca, err := newCanary(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func TestCanary_hold(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+
+	t.Run("canary", func(t *testing.T) {
+		c := testCanary(t, "web-canary-1")
+		dest := filepath.Join(dir, "canary")
+		if err := ioutil.WriteFile(dest, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if reason, ok := c.hold(dest, []byte("new"), now); ok {
+			t.Errorf("expected a canary to write right away, got %q", reason)
+		}
+	})
+
+	t.Run("new_destination", func(t *testing.T) {
+		c := testCanary(t, "web-1")
+		dest := filepath.Join(dir, "missing")
+		if reason, ok := c.hold(dest, []byte("new"), now); ok {
+			t.Errorf("expected a new destination to be written, got %q", reason)
+		}
+	})
+
+	t.Run("bake", func(t *testing.T) {
+		c := testCanary(t, "web-1")
+		dest := filepath.Join(dir, "bake")
+		if err := ioutil.WriteFile(dest, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := c.hold(dest, []byte("old"), now); ok {
+			t.Fatal("expected unchanged contents to be written")
+		}
+
+		reason, ok := c.hold(dest, []byte("new"), now)
+		if !ok || !strings.HasPrefix(reason, "canary bake until") {
+			t.Fatalf("expected the change to bake, got %q", reason)
+		}
+		if exp := now.Add(10 * time.Minute); !c.end.Equal(exp) {
+			t.Errorf("expected the bake to end at %s, got %s", exp, c.end)
+		}
+
+		// New contents restart the bake.
+		later := now.Add(8 * time.Minute)
+		if _, ok := c.hold(dest, []byte("newer"), later); !ok {
+			t.Fatal("expected the new contents to bake")
+		}
+		if _, ok := c.hold(dest, []byte("newer"), now.Add(12*time.Minute)); !ok {
+			t.Fatal("expected the new contents to still bake")
+		}
+
+		if _, ok := c.hold(dest, []byte("newer"), later.Add(10*time.Minute)); ok {
+			t.Fatal("expected the baked contents to be written")
+		}
+		c.commit(dest, []byte("newer"))
+		if _, ok := c.hold(dest, []byte("newer"), later.Add(11*time.Minute)); ok {
+			t.Fatal("expected the committed contents to be written")
+		}
+	})
+}
+
+func TestCanary_killSwitch(t *testing.T) {
+	t.Parallel()
+
+	consul := testConsulServer(t)
+	defer consul.Stop()
+
+	c := config.TestConfig(&config.Config{
+		Canary: &config.CanaryConfig{
+			Bake:          config.TimeDuration(10 * time.Minute),
+			KillSwitchKey: config.String("canary/abort"),
+			NodeName:      config.String("web-1"),
+			Selector:      config.String("web-canary-*"),
+		},
+		Consul: config.String(consul.HTTPAddr),
+	})
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	clients := r.clients
+
+	ca, err := newCanary(c.Canary, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(dest, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, ok := ca.hold(dest, []byte("new"), now); !ok {
+		t.Fatal("expected the change to bake")
+	}
+
+	if _, err := clients.Consul().KV().Put(&consulapi.KVPair{Key: "canary/abort"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	reason, ok := ca.hold(dest, []byte("new"), now.Add(10*time.Minute))
+	if !ok || !strings.Contains(reason, "aborted") {
+		t.Fatalf("expected the change to be aborted, got %q", reason)
+	}
+
+	// The change stays aborted, but new contents bake again.
+	if _, err := clients.Consul().KV().Delete("canary/abort", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ca.hold(dest, []byte("new"), now.Add(20*time.Minute)); !ok {
+		t.Fatal("expected the aborted change to stay held")
+	}
+	if _, ok := ca.hold(dest, []byte("newer"), now.Add(20*time.Minute)); !ok {
+		t.Fatal("expected the new contents to bake")
+	}
+	if _, ok := ca.hold(dest, []byte("newer"), now.Add(30*time.Minute)); ok {
+		t.Fatal("expected the baked contents to be written")
+	}
+}
@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/mattn/go-shellwords"
+	"github.com/pkg/errors"
+)
+
+// childEscalation verifies the child process becomes ready after a reload and
+// escalates through the configured steps when it does not.
+type childEscalation struct {
+	config *config.ExecEscalationConfig
+
+	// check runs the readiness check once, returning nil if the child process
+	// is ready.
+	check func() error
+
+	// stepCh is where escalation steps are sent. The runner owns the child's
+	// lifecycle, so the escalation never restarts the process itself.
+	stepCh chan<- string
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// newChildEscalation creates a new escalation chain for a reload of the child
+// process. The readiness check runs with the given environment.
+func newChildEscalation(conf *config.ExecEscalationConfig, env []string,
+	stepCh chan<- string) *childEscalation {
+	command := config.StringVal(conf.Check)
+	timeout := config.TimeDurationVal(conf.Interval)
+	if timeout <= 0 {
+		timeout = config.DefaultExecEscalationInterval
+	}
+
+	return &childEscalation{
+		config: conf,
+		check: func() error {
+			return runCheck(command, env, timeout)
+		},
+		stepCh: stepCh,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// run waits for the child process to become ready, taking the next step each
+// time it is not ready within the timeout, until the child is ready, the steps
+// are exhausted, the escalation is stopped, or doneCh is closed. This function
+// blocks and should be run in a goroutine.
+func (e *childEscalation) run(doneCh <-chan struct{}) {
+	timeout := config.TimeDurationVal(e.config.Timeout)
+
+	for i := 0; ; i++ {
+		ready, ok := e.waitReady(doneCh)
+		if !ok {
+			return
+		}
+		if ready {
+			log.Printf("[DEBUG] (runner) child process is ready")
+			return
+		}
+
+		if i >= len(e.config.Steps) {
+			log.Printf("[ERR] (runner) child process is not ready after %s and "+
+				"there are no escalation steps left", timeout)
+			return
+		}
+
+		step := e.config.Steps[i]
+		log.Printf("[WARN] (runner) child process is not ready after %s, "+
+			"escalating to %s", timeout, step)
+		select {
+		case e.stepCh <- step:
+		case <-e.stopCh:
+			return
+		case <-doneCh:
+			return
+		}
+	}
+}
+
+// waitReady runs the readiness check at the configured interval until it
+// succeeds or the timeout elapses. The second return value is false if the
+// escalation was stopped while waiting.
+func (e *childEscalation) waitReady(doneCh <-chan struct{}) (bool, bool) {
+	interval := config.TimeDurationVal(e.config.Interval)
+	if interval <= 0 {
+		interval = config.DefaultExecEscalationInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	timeout := time.NewTimer(config.TimeDurationVal(e.config.Timeout))
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := e.check()
+			if err == nil {
+				return true, true
+			}
+			log.Printf("[DEBUG] (runner) child process is not ready: %s", err)
+		case <-timeout.C:
+			return false, true
+		case <-e.stopCh:
+			return false, false
+		case <-doneCh:
+			return false, false
+		}
+	}
+}
+
+// Stop stops the escalation. It is safe to call more than once.
+func (e *childEscalation) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+}
+
+// runCheck runs the given readiness check command, killing it if it does not
+// exit within the timeout.
+func runCheck(command string, env []string, timeout time.Duration) error {
+	p := shellwords.NewParser()
+	p.ParseEnv = true
+	args, err := p.Parse(command)
+	if err != nil {
+		return errors.Wrap(err, "failed parsing check")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("missing check")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("check did not finish within %s", timeout)
+	}
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestChildEscalation_run(t *testing.T) {
+	t.Parallel()
+
+	conf := &config.ExecEscalationConfig{
+		Check:    config.String("check"),
+		Interval: config.TimeDuration(5 * time.Millisecond),
+		Steps: []string{
+			config.ExecEscalationStepRestart,
+			config.ExecEscalationStepKill,
+		},
+		Timeout: config.TimeDuration(20 * time.Millisecond),
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		stepCh := make(chan string, 2)
+		e := newChildEscalation(conf, nil, stepCh)
+		e.check = func() error { return nil }
+
+		e.run(make(chan struct{}))
+		if len(stepCh) != 0 {
+			t.Errorf("expected no steps, got %d", len(stepCh))
+		}
+	})
+
+	t.Run("escalates", func(t *testing.T) {
+		stepCh := make(chan string, 2)
+		e := newChildEscalation(conf, nil, stepCh)
+		e.check = func() error { return errors.New("not ready") }
+
+		e.run(make(chan struct{}))
+		close(stepCh)
+
+		var steps []string
+		for step := range stepCh {
+			steps = append(steps, step)
+		}
+		if !reflect.DeepEqual(conf.Steps, steps) {
+			t.Errorf("\nexp: %#v\nact: %#v", conf.Steps, steps)
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		stepCh := make(chan string)
+		e := newChildEscalation(conf, nil, stepCh)
+		e.check = func() error { return errors.New("not ready") }
+
+		doneCh := make(chan struct{})
+		go func() {
+			e.run(make(chan struct{}))
+			close(doneCh)
+		}()
+
+		e.Stop()
+		select {
+		case <-doneCh:
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestRunCheck(t *testing.T) {
+	t.Parallel()
+
+	if err := runCheck("true", nil, time.Second); err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if err := runCheck("false", nil, time.Second); err == nil {
+		t.Error("expected error")
+	}
+
+	if err := runCheck("sleep 5", nil, 10*time.Millisecond); err == nil {
+		t.Error("expected timeout error")
+	}
+}
@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	// childEventBufferSize is the number of child events buffered for the
+	// embedding program. Events are dropped if it falls behind.
+	childEventBufferSize = 64
+)
+
+// The types of the events in the lifecycle of the child process.
+const (
+	ChildEventStarted   = "started"
+	ChildEventReloaded  = "reloaded"
+	ChildEventExited    = "exited"
+	ChildEventRestarted = "restarted"
+	ChildEventKilled    = "killed"
+)
+
+// The reasons the child process is restarted.
+const (
+	ChildRestartReload     = "reload"
+	ChildRestartMonitor    = "monitor"
+	ChildRestartEscalation = "escalation"
+)
+
+// ChildEvent is an event in the lifecycle of the child process of the exec
+// mode, as delivered by Runner.ChildEvents.
+type ChildEvent struct {
+	Time time.Time
+	Type string
+
+	// Pid is the PID of the child process after the event, or of the process
+	// which exited or was killed.
+	Pid int
+
+	// ExitCode is the exit code of the child process, for exited events.
+	ExitCode int
+
+	// Reason is why the child process was restarted, for restarted events.
+	Reason string
+}
+
+// ChildEvents returns a channel that receives the events in the lifecycle of
+// the child process: it started, was sent the reload signal, exited with an
+// exit code, was restarted, or was force-killed because it did not exit within
+// the kill timeout. Exits caused by a restart or by stopping the runner are
+// not sent as exited events. If the channel buffer is full, the event is
+// dropped.
+func (r *Runner) ChildEvents() <-chan *ChildEvent {
+	return r.childEventCh
+}
+
+// childEvent delivers the given event without blocking.
+func (r *Runner) childEvent(event *ChildEvent) {
+	event.Time = time.Now().UTC()
+	if event.Type == ChildEventRestarted {
+		r.metrics.childRestarted()
+	}
+	select {
+	case r.childEventCh <- event:
+	default:
+		log.Printf("[TRACE] (runner) dropping child %s event", event.Type)
+	}
+}
+
+// childKilled delivers a killed event for the child process with the given
+// PID, which did not exit within the kill timeout.
+func (r *Runner) childKilled(pid int) {
+	r.childEvent(&ChildEvent{Type: ChildEventKilled, Pid: pid})
+}
+
+// childPid returns the PID of the child process, if any.
+func (r *Runner) childPid() int {
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+
+	if r.child == nil {
+		return 0
+	}
+	return r.child.Pid()
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_ChildEvents(t *testing.T) {
+	t.Parallel()
+
+	newRunner := func(t *testing.T, exec *config.ExecConfig, dest string) *Runner {
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: exec,
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`test`),
+					Destination: config.String(dest),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	next := func(t *testing.T, r *Runner) *ChildEvent {
+		select {
+		case event := <-r.ChildEvents():
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+		return nil
+	}
+
+	t.Run("exited", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		r := newRunner(t, &config.ExecConfig{
+			Command: config.String(`sh -c "sleep 0.2; exit 3"`),
+		}, out.Name())
+		go r.Start()
+		defer r.Stop()
+
+		started := next(t, r)
+		if started.Type != ChildEventStarted || started.Pid == 0 {
+			t.Fatalf("expected a started event, got %#v", started)
+		}
+
+		exited := next(t, r)
+		if exited.Type != ChildEventExited || exited.ExitCode != 3 || exited.Pid != started.Pid {
+			t.Fatalf("expected an exited event with code 3, got %#v", exited)
+		}
+
+		select {
+		case err := <-r.ErrCh:
+			if _, ok := err.(*ErrChildDied); !ok {
+				t.Fatalf("expected the child to die, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("killed", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		r := newRunner(t, &config.ExecConfig{
+			Command:     config.String(`sh -c "trap '' TERM; exec sleep 30"`),
+			KillSignal:  config.Signal(syscall.SIGTERM),
+			KillTimeout: config.TimeDuration(100 * time.Millisecond),
+		}, out.Name())
+		go r.Start()
+
+		started := next(t, r)
+		if started.Type != ChildEventStarted {
+			t.Fatalf("expected a started event, got %#v", started)
+		}
+
+		// Let the shell ignore the kill signal before stopping it.
+		time.Sleep(200 * time.Millisecond)
+		r.Stop()
+
+		killed := next(t, r)
+		if killed.Type != ChildEventKilled || killed.Pid != started.Pid {
+			t.Fatalf("expected a killed event, got %#v", killed)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+// childMonitor periodically samples the resource usage of the child process
+// and takes the configured action when a threshold is exceeded.
+type childMonitor struct {
+	child  *child.Child
+	config *config.ExecMonitorConfig
+
+	// restartCh is where restart requests are sent. The runner owns the child's
+	// lifecycle, so the monitor never restarts the process itself.
+	restartCh chan<- struct{}
+
+	// usageLock protects the latest sample.
+	usageLock sync.RWMutex
+	usage     *child.Usage
+}
+
+// newChildMonitor creates a new monitor for the given child process.
+func newChildMonitor(c *child.Child, conf *config.ExecMonitorConfig,
+	restartCh chan<- struct{}) *childMonitor {
+	return &childMonitor{
+		child:     c,
+		config:    conf,
+		restartCh: restartCh,
+	}
+}
+
+// Usage returns the most recent sample, or nil if no sample has been taken.
+func (m *childMonitor) Usage() *child.Usage {
+	m.usageLock.RLock()
+	defer m.usageLock.RUnlock()
+	return m.usage
+}
+
+// run samples the child process at the configured interval until doneCh is
+// closed. This function blocks and should be run in a goroutine.
+func (m *childMonitor) run(doneCh <-chan struct{}) {
+	interval := config.TimeDurationVal(m.config.Interval)
+	if interval <= 0 {
+		interval = config.DefaultExecMonitorInterval
+	}
+	log.Printf("[DEBUG] (runner) monitoring child process every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.sample(); err != nil {
+				if err == child.ErrUsageUnsupported {
+					log.Printf("[WARN] (runner) disabling child process monitor: %s", err)
+					return
+				}
+				log.Printf("[ERR] (runner) failed to sample child process: %s", err)
+			}
+		case <-doneCh:
+			return
+		}
+	}
+}
+
+// sample takes a single sample and acts on any exceeded thresholds.
+func (m *childMonitor) sample() error {
+	u, err := m.child.Usage()
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return nil
+	}
+
+	m.usageLock.Lock()
+	m.usage = u
+	m.usageLock.Unlock()
+
+	log.Printf("[TRACE] (runner) child process %d usage: cpu=%.2f%% rss=%d fds=%d",
+		u.Pid, u.CPUPercent, u.RSS, u.OpenFDs)
+
+	exceeded := m.exceeded(u)
+	if len(exceeded) == 0 {
+		return nil
+	}
+
+	for _, e := range exceeded {
+		log.Printf("[WARN] (runner) child process %d exceeded %s", u.Pid, e)
+	}
+
+	switch config.StringVal(m.config.Action) {
+	case config.ExecMonitorActionSignal:
+		s := config.SignalVal(m.config.Signal)
+		if err := m.child.Signal(s); err != nil {
+			return err
+		}
+	case config.ExecMonitorActionRestart:
+		select {
+		case m.restartCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// exceeded returns a human-readable description of each threshold the sample
+// exceeds.
+func (m *childMonitor) exceeded(u *child.Usage) []string {
+	var result []string
+
+	if max := config.Float64Val(m.config.MaxCPUPercent); max > 0 && u.CPUPercent > max {
+		result = append(result, fmt.Sprintf("max_cpu_percent (%.2f > %.2f)", u.CPUPercent, max))
+	}
+
+	if max := config.Uint64Val(m.config.MaxRSS); max > 0 && u.RSS > max {
+		result = append(result, fmt.Sprintf("max_rss (%d > %d)", u.RSS, max))
+	}
+
+	if max := config.IntVal(m.config.MaxOpenFiles); max > 0 && u.OpenFDs > max {
+		result = append(result, fmt.Sprintf("max_open_files (%d > %d)", u.OpenFDs, max))
+	}
+
+	return result
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestChildMonitor_exceeded(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    *config.ExecMonitorConfig
+		u    *child.Usage
+		e    []string
+	}{
+		{
+			"disabled_thresholds",
+			&config.ExecMonitorConfig{},
+			&child.Usage{CPUPercent: 100, RSS: 1024, OpenFDs: 10},
+			nil,
+		},
+		{
+			"under",
+			&config.ExecMonitorConfig{
+				MaxCPUPercent: config.Float64(50),
+				MaxOpenFiles:  config.Int(10),
+				MaxRSS:        config.Uint64(1024),
+			},
+			&child.Usage{CPUPercent: 50, RSS: 1024, OpenFDs: 10},
+			nil,
+		},
+		{
+			"over",
+			&config.ExecMonitorConfig{
+				MaxCPUPercent: config.Float64(50),
+				MaxOpenFiles:  config.Int(10),
+				MaxRSS:        config.Uint64(1024),
+			},
+			&child.Usage{CPUPercent: 75, RSS: 2048, OpenFDs: 11},
+			[]string{
+				"max_cpu_percent (75.00 > 50.00)",
+				"max_rss (2048 > 1024)",
+				"max_open_files (11 > 10)",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			m := newChildMonitor(nil, tc.c, nil)
+			act := m.exceeded(tc.u)
+			if !reflect.DeepEqual(tc.e, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, act)
+			}
+		})
+	}
+}
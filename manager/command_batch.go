@@ -0,0 +1,243 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// commandBatch is the commands and the reload of the child process queued by
+// one or more runs. Outside of once mode, the event loop hands the batches to
+// the command worker, so it keeps receiving data while the commands wait for
+// their turn or run.
+type commandBatch struct {
+	commands []*queuedCommand
+
+	// reload is true if the child process is reloaded after the commands.
+	reload bool
+
+	// reports are the reports of the runs which queued the batch, emitted once
+	// the batch ran.
+	reports []*RunReport
+}
+
+// queuedCommand is the command of a template queued in a batch.
+type queuedCommand struct {
+	tmpl *config.TemplateConfig
+
+	// tmplIDs are the IDs of the templates which triggered the command, whose
+	// render events record its result.
+	tmplIDs []string
+
+	// changed are the dependencies which changed for the command.
+	changed []string
+
+	// readyCheck is the ready check of the process started by the command, if
+	// any.
+	readyCheck *readyCheck
+
+	// report is the report of the run which queued the command.
+	report *RunReport
+}
+
+// empty returns true if the batch has nothing to run.
+func (b *commandBatch) empty() bool {
+	return len(b.commands) == 0 && !b.reload
+}
+
+// find returns the queued command which runs the same command as the given
+// template, if any.
+func (b *commandBatch) find(t *config.TemplateConfig) *queuedCommand {
+	for _, c := range b.commands {
+		if findCommand(t, []*config.TemplateConfig{c.tmpl}) != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// merge adds the commands of a later batch which did not run yet. Commands
+// which are already queued run once, for the templates and the changes of
+// both.
+func (b *commandBatch) merge(next *commandBatch) {
+	for _, c := range next.commands {
+		if existing := b.find(c.tmpl); existing != nil {
+			existing.tmplIDs = appendUnique(existing.tmplIDs, c.tmplIDs...)
+			existing.changed = appendUnique(existing.changed, c.changed...)
+			continue
+		}
+		b.commands = append(b.commands, c)
+	}
+	b.reload = b.reload || next.reload
+	b.reports = append(b.reports, next.reports...)
+}
+
+// queueCommands hands the given batch to the command worker. If the worker
+// has not run the previous batch yet, the batches are merged. A batch with
+// nothing to run while the worker is idle only emits its report.
+func (r *Runner) queueCommands(b *commandBatch) {
+	r.commandBatchLock.Lock()
+	if r.commandBatch == nil && !r.commandsBusy && b.empty() {
+		r.commandBatchLock.Unlock()
+		r.runCommands(b)
+		return
+	}
+	if r.commandBatch == nil {
+		r.commandBatch = b
+	} else {
+		r.commandBatch.merge(b)
+	}
+	r.commandBatchLock.Unlock()
+
+	select {
+	case r.commandBatchCh <- struct{}{}:
+	default:
+	}
+}
+
+// runCommandBatches runs the queued batches one at a time until the runner is
+// stopped. The errors of a batch are sent to the event loop, which stops the
+// runner, as if the run which queued it had failed.
+func (r *Runner) runCommandBatches() {
+	for {
+		select {
+		case <-r.commandBatchCh:
+		case <-r.DoneCh:
+			return
+		}
+
+		r.commandBatchLock.Lock()
+		b := r.commandBatch
+		r.commandBatch = nil
+		r.commandsBusy = b != nil
+		r.commandBatchLock.Unlock()
+
+		if b == nil {
+			continue
+		}
+
+		err := r.runCommands(b)
+
+		r.commandBatchLock.Lock()
+		r.commandsBusy = false
+		r.commandBatchLock.Unlock()
+
+		if err != nil {
+			select {
+			case r.commandErrCh <- err:
+			case <-r.DoneCh:
+			}
+			return
+		}
+	}
+}
+
+// runCommands executes each command of the batch in sequence, then reloads
+// the child process if needed, collecting any errors that occur - this
+// ensures all commands execute at least once. Commands wait for their turn if
+// the number of running commands is limited, or if reloads are coordinated.
+func (r *Runner) runCommands(b *commandBatch) error {
+	// If we are coordinating reloads, wait for our turn before running any
+	// commands or reloading the child process.
+	if r.coordinator != nil && !b.empty() {
+		release := r.coordinator.Acquire()
+		defer release()
+	}
+
+	var errs []error
+	for _, qc := range b.commands {
+		t := qc.tmpl
+
+		// Supervised processes run for as long as the runner, so they do not
+		// take a slot of the command queue.
+		if config.BoolVal(t.Exec.Supervise) {
+			if err := r.superviseTemplate(qc.tmplIDs[0], t, qc.changed); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		release, ok := r.commands.acquire(t, r.DoneCh)
+		if !ok {
+			break
+		}
+
+		command := config.StringVal(t.Exec.Command)
+		templateLogf(t, "INFO", "executing command %q from %s", command, t.Display())
+		env := t.Exec.Env.Copy()
+		custom := append(r.childEnv(), changedDepsEnv(qc.changed))
+		env.Custom = append(custom, env.Custom...)
+		capture := newCommandCapture(command)
+		c, err := spawnChild(&spawnChildInput{
+			Stdin:        r.inStream,
+			Stdout:       io.MultiWriter(r.outStream, capture.stdout),
+			Stderr:       io.MultiWriter(r.errStream, capture.stderr),
+			Command:      command,
+			Env:          env.Env(),
+			Timeout:      config.TimeDurationVal(t.Exec.Timeout),
+			ReloadSignal: config.SignalVal(t.Exec.ReloadSignal),
+			KillSignal:   config.SignalVal(t.Exec.KillSignal),
+			KillTimeout:  config.TimeDurationVal(t.Exec.KillTimeout),
+			Splay:        config.TimeDurationVal(t.Exec.Splay),
+		})
+		if qc.readyCheck != nil {
+			qc.readyCheck.reset()
+		}
+
+		// Commands without a timeout may still be running, so their result is
+		// recorded again once they exit.
+		tmplIDs := qc.tmplIDs
+		running := c != nil && config.TimeDurationVal(t.Exec.Timeout) == 0
+		release(c, func(code int) {
+			r.markCommand(tmplIDs, capture.result(false, code, nil))
+		})
+		result := capture.result(running, 0, err)
+		r.markCommand(tmplIDs, result)
+		qc.report.addCommand(t, result)
+		if err != nil {
+			s := fmt.Sprintf("failed to execute command %q from %s", command, t.Display())
+			errs = append(errs, errors.Wrap(err, s))
+		}
+	}
+
+	// If we got this far and still have a child process, we need to send the
+	// reload signal to the child process.
+	if b.reload {
+		r.childLock.RLock()
+		if r.child == nil {
+			log.Printf("[DEBUG] (runner) child process exited, skipping reload")
+		} else if err := r.child.Reload(); err != nil {
+			errs = append(errs, err)
+		} else {
+			r.escalateReload()
+			if config.SignalVal(r.config.Exec.ReloadSignal) == nil {
+				// Without a reload signal, the child process is restarted.
+				r.childEvent(&ChildEvent{Type: ChildEventRestarted, Pid: r.child.Pid(),
+					Reason: ChildRestartReload})
+			} else {
+				r.childEvent(&ChildEvent{Type: ChildEventReloaded, Pid: r.child.Pid()})
+			}
+		}
+		r.childLock.RUnlock()
+	}
+
+	for _, report := range b.reports {
+		r.emitReport(report)
+	}
+
+	// If any errors were returned, convert them to an ErrorList for human
+	// readability.
+	if len(errs) != 0 {
+		var result *multierror.Error
+		for _, err := range errs {
+			result = multierror.Append(result, err)
+		}
+		return result.ErrorOrNil()
+	}
+
+	return nil
+}
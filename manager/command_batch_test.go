@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_queueCommands(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	c := config.TestConfig(&config.Config{
+		MaxConcurrentCommands: config.Int(1),
+	})
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	r.outStream, r.errStream = ioutil.Discard, ioutil.Discard
+	go r.runCommandBatches()
+
+	// Hold the only slot of the command queue.
+	release, ok := r.commands.acquire(testCommandTemplate("hold", false), nil)
+	if !ok {
+		t.Fatal("expected to hold the slot")
+	}
+
+	tmpl := testCommandTemplate(`sh -c "echo run >> `+out+`"`, false)
+	tmpl.Exec.Timeout = config.TimeDuration(5 * time.Second)
+	batch := func(id string) *commandBatch {
+		return &commandBatch{
+			commands: []*queuedCommand{{tmpl: tmpl, tmplIDs: []string{id}}},
+		}
+	}
+
+	// Queueing does not wait for the command to run. The worker waits for the
+	// slot with the first batch, and the commands queued meanwhile run once
+	// for both templates.
+	queuedCh := make(chan struct{})
+	go func() {
+		r.queueCommands(batch("a"))
+		time.Sleep(100 * time.Millisecond)
+		r.queueCommands(batch("b"))
+		r.queueCommands(batch("c"))
+		close(queuedCh)
+	}()
+	select {
+	case <-queuedCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected queueing the commands not to wait for the slot")
+	}
+
+	release(nil, nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, ran := r.RenderEvents(), 0
+		for _, id := range []string{"a", "b", "c"} {
+			if e := events[id]; e != nil && e.LastCommand != nil {
+				ran++
+			}
+		}
+		if ran == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the command to run for all templates")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	contents, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "run\nrun\n", string(contents); exp != act {
+		t.Errorf("expected %q, got %q", exp, act)
+	}
+}
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/pkg/errors"
+)
+
+const (
+	// commandOutputLimit is the number of bytes of the standard output and of
+	// the standard error of a command which are kept in its result. Only the
+	// last bytes are kept, which are the most likely to explain a failure.
+	commandOutputLimit = 4 * 1024
+)
+
+// CommandResult is the outcome of a command executed after a template
+// rendered, including the end of what it printed.
+type CommandResult struct {
+	Command string    `json:"command"`
+	Time    time.Time `json:"time"`
+
+	// Running is true if the runner does not wait for the command, because it
+	// has no timeout, and it had not exited yet. ExitCode is only set once it
+	// has exited.
+	Running bool `json:"running,omitempty"`
+
+	// ExitCode is the exit code of the command, or -1 if it did not exit on its
+	// own, such as on a timeout.
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+
+	// Stdout and Stderr are the last commandOutputLimit bytes of the output of
+	// the command. Truncated is true if either was cut.
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// outputBuffer is a writer which keeps the last bytes written to it, up to a
+// limit. It is safe for concurrent use, since a command may still be writing
+// while its result is read.
+type outputBuffer struct {
+	lock      sync.Mutex
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+// newOutputBuffer creates a buffer which keeps the last limit bytes.
+func newOutputBuffer(limit int) *outputBuffer {
+	return &outputBuffer{limit: limit}
+}
+
+// Write implements io.Writer. It never fails, so it does not interrupt the
+// other writers of the output.
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if extra := len(b.buf) - b.limit; extra > 0 {
+		b.buf = append(b.buf[:0], b.buf[extra:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// contents returns the bytes kept in the buffer and whether earlier bytes were
+// dropped.
+func (b *outputBuffer) contents() (string, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return string(b.buf), b.truncated
+}
+
+// commandCapture captures the output of a single command.
+type commandCapture struct {
+	command string
+	stdout  *outputBuffer
+	stderr  *outputBuffer
+}
+
+// newCommandCapture creates a capture for the given command.
+func newCommandCapture(command string) *commandCapture {
+	return &commandCapture{
+		command: command,
+		stdout:  newOutputBuffer(commandOutputLimit),
+		stderr:  newOutputBuffer(commandOutputLimit),
+	}
+}
+
+// result returns the result of the command with the output captured so far.
+// The error is the one returned by spawning the command, if any.
+func (c *commandCapture) result(running bool, code int, err error) *CommandResult {
+	stdout, stdoutTruncated := c.stdout.contents()
+	stderr, stderrTruncated := c.stderr.contents()
+
+	result := &CommandResult{
+		Command:   c.command,
+		Time:      time.Now().UTC(),
+		Running:   running,
+		ExitCode:  code,
+		Stdout:    stdout,
+		Stderr:    stderr,
+		Truncated: stdoutTruncated || stderrTruncated,
+	}
+	if err != nil {
+		result.ExitCode = commandExitCode(err)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// commandExitCode returns the exit code of a command which failed with the
+// given error, or -1 if it did not exit on its own.
+func commandExitCode(err error) int {
+	if exitErr, ok := errors.Cause(err).(*child.ExitError); ok {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
+
+// markCommand records the result of a command executed for the templates with
+// the given IDs in their render events. Once the command has exited, the
+// result is also published to the clients of the control interface.
+func (r *Runner) markCommand(tmplIDs []string, result *CommandResult) {
+	r.renderEventsLock.Lock()
+	for _, id := range tmplIDs {
+		r.renderEventLocked(id).LastCommand = result
+	}
+	r.renderEventsLock.Unlock()
+	r.sendRenderEvents()
+
+	if result.Running {
+		return
+	}
+	for _, id := range tmplIDs {
+		r.publishCommand(id, result)
+	}
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	t.Parallel()
+
+	b := newOutputBuffer(8)
+	b.Write([]byte("abc"))
+	b.Write([]byte("def"))
+	if s, truncated := b.contents(); s != "abcdef" || truncated {
+		t.Errorf("unexpected contents: %q (%t)", s, truncated)
+	}
+
+	b.Write([]byte("ghijk"))
+	if s, truncated := b.contents(); s != "defghijk" || !truncated {
+		t.Errorf("unexpected contents: %q (%t)", s, truncated)
+	}
+
+	b.Write([]byte(strings.Repeat("x", 20)))
+	if s, truncated := b.contents(); s != strings.Repeat("x", 8) || !truncated {
+		t.Errorf("unexpected contents: %q (%t)", s, truncated)
+	}
+}
+
+func TestRunner_Run_commandOutput(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(filepath.Join(dir, "out")),
+				Exec: &config.ExecConfig{
+					Command: config.String(`sh -c "echo reloaded; echo warning >&2"`),
+				},
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream, r.errStream = ioutil.Discard, ioutil.Discard
+
+	events, unsubscribe := r.events.subscribe()
+	defer unsubscribe()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := r.RenderEvents()[r.templates[0].ID()].LastCommand
+	if result == nil || result.Running || result.ExitCode != 0 ||
+		result.Stdout != "reloaded\n" || result.Stderr != "warning\n" {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+
+	for {
+		select {
+		case e := <-events:
+			if e.Type != EventCommandExited {
+				continue
+			}
+			if e.Template != r.templates[0].ID() || e.Command != result {
+				t.Errorf("unexpected event: %#v", e)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatal("expected a command_exited event")
+		}
+	}
+}
+
+func TestRunner_Run_commandOutputBackground(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(filepath.Join(dir, "out")),
+				Exec: &config.ExecConfig{
+					Command: config.String(`sh -c "sleep 0.2; echo done; exit 4"`),
+					Timeout: config.TimeDuration(0),
+				},
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream, r.errStream = ioutil.Discard, ioutil.Discard
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	id := r.templates[0].ID()
+	if result := r.RenderEvents()[id].LastCommand; result == nil || !result.Running {
+		t.Fatalf("expected a running command, got %#v", result)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r.renderEventsLock.RLock()
+		result := r.renderEvents[id].LastCommand
+		r.renderEventsLock.RUnlock()
+
+		if !result.Running {
+			if result.ExitCode != 4 || result.Stdout != "done\n" {
+				t.Errorf("unexpected result: %#v", result)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the command to exit")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"sync"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+// commandQueue limits how many template commands run at the same time, and
+// keeps the command of a serial template from running while its previous run
+// has not exited.
+//
+// Commands with a timeout are waited for by the runner, so they hold their
+// slot until they return. Commands without a timeout are not waited for, and
+// hold their slot until their process exits. The command worker of the runner
+// acquires the slots one command at a time, so commands which have to wait
+// still run in the order they were queued, while the event loop goes on.
+type commandQueue struct {
+	// slots has an entry for each running command, or is nil if the number of
+	// commands is not limited
+	slots chan struct{}
+
+	// running has a channel for each serial command which has not exited yet,
+	// which is closed when it exits. It is keyed by the command, like the
+	// commands of the runner, and protected by lock.
+	running map[string]chan struct{}
+	lock    sync.Mutex
+}
+
+// newCommandQueue creates a new queue which runs at most max commands at the
+// same time. A max of 0 does not limit them.
+func newCommandQueue(max int) *commandQueue {
+	q := &commandQueue{
+		running: make(map[string]chan struct{}),
+	}
+	if max > 0 {
+		q.slots = make(chan struct{}, max)
+	}
+	return q
+}
+
+// acquire waits until the command of the given template may run. It returns
+// a function which must be called with the child spawned for the command, or
+// nil if none was, to release its slot once it exits. If the command is not
+// waited for, exited, if not nil, is called with its exit code after its slot
+// is released, since only one reader may receive the exit code of a child. It
+// returns false if stopCh was closed before the command could run.
+func (q *commandQueue) acquire(t *config.TemplateConfig, stopCh <-chan struct{}) (func(c *child.Child, exited func(int)), bool) {
+	command := config.StringVal(t.Exec.Command)
+	serial := config.BoolVal(t.Serial)
+
+	if serial {
+		q.lock.Lock()
+		prev := q.running[command]
+		q.lock.Unlock()
+
+		if prev != nil {
+			select {
+			case <-prev:
+			default:
+				templateLogf(t, "DEBUG", "command %q from %s waiting for its previous run to exit",
+					command, t.Display())
+				select {
+				case <-prev:
+				case <-stopCh:
+					return nil, false
+				}
+			}
+		}
+	}
+
+	if q.slots != nil {
+		select {
+		case q.slots <- struct{}{}:
+		default:
+			templateLogf(t, "DEBUG", "command %q from %s waiting for one of %d running commands to exit",
+				command, t.Display(), cap(q.slots))
+			select {
+			case q.slots <- struct{}{}:
+			case <-stopCh:
+				return nil, false
+			}
+		}
+	}
+
+	var done chan struct{}
+	if serial {
+		done = make(chan struct{})
+		q.lock.Lock()
+		q.running[command] = done
+		q.lock.Unlock()
+	}
+
+	finish := func() {
+		if q.slots != nil {
+			<-q.slots
+		}
+		if done != nil {
+			q.lock.Lock()
+			if q.running[command] == done {
+				delete(q.running, command)
+			}
+			q.lock.Unlock()
+			close(done)
+		}
+	}
+
+	return func(c *child.Child, exited func(int)) {
+		// A command with a timeout has already exited, or been killed, when
+		// spawning it returns.
+		if c == nil || config.TimeDurationVal(t.Exec.Timeout) != 0 {
+			finish()
+			return
+		}
+		go func() {
+			code := <-c.ExitCh()
+			finish()
+			if exited != nil {
+				exited(code)
+			}
+		}()
+	}, true
+}
@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func testCommandTemplate(command string, serial bool) *config.TemplateConfig {
+	t := &config.TemplateConfig{
+		Exec: &config.ExecConfig{
+			Command: config.String(command),
+			Timeout: config.TimeDuration(0),
+		},
+		Serial: config.Bool(serial),
+	}
+	t.Finalize()
+	return t
+}
+
+func TestCommandQueue_max(t *testing.T) {
+	t.Parallel()
+
+	q := newCommandQueue(1)
+
+	release, ok := q.acquire(testCommandTemplate("a", false), nil)
+	if !ok {
+		t.Fatal("expected the first command to run")
+	}
+
+	acquiredCh := make(chan struct{})
+	go func() {
+		if _, ok := q.acquire(testCommandTemplate("b", false), nil); ok {
+			close(acquiredCh)
+		}
+	}()
+
+	select {
+	case <-acquiredCh:
+		t.Fatal("expected the second command to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release(nil, nil)
+
+	select {
+	case <-acquiredCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the second command to run")
+	}
+}
+
+func TestCommandQueue_serial(t *testing.T) {
+	t.Parallel()
+
+	q := newCommandQueue(0)
+	tmpl := testCommandTemplate("sleep 0.2", true)
+
+	release, ok := q.acquire(tmpl, nil)
+	if !ok {
+		t.Fatal("expected the first run to start")
+	}
+	c, err := spawnChild(&spawnChildInput{
+		Command: config.StringVal(tmpl.Exec.Command),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(c, nil)
+
+	// Other commands are not held up.
+	if _, ok := q.acquire(testCommandTemplate("other", true), nil); !ok {
+		t.Fatal("expected another command to run")
+	}
+
+	start := time.Now()
+	if _, ok := q.acquire(tmpl, nil); !ok {
+		t.Fatal("expected the second run to start")
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("expected the second run to wait for the first, waited %s", d)
+	}
+}
+
+func TestCommandQueue_stop(t *testing.T) {
+	t.Parallel()
+
+	q := newCommandQueue(1)
+	if _, ok := q.acquire(testCommandTemplate("a", false), nil); !ok {
+		t.Fatal("expected the first command to run")
+	}
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+	if _, ok := q.acquire(testCommandTemplate("b", false), stopCh); ok {
+		t.Fatal("expected the second command not to run after stopping")
+	}
+}
@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/mattn/go-shellwords"
+	"github.com/pkg/errors"
+)
+
+// CompareFunc returns true if the existing contents of a destination and the
+// rendered contents are equal, so the destination is left alone.
+type CompareFunc func(existing, contents []byte) bool
+
+// compareHashes are the hashing algorithms of the hash mode, by name.
+var compareHashes = map[string]func() hash.Hash{
+	config.CompareHashMD5:    md5.New,
+	config.CompareHashSHA1:   sha1.New,
+	config.CompareHashSHA256: sha256.New,
+	config.CompareHashSHA512: sha512.New,
+}
+
+// newCompareFunc returns the CompareFunc of the given configuration, or nil to
+// compare the contents byte by byte.
+func newCompareFunc(c *config.CompareConfig) (CompareFunc, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	switch mode := config.StringVal(c.Mode); mode {
+	case "", config.CompareModeBytes:
+		return nil, nil
+	case config.CompareModeHash:
+		name := config.StringVal(c.Hash)
+		newHash, ok := compareHashes[name]
+		if !ok {
+			return nil, fmt.Errorf("compare: unknown hash %q", name)
+		}
+		return func(existing, contents []byte) bool {
+			return bytes.Equal(hashSum(newHash, existing), hashSum(newHash, contents))
+		}, nil
+	case config.CompareModeSemantic:
+		p := shellwords.NewParser()
+		p.ParseEnv = true
+		args, err := p.Parse(config.StringVal(c.Command))
+		if err != nil {
+			return nil, errors.Wrap(err, "compare: failed parsing command")
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("compare: semantic mode requires a command")
+		}
+		timeout := config.TimeDurationVal(c.Timeout)
+		if timeout <= 0 {
+			timeout = config.DefaultCompareTimeout
+		}
+		return func(existing, contents []byte) bool {
+			a, err := normalize(args, existing, timeout)
+			if err != nil {
+				log.Printf("[WARN] (runner) compare: failed normalizing destination, "+
+					"comparing byte by byte: %s", err)
+				return bytes.Equal(existing, contents)
+			}
+			b, err := normalize(args, contents, timeout)
+			if err != nil {
+				log.Printf("[WARN] (runner) compare: failed normalizing contents, "+
+					"comparing byte by byte: %s", err)
+				return bytes.Equal(existing, contents)
+			}
+			return bytes.Equal(a, b)
+		}, nil
+	default:
+		return nil, fmt.Errorf("compare: unknown mode %q", mode)
+	}
+}
+
+// hashSum returns the digest of the given contents.
+func hashSum(newHash func() hash.Hash, contents []byte) []byte {
+	h := newHash()
+	h.Write(contents)
+	return h.Sum(nil)
+}
+
+// normalize runs the given command with the contents on stdin and returns its
+// stdout, killing it if it does not exit within the timeout.
+func normalize(args []string, contents []byte, timeout time.Duration) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(contents)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return stdout.Bytes(), nil
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return nil, fmt.Errorf("command did not finish within %s", timeout)
+	}
+}
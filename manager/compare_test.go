@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewCompareFunc(t *testing.T) {
+	cases := []struct {
+		name string
+		c    *config.CompareConfig
+		nil  bool
+		err  string
+	}{
+		{
+			"nil",
+			nil,
+			true,
+			"",
+		},
+		{
+			"bytes",
+			&config.CompareConfig{Mode: config.String(config.CompareModeBytes)},
+			true,
+			"",
+		},
+		{
+			"hash",
+			&config.CompareConfig{
+				Hash: config.String(config.CompareHashMD5),
+				Mode: config.String(config.CompareModeHash),
+			},
+			false,
+			"",
+		},
+		{
+			"semantic",
+			&config.CompareConfig{Command: config.String("sort")},
+			false,
+			"",
+		},
+		{
+			"unknown_mode",
+			&config.CompareConfig{Mode: config.String("fuzzy")},
+			false,
+			"unknown mode",
+		},
+		{
+			"unknown_hash",
+			&config.CompareConfig{
+				Hash: config.String("crc32"),
+				Mode: config.String(config.CompareModeHash),
+			},
+			false,
+			"unknown hash",
+		},
+		{
+			"missing_command",
+			&config.CompareConfig{Mode: config.String(config.CompareModeSemantic)},
+			false,
+			"requires a command",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.c != nil {
+				tc.c.Finalize()
+			}
+			f, err := newCompareFunc(tc.c)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (f == nil) != tc.nil {
+				t.Errorf("expected nil %t, got %t", tc.nil, f == nil)
+			}
+		})
+	}
+}
+
+func TestCompareFunc(t *testing.T) {
+	t.Run("hash", func(t *testing.T) {
+		c := &config.CompareConfig{Mode: config.String(config.CompareModeHash)}
+		c.Finalize()
+		f, err := newCompareFunc(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f([]byte("a\n"), []byte("a\n")) {
+			t.Error("expected the same contents to be equal")
+		}
+		if f([]byte("a\n"), []byte("b\n")) {
+			t.Error("expected different contents not to be equal")
+		}
+	})
+
+	t.Run("semantic", func(t *testing.T) {
+		c := &config.CompareConfig{Command: config.String("sort")}
+		c.Finalize()
+		f, err := newCompareFunc(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f([]byte("a\nb\n"), []byte("b\na\n")) {
+			t.Error("expected reordered contents to be equal")
+		}
+		if f([]byte("a\nb\n"), []byte("a\nc\n")) {
+			t.Error("expected different contents not to be equal")
+		}
+	})
+
+	t.Run("semantic_failed", func(t *testing.T) {
+		c := &config.CompareConfig{Command: config.String("sh -c 'exit 1'")}
+		c.Finalize()
+		f, err := newCompareFunc(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f([]byte("a\n"), []byte("a\n")) {
+			t.Error("expected the same contents to be equal")
+		}
+		if f([]byte("a\nb\n"), []byte("b\na\n")) {
+			t.Error("expected the contents to be compared byte by byte")
+		}
+	})
+}
+
+func TestRender_compare(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	path := filepath.Join(outDir, "out")
+	if err := ioutil.WriteFile(path, []byte("a\nb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.CompareConfig{Command: config.String("sort")}
+	c.Finalize()
+	compare, err := newCompareFunc(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Render(&RenderInput{
+		Compare:  compare,
+		Contents: []byte("b\na\n"),
+		Path:     path,
+		Perms:    0644,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DidRender {
+		t.Error("expected reordered contents not to render")
+	}
+
+	result, err = Render(&RenderInput{
+		Compare:  compare,
+		Contents: []byte("a\nc\n"),
+		Path:     path,
+		Perms:    0644,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.DidRender {
+		t.Error("expected changed contents to render")
+	}
+}
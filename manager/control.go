@@ -0,0 +1,582 @@
+package manager
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+const (
+	// controlEventBufferSize is the number of events buffered for each client
+	// of the events endpoint. Events are dropped for clients which fall behind.
+	controlEventBufferSize = 64
+)
+
+// The types of the events published to the clients of the control interface.
+const (
+	EventTemplateWouldRender = "template_would_render"
+	EventTemplateDidRender   = "template_did_render"
+	EventDependencyReceived  = "dependency_received"
+	EventPaused              = "paused"
+	EventResumed             = "resumed"
+	EventRenderForced        = "render_forced"
+	EventReloadRequested     = "reload_requested"
+	EventPromoted            = "promoted"
+	EventTemplateFailed      = "template_failed"
+	EventCommandExited       = "command_exited"
+	EventDependencyPaused    = "dependency_paused"
+	EventDependencyResumed   = "dependency_resumed"
+)
+
+// ControlEvent is an event of the runner, as streamed by the events endpoint
+// of the control interface.
+type ControlEvent struct {
+	Time         time.Time `json:"time"`
+	Type         string    `json:"type"`
+	Template     string    `json:"template,omitempty"`
+	TemplateName string    `json:"template_name,omitempty"`
+	Dependency   string    `json:"dependency,omitempty"`
+
+	// Command is the result of the command of a command_exited event.
+	Command *CommandResult `json:"command,omitempty"`
+}
+
+// controlState is the body returned by the state endpoint. Templates is the
+// render state of each template, keyed by its ID, and Dependencies the
+// templates which reference each dependency. PausedDependencies are the
+// dependencies which are not being polled. VaultAddress is the Vault server
+// requests are sent to, if Vault is enabled.
+type controlState struct {
+	Paused             bool                      `json:"paused"`
+	Standby            bool                      `json:"standby"`
+	ChildPid           int                       `json:"child_pid,omitempty"`
+	VaultAddress       string                    `json:"vault_address,omitempty"`
+	Templates          map[string]*templateState `json:"templates"`
+	Dependencies       map[string][]string       `json:"dependencies"`
+	PausedDependencies []string                  `json:"paused_dependencies,omitempty"`
+	Watcher            *watcherStatus            `json:"watcher"`
+}
+
+// templateState is the render state of a template returned by the state
+// endpoint.
+type templateState struct {
+	Name            string    `json:"name,omitempty"`
+	LastWouldRender time.Time `json:"last_would_render"`
+	LastDidRender   time.Time `json:"last_did_render"`
+	LastBlocked     time.Time `json:"last_blocked"`
+	BlockedReason   string    `json:"blocked_reason,omitempty"`
+	LastFailed      time.Time `json:"last_failed"`
+	FailedReason    string    `json:"failed_reason,omitempty"`
+
+	LastCommand *CommandResult `json:"last_command,omitempty"`
+
+	// ChildPid is the pid of the supervised process of the template, if it
+	// has one running.
+	ChildPid int `json:"child_pid,omitempty"`
+}
+
+// eventBroadcaster delivers the events of a runner to any number of
+// subscribers. Publishing never blocks; events are dropped for subscribers
+// whose buffer is full.
+type eventBroadcaster struct {
+	lock        sync.Mutex
+	subscribers map[chan *ControlEvent]struct{}
+}
+
+// newEventBroadcaster creates a new broadcaster without subscribers.
+func newEventBroadcaster() *eventBroadcaster {
+	return &eventBroadcaster{
+		subscribers: make(map[chan *ControlEvent]struct{}),
+	}
+}
+
+// subscribe returns a channel which receives all published events, and a
+// function which must be called to stop receiving them.
+func (b *eventBroadcaster) subscribe() (<-chan *ControlEvent, func()) {
+	ch := make(chan *ControlEvent, controlEventBufferSize)
+
+	b.lock.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.lock.Unlock()
+
+	return ch, func() {
+		b.lock.Lock()
+		delete(b.subscribers, ch)
+		b.lock.Unlock()
+	}
+}
+
+// publish delivers the event to all subscribers.
+func (b *eventBroadcaster) publish(e *ControlEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("[TRACE] (control) dropping %s event for slow client", e.Type)
+		}
+	}
+}
+
+// controlServer is the HTTP server which exposes the operations of a runner, or
+// of the runners of a MultiRunner, on a unix socket and optionally a TCP
+// address.
+type controlServer struct {
+	config    *config.ControlConfig
+	runner    *Runner
+	listeners []net.Listener
+	server    *httpServer
+}
+
+// newControlServer creates a new control server for the given runner. A token
+// is required to listen on a TCP address, since anyone who can reach it could
+// otherwise control the runner.
+func newControlServer(c *config.ControlConfig, r *Runner) (*controlServer, error) {
+	if err := validateControlConfig(c); err != nil {
+		return nil, err
+	}
+
+	s := &controlServer{
+		config: c,
+		runner: r,
+	}
+	s.server = newHTTPServer(s.authorize(s.handler()))
+
+	return s, nil
+}
+
+// newSharedControlServer creates a control server for the runners of a
+// MultiRunner. The operations of each runner are served beneath
+// /v1/runners/NAME/, and /v1/runners lists the names of the runners.
+func newSharedControlServer(c *config.ControlConfig, names []string, runners map[string]*Runner) (*controlServer, error) {
+	if err := validateControlConfig(c); err != nil {
+		return nil, err
+	}
+
+	s := &controlServer{config: c}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/runners", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(names); err != nil {
+			log.Printf("[WARN] (control) failed to write response: %s", err)
+		}
+	})
+	for _, name := range names {
+		prefix := "/v1/runners/" + name
+		rs := &controlServer{config: c, runner: runners[name]}
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, rs.handler()))
+	}
+	s.server = newHTTPServer(s.authorize(mux))
+
+	return s, nil
+}
+
+// validateControlConfig returns an error if the control server of the given
+// configuration would not listen anywhere, or would listen on a TCP address
+// without a token.
+func validateControlConfig(c *config.ControlConfig) error {
+	if !config.StringPresent(c.Path) && !config.StringPresent(c.Address) {
+		return fmt.Errorf("control: a path or an address is required")
+	}
+	if config.StringPresent(c.Address) && !config.StringPresent(c.Token) {
+		return fmt.Errorf("control: a token is required to listen on %s",
+			config.StringVal(c.Address))
+	}
+	return nil
+}
+
+// handler returns the handler of the operations of the runner.
+func (s *controlServer) handler() http.Handler {
+	r := s.runner
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/reload", s.handleAction(r.RequestReload))
+	mux.HandleFunc("/v1/render", s.handleAction(r.ForceRender))
+	mux.HandleFunc("/v1/pause", s.handleAction(r.Pause))
+	mux.HandleFunc("/v1/resume", s.handleAction(r.Resume))
+	mux.HandleFunc("/v1/promote", s.handleAction(r.Promote))
+	mux.HandleFunc("/v1/pause-dependency", s.handleDependencyAction(r.PauseDependency))
+	mux.HandleFunc("/v1/resume-dependency", s.handleDependencyAction(r.ResumeDependency))
+	mux.HandleFunc("/v1/state", s.handleState)
+	mux.HandleFunc("/v1/events", s.handleEvents)
+	return mux
+}
+
+// Start begins listening on the configured socket and address. Requests are
+// served in the background until Stop is called.
+func (s *controlServer) Start() error {
+	if path := config.StringVal(s.config.Path); path != "" {
+		// Remove the socket of a previous process which did not clean up.
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("control: failed to remove %s: %s", path, err)
+		}
+
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return fmt.Errorf("control: failed to listen on %s: %s", path, err)
+		}
+		s.listeners = append(s.listeners, ln)
+
+		if err := os.Chmod(path, 0600); err != nil {
+			ln.Close()
+			s.listeners = nil
+			return fmt.Errorf("control: failed to set permissions on %s: %s", path, err)
+		}
+	}
+
+	if addr := config.StringVal(s.config.Address); addr != "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, ln := range s.listeners {
+				ln.Close()
+			}
+			s.listeners = nil
+			return fmt.Errorf("control: failed to listen on %s: %s", addr, err)
+		}
+		s.listeners = append(s.listeners, ln)
+	}
+
+	for _, ln := range s.listeners {
+		log.Printf("[INFO] (control) listening on %s", ln.Addr())
+
+		go func(ln net.Listener) {
+			if err := s.server.Serve(ln); err != nil {
+				log.Printf("[ERR] (control) server stopped: %s", err)
+			}
+		}(ln)
+	}
+
+	return nil
+}
+
+// Stop closes the listeners and all active connections. The unix socket is
+// removed when its listener is closed.
+func (s *controlServer) Stop() {
+	if len(s.listeners) == 0 {
+		return
+	}
+
+	log.Printf("[INFO] (control) stopping")
+	s.server.Close()
+}
+
+// Addrs returns the addresses the server is listening on, or nil if it has not
+// been started.
+func (s *controlServer) Addrs() []net.Addr {
+	var addrs []net.Addr
+	for _, ln := range s.listeners {
+		addrs = append(addrs, ln.Addr())
+	}
+	return addrs
+}
+
+// authorize returns a handler which rejects requests without the configured
+// token, if any. The token is given as a bearer token in the Authorization
+// header.
+func (s *controlServer) authorize(next http.Handler) http.Handler {
+	token := config.StringVal(s.config.Token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if token != "" {
+			given := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// handleAction returns a handler which calls the given operation of the runner.
+// It only accepts POST requests, since the operations change the state of the
+// runner.
+func (s *controlServer) handleAction(action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		action()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleDependencyAction returns a handler which calls the given operation of
+// the runner with the dependency named by the "dependency" query parameter. Like
+// handleAction, it only accepts POST requests.
+func (s *controlServer) handleDependencyAction(action func(string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := req.URL.Query().Get("dependency")
+		if name == "" {
+			http.Error(w, "missing dependency", http.StatusBadRequest)
+			return
+		}
+
+		if err := action(name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleState responds with the state of the runner, its templates and the
+// dependencies it is watching.
+func (s *controlServer) handleState(w http.ResponseWriter, req *http.Request) {
+	r := s.runner
+
+	result := &controlState{
+		Paused:       r.Paused(),
+		Standby:      r.inStandby(),
+		Templates:    make(map[string]*templateState),
+		Dependencies: r.DependencyReferences(),
+
+		PausedDependencies: r.watcher.Paused(),
+		Watcher: &watcherStatus{
+			Backpressure: config.StringVal(r.config.Watcher.Backpressure),
+			BufferSize:   config.IntVal(r.config.Watcher.BufferSize),
+			Pending:      r.watcher.Pending(),
+			Coalesced:    r.watcher.Coalesced(),
+			Dropped:      r.watcher.Dropped(),
+			Fetches:      r.watcher.Fetches(),
+			FetchErrors:  r.watcher.FetchErrors(),
+		},
+	}
+
+	if config.BoolVal(r.config.Vault.Enabled) {
+		result.VaultAddress = r.clients.VaultAddress()
+	}
+
+	// The events are updated in place, so copy them while holding the lock.
+	r.renderEventsLock.RLock()
+	for id, e := range r.renderEvents {
+		result.Templates[id] = &templateState{
+			Name:            r.templateName(id),
+			LastWouldRender: e.LastWouldRender,
+			LastDidRender:   e.LastDidRender,
+			LastBlocked:     e.LastBlocked,
+			BlockedReason:   e.BlockedReason,
+			LastFailed:      e.LastFailed,
+			FailedReason:    e.FailedReason,
+			LastCommand:     e.LastCommand,
+		}
+	}
+	r.renderEventsLock.RUnlock()
+
+	for id, pid := range r.TemplateChildPids() {
+		if state, ok := result.Templates[id]; ok {
+			state.ChildPid = pid
+		}
+	}
+
+	r.childLock.RLock()
+	if r.child != nil {
+		result.ChildPid = r.child.Pid()
+	}
+	r.childLock.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		log.Printf("[WARN] (control) failed to write response: %s", err)
+	}
+}
+
+// handleEvents streams the events of the runner as newline-delimited JSON until
+// the client disconnects or the server stops.
+func (s *controlServer) handleEvents(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := s.runner.events.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for {
+		select {
+		case e := <-events:
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// Pause stops the runner from rendering templates and running commands.
+// Dependencies are still watched, so the templates render with the latest data
+// once the runner is resumed.
+func (r *Runner) Pause() {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	if r.paused {
+		return
+	}
+
+	log.Printf("[INFO] (runner) pausing")
+	r.paused = true
+	r.publish(EventPaused, "", "")
+}
+
+// Resume resumes a paused runner, which renders any templates that changed in
+// the meantime.
+func (r *Runner) Resume() {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	if !r.paused {
+		return
+	}
+
+	log.Printf("[INFO] (runner) resuming")
+	r.paused = false
+	r.publish(EventResumed, "", "")
+
+	select {
+	case r.resumeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Paused returns true while the runner is paused.
+func (r *Runner) Paused() bool {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	return r.paused
+}
+
+// PauseDependency stops polling the dependency with the given name, such as one
+// which is hammering a broken backend, until it is resumed. The templates keep
+// rendering with the data it last received. Pausing a paused dependency does
+// nothing. An error is returned if the dependency is not being watched.
+func (r *Runner) PauseDependency(name string) error {
+	d, err := r.watchedDependency(name)
+	if err != nil {
+		return err
+	}
+
+	if r.watcher.Pause(d) {
+		log.Printf("[INFO] (runner) pausing %s", name)
+		r.publish(EventDependencyPaused, "", name)
+	}
+	return nil
+}
+
+// ResumeDependency resumes polling the paused dependency with the given name.
+// Resuming a dependency which is not paused does nothing. An error is returned
+// if the dependency is not being watched.
+func (r *Runner) ResumeDependency(name string) error {
+	d, err := r.watchedDependency(name)
+	if err != nil {
+		return err
+	}
+
+	if r.watcher.Resume(d) {
+		log.Printf("[INFO] (runner) resuming %s", name)
+		r.publish(EventDependencyResumed, "", name)
+	}
+	return nil
+}
+
+// watchedDependency returns the dependency with the given name, as listed by
+// the state endpoint, if it is being watched.
+func (r *Runner) watchedDependency(name string) (dep.Dependency, error) {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	d, ok := r.dependencies[name]
+	if !ok {
+		return nil, fmt.Errorf("runner: dependency %q is not being watched", name)
+	}
+	return d, nil
+}
+
+// ForceRender runs the templates immediately, without waiting for their
+// quiescence timers. It runs them even if the runner is paused.
+func (r *Runner) ForceRender() {
+	log.Printf("[INFO] (runner) forcing render")
+	r.publish(EventRenderForced, "", "")
+
+	select {
+	case r.renderCh <- struct{}{}:
+	default:
+	}
+}
+
+// RequestReload asks the caller of the runner to reload the configuration,
+// with a notification on ReloadCh.
+func (r *Runner) RequestReload() {
+	log.Printf("[INFO] (runner) reload requested")
+	r.publish(EventReloadRequested, "", "")
+
+	select {
+	case r.ReloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// publish delivers an event to the clients of the control interface.
+func (r *Runner) publish(typ, tmplID, dependency string) {
+	r.renderEventsLock.RLock()
+	name := r.templateName(tmplID)
+	r.renderEventsLock.RUnlock()
+
+	r.events.publish(&ControlEvent{
+		Time:         time.Now().UTC(),
+		Type:         typ,
+		Template:     tmplID,
+		TemplateName: name,
+		Dependency:   dependency,
+	})
+}
+
+// publishCommand delivers the result of a command executed for the given
+// template to the clients of the control interface.
+func (r *Runner) publishCommand(tmplID string, result *CommandResult) {
+	r.renderEventsLock.RLock()
+	name := r.templateName(tmplID)
+	r.renderEventsLock.RUnlock()
+
+	r.events.publish(&ControlEvent{
+		Time:         time.Now().UTC(),
+		Type:         EventCommandExited,
+		Template:     tmplID,
+		TemplateName: name,
+		Command:      result,
+	})
+}
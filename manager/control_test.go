@@ -0,0 +1,366 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewControlServer(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    *config.ControlConfig
+		err  bool
+	}{
+		{
+			"path",
+			&config.ControlConfig{Path: config.String("/tmp/ct.sock")},
+			false,
+		},
+		{
+			"address_token",
+			&config.ControlConfig{
+				Address: config.String("127.0.0.1:0"),
+				Token:   config.String("abcd1234"),
+			},
+			false,
+		},
+		{
+			"address_no_token",
+			&config.ControlConfig{Address: config.String("127.0.0.1:0")},
+			true,
+		},
+		{
+			"empty",
+			&config.ControlConfig{},
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.c.Finalize()
+
+			r, err := NewRunner(config.TestConfig(nil), true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = newControlServer(tc.c, r)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestControlServer_authorize(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		token  string
+		header string
+		code   int
+	}{
+		{
+			"no_token",
+			"",
+			"",
+			http.StatusNoContent,
+		},
+		{
+			"valid",
+			"abcd1234",
+			"Bearer abcd1234",
+			http.StatusNoContent,
+		},
+		{
+			"invalid",
+			"abcd1234",
+			"Bearer nope",
+			http.StatusForbidden,
+		},
+		{
+			"missing",
+			"abcd1234",
+			"",
+			http.StatusForbidden,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			s := &controlServer{
+				config: &config.ControlConfig{Token: config.String(tc.token)},
+			}
+			h := s.authorize(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/v1/state", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			h.ServeHTTP(w, req)
+
+			if w.Code != tc.code {
+				t.Errorf("\nexp: %d\nact: %d", tc.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestControlServer_handleAction(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRunner(config.TestConfig(nil), true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	s := &controlServer{runner: r}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/v1/pause", nil)
+	s.handleAction(r.Pause)(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("\nexp: %d\nact: %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if r.Paused() {
+		t.Fatal("expected runner not to be paused")
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/v1/pause", nil)
+	s.handleAction(r.Pause)(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("\nexp: %d\nact: %d", http.StatusNoContent, w.Code)
+	}
+	if !r.Paused() {
+		t.Fatal("expected runner to be paused")
+	}
+}
+
+func TestControlServer_unix(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ct.sock")
+	c := config.TestConfig(&config.Config{
+		Control: &config.ControlConfig{
+			Path: config.String(path),
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	s, err := newControlServer(c.Control, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0600 {
+		t.Errorf("\nexp: %#o\nact: %#o", 0600, mode)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", path)
+			},
+		},
+	}
+
+	// Stream the events, and pause the runner.
+	resp, err := client.Get("http://unix/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := client.Post("http://unix/v1/pause", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	eventCh := make(chan *ControlEvent, 1)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		if scanner.Scan() {
+			var e ControlEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+				eventCh <- &e
+			}
+		}
+	}()
+
+	select {
+	case e := <-eventCh:
+		if e.Type != EventPaused {
+			t.Errorf("\nexp: %#v\nact: %#v", EventPaused, e.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("did not receive event")
+	}
+
+	resp, err = client.Get("http://unix/v1/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var state controlState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if !state.Paused {
+		t.Errorf("expected paused state")
+	}
+
+	// The socket is removed once the server stops.
+	s.Stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected socket to be removed: %v", err)
+	}
+}
+
+func TestRunner_pause(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents: config.String("hello"),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream = ioutil.Discard
+	go r.Start()
+	defer r.Stop()
+
+	select {
+	case <-r.TemplateRenderedCh():
+	case err := <-r.ErrCh:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("template did not render")
+	}
+
+	// A forced render runs the templates even while paused.
+	r.Pause()
+	r.ForceRender()
+
+	select {
+	case <-r.TemplateRenderedCh():
+	case err := <-r.ErrCh:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("forced render did not run")
+	}
+
+	r.Resume()
+	if r.Paused() {
+		t.Fatal("expected runner to be resumed")
+	}
+}
+
+func TestRunner_pauseDependency(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents: config.String(`{{ file "` + f.Name() + `" }}`),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream = ioutil.Discard
+	go r.Start()
+	defer r.Stop()
+
+	select {
+	case <-r.TemplateRenderedCh():
+	case err := <-r.ErrCh:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("template did not render")
+	}
+
+	s := &controlServer{runner: r}
+	name := "file(" + f.Name() + ")"
+
+	cases := []struct {
+		name   string
+		method string
+		action func(string) error
+		query  string
+		code   int
+		paused []string
+	}{
+		{"get", "GET", r.PauseDependency, "?dependency=" + name, http.StatusMethodNotAllowed, nil},
+		{"missing", "POST", r.PauseDependency, "", http.StatusBadRequest, nil},
+		{"unknown", "POST", r.PauseDependency, "?dependency=file(nope)", http.StatusNotFound, nil},
+		{"pause", "POST", r.PauseDependency, "?dependency=" + name, http.StatusNoContent, []string{name}},
+		{"pause_again", "POST", r.PauseDependency, "?dependency=" + name, http.StatusNoContent, []string{name}},
+		{"resume", "POST", r.ResumeDependency, "?dependency=" + name, http.StatusNoContent, nil},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, "/v1/dependency"+tc.query, nil)
+		s.handleDependencyAction(tc.action)(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: \nexp: %d\nact: %d", tc.name, tc.code, w.Code)
+		}
+		if act := r.watcher.Paused(); !reflect.DeepEqual(tc.paused, act) {
+			t.Errorf("%s: \nexp: %#v\nact: %#v", tc.name, tc.paused, act)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// coordinateWaitTime is the maximum amount of time a single blocking query
+	// on the reload queue waits for a change.
+	coordinateWaitTime = 60 * time.Second
+)
+
+// ReloadCoordinator is used to limit how many instances of Consul Template
+// sharing a prefix reload at the same time.
+//
+// Each instance holds a session and registers itself under the "instances"
+// path of the prefix. Before running its commands and reloading its child
+// process, an instance adds itself to the "queue" path and waits until it is
+// among the first MaxParallel entries, ordered by when they joined. This
+// makes the instances reload in waves. All keys are deleted along with the
+// session, so instances which die leave the queue.
+type ReloadCoordinator struct {
+	// config is the coordinate configuration
+	config *config.CoordinateConfig
+
+	// clients is used to access the underlying clients
+	clients *dep.ClientSet
+
+	// session is the ID of the current session, or empty if there is none
+	session     string
+	sessionLock sync.RWMutex
+
+	stop     bool
+	stopCh   chan struct{}
+	stopLock sync.Mutex
+}
+
+// NewReloadCoordinator creates a new reload coordinator.
+func NewReloadCoordinator(config *config.CoordinateConfig, clients *dep.ClientSet) (*ReloadCoordinator, error) {
+	if _, err := parseMaxParallel(config); err != nil {
+		return nil, fmt.Errorf("coordinate: %s", err)
+	}
+
+	return &ReloadCoordinator{
+		config:  config,
+		clients: clients,
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// Start is used to start the reload coordinator.
+func (c *ReloadCoordinator) Start() error {
+	log.Printf("[INFO] (coordinate) starting reload coordinator")
+	go c.createSession(c.clients.Consul())
+	return nil
+}
+
+// Stop is used to stop the reload coordinator.
+func (c *ReloadCoordinator) Stop() error {
+	c.stopLock.Lock()
+	defer c.stopLock.Unlock()
+	if c.stop {
+		return nil
+	}
+
+	log.Printf("[INFO] (coordinate) stopping reload coordinator")
+	c.stop = true
+	close(c.stopCh)
+	return nil
+}
+
+// createSession is used to create and maintain a session to Consul.
+func (c *ReloadCoordinator) createSession(client *consulapi.Client) {
+START:
+	log.Printf("[INFO] (coordinate) attempting to create session")
+	session := client.Session()
+	ttl := fmt.Sprintf("%.6fs", float64(*c.config.TTL)/float64(time.Second))
+	se := &consulapi.SessionEntry{
+		Name:     "Consul-Template reload coordination",
+		Behavior: "delete",
+		TTL:      ttl,
+	}
+	id, _, err := session.Create(se, nil)
+	if err != nil {
+		log.Printf("[ERR] (coordinate) failed to create session: %v", err)
+		goto WAIT
+	}
+	log.Printf("[INFO] (coordinate) created session %s", id)
+
+	// Register this instance so peers can size the waves.
+	if _, _, err := client.KV().Acquire(&consulapi.KVPair{
+		Key:     path.Join(*c.config.Prefix, "instances", id),
+		Session: id,
+	}, nil); err != nil {
+		log.Printf("[WARN] (coordinate) failed to register instance: %v", err)
+	}
+
+	c.setSession(id)
+
+	// Renew our session periodically
+	if err := session.RenewPeriodic(ttl, id, nil, c.stopCh); err != nil {
+		log.Printf("[ERR] (coordinate) failed to renew session: %v", err)
+	}
+	c.setSession("")
+
+WAIT:
+	select {
+	case <-time.After(sessionCreateRetry):
+		goto START
+	case <-c.stopCh:
+		return
+	}
+}
+
+// setSession sets the ID of the current session.
+func (c *ReloadCoordinator) setSession(id string) {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	c.session = id
+}
+
+// Acquire blocks until it is this instance's turn to reload, and returns a
+// function which must be called once the reload is done. If coordination is
+// not possible, such as when Consul is unreachable or the timeout expires, a
+// warning is logged and the reload is allowed, so renders are never held back
+// indefinitely.
+func (c *ReloadCoordinator) Acquire() func() {
+	noop := func() {}
+
+	c.sessionLock.RLock()
+	session := c.session
+	c.sessionLock.RUnlock()
+	if session == "" {
+		log.Printf("[WARN] (coordinate) no session, reloading without coordination")
+		return noop
+	}
+
+	client := c.clients.Consul()
+	queuePrefix := path.Join(*c.config.Prefix, "queue") + "/"
+	key := queuePrefix + session
+
+	if _, _, err := client.KV().Acquire(&consulapi.KVPair{
+		Key:     key,
+		Session: session,
+	}, nil); err != nil {
+		log.Printf("[WARN] (coordinate) failed to join queue, reloading without "+
+			"coordination: %v", err)
+		return noop
+	}
+
+	release := func() {
+		if _, err := client.KV().Delete(key, nil); err != nil {
+			log.Printf("[WARN] (coordinate) failed to leave queue: %v", err)
+		}
+	}
+
+	var deadline <-chan time.Time
+	if timeout := config.TimeDurationVal(c.config.Timeout); timeout > 0 {
+		deadline = time.After(timeout)
+	}
+
+	var waitIndex uint64
+	for {
+		instances, _, err := client.KV().Keys(
+			path.Join(*c.config.Prefix, "instances")+"/", "", nil)
+		if err != nil {
+			log.Printf("[WARN] (coordinate) failed to list instances, reloading "+
+				"without coordination: %v", err)
+			return release
+		}
+
+		limit, err := config.ParseMaxParallel(
+			config.StringVal(c.config.MaxParallel), len(instances))
+		if err != nil {
+			log.Printf("[WARN] (coordinate) %s", err)
+			return release
+		}
+
+		pairs, meta, err := client.KV().List(queuePrefix, &consulapi.QueryOptions{
+			WaitIndex: waitIndex,
+			WaitTime:  coordinateWaitTime,
+		})
+		if err != nil {
+			log.Printf("[WARN] (coordinate) failed to list queue, reloading "+
+				"without coordination: %v", err)
+			return release
+		}
+
+		if inWave(pairs, key, limit) {
+			log.Printf("[INFO] (coordinate) reloading (%d of %d instances at a time)",
+				limit, len(instances))
+			return release
+		}
+
+		select {
+		case <-deadline:
+			log.Printf("[WARN] (coordinate) timed out waiting for a turn, " +
+				"reloading anyway")
+			return release
+		case <-c.stopCh:
+			return release
+		default:
+		}
+
+		log.Printf("[DEBUG] (coordinate) waiting for a turn to reload")
+		waitIndex = meta.LastIndex
+	}
+}
+
+// parseMaxParallel validates the max parallel setting of the given
+// configuration.
+func parseMaxParallel(c *config.CoordinateConfig) (int, error) {
+	return config.ParseMaxParallel(config.StringVal(c.MaxParallel), 1)
+}
+
+// inWave returns true if the given key is among the first limit entries of the
+// queue, ordered by when they were created. A key which is not in the queue
+// is never in the wave.
+func inWave(pairs consulapi.KVPairs, key string, limit int) bool {
+	sorted := make(pairsByCreateIndex, len(pairs))
+	copy(sorted, pairs)
+	sort.Sort(sorted)
+
+	for i, pair := range sorted {
+		if i >= limit {
+			return false
+		}
+		if pair.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// pairsByCreateIndex is a sortable list of KV pairs by when they were created.
+type pairsByCreateIndex []*consulapi.KVPair
+
+func (s pairsByCreateIndex) Len() int      { return len(s) }
+func (s pairsByCreateIndex) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s pairsByCreateIndex) Less(i, j int) bool {
+	if s[i].CreateIndex == s[j].CreateIndex {
+		return s[i].Key < s[j].Key
+	}
+	return s[i].CreateIndex < s[j].CreateIndex
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestNewReloadCoordinator(t *testing.T) {
+	cases := []struct {
+		name        string
+		maxParallel string
+		err         bool
+	}{
+		{"count", "2", false},
+		{"percentage", "25%", false},
+		{"zero", "0", true},
+		{"garbage", "nope", true},
+		{"percentage_too_large", "150%", true},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := &config.CoordinateConfig{MaxParallel: config.String(tc.maxParallel)}
+			c.Finalize()
+
+			_, err := NewReloadCoordinator(c, nil)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestInWave(t *testing.T) {
+	pairs := consulapi.KVPairs{
+		{Key: "q/c", CreateIndex: 30},
+		{Key: "q/a", CreateIndex: 10},
+		{Key: "q/b", CreateIndex: 20},
+	}
+
+	cases := []struct {
+		name  string
+		key   string
+		limit int
+		exp   bool
+	}{
+		{"first", "q/a", 1, true},
+		{"second_limit_one", "q/b", 1, false},
+		{"second_limit_two", "q/b", 2, true},
+		{"last_limit_all", "q/c", 3, true},
+		{"missing", "q/d", 10, false},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := inWave(pairs, tc.key, tc.limit); act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
@@ -8,6 +8,10 @@ import (
 	"fmt"
 	"log"
 	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,9 +32,21 @@ const (
 	// listRetry is the interval on which we retry listing a data path
 	listRetry = 10 * time.Second
 
+	// shardRebalance is the interval on which a sharded leader checks if
+	// another instance has become the preferred leader for its template
+	shardRebalance = 10 * time.Second
+
 	// templateDataFlag is added as a flag to the shared data values
 	// so that we can use it as a sanity check
 	templateDataFlag = 0x22b9a127a2c03520
+
+	// templateBatchFlag is added as a flag to the batched data values, which
+	// hold the data of several templates
+	templateBatchFlag = 0x22b9a127a2c03521
+
+	// maxBatchSize is the largest batched data value we write, since Consul
+	// rejects values over 512KB
+	maxBatchSize = 500 * 1024
 )
 
 // templateData is GOB encoded share the depdency values
@@ -38,6 +54,12 @@ type templateData struct {
 	Data map[string]interface{}
 }
 
+// templateBatch is GOB encoded to share the dependency values of several
+// templates at once, keyed by template ID
+type templateBatch struct {
+	Templates map[string]templateData
+}
+
 // DedupManager is used to de-duplicate which instance of Consul-Template
 // is handling each template. For each template, a lock path is determined
 // using the MD5 of the template. This path is used to elect a "leader"
@@ -56,6 +78,16 @@ type templateData struct {
 // instance has 50 view queries, plus 50 additional queries on the lock
 // path for a total of 100.
 //
+// In sharded mode, each instance registers itself under the prefix and the
+// preferred leader of each template is chosen by rendezvous hashing across
+// the registered instances. Non-preferred instances defer to the preferred
+// one, so leadership (and fetch load) is spread across the instances instead
+// of concentrating on whichever started first.
+//
+// In batch mode, the leader does not write the data of each template as it
+// is rendered. Instead, the data of all the templates it leads is collected
+// and flushed periodically in as few values as possible under the session's
+// batch path. Followers watch all the batches with a single query.
 type DedupManager struct {
 	// config is the deduplicate configuration
 	config *config.DedupConfig
@@ -77,6 +109,12 @@ type DedupManager struct {
 	lastWrite     map[*template.Template][]byte
 	lastWriteLock sync.RWMutex
 
+	// batch holds the data of the templates we lead until it is flushed in
+	// batch mode, and batchDirty tracks if it changed since the last flush
+	batch      map[string]templateData
+	batchDirty bool
+	batchLock  sync.Mutex
+
 	// updateCh is used to indicate an update watched data
 	updateCh chan struct{}
 
@@ -97,6 +135,7 @@ func NewDedupManager(config *config.DedupConfig, clients *dep.ClientSet, brain *
 		templates: templates,
 		leader:    make(map[*template.Template]<-chan struct{}),
 		lastWrite: make(map[*template.Template][]byte),
+		batch:     make(map[string]templateData),
 		updateCh:  make(chan struct{}, 1),
 		stopCh:    make(chan struct{}),
 	}
@@ -110,7 +149,11 @@ func (d *DedupManager) Start() error {
 	client := d.clients.Consul()
 	go d.createSession(client)
 
-	// Start to watch each template
+	// Start to watch each template, or all the batches in batch mode
+	if *d.config.Batch {
+		go d.watchBatches(client)
+		return nil
+	}
 	for _, t := range d.templates {
 		go d.watchTemplate(client, t)
 	}
@@ -151,14 +194,31 @@ START:
 	}
 	log.Printf("[INFO] (dedup) created session %s", id)
 
+	// Register this instance so peers can compute the shard owners. The key is
+	// deleted along with the session.
+	if *d.config.Sharded {
+		key := path.Join(d.instancesPrefix(), id)
+		pair := &consulapi.KVPair{Key: key, Session: id}
+		if _, _, err := client.KV().Acquire(pair, nil); err != nil {
+			log.Printf("[WARN] (dedup) failed to register instance '%s': %v",
+				key, err)
+		}
+	}
+
 	// Attempt to lock each template
 	for _, t := range d.templates {
 		d.wg.Add(1)
 		go d.attemptLock(client, id, sessionCh, t)
 	}
 
+	// Flush the batched data periodically. This is not tracked by the wait
+	// group, since it only stops once the session is lost.
+	if *d.config.Batch {
+		go d.flushBatches(client, id, sessionCh)
+	}
+
 	// Renew our session periodically
-	if err := session.RenewPeriodic("15s", id, nil, d.stopCh); err != nil {
+	if err := session.RenewPeriodic(ttl, id, nil, d.stopCh); err != nil {
 		log.Printf("[ERR] (dedup) failed to renew session: %v", err)
 		d.wg.Wait()
 	}
@@ -212,17 +272,25 @@ func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) e
 		}
 	}
 
+	// In batch mode, hold the data until the next flush
+	if *d.config.Batch {
+		d.batchLock.Lock()
+		if existing, ok := d.batch[t.ID()]; !ok || !reflect.DeepEqual(existing, td) {
+			d.batch[t.ID()] = td
+			d.batchDirty = true
+		}
+		d.batchLock.Unlock()
+		return nil
+	}
+
 	// Encode via GOB and LZW compress
-	var buf bytes.Buffer
-	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
-	enc := gob.NewEncoder(compress)
-	if err := enc.Encode(&td); err != nil {
-		return fmt.Errorf("encode failed: %v", err)
+	raw, err := encodeData(&td)
+	if err != nil {
+		return err
 	}
-	compress.Close()
 
 	// Compute MD5 of the buffer
-	hash := md5.Sum(buf.Bytes())
+	hash := md5.Sum(raw)
 	d.lastWriteLock.RLock()
 	existing, ok := d.lastWrite[t]
 	d.lastWriteLock.RUnlock()
@@ -235,7 +303,7 @@ func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) e
 	// Write the KV update
 	kvPair := consulapi.KVPair{
 		Key:   dataPath,
-		Value: buf.Bytes(),
+		Value: raw,
 		Flags: templateDataFlag,
 	}
 	client := d.clients.Consul()
@@ -270,6 +338,13 @@ func (d *DedupManager) setLeader(tmpl *template.Template, lockCh <-chan struct{}
 		d.lastWriteLock.Lock()
 		delete(d.lastWrite, tmpl)
 		d.lastWriteLock.Unlock()
+
+		d.batchLock.Lock()
+		if _, ok := d.batch[tmpl.ID()]; ok {
+			delete(d.batch, tmpl.ID())
+			d.batchDirty = true
+		}
+		d.batchLock.Unlock()
 	}
 
 	// Do an async notify of an update
@@ -371,15 +446,9 @@ START:
 
 // parseData is used to update brain from a KV data pair
 func (d *DedupManager) parseData(path string, raw []byte) {
-	// Setup the decompression and decoders
-	r := bytes.NewReader(raw)
-	decompress := lzw.NewReader(r, lzw.LSB, 8)
-	defer decompress.Close()
-	dec := gob.NewDecoder(decompress)
-
 	// Decode the data
 	var td templateData
-	if err := dec.Decode(&td); err != nil {
+	if err := decodeData(raw, &td); err != nil {
 		log.Printf("[ERR] (dedup) failed to decode '%s': %v",
 			path, err)
 		return
@@ -409,7 +478,24 @@ START:
 		Session:          session,
 		MonitorRetries:   3,
 		MonitorRetryTime: 3 * time.Second,
+		LockWaitTime:     *d.config.LockWait,
 	}
+
+	// In sharded mode, give the preferred instance a head start and only make
+	// a single attempt, so this instance re-evaluates its preference if the
+	// lock stays held.
+	var rebalanceCh <-chan time.Time
+	if *d.config.Sharded && !d.isPreferred(client, session, t) {
+		select {
+		case <-time.After(*d.config.LockWait):
+		case <-sessionCh:
+			return
+		case <-d.stopCh:
+			return
+		}
+		lopts.LockTryOnce = true
+	}
+
 	lock, err := client.LockOpts(lopts)
 	if err != nil {
 		log.Printf("[ERR] (dedup) failed to create lock '%s': %v",
@@ -419,19 +505,37 @@ START:
 
 	var retryCh <-chan time.Time
 	leaderCh, err := lock.Lock(sessionCh)
-	if err != nil {
+	switch {
+	case err != nil:
 		log.Printf("[ERR] (dedup) failed to acquire lock '%s': %v",
 			lopts.Key, err)
 		retryCh = time.After(lockRetry)
-	} else {
+	case leaderCh == nil && lopts.LockTryOnce:
+		log.Printf("[DEBUG] (dedup) lock '%s' held by another instance", lopts.Key)
+		retryCh = time.After(0)
+	case leaderCh != nil:
 		log.Printf("[INFO] (dedup) acquired lock '%s'", lopts.Key)
 		d.setLeader(t, leaderCh)
+		if *d.config.Sharded {
+			rebalanceCh = time.After(shardRebalance)
+		}
 	}
 
+WAIT:
 	select {
 	case <-retryCh:
 		retryCh = nil
 		goto START
+	case <-rebalanceCh:
+		if d.isPreferred(client, session, t) {
+			rebalanceCh = time.After(shardRebalance)
+			goto WAIT
+		}
+		log.Printf("[INFO] (dedup) handing off lock '%s' to preferred instance",
+			lopts.Key)
+		d.setLeader(t, nil)
+		lock.Unlock()
+		goto START
 	case <-leaderCh:
 		log.Printf("[WARN] (dedup) lost lock ownership '%s'", lopts.Key)
 		d.setLeader(t, nil)
@@ -445,3 +549,321 @@ START:
 		lock.Unlock()
 	}
 }
+
+// instancesPrefix returns the KV prefix under which instances register
+// themselves in sharded mode.
+func (d *DedupManager) instancesPrefix() string {
+	return path.Join(*d.config.Prefix, "instances")
+}
+
+// isPreferred returns true if this instance is the preferred leader for the
+// given template. If the registered instances cannot be listed, every instance
+// is considered preferred and leadership falls back to a race for the lock.
+func (d *DedupManager) isPreferred(client *consulapi.Client, session string, t *template.Template) bool {
+	prefix := d.instancesPrefix() + "/"
+	keys, _, err := client.KV().Keys(prefix, "", nil)
+	if err != nil {
+		log.Printf("[WARN] (dedup) failed to list instances '%s': %v", prefix, err)
+		return true
+	}
+
+	instances := make([]string, 0, len(keys))
+	for _, k := range keys {
+		instances = append(instances, strings.TrimPrefix(k, prefix))
+	}
+
+	owner := shardOwner(instances, t.ID())
+	return owner == "" || owner == session
+}
+
+// shardOwner returns the instance with the highest rendezvous hash for the
+// given template ID, or the empty string if there are no instances. Every
+// instance computes the same owner from the same set of instances, and only
+// the templates owned by an instance move when it joins or leaves.
+func shardOwner(instances []string, id string) string {
+	var owner string
+	var best []byte
+	for _, instance := range instances {
+		score := md5.Sum([]byte(instance + "/" + id))
+		if owner == "" || bytes.Compare(score[:], best) > 0 {
+			owner = instance
+			best = score[:]
+		}
+	}
+	return owner
+}
+
+// batchPrefix returns the KV prefix under which the batched data of the given
+// session is written, or of all the sessions if session is empty.
+func (d *DedupManager) batchPrefix(session string) string {
+	return path.Join(*d.config.Prefix, "batch", session) + "/"
+}
+
+// flushBatches writes the batched data of the templates we lead every flush
+// interval, until the session is lost or the manager is stopped.
+func (d *DedupManager) flushBatches(client *consulapi.Client, session string, sessionCh chan struct{}) {
+	ticker := time.NewTicker(*d.config.FlushInterval)
+	defer ticker.Stop()
+
+	var written int
+	for {
+		select {
+		case <-ticker.C:
+			n, err := d.flushBatch(client, session, written)
+			if err != nil {
+				log.Printf("[ERR] (dedup) failed to flush batch: %v", err)
+				continue
+			}
+			written = n
+		case <-sessionCh:
+			return
+		case <-d.stopCh:
+			return
+		}
+	}
+}
+
+// flushBatch writes the batched data under the batch path of the session if
+// it changed since the last flush, and removes any values left over from a
+// previous flush which needed more of them. It returns the number of values
+// which are now written.
+func (d *DedupManager) flushBatch(client *consulapi.Client, session string, written int) (int, error) {
+	d.batchLock.Lock()
+	if !d.batchDirty {
+		d.batchLock.Unlock()
+		return written, nil
+	}
+	data := make(map[string]templateData, len(d.batch))
+	for id, td := range d.batch {
+		data[id] = td
+	}
+	d.batchDirty = false
+	d.batchLock.Unlock()
+
+	// Flag the batch as dirty again on failure, so the next flush retries
+	var err error
+	defer func() {
+		if err != nil {
+			d.batchLock.Lock()
+			d.batchDirty = true
+			d.batchLock.Unlock()
+		}
+	}()
+
+	chunks, err := encodeBatches(data, maxBatchSize)
+	if err != nil {
+		return written, err
+	}
+
+	prefix := d.batchPrefix(session)
+	for i, raw := range chunks {
+		// The values are tied to the session, so they are deleted along with it
+		key := prefix + strconv.Itoa(i)
+		pair := &consulapi.KVPair{
+			Key:     key,
+			Value:   raw,
+			Flags:   templateBatchFlag,
+			Session: session,
+		}
+		var ok bool
+		ok, _, err = client.KV().Acquire(pair, nil)
+		if err != nil {
+			return written, fmt.Errorf("failed to write '%s': %v", key, err)
+		}
+		if !ok {
+			err = fmt.Errorf("failed to write '%s': session %s is invalid", key, session)
+			return written, err
+		}
+	}
+
+	for i := len(chunks); i < written; i++ {
+		key := prefix + strconv.Itoa(i)
+		if _, err = client.KV().Delete(key, nil); err != nil {
+			return written, fmt.Errorf("failed to delete '%s': %v", key, err)
+		}
+	}
+
+	log.Printf("[INFO] (dedup) updated de-duplicate data for %d templates in %d values under '%s'",
+		len(data), len(chunks), prefix)
+	return len(chunks), nil
+}
+
+// watchBatches watches the batched data of every instance and loads the data
+// of the templates we do not lead into the brain.
+func (d *DedupManager) watchBatches(client *consulapi.Client) {
+	prefix := d.batchPrefix("")
+	log.Printf("[INFO] (dedup) starting watch for batches under '%s'", prefix)
+
+	// Setup our query options, allowing stale queries if configured
+	opts := &consulapi.QueryOptions{
+		AllowStale: *d.config.MaxStale != 0,
+		WaitTime:   60 * time.Second,
+	}
+
+	// seen tracks the modify index of each value already loaded
+	seen := make(map[string]uint64)
+
+	for {
+		// Stop listening if we're stopped
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
+
+		// Block for updates on the batch values
+		pairs, meta, err := client.KV().List(prefix, opts)
+		if err != nil {
+			log.Printf("[ERR] (dedup) failed to list '%s': %v", prefix, err)
+			select {
+			case <-time.After(listRetry):
+				continue
+			case <-d.stopCh:
+				return
+			}
+		}
+		opts.WaitIndex = meta.LastIndex
+
+		// If we've exceeded the maximum staleness, retry without stale
+		if opts.AllowStale && meta.LastContact > *d.config.MaxStale {
+			opts.AllowStale = false
+			log.Printf("[DEBUG] (dedup) %s stale data (last contact exceeded max_stale)", prefix)
+			continue
+		}
+
+		// Re-enable stale queries if allowed
+		opts.AllowStale = *d.config.MaxStale > 0
+
+		current := make(map[string]uint64, len(pairs))
+		var updated bool
+		for _, pair := range pairs {
+			current[pair.Key] = pair.ModifyIndex
+			if pair.Flags != templateBatchFlag || seen[pair.Key] == pair.ModifyIndex {
+				continue
+			}
+			if d.parseBatch(pair.Key, pair.Value) {
+				updated = true
+			}
+		}
+		seen = current
+
+		// Trigger the updateCh
+		if updated {
+			select {
+			case d.updateCh <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// parseBatch is used to update the brain from a KV batch pair. Only the data
+// of the templates we know of and do not lead is loaded. It returns true if
+// any data was loaded.
+func (d *DedupManager) parseBatch(path string, raw []byte) bool {
+	var tb templateBatch
+	if err := decodeData(raw, &tb); err != nil {
+		log.Printf("[ERR] (dedup) failed to decode '%s': %v", path, err)
+		return false
+	}
+
+	var loaded bool
+	for _, t := range d.templates {
+		td, ok := tb.Templates[t.ID()]
+		if !ok || d.IsLeader(t) {
+			continue
+		}
+		log.Printf("[INFO] (dedup) loading %d dependencies for template hash %s from '%s'",
+			len(td.Data), t.ID(), path)
+
+		// Update the data in the brain
+		for hashCode, value := range td.Data {
+			d.brain.ForceSet(hashCode, value)
+		}
+		loaded = true
+	}
+	return loaded
+}
+
+// encodeBatches encodes the data of the given templates into as few values as
+// possible, each no larger than limit. Templates are split across the values
+// in order of their ID.
+func encodeBatches(data map[string]templateData, limit int) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(data))
+	for id := range data {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Double the number of values until each of them fits
+	for n := 1; ; n *= 2 {
+		if n > len(ids) {
+			n = len(ids)
+		}
+
+		chunks, fits, err := encodeChunks(data, ids, n, limit)
+		if err != nil {
+			return nil, err
+		}
+		if fits {
+			return chunks, nil
+		}
+		if n == len(ids) {
+			return nil, fmt.Errorf("encoded data for a template exceeds %d bytes", limit)
+		}
+	}
+}
+
+// encodeChunks encodes the templates with the given IDs split into n values.
+// It returns false if any of the values is larger than limit.
+func encodeChunks(data map[string]templateData, ids []string, n, limit int) ([][]byte, bool, error) {
+	chunks := make([][]byte, 0, n)
+	size := (len(ids) + n - 1) / n
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		tb := templateBatch{
+			Templates: make(map[string]templateData, end-start),
+		}
+		for _, id := range ids[start:end] {
+			tb.Templates[id] = data[id]
+		}
+
+		raw, err := encodeData(&tb)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(raw) > limit {
+			return nil, false, nil
+		}
+		chunks = append(chunks, raw)
+	}
+	return chunks, true, nil
+}
+
+// encodeData encodes the given value via GOB and LZW compresses it.
+func encodeData(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
+	enc := gob.NewEncoder(compress)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("encode failed: %v", err)
+	}
+	compress.Close()
+	return buf.Bytes(), nil
+}
+
+// decodeData decompresses and decodes a value encoded by encodeData into v.
+func decodeData(raw []byte, v interface{}) error {
+	r := bytes.NewReader(raw)
+	decompress := lzw.NewReader(r, lzw.LSB, 8)
+	defer decompress.Close()
+	return gob.NewDecoder(decompress).Decode(v)
+}
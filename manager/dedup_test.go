@@ -1,7 +1,10 @@
 package manager
 
 import (
+	"fmt"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,11 +27,12 @@ func testDedupManager(t *testing.T, addr string, tmpls []*template.Template) *De
 		Consul: config.String(addr),
 	})
 
-	// Create the clientset
-	clients, err := newClientSet(c)
+	// Create the clientset the way the runner does
+	r, err := NewRunner(c, true, false)
 	if err != nil {
 		t.Fatalf("runner: %s", err)
 	}
+	clients := r.clients
 
 	// Setup a brain
 	brain := template.NewBrain()
@@ -208,3 +212,192 @@ func TestDedup_FollowerUpdate(t *testing.T) {
 		t.Fatalf("bad: %v", data)
 	}
 }
+
+func TestDedup_shardOwner(t *testing.T) {
+	t.Parallel()
+
+	if owner := shardOwner(nil, "abcd"); owner != "" {
+		t.Fatalf("expected no owner, got %q", owner)
+	}
+
+	instances := []string{"a", "b", "c", "d"}
+
+	// Every instance must agree on the owner, regardless of list order.
+	owner := shardOwner(instances, "abcd")
+	reversed := []string{"d", "c", "b", "a"}
+	if act := shardOwner(reversed, "abcd"); act != owner {
+		t.Fatalf("expected %q to be %q", act, owner)
+	}
+
+	// Templates should be spread across the instances.
+	owners := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		owners[shardOwner(instances, fmt.Sprintf("template-%d", i))] = struct{}{}
+	}
+	if len(owners) != len(instances) {
+		t.Errorf("expected %d owners, got %d", len(instances), len(owners))
+	}
+
+	// Removing an instance that does not own a template must not move it.
+	var remaining []string
+	for _, i := range instances {
+		if i != owner {
+			remaining = append(remaining, i)
+		}
+	}
+	if act := shardOwner(append(remaining[1:], owner), "abcd"); act != owner {
+		t.Errorf("expected %q to be %q", act, owner)
+	}
+}
+
+func TestDedup_BatchFollowerUpdate(t *testing.T) {
+	t.Parallel()
+
+	// Create a template
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: `{{ range service "consul" }}{{ .Node }}{{ end }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consul := testConsulServer(t)
+	defer consul.Stop()
+
+	dedup1 := testDedupManager(t, consul.HTTPAddr, []*template.Template{tmpl})
+	dedup1.config.Batch = config.Bool(true)
+	dedup1.config.FlushInterval = config.TimeDuration(100 * time.Millisecond)
+	if err := dedup1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer dedup1.Stop()
+
+	dedup2 := testDedupManager(t, consul.HTTPAddr, []*template.Template{tmpl})
+	dedup2.config.Batch = config.Bool(true)
+	dedup2.config.FlushInterval = config.TimeDuration(100 * time.Millisecond)
+	if err := dedup2.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer dedup2.Stop()
+
+	// Wait until we have a leader
+	var leader, follow *DedupManager
+	select {
+	case <-dedup1.UpdateCh():
+		if dedup1.IsLeader(tmpl) {
+			leader = dedup1
+			follow = dedup2
+		}
+	case <-dedup2.UpdateCh():
+		if dedup2.IsLeader(tmpl) {
+			leader = dedup2
+			follow = dedup1
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	// Create the dependency
+	dep, err := dependency.NewHealthServiceQuery("consul")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inject data into the brain
+	leader.brain.Remember(dep, 123)
+
+	// Update the dependencies
+	err = leader.UpdateDeps(tmpl, []dependency.Dependency{dep})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Follower should get an update once the batch is flushed
+	select {
+	case <-follow.UpdateCh():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	// Recall from the brain
+	data, ok := follow.brain.Recall(dep)
+	if !ok {
+		t.Fatalf("missing data")
+	}
+	if data != 123 {
+		t.Fatalf("bad: %v", data)
+	}
+}
+
+func TestDedup_encodeBatches(t *testing.T) {
+	t.Parallel()
+
+	data := make(map[string]templateData)
+	for i := 0; i < 10; i++ {
+		data[fmt.Sprintf("template-%d", i)] = templateData{
+			Data: map[string]interface{}{
+				fmt.Sprintf("key(%d)", i): strings.Repeat(fmt.Sprintf("%d", i), 100),
+			},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		limit int
+		n     int
+		err   bool
+	}{
+		{
+			"single",
+			maxBatchSize,
+			1,
+			false,
+		},
+		{
+			"split",
+			200,
+			10,
+			false,
+		},
+		{
+			"too_large",
+			10,
+			0,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			chunks, err := encodeBatches(data, tc.limit)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if len(chunks) != tc.n {
+				t.Fatalf("\nexp: %#v\nact: %#v", tc.n, len(chunks))
+			}
+
+			act := make(map[string]templateData)
+			for _, raw := range chunks {
+				if len(raw) > tc.limit {
+					t.Errorf("value of %d bytes exceeds %d", len(raw), tc.limit)
+				}
+
+				var tb templateBatch
+				if err := decodeData(raw, &tb); err != nil {
+					t.Fatal(err)
+				}
+				for id, td := range tb.Templates {
+					act[id] = td
+				}
+			}
+
+			if tc.err {
+				return
+			}
+			if !reflect.DeepEqual(data, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", data, act)
+			}
+		})
+	}
+}
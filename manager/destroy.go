@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// DestroyRemovedTemplates destroys each template of this runner which is not
+// part of the configuration of next, the runner replacing it on reload. The
+// destination of a destroyed template is deleted if delete_on_destroy is set,
+// and then its destroy command is executed. Templates are matched by their
+// destination. Nothing is destroyed in dry or observe mode.
+func (r *Runner) DestroyRemovedTemplates(next *Runner) error {
+	if r.dry || r.observe || next == nil {
+		return nil
+	}
+
+	var result *multierror.Error
+	for _, tc := range removedTemplates(*r.config.Templates, *next.config.Templates) {
+		if err := r.destroyTemplate(tc); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// removedTemplates returns the template configs in prev whose destination is
+// not the destination of any template config in cur.
+func removedTemplates(prev, cur config.TemplateConfigs) []*config.TemplateConfig {
+	kept := make(map[string]struct{}, len(cur))
+	for _, tc := range cur {
+		kept[config.StringVal(tc.Destination)] = struct{}{}
+	}
+
+	var removed []*config.TemplateConfig
+	for _, tc := range prev {
+		dest := config.StringVal(tc.Destination)
+		if dest == "" {
+			continue
+		}
+		if _, ok := kept[dest]; ok {
+			continue
+		}
+		removed = append(removed, tc)
+	}
+	return removed
+}
+
+// destroyTemplate deletes the destination of the given removed template, if
+// configured, and executes its destroy command.
+func (r *Runner) destroyTemplate(tc *config.TemplateConfig) error {
+	templateLogf(tc, "INFO", "destroying removed template %s", tc.Display())
+
+	if config.BoolVal(tc.DeleteOnDestroy) {
+		dest := config.StringVal(tc.Destination)
+		templateLogf(tc, "DEBUG", "deleting destination %s", dest)
+		remove := os.Remove
+		if config.BoolVal(tc.SplitDestination) {
+			remove = removeSplit
+		} else if config.StringVal(tc.RenderStrategy) == config.TemplateRenderStrategySymlink {
+			remove = removeSymlink
+		}
+		if err := remove(dest); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, fmt.Sprintf("failed to delete destination of %s", tc.Display()))
+		}
+	}
+
+	command := config.StringVal(tc.DestroyCommand)
+	if command == "" {
+		return nil
+	}
+
+	templateLogf(tc, "INFO", "executing destroy command %q from %s", command, tc.Display())
+	env := tc.Exec.Env.Copy()
+	env.Custom = append(r.childEnv(), env.Custom...)
+	if _, err := spawnChild(&spawnChildInput{
+		Stdin:        r.inStream,
+		Stdout:       r.outStream,
+		Stderr:       r.errStream,
+		Command:      command,
+		Env:          env.Env(),
+		Timeout:      config.TimeDurationVal(tc.Exec.Timeout),
+		ReloadSignal: config.SignalVal(tc.Exec.ReloadSignal),
+		KillSignal:   config.SignalVal(tc.Exec.KillSignal),
+		KillTimeout:  config.TimeDurationVal(tc.Exec.KillTimeout),
+		Splay:        config.TimeDurationVal(tc.Exec.Splay),
+	}); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to execute destroy command %q from %s",
+			command, tc.Display()))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRemovedTemplates(t *testing.T) {
+	t.Parallel()
+
+	a := &config.TemplateConfig{Destination: config.String("a")}
+	b := &config.TemplateConfig{Destination: config.String("b")}
+	dry := &config.TemplateConfig{Destination: config.String("")}
+
+	cases := []struct {
+		name string
+		prev config.TemplateConfigs
+		cur  config.TemplateConfigs
+		exp  []*config.TemplateConfig
+	}{
+		{
+			"none_removed",
+			config.TemplateConfigs{a, b},
+			config.TemplateConfigs{a, b},
+			nil,
+		},
+		{
+			"removed",
+			config.TemplateConfigs{a, b},
+			config.TemplateConfigs{a},
+			[]*config.TemplateConfig{b},
+		},
+		{
+			"kept_by_destination",
+			config.TemplateConfigs{a},
+			config.TemplateConfigs{&config.TemplateConfig{
+				Contents:    config.String("changed"),
+				Destination: config.String("a"),
+			}},
+			nil,
+		},
+		{
+			"no_destination",
+			config.TemplateConfigs{dry},
+			config.TemplateConfigs{},
+			nil,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := removedTemplates(tc.prev, tc.cur)
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestRunner_DestroyRemovedTemplates(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kept := filepath.Join(dir, "kept")
+	removed := filepath.Join(dir, "removed")
+	marker := filepath.Join(dir, "marker")
+	for _, path := range []string{kept, removed} {
+		if err := ioutil.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newRunner := func(tcs ...*config.TemplateConfig) *Runner {
+		templates := config.TemplateConfigs(tcs)
+		c := config.DefaultConfig().Merge(&config.Config{
+			Templates: &templates,
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	prev := newRunner(
+		&config.TemplateConfig{
+			Contents:    config.String("kept"),
+			Destination: config.String(kept),
+		},
+		&config.TemplateConfig{
+			Contents:        config.String("removed"),
+			DeleteOnDestroy: config.Bool(true),
+			Destination:     config.String(removed),
+			DestroyCommand:  config.String("touch " + marker),
+		},
+	)
+	next := newRunner(&config.TemplateConfig{
+		Contents:    config.String("kept"),
+		Destination: config.String(kept),
+	})
+
+	if err := prev.DestroyRemovedTemplates(next); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("expected %s to exist: %s", kept, err)
+	}
+	if _, err := os.Stat(removed); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be deleted: %v", removed, err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected destroy command to run: %s", err)
+	}
+}
@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// encodeContents applies the given template encoding to the rendered contents.
+// The encoded contents are stable for the same input, so unchanged templates
+// are not rewritten. An empty encoding returns the contents as-is.
+func encodeContents(encoding string, contents []byte) ([]byte, error) {
+	switch encoding {
+	case "":
+		return contents, nil
+	case config.TemplateEncodingGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(contents); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case config.TemplateEncodingBase64:
+		encoded := make([]byte, base64.StdEncoding.EncodedLen(len(contents)))
+		base64.StdEncoding.Encode(encoded, contents)
+		return encoded, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
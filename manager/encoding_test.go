@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEncodeContents(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		encoding string
+		decode   func([]byte) ([]byte, error)
+		err      bool
+	}{
+		{
+			"none",
+			"",
+			func(b []byte) ([]byte, error) { return b, nil },
+			false,
+		},
+		{
+			"gzip",
+			"gzip",
+			func(b []byte) ([]byte, error) {
+				r, err := gzip.NewReader(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(r)
+			},
+			false,
+		},
+		{
+			"base64",
+			"base64",
+			func(b []byte) ([]byte, error) {
+				if exp, act := "aGVsbG8gd29ybGQ=", string(b); exp != act {
+					return nil, fmt.Errorf("expected %q to be %q", act, exp)
+				}
+				return []byte("hello world"), nil
+			},
+			false,
+		},
+		{
+			"unknown",
+			"zip",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := encodeContents(tc.encoding, []byte("hello world"))
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.err {
+				return
+			}
+
+			// Encoding the same contents twice must give the same bytes, so
+			// unchanged templates are not rewritten.
+			again, err := encodeContents(tc.encoding, []byte("hello world"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(act, again) {
+				t.Errorf("\nexp: %#v\nact: %#v", act, again)
+			}
+
+			decoded, err := tc.decode(act)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := "hello world"; string(decoded) != exp {
+				t.Errorf("\nexp: %#v\nact: %#v", exp, string(decoded))
+			}
+		})
+	}
+}
@@ -29,3 +29,35 @@ func (e *ErrChildDied) Error() string {
 func (e *ErrChildDied) ExitStatus() int {
 	return e.code
 }
+
+var _ error = new(ErrTemplateChildDied)
+var _ ErrExitable = new(ErrTemplateChildDied)
+
+// ErrTemplateChildDied is the error returned when the supervised process of a
+// template prematurely dies.
+type ErrTemplateChildDied struct {
+	// TemplateID is the ID of the template the process was supervised for.
+	TemplateID string
+
+	// Command is the command of the process.
+	Command string
+
+	code int
+}
+
+// NewErrTemplateChildDied creates a new error for the supervised process of
+// the given template with the given exit code.
+func NewErrTemplateChildDied(tmplID, command string, c int) *ErrTemplateChildDied {
+	return &ErrTemplateChildDied{TemplateID: tmplID, Command: command, code: c}
+}
+
+// Error implements the error interface.
+func (e *ErrTemplateChildDied) Error() string {
+	return fmt.Sprintf("supervised process %q of template %s died with exit code %d",
+		e.Command, e.TemplateID, e.code)
+}
+
+// ExitStatus implements the ErrExitable interface.
+func (e *ErrTemplateChildDied) ExitStatus() int {
+	return e.code
+}
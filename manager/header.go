@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/pkg/errors"
+)
+
+// headerData is the data the header template of a template configuration is
+// executed with.
+type headerData struct {
+	Name        string
+	Source      string
+	Destination string
+	Time        string
+}
+
+// newHeader parses the header template of the given template configuration,
+// or returns nil if it does not add a header. Headers are only added to files
+// written as-is, so they cannot be combined with split destinations, encodings
+// or bundles.
+func newHeader(tc *config.TemplateConfig) (*texttemplate.Template, error) {
+	if !config.BoolVal(tc.AddHeader) {
+		return nil, nil
+	}
+
+	switch {
+	case config.BoolVal(tc.SplitDestination):
+		return nil, fmt.Errorf("add_header cannot be used with split_destination")
+	case config.StringVal(tc.Encoding) != "":
+		return nil, fmt.Errorf("add_header cannot be used with encoding")
+	case config.StringVal(tc.Bundle) != "":
+		return nil, fmt.Errorf("add_header cannot be used with bundle")
+	}
+
+	t, err := texttemplate.New("header").
+		Option("missingkey=error").
+		Parse(config.StringVal(tc.HeaderTemplate))
+	if err != nil {
+		return nil, errors.Wrap(err, "header_template")
+	}
+	return t, nil
+}
+
+// renderHeader executes the given header template for the template
+// configuration rendered at the given time. The header always ends with a
+// newline, so it takes whole lines of the destination.
+func renderHeader(t *texttemplate.Template, tc *config.TemplateConfig, now time.Time) ([]byte, error) {
+	source := config.StringVal(tc.Source)
+	if config.StringPresent(tc.Contents) {
+		source = "(dynamic)"
+	}
+
+	name := config.StringVal(tc.Name)
+	if name == "" {
+		name = config.StringVal(tc.ID)
+	}
+	if name == "" {
+		name = filepath.Base(source)
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, &headerData{
+		Name:        name,
+		Source:      source,
+		Destination: config.StringVal(tc.Destination),
+		Time:        now.Format(time.RFC3339),
+	}); err != nil {
+		return nil, errors.Wrap(err, "header_template")
+	}
+
+	header := b.Bytes()
+	if len(header) > 0 && header[len(header)-1] != '\n' {
+		header = append(header, '\n')
+	}
+	return header, nil
+}
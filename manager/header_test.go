@@ -0,0 +1,186 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewHeader(t *testing.T) {
+	cases := []struct {
+		name string
+		c    *config.TemplateConfig
+		nil  bool
+		err  string
+	}{
+		{
+			"disabled",
+			&config.TemplateConfig{},
+			true,
+			"",
+		},
+		{
+			"default",
+			&config.TemplateConfig{AddHeader: config.Bool(true)},
+			false,
+			"",
+		},
+		{
+			"custom",
+			&config.TemplateConfig{HeaderTemplate: config.String("// {{ .Name }}")},
+			false,
+			"",
+		},
+		{
+			"invalid",
+			&config.TemplateConfig{HeaderTemplate: config.String("{{ .Name ")},
+			false,
+			"header_template",
+		},
+		{
+			"split_destination",
+			&config.TemplateConfig{
+				AddHeader:        config.Bool(true),
+				SplitDestination: config.Bool(true),
+			},
+			false,
+			"split_destination",
+		},
+		{
+			"encoding",
+			&config.TemplateConfig{
+				AddHeader: config.Bool(true),
+				Encoding:  config.String(config.TemplateEncodingGzip),
+			},
+			false,
+			"encoding",
+		},
+		{
+			"bundle",
+			&config.TemplateConfig{
+				AddHeader: config.Bool(true),
+				Bundle:    config.String("configs"),
+			},
+			false,
+			"bundle",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.c.Finalize()
+			h, err := newHeader(tc.c)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (h == nil) != tc.nil {
+				t.Errorf("expected nil to be %t, got %v", tc.nil, h)
+			}
+		})
+	}
+}
+
+func TestRenderHeader(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	c := &config.TemplateConfig{
+		AddHeader:   config.Bool(true),
+		Source:      config.String("/etc/templates/app.conf.tpl"),
+		Destination: config.String("/etc/app.conf"),
+	}
+	c.Finalize()
+	h, err := newHeader(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := renderHeader(h, c, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "# Managed by Consul Template. DO NOT EDIT: changes will be overwritten.\n" +
+		"# Template: app.conf.tpl\n" +
+		"# Source: /etc/templates/app.conf.tpl\n" +
+		"# Rendered: 2020-01-02T03:04:05Z\n"
+	if string(act) != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, act)
+	}
+
+	// Custom headers are ended with a newline.
+	c = &config.TemplateConfig{
+		Contents:       config.String("contents"),
+		HeaderTemplate: config.String("// {{ .Name }} {{ .Source }} => {{ .Destination }}"),
+		Name:           config.String("app"),
+		Destination:    config.String("/etc/app.conf"),
+	}
+	c.Finalize()
+	if h, err = newHeader(c); err != nil {
+		t.Fatal(err)
+	}
+	if act, err = renderHeader(h, c, now); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "// app (dynamic) => /etc/app.conf\n"; string(act) != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, act)
+	}
+}
+
+func TestRender_header(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	path := filepath.Join(outDir, "out")
+	render := func(header, contents string) bool {
+		result, err := Render(&RenderInput{
+			Contents: []byte(contents),
+			Header:   []byte(header),
+			Path:     path,
+			Perms:    0644,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.DidRender
+	}
+
+	if !render("# rendered at 1\n", "a\n") {
+		t.Error("expected a new destination to render")
+	}
+	act, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "# rendered at 1\na\n"; string(act) != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, act)
+	}
+
+	// A new header alone does not rewrite the destination.
+	if render("# rendered at 2\n", "a\n") {
+		t.Error("expected unchanged contents not to render")
+	}
+	if !render("# rendered at 3\n", "b\n") {
+		t.Error("expected changed contents to render")
+	}
+
+	// A destination without the header is rewritten.
+	if err := ioutil.WriteFile(path, []byte("b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !render("# rendered at 4\n", "b\n") {
+		t.Error("expected a destination without the header to render")
+	}
+}
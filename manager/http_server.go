@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// httpServer is an HTTP server which can be closed. Closing it closes its
+// listeners and its active connections, so long-lived requests such as event
+// streams end too.
+type httpServer struct {
+	*http.Server
+
+	lock      sync.Mutex
+	closed    bool
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+}
+
+// newHTTPServer creates a new server for the given handler.
+func newHTTPServer(h http.Handler) *httpServer {
+	s := &httpServer{conns: make(map[net.Conn]struct{})}
+	s.Server = &http.Server{
+		Handler:   h,
+		ConnState: s.trackConn,
+	}
+	return s
+}
+
+// Serve accepts connections on the given listener until the server is closed,
+// when it returns nil.
+func (s *httpServer) Serve(ln net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		ln.Close()
+		return nil
+	}
+	s.listeners = append(s.listeners, ln)
+	s.lock.Unlock()
+
+	err := s.Server.Serve(ln)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil
+	}
+	return err
+}
+
+// Close closes the listeners and the active connections of the server.
+func (s *httpServer) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+// trackConn records the active connections of the server.
+func (s *httpServer) trackConn(c net.Conn, state http.ConnState) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch state {
+	case http.StateNew:
+		if s.closed {
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+	case http.StateClosed, http.StateHijacked:
+		delete(s.conns, c)
+	}
+}
@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// childListener is a listening socket passed to the exec child.
+type childListener struct {
+	listener net.Listener
+	file     *os.File
+}
+
+// childListeners holds the sockets passed to the exec child, keyed by address.
+// They outlive the runner so that a configuration reload, which replaces the
+// runner and restarts the child, never closes a listener which is still
+// configured.
+var childListeners = struct {
+	sync.Mutex
+	m map[string]*childListener
+}{m: make(map[string]*childListener)}
+
+// childListenerFiles returns the sockets for the given addresses, in order,
+// opening any which are not already open. Sockets for addresses which are no
+// longer given are closed.
+func childListenerFiles(addrs []string) ([]*os.File, error) {
+	childListeners.Lock()
+	defer childListeners.Unlock()
+
+	keep := make(map[string]struct{}, len(addrs))
+	files := make([]*os.File, 0, len(addrs))
+	for _, addr := range addrs {
+		keep[addr] = struct{}{}
+
+		l, ok := childListeners.m[addr]
+		if !ok {
+			var err error
+			l, err = openChildListener(addr)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("[INFO] (runner) listening on %s for child", addr)
+			childListeners.m[addr] = l
+		}
+		files = append(files, l.file)
+	}
+
+	for addr, l := range childListeners.m {
+		if _, ok := keep[addr]; !ok {
+			log.Printf("[INFO] (runner) closing child listener on %s", addr)
+			l.file.Close()
+			l.listener.Close()
+			delete(childListeners.m, addr)
+		}
+	}
+
+	return files, nil
+}
+
+// openChildListener listens on the given address, which is in the form
+// "network://address", such as "tcp://0.0.0.0:8080" or "unix:///run/app.sock".
+func openChildListener(addr string) (*childListener, error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("runner: invalid listener address %q", addr)
+	}
+	network, address := parts[0], parts[1]
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("runner: failed to listen on %s: %s", addr, err)
+	}
+
+	var f *os.File
+	if l, ok := ln.(interface {
+		File() (*os.File, error)
+	}); ok {
+		f, err = l.File()
+	} else {
+		err = fmt.Errorf("unsupported network %q", network)
+	}
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("runner: failed to listen on %s: %s", addr, err)
+	}
+
+	return &childListener{
+		listener: ln,
+		file:     f,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"net"
+	"testing"
+)
+
+func TestChildListenerFiles(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "tcp://" + ln.Addr().String()
+	ln.Close()
+
+	first, err := childListenerFiles([]string{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(first))
+	}
+
+	// The same socket is returned while the address is configured, so it is
+	// never closed across restarts.
+	second, err := childListenerFiles([]string{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first[0] != second[0] {
+		t.Errorf("expected the listener for %s to be reused", addr)
+	}
+
+	// Removing the address closes the socket.
+	if _, err := childListenerFiles(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Errorf("expected the listener for %s to be closed", addr)
+	}
+}
+
+func TestChildListenerFiles_invalid(t *testing.T) {
+	if _, err := childListenerFiles([]string{"127.0.0.1:0"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
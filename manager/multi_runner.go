@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// multiRunnerNameRe matches the names of the runners of a MultiRunner, which
+// are used in the paths of the control interface and in metric labels.
+var multiRunnerNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// MultiRunner runs several independent runners in one process. Each runner has
+// its own configuration, clients, brain and watcher, and renders its templates
+// as it would in a process of its own. The metrics of all the runners are
+// exported by a single telemetry server, and all of them are operated through
+// a single control interface.
+type MultiRunner struct {
+	// ErrCh and DoneCh are channels where errors and finish notifications
+	// occur. Errors are prefixed with the name of the runner they come from.
+	// DoneCh is closed once every runner is done.
+	ErrCh  chan error
+	DoneCh chan struct{}
+
+	// ReloadCh receives the name of each runner whose reload was requested
+	// through the control interface.
+	ReloadCh chan string
+
+	names   []string
+	runners map[string]*Runner
+
+	// telemetry and control are the shared servers, if enabled.
+	telemetry *telemetryServer
+	control   *controlServer
+
+	stopLock sync.Mutex
+	stopped  bool
+	doneOnce sync.Once
+}
+
+// NewMultiRunnerInput is used as input when creating a MultiRunner.
+type NewMultiRunnerInput struct {
+	// Configs are the configurations of the runners, keyed by their names. The
+	// telemetry and control settings of each configuration are ignored in
+	// favor of the shared ones.
+	Configs map[string]*config.Config
+
+	// Telemetry and Control configure the shared telemetry server and control
+	// interface. Nil values disable them.
+	Telemetry *config.TelemetryConfig
+	Control   *config.ControlConfig
+
+	// Dry and Once are the modes of all the runners.
+	Dry  bool
+	Once bool
+}
+
+// NewMultiRunner creates the runners of the given configurations. If any of
+// them fails, the runners already created are stopped.
+func NewMultiRunner(i *NewMultiRunnerInput) (*MultiRunner, error) {
+	if len(i.Configs) == 0 {
+		return nil, fmt.Errorf("multi: at least one runner is required")
+	}
+
+	m := &MultiRunner{
+		ErrCh:    make(chan error),
+		DoneCh:   make(chan struct{}),
+		ReloadCh: make(chan string, len(i.Configs)),
+		runners:  make(map[string]*Runner, len(i.Configs)),
+	}
+
+	for name := range i.Configs {
+		if !multiRunnerNameRe.MatchString(name) {
+			return nil, fmt.Errorf("multi: invalid runner name %q", name)
+		}
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+
+	for _, name := range m.names {
+		c := i.Configs[name].Copy()
+		if c.Telemetry != nil && config.IntVal(c.Telemetry.PrometheusPort) > 0 {
+			log.Printf("[WARN] (multi) ignoring the telemetry of runner %s, which is shared", name)
+		}
+		if c.Control != nil && (config.BoolVal(c.Control.Enabled) ||
+			config.StringPresent(c.Control.Path) || config.StringPresent(c.Control.Address)) {
+			log.Printf("[WARN] (multi) ignoring the control interface of runner %s, which is shared", name)
+		}
+		c.Telemetry = &config.TelemetryConfig{PrometheusPort: config.Int(0)}
+		c.Control = &config.ControlConfig{Enabled: config.Bool(false)}
+		c.Telemetry.Finalize()
+		c.Control.Finalize()
+
+		r, err := NewRunner(c, i.Dry, i.Once)
+		if err != nil {
+			m.Stop()
+			return nil, fmt.Errorf("multi: %s: %s", name, err)
+		}
+		m.runners[name] = r
+	}
+
+	if i.Telemetry != nil {
+		tc := config.DefaultTelemetryConfig().Merge(i.Telemetry)
+		tc.Finalize()
+		if config.IntVal(tc.PrometheusPort) > 0 {
+			runners := make([]*metricsRunner, 0, len(m.names))
+			for _, name := range m.names {
+				runners = append(runners, &metricsRunner{name: name, runner: m.runners[name]})
+			}
+			m.telemetry = newSharedTelemetryServer(tc, runners)
+		}
+	}
+
+	if i.Control != nil {
+		cc := config.DefaultControlConfig().Merge(i.Control)
+		cc.Finalize()
+		if config.BoolVal(cc.Enabled) && !i.Once {
+			control, err := newSharedControlServer(cc, m.names, m.runners)
+			if err != nil {
+				m.Stop()
+				return nil, err
+			}
+			m.control = control
+		}
+	}
+
+	return m, nil
+}
+
+// Names returns the names of the runners, sorted.
+func (m *MultiRunner) Names() []string {
+	return append([]string{}, m.names...)
+}
+
+// Runner returns the runner with the given name, or nil if there is none.
+func (m *MultiRunner) Runner(name string) *Runner {
+	return m.runners[name]
+}
+
+// Start starts the shared servers and all the runners, and blocks until every
+// runner is done. It should be called as a goroutine.
+func (m *MultiRunner) Start() {
+	log.Printf("[INFO] (multi) starting %d runners", len(m.names))
+
+	if m.telemetry != nil {
+		if err := m.telemetry.Start(); err != nil {
+			m.ErrCh <- err
+			return
+		}
+	}
+	if m.control != nil {
+		if err := m.control.Start(); err != nil {
+			m.ErrCh <- err
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range m.names {
+		r := m.runners[name]
+		wg.Add(1)
+		go r.Start()
+		go func(name string, r *Runner) {
+			defer wg.Done()
+			m.forward(name, r)
+		}(name, r)
+	}
+	wg.Wait()
+
+	m.doneOnce.Do(func() { close(m.DoneCh) })
+}
+
+// forward forwards the errors and reload requests of the given runner until
+// it is done.
+func (m *MultiRunner) forward(name string, r *Runner) {
+	for {
+		select {
+		case err := <-r.ErrCh:
+			select {
+			case m.ErrCh <- fmt.Errorf("%s: %s", name, err):
+			case <-r.DoneCh:
+				return
+			}
+		case <-r.ReloadCh:
+			select {
+			case m.ReloadCh <- name:
+			default:
+			}
+		case <-r.DoneCh:
+			return
+		}
+	}
+}
+
+// Stop stops the shared servers and all the runners.
+func (m *MultiRunner) Stop() {
+	m.stopLock.Lock()
+	defer m.stopLock.Unlock()
+
+	if m.stopped {
+		return
+	}
+
+	log.Printf("[INFO] (multi) stopping")
+	if m.control != nil {
+		m.control.Stop()
+	}
+	if m.telemetry != nil {
+		m.telemetry.Stop()
+	}
+	for _, name := range m.names {
+		if r, ok := m.runners[name]; ok {
+			r.Stop()
+		}
+	}
+
+	m.stopped = true
+	m.doneOnce.Do(func() { close(m.DoneCh) })
+}
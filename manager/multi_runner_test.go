@@ -0,0 +1,187 @@
+package manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func testMultiRunnerConfig(t *testing.T) (*config.Config, func()) {
+	out, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.TestConfig(&config.Config{
+		Telemetry: &config.TelemetryConfig{
+			PrometheusPort: config.Int(9312),
+		},
+		Control: &config.ControlConfig{
+			Path: config.String("/tmp/ct-multi.sock"),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out.Name()),
+			},
+		},
+	})
+	return c, func() { os.Remove(out.Name()) }
+}
+
+func TestNewMultiRunner_invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		configs map[string]*config.Config
+		err     string
+	}{
+		{
+			"empty",
+			map[string]*config.Config{},
+			"at least one runner",
+		},
+		{
+			"invalid_name",
+			map[string]*config.Config{"a/b": config.TestConfig(nil)},
+			"invalid runner name",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewMultiRunner(&NewMultiRunnerInput{Configs: tc.configs})
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestMultiRunner_shared(t *testing.T) {
+	t.Parallel()
+
+	a, cleanupA := testMultiRunnerConfig(t)
+	defer cleanupA()
+	b, cleanupB := testMultiRunnerConfig(t)
+	defer cleanupB()
+
+	m, err := NewMultiRunner(&NewMultiRunnerInput{
+		Configs: map[string]*config.Config{"b": b, "a": a},
+		Telemetry: &config.TelemetryConfig{
+			PrometheusPort: config.Int(9313),
+		},
+		Control: &config.ControlConfig{
+			Path: config.String("/tmp/ct-multi-shared.sock"),
+		},
+		Dry: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	if exp, act := []string{"a", "b"}, m.Names(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("expected %q, got %q", exp, act)
+	}
+	for _, name := range m.Names() {
+		r := m.Runner(name)
+		if r.telemetry != nil || r.control != nil {
+			t.Errorf("expected the servers of runner %s to be shared", name)
+		}
+	}
+	if m.telemetry == nil || m.control == nil {
+		t.Fatal("expected the shared servers to be enabled")
+	}
+
+	// The configurations of the callers are left untouched.
+	if config.IntVal(a.Telemetry.PrometheusPort) != 9312 {
+		t.Errorf("expected the configuration of runner a to be copied")
+	}
+
+	for _, name := range m.Names() {
+		r := m.Runner(name)
+		r.outStream = ioutil.Discard
+		if err := r.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.telemetry.handleMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, exp := range []string{
+		`consul_template_watcher_views{runner="a"} 0`,
+		`consul_template_watcher_views{runner="b"} 0`,
+		`consul_template_render_duration_seconds_count{runner="a"} 1`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("expected %q in:\n%s", exp, body)
+		}
+	}
+	if n := strings.Count(body, "# TYPE consul_template_watcher_views "); n != 1 {
+		t.Errorf("expected a single header per metric, got %d", n)
+	}
+
+	rec = httptest.NewRecorder()
+	m.control.server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/runners", nil))
+	var names []string
+	if err := json.NewDecoder(rec.Body).Decode(&names); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"a", "b"}; !reflect.DeepEqual(exp, names) {
+		t.Errorf("expected %q, got %q", exp, names)
+	}
+
+	rec = httptest.NewRecorder()
+	m.control.server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/runners/b/v1/state", nil))
+	if rec.Code != 200 {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	m.control.server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/runners/c/v1/state", nil))
+	if rec.Code != 404 {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestMultiRunner_once(t *testing.T) {
+	t.Parallel()
+
+	a, cleanupA := testMultiRunnerConfig(t)
+	defer cleanupA()
+	b, cleanupB := testMultiRunnerConfig(t)
+	defer cleanupB()
+
+	m, err := NewMultiRunner(&NewMultiRunnerInput{
+		Configs: map[string]*config.Config{"a": a, "b": b},
+		Dry:     true,
+		Once:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	for _, name := range m.Names() {
+		m.Runner(name).outStream = ioutil.Discard
+	}
+	go m.Start()
+
+	select {
+	case err := <-m.ErrCh:
+		t.Fatal(err)
+	case <-m.DoneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the runners to finish")
+	}
+}
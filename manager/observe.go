@@ -0,0 +1,299 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/pkg/errors"
+)
+
+// observeMaxDiffCells is the largest product of the numbers of differing old
+// and new lines which are diffed line by line. Larger changes are shown as
+// all the old lines replaced by all the new ones.
+const observeMaxDiffCells = 1 << 22
+
+// observation is the last observed render of a template configuration.
+type observation struct {
+	sum     [sha256.Size]byte
+	differs bool
+}
+
+// observeRender compares the rendered contents of the given template
+// configuration with its destination without writing anything. When the
+// contents change and differ from the destination, the diff and the
+// dependencies which changed since the previous observation are logged. The
+// result never reports a render, so no commands run.
+func (r *Runner) observeRender(tc *config.TemplateConfig, i *RenderInput, changed []string) (*RenderResult, error) {
+	result := &RenderResult{
+		DidRender:   false,
+		WouldRender: true,
+	}
+
+	diffs, err := observeDiffs(i)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(i.Contents)
+	prev, seen := r.observed[tc]
+	r.observed[tc] = &observation{sum: sum, differs: len(diffs) > 0}
+	if seen && prev.sum == sum {
+		return result, nil
+	}
+
+	if len(diffs) == 0 {
+		if seen && prev.differs {
+			templateLogf(tc, "INFO", "observe: %s would no longer change", tc.Display())
+		}
+		return result, nil
+	}
+
+	cause := "no dependency changed"
+	if len(changed) > 0 {
+		cause = "changed: " + strings.Join(changed, ", ")
+	}
+	for _, d := range diffs {
+		added, removed := d.counts()
+		templateLogf(tc, "INFO", "observe: %s would write %s (+%d -%d lines, %s)",
+			tc.Display(), d.path, added, removed, cause)
+		for _, line := range d.lines {
+			templateLogf(tc, "INFO", "observe: %s: %s", d.path, line)
+		}
+	}
+	return result, nil
+}
+
+// observeDiffs returns the diff of each file the given input would change.
+func observeDiffs(i *RenderInput) ([]*observeDiff, error) {
+	if !i.Split {
+		existing, err := ioutil.ReadFile(i.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed reading file")
+		}
+		if body, ok := i.withoutHeader(existing); err == nil && ok && i.equal(body, i.Contents) {
+			return nil, nil
+		}
+		return []*observeDiff{newObserveDiff(i.Path, existing, i.output())}, nil
+	}
+
+	files, err := SplitContents(i.Contents)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := ReadSplit(i.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading split destination")
+	}
+
+	keys := make([]string, 0, len(files)+len(existing))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	for key := range existing {
+		if _, ok := files[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []*observeDiff
+	for _, key := range keys {
+		old, oldOK := existing[key]
+		cur, curOK := files[key]
+		if oldOK && curOK && i.equal(old, cur) {
+			continue
+		}
+		diffs = append(diffs, newObserveDiff(filepath.Join(i.Path, key), old, cur))
+	}
+	return diffs, nil
+}
+
+// observeDiff is the line diff of a file, with "+" and "-" lines under "@@"
+// headers giving the line numbers of each hunk.
+type observeDiff struct {
+	path  string
+	lines []string
+}
+
+// counts returns the number of added and removed lines.
+func (d *observeDiff) counts() (int, int) {
+	var added, removed int
+	for _, line := range d.lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// newObserveDiff returns the line diff between the old and new contents of the
+// file at the given path.
+func newObserveDiff(path string, old, cur []byte) *observeDiff {
+	a, b := splitLines(old), splitLines(cur)
+
+	// Only the lines between the common prefix and suffix differ.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	d := &observeDiff{path: path}
+	var hunk []string
+	hunkA, hunkB := prefix, prefix
+	var lenA, lenB int
+	flush := func() {
+		if len(hunk) == 0 {
+			return
+		}
+		d.lines = append(d.lines, fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunkA+1, lenA, hunkB+1, lenB))
+		d.lines = append(d.lines, hunk...)
+		hunk, lenA, lenB = nil, 0, 0
+	}
+
+	ia, ib := 0, 0
+	for _, op := range diffLines(a, b) {
+		switch op {
+		case '=':
+			flush()
+			ia, ib = ia+1, ib+1
+			hunkA, hunkB = prefix+ia, prefix+ib
+		case '-':
+			hunk = append(hunk, "-"+a[ia])
+			ia, lenA = ia+1, lenA+1
+		case '+':
+			hunk = append(hunk, "+"+b[ib])
+			ib, lenB = ib+1, lenB+1
+		}
+	}
+	flush()
+	return d
+}
+
+// splitLines returns the lines of the given contents, without their line
+// endings.
+func splitLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+}
+
+// diffLines returns the edit script turning a into b as a sequence of '='
+// (keep), '-' (remove from a) and '+' (add from b) operations, using the
+// longest common subsequence of the lines. Inputs which are too large to diff
+// are replaced entirely.
+func diffLines(a, b []string) []byte {
+	if len(a)*len(b) > observeMaxDiffCells {
+		ops := make([]byte, 0, len(a)+len(b))
+		for range a {
+			ops = append(ops, '-')
+		}
+		for range b {
+			ops = append(ops, '+')
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]byte, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, '=')
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, '-')
+			i++
+		default:
+			ops = append(ops, '+')
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, '-')
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, '+')
+	}
+	return ops
+}
+
+// observeBundle compares the given archive of a bundle with its destination
+// without writing anything, logging the entries of the bundle and the
+// dependencies which changed when the archive changes and differs from the
+// destination.
+func (r *Runner) observeBundle(b *bundle, dest string, contents []byte) (*RenderResult, error) {
+	result := &RenderResult{
+		DidRender:   false,
+		WouldRender: true,
+	}
+
+	existing, err := ioutil.ReadFile(dest)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, fmt.Sprintf("error reading bundle %q", b.name()))
+	}
+	differs := err != nil || !bytes.Equal(existing, contents)
+
+	sum := sha256.Sum256(contents)
+	prev := b.observed
+	b.observed = &observation{sum: sum, differs: differs}
+	if prev != nil && prev.sum == sum {
+		return result, nil
+	}
+
+	if !differs {
+		if prev != nil && prev.differs {
+			log.Printf("[INFO] (runner) observe: bundle %q would no longer change", b.name())
+		}
+		return result, nil
+	}
+
+	var paths, changed []string
+	for _, tc := range b.configs {
+		e := b.entries[tc]
+		paths = append(paths, config.StringVal(tc.BundlePath))
+		changed = appendUnique(changed, e.changed...)
+	}
+	cause := "no dependency changed"
+	if len(changed) > 0 {
+		cause = "changed: " + strings.Join(changed, ", ")
+	}
+	log.Printf("[INFO] (runner) observe: bundle %q would write %s (entries: %s, %s)",
+		b.name(), dest, strings.Join(paths, ", "), cause)
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewObserveDiff(t *testing.T) {
+	cases := []struct {
+		name string
+		old  string
+		cur  string
+		exp  []string
+	}{
+		{
+			"changed",
+			"a\nb\nc\n",
+			"a\nx\nc\n",
+			[]string{"@@ -2,1 +2,1 @@", "-b", "+x"},
+		},
+		{
+			"appended",
+			"a\nb\n",
+			"a\nb\nc\n",
+			[]string{"@@ -3,0 +3,1 @@", "+c"},
+		},
+		{
+			"hunks",
+			"a\nb\nc\nd\ne\n",
+			"x\nb\nc\nd\ny\n",
+			[]string{"@@ -1,1 +1,1 @@", "-a", "+x", "@@ -5,1 +5,1 @@", "-e", "+y"},
+		},
+		{
+			"new",
+			"",
+			"a\n",
+			[]string{"@@ -1,0 +1,1 @@", "+a"},
+		},
+		{
+			"emptied",
+			"a\nb\n",
+			"",
+			[]string{"@@ -1,2 +1,0 @@", "-a", "-b"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newObserveDiff("/tmp/f", []byte(tc.old), []byte(tc.cur))
+			if !reflect.DeepEqual(tc.exp, d.lines) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, d.lines)
+			}
+		})
+	}
+}
+
+func TestRunner_observe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "out")
+	marker := filepath.Join(dir, "command")
+	if err := ioutil.WriteFile(dest, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.TestConfig(&config.Config{
+		Observe: config.Bool(true),
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String(dest),
+				Exec: &config.ExecConfig{
+					Command: config.String("touch " + marker),
+				},
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	r.Receive(r.dependencies["kv.block(foo)"], "new")
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written and no command runs.
+	if b, err := ioutil.ReadFile(dest); err != nil || string(b) != "old" {
+		t.Errorf("expected the destination to be unchanged, got %q (%v)", b, err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("expected the command not to run, got %v", err)
+	}
+
+	tc := (*r.config.Templates)[0]
+	if o := r.observed[tc]; o == nil || !o.differs {
+		t.Fatalf("expected a differing observation, got %#v", o)
+	}
+	for _, tmpl := range r.templates {
+		event := r.RenderEvents()[tmpl.ID()]
+		if event == nil || event.LastWouldRender.IsZero() || !event.LastDidRender.IsZero() {
+			t.Errorf("expected %s to only be observed, got %#v", r.templateLabel(tmpl.ID()), event)
+		}
+	}
+
+	// Once the destination matches, the template no longer differs.
+	if err := ioutil.WriteFile(dest, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r.Receive(r.dependencies["kv.block(foo)"], "new")
+	delete(r.observed, tc)
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if o := r.observed[tc]; o == nil || o.differs {
+		t.Errorf("expected a matching observation, got %#v", o)
+	}
+}
@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// onceBarrierWaitTime is the maximum amount of time a single blocking
+	// query on the barrier waits for a change.
+	onceBarrierWaitTime = 60 * time.Second
+
+	// onceBarrierSessionTTL is the TTL of the session which holds the entry of
+	// an instance at the barrier.
+	onceBarrierSessionTTL = "15s"
+)
+
+// onceBarrier makes instances in once mode wait for each other after rendering
+// their templates, so they run their commands and exit at the same time.
+//
+// Each instance holds a session and registers itself under the "instances"
+// path of the prefix. The first instance which sees the configured number of
+// instances registered writes the "open" key, which releases all of them. The
+// key is never deleted, so instances which reach the barrier late pass it as
+// well. Instances which die before the barrier opens leave it along with
+// their session.
+type onceBarrier struct {
+	// config is the once barrier configuration
+	config *config.OnceBarrierConfig
+
+	// clients is used to access the underlying clients
+	clients *dep.ClientSet
+}
+
+// newOnceBarrier creates a new once barrier.
+func newOnceBarrier(c *config.OnceBarrierConfig, clients *dep.ClientSet) (*onceBarrier, error) {
+	if config.IntVal(c.Instances) < 1 {
+		return nil, fmt.Errorf("once_barrier: instances must be at least 1, got %d",
+			config.IntVal(c.Instances))
+	}
+
+	return &onceBarrier{
+		config:  c,
+		clients: clients,
+	}, nil
+}
+
+// wait registers this instance at the barrier and blocks until the barrier
+// opens. It returns an error if the barrier does not open before the timeout,
+// if Consul cannot be reached or if doneCh is closed first.
+func (b *onceBarrier) wait(doneCh <-chan struct{}) error {
+	client := b.clients.Consul()
+	prefix := config.StringVal(b.config.Prefix)
+	instances := config.IntVal(b.config.Instances)
+	openKey := path.Join(prefix, "open")
+
+	session := client.Session()
+	id, _, err := session.Create(&consulapi.SessionEntry{
+		Name:     "Consul-Template once barrier",
+		Behavior: "delete",
+		TTL:      onceBarrierSessionTTL,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("once_barrier: failed to create session: %s", err)
+	}
+
+	// The session is destroyed once this instance passes the barrier, which
+	// removes its entry.
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go session.RenewPeriodic(onceBarrierSessionTTL, id, nil, stopCh)
+
+	if _, _, err := client.KV().Acquire(&consulapi.KVPair{
+		Key:     path.Join(prefix, "instances", id),
+		Session: id,
+	}, nil); err != nil {
+		return fmt.Errorf("once_barrier: failed to register instance: %s", err)
+	}
+	log.Printf("[INFO] (once_barrier) waiting for %d instance(s) at %s", instances, prefix)
+
+	var deadline time.Time
+	if timeout := config.TimeDurationVal(b.config.Timeout); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	var waitIndex uint64
+	for {
+		waitTime := onceBarrierWaitTime
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(time.Now())
+			if remaining <= 0 {
+				return fmt.Errorf("once_barrier: timed out waiting for %d instance(s) at %s",
+					instances, prefix)
+			}
+			if remaining < waitTime {
+				waitTime = remaining
+			}
+		}
+
+		pairs, meta, err := client.KV().List(prefix, &consulapi.QueryOptions{
+			WaitIndex: waitIndex,
+			WaitTime:  waitTime,
+		})
+		if err != nil {
+			return fmt.Errorf("once_barrier: failed to list instances: %s", err)
+		}
+
+		open, reached := barrierState(pairs, prefix, instances)
+		if !open && reached >= instances {
+			if _, err := client.KV().Put(&consulapi.KVPair{
+				Key:   openKey,
+				Value: []byte(time.Now().UTC().Format(time.RFC3339)),
+			}, nil); err != nil {
+				return fmt.Errorf("once_barrier: failed to open: %s", err)
+			}
+			open = true
+		}
+		if open {
+			log.Printf("[INFO] (once_barrier) barrier at %s is open", prefix)
+			return nil
+		}
+
+		select {
+		case <-doneCh:
+			return fmt.Errorf("once_barrier: stopped while waiting at %s", prefix)
+		default:
+		}
+
+		log.Printf("[DEBUG] (once_barrier) %d of %d instance(s) at %s",
+			reached, instances, prefix)
+		waitIndex = meta.LastIndex
+	}
+}
+
+// barrierState returns whether the barrier under the given prefix is open and
+// how many instances have reached it. Only entries held by a session count.
+func barrierState(pairs consulapi.KVPairs, prefix string, instances int) (bool, int) {
+	openKey := path.Join(prefix, "open")
+	instancesPrefix := path.Join(prefix, "instances") + "/"
+
+	var reached int
+	for _, pair := range pairs {
+		switch {
+		case pair.Key == openKey:
+			return true, instances
+		case strings.HasPrefix(pair.Key, instancesPrefix) && pair.Session != "":
+			reached++
+		}
+	}
+	return false, reached
+}
+
+// holdForOnceBarrier returns the commands to execute. In once mode with a
+// barrier, commands are held until all templates have rendered, and then
+// returned once the barrier opens. Without a barrier, or once it was passed,
+// the given commands are returned.
+func (r *Runner) holdForOnceBarrier(commands []*config.TemplateConfig) ([]*config.TemplateConfig, error) {
+	if r.onceBarrier == nil || r.onceBarrierPassed {
+		return commands, nil
+	}
+
+	for _, c := range commands {
+		if findCommand(c, r.onceBarrierCommands) == nil {
+			r.onceBarrierCommands = append(r.onceBarrierCommands, c)
+		}
+	}
+
+	if !r.allTemplatesRendered() {
+		if len(commands) > 0 {
+			log.Printf("[INFO] (runner) once barrier: holding %d command(s) until all "+
+				"templates are rendered", len(r.onceBarrierCommands))
+		}
+		return nil, nil
+	}
+
+	log.Printf("[INFO] (runner) all templates rendered, waiting at the once barrier")
+	if err := r.onceBarrier.wait(r.DoneCh); err != nil {
+		return nil, err
+	}
+	r.onceBarrierPassed = true
+
+	held := r.onceBarrierCommands
+	r.onceBarrierCommands = nil
+	return held, nil
+}
@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestNewRunner_onceBarrier(t *testing.T) {
+	cases := []struct {
+		name      string
+		once      bool
+		instances int
+		enabled   bool
+		err       string
+	}{
+		{"once", true, 3, true, ""},
+		{"not_once", false, 3, false, ""},
+		{"no_instances", true, 0, false, "instances must be at least 1"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.TestConfig(&config.Config{
+				OnceBarrier: &config.OnceBarrierConfig{
+					Enabled:   config.Bool(true),
+					Instances: config.Int(tc.instances),
+				},
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("test"),
+						Destination: config.String("/tmp/out"),
+					},
+				},
+			})
+
+			r, err := NewRunner(c, false, tc.once)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (r.onceBarrier != nil) != tc.enabled {
+				t.Errorf("expected the barrier to be enabled %t", tc.enabled)
+			}
+		})
+	}
+}
+
+func TestBarrierState(t *testing.T) {
+	cases := []struct {
+		name    string
+		pairs   consulapi.KVPairs
+		open    bool
+		reached int
+	}{
+		{
+			"empty",
+			nil,
+			false,
+			0,
+		},
+		{
+			"reached",
+			consulapi.KVPairs{
+				{Key: "b/instances/a", Session: "a"},
+				{Key: "b/instances/b", Session: "b"},
+				{Key: "b/instances/c"},
+				{Key: "b/other", Session: "d"},
+			},
+			false,
+			2,
+		},
+		{
+			"open",
+			consulapi.KVPairs{
+				{Key: "b/instances/a", Session: "a"},
+				{Key: "b/open"},
+			},
+			true,
+			3,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			open, reached := barrierState(tc.pairs, "b/", 3)
+			if open != tc.open {
+				t.Errorf("expected open %t, got %t", tc.open, open)
+			}
+			if reached != tc.reached {
+				t.Errorf("expected %d instances, got %d", tc.reached, reached)
+			}
+		})
+	}
+}
+
+func TestRunner_holdForOnceBarrier(t *testing.T) {
+	c := config.TestConfig(&config.Config{
+		OnceBarrier: &config.OnceBarrierConfig{
+			Instances: config.Int(2),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String("/tmp/out"),
+				Exec: &config.ExecConfig{
+					Command: config.String("echo"),
+				},
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Until all templates are rendered, commands are held.
+	commands, err := r.holdForOnceBarrier(*r.config.Templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 0 {
+		t.Errorf("expected no commands, got %d", len(commands))
+	}
+	if len(r.onceBarrierCommands) != 1 {
+		t.Errorf("expected 1 held command, got %d", len(r.onceBarrierCommands))
+	}
+
+	// Once the barrier was passed, commands run right away.
+	r.onceBarrierPassed = true
+	commands, err = r.holdForOnceBarrier(*r.config.Templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 {
+		t.Errorf("expected 1 command, got %d", len(commands))
+	}
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/plugin"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// startPlugins starts the template plugins of the configuration and collects
+// their functions. The plugins which were started are stopped if any of them
+// fails, or if two of them provide a function with the same name.
+func (r *Runner) startPlugins() error {
+	r.pluginFuncs = make(map[string]template.PluginFunc)
+
+	names := make(map[string]struct{})
+	owners := make(map[string]string)
+	for _, c := range *r.config.TemplatePlugins {
+		name, path := config.StringVal(c.Name), config.StringVal(c.Path)
+		if name == "" {
+			return fmt.Errorf("runner: template_plugin: missing name")
+		}
+		if path == "" {
+			return fmt.Errorf("runner: template_plugin %q: missing path", name)
+		}
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("runner: template_plugin %q: declared twice", name)
+		}
+		names[name] = struct{}{}
+
+		client, err := plugin.Start(&plugin.StartInput{
+			Name:        name,
+			Path:        path,
+			Args:        c.Args,
+			CallTimeout: config.TimeDurationVal(c.Timeout),
+		})
+		if err != nil {
+			return fmt.Errorf("runner: %s", err)
+		}
+		r.plugins = append(r.plugins, client)
+
+		for _, fn := range client.Functions() {
+			if owner, ok := owners[fn]; ok {
+				return fmt.Errorf("runner: template_plugin %q: function %q is also provided by %q",
+					name, fn, owner)
+			}
+			owners[fn] = name
+			r.pluginFuncs[fn] = template.PluginFunc(client.Func(fn))
+		}
+	}
+
+	return nil
+}
+
+// stopPlugins stops the template plugins which were started.
+func (r *Runner) stopPlugins() {
+	for _, p := range r.plugins {
+		log.Printf("[DEBUG] (runner) stopping template plugin %s", p.Name())
+		p.Kill()
+	}
+	r.plugins = nil
+}
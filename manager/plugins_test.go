@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewRunner_templatePluginInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		plugins *config.TemplatePluginConfigs
+		err     string
+	}{
+		{
+			"missing_path",
+			&config.TemplatePluginConfigs{
+				&config.TemplatePluginConfig{Name: config.String("mysql")},
+			},
+			"missing path",
+		},
+		{
+			"missing_binary",
+			&config.TemplatePluginConfigs{
+				&config.TemplatePluginConfig{
+					Name: config.String("mysql"),
+					Path: config.String("/nope/ct-plugin-mysql"),
+				},
+			},
+			"plugin mysql",
+		},
+		{
+			"missing_name",
+			&config.TemplatePluginConfigs{
+				&config.TemplatePluginConfig{Path: config.String("/bin/true")},
+			},
+			"missing name",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config.TestConfig(&config.Config{
+				TemplatePlugins: tc.plugins,
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("test"),
+						Destination: config.String("/tmp/out"),
+					},
+				},
+			})
+
+			_, err := NewRunner(c, false, false)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
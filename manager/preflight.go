@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInsufficientSpace is the error returned when the filesystem of a
+	// destination does not have enough free space for the rendered contents.
+	ErrInsufficientSpace = errors.New("not enough free space")
+
+	// ErrNotWritable is the error returned when the directory of a destination
+	// is not writable.
+	ErrNotWritable = errors.New("destination directory is not writable")
+
+	// ErrCrossDevice is the error returned when a destination is on a
+	// different filesystem than its directory, for example a file bind
+	// mounted into a container, so it cannot be atomically replaced.
+	ErrCrossDevice = errors.New("destination is on a different filesystem than its directory")
+
+	// ErrShortWrite is the error returned when fewer bytes than the rendered
+	// contents were written to disk.
+	ErrShortWrite = errors.New("short write")
+
+	// errPreflightUnsupported is returned by the platform checks which are not
+	// supported on this platform. Such checks are skipped.
+	errPreflightUnsupported = errors.New("preflight check is not supported on this platform")
+)
+
+// checkFreeSpace returns ErrInsufficientSpace if the filesystem containing dir
+// does not have at least size bytes available.
+func checkFreeSpace(dir string, size int) error {
+	free, err := freeSpace(dir)
+	if err == errPreflightUnsupported {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "checking free space in %s", dir)
+	}
+
+	if free < uint64(size) {
+		return errors.Wrapf(ErrInsufficientSpace, "%s: %d bytes free, %d needed",
+			dir, free, size)
+	}
+	return nil
+}
+
+// checkWritten returns ErrShortWrite if the file at path is not size bytes
+// long. Some filesystems only report a full disk when the file is closed, if
+// at all, so the size is verified before the file replaces the destination.
+func checkWritten(path string, size int) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if stat.Size() != int64(size) {
+		return errors.Wrapf(ErrShortWrite, "%s: wrote %d of %d bytes",
+			path, stat.Size(), size)
+	}
+	return nil
+}
+
+// checkSameDevice returns ErrCrossDevice if the destination at path exists and
+// is on a different filesystem than the temporary file at tmp.
+func checkSameDevice(tmp, path string) error {
+	same, err := sameDevice(tmp, path)
+	if err == errPreflightUnsupported || os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !same {
+		return errors.Wrapf(ErrCrossDevice, "%s", path)
+	}
+	return nil
+}
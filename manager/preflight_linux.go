@@ -0,0 +1,42 @@
+// +build linux
+
+package manager
+
+import (
+	"os"
+	"syscall"
+)
+
+// freeSpace returns the number of bytes available to unprivileged users on the
+// filesystem containing path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// sameDevice returns whether the files at a and b are on the same filesystem.
+func sameDevice(a, b string) (bool, error) {
+	var statA, statB syscall.Stat_t
+	if err := syscall.Stat(a, &statA); err != nil {
+		return false, &os.PathError{Op: "stat", Path: a, Err: err}
+	}
+	if err := syscall.Stat(b, &statB); err != nil {
+		return false, &os.PathError{Op: "stat", Path: b, Err: err}
+	}
+	return statA.Dev == statB.Dev, nil
+}
+
+// isNotWritable returns whether err means a file could not be created because
+// of its permissions or a read-only filesystem.
+func isNotWritable(err error) bool {
+	if os.IsPermission(err) {
+		return true
+	}
+	if perr, ok := err.(*os.PathError); ok {
+		return perr.Err == syscall.EROFS
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package manager
+
+import "os"
+
+func freeSpace(path string) (uint64, error) {
+	return 0, errPreflightUnsupported
+}
+
+func sameDevice(a, b string) (bool, error) {
+	return false, errPreflightUnsupported
+}
+
+func isNotWritable(err error) bool {
+	return os.IsPermission(err)
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCheckFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("free space is only checked on linux")
+	}
+
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	t.Run("fits", func(t *testing.T) {
+		if err := checkFreeSpace(outDir, 1); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		err := checkFreeSpace(outDir, int(^uint(0)>>1))
+		if errors.Cause(err) != ErrInsufficientSpace {
+			t.Errorf("\nexp: %#v\nact: %#v", ErrInsufficientSpace, err)
+		}
+	})
+}
+
+func TestCheckWritten(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("abc"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := checkWritten(f.Name(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkWritten(f.Name(), 4); errors.Cause(err) != ErrShortWrite {
+		t.Errorf("\nexp: %#v\nact: %#v", ErrShortWrite, err)
+	}
+}
+
+func TestCheckSameDevice(t *testing.T) {
+	t.Parallel()
+
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	tmp := filepath.Join(outDir, "tmp")
+	if err := ioutil.WriteFile(tmp, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("missing_destination", func(t *testing.T) {
+		if err := checkSameDevice(tmp, filepath.Join(outDir, "nope")); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("same", func(t *testing.T) {
+		dst := filepath.Join(outDir, "dst")
+		if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkSameDevice(tmp, dst); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestAtomicWrite_notWritable(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	path := filepath.Join(outDir, "out")
+	if err := ioutil.WriteFile(path, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(outDir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(outDir, 0755)
+
+	err = AtomicWrite(path, []byte("after"), 0644, false)
+	if errors.Cause(err) != ErrNotWritable {
+		t.Errorf("\nexp: %#v\nact: %#v", ErrNotWritable, err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "before", string(contents); exp != act {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
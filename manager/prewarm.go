@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// isVaultDependency returns true if the dependency reads or writes Vault.
+func isVaultDependency(d dep.Dependency) bool {
+	return strings.HasPrefix(d.String(), "vault.")
+}
+
+// prewarmError returns the error for the templates which did not render within
+// the prewarm timeout of the child process. It names each template and the
+// dependencies it has no data for, with those of Vault first, since they are
+// usually why the child process cannot start.
+func (r *Runner) prewarmError(timeout time.Duration) error {
+	r.renderEventsLock.RLock()
+	var pending []string
+	for _, tmpl := range r.templates {
+		if event, ok := r.renderEvents[tmpl.ID()]; !ok || event.LastWouldRender.IsZero() {
+			pending = append(pending, tmpl.ID())
+		}
+	}
+	r.renderEventsLock.RUnlock()
+
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	details := make([]string, 0, len(pending))
+	for _, id := range pending {
+		var vault, other []string
+		for _, d := range r.templateDeps[id] {
+			if _, ok := r.brain.Recall(d); ok {
+				continue
+			}
+			if isVaultDependency(d) {
+				vault = append(vault, d.String())
+			} else {
+				other = append(other, d.String())
+			}
+		}
+		sort.Strings(vault)
+		sort.Strings(other)
+
+		detail := r.templateLabel(id)
+		if missing := append(vault, other...); len(missing) > 0 {
+			detail += " is missing " + strings.Join(missing, ", ")
+		}
+		details = append(details, detail)
+	}
+
+	return fmt.Errorf("runner: templates did not render within the prewarm "+
+		"timeout of %s, not starting the child process: %s",
+		timeout, strings.Join(details, "; "))
+}
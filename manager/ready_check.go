@@ -0,0 +1,268 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/consul-template/config"
+	"google.golang.org/grpc"
+)
+
+const (
+	// grpcHealthCheckMethod is the method of the standard gRPC health checking
+	// protocol.
+	grpcHealthCheckMethod = "/grpc.health.v1.Health/Check"
+
+	// grpcHealthServing is the status of a serving server in the standard gRPC
+	// health checking protocol.
+	grpcHealthServing int32 = 1
+)
+
+// readyCheck checks the readiness of the long-lived process started by the
+// command of a template. It runs until the process is ready, and again after
+// each time the command runs.
+type readyCheck struct {
+	name string
+
+	// check runs the check once, returning nil if the process is ready.
+	check    func() error
+	interval time.Duration
+
+	// ready is true if the check succeeded since the command last ran, and err
+	// is the error of the last failed check. runs counts the runs of the
+	// command, so a check which started before the command ran is ignored.
+	// They are protected by lock.
+	ready bool
+	err   error
+	runs  int
+	lock  sync.Mutex
+
+	// resetCh starts checking again after the command ran. stopCh is closed
+	// when the template is no longer part of the configuration.
+	resetCh chan struct{}
+	stopCh  chan struct{}
+
+	// readyFn is called each time the process becomes ready.
+	readyFn func()
+}
+
+// newReadyCheck creates the ready check of the given template configuration,
+// which calls readyFn each time the process becomes ready. It returns nil if
+// the template has no ready check.
+func newReadyCheck(tc *config.TemplateConfig, readyFn func()) (*readyCheck, error) {
+	conf := tc.ReadyCheck
+	if conf == nil || !config.BoolVal(conf.Enabled) {
+		return nil, nil
+	}
+
+	interval := config.TimeDurationVal(conf.Interval)
+	if interval <= 0 {
+		interval = config.DefaultReadyCheckInterval
+	}
+
+	c := &readyCheck{
+		name:     tc.Display(),
+		interval: interval,
+		err:      fmt.Errorf("not checked yet"),
+		resetCh:  make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		readyFn:  readyFn,
+	}
+
+	address, endpoint := config.StringVal(conf.GRPC), config.StringVal(conf.HTTP)
+	switch {
+	case address != "" && endpoint != "":
+		return nil, fmt.Errorf("ready_check cannot use both grpc and http")
+	case address != "":
+		service := config.StringVal(conf.GRPCService)
+		c.check = func() error {
+			return grpcHealthCheck(address, service, interval)
+		}
+	case endpoint != "":
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("ready_check: invalid http url %q", endpoint)
+		}
+		c.check = func() error {
+			return httpReadyCheck(endpoint, interval)
+		}
+	default:
+		return nil, fmt.Errorf("ready_check requires grpc or http")
+	}
+	return c, nil
+}
+
+// run checks the process at the configured interval until it is ready, then
+// waits for the command to run again, until doneCh is closed or the check is
+// stopped. This function blocks and should be run in a goroutine.
+func (c *readyCheck) run(doneCh <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.resetCh:
+			continue
+		case <-c.stopCh:
+			return
+		case <-doneCh:
+			return
+		}
+
+		c.lock.Lock()
+		ready, runs := c.ready, c.runs
+		c.lock.Unlock()
+		if ready {
+			continue
+		}
+
+		err := c.check()
+		c.lock.Lock()
+		if c.runs != runs {
+			c.lock.Unlock()
+			continue
+		}
+		c.ready, c.err = err == nil, err
+		c.lock.Unlock()
+
+		if err != nil {
+			log.Printf("[DEBUG] (runner) command of %s is not ready: %s", c.name, err)
+			continue
+		}
+		log.Printf("[INFO] (runner) command of %s is ready", c.name)
+		if c.readyFn != nil {
+			c.readyFn()
+		}
+	}
+}
+
+// reset marks the process as not ready after the command ran, so it is checked
+// again.
+func (c *readyCheck) reset() {
+	c.lock.Lock()
+	c.ready, c.err = false, fmt.Errorf("command ran")
+	c.runs++
+	c.lock.Unlock()
+
+	select {
+	case c.resetCh <- struct{}{}:
+	default:
+	}
+}
+
+// stop ends the checks, since the configuration was reloaded without the
+// template.
+func (c *readyCheck) stop() {
+	close(c.stopCh)
+}
+
+// status returns why the process is not ready, or nil if it is.
+func (c *readyCheck) status() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.ready {
+		return nil
+	}
+	return c.err
+}
+
+// commandsNotReady returns the templates whose command started a process which
+// is not ready, with the reason.
+func (r *Runner) commandsNotReady() []string {
+	r.renderEventsLock.RLock()
+	checks := r.readyChecks
+	r.renderEventsLock.RUnlock()
+
+	var failures []string
+	for _, c := range checks {
+		if err := c.status(); err != nil {
+			failures = append(failures, fmt.Sprintf("command of %s is not ready: %s", c.name, err))
+		}
+	}
+	sort.Strings(failures)
+	return failures
+}
+
+// commandReady logs once the processes started by the commands of all the
+// templates are ready.
+func (r *Runner) commandReady() {
+	if len(r.commandsNotReady()) == 0 {
+		log.Printf("[INFO] (runner) all commands are ready")
+	}
+}
+
+// resetReadyCheck checks the process started by the command of the given
+// template configuration again, since the command ran.
+func (r *Runner) resetReadyCheck(tc *config.TemplateConfig) {
+	if c, ok := r.readyChecks[tc]; ok {
+		c.reset()
+	}
+}
+
+// httpReadyCheck returns nil if a GET request to the given URL returns a 2xx
+// status within the timeout.
+func httpReadyCheck(endpoint string, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// grpcHealthCheck returns nil if the gRPC server at the given address reports
+// the given service as serving within the timeout, using the standard health
+// checking protocol.
+func grpcHealthCheck(address, service string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var resp healthCheckResponse
+	err = grpc.Invoke(ctx, grpcHealthCheckMethod, &healthCheckRequest{Service: service}, &resp, conn)
+	if err != nil {
+		return err
+	}
+	if resp.Status != grpcHealthServing {
+		return fmt.Errorf("unexpected status %d", resp.Status)
+	}
+	return nil
+}
+
+// The following are the messages of the standard gRPC health checking
+// protocol. The field numbers must match its protobuf definition.
+
+type healthCheckRequest struct {
+	Service string `protobuf:"bytes,1,opt,name=service,proto3"`
+}
+
+func (m *healthCheckRequest) Reset()         { *m = healthCheckRequest{} }
+func (m *healthCheckRequest) String() string { return proto.CompactTextString(m) }
+func (*healthCheckRequest) ProtoMessage()    {}
+
+type healthCheckResponse struct {
+	Status int32 `protobuf:"varint,1,opt,name=status,proto3"`
+}
+
+func (m *healthCheckResponse) Reset()         { *m = healthCheckResponse{} }
+func (m *healthCheckResponse) String() string { return proto.CompactTextString(m) }
+func (*healthCheckResponse) ProtoMessage()    {}
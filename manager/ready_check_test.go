@@ -0,0 +1,242 @@
+package manager
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestNewReadyCheck(t *testing.T) {
+	cases := []struct {
+		name string
+		c    *config.ReadyCheckConfig
+		nil  bool
+		err  string
+	}{
+		{
+			"disabled",
+			&config.ReadyCheckConfig{},
+			true,
+			"",
+		},
+		{
+			"http",
+			&config.ReadyCheckConfig{HTTP: config.String("http://127.0.0.1:8080/ready")},
+			false,
+			"",
+		},
+		{
+			"grpc",
+			&config.ReadyCheckConfig{GRPC: config.String("127.0.0.1:9000")},
+			false,
+			"",
+		},
+		{
+			"both",
+			&config.ReadyCheckConfig{
+				GRPC: config.String("127.0.0.1:9000"),
+				HTTP: config.String("http://127.0.0.1:8080/ready"),
+			},
+			false,
+			"cannot use both",
+		},
+		{
+			"invalid_url",
+			&config.ReadyCheckConfig{HTTP: config.String("127.0.0.1:8080")},
+			false,
+			"invalid http url",
+		},
+		{
+			"missing_check",
+			&config.ReadyCheckConfig{Enabled: config.Bool(true)},
+			false,
+			"requires grpc or http",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.c.Finalize()
+			c, err := newReadyCheck(&config.TemplateConfig{ReadyCheck: tc.c}, nil)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c == nil) != tc.nil {
+				t.Errorf("expected nil %t, got %#v", tc.nil, c)
+			}
+		})
+	}
+}
+
+func TestReadyCheck_run(t *testing.T) {
+	t.Parallel()
+
+	var status int32 = http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	conf := &config.ReadyCheckConfig{
+		HTTP:     config.String(ts.URL),
+		Interval: config.TimeDuration(20 * time.Millisecond),
+	}
+	conf.Finalize()
+
+	readyCh := make(chan struct{}, 1)
+	c, err := newReadyCheck(&config.TemplateConfig{ReadyCheck: conf}, func() {
+		readyCh <- struct{}{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go c.run(doneCh)
+
+	time.Sleep(100 * time.Millisecond)
+	if err := c.status(); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected the process not to be ready, got %v", err)
+	}
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	select {
+	case <-readyCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+	if err := c.status(); err != nil {
+		t.Fatalf("expected the process to be ready, got %v", err)
+	}
+
+	// The process is checked again after the command runs.
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	c.reset()
+	if err := c.status(); err == nil {
+		t.Fatal("expected the process not to be ready")
+	}
+	atomic.StoreInt32(&status, http.StatusOK)
+	select {
+	case <-readyCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		var req healthCheckRequest
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		switch req.Service {
+		case "":
+			return stream.SendMsg(&healthCheckResponse{Status: grpcHealthServing})
+		case "booting":
+			return stream.SendMsg(&healthCheckResponse{Status: 2})
+		default:
+			return grpc.Errorf(codes.NotFound, "unknown service %q", req.Service)
+		}
+	}
+
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "grpc.health.v1.Health",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Check",
+				Handler:       handler,
+				ServerStreams: true,
+				ClientStreams: true,
+			},
+		},
+	}, struct{}{})
+	go s.Serve(ln)
+	defer s.Stop()
+
+	address := ln.Addr().String()
+	if err := grpcHealthCheck(address, "", time.Second); err != nil {
+		t.Errorf("expected the server to be serving, got %v", err)
+	}
+	if err := grpcHealthCheck(address, "booting", time.Second); err == nil {
+		t.Error("expected the service not to be serving")
+	}
+	if err := grpcHealthCheck(address, "unknown", time.Second); err == nil {
+		t.Error("expected an unknown service")
+	}
+}
+
+func TestStatusServer_ready_commands(t *testing.T) {
+	t.Parallel()
+
+	out, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out.Name()),
+				ReadyCheck: &config.ReadyCheckConfig{
+					HTTP: config.String("http://127.0.0.1:8080/ready"),
+				},
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream = ioutil.Discard
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	s := newStatusServer(c.Status, r)
+
+	tc := (*c.Templates)[0]
+	exp := []string{"command of " + tc.Display() + " is not ready: not checked yet"}
+	if act := s.ready(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	r.readyChecks[tc].ready = true
+	if act := s.ready(); act != nil {
+		t.Errorf("\nexp: %#v\nact: %#v", nil, act)
+	}
+
+	r.resetReadyCheck(tc)
+	s.config.Ready.Commands = config.Bool(false)
+	if act := s.ready(); act != nil {
+		t.Errorf("\nexp: %#v\nact: %#v", nil, act)
+	}
+}
@@ -0,0 +1,275 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// ErrReloadRequiresRestart is the error returned by Reload when the new
+// configuration cannot be applied in place, so the runner must be replaced.
+var ErrReloadRequiresRestart = errors.New("runner: configuration changes more than the templates")
+
+// Reload applies the given configuration to this running runner in place. The
+// templates are diffed with the current ones: removed templates are destroyed
+// and forgotten, and added templates are rendered by the following run. The
+// watcher keeps running, so the dependencies still used by any template keep
+// their blocking queries and their data in the brain. Reload returns
+// ErrReloadRequiresRestart if the configuration changes anything but the
+// templates, or if the runner loads templates from a remote configuration or
+// de-duplicates them, and an error if any new template is invalid, in which
+// case nothing changes.
+func (r *Runner) Reload(newConfig *config.Config) error {
+	next, err := r.prepareReload(newConfig)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case r.reloadConfigCh <- next:
+		return nil
+	case <-r.DoneCh:
+		return fmt.Errorf("runner: stopped")
+	}
+}
+
+// prepareReload parses and validates the templates of the given configuration,
+// returning a runner which only has its template fields set.
+func (r *Runner) prepareReload(newConfig *config.Config) (*Runner, error) {
+	c := config.DefaultConfig().Merge(newConfig)
+	c.Finalize()
+	if !sameSettings(r.config, c) {
+		return nil, ErrReloadRequiresRestart
+	}
+	if config.BoolVal(r.config.RemoteConfig.Enabled) || r.dedup != nil {
+		return nil, ErrReloadRequiresRestart
+	}
+
+	next := &Runner{
+		config:  c,
+		dry:     r.dry,
+		once:    r.once,
+		observe: r.observe,
+
+		pluginFuncs: r.pluginFuncs,
+		parseCache:  r.parseCache,
+	}
+	if err := next.initTemplates(); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// reloadSettings are the settings of a configuration which a reload cannot
+// apply in place. The templates are reloaded in place, the logging is set up
+// again by the CLI before each reload, and the profiles were already applied
+// to the other settings, so they are left out.
+var reloadSettings = []struct {
+	name  string
+	value func(*config.Config) interface{}
+}{
+	{"agent_cache", func(c *config.Config) interface{} { return c.AgentCache }},
+	{"auth", func(c *config.Config) interface{} { return c.Auth }},
+	{"blackout", func(c *config.Config) interface{} { return c.Blackout }},
+	{"bundle", func(c *config.Config) interface{} { return c.Bundles }},
+	{"canary", func(c *config.Config) interface{} { return c.Canary }},
+	{"consul", func(c *config.Config) interface{} { return c.Consul }},
+	{"control", func(c *config.Config) interface{} { return c.Control }},
+	{"coordinate", func(c *config.Config) interface{} { return c.Coordinate }},
+	{"deduplicate", func(c *config.Config) interface{} { return c.Dedup }},
+	{"exec", func(c *config.Config) interface{} { return c.Exec }},
+	{"exec_capture", func(c *config.Config) interface{} { return c.ExecCapture }},
+	{"kill_signal", func(c *config.Config) interface{} { return config.SignalVal(c.KillSignal) }},
+	{"local_cache", func(c *config.Config) interface{} { return c.LocalCache }},
+	{"max_concurrent_commands", func(c *config.Config) interface{} { return config.IntVal(c.MaxConcurrentCommands) }},
+	{"max_stale", func(c *config.Config) interface{} { return config.TimeDurationVal(c.MaxStale) }},
+	{"nomad", func(c *config.Config) interface{} { return c.Nomad }},
+	{"observe", func(c *config.Config) interface{} { return config.BoolVal(c.Observe) }},
+	{"once_barrier", func(c *config.Config) interface{} { return c.OnceBarrier }},
+	{"once_retry_timeout", func(c *config.Config) interface{} { return config.TimeDurationVal(c.OnceRetryTimeout) }},
+	{"pid_file", func(c *config.Config) interface{} { return config.StringVal(c.PidFile) }},
+	{"reload_signal", func(c *config.Config) interface{} { return config.SignalVal(c.ReloadSignal) }},
+	{"remote_config", func(c *config.Config) interface{} { return c.RemoteConfig }},
+	{"report", func(c *config.Config) interface{} { return c.Report }},
+	{"resolve", func(c *config.Config) interface{} { return c.Resolve }},
+	{"retry", func(c *config.Config) interface{} { return config.TimeDurationVal(c.Retry) }},
+	{"ssl", func(c *config.Config) interface{} { return c.SSL }},
+	{"standby", func(c *config.Config) interface{} { return c.Standby }},
+	{"status", func(c *config.Config) interface{} { return c.Status }},
+	{"streaming", func(c *config.Config) interface{} { return c.Streaming }},
+	{"systemd", func(c *config.Config) interface{} { return c.Systemd }},
+	{"telemetry", func(c *config.Config) interface{} { return c.Telemetry }},
+	{"template_filter", func(c *config.Config) interface{} { return config.StringVal(c.TemplateFilter) }},
+	{"template_plugin", func(c *config.Config) interface{} { return c.TemplatePlugins }},
+	{"token", func(c *config.Config) interface{} { return config.StringVal(c.Token) }},
+	{"token_file", func(c *config.Config) interface{} { return config.StringVal(c.TokenFile) }},
+	{"vars", func(c *config.Config) interface{} { return c.Vars }},
+	{"vault", func(c *config.Config) interface{} { return c.Vault }},
+	{"vault_agent_token_file", func(c *config.Config) interface{} { return config.StringVal(c.VaultAgentTokenFile) }},
+	{"wait", func(c *config.Config) interface{} { return c.Wait }},
+	{"watcher", func(c *config.Config) interface{} { return c.Watcher }},
+}
+
+// sameSettings returns true if the given finalized configurations have the
+// same reloadSettings.
+func sameSettings(a, b *config.Config) bool {
+	for _, s := range reloadSettings {
+		if !sameSetting(reflect.ValueOf(s.value(a)), reflect.ValueOf(s.value(b))) {
+			log.Printf("[DEBUG] (runner) reload changes %s", s.name)
+			return false
+		}
+	}
+	return true
+}
+
+// sameSetting returns true if the given values of a setting are equal. Unlike
+// reflect.DeepEqual, nil and empty lists and maps are equal, since merging
+// configurations turns one into the other. Functions are never equal unless
+// both are nil.
+func sameSetting(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameSetting(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !sameSetting(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !sameSetting(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			v := b.MapIndex(k)
+			if !v.IsValid() || !sameSetting(a.MapIndex(k), v) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return a.IsNil() && b.IsNil()
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return false
+}
+
+// applyReload replaces the templates of this runner with the ones of next,
+// which was created by prepareReload. The dependencies only used by removed
+// templates are no longer watched after the following run.
+func (r *Runner) applyReload(next *Runner) {
+	if err := r.DestroyRemovedTemplates(next); err != nil {
+		log.Printf("[ERR] (runner) %s", err)
+	}
+
+	var added, removed int
+	for id := range r.ctemplatesMap {
+		if _, ok := next.ctemplatesMap[id]; !ok {
+			r.forgetTemplate(id)
+			removed++
+		}
+	}
+	for id := range next.ctemplatesMap {
+		if _, ok := r.ctemplatesMap[id]; !ok {
+			added++
+		}
+	}
+
+	for _, c := range r.readyChecks {
+		c.stop()
+	}
+	for _, c := range next.readyChecks {
+		c.readyFn = r.commandReady
+		go c.run(r.DoneCh)
+	}
+
+	r.dependenciesLock.Lock()
+	r.renderEventsLock.Lock()
+	r.config.Templates = next.config.Templates
+	r.templates = next.templates
+	r.ctemplatesMap = next.ctemplatesMap
+	r.inputTemplates = next.inputTemplates
+	r.seeds = next.seeds
+	r.rotations = next.rotations
+	r.blackouts = next.blackouts
+	r.readyChecks = next.readyChecks
+	r.compares = next.compares
+	r.headers = next.headers
+	r.bundles = next.bundles
+	r.templateBundles = next.templateBundles
+	r.logLevels = next.logLevels
+	r.renderEventsLock.Unlock()
+	r.dependenciesLock.Unlock()
+	r.applyLogLevels()
+
+	// Drop the parses of the templates which were removed or changed.
+	r.parseCache.Prune(r.templates)
+
+	log.Printf("[INFO] (runner) reloaded configuration: %d template(s) added, %d removed",
+		added, removed)
+}
+
+// forgetTemplate drops everything this runner remembers about the template
+// with the given ID, which was removed from the configuration, and stops its
+// supervised process, if any.
+func (r *Runner) forgetTemplate(tmplID string) {
+	log.Printf("[DEBUG] (runner) forgetting removed template %s", r.templateLabel(tmplID))
+
+	if q, ok := r.quiescenceMap[tmplID]; ok {
+		if q.timer != nil {
+			q.timer.Stop()
+		}
+		delete(r.quiescenceMap, tmplID)
+	}
+
+	r.templateChildrenLock.Lock()
+	if c, ok := r.templateChildren[tmplID]; ok {
+		delete(r.templateChildren, tmplID)
+		c.Stop()
+	}
+	r.templateChildrenLock.Unlock()
+
+	r.dependenciesLock.Lock()
+	delete(r.templateDeps, tmplID)
+	r.dependenciesLock.Unlock()
+
+	r.renderEventsLock.Lock()
+	delete(r.renderEvents, tmplID)
+	r.renderEventsLock.Unlock()
+
+	delete(r.renderedRevisions, tmplID)
+	delete(r.renderedOutputs, tmplID)
+}
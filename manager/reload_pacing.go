@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+// holdForPacing returns whether to reload the child process now. With reload
+// pacing, a reload is held while the last resource usage sample of the child
+// process exceeds a threshold, and released once the child is quieter or the
+// maximum delay has passed since it was first held. A held reload is checked
+// again by the run at reloadRecheck.
+func (r *Runner) holdForPacing(reload bool, now time.Time) bool {
+	pacing := r.config.Exec.ReloadPacing
+	if !config.BoolVal(pacing.Enabled) {
+		return reload
+	}
+	if !reload && r.reloadHeldSince.IsZero() {
+		return false
+	}
+	if r.reloadHeldSince.IsZero() {
+		r.reloadHeldSince = now
+	}
+
+	busy := childBusy(pacing, r.ChildUsage())
+	held := now.Sub(r.reloadHeldSince)
+	maxDelay := config.TimeDurationVal(pacing.MaxDelay)
+	switch {
+	case busy == "":
+		if held > 0 {
+			log.Printf("[INFO] (runner) reloading child process after delaying for %s", held)
+		}
+	case held >= maxDelay:
+		log.Printf("[WARN] (runner) reloading busy child process (%s) after delaying for %s",
+			busy, held)
+	default:
+		if r.reloadRecheck.IsZero() {
+			log.Printf("[INFO] (runner) delaying reload of child process for up to %s: %s",
+				maxDelay-held, busy)
+		}
+		interval := config.TimeDurationVal(r.config.Exec.Monitor.Interval)
+		if interval <= 0 {
+			interval = config.DefaultExecMonitorInterval
+		}
+		r.reloadRecheck = now.Add(interval)
+		if deadline := r.reloadHeldSince.Add(maxDelay); r.reloadRecheck.After(deadline) {
+			r.reloadRecheck = deadline
+		}
+		return false
+	}
+
+	r.reloadHeldSince, r.reloadRecheck = time.Time{}, time.Time{}
+	return true
+}
+
+// childBusy returns a description of each reload pacing threshold the given
+// sample exceeds, or the empty string if there is none or no sample.
+func childBusy(c *config.ExecReloadPacingConfig, u *child.Usage) string {
+	if u == nil {
+		return ""
+	}
+
+	if max := config.Float64Val(c.MaxCPUPercent); max > 0 && u.CPUPercent > max {
+		return fmt.Sprintf("max_cpu_percent (%.2f > %.2f)", u.CPUPercent, max)
+	}
+
+	if max := config.Uint64Val(c.MaxRSS); max > 0 && u.RSS > max {
+		return fmt.Sprintf("max_rss (%d > %d)", u.RSS, max)
+	}
+
+	return ""
+}
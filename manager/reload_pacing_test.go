@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestChildBusy(t *testing.T) {
+	t.Parallel()
+
+	c := &config.ExecReloadPacingConfig{
+		MaxCPUPercent: config.Float64(80),
+		MaxRSS:        config.Uint64(1024),
+	}
+
+	cases := []struct {
+		name string
+		u    *child.Usage
+		e    string
+	}{
+		{
+			"no_sample",
+			nil,
+			"",
+		},
+		{
+			"quiet",
+			&child.Usage{CPUPercent: 80, RSS: 1024},
+			"",
+		},
+		{
+			"cpu",
+			&child.Usage{CPUPercent: 95.5},
+			"max_cpu_percent (95.50 > 80.00)",
+		},
+		{
+			"rss",
+			&child.Usage{RSS: 2048},
+			"max_rss (2048 > 1024)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if act := childBusy(c, tc.u); act != tc.e {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, act)
+			}
+		})
+	}
+}
+
+func TestRunner_holdForPacing(t *testing.T) {
+	t.Parallel()
+
+	r := &Runner{
+		config: config.TestConfig(&config.Config{
+			Exec: &config.ExecConfig{
+				Monitor: &config.ExecMonitorConfig{
+					Interval: config.TimeDuration(5 * time.Second),
+				},
+				ReloadPacing: &config.ExecReloadPacingConfig{
+					MaxCPUPercent: config.Float64(80),
+					MaxDelay:      config.TimeDuration(60 * time.Second),
+				},
+			},
+		}),
+		childMonitor: &childMonitor{usage: &child.Usage{CPUPercent: 95}},
+	}
+	start := time.Now()
+
+	// A reload is held while the child process is busy, and checked again
+	// once the next sample is taken.
+	if r.holdForPacing(true, start) {
+		t.Fatal("expected the reload to be held")
+	}
+	if exp := start.Add(5 * time.Second); !r.reloadRecheck.Equal(exp) {
+		t.Errorf("expected a recheck at %s, got %s", exp, r.reloadRecheck)
+	}
+	if r.holdForPacing(false, start.Add(5*time.Second)) {
+		t.Fatal("expected the reload to still be held")
+	}
+
+	// It is released once the child process is quieter.
+	r.childMonitor.usage = &child.Usage{CPUPercent: 10}
+	if !r.holdForPacing(false, start.Add(10*time.Second)) {
+		t.Fatal("expected the reload to be released")
+	}
+	if !r.reloadHeldSince.IsZero() || !r.reloadRecheck.IsZero() {
+		t.Errorf("expected no held reload, got %s and %s", r.reloadHeldSince, r.reloadRecheck)
+	}
+	if r.holdForPacing(false, start.Add(15*time.Second)) {
+		t.Fatal("expected no reload")
+	}
+
+	// A reload is never held for longer than the max delay.
+	r.childMonitor.usage = &child.Usage{CPUPercent: 95}
+	if r.holdForPacing(true, start) {
+		t.Fatal("expected the reload to be held")
+	}
+	if r.holdForPacing(false, start.Add(58*time.Second)) {
+		t.Fatal("expected the reload to still be held")
+	}
+	if exp := start.Add(60 * time.Second); !r.reloadRecheck.Equal(exp) {
+		t.Errorf("expected a recheck at %s, got %s", exp, r.reloadRecheck)
+	}
+	if !r.holdForPacing(false, start.Add(60*time.Second)) {
+		t.Fatal("expected the reload to be released after the max delay")
+	}
+}
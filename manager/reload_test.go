@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_Reload(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}{{ key "bar" }}`),
+				Destination: config.String("/tmp/b"),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	foo, bar := r.dependencies["kv.block(foo)"], r.dependencies["kv.block(bar)"]
+	r.Receive(foo, "1")
+	r.Receive(bar, "2")
+
+	// The same configuration is applied in place, finalized or not.
+	for _, same := range []*config.Config{
+		config.TestConfig(&config.Config{Templates: c.Templates}),
+		&config.Config{Templates: c.Templates},
+	} {
+		if _, err := r.prepareReload(same); err != nil {
+			t.Fatalf("expected the same configuration to reload in place, got %v", err)
+		}
+	}
+
+	// Changing anything but the templates requires a new runner.
+	changed := config.TestConfig(&config.Config{
+		Consul:    config.String("1.2.3.4:8500"),
+		Templates: c.Templates,
+	})
+	if _, err := r.prepareReload(changed); err != ErrReloadRequiresRestart {
+		t.Fatalf("expected %q, got %v", ErrReloadRequiresRestart, err)
+	}
+
+	// Invalid templates are rejected without changing the runner.
+	invalid := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+				Priority:    config.String("urgent"),
+			},
+		},
+	})
+	if _, err := r.prepareReload(invalid); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(r.templates) != 2 {
+		t.Fatalf("expected 2 templates, got %d", len(r.templates))
+	}
+
+	next, err := r.prepareReload(config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "baz" }}`),
+				Destination: config.String("/tmp/c"),
+			},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.applyReload(next)
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string][]string{
+		"kv.block(baz)": []string{`"(dynamic)" => "/tmp/c"`},
+		"kv.block(foo)": []string{`"(dynamic)" => "/tmp/a"`},
+	}
+	if act := r.DependencyReferences(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	// The data of the dependencies which are still used is kept.
+	if data, ok := r.brain.Recall(foo); !ok || data != "1" {
+		t.Errorf("expected the data of %s to be kept, got %v", foo, data)
+	}
+	if _, ok := r.brain.Recall(bar); ok {
+		t.Errorf("expected the data of %s to be forgotten", bar)
+	}
+	if !r.watcher.Watching(foo) {
+		t.Errorf("expected %s to still be watched", foo)
+	}
+	if r.watcher.Watching(bar) {
+		t.Errorf("expected %s to no longer be watched", bar)
+	}
+}
+
+func TestSameSettings(t *testing.T) {
+	t.Parallel()
+
+	a := config.TestConfig(&config.Config{})
+	b := config.DefaultConfig().Merge(a)
+	b.Finalize()
+	b.Exec.Listeners, b.Vars = nil, map[string]string{}
+	if !sameSettings(a, b) {
+		t.Error("expected nil and empty settings to be the same")
+	}
+
+	b.Exec.Listeners = []string{"tcp://127.0.0.1:8080"}
+	if sameSettings(a, b) {
+		t.Error("expected the listeners to differ")
+	}
+}
@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// remoteConfigWaitTime is the maximum amount of time a single blocking query
+	// on the remote configuration prefix waits for a change.
+	remoteConfigWaitTime = 60 * time.Second
+
+	// remoteConfigRetry is the amount of time to wait before retrying a failed
+	// query on the remote configuration prefix.
+	remoteConfigRetry = 5 * time.Second
+)
+
+// parseRemoteConfig parses the template blocks stored in the given KV pairs, in
+// the order of their keys. Folders and empty keys are skipped. Only template
+// blocks are read; any other settings in the pairs are ignored.
+func parseRemoteConfig(pairs consulapi.KVPairs) (*config.TemplateConfigs, error) {
+	templates := config.DefaultTemplateConfigs()
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") || len(bytes.TrimSpace(pair.Value)) == 0 {
+			continue
+		}
+
+		c, err := config.Parse(string(pair.Value))
+		if err != nil {
+			return nil, fmt.Errorf("remote config: %s: %s", pair.Key, err)
+		}
+		templates = templates.Merge(c.Templates)
+	}
+
+	templates.Finalize()
+	return templates, nil
+}
+
+// remoteConfigHash returns a hash of the keys and values of the given KV pairs,
+// which changes whenever the remote configuration changes. Unlike the index, it
+// is unaffected by writes which do not change any value.
+func remoteConfigHash(pairs consulapi.KVPairs) string {
+	h := md5.New()
+	for _, pair := range pairs {
+		h.Write([]byte(pair.Key))
+		h.Write([]byte{0})
+		h.Write(pair.Value)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadRemoteConfig reads the template configuration stored under the remote
+// configuration prefix and adds it to the templates of this runner.
+func (r *Runner) loadRemoteConfig() error {
+	prefix := config.StringVal(r.config.RemoteConfig.Prefix)
+	pairs, meta, err := r.clients.Consul().KV().List(prefix, nil)
+	if err != nil {
+		return fmt.Errorf("runner: remote config: %s", err)
+	}
+
+	templates, err := parseRemoteConfig(pairs)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+	log.Printf("[INFO] (runner) loaded %d template(s) from remote config %q",
+		len(*templates), prefix)
+
+	r.config.Templates = r.config.Templates.Merge(templates)
+	r.remoteConfigIndex = meta.LastIndex
+	r.remoteConfigHash = remoteConfigHash(pairs)
+	return nil
+}
+
+// watchRemoteConfig watches the given prefix, starting at the given index, and
+// sends on changeCh once its contents no longer match the given hash. This
+// function blocks until a change is found or doneCh is closed and should be
+// run in a goroutine.
+func watchRemoteConfig(client *consulapi.Client, prefix string, index uint64,
+	hash string, changeCh chan<- struct{}, doneCh <-chan struct{}) {
+	for {
+		pairs, meta, err := client.KV().List(prefix, &consulapi.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  remoteConfigWaitTime,
+		})
+
+		select {
+		case <-doneCh:
+			return
+		default:
+		}
+
+		if err != nil {
+			log.Printf("[WARN] (runner) failed to watch remote config %q: %s",
+				prefix, err)
+			select {
+			case <-time.After(remoteConfigRetry):
+				continue
+			case <-doneCh:
+				return
+			}
+		}
+
+		// Reset the index if it went backwards, such as after a snapshot restore.
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+
+		if remoteConfigHash(pairs) != hash {
+			log.Printf("[INFO] (runner) remote config %q changed", prefix)
+			select {
+			case changeCh <- struct{}{}:
+			default:
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestParseRemoteConfig(t *testing.T) {
+	cases := []struct {
+		name  string
+		pairs consulapi.KVPairs
+		exp   []string
+		err   bool
+	}{
+		{
+			"empty",
+			nil,
+			[]string{},
+			false,
+		},
+		{
+			"in_key_order",
+			consulapi.KVPairs{
+				{Key: "web/a", Value: []byte(`template {
+					contents    = "a"
+					destination = "/tmp/a"
+				}`)},
+				{Key: "web/b", Value: []byte(`
+					template {
+						contents    = "b"
+						destination = "/tmp/b"
+					}
+					template {
+						contents    = "c"
+						destination = "/tmp/c"
+					}
+				`)},
+			},
+			[]string{"/tmp/a", "/tmp/b", "/tmp/c"},
+			false,
+		},
+		{
+			"skips_folders_and_empty",
+			consulapi.KVPairs{
+				{Key: "web/", Value: nil},
+				{Key: "web/a", Value: []byte(" \n")},
+				{Key: "web/b", Value: []byte(`template {
+					contents    = "b"
+					destination = "/tmp/b"
+				}`)},
+			},
+			[]string{"/tmp/b"},
+			false,
+		},
+		{
+			"invalid",
+			consulapi.KVPairs{
+				{Key: "web/a", Value: []byte(`template {`)},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			templates, err := parseRemoteConfig(tc.pairs)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if templates == nil {
+				return
+			}
+
+			act := make([]string, 0, len(*templates))
+			for _, tmpl := range *templates {
+				act = append(act, config.StringVal(tmpl.Destination))
+			}
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestRemoteConfigHash(t *testing.T) {
+	a := consulapi.KVPairs{
+		{Key: "web/a", Value: []byte("foo"), ModifyIndex: 1},
+	}
+	b := consulapi.KVPairs{
+		{Key: "web/a", Value: []byte("foo"), ModifyIndex: 2},
+	}
+	c := consulapi.KVPairs{
+		{Key: "web/a", Value: []byte("bar"), ModifyIndex: 3},
+	}
+
+	if remoteConfigHash(a) != remoteConfigHash(b) {
+		t.Errorf("expected unchanged values to hash the same")
+	}
+	if remoteConfigHash(a) == remoteConfigHash(c) {
+		t.Errorf("expected changed values to hash differently")
+	}
+}
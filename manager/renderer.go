@@ -8,18 +8,64 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/hashicorp/consul-template/config"
 	"github.com/pkg/errors"
 )
 
+// Renderer is the interface for committing the rendered contents of a
+// template. Embedders can provide their own implementation to capture the
+// output or write it somewhere other than the local disk.
+type Renderer interface {
+	Render(*RenderInput) (*RenderResult, error)
+}
+
+// RendererFunc is an adapter to allow the use of an ordinary function as a
+// Renderer.
+type RendererFunc func(*RenderInput) (*RenderResult, error)
+
+// Render calls f(i).
+func (f RendererFunc) Render(i *RenderInput) (*RenderResult, error) {
+	return f(i)
+}
+
+// RenderInput is the input to a Renderer for a single template destination.
+// ACL is an SDDL string which is applied instead of Perms on Windows. If
+// PreservePerms is true, the mode and owner of an existing destination are
+// kept and Perms only applies to a new file. If CreateDestDirs is true, the
+// missing parent directories of Path are created with DestDirPerms and the
+// owner given by DestDirUser and DestDirGroup, which are names or ids. If
+// RequireDestDir is true instead, rendering fails while the directory is
+// missing. Otherwise, it is created with permissions 0755. If Split is true,
+// Path is a directory and Contents, a JSON object, is written to a file per
+// key; see SplitContents. If Strategy is "symlink", Path is a symlink to the
+// newest of up to Versions versioned files; see renderSymlink. Compare decides
+// if Contents differ from an existing destination, which is compared byte by
+// byte if it is nil or empty. Header, if any, is written before Contents and
+// the same number of lines is skipped at the start of the destination when
+// comparing them.
 type RenderInput struct {
-	Backup    bool
-	Contents  []byte
-	Dry       bool
-	DryStream io.Writer
-	Path      string
-	Perms     os.FileMode
+	ACL            string
+	Backup         bool
+	Compare        CompareFunc
+	Contents       []byte
+	CreateDestDirs bool
+	DestDirGroup   string
+	DestDirPerms   os.FileMode
+	DestDirUser    string
+	Dry            bool
+	DryStream      io.Writer
+	Header         []byte
+	Path           string
+	Perms          os.FileMode
+	PreservePerms  bool
+	RequireDestDir bool
+	Split          bool
+	Strategy       string
+	Versions       int
 }
 
+// RenderResult is the result of a Renderer. WouldRender is true if the contents
+// are in place, and DidRender is true if they were actually changed.
 type RenderResult struct {
 	DidRender   bool
 	WouldRender bool
@@ -28,12 +74,16 @@ type RenderResult struct {
 // Render atomically renders a file contents to disk, returning a result of
 // whether it would have rendered and actually did render.
 func Render(i *RenderInput) (*RenderResult, error) {
+	if i.Split {
+		return renderSplit(i)
+	}
+
 	existing, err := ioutil.ReadFile(i.Path)
 	if err != nil && !os.IsNotExist(err) {
 		return nil, errors.Wrap(err, "failed reading file")
 	}
 
-	if bytes.Equal(existing, i.Contents) {
+	if body, ok := i.withoutHeader(existing); ok && i.equal(body, i.Contents) {
 		return &RenderResult{
 			DidRender:   false,
 			WouldRender: true,
@@ -41,9 +91,16 @@ func Render(i *RenderInput) (*RenderResult, error) {
 	}
 
 	if i.Dry {
-		fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.Contents)
+		fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.output())
 	} else {
-		if err := AtomicWrite(i.Path, i.Contents, i.Perms, i.Backup); err != nil {
+		if err := i.prepareDestDir(filepath.Dir(i.Path)); err != nil {
+			return nil, errors.Wrap(err, "failed creating destination directory")
+		}
+		if i.Strategy == config.TemplateRenderStrategySymlink {
+			if err := renderSymlink(i); err != nil {
+				return nil, errors.Wrap(err, "failed writing file")
+			}
+		} else if err := atomicWrite(i.Path, i.output(), i.Perms, i.ACL, i.PreservePerms, i.Backup); err != nil {
 			return nil, errors.Wrap(err, "failed writing file")
 		}
 	}
@@ -54,12 +111,45 @@ func Render(i *RenderInput) (*RenderResult, error) {
 	}, nil
 }
 
+// equal returns true if the existing contents of the destination and the
+// rendered contents are equal, using Compare if it is set.
+func (i *RenderInput) equal(existing, contents []byte) bool {
+	if i.Compare == nil || len(existing) == 0 {
+		return bytes.Equal(existing, contents)
+	}
+	return i.Compare(existing, contents)
+}
+
+// output returns the contents written to the destination, which are Contents
+// preceded by Header.
+func (i *RenderInput) output() []byte {
+	if len(i.Header) == 0 {
+		return i.Contents
+	}
+	return append(append([]byte{}, i.Header...), i.Contents...)
+}
+
+// withoutHeader returns the existing contents of the destination without as
+// many lines as Header has, or false if they have fewer lines, in which case
+// they cannot be equal to the output.
+func (i *RenderInput) withoutHeader(existing []byte) ([]byte, bool) {
+	for n := bytes.Count(i.Header, []byte("\n")); n > 0; n-- {
+		idx := bytes.IndexByte(existing, '\n')
+		if idx < 0 {
+			return nil, false
+		}
+		existing = existing[idx+1:]
+	}
+	return existing, true
+}
+
 // AtomicWrite accepts a destination path and the template contents. It writes
 // the template contents to a TempFile on disk, returning if any errors occur.
 //
 // If the parent destination directory does not exist, it will be created
-// automatically with permissions 0755. To use a different permission, create
-// the directory first or use `chmod` in a Command.
+// automatically with permissions 0755, regardless of the umask. To use a
+// different permission, create the directory first or render with
+// CreateDestDirs and DestDirPerms.
 //
 // If the destination path exists, all attempts will be made to preserve the
 // existing file permissions. If those permissions cannot be read, an error is
@@ -67,22 +157,87 @@ func Render(i *RenderInput) (*RenderResult, error) {
 // permissions 0644. To use a different permission, create the destination file
 // first or use `chmod` in a Command.
 //
+// Before the destination is replaced, the filesystem is checked for enough
+// free space, the written size of the TempFile is verified and the TempFile is
+// checked to be on the same filesystem as the destination. If any check fails,
+// the existing destination is left in place and ErrInsufficientSpace,
+// ErrShortWrite or ErrCrossDevice is returned. If the parent directory is not
+// writable, ErrNotWritable is returned.
+//
 // If no errors occur, the Tempfile is "renamed" (moved) to the destination
-// path.
+// path. On Windows, the rename is retried for a short while if the destination
+// is open by a reader which did not allow it to be replaced.
 func AtomicWrite(path string, contents []byte, perms os.FileMode, backup bool) error {
+	if path != "" {
+		if err := createDestDirs(filepath.Dir(path), 0755, -1, -1); err != nil {
+			return err
+		}
+	}
+	return atomicWrite(path, contents, perms, "", false, backup)
+}
+
+// prepareDestDir creates the given directory of the destination, and its
+// parents, if they are missing. With CreateDestDirs, they are created with
+// DestDirPerms and the owner given by DestDirUser and DestDirGroup. With
+// RequireDestDir, nothing is created, so writing the destination fails while
+// the directory is missing. Otherwise, they are created with permissions 0755.
+func (i *RenderInput) prepareDestDir(dir string) error {
+	switch {
+	case i.CreateDestDirs:
+		uid, gid, err := lookupOwner(i.DestDirUser, i.DestDirGroup)
+		if err != nil {
+			return err
+		}
+		return createDestDirs(dir, i.DestDirPerms, uid, gid)
+	case i.RequireDestDir:
+		return nil
+	}
+
+	dir = longPath(dir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.MkdirAll(dir, 0755)
+	}
+	return nil
+}
+
+// atomicWrite is AtomicWrite which applies the given ACL, if any, instead of
+// the permissions. If preserve is true, the mode and owner of an existing
+// destination are applied instead of the permissions. Unlike AtomicWrite, it
+// does not create the parent directory.
+func atomicWrite(path string, contents []byte, perms os.FileMode, acl string, preserve, backup bool) error {
 	if path == "" {
 		return fmt.Errorf("missing destination")
 	}
+	path = longPath(path)
 
 	parent := filepath.Dir(path)
 	if _, err := os.Stat(parent); os.IsNotExist(err) {
-		if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("destination directory %s does not exist", parent)
+	}
+
+	if err := checkFreeSpace(parent, len(contents)); err != nil {
+		return err
+	}
+
+	// Keep the mode and owner of the existing destination, which may be managed
+	// by other tooling.
+	uid, gid, chown := -1, -1, false
+	if preserve {
+		stat, err := os.Stat(path)
+		switch {
+		case err == nil:
+			perms = stat.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+			uid, gid, chown = fileOwner(stat)
+		case !os.IsNotExist(err):
 			return err
 		}
 	}
 
 	f, err := ioutil.TempFile(parent, "")
 	if err != nil {
+		if isNotWritable(err) {
+			return errors.Wrapf(ErrNotWritable, "%s: %s", parent, err)
+		}
 		return err
 	}
 	defer os.Remove(f.Name())
@@ -99,7 +254,34 @@ func AtomicWrite(path string, contents []byte, perms os.FileMode, backup bool) e
 		return err
 	}
 
-	if err := os.Chmod(f.Name(), perms); err != nil {
+	// The owner is changed first, since changing it clears the setuid and
+	// setgid bits. It is left alone if it already matches, which does not
+	// require privileges.
+	if chown {
+		stat, err := os.Stat(f.Name())
+		if err != nil {
+			return err
+		}
+		if tuid, tgid, _ := fileOwner(stat); tuid != uid || tgid != gid {
+			if err := os.Chown(f.Name(), uid, gid); err != nil {
+				return errors.Wrap(err, "failed to preserve owner")
+			}
+		}
+	}
+
+	if acl != "" {
+		if err := setACL(f.Name(), acl); err != nil {
+			return err
+		}
+	} else if err := os.Chmod(f.Name(), perms); err != nil {
+		return err
+	}
+
+	if err := checkWritten(f.Name(), len(contents)); err != nil {
+		return err
+	}
+
+	if err := checkSameDevice(f.Name(), path); err != nil {
 		return err
 	}
 
@@ -113,15 +295,65 @@ func AtomicWrite(path string, contents []byte, perms os.FileMode, backup bool) e
 		}
 	}
 
-	if err := os.Rename(f.Name(), path); err != nil {
+	if err := renameFile(f.Name(), path); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// createDestDirs creates the given directory and its missing parents with the
+// given permissions, which are applied after each directory is created so the
+// umask does not narrow them. Each created directory is owned by the given
+// user and group, unless they are -1. Existing directories are left alone.
+func createDestDirs(dir string, perms os.FileMode, uid, gid int) error {
+	dir = longPath(dir)
+	perms &= os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		_, err := os.Stat(d)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	// Create the directories from the top down.
+	for i := len(missing) - 1; i >= 0; i-- {
+		d := missing[i]
+		if err := os.Mkdir(d, perms); err != nil {
+			// Another process may have created it in the meantime.
+			if os.IsExist(err) {
+				continue
+			}
+			return err
+		}
+
+		// The owner is changed first, since changing it clears the setuid and
+		// setgid bits.
+		if uid != -1 || gid != -1 {
+			if err := os.Chown(d, uid, gid); err != nil {
+				return errors.Wrap(err, "failed to change owner")
+			}
+		}
+		if err := os.Chmod(d, perms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // copyFile copies the file at src to the path at dst. Any errors that occur
-// are returned.
+// are returned. The copy is written to a TempFile which is then renamed to
+// dst, so a reader which has dst open never sees a partial copy and, on
+// Windows, does not make the copy fail.
 func copyFile(src, dst string) error {
 	s, err := os.Open(src)
 	if err != nil {
@@ -134,13 +366,22 @@ func copyFile(src, dst string) error {
 		return err
 	}
 
-	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, stat.Mode())
+	d, err := ioutil.TempFile(filepath.Dir(dst), "")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(d.Name())
+
 	if _, err := io.Copy(d, s); err != nil {
 		d.Close()
 		return err
 	}
-	return d.Close()
+	if err := d.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(d.Name(), stat.Mode()); err != nil {
+		return err
+	}
+	return renameFile(d.Name(), dst)
 }
@@ -0,0 +1,89 @@
+// +build !windows
+
+package manager
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// errACLUnsupported is returned when a template sets an ACL on a platform
+// other than Windows.
+var errACLUnsupported = errors.New("acl is only supported on Windows")
+
+// renameFile renames src to dst, replacing dst if it exists.
+func renameFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// longPath returns the given path unchanged, since only Windows limits the
+// length of paths.
+func longPath(path string) string {
+	return path
+}
+
+// fileOwner returns the user and group which own the file with the given info.
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// lookupOwner returns the ids of the given user and group, which are names or
+// ids. An empty user or group is returned as -1, which leaves it unchanged.
+func lookupOwner(name, group string) (int, int, error) {
+	uid, gid := -1, -1
+
+	if name != "" {
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return -1, -1, err
+			}
+			if id, err = strconv.Atoi(u.Uid); err != nil {
+				return -1, -1, err
+			}
+		}
+		uid = id
+	}
+
+	if group != "" {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, err
+			}
+			if id, err = strconv.Atoi(g.Gid); err != nil {
+				return -1, -1, err
+			}
+		}
+		gid = id
+	}
+
+	return uid, gid, nil
+}
+
+// validateSplit returns nil, since split destinations are supported.
+func validateSplit() error {
+	return nil
+}
+
+// validateSymlink returns nil, since the symlink render strategy is supported.
+func validateSymlink() error {
+	return nil
+}
+
+func validateACL(sddl string) error {
+	return errACLUnsupported
+}
+
+func setACL(path, sddl string) error {
+	return errACLUnsupported
+}
@@ -0,0 +1,198 @@
+// +build !windows
+
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAtomicWrite_aclUnsupported(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	path := filepath.Join(outDir, "out")
+	err = atomicWrite(path, []byte("after"), 0644, "D:P(A;;FA;;;SY)", false, false)
+	if err != errACLUnsupported {
+		t.Fatalf("expected %q, got %v", errACLUnsupported, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected %s not to be written: %v", path, err)
+	}
+}
+
+func TestAtomicWrite_preservePerms(t *testing.T) {
+	t.Run("existing", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		path := filepath.Join(outDir, "out")
+		if err := ioutil.WriteFile(path, []byte("before"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0750); err != nil {
+			t.Fatal(err)
+		}
+		before, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := atomicWrite(path, []byte("after"), 0644, "", true, false); err != nil {
+			t.Fatal(err)
+		}
+
+		after, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after.Mode() != before.Mode() {
+			t.Errorf("\nexp: %#v\nact: %#v", before.Mode(), after.Mode())
+		}
+
+		uid, gid, _ := fileOwner(before)
+		auid, agid, _ := fileOwner(after)
+		if auid != uid || agid != gid {
+			t.Errorf("expected owner %d:%d, got %d:%d", uid, gid, auid, agid)
+		}
+	})
+
+	t.Run("non_existent", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		path := filepath.Join(outDir, "out")
+		if err := atomicWrite(path, []byte("after"), 0640, "", true, false); err != nil {
+			t.Fatal(err)
+		}
+
+		stat, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := os.FileMode(0640); stat.Mode() != exp {
+			t.Errorf("\nexp: %#v\nact: %#v", exp, stat.Mode())
+		}
+	})
+}
+
+func TestRender_createDestDirs(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		// Permissions wider than the usual umask are applied as given.
+		path := filepath.Join(outDir, "a", "b", "out")
+		if _, err := Render(&RenderInput{
+			Contents:       []byte("after"),
+			CreateDestDirs: true,
+			DestDirPerms:   0777,
+			DestDirUser:    strconv.Itoa(os.Getuid()),
+			Path:           path,
+			Perms:          0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, dir := range []string{filepath.Join(outDir, "a"), filepath.Join(outDir, "a", "b")} {
+			stat, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := os.ModeDir | 0777; stat.Mode() != exp {
+				t.Errorf("%s\nexp: %#v\nact: %#v", dir, exp, stat.Mode())
+			}
+		}
+
+		stat, err := os.Stat(outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := os.ModeDir | 0700; stat.Mode() != exp {
+			t.Errorf("expected the existing directory to be left alone\nexp: %#v\nact: %#v", exp, stat.Mode())
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		// Without any of the options, missing directories are still created.
+		path := filepath.Join(outDir, "a", "b", "out")
+		if _, err := Render(&RenderInput{
+			Contents: []byte("after"),
+			Path:     path,
+			Perms:    0644,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := "after"; string(b) != exp {
+			t.Errorf("\nexp: %q\nact: %q", exp, b)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		path := filepath.Join(outDir, "a", "out")
+		_, err = Render(&RenderInput{
+			Contents:       []byte("after"),
+			Path:           path,
+			Perms:          0644,
+			RequireDestDir: true,
+		})
+		if err == nil || !strings.Contains(err.Error(), "does not exist") {
+			t.Fatalf("expected a missing directory error, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s not to be created: %v", filepath.Dir(path), err)
+		}
+	})
+
+	t.Run("unknown_user", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		if _, err := Render(&RenderInput{
+			Contents:       []byte("after"),
+			CreateDestDirs: true,
+			DestDirPerms:   0755,
+			DestDirUser:    "not-a-real-user-consul-template",
+			Path:           filepath.Join(outDir, "a", "out"),
+			Perms:          0644,
+		}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,222 @@
+// +build windows
+
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// renameAttempts and renameRetry bound how long renameFile waits for the
+	// readers of the destination which did not open it with FILE_SHARE_DELETE.
+	renameAttempts = 20
+	renameRetry    = 50 * time.Millisecond
+
+	// longPathThreshold is the length from which paths are given the extended
+	// length prefix. It is MAX_PATH minus room for a file name in 8.3 format,
+	// which is the limit for directories.
+	longPathThreshold = 248
+
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+
+	sddlRevision1                      = 1
+	seFileObject                       = 1
+	seDaclProtected                    = 0x1000
+	daclSecurityInformation            = 0x00000004
+	protectedDaclSecurityInformation   = 0x80000000
+	unprotectedDaclSecurityInformation = 0x20000000
+)
+
+// advapi32 and kernel32 are KnownDLLs, so they are always loaded from the
+// system directory.
+var (
+	advapi32 = syscall.NewLazyDLL("advapi32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procConvertStringSecurityDescriptorToSecurityDescriptorW = advapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+	procGetSecurityDescriptorControl                         = advapi32.NewProc("GetSecurityDescriptorControl")
+	procGetSecurityDescriptorDacl                            = advapi32.NewProc("GetSecurityDescriptorDacl")
+	procSetNamedSecurityInfoW                                = advapi32.NewProc("SetNamedSecurityInfoW")
+	procLocalFree                                            = kernel32.NewProc("LocalFree")
+)
+
+// renameFile renames src to dst, replacing dst if it exists. Windows refuses
+// to replace a file which is open by a reader that did not allow it to be
+// deleted, so the rename is retried until the reader closes the file or the
+// attempts run out.
+func renameFile(src, dst string) error {
+	var err error
+	for attempt := 0; attempt < renameAttempts; attempt++ {
+		if err = os.Rename(src, dst); err == nil || !isSharingViolation(err) {
+			return err
+		}
+		time.Sleep(renameRetry)
+	}
+	return err
+}
+
+// isSharingViolation returns true if the given error is caused by another
+// process having the file open. Replacing such a file fails with an access
+// denied error, so genuine permission errors are retried as well.
+func isSharingViolation(err error) bool {
+	if lerr, ok := err.(*os.LinkError); ok {
+		err = lerr.Err
+	}
+
+	switch err {
+	case errorSharingViolation, errorLockViolation, syscall.ERROR_ACCESS_DENIED:
+		return true
+	}
+	return false
+}
+
+// longPath returns the extended length form of the given path if it is too
+// long for the regular Windows API, so files can be rendered to deeply nested
+// destinations. Shorter paths are returned unchanged.
+func longPath(path string) string {
+	if len(path) < longPathThreshold || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// lookupOwner returns an error if a user or group is given, since files are
+// owned by security identifiers instead of user and group IDs on Windows.
+func lookupOwner(name, group string) (int, int, error) {
+	if name != "" || group != "" {
+		return -1, -1, fmt.Errorf("dest_dir_user and dest_dir_group are not supported on Windows")
+	}
+	return -1, -1, nil
+}
+
+// validateSplit returns an error, since split destinations rely on symlinks,
+// which require privileges on Windows.
+func validateSplit() error {
+	return fmt.Errorf("split_destination is not supported on Windows")
+}
+
+// validateSymlink returns an error, since the symlink render strategy relies
+// on symlinks, which require privileges on Windows.
+func validateSymlink() error {
+	return fmt.Errorf("render_strategy \"symlink\" is not supported on Windows")
+}
+
+// fileOwner returns false, since files are owned by security identifiers
+// instead of user and group IDs on Windows.
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return -1, -1, false
+}
+
+// securityDescriptor is a security descriptor converted from an SDDL string.
+// It must be freed with free.
+type securityDescriptor uintptr
+
+// newSecurityDescriptor converts the given SDDL string.
+func newSecurityDescriptor(sddl string) (securityDescriptor, error) {
+	s, err := syscall.UTF16PtrFromString(sddl)
+	if err != nil {
+		return 0, err
+	}
+
+	var sd uintptr
+	r, _, err := procConvertStringSecurityDescriptorToSecurityDescriptorW.Call(
+		uintptr(unsafe.Pointer(s)), sddlRevision1, uintptr(unsafe.Pointer(&sd)), 0)
+	if r == 0 {
+		return 0, fmt.Errorf("invalid acl %q: %s", sddl, err)
+	}
+	return securityDescriptor(sd), nil
+}
+
+func (sd securityDescriptor) free() {
+	procLocalFree.Call(uintptr(sd))
+}
+
+// dacl returns the DACL of the security descriptor and the security
+// information flags to apply it with.
+func (sd securityDescriptor) dacl() (uintptr, uint32, error) {
+	var control uint16
+	var revision uint32
+	r, _, err := procGetSecurityDescriptorControl.Call(uintptr(sd),
+		uintptr(unsafe.Pointer(&control)), uintptr(unsafe.Pointer(&revision)))
+	if r == 0 {
+		return 0, 0, err
+	}
+
+	var present, defaulted int32
+	var dacl uintptr
+	r, _, err = procGetSecurityDescriptorDacl.Call(uintptr(sd),
+		uintptr(unsafe.Pointer(&present)), uintptr(unsafe.Pointer(&dacl)),
+		uintptr(unsafe.Pointer(&defaulted)))
+	if r == 0 {
+		return 0, 0, err
+	}
+	if present == 0 {
+		return 0, 0, fmt.Errorf("acl has no DACL")
+	}
+
+	info := uint32(daclSecurityInformation)
+	if control&seDaclProtected != 0 {
+		info |= protectedDaclSecurityInformation
+	} else {
+		info |= unprotectedDaclSecurityInformation
+	}
+	return dacl, info, nil
+}
+
+// validateACL returns an error if the given SDDL string is invalid or has no
+// DACL.
+func validateACL(sddl string) error {
+	sd, err := newSecurityDescriptor(sddl)
+	if err != nil {
+		return err
+	}
+	defer sd.free()
+
+	_, _, err = sd.dacl()
+	return err
+}
+
+// setACL applies the DACL of the given SDDL string to the file at path. A DACL
+// marked as protected ("D:P") does not inherit entries from the parent
+// directory.
+func setACL(path, sddl string) error {
+	sd, err := newSecurityDescriptor(sddl)
+	if err != nil {
+		return err
+	}
+	defer sd.free()
+
+	dacl, info, err := sd.dacl()
+	if err != nil {
+		return fmt.Errorf("acl %q: %s", sddl, err)
+	}
+
+	p, err := syscall.UTF16PtrFromString(longPath(path))
+	if err != nil {
+		return err
+	}
+
+	// SetNamedSecurityInfo returns the error code instead of setting the last
+	// error.
+	r, _, _ := procSetNamedSecurityInfoW.Call(uintptr(unsafe.Pointer(p)),
+		seFileObject, uintptr(info), 0, 0, dacl, 0)
+	if r != 0 {
+		return fmt.Errorf("failed to set acl of %s: %s", path, syscall.Errno(r))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// +build windows
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLongPath(t *testing.T) {
+	long := `C:\` + strings.Repeat(`a\`, 130) + "out"
+
+	cases := []struct {
+		name string
+		path string
+		exp  string
+	}{
+		{
+			"short",
+			`C:\out`,
+			`C:\out`,
+		},
+		{
+			"long",
+			long,
+			`\\?\` + long,
+		},
+		{
+			"long_unc",
+			`\\server\share\` + long[3:],
+			`\\?\UNC\server\share\` + long[3:],
+		},
+		{
+			"prefixed",
+			`\\?\` + long,
+			`\\?\` + long,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := longPath(tc.path); act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestAtomicWrite_windows(t *testing.T) {
+	t.Run("destination_open", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		path := filepath.Join(outDir, "out")
+		if err := ioutil.WriteFile(path, []byte("before"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Hold the destination open like a reader would, and close it while the
+		// rename is being retried.
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.AfterFunc(200*time.Millisecond, func() { f.Close() })
+
+		if err := AtomicWrite(path, []byte("after"), 0644, true); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "after" {
+			t.Errorf("\nexp: %#v\nact: %#v", "after", string(b))
+		}
+	})
+
+	t.Run("acl", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		path := filepath.Join(outDir, "out")
+		if err := atomicWrite(path, []byte("after"), 0, "D:P(A;;FA;;;WD)", false, false); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ioutil.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("acl_invalid", func(t *testing.T) {
+		if err := validateACL("not an acl"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
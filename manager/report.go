@@ -0,0 +1,208 @@
+package manager
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// These are the reasons reported for each template in a run report.
+const (
+	ReportReasonRendered            = "rendered"
+	ReportReasonUnchanged           = "unchanged"
+	ReportReasonWaitingForInput     = "waiting_for_input"
+	ReportReasonWaitingForBundle    = "waiting_for_bundle"
+	ReportReasonAlreadyRendered     = "already_rendered"
+	ReportReasonNewDependencies     = "new_dependencies"
+	ReportReasonMissingDependencies = "missing_dependencies"
+	ReportReasonQuiescence          = "quiescence"
+	ReportReasonBlocked             = "blocked"
+	ReportReasonBlackout            = "blackout"
+	ReportReasonCanary              = "canary"
+	ReportReasonFailed              = "failed"
+)
+
+// RunReport is the machine-readable report of a single run.
+type RunReport struct {
+	// Time is the time of the run.
+	Time time.Time `json:"time"`
+
+	// Templates is the outcome of each template destination.
+	Templates []*TemplateReport `json:"templates"`
+
+	// Commands is the result of each command executed after the run.
+	Commands []*CommandReport `json:"commands"`
+}
+
+// TemplateReport is the outcome of a single template destination in a run.
+type TemplateReport struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+
+	// Rendered is true if the destination was written. Reason is one of the
+	// ReportReason constants, and Detail explains it further, if needed.
+	Rendered bool   `json:"rendered"`
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`
+
+	// Dependencies are the dependencies the template used, and Missing are the
+	// ones which had no data yet.
+	Dependencies []string `json:"dependencies"`
+	Missing      []string `json:"missing,omitempty"`
+}
+
+// CommandReport is the result of a single command executed after a run.
+type CommandReport struct {
+	Command     string `json:"command"`
+	Name        string `json:"name,omitempty"`
+	Destination string `json:"destination"`
+
+	// ExitCode is the exit code of the command, or -1 if it did not exit on its
+	// own, such as on a timeout.
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+
+	// Running is true if the command had not exited yet when the report was
+	// written, which happens for commands without a timeout.
+	Running bool `json:"running,omitempty"`
+
+	// Stdout and Stderr are the end of the output of the command, and
+	// Truncated is true if either was cut.
+	Stdout    string `json:"stdout,omitempty"`
+	Stderr    string `json:"stderr,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// newRunReport creates an empty report of a run at the given time.
+func newRunReport(t time.Time) *RunReport {
+	return &RunReport{
+		Time:      t,
+		Templates: []*TemplateReport{},
+		Commands:  []*CommandReport{},
+	}
+}
+
+// addTemplates records the same outcome for each of the given template
+// configs of a template. Used and missing may be nil if the template was not
+// executed. It is a no-op on a nil report.
+func (rr *RunReport) addTemplates(tmpl *template.Template, tcs []*config.TemplateConfig,
+	rendered bool, reason, detail string, used, missing *dep.Set) {
+	for _, tc := range tcs {
+		rr.addTemplate(tmpl, tc, rendered, reason, detail, used, missing)
+	}
+}
+
+// addTemplate records the outcome of a single template config. It is a no-op
+// on a nil report.
+func (rr *RunReport) addTemplate(tmpl *template.Template, tc *config.TemplateConfig,
+	rendered bool, reason, detail string, used, missing *dep.Set) {
+	if rr == nil {
+		return
+	}
+
+	rr.Templates = append(rr.Templates, &TemplateReport{
+		ID:           tmpl.ID(),
+		Name:         config.StringVal(tc.Name),
+		Source:       tmpl.Source(),
+		Destination:  config.StringVal(tc.Destination),
+		Rendered:     rendered,
+		Reason:       reason,
+		Detail:       detail,
+		Dependencies: dependencyStrings(used),
+		Missing:      dependencyStrings(missing),
+	})
+}
+
+// addCommand records the result of a command executed for the given template
+// config. It is a no-op on a nil report.
+func (rr *RunReport) addCommand(tc *config.TemplateConfig, result *CommandResult) {
+	if rr == nil {
+		return
+	}
+
+	rr.Commands = append(rr.Commands, &CommandReport{
+		Command:     result.Command,
+		Name:        config.StringVal(tc.Name),
+		Destination: config.StringVal(tc.Destination),
+		ExitCode:    result.ExitCode,
+		Error:       result.Error,
+		Running:     result.Running,
+		Stdout:      result.Stdout,
+		Stderr:      result.Stderr,
+		Truncated:   result.Truncated,
+	})
+}
+
+// dependencyStrings returns the string form of each dependency in the set.
+func dependencyStrings(s *dep.Set) []string {
+	if s == nil {
+		return nil
+	}
+
+	list := s.List()
+	result := make([]string, 0, len(list))
+	for _, d := range list {
+		result = append(result, d.String())
+	}
+	return result
+}
+
+// emitReport stores the report of the last run, for the status server, and
+// writes it to the configured path, if any. It is a no-op on a nil report.
+func (r *Runner) emitReport(report *RunReport) {
+	if report == nil {
+		return
+	}
+
+	r.reportLock.Lock()
+	r.lastReport = report
+	r.reportLock.Unlock()
+
+	if path := config.StringVal(r.config.Report.Path); path != "" {
+		if err := writeReport(path, report); err != nil {
+			log.Printf("[WARN] (runner) failed to write run report: %s", err)
+		}
+	}
+}
+
+// LastReport returns the report of the last run, or nil if reports are not
+// enabled or no run has finished yet.
+func (r *Runner) LastReport() *RunReport {
+	r.reportLock.RLock()
+	defer r.reportLock.RUnlock()
+	return r.lastReport
+}
+
+// writeReport writes the report to the given path as a single line of JSON.
+// If the path is a named pipe, the line is written to it without blocking, and
+// dropped if there is no reader. Otherwise the file is atomically replaced.
+func writeReport(path string, report *RunReport) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	stat, err := os.Stat(path)
+	if err != nil || stat.Mode()&os.ModeNamedPipe == 0 {
+		return AtomicWrite(path, b, 0644, false)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		log.Printf("[DEBUG] (runner) dropping run report, no reader on %s: %s", path, err)
+		return nil
+	}
+	defer f.Close()
+
+	_, err = f.Write(b)
+	return err
+}
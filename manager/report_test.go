@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_Run_report(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out")
+	path := filepath.Join(dir, "report.json")
+
+	c := config.TestConfig(&config.Config{
+		Report: &config.ReportConfig{
+			Path: config.String(path),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out),
+				Name:        config.String("web"),
+				Exec: &config.ExecConfig{
+					Command: config.String(`sh -c "echo reloading; echo failed >&2; exit 3"`),
+				},
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() *RunReport {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report RunReport
+		if err := json.Unmarshal(b, &report); err != nil {
+			t.Fatal(err)
+		}
+		return &report
+	}
+
+	// The command fails, which fails the run, but the report is still written.
+	if err := r.Run(); err == nil {
+		t.Fatal("expected command error")
+	}
+
+	report := read()
+	if l := len(report.Templates); l != 1 {
+		t.Fatalf("expected 1 template, got %d", l)
+	}
+	if tr := report.Templates[0]; !tr.Rendered || tr.Reason != ReportReasonRendered ||
+		tr.Destination != out || tr.Name != "web" {
+		t.Errorf("unexpected template report: %#v", tr)
+	}
+	if l := len(report.Commands); l != 1 {
+		t.Fatalf("expected 1 command, got %d", l)
+	}
+	if cr := report.Commands[0]; cr.ExitCode != 3 || cr.Error == "" || cr.Name != "web" ||
+		cr.Stdout != "reloading\n" || cr.Stderr != "failed\n" {
+		t.Errorf("unexpected command report: %#v", cr)
+	}
+
+	// The second run does not change the destination or run the command.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	report = read()
+	if tr := report.Templates[0]; tr.Rendered || tr.Reason != ReportReasonUnchanged {
+		t.Errorf("unexpected template report: %#v", tr)
+	}
+	if l := len(report.Commands); l != 0 {
+		t.Errorf("expected no commands, got %d", l)
+	}
+
+	// The status server serves the same report.
+	s := newStatusServer(c.Status, r)
+	rec := httptest.NewRecorder()
+	s.handleReport(rec, httptest.NewRequest("GET", "/report", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestStatusServer_handleReport_disabled(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRunner(config.TestConfig(nil), true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newStatusServer(r.config.Status, r)
+	rec := httptest.NewRecorder()
+	s.handleReport(rec, httptest.NewRequest("GET", "/report", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
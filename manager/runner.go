@@ -1,18 +1,27 @@
 package manager
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/hashicorp/consul-template/child"
 	"github.com/hashicorp/consul-template/config"
 	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/logging"
+	"github.com/hashicorp/consul-template/plugin"
 	"github.com/hashicorp/consul-template/template"
 	"github.com/hashicorp/consul-template/watch"
 	"github.com/hashicorp/go-multierror"
@@ -32,6 +41,14 @@ type Runner struct {
 	ErrCh  chan error
 	DoneCh chan struct{}
 
+	// ReloadCh receives a notification when the configuration should be
+	// reloaded, such as when the remote configuration changes.
+	ReloadCh chan struct{}
+
+	// reloadConfigCh receives the templates of a configuration reloaded in
+	// place, prepared by Reload.
+	reloadConfigCh chan *Runner
+
 	// config is the Config that created this Runner. It is used internally to
 	// construct other objects and pass data.
 	config *config.Config
@@ -41,6 +58,14 @@ type Runner struct {
 	// time and then stop.
 	dry, once bool
 
+	// observe signals that the changes which would be rendered are logged
+	// instead of written, and no commands run.
+	observe bool
+
+	// observed is the last observed render of each template configuration in
+	// observe mode.
+	observed map[*config.TemplateConfig]*observation
+
 	// outStream and errStream are the io.Writer streams where the runner will
 	// write information. These streams can be set using the SetOutStream()
 	// and SetErrStream() functions.
@@ -49,11 +74,17 @@ type Runner struct {
 	outStream, errStream io.Writer
 	inStream             io.Reader
 
+	// renderer commits the rendered contents of each template. It can be set
+	// using the SetRenderer() function.
+	renderer Renderer
+
 	// ctemplatesMap is a map of each template ID to the TemplateConfigs
 	// that made it.
 	ctemplatesMap map[string]config.TemplateConfigs
 
-	// templates is the list of calculated templates.
+	// templates is the list of calculated templates. It, ctemplatesMap and
+	// readyChecks are replaced by a reload while holding renderEventsLock, so
+	// they are read with renderEventsLock held outside of the event loop.
 	templates []*template.Template
 
 	// renderEvents is a mapping of a template ID to the render event.
@@ -62,18 +93,81 @@ type Runner struct {
 	// renderEventLock protects access into the renderEvents map
 	renderEventsLock sync.RWMutex
 
+	// renderEventCh receives a copy of the render event of each template which
+	// changed, once per run and once for each command result. changedEvents
+	// are the IDs of the templates whose events changed since the last copies
+	// were sent, and is protected by renderEventsLock.
+	renderEventCh chan *RenderEvent
+	changedEvents map[string]struct{}
+
 	// renderedCh is used to signal that a template has been rendered
 	renderedCh chan struct{}
 
+	// wouldRenderCh and didRenderCh receive the ID of each template that would
+	// have rendered or was actually written to disk, respectively. These
+	// channels are buffered and sends never block the runner.
+	wouldRenderCh chan string
+	didRenderCh   chan string
+
 	// dependencies is the list of dependencies this runner is watching.
 	dependencies map[string]dep.Dependency
 
-	// dependenciesLock is a lock around touching the dependencies map.
+	// templateDeps is a mapping of a template ID to the dependencies it used
+	// the last time it was executed. A dependency is watched as long as at
+	// least one template references it, so templates which are skipped during
+	// a run keep their dependencies.
+	templateDeps map[string]map[string]dep.Dependency
+
+	// staleSince is the time each dependency which failed after having
+	// returned data started failing. Its templates keep rendering that data
+	// for the stale grace period of the watcher.
+	staleSince map[string]time.Time
+
+	// dependenciesLock is a lock around touching the dependencies,
+	// templateDeps and staleSince maps.
 	dependenciesLock sync.Mutex
 
+	// inputTemplates is a mapping of a template ID to the ID of the template
+	// whose output it takes as input. renderedOutputs is a mapping of a template
+	// ID to its most recently rendered output.
+	inputTemplates  map[string]string
+	renderedOutputs map[string][]byte
+
+	// seeds is a mapping of a template ID to the persisted seed of its random
+	// functions. Templates without a seed file are not in the map.
+	seeds map[string]int64
+
+	// rotations is a mapping of a template ID to the secret rotation which
+	// holds back new versions of its secrets. Templates without rotation
+	// windows are not in the map.
+	rotations map[string]*template.SecretRotation
+
+	// renderedRevisions is a mapping of a template ID to the brain revision of
+	// each dependency at the time the template was last rendered. It is used to
+	// determine which dependencies changed between renders.
+	renderedRevisions map[string]map[string]uint64
+
+	// lastReport is the report of the last run, if reports are enabled.
+	// reportLock protects it.
+	lastReport *RunReport
+	reportLock sync.RWMutex
+
 	// watcher is the watcher this runner is using.
 	watcher *watch.Watcher
 
+	// clients is the set of API clients shared by all dependencies.
+	clients *dep.ClientSet
+
+	// consulToken and vaultToken are the tokens the clients were created with.
+	// tokenCh receives a notification when a configured token file changes.
+	consulToken, vaultToken string
+	tokenCh                 chan struct{}
+
+	// remoteConfigIndex and remoteConfigHash are the index and hash of the
+	// remote configuration this runner was created with.
+	remoteConfigIndex uint64
+	remoteConfigHash  string
+
 	// brain is the internal storage database of returned dependency data.
 	brain *template.Brain
 
@@ -84,15 +178,153 @@ type Runner struct {
 	// childLock is the internal lock around the child process.
 	childLock sync.RWMutex
 
+	// reloadHeldSince is when a reload of the child process was first held by
+	// reload pacing, and reloadRecheck is when the held reload is checked
+	// again. Both are zero when no reload is held.
+	reloadHeldSince, reloadRecheck time.Time
+
+	// childMonitor samples the resource usage of the child process, if enabled.
+	// childRestartCh is where the monitor requests a restart of the child.
+	childMonitor   *childMonitor
+	childRestartCh chan struct{}
+
+	// childEscalation verifies the child process is ready after the last
+	// reload, if enabled. childEscalateCh is where it requests the next step.
+	childEscalation *childEscalation
+	childEscalateCh chan string
+
+	// templateChildren are the supervised processes of the templates whose exec
+	// block sets supervise, keyed by the template ID. They are protected by
+	// templateChildrenLock.
+	templateChildren     map[string]*child.Child
+	templateChildrenLock sync.Mutex
+
+	// readyChecks are the ready checks of the processes started by the commands
+	// of the template configurations which have one.
+	readyChecks map[*config.TemplateConfig]*readyCheck
+
+	// compares are the functions which compare the rendered contents with the
+	// destination of the template configurations which do not compare them
+	// byte by byte.
+	compares map[*config.TemplateConfig]CompareFunc
+
+	// headers are the parsed header templates of the template configurations
+	// which add a header to their output.
+	headers map[*config.TemplateConfig]*texttemplate.Template
+
+	// logLevels are the log levels of the template log tags, applied to the
+	// logging once the templates are in use.
+	logLevels map[string]string
+
+	// childEventCh receives the events in the lifecycle of the child process.
+	// It is buffered and sends never block the runner.
+	childEventCh chan *ChildEvent
+
 	// quiescenceMap is the map of templates to their quiescence timers.
 	// quiescenceCh is the channel where templates report returns from quiescence
 	// fires.
 	quiescenceMap map[string]*quiescence
 	quiescenceCh  chan *template.Template
 
+	// blackouts are the blackout windows of each template configuration,
+	// including the global ones. blackoutEnd is the earliest end of the
+	// blackouts which held a template in the last run, or zero if none did.
+	blackouts   map[*config.TemplateConfig][]*blackoutWindow
+	blackoutEnd time.Time
+
+	// bundles are the archives which package the rendered output of several
+	// template configurations, and templateBundles is the bundle of each
+	// template configuration packaged into one.
+	bundles         []*bundle
+	templateBundles map[*config.TemplateConfig]*bundle
+
+	// canary holds changed contents on hosts which are not canaries until they
+	// have baked, if the canary render mode is enabled.
+	canary *canary
+
 	// dedup is the deduplication manager if enabled
 	dedup *DedupManager
 
+	// coordinator limits how many instances reload at a time, if enabled
+	coordinator *ReloadCoordinator
+
+	// onceBarrier is the barrier which instances in once mode wait at before
+	// running their commands, if enabled. onceBarrierCommands are the commands
+	// held until it opens, and onceBarrierPassed is true once it did.
+	onceBarrier         *onceBarrier
+	onceBarrierCommands []*config.TemplateConfig
+	onceBarrierPassed   bool
+
+	// commands limits how many template commands run at a time
+	commands *commandQueue
+
+	// commandBatch is the batch of commands queued for the command worker
+	// which did not run yet, and commandsBusy is true while the worker runs a
+	// batch. Both are protected by commandBatchLock. commandBatchCh wakes up
+	// the worker, which sends the errors of a batch on commandErrCh.
+	commandBatch     *commandBatch
+	commandsBusy     bool
+	commandBatchLock sync.Mutex
+	commandBatchCh   chan struct{}
+	commandErrCh     chan error
+
+	// execCapture runs the allowed commands of the execCapture function and
+	// caches their output across renders
+	execCapture *template.ExecCapture
+
+	// vaultCapabilities checks the capabilities of the Vault token before the
+	// Vault dependencies are watched, if enabled.
+	vaultCapabilities *dep.VaultCapabilityChecker
+
+	// plugins are the running template plugins and pluginFuncs are the
+	// template functions they provide.
+	plugins     []*plugin.Client
+	pluginFuncs map[string]template.PluginFunc
+
+	// parseCache owns the parses of the templates of this runner in the parse
+	// cache, which is shared with the other runners of the process.
+	parseCache *template.CacheOwner
+
+	// status is the HTTP status server, if enabled.
+	status *statusServer
+
+	// telemetry is the HTTP server which exports the metrics, if enabled.
+	// metrics are the metrics it exports which are only collected for it.
+	telemetry *telemetryServer
+	metrics   *runnerMetrics
+
+	// control is the control server, if enabled. events delivers the events of
+	// the runner to its clients.
+	control *controlServer
+	events  *eventBroadcaster
+
+	// paused is true while the runner skips its runs, and is protected by
+	// pauseLock. renderCh and resumeCh receive a notification when a render is
+	// forced or the runner is resumed.
+	paused    bool
+	pauseLock sync.Mutex
+	renderCh  chan struct{}
+	resumeCh  chan struct{}
+
+	// standby is true while the runner holds its commands and the reload of
+	// the child process. standbyCommands and standbyReload are what it held,
+	// and promoted is true once the runner was promoted. All of them are
+	// protected by standbyLock. promoteCh receives a notification when the
+	// runner is promoted.
+	standby         bool
+	standbyCommands []*config.TemplateConfig
+	standbyReload   bool
+	promoted        bool
+	standbyLock     sync.Mutex
+	promoteCh       chan struct{}
+
+	// lastLoop is the last time the event loop made progress, and
+	// watcherFailingSince is the time the watcher started reporting errors
+	// without returning any data. Both are protected by healthLock.
+	lastLoop            time.Time
+	watcherFailingSince time.Time
+	healthLock          sync.RWMutex
+
 	// Env represents a custom set of environment variables to populate the
 	// template and command runtime with. These environment variables will be
 	// available in both the command's environment as well as the template's
@@ -109,11 +341,50 @@ type Runner struct {
 // RenderEvent captures the time and events that occurred for a template
 // rendering.
 type RenderEvent struct {
+	// TemplateID is the ID of the template, and Destinations are the paths its
+	// template configurations are written to.
+	TemplateID   string
+	Destinations []string
+
 	// LastWouldRender marks the last time the template would have rendered.
-	LastWouldRender time.Time
+	// WouldRenderCount is the number of times a destination of the template
+	// would have rendered.
+	LastWouldRender  time.Time
+	WouldRenderCount uint64
 
 	// LastDidRender marks the last time the template was written to disk.
-	LastDidRender time.Time
+	// DidRenderCount is the number of times a destination of the template was
+	// written to disk.
+	LastDidRender  time.Time
+	DidRenderCount uint64
+
+	// Checksum is the hex-encoded SHA-256 checksum of the last complete output
+	// of the template, before any encoding.
+	Checksum string
+
+	// MissingDependencies are the dependencies without data when the template
+	// was last executed, which must be fetched before it can render.
+	MissingDependencies []string
+
+	// LastError marks the last time the template could not be executed or
+	// rendered. Error describes why.
+	LastError time.Time
+	Error     string
+
+	// LastBlocked marks the last time a min_instances guard refused to write
+	// the template to disk. BlockedReason describes why.
+	LastBlocked   time.Time
+	BlockedReason string
+
+	// LastFailed marks the last time the template panicked while executing or
+	// rendering. FailedReason describes the panic.
+	LastFailed   time.Time
+	FailedReason string
+
+	// LastCommand is the result of the last command executed after the
+	// template rendered. It is replaced, not updated, so it may be read
+	// without holding the lock once it is copied.
+	LastCommand *CommandResult
 }
 
 // NewRunner accepts a slice of TemplateConfigs and returns a pointer to the new
@@ -122,12 +393,15 @@ func NewRunner(config *config.Config, dry, once bool) (*Runner, error) {
 	log.Printf("[INFO] (runner) creating new runner (dry: %v, once: %v)", dry, once)
 
 	runner := &Runner{
-		config: config,
-		dry:    dry,
-		once:   once,
+		config:     config,
+		dry:        dry,
+		once:       once,
+		parseCache: template.NewCacheOwner(),
 	}
 
 	if err := runner.init(); err != nil {
+		runner.stopPlugins()
+		runner.parseCache.Release()
 		return nil, err
 	}
 
@@ -140,10 +414,47 @@ func NewRunner(config *config.Config, dry, once bool) (*Runner, error) {
 func (r *Runner) Start() {
 	log.Printf("[INFO] (runner) starting")
 
-	// Create the pid before doing anything.
-	if err := r.storePid(); err != nil {
-		r.ErrCh <- err
-		return
+	// Create the pid before doing anything. Observe mode runs alongside the
+	// instance which renders the same configuration, so it leaves the pid alone.
+	if !r.observe {
+		if err := r.storePid(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+
+	// Start the status server
+	var heartbeatCh <-chan time.Time
+	if r.status != nil {
+		if err := r.status.Start(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+
+		heartbeat := time.NewTicker(statusHeartbeatInterval)
+		defer heartbeat.Stop()
+		heartbeatCh = heartbeat.C
+	}
+
+	// Start the telemetry server
+	if r.telemetry != nil {
+		if err := r.telemetry.Start(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+
+	// Start the control server
+	if r.control != nil {
+		if err := r.control.Start(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+
+	// Start the ready checks of the processes started by template commands
+	for _, c := range r.readyChecks {
+		go c.run(r.DoneCh)
 	}
 
 	// Start the de-duplication manager
@@ -156,41 +467,138 @@ func (r *Runner) Start() {
 		dedupCh = r.dedup.UpdateCh()
 	}
 
+	// Start the reload coordinator
+	if r.coordinator != nil {
+		if err := r.coordinator.Start(); err != nil {
+			r.ErrCh <- err
+			return
+		}
+	}
+
+	// Watch the token files, if any, for rotation
+	if files := r.tokenFiles(); len(files) > 0 {
+		go watchTokenFiles(files, tokenFilePollInterval, r.tokenCh, r.DoneCh)
+	}
+
+	// Log in to Vault again before the token from the auth method expires
+	if config.StringVal(r.config.Vault.AuthMethod) == config.VaultAuthMethodCert && !r.once {
+		go watchVaultLogin(r.clients, r.config,
+			config.TimeDurationVal(r.config.Retry), r.DoneCh)
+	}
+
+	// Watch the remote configuration for changes
+	if config.BoolVal(r.config.RemoteConfig.Enabled) && !r.once {
+		go watchRemoteConfig(r.clients.Consul(),
+			config.StringVal(r.config.RemoteConfig.Prefix), r.remoteConfigIndex,
+			r.remoteConfigHash, r.ReloadCh, r.DoneCh)
+	}
+
+	// Watch the standby key for the promotion
+	if r.inStandby() && config.StringPresent(r.config.Standby.Key) {
+		go watchStandbyKey(r.clients.Consul(),
+			config.StringVal(r.config.Standby.Key), r.Promote, r.DoneCh)
+	}
+
 	// Setup the child process exit channel
 	var childExitCh <-chan int
 
+	// blackoutCh fires when the blackouts which held templates end, so the
+	// held templates are written. blackoutAt is when it fires.
+	var blackoutCh <-chan time.Time
+	var blackoutAt time.Time
+
+	// canaryCh fires when the bake of a change held on this host ends, so the
+	// change is written. canaryAt is when it fires.
+	var canaryCh <-chan time.Time
+	var canaryAt time.Time
+
+	// pacingCh fires when a reload of the child process held by reload pacing
+	// is checked again. pacingAt is when it fires.
+	var pacingCh <-chan time.Time
+	var pacingAt time.Time
+
+	// rotationCh fires when a new version of a secret which is held back by
+	// its rotation window is written. rotationAt is when it fires.
+	var rotationCh <-chan time.Time
+	var rotationAt time.Time
+
+	// staleCh fires when the grace period of a dependency which renders stale
+	// data ends, so the runner fails if it is still failing. staleAt is when
+	// it fires.
+	staleGrace := config.TimeDurationVal(r.config.Watcher.StaleGracePeriod)
+	var staleCh <-chan time.Time
+	var staleAt time.Time
+
+	// In once mode, failed dependencies are retried until the retry timeout,
+	// which starts with the first failure, so renders on a cold start survive
+	// Consul not being up yet.
+	var onceRetryCh <-chan time.Time
+	var onceErr error
+
+	// The child process is only started once all templates have rendered. With
+	// a prewarm timeout, give up if they do not render in time instead of
+	// waiting forever.
+	var prewarmCh <-chan time.Time
+	if timeout := config.TimeDurationVal(r.config.Exec.PrewarmTimeout); timeout > 0 &&
+		config.StringPresent(r.config.Exec.Command) {
+		log.Printf("[INFO] (runner) waiting up to %s for templates to render "+
+			"before starting the child process", timeout)
+		prewarmCh = time.After(timeout)
+	}
+
+	// The start gate holds the child process even once all templates have
+	// rendered, until its conditions are met. It is open when there is none.
+	var startGateCh chan struct{}
+	startGateOpen, startGateHeld := true, false
+	if config.StringPresent(r.config.Exec.Command) && hasStartGate(r.config.Exec) {
+		startGateCh = make(chan struct{})
+		startGateOpen = false
+		go watchStartGate(r.clients.Consul(), r.config.Exec, startGateCh, r.DoneCh)
+	}
+
 	// Fire an initial run to parse all the templates and setup the first-pass
 	// dependencies. This also forces any templates that have no dependencies to
 	// be rendered immediately (since they are already renderable).
 	log.Printf("[DEBUG] (runner) running initial templates")
-	if err := r.Run(); err != nil {
+	if !r.once {
+		go r.runCommandBatches()
+	}
+	if err := r.run(); err != nil {
 		r.ErrCh <- err
 		return
 	}
 
 	for {
+		r.markLoop()
+
 		// Enable quiescence for all templates if we have specified wait
 		// intervals.
-	NEXT_Q:
-		for _, t := range r.templates {
-			if _, ok := r.quiescenceMap[t.ID()]; ok {
-				continue NEXT_Q
-			}
+		r.enableQuiescence()
 
-			for _, c := range r.templateConfigsFor(t) {
-				if *c.Wait.Enabled {
-					log.Printf("[DEBUG] (runner) enabling template-specific quiescence for %q", t.ID())
-					r.quiescenceMap[t.ID()] = newQuiescence(
-						r.quiescenceCh, *c.Wait.Min, *c.Wait.Max, t)
-					continue NEXT_Q
-				}
-			}
+		if !r.blackoutEnd.IsZero() && !r.blackoutEnd.Equal(blackoutAt) {
+			blackoutAt = r.blackoutEnd
+			blackoutCh = time.After(blackoutAt.Sub(time.Now()))
+		}
 
-			if *r.config.Wait.Enabled {
-				log.Printf("[DEBUG] (runner) enabling global quiescence for %q", t.ID())
-				r.quiescenceMap[t.ID()] = newQuiescence(
-					r.quiescenceCh, *r.config.Wait.Min, *r.config.Wait.Max, t)
-				continue NEXT_Q
+		if r.canary != nil && !r.canary.end.IsZero() && !r.canary.end.Equal(canaryAt) {
+			canaryAt = r.canary.end
+			canaryCh = time.After(canaryAt.Sub(time.Now()))
+		}
+
+		if !r.reloadRecheck.IsZero() && !r.reloadRecheck.Equal(pacingAt) {
+			pacingAt = r.reloadRecheck
+			pacingCh = time.After(pacingAt.Sub(time.Now()))
+		}
+
+		if next := r.nextSecretRotation(); !next.IsZero() && !next.Equal(rotationAt) {
+			rotationAt = next
+			rotationCh = time.After(rotationAt.Sub(time.Now()))
+		}
+
+		if staleGrace > 0 {
+			if next := r.nextStaleDeadline(staleGrace); !next.IsZero() && !next.Equal(staleAt) {
+				staleAt = next
+				staleCh = time.After(staleAt.Sub(time.Now()))
 			}
 		}
 
@@ -202,26 +610,43 @@ func (r *Runner) Start() {
 			log.Printf("[DEBUG] (runner) watching %d dependencies", r.watcher.Size())
 		}
 
-		if r.allTemplatesRendered() {
+		rendered := r.allTemplatesRendered()
+		if rendered && !startGateOpen && !startGateHeld {
+			// The templates rendered in time, so only the start gate is left.
+			startGateHeld = true
+			prewarmCh = nil
+			log.Printf("[INFO] (runner) all templates rendered, waiting for the " +
+				"start gate before starting the child process")
+		}
+
+		if rendered && startGateOpen {
 			// If an exec command was given and a command is not currently running,
 			// spawn the child process for supervision.
-			if config.StringPresent(r.config.Exec.Command) {
+			if config.StringPresent(r.config.Exec.Command) && !r.observe {
 				// Lock the child because we are about to check if it exists.
 				r.childLock.Lock()
 
 				if r.child == nil {
 					env := r.config.Exec.Env.Copy()
 					env.Custom = append(r.childEnv(), env.Custom...)
+					listeners, err := childListenerFiles(r.config.Exec.Listeners)
+					if err != nil {
+						r.ErrCh <- err
+						r.childLock.Unlock()
+						return
+					}
 					child, err := spawnChild(&spawnChildInput{
-						Stdin:        r.inStream,
-						Stdout:       r.outStream,
-						Stderr:       r.errStream,
-						Command:      config.StringVal(r.config.Exec.Command),
-						Env:          env.Env(),
-						ReloadSignal: config.SignalVal(r.config.Exec.ReloadSignal),
-						KillSignal:   config.SignalVal(r.config.Exec.KillSignal),
-						KillTimeout:  config.TimeDurationVal(r.config.Exec.KillTimeout),
-						Splay:        config.TimeDurationVal(r.config.Exec.Splay),
+						Stdin:           r.inStream,
+						Stdout:          r.outStream,
+						Stderr:          r.errStream,
+						Command:         config.StringVal(r.config.Exec.Command),
+						Env:             env.Env(),
+						ReloadSignal:    config.SignalVal(r.config.Exec.ReloadSignal),
+						KillSignal:      config.SignalVal(r.config.Exec.KillSignal),
+						KillTimeout:     config.TimeDurationVal(r.config.Exec.KillTimeout),
+						KillTimeoutFunc: r.childKilled,
+						Splay:           config.TimeDurationVal(r.config.Exec.Splay),
+						Listeners:       listeners,
 					})
 					if err != nil {
 						r.ErrCh <- err
@@ -229,10 +654,19 @@ func (r *Runner) Start() {
 						return
 					}
 					r.child = child
+					r.childEvent(&ChildEvent{Type: ChildEventStarted, Pid: child.Pid()})
+
+					// Reload pacing relies on the samples of the monitor.
+					if config.BoolVal(r.config.Exec.Monitor.Enabled) ||
+						config.BoolVal(r.config.Exec.ReloadPacing.Enabled) {
+						r.childMonitor = newChildMonitor(child, r.config.Exec.Monitor, r.childRestartCh)
+						go r.childMonitor.run(r.DoneCh)
+					}
 				}
 
 				// Unlock the child, we are done now.
 				r.childLock.Unlock()
+				prewarmCh = nil
 
 				// It's possible that we didn't start a process, in which case no
 				// channel is returned. If we did get a new exitCh, that means a child
@@ -258,6 +692,7 @@ func (r *Runner) Start() {
 					select {
 					case c := <-childExitCh:
 						log.Printf("[INFO] (runner) child process died")
+						r.childEvent(&ChildEvent{Type: ChildEventExited, Pid: r.childPid(), ExitCode: c})
 						r.ErrCh <- NewErrChildDied(c)
 						return
 					case <-r.DoneCh:
@@ -269,10 +704,15 @@ func (r *Runner) Start() {
 			}
 		}
 
+		// forced is set when a render is forced, which runs even if the runner is
+		// paused.
+		forced := false
+
 	OUTER:
 		select {
 		case view := <-r.watcher.DataCh:
 			// Receive this update
+			r.markWatcher(false)
 			r.Receive(view.Dependency, view.Data())
 
 			// Drain all dependency data. Given a large number of dependencies, it is
@@ -301,6 +741,29 @@ func (r *Runner) Start() {
 			break OUTER
 
 		case err := <-r.watcher.ErrCh:
+			r.markWatcher(true)
+
+			// Remember which dependency failed, so templates can check it with
+			// errorFor and render a fallback.
+			var newError bool
+			var requestID string
+			var stale time.Duration
+			var isStale bool
+			if verr, ok := err.(*watch.ViewError); ok {
+				newError = r.brain.RememberError(verr.Dependency, verr.Err)
+				requestID = verr.RequestID
+				err = verr.Err
+
+				// Within the stale grace period, a dependency which returned data
+				// before only warns, and its templates render the last data.
+				if staleGrace > 0 {
+					stale, isStale = r.markStale(verr.Dependency)
+					if isStale {
+						err = fmt.Errorf("%s: %s", verr.Dependency, err)
+					}
+				}
+			}
+
 			// If this is our own internal error, see if we should hard exit.
 			if derr, ok := err.(*dep.FetchError); ok {
 				log.Printf("[DEBUG] (runner) detected custom error type")
@@ -318,31 +781,191 @@ func (r *Runner) Start() {
 			// if err.Contains(Something) {
 			//   errCh <- err
 			// }
-			log.Printf("[ERR] (runner) watcher reported error: %s", err)
+			if isStale {
+				log.Printf("[WARN] (runner) rendering stale data, failing for %s "+
+					"of the %s grace period: %s", stale/time.Millisecond*time.Millisecond, staleGrace, err)
+			} else if requestID != "" {
+				log.Printf("[ERR] (runner) watcher reported error (request %s): %s", requestID, err)
+			} else {
+				log.Printf("[ERR] (runner) watcher reported error: %s", err)
+			}
 			if r.once {
-				r.ErrCh <- err
-				return
+				timeout := config.TimeDurationVal(r.config.OnceRetryTimeout)
+				if timeout <= 0 {
+					r.ErrCh <- err
+					return
+				}
+
+				onceErr = err
+				if onceRetryCh == nil {
+					log.Printf("[WARN] (runner) once mode retrying failed dependencies "+
+						"for up to %s", timeout)
+					onceRetryCh = time.After(timeout)
+				}
+			}
+
+			// Re-run the templates the first time a dependency fails, in case
+			// any of them render a fallback for it.
+			if newError {
+				break OUTER
 			}
 
+		case <-startGateCh:
+			// The start gate opened, so the child process is started at the top
+			// of the next loop if the templates have rendered.
+			startGateOpen = true
+			startGateCh = nil
+
+		case <-prewarmCh:
+			err := r.prewarmError(config.TimeDurationVal(r.config.Exec.PrewarmTimeout))
+			log.Printf("[ERR] (runner) %s", err)
+			r.ErrCh <- err
+			return
+
+		case <-onceRetryCh:
+			log.Printf("[ERR] (runner) once mode giving up after retrying for %s",
+				config.TimeDurationVal(r.config.OnceRetryTimeout))
+			r.ErrCh <- onceErr
+			return
+
 		case tmpl := <-r.quiescenceCh:
 			// Remove the quiescence for this template from the map. This will force
 			// the upcoming Run call to actually evaluate and render the template.
-			log.Printf("[DEBUG] (runner) received template %q from quiescence", tmpl.ID())
+			log.Printf("[DEBUG] (runner) received template %s from quiescence", r.templateLabel(tmpl.ID()))
 			delete(r.quiescenceMap, tmpl.ID())
 
 		case c := <-childExitCh:
 			log.Printf("[INFO] (runner) child process died")
+			r.childEvent(&ChildEvent{Type: ChildEventExited, Pid: r.childPid(), ExitCode: c})
 			r.ErrCh <- NewErrChildDied(c)
 			return
 
+		case <-r.tokenCh:
+			// A token file changed, so swap the clients and restart the views. The
+			// following run re-creates the views with the new token.
+			if err := r.rotateTokens(); err != nil {
+				log.Printf("[ERR] (runner) failed to rotate tokens: %s", err)
+				continue
+			}
+
+		case <-r.childRestartCh:
+			// The child monitor detected an exceeded threshold. The new exit channel
+			// is picked up at the top of the next loop.
+			r.childLock.RLock()
+			err := r.child.Restart()
+			r.childLock.RUnlock()
+			if err != nil {
+				r.ErrCh <- fmt.Errorf("runner: failed to restart child: %s", err)
+				return
+			}
+			r.childEvent(&ChildEvent{Type: ChildEventRestarted, Pid: r.childPid(),
+				Reason: ChildRestartMonitor})
+			continue
+
+		case step := <-r.childEscalateCh:
+			// The child process did not become ready after a reload. The new exit
+			// channel is picked up at the top of the next loop.
+			r.childLock.RLock()
+			var err error
+			switch step {
+			case config.ExecEscalationStepRestart:
+				err = r.child.Restart()
+			case config.ExecEscalationStepKill:
+				err = r.child.ForceRestart()
+			}
+			r.childLock.RUnlock()
+			if err != nil {
+				r.ErrCh <- fmt.Errorf("runner: failed to %s child: %s", step, err)
+				return
+			}
+			r.childEvent(&ChildEvent{Type: ChildEventRestarted, Pid: r.childPid(),
+				Reason: ChildRestartEscalation})
+			continue
+
+		case <-r.promoteCh:
+			// The runner was promoted, so the following run executes the commands
+			// and the reload of the child process it held in standby.
+			log.Printf("[INFO] (runner) promoted from standby")
+
+		case <-r.renderCh:
+			// Drop the quiescence timers, so the following run renders every
+			// template immediately.
+			for id, q := range r.quiescenceMap {
+				if q.timer != nil {
+					q.timer.Stop()
+				}
+				delete(r.quiescenceMap, id)
+			}
+			forced = true
+
+		case <-r.resumeCh:
+			// The runner was resumed, so the following run renders any templates
+			// which changed while it was paused.
+
+		case next := <-r.reloadConfigCh:
+			// The configuration was reloaded, so the following run renders the
+			// added templates and stops watching the dependencies only the
+			// removed ones used.
+			r.applyReload(next)
+
+		case <-blackoutCh:
+			// The following run writes the templates held by the blackout.
+			log.Printf("[INFO] (runner) blackout ended, rendering held templates")
+			blackoutCh, blackoutAt = nil, time.Time{}
+			r.blackoutEnd = time.Time{}
+
+		case <-canaryCh:
+			// The following run writes the changes which finished baking.
+			log.Printf("[INFO] (runner) canary bake ended, rendering held templates")
+			canaryCh, canaryAt = nil, time.Time{}
+
+		case <-pacingCh:
+			// The following run reloads the child process if it is quieter now.
+			log.Printf("[DEBUG] (runner) checking the held reload of the child process")
+			pacingCh, pacingAt = nil, time.Time{}
+
+		case <-rotationCh:
+			// The following run writes the secrets held back until now.
+			log.Printf("[INFO] (runner) rendering held secrets")
+			rotationCh, rotationAt = nil, time.Time{}
+
+		case <-staleCh:
+			// Fail if a dependency is still failing at the end of its grace
+			// period, otherwise wait for the next one.
+			staleCh, staleAt = nil, time.Time{}
+			if err := r.staleError(staleGrace); err != nil {
+				log.Printf("[ERR] (runner) %s", err)
+				r.ErrCh <- err
+				return
+			}
+			continue
+
+		case <-heartbeatCh:
+			// Record that the event loop is still responsive and wait for the next
+			// event without re-rendering.
+			r.markLoop()
+			goto OUTER
+
+		case err := <-r.commandErrCh:
+			// The commands queued by a previous run failed.
+			r.ErrCh <- err
+			return
+
 		case <-r.DoneCh:
 			log.Printf("[INFO] (runner) received finish")
 			return
 		}
 
+		// While paused, only remember the new data until the runner is resumed or
+		// a render is forced.
+		if !forced && r.Paused() {
+			log.Printf("[DEBUG] (runner) paused, skipping run")
+			continue
+		}
+
 		// If we got this far, that means we got new data or one of the timers fired,
 		// so attempt to re-render.
-		if err := r.Run(); err != nil {
+		if err := r.run(); err != nil {
 			r.ErrCh <- err
 			return
 		}
@@ -360,8 +983,15 @@ func (r *Runner) Stop() {
 
 	log.Printf("[INFO] (runner) stopping")
 	r.stopDedup()
+	r.stopCoordinator()
 	r.stopWatcher()
 	r.stopChild()
+	r.stopTemplateChildren()
+	r.stopStatus()
+	r.stopTelemetry()
+	r.stopControl()
+	r.stopPlugins()
+	r.parseCache.Release()
 
 	if err := r.deletePid(); err != nil {
 		log.Printf("[WARN] (runner) could not remove pid at %q: %s",
@@ -373,12 +1003,56 @@ func (r *Runner) Stop() {
 	close(r.DoneCh)
 }
 
+// SetRenderer replaces the Renderer used to commit rendered templates. Passing
+// nil restores the default, which atomically writes each template to its
+// destination on disk. This must be called before Start.
+func (r *Runner) SetRenderer(renderer Renderer) {
+	if renderer == nil {
+		renderer = RendererFunc(Render)
+	}
+	r.renderer = renderer
+}
+
 // TemplateRenderedCh returns a channel that will return the path of the
 // template when it is rendered.
 func (r *Runner) TemplateRenderedCh() <-chan struct{} {
 	return r.renderedCh
 }
 
+// TemplateWouldRenderCh returns a channel that receives the ID of each
+// template that would have been rendered during a run, even if the contents
+// on disk did not change. If the channel buffer is full, the event is dropped.
+func (r *Runner) TemplateWouldRenderCh() <-chan string {
+	return r.wouldRenderCh
+}
+
+// TemplateDidRenderCh returns a channel that receives the ID of each template
+// that was actually written to disk during a run. If the channel buffer is
+// full, the event is dropped.
+func (r *Runner) TemplateDidRenderCh() <-chan string {
+	return r.didRenderCh
+}
+
+// ChildUsage returns the most recent resource usage sample of the child
+// process. It returns nil if the child is not being monitored or has not been
+// sampled yet.
+func (r *Runner) ChildUsage() *child.Usage {
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+
+	if r.childMonitor == nil {
+		return nil
+	}
+	return r.childMonitor.Usage()
+}
+
+// RenderEventCh returns a channel that receives a copy of the render event of
+// each template whose event changed, once at the end of each run and once for
+// each command result. If the channel buffer is full, the event is dropped.
+func (r *Runner) RenderEventCh() <-chan *RenderEvent {
+	return r.renderEventCh
+}
+
 // RenderEvents returns the render events for each template was rendered. The
 // map is keyed by template ID.
 func (r *Runner) RenderEvents() map[string]*RenderEvent {
@@ -399,6 +1073,31 @@ func (r *Runner) stopDedup() {
 	}
 }
 
+func (r *Runner) stopCoordinator() {
+	if r.coordinator != nil {
+		log.Printf("[DEBUG] (runner) stopping reload coordinator")
+		r.coordinator.Stop()
+	}
+}
+
+func (r *Runner) stopStatus() {
+	if r.status != nil {
+		r.status.Stop()
+	}
+}
+
+func (r *Runner) stopTelemetry() {
+	if r.telemetry != nil {
+		r.telemetry.Stop()
+	}
+}
+
+func (r *Runner) stopControl() {
+	if r.control != nil {
+		r.control.Stop()
+	}
+}
+
 func (r *Runner) stopWatcher() {
 	if r.watcher != nil {
 		log.Printf("[DEBUG] (runner) stopping watcher")
@@ -416,6 +1115,57 @@ func (r *Runner) stopChild() {
 	}
 }
 
+// escalateReload starts verifying the child process becomes ready after a
+// reload, replacing the escalation of any previous reload. It does nothing if
+// escalation is not enabled.
+func (r *Runner) escalateReload() {
+	if !config.BoolVal(r.config.Exec.Escalation.Enabled) {
+		return
+	}
+
+	if r.childEscalation != nil {
+		r.childEscalation.Stop()
+	}
+
+	env := r.config.Exec.Env.Copy()
+	env.Custom = append(r.childEnv(), env.Custom...)
+	r.childEscalation = newChildEscalation(r.config.Exec.Escalation, env.Env(),
+		r.childEscalateCh)
+	go r.childEscalation.run(r.DoneCh)
+}
+
+// enableQuiescence starts a quiescence timer for each template which has a
+// template-specific or global wait configured and does not have a timer yet.
+// Critical templates never wait.
+func (r *Runner) enableQuiescence() {
+NEXT_Q:
+	for _, t := range r.templates {
+		if _, ok := r.quiescenceMap[t.ID()]; ok {
+			continue NEXT_Q
+		}
+
+		if criticalTemplate(r.templateConfigsFor(t)) {
+			continue NEXT_Q
+		}
+
+		for _, c := range r.templateConfigsFor(t) {
+			if *c.Wait.Enabled {
+				templateLogf(c, "DEBUG", "enabling template-specific quiescence for %s", r.templateLabel(t.ID()))
+				r.quiescenceMap[t.ID()] = newQuiescence(
+					r.quiescenceCh, *c.Wait.Min, *c.Wait.Max, t)
+				continue NEXT_Q
+			}
+		}
+
+		if *r.config.Wait.Enabled {
+			templateLogf(r.logConfigFor(t), "DEBUG", "enabling global quiescence for %s", r.templateLabel(t.ID()))
+			r.quiescenceMap[t.ID()] = newQuiescence(
+				r.quiescenceCh, *r.config.Wait.Min, *r.config.Wait.Max, t)
+			continue NEXT_Q
+		}
+	}
+}
+
 // Receive accepts a Dependency and data for that dep. This data is
 // cached on the Runner. This data is then used to determine if a Template
 // is "renderable" (i.e. all its Dependencies have been downloaded at least
@@ -440,12 +1190,20 @@ func (r *Runner) Receive(d dep.Dependency, data interface{}) {
 	if _, ok := r.dependencies[d.String()]; ok {
 		log.Printf("[DEBUG] (runner) receiving dependency %s", d)
 		r.brain.Remember(d, data)
+		delete(r.staleSince, d.String())
+		r.publish(EventDependencyReceived, "", d.String())
 	}
 }
 
 // Signal sends a signal to the child process, if it exists. Any errors that
-// occur are returned.
+// occur are returned. In standby, the standby signal promotes the runner
+// instead of being sent to the child process.
 func (r *Runner) Signal(s os.Signal) error {
+	if r.isPromoteSignal(s) {
+		r.Promote()
+		return nil
+	}
+
 	r.childLock.RLock()
 	defer r.childLock.RUnlock()
 	if r.child == nil {
@@ -462,14 +1220,79 @@ func (r *Runner) Signal(s os.Signal) error {
 // Please note that all templates are rendered **and then** any commands are
 // executed.
 func (r *Runner) Run() error {
+	b, err := r.render()
+	if err != nil {
+		return err
+	}
+	return r.runCommands(b)
+}
+
+// run renders the templates for the event loop. In once mode, their commands
+// run right away. Otherwise they are queued for the command worker, so the
+// event loop does not wait for the commands or for its turn to reload.
+func (r *Runner) run() error {
+	if r.once {
+		return r.Run()
+	}
+
+	b, err := r.render()
+	if err != nil {
+		return err
+	}
+	r.queueCommands(b)
+	return nil
+}
+
+// render renders each template in this Runner, and returns the commands to
+// run and whether the child process is reloaded.
+func (r *Runner) render() (*commandBatch, error) {
 	log.Printf("[INFO] (runner) initiating run")
+	defer r.sendRenderEvents()
 
 	var wouldRenderAny, renderedAny bool
 	var commands []*config.TemplateConfig
-	depsMap := make(map[string]dep.Dependency)
+	changes := make(map[*config.TemplateConfig][]string)
+
+	// commandTemplates are the IDs of the templates which triggered each
+	// command, whose render events record its result.
+	commandTemplates := make(map[*config.TemplateConfig][]string)
+
+	// Every template rendered in this run sees the same time.
+	renderTime := time.Now().UTC()
+
+	// The blackouts which hold templates in this run end at blackoutEnd.
+	r.blackoutEnd = time.Time{}
+	if r.canary != nil {
+		r.canary.end = time.Time{}
+	}
+
+	// The capabilities missing for the new dependencies of every template are
+	// reported together at the end of the run.
+	var capabilityErrs *multierror.Error
+
+	// Collect the outcome of each template for the run report, if enabled.
+	var report *RunReport
+	if config.BoolVal(r.config.Report.Enabled) {
+		report = newRunReport(renderTime)
+	}
 
 	for _, tmpl := range r.templates {
-		log.Printf("[DEBUG] (runner) checking template %s", tmpl.ID())
+		logConfig := r.logConfigFor(tmpl)
+		templateLogf(logConfig, "DEBUG", "checking template %s", r.templateLabel(tmpl.ID()))
+
+		// If this template takes the output of another template as input, it
+		// cannot be rendered until the input template has been rendered.
+		var input []byte
+		if id, ok := r.inputTemplates[tmpl.ID()]; ok {
+			output, ok := r.renderedOutputs[id]
+			if !ok {
+				templateLogf(logConfig, "DEBUG", "waiting for input template %s", id)
+				report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+					ReportReasonWaitingForInput, id, nil, nil)
+				continue
+			}
+			input = output
+		}
 
 		// Check if we are currently the leader instance
 		isLeader := true
@@ -482,10 +1305,12 @@ func (r *Runner) Run() error {
 		// in once mode, and we certainly do not want to re-run any commands.
 		if r.once {
 			r.renderEventsLock.RLock()
-			_, rendered := r.renderEvents[tmpl.ID()]
+			event, ok := r.renderEvents[tmpl.ID()]
 			r.renderEventsLock.RUnlock()
-			if rendered {
-				log.Printf("[DEBUG] (runner) once mode and already rendered")
+			if ok && !event.LastWouldRender.IsZero() {
+				templateLogf(logConfig, "DEBUG", "once mode and already rendered")
+				report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+					ReportReasonAlreadyRendered, "", nil, nil)
 				continue
 			}
 		}
@@ -493,18 +1318,37 @@ func (r *Runner) Run() error {
 		// Attempt to render the template, returning any missing dependencies and
 		// the rendered contents. If there are any missing dependencies, the
 		// contents cannot be rendered or trusted!
-		result, err := tmpl.Execute(&template.ExecuteInput{
-			Brain: r.brain,
-			Env:   r.childEnv(),
+		renderStart := time.Now()
+		result, err := executeRecovered(tmpl, &template.ExecuteInput{
+			Brain:            r.brain,
+			Env:              r.childEnv(),
+			ExecCapture:      r.execCapture,
+			Input:            input,
+			Now:              renderTime,
+			PKIRenewFraction: config.Float64Val(r.config.Vault.PKIRenewFraction),
+			PreferFamily:     config.StringVal(r.config.Resolve.PreferFamily),
+			SecretRotation:   r.rotations[tmpl.ID()],
+			Seed:             r.seeds[tmpl.ID()],
+			Vars:             r.config.Vars,
 		})
+		if perr, ok := err.(*TemplatePanicError); ok {
+			if err := r.failTemplate(tmpl, nil, perr, report); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if err != nil {
-			return errors.Wrap(err, tmpl.Source())
+			r.markError(tmpl.ID(), err)
+			return nil, errors.Wrap(err, tmpl.Source())
 		}
 
 		// Grab the list of used and missing dependencies.
 		missing, used := result.Missing, result.Used
+		templateLogf(logConfig, "TRACE", "template %s used %d dependencies: %s",
+			tmpl.ID(), used.Len(), used.List())
 
-		// Add the dependency to the list of dependencies for this runner.
+		// Add the dependency to the list of dependencies for this template.
+		tmplDeps := make(map[string]dep.Dependency, used.Len())
 		for _, d := range used.List() {
 			// If we've taken over leadership for a template, we may have data
 			// that is cached, but not have the watcher. We must treat this as
@@ -512,15 +1356,18 @@ func (r *Runner) Run() error {
 			if isLeader && !r.watcher.Watching(d) {
 				missing.Add(d)
 			}
-			if _, ok := depsMap[d.String()]; !ok {
-				depsMap[d.String()] = d
-			}
+			tmplDeps[d.String()] = d
 		}
+		r.setTemplateDeps(tmpl.ID(), tmplDeps)
+		r.markExecuted(tmpl.ID(), missing.List(), result.Output)
 
 		// Diff any missing dependencies the template reported with dependencies
-		// the watcher is watching.
+		// the watcher is watching. Until they have data, they are fetched in the
+		// order of the priority of their templates.
+		priority := dependencyPriority(r.templateConfigsFor(tmpl))
 		var unwatched []dep.Dependency
 		for _, d := range missing.List() {
+			r.watcher.Prioritize(d, priority)
 			if !r.watcher.Watching(d) {
 				unwatched = append(unwatched, d)
 			}
@@ -529,7 +1376,24 @@ func (r *Runner) Run() error {
 		// If there are unwatched dependencies, start the watcher and move onto the
 		// next one.
 		if len(unwatched) > 0 {
-			log.Printf("[DEBUG] (runner) was not watching %d dependencies", len(unwatched))
+			templateLogf(logConfig, "DEBUG", "was not watching %d dependencies", len(unwatched))
+
+			// Check the Vault token may fetch the new dependencies, rather than
+			// retrying requests which are denied.
+			if r.vaultCapabilities != nil {
+				if err := r.vaultCapabilities.Check(r.clients, unwatched); err != nil {
+					if _, ok := err.(*dep.VaultCapabilityError); ok {
+						capabilityErrs = multierror.Append(capabilityErrs,
+							fmt.Errorf("%s: %s", r.templateLabel(tmpl.ID()), err))
+						r.markError(tmpl.ID(), err)
+						report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+							ReportReasonFailed, err.Error(), used, missing)
+						continue
+					}
+					log.Printf("[WARN] (runner) failed to check vault capabilities: %s", err)
+				}
+			}
+
 			for _, d := range unwatched {
 				// If we are deduplicating, we must still handle non-sharable
 				// dependencies, since those will be ignored.
@@ -537,13 +1401,17 @@ func (r *Runner) Run() error {
 					r.watcher.Add(d)
 				}
 			}
+			report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+				ReportReasonNewDependencies, "", used, missing)
 			continue
 		}
 
 		// If the template is missing data for some dependencies then we are not
 		// ready to render and need to move on to the next one.
 		if l := missing.Len(); l > 0 {
-			log.Printf("[DEBUG] (runner) missing data for %d dependencies", l)
+			templateLogf(logConfig, "DEBUG", "missing data for %d dependencies", l)
+			report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+				ReportReasonMissingDependencies, "", used, missing)
 			continue
 		}
 
@@ -558,25 +1426,138 @@ func (r *Runner) Run() error {
 		// We do not want to render the templates yet.
 		if q, ok := r.quiescenceMap[tmpl.ID()]; ok {
 			q.tick()
+			report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+				ReportReasonQuiescence, "", used, nil)
 			continue
 		}
 
+		// Keep the output so templates that use it as input see the new contents.
+		r.renderedOutputs[tmpl.ID()] = result.Output
+
+		// Calculate which dependencies changed since this template was last
+		// rendered. This is exposed to commands via the environment.
+		changed, revisions := r.changedDeps(tmpl, used.List())
+
 		// For each template configuration that is tied to this template, attempt to
 		// render it to disk and accumulate commands for later use.
+		var wouldRender, didRender bool
 		for _, templateConfig := range r.templateConfigsFor(tmpl) {
-			log.Printf("[DEBUG] (runner) rendering %s", templateConfig.Display())
-
-			// Render the template, taking dry mode into account
-			result, err := Render(&RenderInput{
-				Backup:    config.BoolVal(templateConfig.Backup),
-				Contents:  result.Output,
-				Dry:       r.dry,
-				DryStream: r.outStream,
-				Path:      config.StringVal(templateConfig.Destination),
-				Perms:     config.FileModeVal(templateConfig.Perms),
-			})
+			templateLogf(templateConfig, "DEBUG", "rendering %s", templateConfig.Display())
+
+			// Hold the template during a blackout. The run after the blackout
+			// ends writes the latest contents.
+			if until, ok := blackoutUntil(r.blackouts[templateConfig], renderTime.Local()); ok {
+				reason := fmt.Sprintf("blackout until %s", until.Format(time.RFC3339))
+				templateLogf(templateConfig, "INFO", "not rendering %s: %s",
+					templateConfig.Display(), reason)
+				r.markBlocked(tmpl.ID(), reason)
+				report.addTemplate(tmpl, templateConfig, false, ReportReasonBlackout,
+					reason, used, nil)
+				if r.blackoutEnd.IsZero() || until.Before(r.blackoutEnd) {
+					r.blackoutEnd = until
+				}
+				continue
+			}
+
+			// Keep the last good file if the data is missing too many instances
+			// of a guarded service.
+			if reason := r.minInstancesViolation(templateConfig, used.List()); reason != "" {
+				templateLogf(templateConfig, "WARN", "not rendering %s: %s",
+					templateConfig.Display(), reason)
+				r.markBlocked(tmpl.ID(), reason)
+				report.addTemplate(tmpl, templateConfig, false, ReportReasonBlocked,
+					reason, used, nil)
+				continue
+			}
+
+			// Apply the output encoding, if any
+			contents, err := encodeContents(config.StringVal(templateConfig.Encoding), result.Output)
+			if err != nil {
+				r.markError(tmpl.ID(), err)
+				return nil, errors.Wrap(err, "error encoding "+templateConfig.Display())
+			}
+
+			// Package the contents into their bundle instead of writing them. The
+			// archive is written once every template of this run is done.
+			if b, ok := r.templateBundles[templateConfig]; ok {
+				b.set(templateConfig, &bundleEntry{
+					tmpl:      tmpl,
+					contents:  contents,
+					used:      used.List(),
+					changed:   changed,
+					revisions: revisions,
+				})
+				continue
+			}
+
+			// Hold changed contents until they have baked on the canaries. The
+			// run after the bake ends writes them.
+			if r.canary != nil {
+				dest := config.StringVal(templateConfig.Destination)
+				if reason, ok := r.canary.hold(dest, contents, renderTime); ok {
+					templateLogf(templateConfig, "INFO", "not rendering %s: %s",
+						templateConfig.Display(), reason)
+					r.markBlocked(tmpl.ID(), reason)
+					report.addTemplate(tmpl, templateConfig, false, ReportReasonCanary,
+						reason, used, nil)
+					continue
+				}
+			}
+
+			// Render the managed header, which is not part of the comparison with
+			// the destination.
+			var header []byte
+			if t, ok := r.headers[templateConfig]; ok {
+				if header, err = renderHeader(t, templateConfig, renderTime); err != nil {
+					r.markError(tmpl.ID(), err)
+					return nil, errors.Wrap(err, "error rendering header of "+templateConfig.Display())
+				}
+			}
+
+			// Render the template, taking dry and observe modes into account
+			input := &RenderInput{
+				ACL:            config.StringVal(templateConfig.ACL),
+				Backup:         config.BoolVal(templateConfig.Backup),
+				Compare:        r.compares[templateConfig],
+				Contents:       contents,
+				CreateDestDirs: config.BoolVal(templateConfig.CreateDestDirs),
+				DestDirGroup:   config.StringVal(templateConfig.DestDirGroup),
+				DestDirPerms:   config.FileModeVal(templateConfig.DestDirPerms),
+				DestDirUser:    config.StringVal(templateConfig.DestDirUser),
+				Dry:            r.dry,
+				DryStream:      r.outStream,
+				Header:         header,
+				Path:           config.StringVal(templateConfig.Destination),
+				Perms:          config.FileModeVal(templateConfig.Perms),
+				PreservePerms:  config.BoolVal(templateConfig.PreservePerms),
+				RequireDestDir: !config.BoolVal(templateConfig.CreateDestDirs),
+				Split:          config.BoolVal(templateConfig.SplitDestination),
+				Strategy:       config.StringVal(templateConfig.RenderStrategy),
+				Versions:       config.IntVal(templateConfig.RenderVersions),
+			}
+			var result *RenderResult
+			if r.observe {
+				result, err = r.observeRender(templateConfig, input, changed)
+			} else {
+				result, err = renderRecovered(r.renderer, input)
+			}
+			if perr, ok := err.(*TemplatePanicError); ok {
+				if err := r.failTemplate(tmpl, templateConfig, perr, report); err != nil {
+					return nil, err
+				}
+				continue
+			}
 			if err != nil {
-				return errors.Wrap(err, "error rendering "+templateConfig.Display())
+				r.markError(tmpl.ID(), err)
+				return nil, errors.Wrap(err, "error rendering "+templateConfig.Display())
+			}
+
+			if result.DidRender {
+				report.addTemplate(tmpl, templateConfig, true, ReportReasonRendered,
+					"", used, nil)
+			} else {
+				report.addTemplate(tmpl, templateConfig, false, ReportReasonUnchanged,
+					"", used, nil)
 			}
 
 			// If we would have rendered this template (but we did not because the
@@ -585,26 +1566,54 @@ func (r *Runner) Run() error {
 			// will not fire commands unless the template was _actually_ rendered to
 			// disk though.
 			if result.WouldRender {
-				// Make a note that we have rendered this template (required for once
+				if r.canary != nil && !r.dry && !r.observe {
+					r.canary.commit(config.StringVal(templateConfig.Destination), contents)
+				}
+
+				// Make a note that we have rendered this template (required for once
 				// mode and just generally nice for debugging purposes).
 				r.markRenderTime(tmpl.ID(), false)
 
 				// Record that at least one template would have been rendered.
-				wouldRenderAny = true
+				wouldRender, wouldRenderAny = true, true
+			}
+
+			// A supervised process is started once its template renders, even if
+			// the destination was already up to date.
+			if result.WouldRender && !r.dry && !r.observe && config.BoolVal(templateConfig.Exec.Supervise) &&
+				!r.templateChildRunning(tmpl.ID()) && findCommand(templateConfig, commands) == nil {
+				commands = append(commands, templateConfig)
+				changes[templateConfig] = appendUnique(nil, changed...)
+				commandTemplates[templateConfig] = appendUnique(nil, tmpl.ID())
 			}
 
 			// If we _actually_ rendered the template to disk, we want to run the
 			// appropriate commands.
 			if result.DidRender {
-				log.Printf("[INFO] (runner) rendered %s", templateConfig.Display())
+				templateLogf(templateConfig, "INFO", "rendered %s", templateConfig.Display())
 
 				// Record that at least one template was rendered.
-				renderedAny = true
+				didRender, renderedAny = true, true
 
 				// Store the render time
 				r.markRenderTime(tmpl.ID(), true)
 
 				if !r.dry {
+					// systemd only sees the changes to a unit after a daemon-reload,
+					// which runs once, before the commands which restart the services.
+					if config.BoolVal(r.config.Systemd.Enabled) &&
+						systemdUnitPath(r.config.Systemd, config.StringVal(templateConfig.Destination)) {
+						reload := daemonReloadTemplate(r.config.Systemd, templateConfig)
+						if existing := findCommand(reload, commands); existing != nil {
+							reload = existing
+						} else {
+							templateLogf(templateConfig, "DEBUG", "prepending command %q for systemd unit %s",
+								config.StringVal(reload.Exec.Command), templateConfig.Display())
+							commands = append([]*config.TemplateConfig{reload}, commands...)
+						}
+						commandTemplates[reload] = appendUnique(commandTemplates[reload], tmpl.ID())
+					}
+
 					// If the template was rendered (changed) and we are not in dry-run mode,
 					// aggregate commands, ignoring previously known commands
 					//
@@ -618,17 +1627,109 @@ func (r *Runner) Run() error {
 					if c := config.StringVal(templateConfig.Exec.Command); c != "" {
 						existing := findCommand(templateConfig, commands)
 						if existing != nil {
-							log.Printf("[DEBUG] (runner) skipping command %q from %s (already appended from %s)",
+							templateLogf(templateConfig, "DEBUG", "skipping command %q from %s (already appended from %s)",
 								c, templateConfig.Display(), existing.Display())
+							changes[existing] = appendUnique(changes[existing], changed...)
+							commandTemplates[existing] = appendUnique(commandTemplates[existing], tmpl.ID())
 						} else {
-							log.Printf("[DEBUG] (runner) appending command %q from %s",
+							templateLogf(templateConfig, "DEBUG", "appending command %q from %s",
 								c, templateConfig.Display())
 							commands = append(commands, templateConfig)
+							changes[templateConfig] = appendUnique(nil, changed...)
+							commandTemplates[templateConfig] = appendUnique(nil, tmpl.ID())
 						}
 					}
 				}
 			}
 		}
+
+		r.metrics.observeRender(tmpl.ID(), time.Since(renderStart), didRender)
+
+		// Notify any listeners of the per-template render state.
+		if wouldRender {
+			sendTemplateID(r.wouldRenderCh, tmpl.ID())
+			r.publish(EventTemplateWouldRender, tmpl.ID(), "")
+		}
+		if didRender {
+			sendTemplateID(r.didRenderCh, tmpl.ID())
+			r.publish(EventTemplateDidRender, tmpl.ID(), "")
+
+			// Only record the revisions once the contents are actually written,
+			// so the next render reports every change since the last write.
+			r.renderedRevisions[tmpl.ID()] = revisions
+			r.brain.MarkRendered(tmpl.ID(), used.List())
+		} else if wouldRender && r.observe {
+			// Nothing is ever written in observe mode, so each observation
+			// reports the changes since the previous one.
+			r.renderedRevisions[tmpl.ID()] = revisions
+		}
+	}
+
+	if err := capabilityErrs.ErrorOrNil(); err != nil {
+		return nil, errors.Wrap(err, "runner: vault capability check failed")
+	}
+
+	// Write the archives of the bundles whose entries changed in this run, and
+	// treat their entries as rendered if the archive is.
+	for _, b := range r.bundles {
+		if !b.changed {
+			continue
+		}
+		result, err := r.renderBundle(b)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tc := range b.configs {
+			e, ok := b.entries[tc]
+			if !ok {
+				continue
+			}
+			if result == nil {
+				report.addTemplate(e.tmpl, tc, false, ReportReasonWaitingForBundle,
+					b.name(), nil, nil)
+				continue
+			}
+
+			id := e.tmpl.ID()
+			if result.DidRender {
+				report.addTemplate(e.tmpl, tc, true, ReportReasonRendered, "", nil, nil)
+			} else {
+				report.addTemplate(e.tmpl, tc, false, ReportReasonUnchanged, "", nil, nil)
+			}
+
+			if result.WouldRender {
+				r.markRenderTime(id, false)
+				wouldRenderAny = true
+				sendTemplateID(r.wouldRenderCh, id)
+				r.publish(EventTemplateWouldRender, id, "")
+				if r.observe {
+					r.renderedRevisions[id] = e.revisions
+				}
+			}
+
+			if result.DidRender {
+				templateLogf(tc, "INFO", "rendered %s", tc.Display())
+				renderedAny = true
+				r.markRenderTime(id, true)
+				sendTemplateID(r.didRenderCh, id)
+				r.publish(EventTemplateDidRender, id, "")
+				r.renderedRevisions[id] = e.revisions
+				r.brain.MarkRendered(id, e.used)
+
+				if c := config.StringVal(tc.Exec.Command); c != "" && !r.dry {
+					if existing := findCommand(tc, commands); existing != nil {
+						changes[existing] = appendUnique(changes[existing], e.changed...)
+						commandTemplates[existing] = appendUnique(commandTemplates[existing], id)
+					} else {
+						templateLogf(tc, "DEBUG", "appending command %q from %s", c, tc.Display())
+						commands = append(commands, tc)
+						changes[tc] = appendUnique(nil, e.changed...)
+						commandTemplates[tc] = appendUnique(nil, id)
+					}
+				}
+			}
+		}
 	}
 
 	// Check if we need to deliver any rendered signals
@@ -641,54 +1742,37 @@ func (r *Runner) Run() error {
 	}
 
 	// Perform the diff and update the known dependencies.
-	r.diffAndUpdateDeps(depsMap)
+	r.diffAndUpdateDeps()
 
-	// Execute each command in sequence, collecting any errors that occur - this
-	// ensures all commands execute at least once.
-	var errs []error
-	for _, t := range commands {
-		command := config.StringVal(t.Exec.Command)
-		log.Printf("[INFO] (runner) executing command %q from %s", command, t.Display())
-		env := t.Exec.Env.Copy()
-		env.Custom = append(r.childEnv(), env.Custom...)
-		if _, err := spawnChild(&spawnChildInput{
-			Stdin:        r.inStream,
-			Stdout:       r.outStream,
-			Stderr:       r.errStream,
-			Command:      command,
-			Env:          env.Env(),
-			Timeout:      config.TimeDurationVal(t.Exec.Timeout),
-			ReloadSignal: config.SignalVal(t.Exec.ReloadSignal),
-			KillSignal:   config.SignalVal(t.Exec.KillSignal),
-			KillTimeout:  config.TimeDurationVal(t.Exec.KillTimeout),
-			Splay:        config.TimeDurationVal(t.Exec.Splay),
-		}); err != nil {
-			s := fmt.Sprintf("failed to execute command %q from %s", command, t.Display())
-			errs = append(errs, errors.Wrap(err, s))
-		}
-	}
-
-	// If we got this far and have a child process, we need to send the reload
-	// signal to the child process.
-	if renderedAny && r.child != nil {
-		r.childLock.RLock()
-		if err := r.child.Reload(); err != nil {
-			errs = append(errs, err)
-		}
-		r.childLock.RUnlock()
-	}
-
-	// If any errors were returned, convert them to an ErrorList for human
-	// readability.
-	if len(errs) != 0 {
-		var result *multierror.Error
-		for _, err := range errs {
-			result = multierror.Append(result, err)
-		}
-		return result.ErrorOrNil()
+	// In standby, hold the commands and the reload of the child process until
+	// the runner is promoted.
+	commands, reload := r.holdForStandby(commands, renderedAny && r.child != nil)
+
+	// With reload pacing, hold the reload of the child process while it is
+	// busy.
+	reload = r.holdForPacing(reload, time.Now()) && r.child != nil
+
+	// In once mode with a barrier, hold the commands until all templates have
+	// rendered and the other instances reached the barrier.
+	commands, err := r.holdForOnceBarrier(commands)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	b := &commandBatch{reload: reload}
+	for _, t := range commands {
+		b.commands = append(b.commands, &queuedCommand{
+			tmpl:       t,
+			tmplIDs:    commandTemplates[t],
+			changed:    changes[t],
+			readyCheck: r.readyChecks[t],
+			report:     report,
+		})
+	}
+	if report != nil {
+		b.reports = append(b.reports, report)
+	}
+	return b, nil
 }
 
 // init() creates the Runner's underlying data structures and returns an error
@@ -705,10 +1789,29 @@ func (r *Runner) init() error {
 	log.Printf("[DEBUG] (runner) final config: %s", result)
 
 	// Create the clientset
-	clients, err := newClientSet(r.config)
+	r.consulToken, r.vaultToken, err = configTokens(r.config)
 	if err != nil {
 		return fmt.Errorf("runner: %s", err)
 	}
+	clients := dep.NewClientSet()
+	if err := createConsulClient(clients, r.config, r.consulToken); err != nil {
+		return err
+	}
+	if err := createVaultClient(clients, r.config, r.vaultToken); err != nil {
+		return err
+	}
+	if err := createNomadClient(clients, r.config); err != nil {
+		return err
+	}
+	clients.SetResolver(newResolver(r.config))
+	r.clients = clients
+
+	// Add the templates stored in Consul, if configured
+	if config.BoolVal(r.config.RemoteConfig.Enabled) {
+		if err := r.loadRemoteConfig(); err != nil {
+			return err
+		}
+	}
 
 	// Create the watcher
 	watcher, err := newWatcher(r.config, clients, r.once)
@@ -717,20 +1820,240 @@ func (r *Runner) init() error {
 	}
 	r.watcher = watcher
 
+	// Start the template plugins, whose functions the templates may call
+	if err := r.startPlugins(); err != nil {
+		return err
+	}
+
+	// Parse and validate the templates
+	if err := r.initTemplates(); err != nil {
+		return err
+	}
+	r.applyLogLevels()
 	numTemplates := len(*r.config.Templates)
-	templates := make([]*template.Template, 0, numTemplates)
+
+	r.renderEvents = make(map[string]*RenderEvent, numTemplates)
+	r.renderEventCh = make(chan *RenderEvent, renderChBufferSize(numTemplates))
+	r.changedEvents = make(map[string]struct{}, numTemplates)
+	r.dependencies = make(map[string]dep.Dependency)
+	r.templateDeps = make(map[string]map[string]dep.Dependency, numTemplates)
+	r.staleSince = make(map[string]time.Time)
+	r.renderedRevisions = make(map[string]map[string]uint64, numTemplates)
+	r.observe = config.BoolVal(r.config.Observe)
+	r.observed = make(map[*config.TemplateConfig]*observation)
+
+	r.renderedCh = make(chan struct{}, 1)
+	r.wouldRenderCh = make(chan string, renderChBufferSize(numTemplates))
+	r.didRenderCh = make(chan string, renderChBufferSize(numTemplates))
+
+	r.inStream = os.Stdin
+	r.outStream = os.Stdout
+	r.errStream = os.Stderr
+	r.renderer = RendererFunc(Render)
+	r.brain = template.NewBrain()
+
+	r.ErrCh = make(chan error)
+	r.DoneCh = make(chan struct{})
+	r.ReloadCh = make(chan struct{}, 1)
+	r.commandBatchCh = make(chan struct{}, 1)
+	r.commandErrCh = make(chan error)
+	r.reloadConfigCh = make(chan *Runner)
+
+	r.quiescenceMap = make(map[string]*quiescence)
+	r.quiescenceCh = make(chan *template.Template)
+
+	r.childRestartCh = make(chan struct{}, 1)
+	r.childEscalateCh = make(chan string)
+	r.childEventCh = make(chan *ChildEvent, childEventBufferSize)
+	r.tokenCh = make(chan struct{}, 1)
+
+	r.standby = config.BoolVal(r.config.Standby.Enabled)
+	r.promoteCh = make(chan struct{}, 1)
+
+	r.events = newEventBroadcaster()
+	r.renderCh = make(chan struct{}, 1)
+	r.resumeCh = make(chan struct{}, 1)
+
+	// Validate the child monitor action
+	if config.BoolVal(r.config.Exec.Monitor.Enabled) {
+		switch action := config.StringVal(r.config.Exec.Monitor.Action); action {
+		case config.ExecMonitorActionLog, config.ExecMonitorActionSignal,
+			config.ExecMonitorActionRestart:
+		default:
+			return fmt.Errorf("runner: invalid exec monitor action %q", action)
+		}
+	}
+
+	// Validate the reload escalation
+	if esc := r.config.Exec.Escalation; config.BoolVal(esc.Enabled) {
+		if !config.StringPresent(esc.Check) {
+			return fmt.Errorf("runner: exec escalation requires a check")
+		}
+		for _, step := range esc.Steps {
+			switch step {
+			case config.ExecEscalationStepRestart, config.ExecEscalationStepKill:
+			default:
+				return fmt.Errorf("runner: invalid exec escalation step %q", step)
+			}
+		}
+	}
+
+	if config.BoolVal(r.config.Vault.CheckCapabilities) {
+		r.vaultCapabilities = dep.NewVaultCapabilityChecker()
+	}
+
+	// Validate the PKI renew fraction
+	if f := r.config.Vault.PKIRenewFraction; f != nil && (*f <= 0 || *f >= 1) {
+		return fmt.Errorf("runner: vault.pki_renew_fraction must be between 0 and 1, got %v", *f)
+	}
+
+	// Validate the preferred address family
+	switch family := config.StringVal(r.config.Resolve.PreferFamily); family {
+	case "", config.ResolveFamilyV4, config.ResolveFamilyV6:
+	default:
+		return fmt.Errorf("runner: resolve.prefer_family must be %q or %q, got %q",
+			config.ResolveFamilyV4, config.ResolveFamilyV6, family)
+	}
+
+	r.templateChildren = make(map[string]*child.Child)
+
+	// Setup the status server if needed
+	r.lastLoop = time.Now()
+	if config.BoolVal(r.config.Status.Enabled) {
+		r.status = newStatusServer(r.config.Status, r)
+	}
+
+	// Setup the telemetry server if needed
+	r.metrics = newRunnerMetrics()
+	if config.IntVal(r.config.Telemetry.PrometheusPort) > 0 {
+		r.telemetry = newTelemetryServer(r.config.Telemetry, r)
+	}
+
+	// Setup the control server if needed
+	if config.BoolVal(r.config.Control.Enabled) {
+		if r.once {
+			log.Printf("[INFO] (runner) disabling the control server in once mode")
+		} else {
+			r.control, err = newControlServer(r.config.Control, r)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Setup the dedup manager if needed. This is
+	if config.BoolVal(r.config.Dedup.Enabled) {
+		if r.once {
+			log.Printf("[INFO] (runner) disabling de-duplication in once mode")
+		} else if r.observe {
+			log.Printf("[INFO] (runner) disabling de-duplication in observe mode")
+		} else {
+			r.dedup, err = NewDedupManager(r.config.Dedup, clients, r.brain, r.templates)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Setup the reload coordinator if needed
+	if config.BoolVal(r.config.Coordinate.Enabled) {
+		if r.once {
+			log.Printf("[INFO] (runner) disabling coordinated reloads in once mode")
+		} else if r.observe {
+			log.Printf("[INFO] (runner) disabling coordinated reloads in observe mode")
+		} else {
+			r.coordinator, err = NewReloadCoordinator(r.config.Coordinate, clients)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Setup the canary render mode if needed
+	if config.BoolVal(r.config.Canary.Enabled) {
+		if r.once {
+			log.Printf("[INFO] (runner) disabling the canary render mode in once mode")
+		} else if r.observe {
+			log.Printf("[INFO] (runner) disabling the canary render mode in observe mode")
+		} else {
+			r.canary, err = newCanary(r.config.Canary, clients)
+			if err != nil {
+				return fmt.Errorf("runner: %s", err)
+			}
+		}
+	}
+
+	// Setup the once barrier if needed
+	if config.BoolVal(r.config.OnceBarrier.Enabled) {
+		if !r.once {
+			log.Printf("[INFO] (runner) ignoring the once barrier outside of once mode")
+		} else {
+			r.onceBarrier, err = newOnceBarrier(r.config.OnceBarrier, clients)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	r.commands = newCommandQueue(config.IntVal(r.config.MaxConcurrentCommands))
+
+	r.execCapture = template.NewExecCapture(&template.NewExecCaptureInput{
+		Allowed:  r.config.ExecCapture.Allowed,
+		CacheTTL: config.TimeDurationVal(r.config.ExecCapture.CacheTTL),
+		Timeout:  config.TimeDurationVal(r.config.ExecCapture.Timeout),
+	})
+
+	return nil
+}
+
+// initTemplates parses the templates of the configuration and prepares
+// everything derived from their configurations, failing on any invalid
+// one. It only sets the template fields of the runner, so it also prepares
+// the templates of a reloaded configuration.
+func (r *Runner) initTemplates() error {
+	// Only activate the templates matching the filter, if configured
+	if filter := config.StringVal(r.config.TemplateFilter); filter != "" {
+		templates, err := filterTemplates(*r.config.Templates, filter)
+		if err != nil {
+			return fmt.Errorf("runner: %s", err)
+		}
+		log.Printf("[INFO] (runner) template filter %q matches %d of %d templates",
+			filter, len(templates), len(*r.config.Templates))
+		r.config.Templates = &templates
+	}
+
+	templates := make([]*template.Template, 0, len(*r.config.Templates))
 	ctemplatesMap := make(map[string]config.TemplateConfigs)
 
+	// Validate the per-template log levels, which are only applied once the
+	// templates are in use, so a rejected reload does not change them.
+	logLevels := templateLogLevels(*r.config.Templates)
+	if err := logging.ValidateTagLevels(logLevels); err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+
 	// Iterate over each TemplateConfig, creating a new Template resource for each
 	// entry. Templates are parsed and saved, and a map of templates to their
 	// config templates is kept so templates can lookup their commands and output
 	// destinations.
 	for _, ctmpl := range *r.config.Templates {
+		// Fail early on an unknown output encoding
+		if _, err := encodeContents(config.StringVal(ctmpl.Encoding), nil); err != nil {
+			return fmt.Errorf("runner: %s: %s", ctmpl.Display(), err)
+		}
+
 		tmpl, err := template.NewTemplate(&template.NewTemplateInput{
 			Source:     config.StringVal(ctmpl.Source),
 			Contents:   config.StringVal(ctmpl.Contents),
 			LeftDelim:  config.StringVal(ctmpl.LeftDelim),
 			RightDelim: config.StringVal(ctmpl.RightDelim),
+			Strict:     config.BoolVal(ctmpl.Strict),
+
+			MaxOutputSize:      config.IntVal(ctmpl.MaxOutputSize),
+			MaxRangeIterations: config.IntVal(ctmpl.MaxRangeIterations),
+			SandboxPath:        config.StringVal(ctmpl.SandboxPath),
+			PluginFuncs:        r.pluginFuncs,
+			CacheOwner:         r.parseCache,
 		})
 		if err != nil {
 			return err
@@ -746,35 +2069,201 @@ func (r *Runner) init() error {
 		ctemplatesMap[tmpl.ID()] = append(ctemplatesMap[tmpl.ID()], ctmpl)
 	}
 
+	// Resolve the input templates and order the templates so that critical
+	// templates are rendered first and each one is rendered after its input
+	// template.
+	inputTemplates, err := resolveInputTemplates(ctemplatesMap)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+	templates = prioritizeCriticalTemplates(templates, ctemplatesMap)
+	templates, err = sortTemplatesByInput(templates, inputTemplates)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+	r.inputTemplates = inputTemplates
+	r.renderedOutputs = make(map[string][]byte)
+
+	// Load the seeds of the random template functions
+	seeds, err := templateSeeds(ctemplatesMap, r.dry)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+	r.seeds = seeds
+
+	// Parse the secret rotation windows
+	rotations, err := templateSecretRotations(ctemplatesMap)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+	r.rotations = rotations
+
 	// Convert the map of templates (which was only used to ensure uniqueness)
 	// back into an array of templates.
 	r.templates = templates
+	r.ctemplatesMap = ctemplatesMap
+	r.logLevels = logLevels
 
-	r.renderEvents = make(map[string]*RenderEvent, numTemplates)
-	r.dependencies = make(map[string]dep.Dependency)
+	// Validate the ACLs, which are only supported on Windows
+	for _, tc := range *r.config.Templates {
+		if acl := config.StringVal(tc.ACL); acl != "" {
+			if err := validateACL(acl); err != nil {
+				return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+			}
+		}
+	}
 
-	r.renderedCh = make(chan struct{}, 1)
+	// Validate the owners of the destination directories, which are not
+	// supported on Windows
+	for _, tc := range *r.config.Templates {
+		if _, _, err := lookupOwner(config.StringVal(tc.DestDirUser), config.StringVal(tc.DestDirGroup)); err != nil {
+			return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+		}
+	}
 
-	r.ctemplatesMap = ctemplatesMap
-	r.inStream = os.Stdin
-	r.outStream = os.Stdout
-	r.errStream = os.Stderr
-	r.brain = template.NewBrain()
+	// Validate the split destinations, whose output is parsed as JSON and so
+	// cannot be encoded
+	for _, tc := range *r.config.Templates {
+		if !config.BoolVal(tc.SplitDestination) {
+			continue
+		}
+		if err := validateSplit(); err != nil {
+			return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+		}
+		if config.StringVal(tc.Encoding) != "" {
+			return fmt.Errorf("runner: %s: split_destination cannot be used with encoding", tc.Display())
+		}
+	}
 
-	r.ErrCh = make(chan error)
-	r.DoneCh = make(chan struct{})
+	// Validate the render strategies. Split destinations already switch their
+	// files with a symlink.
+	for _, tc := range *r.config.Templates {
+		switch strategy := config.StringVal(tc.RenderStrategy); strategy {
+		case config.TemplateRenderStrategyAtomic:
+		case config.TemplateRenderStrategySymlink:
+			if err := validateSymlink(); err != nil {
+				return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+			}
+			if config.BoolVal(tc.SplitDestination) {
+				return fmt.Errorf("runner: %s: render_strategy %q cannot be used with "+
+					"split_destination", tc.Display(), strategy)
+			}
+		default:
+			return fmt.Errorf("runner: %s: invalid render_strategy %q", tc.Display(), strategy)
+		}
+	}
 
-	r.quiescenceMap = make(map[string]*quiescence)
-	r.quiescenceCh = make(chan *template.Template)
+	// Validate the supervised processes, which are keyed by the template ID, so
+	// templates with the same contents cannot each supervise one.
+	for id, tcs := range r.ctemplatesMap {
+		var supervised int
+		for _, tc := range tcs {
+			if !config.BoolVal(tc.Exec.Supervise) {
+				continue
+			}
+			if !config.StringPresent(tc.Exec.Command) {
+				return fmt.Errorf("runner: %s: supervise requires a command", tc.Display())
+			}
+			if supervised++; supervised > 1 {
+				return fmt.Errorf("runner: %s: only one template with the same contents "+
+					"may supervise a process (template %s)", tc.Display(), id)
+			}
+		}
+	}
 
-	// Setup the dedup manager if needed. This is
-	if config.BoolVal(r.config.Dedup.Enabled) {
-		if r.once {
-			log.Printf("[INFO] (runner) disabling de-duplication in once mode")
-		} else {
-			r.dedup, err = NewDedupManager(r.config.Dedup, clients, r.brain, r.templates)
-			if err != nil {
-				return err
+	// Validate the sandboxes. Templates with the same contents are executed
+	// once, so they must share their sandbox.
+	for _, tcs := range r.ctemplatesMap {
+		for _, tc := range tcs {
+			sandbox := config.StringVal(tc.SandboxPath)
+			if sandbox != "" && !filepath.IsAbs(sandbox) {
+				return fmt.Errorf("runner: %s: sandbox_path %q must be absolute",
+					tc.Display(), sandbox)
+			}
+			if sandbox != config.StringVal(tcs[0].SandboxPath) {
+				return fmt.Errorf("runner: %s: templates with the same contents must "+
+					"use the same sandbox_path (%s)", tc.Display(), tcs[0].Display())
+			}
+		}
+	}
+
+	// Setup the bundles
+	r.bundles, r.templateBundles, err = newBundles(r.config.Bundles, *r.config.Templates)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+
+	// Validate the priorities of the dependencies
+	for _, tc := range *r.config.Templates {
+		switch priority := config.StringVal(tc.Priority); priority {
+		case config.TemplatePriorityHigh, config.TemplatePriorityNormal, config.TemplatePriorityLow:
+		default:
+			return fmt.Errorf("runner: %s: invalid priority %q", tc.Display(), priority)
+		}
+	}
+
+	// Parse the blackout windows
+	global, err := newBlackoutWindows(r.config.Blackout)
+	if err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+	r.blackouts = make(map[*config.TemplateConfig][]*blackoutWindow)
+	for _, tc := range *r.config.Templates {
+		windows, err := newBlackoutWindows(tc.Blackout)
+		if err != nil {
+			return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+		}
+		if windows = append(append([]*blackoutWindow{}, global...), windows...); len(windows) > 0 {
+			r.blackouts[tc] = windows
+		}
+	}
+
+	// Create the ready checks of the processes started by template commands
+	r.readyChecks = make(map[*config.TemplateConfig]*readyCheck)
+	for _, tc := range *r.config.Templates {
+		c, err := newReadyCheck(tc, r.commandReady)
+		if err != nil {
+			return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+		}
+		if c != nil {
+			r.readyChecks[tc] = c
+		}
+	}
+
+	// Create the comparisons of the rendered contents with the destinations
+	r.compares = make(map[*config.TemplateConfig]CompareFunc)
+	for _, tc := range *r.config.Templates {
+		compare, err := newCompareFunc(tc.Compare)
+		if err != nil {
+			return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+		}
+		if compare != nil {
+			r.compares[tc] = compare
+		}
+	}
+
+	// Parse the headers added to the rendered output
+	r.headers = make(map[*config.TemplateConfig]*texttemplate.Template)
+	for _, tc := range *r.config.Templates {
+		header, err := newHeader(tc)
+		if err != nil {
+			return fmt.Errorf("runner: %s: %s", tc.Display(), err)
+		}
+		if header != nil {
+			r.headers[tc] = header
+		}
+	}
+
+	// Validate the min_instances guards
+	for _, tc := range *r.config.Templates {
+		for _, guard := range *tc.MinInstances {
+			if !config.StringPresent(guard.Service) {
+				return fmt.Errorf("runner: %s: min_instances requires a service",
+					tc.Display())
+			}
+			if config.IntVal(guard.Count) < 1 {
+				return fmt.Errorf("runner: %s: min_instances count must be at least 1",
+					tc.Display())
 			}
 		}
 	}
@@ -782,15 +2271,34 @@ func (r *Runner) init() error {
 	return nil
 }
 
+// setTemplateDeps records the dependencies the given template used the last
+// time it was executed.
+func (r *Runner) setTemplateDeps(tmplID string, deps map[string]dep.Dependency) {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	r.templateDeps[tmplID] = deps
+}
+
 // diffAndUpdateDeps iterates through the current map of dependencies on this
-// runner and stops the watcher for any deps that are no longer required.
+// runner and stops the watcher for any deps that are no longer referenced by
+// any template.
 //
-// At the end of this function, the given depsMap is converted to a slice and
-// stored on the runner.
-func (r *Runner) diffAndUpdateDeps(depsMap map[string]dep.Dependency) {
+// At the end of this function, the dependencies of all templates are merged
+// and stored on the runner.
+func (r *Runner) diffAndUpdateDeps() {
 	r.dependenciesLock.Lock()
 	defer r.dependenciesLock.Unlock()
 
+	depsMap := make(map[string]dep.Dependency)
+	for _, deps := range r.templateDeps {
+		for key, d := range deps {
+			if _, ok := depsMap[key]; !ok {
+				depsMap[key] = d
+			}
+		}
+	}
+
 	// Diff and up the list of dependencies, stopping any unneeded watchers.
 	log.Printf("[DEBUG] (runner) diffing and updating dependencies")
 
@@ -799,6 +2307,7 @@ func (r *Runner) diffAndUpdateDeps(depsMap map[string]dep.Dependency) {
 			log.Printf("[DEBUG] (runner) %s is no longer needed", d)
 			r.watcher.Remove(d)
 			r.brain.Forget(d)
+			delete(r.staleSince, key)
 		} else {
 			log.Printf("[DEBUG] (runner) %s is still needed", d)
 		}
@@ -807,11 +2316,141 @@ func (r *Runner) diffAndUpdateDeps(depsMap map[string]dep.Dependency) {
 	r.dependencies = depsMap
 }
 
+// DependencyReferences returns a mapping of each dependency this runner is
+// watching to the templates which reference it, identified by the display
+// names of their configurations. A dependency is watched until the last
+// template referencing it no longer uses it.
+func (r *Runner) DependencyReferences() map[string][]string {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	refs := make(map[string][]string, len(r.dependencies))
+	for key := range r.dependencies {
+		refs[key] = []string{}
+	}
+
+	for id, deps := range r.templateDeps {
+		for key := range deps {
+			if _, ok := refs[key]; !ok {
+				continue
+			}
+			for _, ctmpl := range r.ctemplatesMap[id] {
+				refs[key] = append(refs[key], ctmpl.Display())
+			}
+		}
+	}
+
+	for _, names := range refs {
+		sort.Strings(names)
+	}
+
+	return refs
+}
+
 // TemplateConfigFor returns the TemplateConfig for the given Template
 func (r *Runner) templateConfigsFor(tmpl *template.Template) []*config.TemplateConfig {
 	return r.ctemplatesMap[tmpl.ID()]
 }
 
+// templateName returns the names of the template configurations of the given
+// template ID, separated by commas, or the empty string if none has a name.
+// Outside of the event loop, the caller must hold renderEventsLock.
+func (r *Runner) templateName(tmplID string) string {
+	var names []string
+	for _, c := range r.ctemplatesMap[tmplID] {
+		if name := config.StringVal(c.Name); name != "" {
+			names = appendUnique(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// templateLabel returns the given template ID for log lines, prefixed with
+// the name of the template, if any.
+func (r *Runner) templateLabel(tmplID string) string {
+	if name := r.templateName(tmplID); name != "" {
+		return fmt.Sprintf("%s (%s)", name, tmplID)
+	}
+	return tmplID
+}
+
+// logConfigFor returns the template configuration whose log settings apply to
+// the log lines about the template as a whole, which is the first one.
+func (r *Runner) logConfigFor(tmpl *template.Template) *config.TemplateConfig {
+	if configs := r.templateConfigsFor(tmpl); len(configs) > 0 {
+		return configs[0]
+	}
+	return nil
+}
+
+// templateLogTag returns the tag of the log lines about the given template
+// configuration. If only a log level is given, the destination is used as the
+// tag, so the level can still be applied.
+func templateLogTag(c *config.TemplateConfig) string {
+	if c == nil {
+		return ""
+	}
+	if tag := config.StringVal(c.LogTag); tag != "" {
+		return tag
+	}
+	if config.StringPresent(c.LogLevel) {
+		if dest := config.StringVal(c.Destination); dest != "" {
+			return dest
+		}
+		return "template"
+	}
+	return ""
+}
+
+// templateLogf logs a runner line about the given template configuration at
+// the given level. The line is tagged with the template's log tag, so the
+// template's log level applies to it instead of the global one.
+func templateLogf(c *config.TemplateConfig, level, format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if tag := templateLogTag(c); tag != "" {
+		msg = "[" + tag + "] " + msg
+	}
+	log.Printf("[%s] (runner) %s", level, msg)
+}
+
+// applyLogLevels applies the log levels of the template log tags, which were
+// validated by initTemplates, to the logging.
+func (r *Runner) applyLogLevels() {
+	if err := logging.SetTagLevels(r.logLevels); err != nil {
+		log.Printf("[ERR] (runner) %s", err)
+	}
+}
+
+// templateLogLevels returns the log level of each template log tag. If several
+// templates share a tag, the most verbose level is used.
+func templateLogLevels(configs config.TemplateConfigs) map[string]string {
+	levels := make(map[string]string)
+	for _, c := range configs {
+		level := strings.ToUpper(config.StringVal(c.LogLevel))
+		if level == "" {
+			continue
+		}
+
+		tag := templateLogTag(c)
+		if existing, ok := levels[tag]; ok && logLevelIndex(existing) <= logLevelIndex(level) {
+			continue
+		}
+		levels[tag] = level
+	}
+	return levels
+}
+
+// logLevelIndex returns the position of the given level in the log levels, from
+// the most verbose. Unknown levels sort last.
+func logLevelIndex(level string) int {
+	for i, l := range logging.Levels {
+		if string(l) == level {
+			return i
+		}
+	}
+	return len(logging.Levels)
+}
+
 // TemplateConfigMapping returns a mapping between the template ID and the set
 // of TemplateConfig represented by the template ID
 func (r *Runner) TemplateConfigMapping() map[string][]config.TemplateConfig {
@@ -835,7 +2474,8 @@ func (r *Runner) allTemplatesRendered() bool {
 	defer r.renderEventsLock.RUnlock()
 
 	for _, tmpl := range r.templates {
-		if _, rendered := r.renderEvents[tmpl.ID()]; !rendered {
+		event, ok := r.renderEvents[tmpl.ID()]
+		if !ok || event.LastWouldRender.IsZero() {
 			return false
 		}
 	}
@@ -843,6 +2483,47 @@ func (r *Runner) allTemplatesRendered() bool {
 	return true
 }
 
+// childRunning returns true if the child process is running.
+func (r *Runner) childRunning() bool {
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+
+	return r.child != nil && r.child.Pid() != 0
+}
+
+// markLoop records that the event loop made progress.
+func (r *Runner) markLoop() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+
+	r.lastLoop = time.Now()
+}
+
+// markWatcher records whether the watcher last reported an error or data. The
+// time of the first error since the watcher last returned data is kept.
+func (r *Runner) markWatcher(failed bool) {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+
+	if !failed {
+		r.watcherFailingSince = time.Time{}
+		return
+	}
+
+	if r.watcherFailingSince.IsZero() {
+		r.watcherFailingSince = time.Now()
+	}
+}
+
+// health returns the last time the event loop made progress and the time the
+// watcher started failing, which is zero if the watcher is healthy.
+func (r *Runner) health() (time.Time, time.Time) {
+	r.healthLock.RLock()
+	defer r.healthLock.RUnlock()
+
+	return r.lastLoop, r.watcherFailingSince
+}
+
 // markRenderTime stores the render time for the given template. If didRender is
 // true, it stores the time for the template having been rendered, otherwise it
 // stores it as would have been rendered.
@@ -853,18 +2534,206 @@ func (r *Runner) markRenderTime(tmplID string, didRender bool) {
 	// Get the current time
 	now := time.Now()
 
-	// Create the event for the template ID if it is the first time
+	event := r.renderEventLocked(tmplID)
+	if didRender {
+		event.LastDidRender = now
+		event.DidRenderCount++
+	} else {
+		event.LastWouldRender = now
+		event.WouldRenderCount++
+	}
+}
+
+// markBlocked stores the time and reason a min_instances guard refused to
+// render the given template.
+func (r *Runner) markBlocked(tmplID, reason string) {
+	r.renderEventsLock.Lock()
+	defer r.renderEventsLock.Unlock()
+
+	event := r.renderEventLocked(tmplID)
+	event.LastBlocked = time.Now()
+	event.BlockedReason = reason
+}
+
+// markFailed records that the template with the given ID failed for the
+// given reason.
+func (r *Runner) markFailed(tmplID, reason string) {
+	r.renderEventsLock.Lock()
+	defer r.renderEventsLock.Unlock()
+
+	event := r.renderEventLocked(tmplID)
+	event.LastFailed = time.Now()
+	event.FailedReason = reason
+}
+
+// markError records that the template with the given ID could not be
+// executed or rendered because of the given error.
+func (r *Runner) markError(tmplID string, err error) {
+	r.renderEventsLock.Lock()
+	defer r.renderEventsLock.Unlock()
+
+	event := r.renderEventLocked(tmplID)
+	event.LastError = time.Now()
+	event.Error = err.Error()
+}
+
+// markExecuted records the dependencies the template with the given ID was
+// missing when it was last executed, and the checksum of its output if it was
+// complete.
+func (r *Runner) markExecuted(tmplID string, missing []dep.Dependency, output []byte) {
+	r.renderEventsLock.Lock()
+	defer r.renderEventsLock.Unlock()
+
+	event := r.renderEventLocked(tmplID)
+	event.MissingDependencies = nil
+	for _, d := range missing {
+		event.MissingDependencies = append(event.MissingDependencies, d.String())
+	}
+	if len(missing) == 0 {
+		sum := sha256.Sum256(output)
+		event.Checksum = hex.EncodeToString(sum[:])
+	}
+}
+
+// renderEventLocked returns the render event of the template with the given
+// ID, creating it if it is the first time, and marks it as changed. The caller
+// must hold renderEventsLock for writing.
+func (r *Runner) renderEventLocked(tmplID string) *RenderEvent {
 	event, ok := r.renderEvents[tmplID]
 	if !ok {
-		event = &RenderEvent{}
+		event = &RenderEvent{TemplateID: tmplID}
 		r.renderEvents[tmplID] = event
 	}
 
-	if didRender {
-		event.LastDidRender = now
-	} else {
-		event.LastWouldRender = now
+	event.Destinations = nil
+	for _, tc := range r.ctemplatesMap[tmplID] {
+		dest := config.StringVal(tc.Destination)
+		if b, ok := r.templateBundles[tc]; ok {
+			dest = config.StringVal(b.config.Destination)
+		}
+		if dest != "" {
+			event.Destinations = appendUnique(event.Destinations, dest)
+		}
+	}
+
+	r.changedEvents[tmplID] = struct{}{}
+	return event
+}
+
+// sendRenderEvents delivers a copy of the render event of each template whose
+// event changed since the last copies were sent onto the render event
+// channel, without blocking. If the channel is full, the events are dropped.
+func (r *Runner) sendRenderEvents() {
+	r.renderEventsLock.Lock()
+	ids := make([]string, 0, len(r.changedEvents))
+	for id := range r.changedEvents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	events := make([]*RenderEvent, 0, len(ids))
+	for _, id := range ids {
+		if event, ok := r.renderEvents[id]; ok {
+			events = append(events, event.copy())
+		}
+		delete(r.changedEvents, id)
+	}
+	r.renderEventsLock.Unlock()
+
+	for _, event := range events {
+		select {
+		case r.renderEventCh <- event:
+		default:
+			log.Printf("[TRACE] (runner) dropping render event for %q", event.TemplateID)
+		}
+	}
+}
+
+// copy returns a copy of this render event which is not changed by later
+// renders.
+func (e *RenderEvent) copy() *RenderEvent {
+	c := *e
+	c.Destinations = append([]string(nil), e.Destinations...)
+	c.MissingDependencies = append([]string(nil), e.MissingDependencies...)
+	return &c
+}
+
+// minInstancesViolation returns a description of the first min_instances guard
+// on the template configuration which the given dependencies do not satisfy,
+// or the empty string if all guards are satisfied. A guard on a service the
+// template does not query never blocks.
+func (r *Runner) minInstancesViolation(tc *config.TemplateConfig, deps []dep.Dependency) string {
+	if tc.MinInstances == nil {
+		return ""
+	}
+
+	for _, guard := range *tc.MinInstances {
+		service := config.StringVal(guard.Service)
+		count := config.IntVal(guard.Count)
+
+		var found bool
+		for _, d := range deps {
+			q, ok := d.(*dep.HealthServiceQuery)
+			if !ok || q.Name() != service {
+				continue
+			}
+			found = true
+
+			data, ok := r.brain.Recall(d)
+			if !ok {
+				continue
+			}
+			services, _ := data.([]*dep.HealthService)
+			if len(services) < count {
+				return fmt.Sprintf("%s has %d healthy instances, need at least %d",
+					d, len(services), count)
+			}
+		}
+
+		if !found {
+			log.Printf("[WARN] (runner) min_instances service %q is not used by %s",
+				service, tc.Display())
+		}
+	}
+
+	return ""
+}
+
+// changedDeps returns the display names of the given dependencies that have
+// received new data since the template was last rendered, along with the
+// current revisions of those dependencies. Every dependency is considered
+// changed on the first render of a template.
+func (r *Runner) changedDeps(tmpl *template.Template, deps []dep.Dependency) ([]string, map[string]uint64) {
+	last := r.renderedRevisions[tmpl.ID()]
+
+	changed := make([]string, 0, len(deps))
+	revisions := make(map[string]uint64, len(deps))
+	for _, d := range deps {
+		rev := r.brain.Revision(d)
+		revisions[d.String()] = rev
+
+		if prev, ok := last[d.String()]; !ok || prev != rev {
+			changed = append(changed, d.String())
+		}
+	}
+
+	return changed, revisions
+}
+
+// changedDepsEnv returns the environment variable that lists the names of the
+// changed dependencies for a command, encoded as a JSON array.
+func changedDepsEnv(changed []string) string {
+	if changed == nil {
+		changed = []string{}
+	}
+
+	b, err := json.Marshal(changed)
+	if err != nil {
+		// This should never happen when marshaling a slice of strings.
+		log.Printf("[ERR] (runner) failed to encode changed dependencies: %s", err)
+		b = []byte("[]")
 	}
+	return "CT_CHANGED_DEPS=" + string(b)
 }
 
 // childEnv creates a map of environment variables for child processes to have
@@ -982,6 +2851,11 @@ type spawnChildInput struct {
 	KillSignal   os.Signal
 	KillTimeout  time.Duration
 	Splay        time.Duration
+	Listeners    []*os.File
+
+	// KillTimeoutFunc is called when the child process is force-killed after
+	// the kill timeout. See child.NewInput.
+	KillTimeoutFunc func(pid int)
 }
 
 // spawnChild spawns a child process with the given inputs and returns the
@@ -996,17 +2870,19 @@ func spawnChild(i *spawnChildInput) (*child.Child, error) {
 	}
 
 	child, err := child.New(&child.NewInput{
-		Stdin:        i.Stdin,
-		Stdout:       i.Stdout,
-		Stderr:       i.Stderr,
-		Command:      args[0],
-		Args:         args[1:],
-		Env:          i.Env,
-		Timeout:      i.Timeout,
-		ReloadSignal: i.ReloadSignal,
-		KillSignal:   i.KillSignal,
-		KillTimeout:  i.KillTimeout,
-		Splay:        i.Splay,
+		Stdin:           i.Stdin,
+		Stdout:          i.Stdout,
+		Stderr:          i.Stderr,
+		Command:         args[0],
+		Args:            args[1:],
+		Env:             i.Env,
+		Timeout:         i.Timeout,
+		ReloadSignal:    i.ReloadSignal,
+		KillSignal:      i.KillSignal,
+		KillTimeout:     i.KillTimeout,
+		KillTimeoutFunc: i.KillTimeoutFunc,
+		Splay:           i.Splay,
+		Listeners:       i.Listeners,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating child")
@@ -1070,11 +2946,197 @@ func (q *quiescence) tick() {
 	}
 }
 
+// renderChBufferSize returns the buffer size for the per-template render
+// channels. The buffer holds a few full runs worth of events so that slow
+// consumers do not miss notifications.
+func renderChBufferSize(numTemplates int) int {
+	if numTemplates < 1 {
+		numTemplates = 1
+	}
+	return 4 * numTemplates
+}
+
+// sendTemplateID delivers the template ID onto the given channel without
+// blocking. If the channel is full, the ID is dropped.
+func sendTemplateID(ch chan string, id string) {
+	select {
+	case ch <- id:
+	default:
+		log.Printf("[TRACE] (runner) dropping render notification for %q", id)
+	}
+}
+
+// appendUnique appends the given values to the list, skipping any values that
+// are already present while preserving the relative order.
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// resolveInputTemplates maps the ID of each template that declares an input
+// template to the ID of that input template. Template IDs in the config are
+// user-defined, so they are translated to the internal template IDs.
+func resolveInputTemplates(ctemplatesMap map[string]config.TemplateConfigs) (map[string]string, error) {
+	ids := make(map[string]string)
+	for tmplID, ctmpls := range ctemplatesMap {
+		for _, ctmpl := range ctmpls {
+			id := config.StringVal(ctmpl.ID)
+			if id == "" {
+				continue
+			}
+			if existing, ok := ids[id]; ok && existing != tmplID {
+				return nil, fmt.Errorf("duplicate template id %q", id)
+			}
+			ids[id] = tmplID
+		}
+	}
+
+	inputs := make(map[string]string)
+	for tmplID, ctmpls := range ctemplatesMap {
+		for _, ctmpl := range ctmpls {
+			id := config.StringVal(ctmpl.InputTemplate)
+			if id == "" {
+				continue
+			}
+
+			inputID, ok := ids[id]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown input template %q", ctmpl.Display(), id)
+			}
+			if inputID == tmplID {
+				return nil, fmt.Errorf("%s: template cannot be its own input", ctmpl.Display())
+			}
+			if existing, ok := inputs[tmplID]; ok && existing != inputID {
+				return nil, fmt.Errorf("%s: templates with the same contents must "+
+					"use the same input template", ctmpl.Display())
+			}
+			inputs[tmplID] = inputID
+		}
+	}
+
+	return inputs, nil
+}
+
+// sortTemplatesByInput orders the templates so that each template comes after
+// its input template, keeping the given order otherwise. An error is returned
+// if the input templates form a cycle.
+func sortTemplatesByInput(templates []*template.Template, inputs map[string]string) ([]*template.Template, error) {
+	byID := make(map[string]*template.Template, len(templates))
+	for _, t := range templates {
+		byID[t.ID()] = t
+	}
+
+	sorted := make([]*template.Template, 0, len(templates))
+	visited := make(map[string]bool, len(templates))
+	visiting := make(map[string]bool)
+
+	var visit func(t *template.Template) error
+	visit = func(t *template.Template) error {
+		if visited[t.ID()] {
+			return nil
+		}
+		if visiting[t.ID()] {
+			return fmt.Errorf("input templates form a cycle at %s", t.Source())
+		}
+
+		visiting[t.ID()] = true
+		if id, ok := inputs[t.ID()]; ok {
+			if err := visit(byID[id]); err != nil {
+				return err
+			}
+		}
+		visiting[t.ID()] = false
+
+		visited[t.ID()] = true
+		sorted = append(sorted, t)
+		return nil
+	}
+
+	for _, t := range templates {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// criticalTemplate returns whether any of the given template configs is
+// critical.
+func criticalTemplate(configs []*config.TemplateConfig) bool {
+	for _, c := range configs {
+		if config.BoolVal(c.Critical) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyPriority returns the watcher priority of the dependencies of a
+// template, which is the highest priority of its configurations.
+func dependencyPriority(configs []*config.TemplateConfig) int {
+	if len(configs) == 0 {
+		return watch.PriorityNormal
+	}
+
+	priority := watch.PriorityLow
+	for _, c := range configs {
+		var p int
+		switch config.StringVal(c.Priority) {
+		case config.TemplatePriorityHigh:
+			p = watch.PriorityHigh
+		case config.TemplatePriorityLow:
+			p = watch.PriorityLow
+		default:
+			p = watch.PriorityNormal
+		}
+		if p > priority {
+			priority = p
+		}
+	}
+	return priority
+}
+
+// prioritizeCriticalTemplates moves the critical templates before the other
+// templates, keeping the order within each group. The input templates of a
+// critical template are moved up with it when the templates are sorted by
+// input.
+func prioritizeCriticalTemplates(templates []*template.Template, ctemplatesMap map[string]config.TemplateConfigs) []*template.Template {
+	sorted := make([]*template.Template, 0, len(templates))
+	var rest []*template.Template
+	for _, t := range templates {
+		if criticalTemplate(ctemplatesMap[t.ID()]) {
+			sorted = append(sorted, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	return append(sorted, rest...)
+}
+
 // findCommand searches the list of template configs for the given command and
-// returns it if it exists.
+// returns it if it exists. Supervised processes belong to their template, so
+// they are only found for the same template config.
 func findCommand(c *config.TemplateConfig, templates []*config.TemplateConfig) *config.TemplateConfig {
 	needle := config.StringVal(c.Exec.Command)
 	for _, t := range templates {
+		if config.BoolVal(c.Exec.Supervise) || config.BoolVal(t.Exec.Supervise) {
+			if c == t {
+				return t
+			}
+			continue
+		}
 		if needle == config.StringVal(t.Exec.Command) {
 			return t
 		}
@@ -1082,13 +3144,19 @@ func findCommand(c *config.TemplateConfig, templates []*config.TemplateConfig) *
 	return nil
 }
 
-// newClientSet creates a new client set from the given config.
-func newClientSet(c *config.Config) (*dep.ClientSet, error) {
-	clients := dep.NewClientSet()
+// newResolver creates the resolver for the addresses returned by services and
+// nodes from the config.
+func newResolver(c *config.Config) *dep.Resolver {
+	return dep.NewResolver(config.TimeDurationVal(c.Resolve.TTL),
+		config.TimeDurationVal(c.Resolve.Timeout))
+}
 
-	if err := clients.CreateConsulClient(&dep.CreateConsulClientInput{
+// createConsulClient creates the Consul client in the given client set from
+// the config, using the given token.
+func createConsulClient(clients *dep.ClientSet, c *config.Config, token string) error {
+	i := &dep.CreateConsulClientInput{
 		Address:      config.StringVal(c.Consul),
-		Token:        config.StringVal(c.Token),
+		Token:        token,
 		AuthEnabled:  config.BoolVal(c.Auth.Enabled),
 		AuthUsername: config.StringVal(c.Auth.Username),
 		AuthPassword: config.StringVal(c.Auth.Password),
@@ -1099,14 +3167,65 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 		SSLCACert:    config.StringVal(c.SSL.CaCert),
 		SSLCAPath:    config.StringVal(c.SSL.CaPath),
 		ServerName:   config.StringVal(c.SSL.ServerName),
-	}); err != nil {
-		return nil, fmt.Errorf("runner: %s", err)
+
+		AgentCacheEnabled:           config.BoolVal(c.AgentCache.UseCache),
+		AgentCacheBackgroundRefresh: config.BoolVal(c.AgentCache.BackgroundRefresh),
+		AgentCacheMaxAge:            config.TimeDurationVal(c.AgentCache.MaxAge),
+
+		StreamingEnabled: config.BoolVal(c.Streaming.Enabled),
+		StreamingAddress: streamingAddress(c),
+	}
+
+	// The cache server of the host queries Consul on behalf of this instance,
+	// with its own connection settings.
+	if config.BoolVal(c.LocalCache.Enabled) {
+		i.Address = "unix://" + config.StringVal(c.LocalCache.Path)
+		i.AuthEnabled = false
+		i.SSLEnabled = false
+	}
+
+	if err := clients.CreateConsulClient(i); err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+
+	return nil
+}
+
+// streamingAddress returns the address of the Consul server to stream service
+// health from. It defaults to the host of the Consul address with the RPC port
+// of a server.
+func streamingAddress(c *config.Config) string {
+	if address := config.StringVal(c.Streaming.Address); address != "" {
+		return address
+	}
+
+	host := config.StringVal(c.Consul)
+	if i := strings.Index(host, "://"); i != -1 {
+		host = host[i+3:]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return net.JoinHostPort(host, config.DefaultStreamingServerPort)
+}
+
+// createVaultClient creates the Vault client in the given client set from the
+// config, using the given token.
+func createVaultClient(clients *dep.ClientSet, c *config.Config, token string) error {
+	unwrap := config.BoolVal(c.Vault.UnwrapToken)
+
+	// The token comes from logging in, so any configured token is ignored.
+	if config.StringVal(c.Vault.AuthMethod) == config.VaultAuthMethodCert {
+		token, unwrap = "", false
 	}
 
 	if err := clients.CreateVaultClient(&dep.CreateVaultClientInput{
 		Address:     config.StringVal(c.Vault.Address),
-		Token:       config.StringVal(c.Vault.Token),
-		UnwrapToken: config.BoolVal(c.Vault.UnwrapToken),
+		Token:       token,
+		UnwrapToken: unwrap,
+		AuthMethod:  config.StringVal(c.Vault.AuthMethod),
+		AuthMount:   config.StringVal(c.Vault.AuthMount),
+		AuthRole:    config.StringVal(c.Vault.AuthRole),
 		SSLEnabled:  config.BoolVal(c.Vault.SSL.Enabled),
 		SSLVerify:   config.BoolVal(c.Vault.SSL.Verify),
 		SSLCert:     config.StringVal(c.Vault.SSL.Cert),
@@ -1114,11 +3233,41 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 		SSLCACert:   config.StringVal(c.Vault.SSL.CaCert),
 		SSLCAPath:   config.StringVal(c.Vault.SSL.CaPath),
 		ServerName:  config.StringVal(c.Vault.SSL.ServerName),
+
+		Addresses:            c.Vault.Addresses,
+		HealthCheckInterval:  config.TimeDurationVal(c.Vault.HealthCheckInterval),
+		PerformanceStandbyOK: config.BoolVal(c.Vault.PerformanceStandbyOK),
+	}); err != nil {
+		return fmt.Errorf("runner: %s", err)
+	}
+
+	return nil
+}
+
+// createNomadClient creates the Nomad client in the given client set from the
+// config, if Nomad is enabled.
+func createNomadClient(clients *dep.ClientSet, c *config.Config) error {
+	if !config.BoolVal(c.Nomad.Enabled) {
+		return nil
+	}
+
+	if err := clients.CreateNomadClient(&dep.CreateNomadClientInput{
+		Address:    config.StringVal(c.Nomad.Address),
+		Token:      config.StringVal(c.Nomad.Token),
+		Namespace:  config.StringVal(c.Nomad.Namespace),
+		Region:     config.StringVal(c.Nomad.Region),
+		SSLEnabled: config.BoolVal(c.Nomad.SSL.Enabled),
+		SSLVerify:  config.BoolVal(c.Nomad.SSL.Verify),
+		SSLCert:    config.StringVal(c.Nomad.SSL.Cert),
+		SSLKey:     config.StringVal(c.Nomad.SSL.Key),
+		SSLCACert:  config.StringVal(c.Nomad.SSL.CaCert),
+		SSLCAPath:  config.StringVal(c.Nomad.SSL.CaPath),
+		ServerName: config.StringVal(c.Nomad.SSL.ServerName),
 	}); err != nil {
-		return nil, fmt.Errorf("runner: %s", err)
+		return fmt.Errorf("runner: %s", err)
 	}
 
-	return clients, nil
+	return nil
 }
 
 // newWatcher creates a new watcher.
@@ -1126,13 +3275,19 @@ func newWatcher(c *config.Config, clients *dep.ClientSet, once bool) (*watch.Wat
 	log.Printf("[INFO] (runner) creating Watcher")
 
 	watcher, err := watch.NewWatcher(&watch.WatcherConfig{
-		Clients:  clients,
-		Once:     once,
-		MaxStale: config.TimeDurationVal(c.MaxStale),
+		Clients:              clients,
+		Once:                 once,
+		MaxStale:             config.TimeDurationVal(c.MaxStale),
+		BufferSize:           config.IntVal(c.Watcher.BufferSize),
+		Backpressure:         config.StringVal(c.Watcher.Backpressure),
+		MaxConcurrentFetches: config.IntVal(c.Watcher.MaxConcurrentFetches),
 		RetryFunc: func(current time.Duration) time.Duration {
 			return config.TimeDurationVal(c.Retry)
 		},
-		RenewVault: config.StringPresent(c.Vault.Token) && config.BoolVal(c.Vault.RenewToken),
+		RenewVault: config.StringPresent(c.Vault.Token) &&
+			!config.StringPresent(c.VaultAgentTokenFile) &&
+			config.StringVal(c.Vault.AuthMethod) != config.VaultAuthMethodCert &&
+			config.BoolVal(c.Vault.RenewToken),
 	})
 	if err != nil {
 		return nil, err
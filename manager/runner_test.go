@@ -4,14 +4,21 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul-template/config"
 	dep "github.com/hashicorp/consul-template/dependency"
 	"github.com/hashicorp/consul-template/template"
+	"github.com/hashicorp/consul-template/watch"
 )
 
 func TestRunner_Receive(t *testing.T) {
@@ -105,6 +112,30 @@ func TestRunner_Run(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"custom_renderer",
+			func(t *testing.T, r *Runner) {
+				r.SetRenderer(RendererFunc(func(i *RenderInput) (*RenderResult, error) {
+					fmt.Fprintf(i.DryStream, "captured %s: %s", i.Path, i.Contents)
+					return &RenderResult{DidRender: true, WouldRender: true}, nil
+				}))
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String(`hello`),
+						Destination: config.String("/foo/bar"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				exp := "captured /foo/bar: hello"
+				if out != exp {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+			},
+			false,
+		},
 		{
 			"accumulates_deps",
 			nil,
@@ -297,6 +328,265 @@ func TestRunner_Run(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"systemd_daemon_reload",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+			},
+			&config.Config{
+				Systemd: &config.SystemdConfig{
+					DaemonReloadCommand: config.String("echo reload"),
+					Enabled:             config.Bool(true),
+					UnitDirs:            []string{"/tmp/ct-systemd_daemon_reload"},
+				},
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("hello"),
+						Command:     config.String("echo other"),
+						Destination: config.String("/tmp/ct-systemd_daemon_reload_other"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String("[Service]"),
+						Command:     config.String("echo 123"),
+						Destination: config.String("/tmp/ct-systemd_daemon_reload/a.service.d/override.conf"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String("[Service]"),
+						Command:     config.String("echo 456"),
+						Destination: config.String("/tmp/ct-systemd_daemon_reload/b.service"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				// The daemon-reload runs once, before the commands of the units.
+				exp := "reload\nother\n123\n456\n"
+				if out != exp {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+				os.RemoveAll("/tmp/ct-systemd_daemon_reload")
+				os.Remove("/tmp/ct-systemd_daemon_reload_other")
+			},
+			false,
+		},
+		{
+			"would_and_did_render_channels",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+				if err := ioutil.WriteFile("/tmp/ct-render_channels_a", []byte("hello"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("hello"),
+						Destination: config.String("/tmp/ct-render_channels_a"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String("world"),
+						Destination: config.String("/tmp/ct-render_channels_b"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				defer os.Remove("/tmp/ct-render_channels_a")
+				defer os.Remove("/tmp/ct-render_channels_b")
+
+				var would []string
+				for len(r.TemplateWouldRenderCh()) > 0 {
+					would = append(would, <-r.TemplateWouldRenderCh())
+				}
+				if l := len(would); l != 2 {
+					t.Errorf("\nexp: %#v\nact: %#v", 2, l)
+				}
+
+				var did []string
+				for len(r.TemplateDidRenderCh()) > 0 {
+					did = append(did, <-r.TemplateDidRenderCh())
+				}
+				exp := []string{r.templates[1].ID()}
+				if !reflect.DeepEqual(exp, did) {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, did)
+				}
+			},
+			false,
+		},
+		{
+			"render_event_channel",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+				if err := ioutil.WriteFile("/tmp/ct-render_event_channel", []byte("hello"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("hello"),
+						Destination: config.String("/tmp/ct-render_event_channel"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String(`{{ key "foo" }}`),
+						Destination: config.String("/tmp/ct-render_event_channel_missing"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				defer os.Remove("/tmp/ct-render_event_channel")
+
+				events := make(map[string]*RenderEvent)
+				for len(r.RenderEventCh()) > 0 {
+					e := <-r.RenderEventCh()
+					events[e.TemplateID] = e
+				}
+				if l := len(events); l != 2 {
+					t.Fatalf("expected 2 events, got %d", l)
+				}
+
+				rendered := events[r.templates[0].ID()]
+				if rendered.WouldRenderCount != 1 || rendered.DidRenderCount != 0 {
+					t.Errorf("expected 1 would and 0 did renders, got %d and %d",
+						rendered.WouldRenderCount, rendered.DidRenderCount)
+				}
+				// The SHA-256 checksum of "hello".
+				sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+				if rendered.Checksum != sum {
+					t.Errorf("\nexp: %#v\nact: %#v", sum, rendered.Checksum)
+				}
+				exp := []string{"/tmp/ct-render_event_channel"}
+				if !reflect.DeepEqual(exp, rendered.Destinations) {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, rendered.Destinations)
+				}
+
+				missing := events[r.templates[1].ID()]
+				exp = []string{"kv.block(foo)"}
+				if !reflect.DeepEqual(exp, missing.MissingDependencies) {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, missing.MissingDependencies)
+				}
+				if missing.Checksum != "" || !missing.LastWouldRender.IsZero() {
+					t.Errorf("expected %s not to render, got %#v", missing.TemplateID, missing)
+				}
+			},
+			false,
+		},
+		{
+			"min_instances_blocks",
+			func(t *testing.T, r *Runner) {
+				d, err := dep.NewHealthServiceQuery("web")
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.brain.Remember(d, []*dep.HealthService{
+					&dep.HealthService{Address: "1.2.3.4"},
+				})
+				r.watcher.ForceWatching(d, true)
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String(`{{ range service "web" }}{{ .Address }}{{ end }}`),
+						Destination: config.String("/foo/bar"),
+						MinInstances: &config.MinInstancesConfigs{
+							&config.MinInstancesConfig{
+								Count:   config.Int(2),
+								Service: config.String("web"),
+							},
+						},
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				if out != "" {
+					t.Errorf("\nexp: %#v\nact: %#v", "", out)
+				}
+
+				event := r.RenderEvents()[r.templates[0].ID()]
+				if event == nil || event.LastBlocked.IsZero() || event.BlockedReason == "" {
+					t.Errorf("expected blocked render event, got %#v", event)
+				}
+				if r.allTemplatesRendered() {
+					t.Errorf("expected template to not be rendered")
+				}
+			},
+			false,
+		},
+		{
+			"min_instances_allows",
+			func(t *testing.T, r *Runner) {
+				d, err := dep.NewHealthServiceQuery("web")
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.brain.Remember(d, []*dep.HealthService{
+					&dep.HealthService{Address: "1.2.3.4"},
+					&dep.HealthService{Address: "5.6.7.8"},
+				})
+				r.watcher.ForceWatching(d, true)
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String(`{{ range service "web" }}{{ .Address }} {{ end }}`),
+						Destination: config.String("/foo/bar"),
+						MinInstances: &config.MinInstancesConfigs{
+							&config.MinInstancesConfig{
+								Count:   config.Int(2),
+								Service: config.String("web"),
+							},
+						},
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				exp := "> /foo/bar\n1.2.3.4 5.6.7.8 "
+				if out != exp {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+			},
+			false,
+		},
+		{
+			"changed_deps_env",
+			func(t *testing.T, r *Runner) {
+				r.dry = false
+
+				d, err := dep.NewKVGetQuery("foo")
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.EnableBlocking()
+				r.brain.Remember(d, "bar")
+				r.watcher.ForceWatching(d, true)
+			},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String(`{{ key "foo" }}`),
+						Command:     config.String("env"),
+						Destination: config.String("/tmp/ct-changed_deps_env_a"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				defer os.Remove("/tmp/ct-changed_deps_env_a")
+
+				exp := `CT_CHANGED_DEPS=["kv.block(foo)"]`
+				if !strings.Contains(out, exp) {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+
+				d, err := dep.NewKVGetQuery("foo")
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.EnableBlocking()
+				changed, _ := r.changedDeps(r.templates[0], []dep.Dependency{d})
+				if len(changed) != 0 {
+					t.Errorf("\nexp: %#v\nact: %#v", []string{}, changed)
+				}
+			},
+			false,
+		},
 		{
 			"env",
 			func(t *testing.T, r *Runner) {
@@ -321,6 +611,75 @@ func TestRunner_Run(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"input_template",
+			nil,
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:      config.String(`{{ .Input.foo }}`),
+						Destination:   config.String("/tmp/ct-input_b"),
+						InputTemplate: config.String("model"),
+					},
+					&config.TemplateConfig{
+						ID:          config.String("model"),
+						Contents:    config.String(`{"foo": "bar"}`),
+						Destination: config.String("/tmp/ct-input_a"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				exp := "> /tmp/ct-input_a\n{\"foo\": \"bar\"}> /tmp/ct-input_b\nbar"
+				if out != exp {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+			},
+			false,
+		},
+		{
+			"critical_first",
+			nil,
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("a"),
+						Destination: config.String("/tmp/ct-critical_a"),
+					},
+					&config.TemplateConfig{
+						Contents:    config.String("b"),
+						Critical:    config.Bool(true),
+						Destination: config.String("/tmp/ct-critical_b"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				exp := "> /tmp/ct-critical_b\nb> /tmp/ct-critical_a\na"
+				if out != exp {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+			},
+			false,
+		},
+		{
+			"base64_encoding",
+			nil,
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:    config.String("hello"),
+						Destination: config.String("/tmp/ct-encoding"),
+						Encoding:    config.String("base64"),
+					},
+				},
+			},
+			func(t *testing.T, r *Runner, out string) {
+				exp := "> /tmp/ct-encoding\naGVsbG8="
+				if out != exp {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, out)
+				}
+			},
+			false,
+		},
 	}
 
 	for i, tc := range cases {
@@ -355,34 +714,200 @@ func TestRunner_Run(t *testing.T) {
 	}
 }
 
-func TestRunner_Start(t *testing.T) {
+func TestRunner_DependencyReferences(t *testing.T) {
 	t.Parallel()
 
-	t.Run("store_pid", func(t *testing.T) {
-		t.Parallel()
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}{{ key "bar" }}`),
+				Destination: config.String("/tmp/b"),
+			},
+		},
+	})
 
-		pid, err := ioutil.TempFile("", "")
-		if err != nil {
-			t.Fatal(err)
-		}
-		defer os.Remove(pid.Name())
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
 
-		out, err := ioutil.TempFile("", "")
-		if err != nil {
-			t.Fatal(err)
-		}
-		defer os.Remove(out.Name())
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
 
-		c := config.DefaultConfig().Merge(&config.Config{
-			PidFile: config.String(pid.Name()),
-			Templates: &config.TemplateConfigs{
-				&config.TemplateConfig{
-					Contents:    config.String("test"),
-					Destination: config.String(out.Name()),
-				},
-			},
-		})
-		c.Finalize()
+	exp := map[string][]string{
+		"kv.block(bar)": []string{`"(dynamic)" => "/tmp/b"`},
+		"kv.block(foo)": []string{`"(dynamic)" => "/tmp/a"`, `"(dynamic)" => "/tmp/b"`},
+	}
+	if act := r.DependencyReferences(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	// Dropping the only template which references a dependency stops watching
+	// it, but dependencies shared with other templates are kept.
+	delete(r.templateDeps, r.templates[1].ID())
+	r.diffAndUpdateDeps()
+
+	exp = map[string][]string{
+		"kv.block(foo)": []string{`"(dynamic)" => "/tmp/a"`},
+	}
+	if act := r.DependencyReferences(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
+
+func TestNewRunner_inputTemplates(t *testing.T) {
+	cases := []struct {
+		name string
+		c    *config.TemplateConfigs
+		err  bool
+	}{
+		{
+			"valid",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					ID:       config.String("a"),
+					Contents: config.String("a"),
+				},
+				&config.TemplateConfig{
+					Contents:      config.String("b"),
+					InputTemplate: config.String("a"),
+				},
+			},
+			false,
+		},
+		{
+			"unknown",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:      config.String("b"),
+					InputTemplate: config.String("a"),
+				},
+			},
+			true,
+		},
+		{
+			"duplicate_id",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					ID:       config.String("a"),
+					Contents: config.String("a"),
+				},
+				&config.TemplateConfig{
+					ID:       config.String("a"),
+					Contents: config.String("b"),
+				},
+			},
+			true,
+		},
+		{
+			"self",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					ID:            config.String("a"),
+					Contents:      config.String("a"),
+					InputTemplate: config.String("a"),
+				},
+			},
+			true,
+		},
+		{
+			"cycle",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					ID:            config.String("a"),
+					Contents:      config.String("a"),
+					InputTemplate: config.String("b"),
+				},
+				&config.TemplateConfig{
+					ID:            config.String("b"),
+					Contents:      config.String("b"),
+					InputTemplate: config.String("a"),
+				},
+			},
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.TestConfig(&config.Config{Templates: tc.c})
+			_, err := NewRunner(c, true, true)
+			if (err != nil) != tc.err {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRunner_enableQuiescence(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Wait: &config.WaitConfig{
+			Min: config.TimeDuration(5 * time.Second),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents: config.String("a"),
+			},
+			&config.TemplateConfig{
+				Contents: config.String("b"),
+				Critical: config.Bool(true),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	r.enableQuiescence()
+
+	for _, tmpl := range r.templates {
+		_, act := r.quiescenceMap[tmpl.ID()]
+		exp := !criticalTemplate(r.templateConfigsFor(tmpl))
+		if exp != act {
+			t.Errorf("%s\nexp: %#v\nact: %#v", tmpl.Source(), exp, act)
+		}
+	}
+}
+
+func TestRunner_Start(t *testing.T) {
+	t.Parallel()
+
+	t.Run("store_pid", func(t *testing.T) {
+		t.Parallel()
+
+		pid, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(pid.Name())
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			PidFile: config.String(pid.Name()),
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String("test"),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
 
 		r, err := NewRunner(c, false, false)
 		if err != nil {
@@ -609,6 +1134,201 @@ func TestRunner_Start(t *testing.T) {
 		}
 	})
 
+	t.Run("exec_monitor_restart", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("resource usage sampling is only supported on linux")
+		}
+		t.Parallel()
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command:     config.String(`sleep 30`),
+				KillTimeout: config.TimeDuration(10 * time.Millisecond),
+				Monitor: &config.ExecMonitorConfig{
+					Action:       config.String(config.ExecMonitorActionRestart),
+					Interval:     config.TimeDuration(50 * time.Millisecond),
+					MaxOpenFiles: config.Int(1),
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`test`),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		var pids []int
+		for i := 0; i < 40 && len(pids) < 2; i++ {
+			time.Sleep(50 * time.Millisecond)
+
+			r.childLock.RLock()
+			if r.child != nil {
+				if pid := r.child.Pid(); pid != 0 &&
+					(len(pids) == 0 || pids[len(pids)-1] != pid) {
+					pids = append(pids, pid)
+				}
+			}
+			r.childLock.RUnlock()
+		}
+		if len(pids) < 2 {
+			t.Errorf("expected child to restart, got pids %v", pids)
+		}
+
+		if u := r.ChildUsage(); u == nil {
+			t.Error("expected child usage to be sampled")
+		}
+	})
+
+	t.Run("exec_prewarm_timeout", func(t *testing.T) {
+		t.Parallel()
+
+		// Vault never answers, so the secret is never fetched.
+		stopCh := make(chan struct{})
+		vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case <-stopCh:
+			case <-req.Context().Done():
+			}
+		}))
+		defer vault.Close()
+		defer close(stopCh)
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command:        config.String(`sleep 30`),
+				PrewarmTimeout: config.TimeDuration(500 * time.Millisecond),
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`{{ with secret "secret/foo" }}{{ .Data.password }}{{ end }}`),
+					Destination: config.String(out.Name()),
+					Name:        config.String("app"),
+				},
+			},
+			Vault: &config.VaultConfig{
+				Address:    config.String(vault.URL),
+				RenewToken: config.Bool(false),
+				Token:      config.String("token"),
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			if !strings.Contains(err.Error(), "prewarm") ||
+				!strings.Contains(err.Error(), "app (") ||
+				!strings.Contains(err.Error(), "vault.read(secret/foo)") {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if r.childRunning() {
+				t.Error("expected the child process not to start")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("exec_wait_for_file", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		ready := filepath.Join(dir, "ready")
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command:     config.String(`sleep 30`),
+				WaitForFile: config.String(ready),
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`test`),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		// The templates rendered, but the gate holds the child process.
+		time.Sleep(200 * time.Millisecond)
+		if r.childRunning() {
+			t.Fatal("expected the child process not to start before the gate opens")
+		}
+
+		if err := ioutil.WriteFile(ready, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 40 && !r.childRunning(); i++ {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if !r.childRunning() {
+			t.Error("expected the child process to start once the gate opens")
+		}
+	})
+
 	t.Run("exec_once", func(t *testing.T) {
 		t.Parallel()
 
@@ -650,6 +1370,111 @@ func TestRunner_Start(t *testing.T) {
 	})
 }
 
+func TestRunner_onceRetry(t *testing.T) {
+	t.Parallel()
+
+	newRunner := func(t *testing.T, in, out string, timeout time.Duration) *Runner {
+		c := config.DefaultConfig().Merge(&config.Config{
+			OnceRetryTimeout: config.TimeDuration(timeout),
+			Retry:            config.TimeDuration(50 * time.Millisecond),
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, in)),
+					Destination: config.String(out),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("recovers", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+		r := newRunner(t, in, out, 5*time.Second)
+		go r.Start()
+		defer r.Stop()
+
+		time.Sleep(200 * time.Millisecond)
+		if err := ioutil.WriteFile(in, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.DoneCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		b, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "hello" {
+			t.Errorf("\nexp: %#v\nact: %#v", "hello", string(b))
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		r := newRunner(t, filepath.Join(dir, "in"), filepath.Join(dir, "out"), 200*time.Millisecond)
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			if !strings.Contains(err.Error(), "no such file") {
+				t.Errorf("expected missing file error, got %s", err)
+			}
+		case <-r.DoneCh:
+			t.Fatal("expected once mode to give up")
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		r := newRunner(t, filepath.Join(dir, "in"), filepath.Join(dir, "out"), 0)
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case <-r.ErrCh:
+		case <-time.After(time.Second):
+			t.Fatal("expected once mode to fail on the first error")
+		}
+	})
+}
+
 func TestRunner_quiescence(t *testing.T) {
 	t.Parallel()
 
@@ -740,3 +1565,260 @@ func TestRunner_quiescence(t *testing.T) {
 		}
 	})
 }
+
+func TestTemplateLogLevels(t *testing.T) {
+	t.Parallel()
+
+	configs := config.TemplateConfigs{
+		&config.TemplateConfig{
+			Destination: config.String("/tmp/a"),
+		},
+		&config.TemplateConfig{
+			Destination: config.String("/tmp/b"),
+			LogLevel:    config.String("debug"),
+		},
+		&config.TemplateConfig{
+			Destination: config.String("/tmp/c"),
+			LogLevel:    config.String("warn"),
+			LogTag:      config.String("web"),
+		},
+		&config.TemplateConfig{
+			Destination: config.String("/tmp/d"),
+			LogLevel:    config.String("trace"),
+			LogTag:      config.String("web"),
+		},
+		&config.TemplateConfig{
+			Destination: config.String("/tmp/e"),
+			LogTag:      config.String("quiet"),
+		},
+	}
+
+	exp := map[string]string{
+		"/tmp/b": "DEBUG",
+		"web":    "TRACE",
+	}
+	if act := templateLogLevels(configs); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	tags := []string{"", "/tmp/b", "web", "web", "quiet"}
+	for i, c := range configs {
+		if act := templateLogTag(c); act != tags[i] {
+			t.Errorf("\nexp: %#v\nact: %#v", tags[i], act)
+		}
+	}
+}
+
+func TestStreamingAddress(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		consul    string
+		streaming string
+		exp       string
+	}{
+		{
+			"default",
+			"127.0.0.1:8500",
+			"",
+			"127.0.0.1:8300",
+		},
+		{
+			"scheme",
+			"https://consul.service.consul:8501",
+			"",
+			"consul.service.consul:8300",
+		},
+		{
+			"no_port",
+			"consul.service.consul",
+			"",
+			"consul.service.consul:8300",
+		},
+		{
+			"address",
+			"127.0.0.1:8500",
+			"10.0.0.1:8300",
+			"10.0.0.1:8300",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultConfig().Merge(&config.Config{
+				Consul: config.String(tc.consul),
+				Streaming: &config.StreamingConfig{
+					Address: config.String(tc.streaming),
+				},
+			})
+			c.Finalize()
+
+			if act := streamingAddress(c); act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestDependencyPriority(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		priorities []string
+		exp        int
+	}{
+		{"none", nil, watch.PriorityNormal},
+		{"normal", []string{config.TemplatePriorityNormal}, watch.PriorityNormal},
+		{"low", []string{config.TemplatePriorityLow}, watch.PriorityLow},
+		{"highest", []string{config.TemplatePriorityLow, config.TemplatePriorityHigh}, watch.PriorityHigh},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var configs []*config.TemplateConfig
+			for _, p := range tc.priorities {
+				configs = append(configs, &config.TemplateConfig{Priority: config.String(p)})
+			}
+			if act := dependencyPriority(configs); act != tc.exp {
+				t.Errorf("\nexp: %d\nact: %d", tc.exp, act)
+			}
+		})
+	}
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents: config.String("test"),
+				Priority: config.String("urgent"),
+			},
+		},
+	})
+	if _, err := NewRunner(c, true, true); err == nil || !strings.Contains(err.Error(), `invalid priority "urgent"`) {
+		t.Errorf("expected an invalid priority error, got %v", err)
+	}
+}
+
+func TestNewRunner_sandboxPath(t *testing.T) {
+	cases := []struct {
+		name      string
+		sandboxes []string
+		err       string
+	}{
+		{"same", []string{"/etc/templates", "/etc/templates"}, ""},
+		{"relative", []string{"templates"}, "must be absolute"},
+		{"different", []string{"/etc/templates", ""}, "must use the same sandbox_path"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var templates config.TemplateConfigs
+			for j, sandbox := range tc.sandboxes {
+				templates = append(templates, &config.TemplateConfig{
+					Contents:    config.String(`{{ file "/etc/templates/a" }}`),
+					Destination: config.String(fmt.Sprintf("/tmp/out-%d", j)),
+					SandboxPath: config.String(sandbox),
+				})
+			}
+			c := config.TestConfig(&config.Config{Templates: &templates})
+
+			_, err := NewRunner(c, true, true)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRunner_vaultCapabilities(t *testing.T) {
+	// The token may read secret/bar, but neither read secret/foo nor issue
+	// certificates from pki/issue/web.
+	var lookups, reads int32
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/sys/capabilities-self":
+			atomic.AddInt32(&lookups, 1)
+			fmt.Fprint(w, `{"data": {
+				"secret/foo": ["deny"],
+				"secret/bar": ["read", "list"],
+				"pki/issue/web": ["read"]
+			}}`)
+		case "/v1/secret/foo", "/v1/secret/bar":
+			atomic.AddInt32(&reads, 1)
+			fmt.Fprint(w, `{"data": {"password": "hunter2"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ with secret "secret/foo" }}{{ end }}{{ with secret "secret/bar" }}{{ end }}`),
+				Destination: config.String("/tmp/out-a"),
+				Name:        config.String("a"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ with pkiCert "pki/issue/web" "common_name=web" }}{{ end }}`),
+				Destination: config.String("/tmp/out-b"),
+				Name:        config.String("b"),
+			},
+		},
+		Vault: &config.VaultConfig{
+			Address:           config.String(vault.URL),
+			CheckCapabilities: config.Bool(true),
+			RenewToken:        config.Bool(false),
+			Token:             config.String("token"),
+		},
+	})
+
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	err = r.Run()
+	if err == nil {
+		t.Fatal("expected the capability check to fail")
+	}
+	for _, exp := range []string{
+		`vault.read(secret/foo) needs read on "secret/foo" (granted: deny)`,
+		`needs update on "pki/issue/web" (granted: read)`,
+	} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("expected %q in %s", exp, err)
+		}
+	}
+	if strings.Contains(err.Error(), "secret/bar") {
+		t.Errorf("expected secret/bar to be allowed: %s", err)
+	}
+
+	// The capabilities are looked up once per template, and no secret of a
+	// template with missing capabilities is watched.
+	if n := atomic.LoadInt32(&lookups); n != 2 {
+		t.Errorf("expected 2 lookups, got %d", n)
+	}
+	if r.watcher.Size() != 0 {
+		t.Errorf("expected no dependencies to be watched, got %d", r.watcher.Size())
+	}
+	if n := atomic.LoadInt32(&reads); n != 0 {
+		t.Errorf("expected no secrets to be read, got %d", n)
+	}
+
+	// A second run is answered from the cache.
+	if err := r.Run(); err == nil {
+		t.Fatal("expected the capability check to fail")
+	}
+	if n := atomic.LoadInt32(&lookups); n != 2 {
+		t.Errorf("expected the capabilities to be cached, got %d lookups", n)
+	}
+}
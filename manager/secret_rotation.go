@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// templateSecretRotations returns the secret rotation of each template ID
+// which has rotation windows. If a template is rendered to several
+// destinations, the windows of all of them apply, in order.
+func templateSecretRotations(ctemplatesMap map[string]config.TemplateConfigs) (map[string]*template.SecretRotation, error) {
+	rotations := make(map[string]*template.SecretRotation)
+	for id, ctmpls := range ctemplatesMap {
+		var rules []template.SecretRotationRule
+		for _, ctmpl := range ctmpls {
+			if ctmpl.SecretRotation == nil {
+				continue
+			}
+			for _, c := range *ctmpl.SecretRotation {
+				if !config.StringPresent(c.RotateWithin) {
+					return nil, fmt.Errorf("%s: secret_rotation requires rotate_within",
+						ctmpl.Display())
+				}
+				rules = append(rules, template.SecretRotationRule{
+					Path:   config.StringVal(c.Path),
+					Window: config.StringVal(c.RotateWithin),
+				})
+			}
+		}
+
+		rotation, err := template.NewSecretRotation(rules)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", ctmpls[0].Display(), err)
+		}
+		if rotation != nil {
+			rotations[id] = rotation
+		}
+	}
+	return rotations, nil
+}
+
+// nextSecretRotation returns the earliest time a new version of a secret which
+// is held back by its rotation window is written, or zero if none is.
+func (r *Runner) nextSecretRotation() time.Time {
+	var next time.Time
+	for _, rotation := range r.rotations {
+		if t := rotation.Next(); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestNewRunner_secretRotationInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		config *config.SecretRotationConfig
+		err    string
+	}{
+		{
+			"missing_window",
+			&config.SecretRotationConfig{
+				Path: config.String("database/creds/*"),
+			},
+			"requires rotate_within",
+		},
+		{
+			"invalid_window",
+			&config.SecretRotationConfig{
+				RotateWithin: config.String("02:00"),
+			},
+			"invalid rotate_within",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config.TestConfig(&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{
+						Contents:       config.String("test"),
+						Destination:    config.String("/tmp/out"),
+						SecretRotation: &config.SecretRotationConfigs{tc.config},
+					},
+				},
+			})
+
+			_, err := NewRunner(c, false, false)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// templateSeeds returns the persisted seed of the random template functions
+// for each template ID which has a seed file. If a template is rendered to
+// several destinations, the first seed file is used. Missing seed files are
+// created, unless dry is set.
+func templateSeeds(ctemplatesMap map[string]config.TemplateConfigs, dry bool) (map[string]int64, error) {
+	seeds := make(map[string]int64)
+	for id, ctmpls := range ctemplatesMap {
+		for _, ctmpl := range ctmpls {
+			path := config.StringVal(ctmpl.SeedFile)
+			if path == "" {
+				continue
+			}
+
+			seed, err := loadSeed(path, dry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", ctmpl.Display(), err)
+			}
+			seeds[id] = seed
+			break
+		}
+	}
+	return seeds, nil
+}
+
+// loadSeed reads the seed in the file at the given path. If the file does not
+// exist, a new seed is generated and written to it, unless dry is set.
+func loadSeed(path string, dry bool) (int64, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err == nil {
+		seed, err := strconv.ParseInt(strings.TrimSpace(string(contents)), 10, 64)
+		if err != nil || seed == 0 {
+			return 0, fmt.Errorf("invalid seed file %s", path)
+		}
+		return seed, nil
+	}
+	if !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read seed file: %s", err)
+	}
+
+	seed := template.NewSeed()
+	for seed == 0 {
+		seed = template.NewSeed()
+	}
+	if dry {
+		return seed, nil
+	}
+
+	log.Printf("[INFO] (runner) creating seed file %s", path)
+	if err := AtomicWrite(path, []byte(strconv.FormatInt(seed, 10)+"\n"), 0600, false); err != nil {
+		return 0, fmt.Errorf("failed to write seed file: %s", err)
+	}
+	return seed, nil
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSeed(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("creates_and_reloads", func(t *testing.T) {
+		path := filepath.Join(dir, "nested", "seed")
+
+		seed, err := loadSeed(path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stat, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0600 {
+			t.Errorf("expected perms 0600, got %s", stat.Mode().Perm())
+		}
+
+		reloaded, err := loadSeed(path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reloaded != seed {
+			t.Errorf("expected %d to be %d", reloaded, seed)
+		}
+	})
+
+	t.Run("dry_does_not_create", func(t *testing.T) {
+		path := filepath.Join(dir, "dry")
+
+		if _, err := loadSeed(path, true); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to exist: %v", path, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid")
+		if err := ioutil.WriteFile(path, []byte("nope"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadSeed(path, false); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
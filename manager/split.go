@@ -0,0 +1,332 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// splitDataLink is the symlink in a split destination which points to the
+	// directory holding the current files. Replacing it switches all the
+	// files at once.
+	splitDataLink = "..data"
+
+	// splitDataPrefix is the prefix of the directories holding the files of a
+	// split destination.
+	splitDataPrefix = "..data_"
+
+	// splitTmpPrefix is the prefix of the symlinks which are created and then
+	// renamed over the links of a split destination.
+	splitTmpPrefix = "..tmp_"
+)
+
+// SplitContents parses the rendered output of a template with a split
+// destination, which must be a JSON object, into the contents of the file of
+// each key. String values are written as-is and other values as JSON. Keys
+// must be usable as file names.
+func SplitContents(contents []byte) (map[string][]byte, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(contents, &data); err != nil {
+		return nil, errors.Wrap(err, "split destination: output is not a JSON object")
+	}
+
+	files := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if err := validateSplitKey(key); err != nil {
+			return nil, err
+		}
+
+		if s, ok := value.(string); ok {
+			files[key] = []byte(s)
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "split destination: key %q", key)
+		}
+		files[key] = b
+	}
+	return files, nil
+}
+
+// validateSplitKey returns an error if the given key cannot be the name of a
+// file in a split destination.
+func validateSplitKey(key string) error {
+	switch {
+	case key == "", key == ".", key == "..":
+		return fmt.Errorf("split destination: invalid key %q", key)
+	case strings.ContainsAny(key, `/\`):
+		return fmt.Errorf("split destination: key %q contains a path separator", key)
+	case strings.HasPrefix(key, ".."):
+		return fmt.Errorf("split destination: key %q starts with \"..\"", key)
+	}
+	return nil
+}
+
+// ReadSplit returns the contents of the files of the split destination in the
+// given directory, by key. Only the files written by a split destination are
+// returned; other files in the directory are ignored. A missing directory has
+// no files.
+func ReadSplit(dir string) (map[string][]byte, error) {
+	keys, err := splitKeys(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		b, err := ioutil.ReadFile(filepath.Join(dir, key))
+		if os.IsNotExist(err) {
+			// The link of a removed key which was not cleaned up.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[key] = b
+	}
+	return files, nil
+}
+
+// splitKeys returns the keys of the files of the split destination in the given
+// directory, which are the symlinks pointing into the data directory.
+func splitKeys(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if target == filepath.Join(splitDataLink, entry.Name()) {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys, nil
+}
+
+// renderSplit renders the output of a template with a split destination into
+// the directory at the path of the input. The files are written to a new data
+// directory, which then replaces the current one by switching the data
+// symlink, so readers see either all the old files or all the new ones. Each
+// key is a symlink to its file through the data symlink. The links of keys
+// which are no longer in the output and the previous data directory are
+// removed afterwards.
+func renderSplit(i *RenderInput) (*RenderResult, error) {
+	files, err := SplitContents(i.Contents)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := ReadSplit(i.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading split destination")
+	}
+	if splitEqual(existing, files, i.equal) {
+		return &RenderResult{
+			DidRender:   false,
+			WouldRender: true,
+		}, nil
+	}
+
+	if i.Dry {
+		for _, key := range sortedSplitKeys(files) {
+			fmt.Fprintf(i.DryStream, "> %s\n%s", filepath.Join(i.Path, key), files[key])
+		}
+		return &RenderResult{
+			DidRender:   true,
+			WouldRender: true,
+		}, nil
+	}
+
+	if err := i.prepareDestDir(i.Path); err != nil {
+		return nil, errors.Wrap(err, "failed creating destination directory")
+	}
+
+	if err := writeSplit(i.Path, files, i.Perms); err != nil {
+		return nil, errors.Wrap(err, "failed writing split destination")
+	}
+
+	return &RenderResult{
+		DidRender:   true,
+		WouldRender: true,
+	}, nil
+}
+
+// writeSplit replaces the files of the split destination in the given
+// directory with the given files.
+func writeSplit(dir string, files map[string][]byte, perms os.FileMode) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("destination directory %s does not exist", dir)
+	}
+
+	data, err := ioutil.TempDir(dir, splitDataPrefix)
+	if err != nil {
+		if isNotWritable(err) {
+			return errors.Wrapf(ErrNotWritable, "%s: %s", dir, err)
+		}
+		return err
+	}
+
+	for key, contents := range files {
+		path := filepath.Join(data, key)
+		if err := ioutil.WriteFile(path, contents, perms); err != nil {
+			os.RemoveAll(data)
+			return err
+		}
+		if err := os.Chmod(path, perms); err != nil {
+			os.RemoveAll(data)
+			return err
+		}
+	}
+
+	// The data directory is only readable by its owner when it is created.
+	if err := os.Chmod(data, 0755); err != nil {
+		os.RemoveAll(data)
+		return err
+	}
+
+	prev, err := os.Readlink(filepath.Join(dir, splitDataLink))
+	if err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(data)
+		return err
+	}
+
+	// Switch all the files at once.
+	if err := replaceSymlink(filepath.Base(data), filepath.Join(dir, splitDataLink)); err != nil {
+		os.RemoveAll(data)
+		return err
+	}
+
+	// Link the new keys. Links which already exist resolve to the new files
+	// through the data symlink.
+	for key := range files {
+		target := filepath.Join(splitDataLink, key)
+		if current, err := os.Readlink(filepath.Join(dir, key)); err == nil && current == target {
+			continue
+		}
+		if err := replaceSymlink(target, filepath.Join(dir, key)); err != nil {
+			return err
+		}
+	}
+
+	// Remove the links of the removed keys, which now dangle.
+	keys, err := splitKeys(dir)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, ok := files[key]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if prev != "" && prev != filepath.Base(data) && strings.HasPrefix(prev, splitDataPrefix) {
+		if err := os.RemoveAll(filepath.Join(dir, prev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeSplit removes the files of the split destination in the given
+// directory, and the directory itself if nothing else is left in it.
+func removeSplit(dir string) error {
+	keys, err := splitKeys(dir)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := os.Remove(filepath.Join(dir, key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	data, err := os.Readlink(filepath.Join(dir, splitDataLink))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, splitDataLink)); err != nil {
+		return err
+	}
+	if strings.HasPrefix(data, splitDataPrefix) {
+		if err := os.RemoveAll(filepath.Join(dir, data)); err != nil {
+			return err
+		}
+	}
+
+	// Keep the directory if other files are in it.
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return os.Remove(dir)
+	}
+	return nil
+}
+
+// replaceSymlink atomically creates or replaces the symlink at path with one
+// pointing to target.
+func replaceSymlink(target, path string) error {
+	tmp := filepath.Join(filepath.Dir(path), splitTmpPrefix+filepath.Base(path))
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// splitEqual returns true if both sets of files have the same keys and equal
+// contents.
+func splitEqual(a, b map[string][]byte, equal func(a, b []byte) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, contents := range a {
+		other, ok := b[key]
+		if !ok || !equal(contents, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedSplitKeys returns the keys of the given files in order.
+func sortedSplitKeys(files map[string][]byte) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,181 @@
+// +build !windows
+
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitContents(t *testing.T) {
+	cases := []struct {
+		name string
+		i    string
+		e    map[string][]byte
+		err  bool
+	}{
+		{
+			"strings",
+			`{"user": "admin", "password": "s3cr3t\n"}`,
+			map[string][]byte{
+				"user":     []byte("admin"),
+				"password": []byte("s3cr3t\n"),
+			},
+			false,
+		},
+		{
+			"non_strings",
+			`{"port": 5432, "tags": ["a", "b"]}`,
+			map[string][]byte{
+				"port": []byte("5432"),
+				"tags": []byte(`["a","b"]`),
+			},
+			false,
+		},
+		{
+			"not_an_object",
+			`["a", "b"]`,
+			nil,
+			true,
+		},
+		{
+			"path_separator",
+			`{"../etc/passwd": "x"}`,
+			nil,
+			true,
+		},
+		{
+			"dot_dot_prefix",
+			`{"..data": "x"}`,
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := SplitContents([]byte(tc.i))
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.e, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, act)
+			}
+		})
+	}
+}
+
+func TestRender_split(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "secrets")
+	render := func(contents string) *RenderResult {
+		result, err := Render(&RenderInput{
+			Contents:       []byte(contents),
+			CreateDestDirs: true,
+			DestDirPerms:   0755,
+			Path:           dest,
+			Perms:          0600,
+			Split:          true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := render(`{"a": "1", "b": "2"}`); !result.DidRender {
+		t.Fatal("expected the first render to write the files")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dest, "unrelated"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prev, err := os.Readlink(filepath.Join(dest, splitDataLink))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := render(`{"a": "3", "c": "4"}`); !result.DidRender {
+		t.Fatal("expected the second render to write the files")
+	}
+
+	files, err := ReadSplit(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string][]byte{"a": []byte("3"), "c": []byte("4")}
+	if !reflect.DeepEqual(exp, files) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, files)
+	}
+
+	if _, err := os.Lstat(filepath.Join(dest, "b")); !os.IsNotExist(err) {
+		t.Errorf("expected the removed key to be deleted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, prev)); !os.IsNotExist(err) {
+		t.Errorf("expected the previous data directory to be deleted: %v", err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dest, "unrelated")); err != nil || !bytes.Equal(b, []byte("keep")) {
+		t.Errorf("expected other files to be kept: %q %v", b, err)
+	}
+
+	stat, err := os.Stat(filepath.Join(dest, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := os.FileMode(0600); stat.Mode() != exp {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, stat.Mode())
+	}
+
+	if result := render(`{"c": "4", "a": "3"}`); result.DidRender || !result.WouldRender {
+		t.Errorf("expected unchanged files not to be written: %#v", result)
+	}
+
+	if err := removeSplit(dest); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "unrelated" {
+		t.Errorf("expected only the unrelated file to be left, got %v", entries)
+	}
+}
+
+func TestRender_splitDry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	dest := filepath.Join(dir, "secrets")
+	if _, err := Render(&RenderInput{
+		Contents:  []byte(`{"b": "2", "a": "1"}`),
+		Dry:       true,
+		DryStream: &out,
+		Path:      dest,
+		Split:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "> " + filepath.Join(dest, "a") + "\n1" +
+		"> " + filepath.Join(dest, "b") + "\n2"
+	if out.String() != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, out.String())
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written in dry mode: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// markStale records that the given dependency failed. If it returned data
+// before, its templates keep rendering that data, and markStale returns how
+// long it has been failing and true. Otherwise it returns false.
+func (r *Runner) markStale(d dep.Dependency) (time.Duration, bool) {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	if _, ok := r.brain.Recall(d); !ok {
+		return 0, false
+	}
+
+	since, ok := r.staleSince[d.String()]
+	if !ok {
+		since = time.Now()
+		r.staleSince[d.String()] = since
+	}
+	return time.Since(since), true
+}
+
+// nextStaleDeadline returns the earliest end of the grace period of the
+// dependencies which render stale data, or zero if none does.
+func (r *Runner) nextStaleDeadline(grace time.Duration) time.Time {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	var next time.Time
+	for _, since := range r.staleSince {
+		if t := since.Add(grace); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// staleError returns an error for the dependency which has been failing for
+// the longest time, if that is longer than the grace period. It returns nil
+// if all dependencies which render stale data are within the grace period.
+func (r *Runner) staleError(grace time.Duration) error {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	var key string
+	var oldest time.Time
+	for k, since := range r.staleSince {
+		if oldest.IsZero() || since.Before(oldest) {
+			key, oldest = k, since
+		}
+	}
+	if oldest.IsZero() || time.Since(oldest) < grace {
+		return nil
+	}
+
+	err := fmt.Errorf("runner: %s failed for longer than the stale grace period of %s",
+		key, grace)
+	if d, ok := r.dependencies[key]; ok {
+		if derr := r.brain.Error(d); derr != nil {
+			err = fmt.Errorf("%s: %s", err, derr)
+		}
+	}
+	return err
+}
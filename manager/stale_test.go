@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestRunner_stale(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/out"),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.dependencies[d.String()] = d
+
+	// A dependency which never returned data has nothing to render.
+	if _, ok := r.markStale(d); ok {
+		t.Fatal("expected a dependency without data not to be stale")
+	}
+
+	r.Receive(d, "bar")
+	r.brain.RememberError(d, fmt.Errorf("Permission denied"))
+	if _, ok := r.markStale(d); !ok {
+		t.Fatal("expected a dependency with data to be stale")
+	}
+
+	grace := 50 * time.Millisecond
+	if next := r.nextStaleDeadline(grace); next.IsZero() || next.Sub(time.Now()) > grace {
+		t.Errorf("expected a deadline within %s, got %s", grace, next)
+	}
+	if err := r.staleError(grace); err != nil {
+		t.Errorf("expected no error within the grace period, got %s", err)
+	}
+
+	time.Sleep(grace)
+	err = r.staleError(grace)
+	if err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("expected the error of the dependency, got %v", err)
+	}
+
+	// Receiving data ends the grace period.
+	r.Receive(d, "baz")
+	if err := r.staleError(grace); err != nil {
+		t.Errorf("expected no error once data was received, got %s", err)
+	}
+	if next := r.nextStaleDeadline(grace); !next.IsZero() {
+		t.Errorf("expected no deadline, got %s", next)
+	}
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// standbyKeyWaitTime is the maximum amount of time a single blocking query
+	// on the standby key waits for a change.
+	standbyKeyWaitTime = 60 * time.Second
+
+	// standbyKeyRetry is the amount of time to wait before retrying a failed
+	// query on the standby key.
+	standbyKeyRetry = 5 * time.Second
+)
+
+// Promote takes the runner out of standby. The commands and the reload of the
+// child process held in standby are run by the next run, which is triggered
+// right away. Promoting a runner which is not in standby does nothing.
+func (r *Runner) Promote() {
+	r.standbyLock.Lock()
+	defer r.standbyLock.Unlock()
+
+	if !r.standby {
+		return
+	}
+
+	log.Printf("[INFO] (runner) promoting from standby")
+	r.standby = false
+	r.promoted = true
+	r.publish(EventPromoted, "", "")
+
+	select {
+	case r.promoteCh <- struct{}{}:
+	default:
+	}
+}
+
+// Promoted returns true if the runner was promoted from standby. A runner
+// which replaces it on a reload should be promoted as well.
+func (r *Runner) Promoted() bool {
+	r.standbyLock.Lock()
+	defer r.standbyLock.Unlock()
+
+	return r.promoted
+}
+
+// inStandby returns true while the runner is in standby.
+func (r *Runner) inStandby() bool {
+	r.standbyLock.Lock()
+	defer r.standbyLock.Unlock()
+
+	return r.standby
+}
+
+// isPromoteSignal returns true if the given signal is the configured standby
+// signal and the runner is still in standby.
+func (r *Runner) isPromoteSignal(s os.Signal) bool {
+	signal := r.config.Standby.Signal
+	return signal != nil && *signal == s && r.inStandby()
+}
+
+// holdForStandby returns the commands to execute and whether to reload the
+// child process. In standby, the given commands and reload are held and
+// nothing is returned. Otherwise, anything held is returned along with the
+// given commands, keeping the order in which the commands were first seen.
+func (r *Runner) holdForStandby(commands []*config.TemplateConfig, reload bool) ([]*config.TemplateConfig, bool) {
+	r.standbyLock.Lock()
+	defer r.standbyLock.Unlock()
+
+	if r.standby {
+		for _, c := range commands {
+			if findCommand(c, r.standbyCommands) == nil {
+				r.standbyCommands = append(r.standbyCommands, c)
+			}
+		}
+		r.standbyReload = r.standbyReload || reload
+
+		if len(commands) > 0 || reload {
+			log.Printf("[INFO] (runner) standby: holding %d command(s) (reload: %t)",
+				len(r.standbyCommands), r.standbyReload)
+		}
+		return nil, false
+	}
+
+	held := r.standbyCommands
+	for _, c := range commands {
+		if findCommand(c, held) == nil {
+			held = append(held, c)
+		}
+	}
+	reload = reload || r.standbyReload
+
+	r.standbyCommands = nil
+	r.standbyReload = false
+	return held, reload
+}
+
+// standbyKeyPromotes returns true if the value of the standby key promotes the
+// runner.
+func standbyKeyPromotes(pair *consulapi.KVPair) bool {
+	if pair == nil {
+		return false
+	}
+	ok, err := strconv.ParseBool(strings.TrimSpace(string(pair.Value)))
+	return err == nil && ok
+}
+
+// watchStandbyKey watches the given key and calls promote once its value is
+// true. This function blocks until the runner is promoted or doneCh is closed
+// and should be run in a goroutine.
+func watchStandbyKey(client *consulapi.Client, key string, promote func(),
+	doneCh <-chan struct{}) {
+	var index uint64
+	for {
+		pair, meta, err := client.KV().Get(key, &consulapi.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  standbyKeyWaitTime,
+		})
+
+		select {
+		case <-doneCh:
+			return
+		default:
+		}
+
+		if err != nil {
+			log.Printf("[WARN] (runner) failed to watch standby key %q: %s", key, err)
+			select {
+			case <-time.After(standbyKeyRetry):
+				continue
+			case <-doneCh:
+				return
+			}
+		}
+
+		if standbyKeyPromotes(pair) {
+			log.Printf("[INFO] (runner) standby key %q promotes the runner", key)
+			promote()
+			return
+		}
+
+		// Reset the index if it went backwards, such as after a snapshot restore.
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestStandbyKeyPromotes(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		pair *consulapi.KVPair
+		exp  bool
+	}{
+		{
+			"missing",
+			nil,
+			false,
+		},
+		{
+			"true",
+			&consulapi.KVPair{Value: []byte("true")},
+			true,
+		},
+		{
+			"true_newline",
+			&consulapi.KVPair{Value: []byte("1\n")},
+			true,
+		},
+		{
+			"false",
+			&consulapi.KVPair{Value: []byte("false")},
+			false,
+		},
+		{
+			"invalid",
+			&consulapi.KVPair{Value: []byte("primary")},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := standbyKeyPromotes(tc.pair); act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestRunner_standby(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out")
+	marker := filepath.Join(dir, "marker")
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Standby: &config.StandbyConfig{
+			Signal: config.Signal(syscall.SIGTERM),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("hello"),
+				Command:     config.String("touch " + marker),
+				Destination: config.String(out),
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("expected %s to be rendered in standby: %s", out, err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("expected command to be held in standby: %v", err)
+	}
+
+	if err := r.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Promoted() {
+		t.Fatal("expected standby signal to promote")
+	}
+
+	// The template does not render again, but the held command runs.
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected held command to run after promotion: %s", err)
+	}
+}
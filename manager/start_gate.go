@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// startGateWaitTime is the maximum amount of time a single blocking query
+	// of the start gate waits for a change.
+	startGateWaitTime = 60 * time.Second
+
+	// startGateRetry is the amount of time to wait before retrying a failed
+	// query of the start gate.
+	startGateRetry = 5 * time.Second
+
+	// startGateFileInterval is how often the start gate checks if its file
+	// exists.
+	startGateFileInterval = 1 * time.Second
+)
+
+// hasStartGate returns true if the given exec configuration gates the start of
+// the child process on a condition.
+func hasStartGate(c *config.ExecConfig) bool {
+	return config.StringPresent(c.WaitForFile) ||
+		config.StringPresent(c.WaitForKey) ||
+		config.StringPresent(c.WaitForService)
+}
+
+// watchStartGate waits for each condition of the start gate of the given exec
+// configuration in turn, and closes openCh once all of them were met. The gate
+// stays open afterwards. This function blocks until the gate opens or doneCh
+// is closed and should be run in a goroutine.
+func watchStartGate(client *consulapi.Client, c *config.ExecConfig,
+	openCh chan<- struct{}, doneCh <-chan struct{}) {
+	if path := config.StringVal(c.WaitForFile); path != "" {
+		log.Printf("[INFO] (runner) start gate waiting for file %q", path)
+		if !waitForStartGateFile(path, startGateFileInterval, doneCh) {
+			return
+		}
+	}
+
+	if key := config.StringVal(c.WaitForKey); key != "" {
+		log.Printf("[INFO] (runner) start gate waiting for key %q", key)
+		if !waitForStartGateQuery(startGateKeyQuery(client, key), "key "+key, doneCh) {
+			return
+		}
+	}
+
+	if service := config.StringVal(c.WaitForService); service != "" {
+		log.Printf("[INFO] (runner) start gate waiting for service %q", service)
+		if !waitForStartGateQuery(startGateServiceQuery(client, service), "service "+service, doneCh) {
+			return
+		}
+	}
+
+	log.Printf("[INFO] (runner) start gate is open")
+	close(openCh)
+}
+
+// waitForStartGateFile returns true once the given file exists, checking it at
+// the given interval, or false if doneCh is closed first.
+func waitForStartGateFile(path string, interval time.Duration, doneCh <-chan struct{}) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-doneCh:
+			return false
+		}
+	}
+}
+
+// startGateQuery is a blocking query of a start gate condition. It returns
+// true if the condition is met and the index of the result.
+type startGateQuery func(index uint64) (bool, uint64, error)
+
+// startGateKeyQuery returns the query of the given key, which is met when the
+// value of the key is true, like the standby key.
+func startGateKeyQuery(client *consulapi.Client, key string) startGateQuery {
+	return func(index uint64) (bool, uint64, error) {
+		pair, meta, err := client.KV().Get(key, &consulapi.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  startGateWaitTime,
+		})
+		if err != nil {
+			return false, 0, err
+		}
+		return standbyKeyPromotes(pair), meta.LastIndex, nil
+	}
+}
+
+// startGateServiceQuery returns the query of the given service, which is met
+// when the service has a passing instance.
+func startGateServiceQuery(client *consulapi.Client, service string) startGateQuery {
+	return func(index uint64) (bool, uint64, error) {
+		entries, meta, err := client.Health().Service(service, "", true, &consulapi.QueryOptions{
+			WaitIndex: index,
+			WaitTime:  startGateWaitTime,
+		})
+		if err != nil {
+			return false, 0, err
+		}
+		return len(entries) > 0, meta.LastIndex, nil
+	}
+}
+
+// waitForStartGateQuery runs the given blocking query until its condition is
+// met and returns true, or returns false if doneCh is closed first. Failed
+// queries are retried.
+func waitForStartGateQuery(query startGateQuery, name string, doneCh <-chan struct{}) bool {
+	var index uint64
+	for {
+		ok, lastIndex, err := query(index)
+
+		select {
+		case <-doneCh:
+			return false
+		default:
+		}
+
+		if err != nil {
+			log.Printf("[WARN] (runner) start gate failed to query %s: %s", name, err)
+			select {
+			case <-time.After(startGateRetry):
+				continue
+			case <-doneCh:
+				return false
+			}
+		}
+
+		if ok {
+			return true
+		}
+
+		// Reset the index if it went backwards, such as after a snapshot restore.
+		if lastIndex < index {
+			index = 0
+		} else {
+			index = lastIndex
+		}
+	}
+}
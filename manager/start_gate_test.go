@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestHasStartGate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		c    *config.ExecConfig
+		exp  bool
+	}{
+		{
+			"none",
+			&config.ExecConfig{WaitForKey: config.String("")},
+			false,
+		},
+		{
+			"file",
+			&config.ExecConfig{WaitForFile: config.String("/run/ready")},
+			true,
+		},
+		{
+			"key",
+			&config.ExecConfig{WaitForKey: config.String("locks/deploy-ready")},
+			true,
+		},
+		{
+			"service",
+			&config.ExecConfig{WaitForService: config.String("db")},
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := hasStartGate(tc.c); act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestWaitForStartGateFile(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ready")
+
+	resultCh := make(chan bool, 1)
+	go func() {
+		resultCh <- waitForStartGateFile(path, 10*time.Millisecond, make(chan struct{}))
+	}()
+
+	select {
+	case <-resultCh:
+		t.Fatal("expected to wait for the file")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ok := <-resultCh:
+		if !ok {
+			t.Error("expected the file to open the gate")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	doneCh := make(chan struct{})
+	close(doneCh)
+	if waitForStartGateFile(filepath.Join(dir, "missing"), 10*time.Millisecond, doneCh) {
+		t.Error("expected a stopped wait not to open the gate")
+	}
+}
+
+func TestWaitForStartGateQuery(t *testing.T) {
+	t.Parallel()
+
+	// The condition is met on the third result, and every query must block on
+	// the index of the previous one.
+	var indexes []uint64
+	query := func(index uint64) (bool, uint64, error) {
+		indexes = append(indexes, index)
+		return len(indexes) == 3, uint64(len(indexes) * 10), nil
+	}
+
+	if !waitForStartGateQuery(query, "test", make(chan struct{})) {
+		t.Fatal("expected the query to open the gate")
+	}
+	if exp := []uint64{0, 10, 20}; fmt.Sprint(exp) != fmt.Sprint(indexes) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, indexes)
+	}
+
+	doneCh := make(chan struct{})
+	close(doneCh)
+	never := func(index uint64) (bool, uint64, error) {
+		return false, index + 1, nil
+	}
+	if waitForStartGateQuery(never, "test", doneCh) {
+		t.Error("expected a stopped wait not to open the gate")
+	}
+}
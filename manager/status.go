@@ -0,0 +1,434 @@
+package manager
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+const (
+	// statusHeartbeatInterval is the interval on which the runner's event loop
+	// records that it is making progress while the status server is enabled.
+	statusHeartbeatInterval = 5 * time.Second
+)
+
+// probeResult is the body returned by the readiness and liveness probes.
+// Failures is the list of criteria which are not met.
+type probeResult struct {
+	OK       bool     `json:"ok"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// dependencyStatus is the body returned for each dependency by the
+// dependencies endpoint. References is the number of templates which use the
+// dependency; the watcher is kept until it drops to zero.
+type dependencyStatus struct {
+	References int      `json:"references"`
+	Templates  []string `json:"templates"`
+}
+
+// watcherStatus is the body returned by the watcher endpoint. Pending is the
+// number of updates waiting to be processed, and Coalesced and Dropped count
+// the updates the backpressure policy coalesced or dropped. Fetches and
+// FetchErrors count the requests made for the dependencies and the ones which
+// failed.
+type watcherStatus struct {
+	Backpressure string `json:"backpressure"`
+	BufferSize   int    `json:"buffer_size"`
+	Pending      int    `json:"pending"`
+	Coalesced    uint64 `json:"coalesced"`
+	Dropped      uint64 `json:"dropped"`
+	Fetches      uint64 `json:"fetches"`
+	FetchErrors  uint64 `json:"fetch_errors"`
+}
+
+// runnerStatus is the body returned by the status endpoint. Rendered is true
+// once every template has rendered, and ChildPid is the pid of the child
+// process in exec mode, if it is running. ChildUsage is the latest resource
+// usage sample of the child process, if it is monitored.
+type runnerStatus struct {
+	Rendered   bool              `json:"rendered"`
+	ChildPid   int               `json:"child_pid,omitempty"`
+	ChildUsage *childUsageStatus `json:"child_usage,omitempty"`
+	Templates  []*templateStatus `json:"templates"`
+}
+
+// childUsageStatus is a resource usage sample of the child process in the
+// status endpoint.
+type childUsageStatus struct {
+	Pid        int       `json:"pid"`
+	CPUSeconds float64   `json:"cpu_seconds"`
+	CPUPercent float64   `json:"cpu_percent"`
+	RSSBytes   uint64    `json:"rss_bytes"`
+	OpenFDs    int       `json:"open_fds"`
+	SampledAt  time.Time `json:"sampled_at"`
+}
+
+// templateStatus is the status of a single template in the status endpoint.
+// Dependencies is the number of dependencies the template used the last time
+// it was executed, and Missing are the ones which had no data. Pid is the pid
+// of the supervised process of the template, if it is running.
+type templateStatus struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name,omitempty"`
+	Source       string   `json:"source"`
+	Destinations []string `json:"destinations"`
+
+	Rendered         bool       `json:"rendered"`
+	LastWouldRender  *time.Time `json:"last_would_render,omitempty"`
+	LastDidRender    *time.Time `json:"last_did_render,omitempty"`
+	WouldRenderCount uint64     `json:"would_render_count"`
+	DidRenderCount   uint64     `json:"did_render_count"`
+
+	Dependencies int      `json:"dependencies"`
+	Missing      []string `json:"missing,omitempty"`
+
+	LastError *time.Time `json:"last_error,omitempty"`
+	Error     string     `json:"error,omitempty"`
+
+	Pid int `json:"pid,omitempty"`
+}
+
+// templateStatusByID is a sortable list of template statuses by their ID.
+type templateStatusByID []*templateStatus
+
+func (s templateStatusByID) Len() int           { return len(s) }
+func (s templateStatusByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s templateStatusByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+// statusServer is the HTTP server which serves the readiness and liveness
+// probes of a runner.
+type statusServer struct {
+	config   *config.StatusConfig
+	runner   *Runner
+	listener net.Listener
+	server   *httpServer
+}
+
+// newStatusServer creates a new status server for the given runner.
+func newStatusServer(c *config.StatusConfig, r *Runner) *statusServer {
+	s := &statusServer{
+		config: c,
+		runner: r,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ready", s.handleProbe(s.ready))
+	mux.HandleFunc("/live", s.handleProbe(s.live))
+	mux.HandleFunc("/dependencies", s.handleDependencies)
+	mux.HandleFunc("/report", s.handleReport)
+	mux.HandleFunc("/watcher", s.handleWatcher)
+	mux.HandleFunc("/promote", s.handlePromote)
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/v1/health", s.handleProbe(s.health))
+
+	// The debug endpoints expose the internals of the process, so they are
+	// only served if enabled.
+	if config.BoolVal(c.Pprof) {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if config.BoolVal(c.Expvar) {
+		mux.HandleFunc("/debug/vars", handleExpvar)
+	}
+	s.server = newHTTPServer(mux)
+
+	return s
+}
+
+// Start begins listening on the configured address. Requests are served in the
+// background until Stop is called.
+func (s *statusServer) Start() error {
+	addr := config.StringVal(s.config.Address)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("status: failed to listen on %s: %s", addr, err)
+	}
+	s.listener = ln
+
+	log.Printf("[INFO] (status) listening on %s", ln.Addr())
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil {
+			log.Printf("[ERR] (status) server stopped: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes the listener and all active connections.
+func (s *statusServer) Stop() {
+	if s.listener == nil {
+		return
+	}
+
+	log.Printf("[INFO] (status) stopping")
+	s.server.Close()
+}
+
+// Addr returns the address the server is listening on, or nil if it has not
+// been started.
+func (s *statusServer) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// handleProbe returns a handler which responds with 200 if the given probe
+// reports no failures and 503 otherwise.
+func (s *statusServer) handleProbe(probe func() []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		failures := probe()
+		result := &probeResult{
+			OK:       len(failures) == 0,
+			Failures: failures,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !result.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("[WARN] (status) failed to write response: %s", err)
+		}
+	}
+}
+
+// handleDependencies responds with each dependency the runner is watching and
+// the templates which reference it.
+func (s *statusServer) handleDependencies(w http.ResponseWriter, req *http.Request) {
+	refs := s.runner.DependencyReferences()
+	result := make(map[string]*dependencyStatus, len(refs))
+	for key, templates := range refs {
+		result[key] = &dependencyStatus{
+			References: len(templates),
+			Templates:  templates,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		log.Printf("[WARN] (status) failed to write response: %s", err)
+	}
+}
+
+// handleReport responds with the report of the last run. It responds with a
+// 404 if reports are not enabled or no run has finished yet.
+func (s *statusServer) handleReport(w http.ResponseWriter, req *http.Request) {
+	report := s.runner.LastReport()
+	if report == nil {
+		http.Error(w, "no run report", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(report); err != nil {
+		log.Printf("[WARN] (status) failed to write response: %s", err)
+	}
+}
+
+// handleWatcher responds with the state of the buffer between the dependency
+// watches and the runner.
+func (s *statusServer) handleWatcher(w http.ResponseWriter, req *http.Request) {
+	result := &watcherStatus{
+		Backpressure: config.StringVal(s.runner.config.Watcher.Backpressure),
+		BufferSize:   config.IntVal(s.runner.config.Watcher.BufferSize),
+		Pending:      s.runner.watcher.Pending(),
+		Coalesced:    s.runner.watcher.Coalesced(),
+		Dropped:      s.runner.watcher.Dropped(),
+		Fetches:      s.runner.watcher.Fetches(),
+		FetchErrors:  s.runner.watcher.FetchErrors(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("[WARN] (status) failed to write response: %s", err)
+	}
+}
+
+// handleStatus responds with the state of every template of the runner and
+// the pids of the processes it runs.
+func (s *statusServer) handleStatus(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s.status()); err != nil {
+		log.Printf("[WARN] (status) failed to write response: %s", err)
+	}
+}
+
+// handlePromote promotes the runner from standby. It only accepts POST
+// requests, since it changes the state of the runner.
+func (s *statusServer) handlePromote(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.runner.Promote()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExpvar responds with the published expvar variables as a JSON object,
+// the way the handler which the expvar package registers does.
+func handleExpvar(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// ready returns the readiness criteria which are not met.
+func (s *statusServer) ready() []string {
+	var failures []string
+
+	if config.BoolVal(s.config.Ready.Templates) && !s.runner.allTemplatesRendered() {
+		failures = append(failures, "templates have not all rendered")
+	}
+
+	if config.BoolVal(s.config.Ready.Child) &&
+		config.StringPresent(s.runner.config.Exec.Command) && !s.runner.childRunning() {
+		failures = append(failures, "child process is not running")
+	}
+
+	if config.BoolVal(s.config.Ready.Commands) {
+		failures = append(failures, s.runner.commandsNotReady()...)
+	}
+
+	return failures
+}
+
+// health returns the failures of the health endpoint, which is healthy once
+// every template has rendered, regardless of the readiness criteria.
+func (s *statusServer) health() []string {
+	if !s.runner.allTemplatesRendered() {
+		return []string{"templates have not all rendered"}
+	}
+	return nil
+}
+
+// status returns the state of every template of the runner, sorted by ID.
+func (s *statusServer) status() *runnerStatus {
+	r := s.runner
+
+	r.dependenciesLock.Lock()
+	deps := make(map[string]int, len(r.templateDeps))
+	for id, d := range r.templateDeps {
+		deps[id] = len(d)
+	}
+	r.dependenciesLock.Unlock()
+
+	childPid, pids := r.childPid(), r.TemplateChildPids()
+
+	var usage *childUsageStatus
+	if u := r.ChildUsage(); u != nil {
+		usage = &childUsageStatus{
+			Pid:        u.Pid,
+			CPUSeconds: u.CPUTime.Seconds(),
+			CPUPercent: u.CPUPercent,
+			RSSBytes:   u.RSS,
+			OpenFDs:    u.OpenFDs,
+			SampledAt:  u.SampledAt,
+		}
+	}
+
+	r.renderEventsLock.RLock()
+	result := &runnerStatus{
+		Rendered:   true,
+		ChildPid:   childPid,
+		ChildUsage: usage,
+		Templates:  make([]*templateStatus, 0, len(r.templates)),
+	}
+	for _, tmpl := range r.templates {
+		ts := &templateStatus{
+			ID:           tmpl.ID(),
+			Name:         r.templateName(tmpl.ID()),
+			Source:       tmpl.Source(),
+			Destinations: []string{},
+			Dependencies: deps[tmpl.ID()],
+			Pid:          pids[tmpl.ID()],
+		}
+		for _, c := range r.ctemplatesMap[tmpl.ID()] {
+			ts.Destinations = append(ts.Destinations, config.StringVal(c.Destination))
+		}
+		if event, ok := r.renderEvents[tmpl.ID()]; ok {
+			ts.Rendered = !event.LastWouldRender.IsZero()
+			ts.LastWouldRender = timeOrNil(event.LastWouldRender)
+			ts.LastDidRender = timeOrNil(event.LastDidRender)
+			ts.WouldRenderCount = event.WouldRenderCount
+			ts.DidRenderCount = event.DidRenderCount
+			ts.Missing = event.MissingDependencies
+			ts.LastError = timeOrNil(event.LastError)
+			ts.Error = event.Error
+		}
+		if !ts.Rendered {
+			result.Rendered = false
+		}
+		result.Templates = append(result.Templates, ts)
+	}
+	r.renderEventsLock.RUnlock()
+
+	sort.Sort(templateStatusByID(result.Templates))
+	return result
+}
+
+// timeOrNil returns a pointer to the given time, or nil if it is zero, so
+// times which never happened are left out of the responses.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// live returns the liveness criteria which are not met.
+func (s *statusServer) live() []string {
+	var failures []string
+
+	lastLoop, failingSince := s.runner.health()
+	now := time.Now()
+
+	if timeout := config.TimeDurationVal(s.config.Live.LoopTimeout); timeout > 0 {
+		if since := now.Sub(lastLoop); since > timeout {
+			failures = append(failures,
+				fmt.Sprintf("event loop has not made progress in %s", since))
+		}
+	}
+
+	if timeout := config.TimeDurationVal(s.config.Live.WatcherTimeout); timeout > 0 {
+		if !failingSince.IsZero() {
+			if since := now.Sub(failingSince); since > timeout {
+				failures = append(failures,
+					fmt.Sprintf("watcher has been failing for %s", since))
+			}
+		}
+	}
+
+	return failures
+}
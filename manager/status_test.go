@@ -0,0 +1,529 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestStatusServer_ready(t *testing.T) {
+	t.Parallel()
+
+	out, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out.Name()),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream = ioutil.Discard
+	s := newStatusServer(c.Status, r)
+
+	exp := []string{"templates have not all rendered"}
+	if act := s.ready(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if act := s.ready(); act != nil {
+		t.Errorf("\nexp: %#v\nact: %#v", nil, act)
+	}
+
+	// Require a running child process when exec is configured.
+	r.config.Exec.Command = config.String("sleep 30")
+	exp = []string{"child process is not running"}
+	if act := s.ready(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	s.config.Ready.Child = config.Bool(false)
+	if act := s.ready(); act != nil {
+		t.Errorf("\nexp: %#v\nact: %#v", nil, act)
+	}
+}
+
+func TestStatusServer_live(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		lastLoop time.Duration
+		failing  time.Duration
+		exp      int
+	}{
+		{
+			"healthy",
+			0,
+			0,
+			0,
+		},
+		{
+			"stuck_loop",
+			2 * time.Minute,
+			0,
+			1,
+		},
+		{
+			"watcher_failing_briefly",
+			0,
+			time.Minute,
+			0,
+		},
+		{
+			"watcher_failing",
+			0,
+			10 * time.Minute,
+			1,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.TestConfig(nil)
+			r, err := NewRunner(c, true, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := newStatusServer(c.Status, r)
+
+			now := time.Now()
+			r.lastLoop = now.Add(-tc.lastLoop)
+			if tc.failing > 0 {
+				r.watcherFailingSince = now.Add(-tc.failing)
+			}
+
+			if act := s.live(); len(act) != tc.exp {
+				t.Errorf("\nexp: %d failures\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestStatusServer_handleProbe(t *testing.T) {
+	t.Parallel()
+
+	s := newStatusServer(config.DefaultStatusConfig(), nil)
+
+	cases := []struct {
+		name     string
+		failures []string
+		code     int
+		exp      *probeResult
+	}{
+		{
+			"ok",
+			nil,
+			http.StatusOK,
+			&probeResult{OK: true},
+		},
+		{
+			"failures",
+			[]string{"nope"},
+			http.StatusServiceUnavailable,
+			&probeResult{OK: false, Failures: []string{"nope"}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/ready", nil)
+			s.handleProbe(func() []string { return tc.failures })(w, req)
+
+			if w.Code != tc.code {
+				t.Errorf("\nexp: %d\nact: %d", tc.code, w.Code)
+			}
+
+			var act probeResult
+			if err := json.NewDecoder(w.Body).Decode(&act); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.exp, &act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, &act)
+			}
+		})
+	}
+}
+
+func TestStatusServer_debug(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		pprof  bool
+		expvar bool
+	}{
+		{"disabled", false, false},
+		{"pprof", true, false},
+		{"expvar", false, true},
+	}
+
+	paths := []struct {
+		path   string
+		pprof  bool
+		expvar bool
+		body   string
+	}{
+		{"/debug/pprof/", true, false, "goroutine"},
+		{"/debug/pprof/heap?debug=1", true, false, "heap profile"},
+		{"/debug/pprof/goroutine?debug=1", true, false, "goroutine profile"},
+		{"/debug/vars", false, true, "memstats"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.DefaultStatusConfig()
+			c.Pprof = config.Bool(tc.pprof)
+			c.Expvar = config.Bool(tc.expvar)
+			c.Finalize()
+			s := newStatusServer(c, nil)
+
+			for _, p := range paths {
+				w := httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", p.path, nil))
+
+				enabled := (p.pprof && tc.pprof) || (p.expvar && tc.expvar)
+				if !enabled {
+					if w.Code != http.StatusNotFound {
+						t.Errorf("%s: expected %d, got %d", p.path, http.StatusNotFound, w.Code)
+					}
+					continue
+				}
+				if w.Code != http.StatusOK {
+					t.Errorf("%s: expected %d, got %d", p.path, http.StatusOK, w.Code)
+				}
+				if !strings.Contains(w.Body.String(), p.body) {
+					t.Errorf("%s: expected %q in %q", p.path, p.body, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestStatusServer_handleDependencies(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	s := newStatusServer(c.Status, r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/dependencies", nil)
+	s.handleDependencies(w, req)
+
+	var act map[string]*dependencyStatus
+	if err := json.NewDecoder(w.Body).Decode(&act); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]*dependencyStatus{
+		"kv.block(foo)": &dependencyStatus{
+			References: 1,
+			Templates:  []string{`"(dynamic)" => "/tmp/a"`},
+		},
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
+
+func TestStatusServer_handleWatcher(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Watcher: &config.WatcherConfig{
+			Backpressure: config.String(config.WatcherBackpressureDrop),
+			BufferSize:   config.Int(16),
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	s := newStatusServer(c.Status, r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/watcher", nil)
+	s.handleWatcher(w, req)
+
+	var act watcherStatus
+	if err := json.NewDecoder(w.Body).Decode(&act); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := watcherStatus{
+		Backpressure: config.WatcherBackpressureDrop,
+		BufferSize:   16,
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
+
+func TestStatusServer_handlePromote(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Standby: &config.StandbyConfig{
+			Enabled: config.Bool(true),
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	s := newStatusServer(c.Status, r)
+
+	w := httptest.NewRecorder()
+	s.handlePromote(w, httptest.NewRequest("GET", "/promote", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if r.Promoted() {
+		t.Fatal("expected GET not to promote")
+	}
+
+	w = httptest.NewRecorder()
+	s.handlePromote(w, httptest.NewRequest("POST", "/promote", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if !r.Promoted() {
+		t.Fatal("expected POST to promote")
+	}
+}
+
+func TestRunner_Start_status(t *testing.T) {
+	t.Parallel()
+
+	out, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+
+	c := config.TestConfig(&config.Config{
+		Status: &config.StatusConfig{
+			Address: config.String("127.0.0.1:0"),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out.Name()),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go r.Start()
+	defer r.Stop()
+
+	select {
+	case err := <-r.ErrCh:
+		t.Fatal(err)
+	case <-r.renderedCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	for _, path := range []string{"/ready", "/live"} {
+		resp, err := http.Get(fmt.Sprintf("http://%s%s", r.status.Addr(), path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestStatusServer_handleStatus(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+				Name:        config.String("a"),
+			},
+			&config.TemplateConfig{
+				Contents:    config.String(`static`),
+				Destination: config.String("/tmp/b"),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	r.outStream = ioutil.Discard
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	s := newStatusServer(c.Status, r)
+
+	status := func() (*runnerStatus, map[string]*templateStatus) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/v1/status", nil)
+		s.handleStatus(w, req)
+
+		var act runnerStatus
+		if err := json.NewDecoder(w.Body).Decode(&act); err != nil {
+			t.Fatal(err)
+		}
+		byDest := make(map[string]*templateStatus, len(act.Templates))
+		for _, ts := range act.Templates {
+			byDest[ts.Destinations[0]] = ts
+		}
+		return &act, byDest
+	}
+
+	act, templates := status()
+	if act.Rendered {
+		t.Error("expected the templates not to have all rendered")
+	}
+	if len(templates) != 2 {
+		t.Fatalf("expected 2 templates, got %#v", act.Templates)
+	}
+	a, b := templates["/tmp/a"], templates["/tmp/b"]
+	if a.Name != "a" || a.Rendered || a.Dependencies != 1 ||
+		!reflect.DeepEqual([]string{"kv.block(foo)"}, a.Missing) {
+		t.Errorf("unexpected status of a: %#v", a)
+	}
+	if !b.Rendered || b.LastWouldRender == nil || b.WouldRenderCount != 1 || b.Dependencies != 0 {
+		t.Errorf("unexpected status of b: %#v", b)
+	}
+
+	r.Receive(r.dependencies["kv.block(foo)"], "bar")
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if act, templates = status(); !act.Rendered || !templates["/tmp/a"].Rendered {
+		t.Errorf("expected the templates to have rendered: %#v", act)
+	}
+	if act.ChildUsage != nil {
+		t.Errorf("expected no child usage, got %#v", act.ChildUsage)
+	}
+
+	sampledAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	r.childLock.Lock()
+	r.childMonitor = &childMonitor{usage: &child.Usage{
+		Pid:        1234,
+		CPUTime:    1500 * time.Millisecond,
+		CPUPercent: 12.5,
+		RSS:        64 << 20,
+		OpenFDs:    17,
+		SampledAt:  sampledAt,
+	}}
+	r.childLock.Unlock()
+
+	act, _ = status()
+	exp := &childUsageStatus{
+		Pid:        1234,
+		CPUSeconds: 1.5,
+		CPUPercent: 12.5,
+		RSSBytes:   64 << 20,
+		OpenFDs:    17,
+		SampledAt:  sampledAt,
+	}
+	if !reflect.DeepEqual(exp, act.ChildUsage) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act.ChildUsage)
+	}
+}
+
+func TestStatusServer_health(t *testing.T) {
+	t.Parallel()
+
+	c := config.TestConfig(&config.Config{
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String(`{{ key "foo" }}`),
+				Destination: config.String("/tmp/a"),
+			},
+		},
+	})
+	c.Status.Ready.Child = config.Bool(true)
+	c.Exec.Command = config.String("sleep 30")
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	r.outStream = ioutil.Discard
+	s := newStatusServer(c.Status, r)
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if act := s.health(); len(act) != 1 {
+		t.Errorf("expected the health check to fail, got %#v", act)
+	}
+
+	// The health check only waits for the templates, not for the child.
+	r.Receive(r.dependencies["kv.block(foo)"], "bar")
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if act := s.health(); len(act) != 0 {
+		t.Errorf("expected the health check to pass, got %#v", act)
+	}
+	if act := s.ready(); len(act) != 1 {
+		t.Errorf("expected the readiness check to fail, got %#v", act)
+	}
+}
@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// symlinkVersionLayout is the layout of the time in the names of the
+	// versioned files of the symlink render strategy. Names sort in the order
+	// the versions were rendered.
+	symlinkVersionLayout = "20060102T150405.000000000Z"
+)
+
+// renderSymlink writes the contents to a new versioned file next to the
+// destination, named after the destination and the current time, and then
+// atomically points the destination, a symlink, to it. Only the newest
+// Versions files are kept. A destination which is a regular file, such as one
+// rendered before the symlink strategy was enabled, is kept as a version so it
+// can be rolled back to.
+func renderSymlink(i *RenderInput) error {
+	path := longPath(i.Path)
+	version := path + "." + time.Now().UTC().Format(symlinkVersionLayout)
+
+	// Versions are new files, so the mode of the current version is kept
+	// explicitly.
+	perms := i.Perms
+	if i.PreservePerms {
+		if stat, err := os.Stat(path); err == nil {
+			perms = stat.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		}
+	}
+
+	if err := atomicWrite(version, i.output(), perms, i.ACL, false, false); err != nil {
+		return err
+	}
+
+	if stat, err := os.Lstat(path); err == nil && stat.Mode().IsRegular() {
+		prev := path + "." + stat.ModTime().UTC().Format(symlinkVersionLayout)
+		if err := copyFile(path, prev); err != nil {
+			os.Remove(version)
+			return errors.Wrap(err, "failed keeping the existing destination")
+		}
+	}
+
+	if err := replaceSymlink(filepath.Base(version), path); err != nil {
+		os.Remove(version)
+		return err
+	}
+
+	return pruneSymlinkVersions(path, i.Versions)
+}
+
+// symlinkVersions returns the paths of the versioned files of the given
+// destination, from the oldest to the newest.
+func symlinkVersions(path string) ([]string, error) {
+	dir, base := filepath.Dir(path), filepath.Base(path)+"."
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || !entry.Mode().IsRegular() {
+			continue
+		}
+		if _, err := time.Parse(symlinkVersionLayout, strings.TrimPrefix(name, base)); err != nil {
+			continue
+		}
+		versions = append(versions, filepath.Join(dir, name))
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// pruneSymlinkVersions removes the oldest versioned files of the given
+// destination until only keep are left. The version the destination points to
+// is never removed.
+func pruneSymlinkVersions(path string, keep int) error {
+	if keep < 1 {
+		keep = 1
+	}
+
+	versions, err := symlinkVersions(path)
+	if err != nil {
+		return errors.Wrap(err, "failed listing versions")
+	}
+
+	current, _ := os.Readlink(path)
+	for len(versions) > keep {
+		old := versions[0]
+		versions = versions[1:]
+		if filepath.Base(old) == current {
+			continue
+		}
+		if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed removing old version")
+		}
+	}
+	return nil
+}
+
+// removeSymlink removes the given destination of the symlink render strategy
+// along with all of its versioned files.
+func removeSymlink(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	versions, err := symlinkVersions(path)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if err := os.Remove(version); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
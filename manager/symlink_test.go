@@ -0,0 +1,119 @@
+// +build !windows
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRender_symlink(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	// A destination rendered before the symlink strategy was enabled is kept
+	// as a version.
+	path := filepath.Join(outDir, "out")
+	if err := ioutil.WriteFile(path, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		contents := []byte(fmt.Sprintf("contents %d", i))
+		result, err := Render(&RenderInput{
+			Contents: contents,
+			Path:     path,
+			Perms:    0640,
+			Strategy: config.TemplateRenderStrategySymlink,
+			Versions: 3,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.DidRender {
+			t.Fatal("expected the template to render")
+		}
+
+		act, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(act) != string(contents) {
+			t.Errorf("\nexp: %q\nact: %q", contents, act)
+		}
+	}
+
+	stat, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected %s to be a symlink, got %s", path, stat.Mode())
+	}
+
+	versions, err := symlinkVersions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %q", versions)
+	}
+
+	// The destination points to the newest version, and the oldest ones,
+	// including the kept destination, were removed.
+	target, err := os.Readlink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := filepath.Base(versions[2]); target != exp {
+		t.Errorf("expected the destination to point to %s, got %s", exp, target)
+	}
+	first, err := ioutil.ReadFile(versions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != "contents 1" {
+		t.Errorf("expected the oldest version to be kept, got %q", first)
+	}
+	info, err := os.Stat(versions[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640, got %s", info.Mode())
+	}
+
+	// Unchanged contents do not create a new version.
+	result, err := Render(&RenderInput{
+		Contents: []byte("contents 3"),
+		Path:     path,
+		Perms:    0640,
+		Strategy: config.TemplateRenderStrategySymlink,
+		Versions: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DidRender {
+		t.Error("expected unchanged contents not to render")
+	}
+
+	if err := removeSymlink(path); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected all versions to be removed, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// systemdUnitPath returns true if the given destination is in one of the unit
+// directories of the configuration, or in one of their subdirectories, such
+// as the directory of the drop-ins of a unit.
+func systemdUnitPath(c *config.SystemdConfig, path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	for _, dir := range c.UnitDirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// daemonReloadTemplate returns the template configuration which runs the
+// daemon-reload command after the given template rendered a unit. It runs like
+// the command of a template, and is displayed as the given template in logs
+// and reports.
+func daemonReloadTemplate(c *config.SystemdConfig, tc *config.TemplateConfig) *config.TemplateConfig {
+	t := &config.TemplateConfig{
+		Command:     c.DaemonReloadCommand,
+		Contents:    tc.Contents,
+		Destination: tc.Destination,
+		LogTag:      tc.LogTag,
+		Name:        tc.Name,
+		Source:      tc.Source,
+	}
+	t.Finalize()
+	return t
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestSystemdUnitPath(t *testing.T) {
+	c := config.DefaultSystemdConfig()
+	c.Finalize()
+
+	cases := []struct {
+		name string
+		path string
+		exp  bool
+	}{
+		{"unit", "/etc/systemd/system/app.service", true},
+		{"drop_in", "/etc/systemd/system/app.service.d/override.conf", true},
+		{"runtime_unit", "/run/systemd/system/app.service", true},
+		{"parent", "/etc/systemd/system/../app.conf", false},
+		{"prefix", "/etc/systemd/system-app.conf", false},
+		{"dir", "/etc/systemd/system", false},
+		{"other", "/etc/app.conf", false},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := systemdUnitPath(c, tc.path); act != tc.exp {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+// renderDurationBuckets are the upper bounds, in seconds, of the buckets of
+// the render duration histogram.
+var renderDurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// labelEscaper escapes the values of Prometheus labels.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// runnerMetrics are the metrics the runner collects for the telemetry server
+// which are not already tracked elsewhere.
+type runnerMetrics struct {
+	sync.Mutex
+
+	// renders is the number of times each template was written to disk.
+	renders map[string]uint64
+
+	// renderBuckets, renderSum and renderCount are the histogram of the time
+	// it took to execute and render templates.
+	renderBuckets []uint64
+	renderSum     float64
+	renderCount   uint64
+
+	// childRestarts is the number of times the child process was restarted.
+	childRestarts uint64
+}
+
+// newRunnerMetrics creates a new, empty set of metrics.
+func newRunnerMetrics() *runnerMetrics {
+	return &runnerMetrics{
+		renders:       make(map[string]uint64),
+		renderBuckets: make([]uint64, len(renderDurationBuckets)),
+	}
+}
+
+// observeRender records that a template took the given time to execute and
+// render, and whether it was written to disk.
+func (m *runnerMetrics) observeRender(tmplID string, d time.Duration, didRender bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	if didRender {
+		m.renders[tmplID]++
+	}
+
+	seconds := d.Seconds()
+	for i, bound := range renderDurationBuckets {
+		if seconds <= bound {
+			m.renderBuckets[i]++
+		}
+	}
+	m.renderSum += seconds
+	m.renderCount++
+}
+
+// childRestarted records that the child process was restarted.
+func (m *runnerMetrics) childRestarted() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.childRestarts++
+}
+
+// metricsRunner is a runner whose metrics are exported. Name labels its
+// metrics when the metrics of the runners of a MultiRunner are exported
+// together, and is empty otherwise.
+type metricsRunner struct {
+	name   string
+	runner *Runner
+}
+
+// labels returns the given labels of a metric of the runner in braces, with
+// the runner label first, if any.
+func (m *metricsRunner) labels(labels string) string {
+	if m.name != "" {
+		runner := fmt.Sprintf("runner=\"%s\"", labelEscaper.Replace(m.name))
+		if labels == "" {
+			labels = runner
+		} else {
+			labels = runner + "," + labels
+		}
+	}
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+// telemetryServer is the HTTP server which exports the metrics of one or more
+// runners in the Prometheus text format.
+type telemetryServer struct {
+	config   *config.TelemetryConfig
+	runners  []*metricsRunner
+	listener net.Listener
+	server   *httpServer
+}
+
+// newTelemetryServer creates a new telemetry server for the given runner.
+func newTelemetryServer(c *config.TelemetryConfig, r *Runner) *telemetryServer {
+	return newSharedTelemetryServer(c, []*metricsRunner{{runner: r}})
+}
+
+// newSharedTelemetryServer creates a new telemetry server which exports the
+// metrics of all the given runners.
+func newSharedTelemetryServer(c *config.TelemetryConfig, runners []*metricsRunner) *telemetryServer {
+	s := &telemetryServer{
+		config:  c,
+		runners: runners,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	s.server = newHTTPServer(mux)
+
+	return s
+}
+
+// Start begins listening on the configured port. Requests are served in the
+// background until Stop is called.
+func (s *telemetryServer) Start() error {
+	addr := net.JoinHostPort("", strconv.Itoa(config.IntVal(s.config.PrometheusPort)))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("telemetry: failed to listen on %s: %s", addr, err)
+	}
+	s.listener = ln
+
+	log.Printf("[INFO] (telemetry) serving metrics on %s", ln.Addr())
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil {
+			log.Printf("[ERR] (telemetry) server stopped: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes the listener and all active connections.
+func (s *telemetryServer) Stop() {
+	if s.listener == nil {
+		return
+	}
+
+	log.Printf("[INFO] (telemetry) stopping")
+	s.server.Close()
+}
+
+// Addr returns the address the server is listening on, or nil if it has not
+// been started.
+func (s *telemetryServer) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// handleMetrics responds with the metrics of the runners.
+func (s *telemetryServer) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	buf := bufio.NewWriter(w)
+	writeMetrics(buf, s.runners)
+	if err := buf.Flush(); err != nil {
+		log.Printf("[WARN] (telemetry) failed to write response: %s", err)
+	}
+}
+
+// metricsSnapshot is a copy of the metrics of a runner, so the lock is not
+// held while they are written.
+type metricsSnapshot struct {
+	renders       map[string]uint64
+	buckets       []uint64
+	renderSum     float64
+	renderCount   uint64
+	childRestarts uint64
+}
+
+// snapshot returns a copy of the metrics.
+func (m *runnerMetrics) snapshot() *metricsSnapshot {
+	m.Lock()
+	defer m.Unlock()
+
+	renders := make(map[string]uint64, len(m.renders))
+	for id, n := range m.renders {
+		renders[id] = n
+	}
+	return &metricsSnapshot{
+		renders:       renders,
+		buckets:       append([]uint64{}, m.renderBuckets...),
+		renderSum:     m.renderSum,
+		renderCount:   m.renderCount,
+		childRestarts: m.childRestarts,
+	}
+}
+
+// writeMetrics writes the metrics of the given runners in the Prometheus text
+// format. Each metric is written once, with a sample for every runner.
+func writeMetrics(w *bufio.Writer, runners []*metricsRunner) {
+	snapshots := make([]*metricsSnapshot, len(runners))
+	for i, m := range runners {
+		snapshots[i] = m.runner.metrics.snapshot()
+	}
+
+	metricHeader(w, "consul_template_renders_total", "counter",
+		"Number of times each template was written to disk.")
+	for i, m := range runners {
+		r := m.runner
+		r.renderEventsLock.RLock()
+		for _, tmpl := range r.templates {
+			fmt.Fprintf(w, "consul_template_renders_total%s %d\n",
+				m.labels(r.templateLabels(tmpl.ID())), snapshots[i].renders[tmpl.ID()])
+		}
+		r.renderEventsLock.RUnlock()
+	}
+
+	metricHeader(w, "consul_template_last_render_timestamp_seconds", "gauge",
+		"Time each template was last written to disk, in seconds since the epoch.")
+	for _, m := range runners {
+		r := m.runner
+		r.renderEventsLock.RLock()
+		for _, tmpl := range r.templates {
+			if event, ok := r.renderEvents[tmpl.ID()]; ok && !event.LastDidRender.IsZero() {
+				fmt.Fprintf(w, "consul_template_last_render_timestamp_seconds%s %s\n",
+					m.labels(r.templateLabels(tmpl.ID())), formatFloat(float64(event.LastDidRender.UnixNano())/1e9))
+			}
+		}
+		r.renderEventsLock.RUnlock()
+	}
+
+	metricHeader(w, "consul_template_render_duration_seconds", "histogram",
+		"Time it took to execute and render templates.")
+	for i, m := range runners {
+		snap := snapshots[i]
+		for j, bound := range renderDurationBuckets {
+			fmt.Fprintf(w, "consul_template_render_duration_seconds_bucket%s %d\n",
+				m.labels(fmt.Sprintf("le=\"%s\"", formatFloat(bound))), snap.buckets[j])
+		}
+		fmt.Fprintf(w, "consul_template_render_duration_seconds_bucket%s %d\n",
+			m.labels(`le="+Inf"`), snap.renderCount)
+		fmt.Fprintf(w, "consul_template_render_duration_seconds_sum%s %s\n",
+			m.labels(""), formatFloat(snap.renderSum))
+		fmt.Fprintf(w, "consul_template_render_duration_seconds_count%s %d\n",
+			m.labels(""), snap.renderCount)
+	}
+
+	metricHeader(w, "consul_template_dependency_fetches_total", "counter",
+		"Number of requests made for the dependencies.")
+	for _, m := range runners {
+		fmt.Fprintf(w, "consul_template_dependency_fetches_total%s %d\n",
+			m.labels(""), m.runner.watcher.Fetches())
+	}
+
+	metricHeader(w, "consul_template_dependency_fetch_errors_total", "counter",
+		"Number of requests made for the dependencies which failed.")
+	for _, m := range runners {
+		fmt.Fprintf(w, "consul_template_dependency_fetch_errors_total%s %d\n",
+			m.labels(""), m.runner.watcher.FetchErrors())
+	}
+
+	metricHeader(w, "consul_template_watcher_views", "gauge",
+		"Number of dependencies being watched.")
+	for _, m := range runners {
+		fmt.Fprintf(w, "consul_template_watcher_views%s %d\n",
+			m.labels(""), m.runner.watcher.Size())
+	}
+
+	metricHeader(w, "consul_template_child_restarts_total", "counter",
+		"Number of times the child process was restarted.")
+	for i, m := range runners {
+		fmt.Fprintf(w, "consul_template_child_restarts_total%s %d\n",
+			m.labels(""), snapshots[i].childRestarts)
+	}
+
+	// The usage of the child process is only known while it is monitored.
+	usages := make([]*child.Usage, len(runners))
+	for i, m := range runners {
+		usages[i] = m.runner.ChildUsage()
+	}
+
+	metricHeader(w, "consul_template_child_cpu_seconds", "gauge",
+		"Total user and system CPU time consumed by the child process.")
+	for i, m := range runners {
+		if u := usages[i]; u != nil {
+			fmt.Fprintf(w, "consul_template_child_cpu_seconds%s %s\n",
+				m.labels(""), formatFloat(u.CPUTime.Seconds()))
+		}
+	}
+
+	metricHeader(w, "consul_template_child_rss_bytes", "gauge",
+		"Resident set size of the child process.")
+	for i, m := range runners {
+		if u := usages[i]; u != nil {
+			fmt.Fprintf(w, "consul_template_child_rss_bytes%s %d\n", m.labels(""), u.RSS)
+		}
+	}
+
+	metricHeader(w, "consul_template_child_open_fds", "gauge",
+		"Number of file descriptors the child process has open.")
+	for i, m := range runners {
+		if u := usages[i]; u != nil {
+			fmt.Fprintf(w, "consul_template_child_open_fds%s %d\n", m.labels(""), u.OpenFDs)
+		}
+	}
+}
+
+// templateLabels returns the labels of the metrics of the template with the
+// given ID.
+func (r *Runner) templateLabels(tmplID string) string {
+	return fmt.Sprintf("template=\"%s\",name=\"%s\"",
+		labelEscaper.Replace(tmplID), labelEscaper.Replace(r.templateName(tmplID)))
+}
+
+// metricHeader writes the help and type lines of the given metric.
+func metricHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+// formatFloat formats the given value for the Prometheus text format.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestTelemetryServer_metrics(t *testing.T) {
+	t.Parallel()
+
+	out, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+
+	c := config.TestConfig(&config.Config{
+		Telemetry: &config.TelemetryConfig{
+			PrometheusPort: config.Int(9311),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:    config.String("test"),
+				Destination: config.String(out.Name()),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.outStream = ioutil.Discard
+	if r.telemetry == nil {
+		t.Fatal("expected the telemetry server to be enabled")
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	r.childEvent(&ChildEvent{Type: ChildEventRestarted})
+
+	rec := httptest.NewRecorder()
+	r.telemetry.handleMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	id := (*r.templates[0]).ID()
+	for _, exp := range []string{
+		`consul_template_renders_total{template="` + id + `",name=`,
+		"consul_template_last_render_timestamp_seconds{",
+		`consul_template_render_duration_seconds_bucket{le="+Inf"} 1`,
+		"consul_template_render_duration_seconds_count 1",
+		"consul_template_dependency_fetches_total 0",
+		"consul_template_dependency_fetch_errors_total 0",
+		"consul_template_watcher_views 0",
+		"consul_template_child_restarts_total 1",
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("expected %q in:\n%s", exp, body)
+		}
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "consul_template_renders_total{") && !strings.HasSuffix(line, " 1") {
+			t.Errorf("expected the template to have rendered once, got %q", line)
+		}
+	}
+
+	if strings.Contains(body, "\nconsul_template_child_rss_bytes ") {
+		t.Errorf("expected no child usage without a sample:\n%s", body)
+	}
+
+	r.childLock.Lock()
+	r.childMonitor = &childMonitor{usage: &child.Usage{
+		CPUTime: 2500 * time.Millisecond,
+		RSS:     64 << 20,
+		OpenFDs: 17,
+	}}
+	r.childLock.Unlock()
+
+	rec = httptest.NewRecorder()
+	r.telemetry.handleMetrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body = rec.Body.String()
+	for _, exp := range []string{
+		"consul_template_child_cpu_seconds 2.5\n",
+		"consul_template_child_rss_bytes 67108864\n",
+		"consul_template_child_open_fds 17\n",
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("expected %q in:\n%s", exp, body)
+		}
+	}
+}
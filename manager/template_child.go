@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+	"github.com/pkg/errors"
+)
+
+// superviseTemplate starts the supervised process of the given template
+// configuration of the template with the given ID, or reloads it if it is
+// already running. A process without a reload signal is restarted instead.
+func (r *Runner) superviseTemplate(tmplID string, t *config.TemplateConfig, changed []string) error {
+	r.templateChildrenLock.Lock()
+	defer r.templateChildrenLock.Unlock()
+
+	command := config.StringVal(t.Exec.Command)
+
+	if c, ok := r.templateChildren[tmplID]; ok {
+		if config.SignalVal(t.Exec.ReloadSignal) != nil {
+			templateLogf(t, "INFO", "reloading supervised process %q from %s", command, t.Display())
+			if err := c.Reload(); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("failed to reload supervised process %q from %s",
+					command, t.Display()))
+			}
+			return nil
+		}
+
+		templateLogf(t, "INFO", "restarting supervised process %q from %s", command, t.Display())
+		if err := c.Restart(); err != nil {
+			delete(r.templateChildren, tmplID)
+			return errors.Wrap(err, fmt.Sprintf("failed to restart supervised process %q from %s",
+				command, t.Display()))
+		}
+		go r.watchTemplateChild(tmplID, t, c)
+		return nil
+	}
+
+	templateLogf(t, "INFO", "starting supervised process %q from %s", command, t.Display())
+	env := t.Exec.Env.Copy()
+	custom := append(r.childEnv(), changedDepsEnv(changed))
+	env.Custom = append(custom, env.Custom...)
+
+	// Supervised processes run until they are stopped, so the timeout of the
+	// exec block does not apply.
+	c, err := spawnChild(&spawnChildInput{
+		Stdin:        r.inStream,
+		Stdout:       r.outStream,
+		Stderr:       r.errStream,
+		Command:      command,
+		Env:          env.Env(),
+		ReloadSignal: config.SignalVal(t.Exec.ReloadSignal),
+		KillSignal:   config.SignalVal(t.Exec.KillSignal),
+		KillTimeout:  config.TimeDurationVal(t.Exec.KillTimeout),
+		Splay:        config.TimeDurationVal(t.Exec.Splay),
+	})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to start supervised process %q from %s",
+			command, t.Display()))
+	}
+
+	r.templateChildren[tmplID] = c
+	go r.watchTemplateChild(tmplID, t, c)
+	return nil
+}
+
+// watchTemplateChild waits for the current process of the given supervised
+// child to exit, and reports its exit status on ErrCh. Processes which were
+// restarted or stopped by the runner are not reported.
+func (r *Runner) watchTemplateChild(tmplID string, t *config.TemplateConfig, c *child.Child) {
+	exitCh := c.ExitCh()
+
+	var code int
+	select {
+	case code = <-exitCh:
+	case <-r.DoneCh:
+		return
+	}
+
+	r.templateChildrenLock.Lock()
+	current := r.templateChildren[tmplID] == c && c.ExitCh() == exitCh
+	if current {
+		delete(r.templateChildren, tmplID)
+	}
+	r.templateChildrenLock.Unlock()
+
+	if !current {
+		return
+	}
+
+	command := config.StringVal(t.Exec.Command)
+	log.Printf("[INFO] (runner) supervised process %q from %s died", command, t.Display())
+
+	select {
+	case r.ErrCh <- NewErrTemplateChildDied(tmplID, command, code):
+	case <-r.DoneCh:
+	}
+}
+
+// templateChildRunning returns true if the template with the given ID has a
+// running supervised process.
+func (r *Runner) templateChildRunning(tmplID string) bool {
+	r.templateChildrenLock.Lock()
+	defer r.templateChildrenLock.Unlock()
+
+	_, ok := r.templateChildren[tmplID]
+	return ok
+}
+
+// TemplateChildPids returns the pid of the supervised process of each template
+// which has one running, keyed by the template ID.
+func (r *Runner) TemplateChildPids() map[string]int {
+	r.templateChildrenLock.Lock()
+	defer r.templateChildrenLock.Unlock()
+
+	pids := make(map[string]int, len(r.templateChildren))
+	for id, c := range r.templateChildren {
+		pids[id] = c.Pid()
+	}
+	return pids
+}
+
+func (r *Runner) stopTemplateChildren() {
+	r.templateChildrenLock.Lock()
+	defer r.templateChildrenLock.Unlock()
+
+	for id, c := range r.templateChildren {
+		log.Printf("[DEBUG] (runner) stopping supervised process of %s", r.templateLabel(id))
+		c.Stop()
+	}
+}
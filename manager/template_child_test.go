@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_superviseTemplate(t *testing.T) {
+	t.Parallel()
+
+	newRunner := func(t *testing.T, dir, command string) *Runner {
+		src := filepath.Join(dir, "src")
+		if err := ioutil.WriteFile(src, []byte("one"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := config.TestConfig(&config.Config{
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`{{ file "` + src + `" }}`),
+					Destination: config.String(filepath.Join(dir, "out")),
+					Exec: &config.ExecConfig{
+						Command:   config.String(command),
+						Supervise: config.Bool(true),
+					},
+				},
+			},
+		})
+
+		r, err := NewRunner(c, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.outStream, r.errStream = ioutil.Discard, ioutil.Discard
+		return r
+	}
+
+	waitFor := func(t *testing.T, r *Runner, cond func() bool) {
+		for i := 0; !cond(); i++ {
+			select {
+			case err := <-r.ErrCh:
+				t.Fatal(err)
+			default:
+			}
+			if i == 250 {
+				t.Fatal("timeout")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		log := filepath.Join(dir, "log")
+		r := newRunner(t, dir, fmt.Sprintf(
+			`sh -c "trap 'echo reloaded >> %s' HUP; while true; do sleep 0.05; done"`, log))
+		go r.Start()
+		defer r.Stop()
+
+		var pid int
+		waitFor(t, r, func() bool {
+			for _, p := range r.TemplateChildPids() {
+				pid = p
+			}
+			return pid != 0
+		})
+
+		// Give the shell time to install its trap before the template changes.
+		time.Sleep(200 * time.Millisecond)
+		if err := ioutil.WriteFile(filepath.Join(dir, "src"), []byte("two"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		waitFor(t, r, func() bool {
+			b, _ := ioutil.ReadFile(log)
+			return strings.Contains(string(b), "reloaded")
+		})
+
+		for _, p := range r.TemplateChildPids() {
+			if p != pid {
+				t.Errorf("expected the process %d to be reloaded, got %d", pid, p)
+			}
+		}
+	})
+
+	t.Run("died", func(t *testing.T) {
+		t.Parallel()
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		r := newRunner(t, dir, `sh -c "sleep 0.2; exit 4"`)
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			died, ok := err.(*ErrTemplateChildDied)
+			if !ok {
+				t.Fatalf("expected the supervised process to die, got %v", err)
+			}
+			if died.ExitStatus() != 4 {
+				t.Errorf("expected exit code 4, got %d", died.ExitStatus())
+			}
+			if died.TemplateID == "" {
+				t.Error("expected the template ID")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestNewRunner_superviseTemplate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		templates *config.TemplateConfigs
+		err       string
+	}{
+		{
+			"no_command",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents: config.String("a"),
+					Exec:     &config.ExecConfig{Supervise: config.Bool(true)},
+				},
+			},
+			"supervise requires a command",
+		},
+		{
+			"same_contents",
+			&config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String("a"),
+					Destination: config.String("/tmp/a"),
+					Exec: &config.ExecConfig{
+						Command:   config.String("app"),
+						Supervise: config.Bool(true),
+					},
+				},
+				&config.TemplateConfig{
+					Contents:    config.String("a"),
+					Destination: config.String("/tmp/b"),
+					Exec: &config.ExecConfig{
+						Command:   config.String("app"),
+						Supervise: config.Bool(true),
+					},
+				},
+			},
+			"only one template with the same contents",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c := config.TestConfig(&config.Config{Templates: tc.templates})
+			_, err := NewRunner(c, true, false)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+// filterTemplates returns the templates whose ID or destination matches the
+// given glob. It is an error if the glob is malformed or if it matches no
+// templates, since a runner with nothing to render is almost certainly
+// misconfigured.
+func filterTemplates(configs config.TemplateConfigs, pattern string) (config.TemplateConfigs, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("template filter %q: %s", pattern, err)
+	}
+
+	filtered := make(config.TemplateConfigs, 0, len(configs))
+	for _, c := range configs {
+		if templateMatches(c, pattern) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("template filter %q matches no templates", pattern)
+	}
+	return filtered, nil
+}
+
+// templateMatches returns true if the ID or the destination of the template
+// matches the given glob.
+func templateMatches(c *config.TemplateConfig, pattern string) bool {
+	for _, name := range []string{config.StringVal(c.ID), config.StringVal(c.Destination)} {
+		if name == "" {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestFilterTemplates(t *testing.T) {
+	t.Parallel()
+
+	nginx := &config.TemplateConfig{
+		ID:          config.String("nginx"),
+		Destination: config.String("/etc/nginx/nginx.conf"),
+	}
+	upstreams := &config.TemplateConfig{
+		Destination: config.String("/etc/nginx/upstreams.conf"),
+	}
+	haproxy := &config.TemplateConfig{
+		ID:          config.String("haproxy"),
+		Destination: config.String("/etc/haproxy/haproxy.cfg"),
+	}
+	configs := config.TemplateConfigs{nginx, upstreams, haproxy}
+
+	cases := []struct {
+		name    string
+		pattern string
+		exp     config.TemplateConfigs
+		err     bool
+	}{
+		{
+			"id",
+			"haproxy",
+			config.TemplateConfigs{haproxy},
+			false,
+		},
+		{
+			"id_glob",
+			"ngin?",
+			config.TemplateConfigs{nginx},
+			false,
+		},
+		{
+			"destination_glob",
+			"/etc/nginx/*.conf",
+			config.TemplateConfigs{nginx, upstreams},
+			false,
+		},
+		{
+			"no_match",
+			"postgres",
+			nil,
+			true,
+		},
+		{
+			"malformed",
+			"[",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := filterTemplates(configs, tc.pattern)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/pkg/errors"
+)
+
+// TemplatePanicError is the error of a template whose execution or render
+// panicked. The panic is recovered so it only fails that template, and the
+// other templates of the run are still rendered.
+type TemplatePanicError struct {
+	// Value is the value the panic was called with.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine at the panic. It is empty if
+	// the panic was already turned into an error.
+	Stack []byte
+}
+
+func (e *TemplatePanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverTemplatePanic recovers a panic and sets the given error to a
+// TemplatePanicError for it. It must be deferred.
+func recoverTemplatePanic(err *error) {
+	if v := recover(); v != nil {
+		*err = &TemplatePanicError{Value: v, Stack: debug.Stack()}
+	}
+}
+
+// executeRecovered executes the given template, returning a
+// TemplatePanicError if it panics.
+func executeRecovered(tmpl *template.Template, i *template.ExecuteInput) (result *template.ExecuteResult, err error) {
+	defer recoverTemplatePanic(&err)
+
+	// Newer versions of the template package recover panics in template
+	// functions themselves and return them as errors, so runtime errors such
+	// as nil pointer dereferences are treated as panics as well.
+	result, err = tmpl.Execute(i)
+	if isRuntimeError(err) {
+		return nil, &TemplatePanicError{Value: err}
+	}
+	return result, err
+}
+
+// isRuntimeError returns true if the given error was caused by a runtime
+// error. The template package wraps the errors of template functions in
+// errors which only expose them through an Unwrap method.
+func isRuntimeError(err error) bool {
+	for err != nil {
+		err = errors.Cause(err)
+		if _, ok := err.(runtime.Error); ok {
+			return true
+		}
+		u, ok := err.(interface {
+			Unwrap() error
+		})
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
+
+// renderRecovered renders with the given renderer, returning a
+// TemplatePanicError if it panics.
+func renderRecovered(renderer Renderer, i *RenderInput) (result *RenderResult, err error) {
+	defer recoverTemplatePanic(&err)
+	return renderer.Render(i)
+}
+
+// failTemplate records that the given template, or only the given template
+// configuration of it if not nil, failed with a panic, so the run can go on
+// with the other templates. In once mode the template can never render, so
+// the error is returned instead.
+func (r *Runner) failTemplate(tmpl *template.Template, tc *config.TemplateConfig,
+	perr *TemplatePanicError, report *RunReport) error {
+	label := r.templateLabel(tmpl.ID())
+	if tc != nil {
+		label = tc.Display()
+	}
+
+	if r.once {
+		return fmt.Errorf("%s: %s", label, perr)
+	}
+
+	log.Printf("[ERR] (runner) template %s failed: %s", label, perr)
+	if len(perr.Stack) > 0 {
+		log.Printf("[DEBUG] (runner) template %s panic stack:\n%s", label, perr.Stack)
+	}
+
+	r.markFailed(tmpl.ID(), perr.Error())
+	r.publish(EventTemplateFailed, tmpl.ID(), "")
+	if tc != nil {
+		report.addTemplate(tmpl, tc, false, ReportReasonFailed, perr.Error(), nil, nil)
+	} else {
+		report.addTemplates(tmpl, r.templateConfigsFor(tmpl), false,
+			ReportReasonFailed, perr.Error(), nil, nil)
+	}
+	return nil
+}
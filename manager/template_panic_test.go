@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+func TestExecuteRecovered(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: `{{ service "webapp" | byTag }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A nil service makes byTag dereference a nil pointer.
+	brain := template.NewBrain()
+	d, err := dep.NewHealthServiceQuery("webapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	brain.Remember(d, []*dep.HealthService{nil})
+
+	_, err = executeRecovered(tmpl, &template.ExecuteInput{Brain: brain})
+	if _, ok := err.(*TemplatePanicError); !ok {
+		t.Fatalf("expected a panic error, got %#v", err)
+	}
+	if !strings.Contains(err.Error(), "nil pointer dereference") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestRunner_Run_templatePanic(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bad, good := filepath.Join(dir, "bad"), filepath.Join(dir, "good")
+	newRunner := func(once bool) *Runner {
+		c := config.TestConfig(&config.Config{
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String("bad"),
+					Destination: config.String(bad),
+				},
+				&config.TemplateConfig{
+					Contents:    config.String("good"),
+					Destination: config.String(good),
+				},
+			},
+		})
+
+		r, err := NewRunner(c, false, once)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.SetRenderer(RendererFunc(func(i *RenderInput) (*RenderResult, error) {
+			if i.Path == bad {
+				panic("boom")
+			}
+			return Render(i)
+		}))
+		return r
+	}
+
+	// The panic only fails its template, and the other one still renders.
+	r := newRunner(false)
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := ioutil.ReadFile(good); err != nil || string(b) != "good" {
+		t.Errorf("expected the other template to render, got %q (%v)", b, err)
+	}
+
+	var failed int
+	for _, e := range r.RenderEvents() {
+		if !e.LastFailed.IsZero() {
+			failed++
+			if e.FailedReason != "panic: boom" {
+				t.Errorf("unexpected reason: %q", e.FailedReason)
+			}
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 failed template, got %d", failed)
+	}
+
+	// In once mode the failed template can never render, so the run fails.
+	r = newRunner(true)
+	if err := r.Run(); err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("expected the panic to fail the run, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// tokenFilePollInterval is the interval at which token files are checked for
+// changes.
+const tokenFilePollInterval = 1 * time.Second
+
+// readTokenFile reads the token from the file at the given path. Surrounding
+// whitespace is removed. An empty file is an error, since it usually means the
+// file is in the middle of being rewritten.
+func readTokenFile(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("token file: %s", err)
+	}
+
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return "", fmt.Errorf("token file: %q is empty", path)
+	}
+	return token, nil
+}
+
+// configTokens returns the Consul and Vault tokens for the given config,
+// reading them from the token files if configured.
+func configTokens(c *config.Config) (string, string, error) {
+	consulToken := config.StringVal(c.Token)
+	if path := config.StringVal(c.TokenFile); path != "" {
+		token, err := readTokenFile(path)
+		if err != nil {
+			return "", "", err
+		}
+		consulToken = token
+	}
+
+	vaultToken := config.StringVal(c.Vault.Token)
+	if path := config.StringVal(c.VaultAgentTokenFile); path != "" {
+		token, err := readTokenFile(path)
+		if err != nil {
+			return "", "", err
+		}
+		vaultToken = token
+	}
+
+	return consulToken, vaultToken, nil
+}
+
+// watchTokenFiles polls the given files, which map a path to its last known
+// token, and sends on changeCh whenever the contents of any of them change.
+// This function blocks until doneCh is closed and should be run in a
+// goroutine.
+func watchTokenFiles(files map[string]string, interval time.Duration,
+	changeCh chan<- struct{}, doneCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			changed := false
+			for path, last := range files {
+				token, err := readTokenFile(path)
+				if err != nil {
+					log.Printf("[WARN] (runner) %s", err)
+					continue
+				}
+
+				if token != last {
+					log.Printf("[INFO] (runner) token file %q changed", path)
+					files[path] = token
+					changed = true
+				}
+			}
+
+			if changed {
+				select {
+				case changeCh <- struct{}{}:
+				default:
+				}
+			}
+		case <-doneCh:
+			return
+		}
+	}
+}
+
+// rotateTokens re-reads the configured token files and recreates the clients
+// for any token that changed. The views of the dependencies served by those
+// clients are then restarted so that in-flight queries are re-issued with the
+// new token. The data in the brain is kept, so templates continue to render
+// from the last known values in the meantime.
+func (r *Runner) rotateTokens() error {
+	consulToken, vaultToken, err := configTokens(r.config)
+	if err != nil {
+		return err
+	}
+
+	consulChanged := consulToken != r.consulToken
+	if consulChanged {
+		log.Printf("[INFO] (runner) recreating consul client with new token")
+		if err := createConsulClient(r.clients, r.config, consulToken); err != nil {
+			return err
+		}
+		r.consulToken = consulToken
+	}
+
+	vaultChanged := vaultToken != r.vaultToken
+	if vaultChanged {
+		log.Printf("[INFO] (runner) recreating vault client with new token")
+		if err := createVaultClient(r.clients, r.config, vaultToken); err != nil {
+			return err
+		}
+		r.vaultToken = vaultToken
+	}
+
+	if consulChanged || vaultChanged {
+		r.restartWatches(func(d dep.Dependency) bool {
+			return (consulChanged && isConsulDependency(d)) ||
+				(vaultChanged && isVaultDependency(d))
+		})
+	}
+
+	return nil
+}
+
+// restartWatches stops the view for every dependency this runner is watching
+// for which the given function returns true. The next run notices the
+// dependencies are no longer watched and creates new views for them.
+func (r *Runner) restartWatches(restart func(dep.Dependency) bool) {
+	r.dependenciesLock.Lock()
+	defer r.dependenciesLock.Unlock()
+
+	var restarted int
+	for _, d := range r.dependencies {
+		if restart(d) {
+			r.watcher.Remove(d)
+			restarted++
+		}
+	}
+
+	log.Printf("[DEBUG] (runner) restarted %d of %d watches", restarted, len(r.dependencies))
+}
+
+// isConsulDependency returns true if the dependency queries Consul, which is
+// every dependency that does not read Vault, Nomad or a local file.
+func isConsulDependency(d dep.Dependency) bool {
+	switch d.(type) {
+	case *dep.FileQuery, *dep.NomadServiceQuery, *dep.NomadServicesQuery:
+		return false
+	}
+	return !isVaultDependency(d)
+}
+
+// tokenFiles returns the configured token files mapped to the token currently
+// in use for each.
+func (r *Runner) tokenFiles() map[string]string {
+	files := make(map[string]string)
+	if path := config.StringVal(r.config.TokenFile); path != "" {
+		files[path] = r.consulToken
+	}
+	if path := config.StringVal(r.config.VaultAgentTokenFile); path != "" {
+		files[path] = r.vaultToken
+	}
+	return files
+}
@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func testTokenFile(t *testing.T, contents string) *os.File {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestReadTokenFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("trims", func(t *testing.T) {
+		f := testTokenFile(t, "  token\n")
+		defer os.Remove(f.Name())
+
+		act, err := readTokenFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := "token"; exp != act {
+			t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		f := testTokenFile(t, "\n")
+		defer os.Remove(f.Name())
+
+		if _, err := readTokenFile(f.Name()); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := readTokenFile("/not/a/real/path"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestWatchTokenFiles(t *testing.T) {
+	t.Parallel()
+
+	f := testTokenFile(t, "one")
+	defer os.Remove(f.Name())
+
+	changeCh := make(chan struct{}, 1)
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	go watchTokenFiles(map[string]string{f.Name(): "one"}, 10*time.Millisecond,
+		changeCh, doneCh)
+
+	select {
+	case <-changeCh:
+		t.Fatal("unexpected change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := ioutil.WriteFile(f.Name(), []byte("two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changeCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestRunner_rotateTokens(t *testing.T) {
+	t.Parallel()
+
+	consulFile := testTokenFile(t, "consul-one")
+	defer os.Remove(consulFile.Name())
+
+	vaultFile := testTokenFile(t, "vault-one")
+	defer os.Remove(vaultFile.Name())
+
+	c := config.TestConfig(&config.Config{
+		Token:               config.String("ignored"),
+		TokenFile:           config.String(consulFile.Name()),
+		VaultAgentTokenFile: config.String(vaultFile.Name()),
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.consulToken != "consul-one" {
+		t.Errorf("\nexp: %#v\nact: %#v", "consul-one", r.consulToken)
+	}
+	if act := r.clients.Vault().Token(); act != "vault-one" {
+		t.Errorf("\nexp: %#v\nact: %#v", "vault-one", act)
+	}
+
+	consul := r.clients.Consul()
+
+	if err := ioutil.WriteFile(consulFile.Name(), []byte("consul-two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(vaultFile.Name(), []byte("vault-two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.rotateTokens(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.consulToken != "consul-two" {
+		t.Errorf("\nexp: %#v\nact: %#v", "consul-two", r.consulToken)
+	}
+	if r.clients.Consul() == consul {
+		t.Error("expected consul client to be recreated")
+	}
+	if act := r.clients.Vault().Token(); act != "vault-two" {
+		t.Errorf("\nexp: %#v\nact: %#v", "vault-two", act)
+	}
+}
+
+func TestRunner_rotateTokens_restartsOnlyChangedClient(t *testing.T) {
+	t.Parallel()
+
+	consulFile := testTokenFile(t, "consul-one")
+	defer os.Remove(consulFile.Name())
+
+	vaultFile := testTokenFile(t, "vault-one")
+	defer os.Remove(vaultFile.Name())
+
+	c := config.TestConfig(&config.Config{
+		TokenFile:           config.String(consulFile.Name()),
+		VaultAgentTokenFile: config.String(vaultFile.Name()),
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents: config.String(`{{ key "foo" }}{{ with secret "secret/foo" }}{{ end }}`),
+			},
+		},
+	})
+
+	r, err := NewRunner(c, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	kv, secret := r.dependencies["kv.block(foo)"], r.dependencies["vault.read(secret/foo)"]
+	if kv == nil || secret == nil {
+		t.Fatalf("expected both dependencies, got %v", r.dependencies)
+	}
+
+	// Rotating the Vault token leaves the Consul queries alone.
+	if err := ioutil.WriteFile(vaultFile.Name(), []byte("vault-two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.rotateTokens(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.watcher.Watching(kv) {
+		t.Errorf("expected %s to still be watched", kv)
+	}
+	if r.watcher.Watching(secret) {
+		t.Errorf("expected %s to be restarted", secret)
+	}
+}
@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"log"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// vaultLoginInterval returns how long to wait before logging in to Vault again
+// for a token with the given lease duration. The login is repeated well before
+// the token expires, so there is time to retry if Vault is unavailable. A zero
+// lease duration means the token does not expire.
+func vaultLoginInterval(leaseDuration time.Duration) time.Duration {
+	return leaseDuration * 2 / 3
+}
+
+// watchVaultLogin logs in to Vault again with the configured auth method
+// before the token the Vault client logged in with expires, replacing the
+// client in the given client set. If logging in fails, it is retried after the
+// retry interval. This function blocks until doneCh is closed or the token
+// does not expire, and should be run in a goroutine.
+func watchVaultLogin(clients *dep.ClientSet, c *config.Config, retry time.Duration,
+	doneCh <-chan struct{}) {
+	for {
+		wait := vaultLoginInterval(clients.VaultLoginLeaseDuration())
+		if wait == 0 {
+			log.Printf("[DEBUG] (runner) vault token does not expire, not logging in again")
+			return
+		}
+
+		log.Printf("[DEBUG] (runner) logging in to vault again in %s", wait)
+
+		select {
+		case <-time.After(wait):
+		case <-doneCh:
+			return
+		}
+
+		for {
+			log.Printf("[INFO] (runner) logging in to vault")
+			err := createVaultClient(clients, c, "")
+			if err == nil {
+				break
+			}
+
+			log.Printf("[WARN] (runner) vault login failed, retrying in %s: %s", retry, err)
+
+			select {
+			case <-time.After(retry):
+			case <-doneCh:
+				return
+			}
+		}
+	}
+}
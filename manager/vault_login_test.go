@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// testVaultCertServer returns a server which answers cert auth logins at the
+// given mount with a new token each time, leased for one second.
+func testVaultCertServer(t *testing.T, mount string) (*httptest.Server, func() []string) {
+	var lock sync.Mutex
+	var roles []string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/v1/auth/%s/login", mount) {
+			http.NotFound(w, r)
+			return
+		}
+
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+
+		lock.Lock()
+		roles = append(roles, body.Name)
+		token := fmt.Sprintf("token-%d", len(roles))
+		lock.Unlock()
+
+		fmt.Fprintf(w, `{"auth": {"client_token": %q, "lease_duration": 1, "renewable": true}}`, token)
+	}))
+
+	return s, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), roles...)
+	}
+}
+
+func testVaultCertConfig(address, mount string) *config.Config {
+	c := config.DefaultConfig().Merge(&config.Config{
+		Vault: &config.VaultConfig{
+			Address:    config.String(address),
+			AuthMethod: config.String(config.VaultAuthMethodCert),
+			AuthMount:  config.String(mount),
+			AuthRole:   config.String("web"),
+			SSL: &config.SSLConfig{
+				Enabled: config.Bool(false),
+			},
+		},
+	})
+	c.Finalize()
+	return c
+}
+
+func TestCreateVaultClient_cert(t *testing.T) {
+	t.Parallel()
+
+	s, roles := testVaultCertServer(t, "certs")
+	defer s.Close()
+
+	clients := dep.NewClientSet()
+	if err := createVaultClient(clients, testVaultCertConfig(s.URL, "certs"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "token-1", clients.Vault().Token(); exp != act {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+	if exp, act := 1*time.Second, clients.VaultLoginLeaseDuration(); exp != act {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+	if exp, act := []string{"web"}, roles(); len(act) != 1 || exp[0] != act[0] {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
+
+func TestCreateVaultClient_unknownAuthMethod(t *testing.T) {
+	t.Parallel()
+
+	c := testVaultCertConfig("http://127.0.0.1:0", "certs")
+	c.Vault.AuthMethod = config.String("nope")
+
+	if err := createVaultClient(dep.NewClientSet(), c, ""); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestWatchVaultLogin(t *testing.T) {
+	t.Parallel()
+
+	s, _ := testVaultCertServer(t, "cert")
+	defer s.Close()
+
+	c := testVaultCertConfig(s.URL, "cert")
+	clients := dep.NewClientSet()
+	if err := createVaultClient(clients, c, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go watchVaultLogin(clients, c, 10*time.Millisecond, doneCh)
+
+	timeout := time.After(5 * time.Second)
+	for clients.Vault().Token() == "token-1" {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("did not log in again")
+		}
+	}
+
+	if exp, act := "token-2", clients.Vault().Token(); exp != act {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
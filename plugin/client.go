@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const (
+	// DefaultStartTimeout is the default amount of time a plugin has to
+	// announce the address it serves on.
+	DefaultStartTimeout = 10 * time.Second
+
+	// DefaultCallTimeout is the default amount of time a call to a function of
+	// a plugin may take.
+	DefaultCallTimeout = 5 * time.Second
+)
+
+// StartInput is the input to Start.
+type StartInput struct {
+	// Name is the name of the plugin, used in logs and errors.
+	Name string
+
+	// Path is the path to the plugin binary and Args are its arguments.
+	Path string
+	Args []string
+
+	// StartTimeout is the amount of time the plugin has to announce the
+	// address it serves on, and CallTimeout is the amount of time a call to
+	// one of its functions may take. Zero values use the defaults.
+	StartTimeout time.Duration
+	CallTimeout  time.Duration
+}
+
+// Client is a running plugin.
+type Client struct {
+	name        string
+	cmd         *exec.Cmd
+	conn        *grpc.ClientConn
+	functions   []string
+	callTimeout time.Duration
+
+	exitCh   chan struct{}
+	killOnce sync.Once
+}
+
+// Start starts the given plugin, waits for its handshake and lists its
+// functions. The plugin runs until Kill is called.
+func Start(i *StartInput) (*Client, error) {
+	startTimeout := i.StartTimeout
+	if startTimeout <= 0 {
+		startTimeout = DefaultStartTimeout
+	}
+	callTimeout := i.CallTimeout
+	if callTimeout <= 0 {
+		callTimeout = DefaultCallTimeout
+	}
+
+	cmd := exec.Command(i.Path, i.Args...)
+	cmd.Env = append(os.Environ(), MagicCookieKey+"="+MagicCookieValue)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin %s", i.Name)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin %s", i.Name)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "plugin %s", i.Name)
+	}
+
+	c := &Client{
+		name:        i.Name,
+		cmd:         cmd,
+		callTimeout: callTimeout,
+		exitCh:      make(chan struct{}),
+	}
+	go c.logStderr(stderr)
+
+	// Read the handshake, and then discard the rest of stdout so the plugin
+	// never blocks writing to it.
+	lineCh := make(chan string, 1)
+	go func() {
+		r := bufio.NewReader(stdout)
+		line, _ := r.ReadString('\n')
+		lineCh <- line
+		io.Copy(ioutil.Discard, r)
+	}()
+	go func() {
+		cmd.Wait()
+		close(c.exitCh)
+	}()
+
+	var line string
+	select {
+	case line = <-lineCh:
+	case <-time.After(startTimeout):
+		c.Kill()
+		return nil, fmt.Errorf("plugin %s: no handshake within %s", i.Name, startTimeout)
+	}
+
+	h, err := parseHandshake(line)
+	if err != nil {
+		c.Kill()
+		return nil, errors.Wrapf(err, "plugin %s", i.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	c.conn, err = grpc.DialContext(ctx, h.address,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout(h.network, addr, timeout)
+		}))
+	if err != nil {
+		c.Kill()
+		return nil, errors.Wrapf(err, "plugin %s: failed to connect", i.Name)
+	}
+
+	var resp functionsResponse
+	if err := grpc.Invoke(ctx, functionsMethod, &functionsRequest{}, &resp, c.conn); err != nil {
+		c.Kill()
+		return nil, errors.Wrapf(err, "plugin %s: failed to list functions", i.Name)
+	}
+	c.functions = resp.Names
+
+	log.Printf("[INFO] (plugin) started %s with functions %v", i.Name, c.functions)
+	return c, nil
+}
+
+// Name returns the name of the plugin.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Functions returns the names of the functions of the plugin.
+func (c *Client) Functions() []string {
+	return c.functions
+}
+
+// Func returns the function of the plugin with the given name.
+func (c *Client) Func(name string) Func {
+	return func(args ...string) (string, error) {
+		return c.Call(name, args...)
+	}
+}
+
+// Call calls the function of the plugin with the given name.
+func (c *Client) Call(name string, args ...string) (string, error) {
+	select {
+	case <-c.exitCh:
+		return "", fmt.Errorf("plugin %s: exited", c.name)
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout)
+	defer cancel()
+
+	var resp callResponse
+	if err := grpc.Invoke(ctx, callMethod, &callRequest{Name: name, Args: args}, &resp, c.conn); err != nil {
+		return "", errors.Wrapf(err, "plugin %s: %s", c.name, name)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("plugin %s: %s: %s", c.name, name, resp.Error)
+	}
+	return resp.Result, nil
+}
+
+// Kill stops the plugin and waits for it to exit.
+func (c *Client) Kill() {
+	c.killOnce.Do(func() {
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.cmd.Process.Kill()
+		<-c.exitCh
+		log.Printf("[DEBUG] (plugin) stopped %s", c.name)
+	})
+}
+
+// logStderr logs the lines the plugin writes to stderr.
+func (c *Client) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("[INFO] (plugin) %s: %s", c.name, scanner.Text())
+	}
+}
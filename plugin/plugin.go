@@ -0,0 +1,128 @@
+// Package plugin implements template plugins: external binaries which provide
+// template functions to Consul Template over gRPC. A plugin is started once by
+// the runner and announces the address it serves on with a handshake line on
+// its stdout, so calls to its functions do not start a process each.
+//
+// Plugins written in Go call Serve with their functions:
+//
+//	func main() {
+//		plugin.Serve(map[string]plugin.Func{
+//			"upper": func(args ...string) (string, error) {
+//				return strings.ToUpper(strings.Join(args, " ")), nil
+//			},
+//		})
+//	}
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// MagicCookieKey and MagicCookieValue are the environment variable and its
+	// value which are set for plugins started by Consul Template. Serve exits
+	// if they are missing, so a plugin run by hand explains what it is instead
+	// of waiting for calls.
+	MagicCookieKey   = "CONSUL_TEMPLATE_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "d2c3f0a1b7e94c6e8a5f3b1d9e7c2a40"
+
+	// CoreProtocolVersion is the version of the handshake and ProtocolVersion
+	// is the version of the gRPC service. A plugin must announce both.
+	CoreProtocolVersion = 1
+	ProtocolVersion     = 1
+
+	// serviceName is the name of the gRPC service of the plugins.
+	serviceName = "consultemplate.plugin.v1.TemplatePlugin"
+
+	// functionsMethod lists the functions of a plugin and callMethod calls one.
+	functionsMethod = "/" + serviceName + "/Functions"
+	callMethod      = "/" + serviceName + "/Call"
+)
+
+// Func is a template function provided by a plugin. It is called with the
+// arguments given in the template, converted to strings, and its result is
+// rendered as-is.
+type Func func(args ...string) (string, error)
+
+// handshake is the line a plugin prints on its stdout once it serves, in the
+// format "CORE-VERSION|VERSION|NETWORK|ADDRESS|PROTOCOL".
+type handshake struct {
+	network string
+	address string
+}
+
+// parseHandshake parses the handshake line of a plugin.
+func parseHandshake(line string) (*handshake, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid handshake %q", line)
+	}
+
+	if v, err := strconv.Atoi(parts[0]); err != nil || v != CoreProtocolVersion {
+		return nil, fmt.Errorf("unsupported core protocol version %q, expected %d",
+			parts[0], CoreProtocolVersion)
+	}
+	if v, err := strconv.Atoi(parts[1]); err != nil || v != ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %q, expected %d",
+			parts[1], ProtocolVersion)
+	}
+
+	switch parts[2] {
+	case "tcp", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported network %q", parts[2])
+	}
+	if parts[4] != "grpc" {
+		return nil, fmt.Errorf("unsupported protocol %q, expected \"grpc\"", parts[4])
+	}
+
+	return &handshake{
+		network: parts[2],
+		address: parts[3],
+	}, nil
+}
+
+// String returns the handshake line, without its newline.
+func (h *handshake) String() string {
+	return fmt.Sprintf("%d|%d|%s|%s|grpc", CoreProtocolVersion, ProtocolVersion,
+		h.network, h.address)
+}
+
+// The following are the messages of the gRPC service of the plugins. The
+// field numbers must not change.
+
+type functionsRequest struct{}
+
+func (m *functionsRequest) Reset()         { *m = functionsRequest{} }
+func (m *functionsRequest) String() string { return proto.CompactTextString(m) }
+func (*functionsRequest) ProtoMessage()    {}
+
+type functionsResponse struct {
+	Names []string `protobuf:"bytes,1,rep,name=names,proto3"`
+}
+
+func (m *functionsResponse) Reset()         { *m = functionsResponse{} }
+func (m *functionsResponse) String() string { return proto.CompactTextString(m) }
+func (*functionsResponse) ProtoMessage()    {}
+
+type callRequest struct {
+	Name string   `protobuf:"bytes,1,opt,name=name,proto3"`
+	Args []string `protobuf:"bytes,2,rep,name=args,proto3"`
+}
+
+func (m *callRequest) Reset()         { *m = callRequest{} }
+func (m *callRequest) String() string { return proto.CompactTextString(m) }
+func (*callRequest) ProtoMessage()    {}
+
+type callResponse struct {
+	Result string `protobuf:"bytes,1,opt,name=result,proto3"`
+	Error  string `protobuf:"bytes,2,opt,name=error,proto3"`
+}
+
+func (m *callResponse) Reset()         { *m = callResponse{} }
+func (m *callResponse) String() string { return proto.CompactTextString(m) }
+func (*callResponse) ProtoMessage()    {}
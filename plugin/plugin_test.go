@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary serve as a plugin for the tests which start
+// one.
+func TestMain(m *testing.M) {
+	if os.Getenv("CT_TEST_PLUGIN") == "1" {
+		Serve(testFuncs)
+	}
+	os.Exit(m.Run())
+}
+
+var testFuncs = map[string]Func{
+	"join": func(args ...string) (string, error) {
+		return strings.Join(args, ","), nil
+	},
+	"fail": func(args ...string) (string, error) {
+		return "", errors.New("failed")
+	},
+	"panic": func(args ...string) (string, error) {
+		panic("oops")
+	},
+}
+
+func TestParseHandshake(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		exp  *handshake
+		err  bool
+	}{
+		{
+			"tcp",
+			"1|1|tcp|127.0.0.1:1234|grpc\n",
+			&handshake{network: "tcp", address: "127.0.0.1:1234"},
+			false,
+		},
+		{
+			"unix",
+			"1|1|unix|/tmp/plugin.sock|grpc",
+			&handshake{network: "unix", address: "/tmp/plugin.sock"},
+			false,
+		},
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"core_version",
+			"2|1|tcp|127.0.0.1:1234|grpc",
+			nil,
+			true,
+		},
+		{
+			"version",
+			"1|2|tcp|127.0.0.1:1234|grpc",
+			nil,
+			true,
+		},
+		{
+			"network",
+			"1|1|udp|127.0.0.1:1234|grpc",
+			nil,
+			true,
+		},
+		{
+			"protocol",
+			"1|1|tcp|127.0.0.1:1234|netrpc",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := parseHandshake(tc.line)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.exp, act)
+			}
+			if act != nil {
+				if _, err := parseHandshake(act.String()); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStart(t *testing.T) {
+	if err := os.Setenv("CT_TEST_PLUGIN", "1"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("CT_TEST_PLUGIN")
+
+	c, err := Start(&StartInput{
+		Name: "test",
+		Path: os.Args[0],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Kill()
+
+	if exp, act := []string{"fail", "join", "panic"}, c.Functions(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	act, err := c.Func("join")("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "a,b"; act != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, act)
+	}
+
+	for name, exp := range map[string]string{
+		"fail":    "fail: failed",
+		"panic":   "panic panicked: oops",
+		"missing": "unknown function",
+	} {
+		if _, err := c.Call(name); err == nil || !strings.Contains(err.Error(), exp) {
+			t.Errorf("%s: expected %q, got %v", name, exp, err)
+		}
+	}
+
+	c.Kill()
+	if _, err := c.Call("join"); err == nil {
+		t.Error("expected a call to a killed plugin to fail")
+	}
+}
+
+func TestStart_noHandshake(t *testing.T) {
+	_, err := Start(&StartInput{
+		Name:         "test",
+		Path:         "sleep",
+		Args:         []string{"5"},
+		StartTimeout: 100 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no handshake") {
+		t.Fatalf("expected a handshake timeout, got %v", err)
+	}
+}
+
+func TestStart_invalidHandshake(t *testing.T) {
+	_, err := Start(&StartInput{
+		Name: "test",
+		Path: os.Args[0],
+		Args: []string{"-test.run=^$"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid handshake") {
+		t.Fatalf("expected an invalid handshake, got %v", err)
+	}
+}
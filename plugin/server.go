@@ -0,0 +1,117 @@
+package plugin
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// Serve serves the given functions to Consul Template and never returns. It
+// listens on a local port, announces it on stdout and serves until Consul
+// Template stops the plugin. Anything the functions write to stderr is logged
+// by Consul Template; stdout must be left alone. If the plugin was not started
+// by Consul Template, Serve prints an explanation and exits.
+func Serve(funcs map[string]Func) {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(os.Stderr, "This binary is a Consul Template plugin. It is not "+
+			"meant to be executed directly; declare it in a template_plugin block.")
+		os.Exit(1)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "plugin: failed to listen: %s\n", err)
+		os.Exit(1)
+	}
+
+	h := &handshake{network: ln.Addr().Network(), address: ln.Addr().String()}
+	fmt.Fprintln(os.Stdout, h.String())
+
+	if err := newServer(funcs).Serve(ln); err != nil {
+		fmt.Fprintf(os.Stderr, "plugin: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// newServer returns a gRPC server which serves the given functions.
+func newServer(funcs map[string]Func) *grpc.Server {
+	s := grpc.NewServer()
+	s.RegisterService(&serviceDesc, &server{funcs: funcs})
+	return s
+}
+
+// service is the interface of the gRPC service of the plugins.
+type service interface {
+	functions() *functionsResponse
+	call(*callRequest) *callResponse
+}
+
+// server implements the gRPC service of the plugins for the given functions.
+type server struct {
+	funcs map[string]Func
+}
+
+// functions returns the names of the functions, sorted.
+func (s *server) functions() *functionsResponse {
+	names := make([]string, 0, len(s.funcs))
+	for name := range s.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &functionsResponse{Names: names}
+}
+
+// call calls the requested function. Errors, including panics, are returned
+// in the response so they surface as template errors.
+func (s *server) call(req *callRequest) (resp *callResponse) {
+	f, ok := s.funcs[req.Name]
+	if !ok {
+		return &callResponse{Error: fmt.Sprintf("unknown function %q", req.Name)}
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			resp = &callResponse{Error: fmt.Sprintf("%s panicked: %v", req.Name, p)}
+		}
+	}()
+
+	result, err := f(req.Args...)
+	if err != nil {
+		return &callResponse{Error: err.Error()}
+	}
+	return &callResponse{Result: result}
+}
+
+// serviceDesc describes the gRPC service of the plugins.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Functions",
+			Handler: func(srv interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				var req functionsRequest
+				if err := dec(&req); err != nil {
+					return nil, err
+				}
+				return srv.(service).functions(), nil
+			},
+		},
+		{
+			MethodName: "Call",
+			Handler: func(srv interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				var req callRequest
+				if err := dec(&req); err != nil {
+					return nil, err
+				}
+				return srv.(service).call(&req), nil
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/manager"
+)
+
+// RenderCommand is the name of the subcommand which renders a single template
+// to standard out.
+const RenderCommand = "render"
+
+// runRender fetches the data for a single template, renders it to the output
+// stream once and exits. No pid file is written, no commands are run and
+// nothing is written to disk.
+func (cli *CLI) runRender(args []string) int {
+	conf, err := cli.ParseRenderFlags(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return cli.handleError(err, ExitCodeParseFlagsError)
+	}
+
+	conf, err = cli.setup(conf)
+	if err != nil {
+		return cli.handleError(err, ExitCodeConfigError)
+	}
+
+	runner, err := manager.NewRunner(conf, false, true)
+	if err != nil {
+		return cli.handleError(err, ExitCodeRunnerError)
+	}
+	runner.SetRenderer(manager.RendererFunc(func(i *manager.RenderInput) (*manager.RenderResult, error) {
+		if _, err := cli.outStream.Write(i.Contents); err != nil {
+			return nil, err
+		}
+		return &manager.RenderResult{
+			DidRender:   true,
+			WouldRender: true,
+		}, nil
+	}))
+	go runner.Start()
+	defer runner.Stop()
+
+	select {
+	case err := <-runner.ErrCh:
+		return cli.handleError(err, ExitCodeRunnerError)
+	case <-runner.DoneCh:
+		return ExitCodeOK
+	case <-cli.stopCh:
+		return ExitCodeOK
+	}
+}
+
+// ParseRenderFlags parses the flags of the render subcommand. Connection
+// settings and vars are read from the given configuration files and flags, but
+// any templates, exec settings, pid file and other daemon-only settings in the
+// configuration files are ignored.
+func (cli *CLI) ParseRenderFlags(args []string) (*config.Config, error) {
+	var source string
+
+	c := config.DefaultConfig()
+
+	// configPaths stores the list of configuration paths on disk
+	configPaths := make([]string, 0, 6)
+
+	// vars and varFiles store the variables given with -var and -var-file
+	vars := make(map[string]string)
+	varFiles := make([]string, 0, 2)
+
+	flags := flag.NewFlagSet(RenderCommand, flag.ContinueOnError)
+	flags.SetOutput(cli.errStream)
+	flags.Usage = func() { fmt.Fprintf(cli.errStream, renderUsage, Name) }
+
+	flags.Var((funcVar)(func(s string) error {
+		configPaths = append(configPaths, s)
+		return nil
+	}), "config", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Consul = config.String(s)
+		return nil
+	}), "consul", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.LogLevel = config.String(s)
+		return nil
+	}), "log-level", "")
+
+	flags.StringVar(&source, "template", "", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Token = config.String(s)
+		return nil
+	}), "token", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		k, v, err := config.ParseVar(s)
+		if err != nil {
+			return err
+		}
+		vars[k] = v
+		return nil
+	}), "var", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		varFiles = append(varFiles, s)
+		return nil
+	}), "var-file", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Vault.Address = config.String(s)
+		return nil
+	}), "vault-addr", "")
+
+	flags.Var((funcVar)(func(s string) error {
+		c.Vault.Token = config.String(s)
+		return nil
+	}), "vault-token", "")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if args := flags.Args(); len(args) > 0 {
+		return nil, fmt.Errorf("cli: extra args: %q", args)
+	}
+
+	if source == "" {
+		return nil, fmt.Errorf("cli: %s: -template is required", RenderCommand)
+	}
+
+	if err := mergeCLIVars(c, varFiles, vars); err != nil {
+		return nil, err
+	}
+
+	finalC := config.DefaultConfig()
+	for _, path := range configPaths {
+		c, err := config.FromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		finalC = finalC.Merge(c)
+	}
+	finalC = finalC.Merge(c)
+
+	// Only render the given template, and none of the daemon behavior.
+	finalC.Templates = &config.TemplateConfigs{
+		&config.TemplateConfig{Source: config.String(source)},
+	}
+	finalC.Control = config.DefaultControlConfig()
+	finalC.Coordinate = config.DefaultCoordinateConfig()
+	finalC.Dedup = config.DefaultDedupConfig()
+	finalC.Exec = config.DefaultExecConfig()
+	finalC.PidFile = config.String("")
+	finalC.RemoteConfig = config.DefaultRemoteConfigConfig()
+	finalC.Status = config.DefaultStatusConfig()
+	finalC.Wait = config.DefaultWaitConfig()
+
+	finalC.Finalize()
+
+	return finalC, nil
+}
+
+const renderUsage = `
+Usage: %s render [options]
+
+  Fetches the data for a single template, renders it to standard out and
+  exits. No pid file is written, no commands are run and nothing is written to
+  disk.
+
+Options:
+
+  -config=<path>
+      Sets the path to a configuration file or folder on disk to read
+      connection settings and vars from. Templates and other settings for
+      running as a daemon are ignored. This can be specified multiple times
+
+  -consul=<address>
+      Sets the address of the Consul instance
+
+  -log-level=<level>
+      Set the logging level - values are "debug", "info", "warn", and "err"
+
+  -template=<path>
+      Path to the template to render
+
+  -token=<token>
+      Sets the Consul API token
+
+  -var=<key=value>
+      Sets a variable available to the template through the var function.
+      This can be specified multiple times
+
+  -var-file=<path>
+      Sets the path to an HCL or JSON file of variables available to the
+      template through the var function. This can be specified multiple times
+
+  -vault-addr=<address>
+      Sets the address of the Vault server
+
+  -vault-token=<token>
+      Sets the Vault API token
+`
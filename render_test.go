@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestCLI_ParseRenderFlags(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+		consul   = "1.2.3.4:8500"
+		pid_file = "/var/run/ct.pid"
+		template {
+			source      = "other.tpl"
+			destination = "other.out"
+			command     = "reload"
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		f    []string
+		e    *config.Config
+		err  bool
+	}{
+		{
+			"template",
+			[]string{"-template", "in.tpl"},
+			&config.Config{
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{Source: config.String("in.tpl")},
+				},
+			},
+			false,
+		},
+		{
+			"config_connection_only",
+			[]string{"-config", f.Name(), "-template", "in.tpl"},
+			&config.Config{
+				Consul: config.String("1.2.3.4:8500"),
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{Source: config.String("in.tpl")},
+				},
+			},
+			false,
+		},
+		{
+			"flags_override_config",
+			[]string{"-config", f.Name(), "-consul", "5.6.7.8:8500", "-template", "in.tpl"},
+			&config.Config{
+				Consul: config.String("5.6.7.8:8500"),
+				Templates: &config.TemplateConfigs{
+					&config.TemplateConfig{Source: config.String("in.tpl")},
+				},
+			},
+			false,
+		},
+		{
+			"missing_template",
+			[]string{"-consul", "1.2.3.4:8500"},
+			nil,
+			true,
+		},
+		{
+			"extra_args",
+			[]string{"-template", "in.tpl", "foo"},
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var out bytes.Buffer
+			cli := NewCLI(&out, &out)
+
+			c, err := cli.ParseRenderFlags(tc.f)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			var e *config.Config
+			if tc.e != nil {
+				e = config.DefaultConfig().Merge(tc.e)
+				e.Finalize()
+			}
+
+			if !reflect.DeepEqual(e, c) {
+				t.Errorf("\nexp: %#v\nact: %#v", e, c)
+			}
+		})
+	}
+}
+
+func TestCLI_Run_render(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`hello {{ "world" }}`); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cli := NewCLI(&out, &out)
+
+	exit := cli.Run([]string{"consul-template", "render", "-template", f.Name()})
+	if exit != 0 {
+		t.Fatalf("expected 0 exit, got %d: %s", exit, out.String())
+	}
+
+	if act := out.String(); !strings.Contains(act, "hello world") {
+		t.Errorf("\nexp: %q\nact: %q", "hello world", act)
+	}
+}
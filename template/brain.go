@@ -18,6 +18,21 @@ type Brain struct {
 	// receivedData is an internal tracker of which dependencies have stored data
 	// in the brain.
 	receivedData map[string]struct{}
+
+	// revisions tracks the revision at which each dependency last received
+	// data. revision is incremented on every write, so a dependency's revision
+	// changes any time its data is replaced.
+	revisions map[string]uint64
+	revision  uint64
+
+	// errors is the error the most recent fetch of each dependency returned.
+	// It is cleared when the dependency receives data.
+	errors map[string]error
+
+	// rendered is the data of each dependency as of the last time each template
+	// rendered, keyed by the template ID and then the dependency. It is the
+	// baseline for the changes a template sees since its last render.
+	rendered map[string]map[string]interface{}
 }
 
 // NewBrain creates a new Brain with empty values for each
@@ -26,6 +41,9 @@ func NewBrain() *Brain {
 	return &Brain{
 		data:         make(map[string]interface{}),
 		receivedData: make(map[string]struct{}),
+		revisions:    make(map[string]uint64),
+		errors:       make(map[string]error),
+		rendered:     make(map[string]map[string]interface{}),
 	}
 }
 
@@ -38,6 +56,28 @@ func (b *Brain) Remember(d dep.Dependency, data interface{}) {
 
 	b.data[d.String()] = data
 	b.receivedData[d.String()] = struct{}{}
+	b.revision++
+	b.revisions[d.String()] = b.revision
+	delete(b.errors, d.String())
+}
+
+// RememberError stores the error the most recent fetch of the given dependency
+// returned. It returns true if the dependency did not already have an error.
+func (b *Brain) RememberError(d dep.Dependency, err error) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	_, ok := b.errors[d.String()]
+	b.errors[d.String()] = err
+	return !ok
+}
+
+// Error returns the error the most recent fetch of the given dependency
+// returned, or nil if it has not failed since it last received data.
+func (b *Brain) Error(d dep.Dependency) error {
+	b.RLock()
+	defer b.RUnlock()
+	return b.errors[d.String()]
 }
 
 // Recall gets the current value for the given dependency in the Brain.
@@ -61,6 +101,44 @@ func (b *Brain) ForceSet(hashCode string, data interface{}) {
 
 	b.data[hashCode] = data
 	b.receivedData[hashCode] = struct{}{}
+	b.revision++
+	b.revisions[hashCode] = b.revision
+	delete(b.errors, hashCode)
+}
+
+// Revision returns the revision at which the given dependency last received
+// data. If the dependency has no data in the brain, 0 is returned.
+func (b *Brain) Revision(d dep.Dependency) uint64 {
+	b.RLock()
+	defer b.RUnlock()
+	return b.revisions[d.String()]
+}
+
+// MarkRendered records the current data of the given dependencies as the data
+// the given template last rendered with. Dependencies the template no longer
+// uses are dropped.
+func (b *Brain) MarkRendered(id string, deps []dep.Dependency) {
+	b.Lock()
+	defer b.Unlock()
+
+	rendered := make(map[string]interface{}, len(deps))
+	for _, d := range deps {
+		if data, ok := b.data[d.String()]; ok {
+			rendered[d.String()] = data
+		}
+	}
+	b.rendered[id] = rendered
+}
+
+// RecallRendered returns the data of the given dependency as of the last time
+// the given template rendered. It returns false if the template has not
+// rendered with the dependency.
+func (b *Brain) RecallRendered(id string, d dep.Dependency) (interface{}, bool) {
+	b.RLock()
+	defer b.RUnlock()
+
+	data, ok := b.rendered[id][d.String()]
+	return data, ok
 }
 
 // Forget accepts a dependency and removes all associated data with this
@@ -71,4 +149,9 @@ func (b *Brain) Forget(d dep.Dependency) {
 
 	delete(b.data, d.String())
 	delete(b.receivedData, d.String())
+	delete(b.revisions, d.String())
+	delete(b.errors, d.String())
+	for _, rendered := range b.rendered {
+		delete(rendered, d.String())
+	}
 }
@@ -1,6 +1,7 @@
 package template
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -97,3 +98,87 @@ func TestForget(t *testing.T) {
 		t.Errorf("expected %#v to not be forgotten", d)
 	}
 }
+
+func TestRememberError(t *testing.T) {
+	b := NewBrain()
+
+	d, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Error(d); err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if !b.RememberError(d, fmt.Errorf("a")) {
+		t.Errorf("expected first error to be new")
+	}
+	if b.RememberError(d, fmt.Errorf("b")) {
+		t.Errorf("expected second error to not be new")
+	}
+	if err := b.Error(d); err == nil || err.Error() != "b" {
+		t.Errorf("expected %q to be %q", err, "b")
+	}
+
+	b.Remember(d, "bar")
+	if err := b.Error(d); err != nil {
+		t.Errorf("expected data to clear the error, got %s", err)
+	}
+}
+
+func TestRevision(t *testing.T) {
+	b := NewBrain()
+
+	d, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := b.Revision(d); r != 0 {
+		t.Errorf("expected %d to be 0", r)
+	}
+
+	b.Remember(d, "bar")
+	first := b.Revision(d)
+	if first == 0 {
+		t.Errorf("expected revision to be set")
+	}
+
+	b.Remember(d, "baz")
+	if second := b.Revision(d); second <= first {
+		t.Errorf("expected %d to be greater than %d", second, first)
+	}
+
+	b.Forget(d)
+	if r := b.Revision(d); r != 0 {
+		t.Errorf("expected %d to be 0", r)
+	}
+}
+
+func TestMarkRendered(t *testing.T) {
+	b := NewBrain()
+
+	d, err := dep.NewKVListQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := b.RecallRendered("tmpl", d); ok {
+		t.Errorf("expected no rendered data")
+	}
+
+	b.Remember(d, "bar")
+	b.MarkRendered("tmpl", []dep.Dependency{d})
+	b.Remember(d, "baz")
+
+	data, ok := b.RecallRendered("tmpl", d)
+	if !ok || data != "bar" {
+		t.Errorf("expected %#v to be %#v", data, "bar")
+	}
+
+	b.Forget(d)
+	if _, ok := b.RecallRendered("tmpl", d); ok {
+		t.Errorf("expected no rendered data")
+	}
+}
@@ -0,0 +1,136 @@
+package template
+
+import (
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// parseCache holds the parsed form of every template in this process, keyed by
+// the hash of the contents, the delimiters and the plugin functions. Parsing
+// large templates is expensive, so templates with the same contents share a
+// single parse across template configurations and configuration reloads.
+var parseCache = struct {
+	sync.Mutex
+	m map[string]*cacheEntry
+}{m: make(map[string]*cacheEntry)}
+
+// cacheEntry is a parsed template in the parse cache and the owners of the
+// templates which use it. It is removed once it has no owners.
+type cacheEntry struct {
+	tmpl   *template.Template
+	owners map[*CacheOwner]struct{}
+}
+
+// CacheOwner owns parsed templates in the parse cache. Each runner has its own
+// owner, so pruning the templates of one runner does not evict the templates
+// of the others, while templates with the same contents still share a parse.
+type CacheOwner struct {
+	// The owner has a size so distinct owners never share an address.
+	_ byte
+}
+
+// NewCacheOwner returns a new owner of parsed templates.
+func NewCacheOwner() *CacheOwner {
+	return &CacheOwner{}
+}
+
+// cacheKey returns the key of this template in the parse cache.
+func (t *Template) cacheKey() string {
+	return t.hexMD5 + ":" + t.leftDelim + ":" + t.rightDelim + ":" +
+		pluginFuncNames(t.pluginFuncs)
+}
+
+// parse returns the parsed template, parsing it if it is not in the cache. The
+// returned template must not be executed directly; callers should Clone it
+// and bind their own functions. Templates without an owner are not cached.
+func (t *Template) parse() (*template.Template, error) {
+	if t.cacheOwner == nil {
+		return t.parseContents()
+	}
+
+	key := t.cacheKey()
+
+	parseCache.Lock()
+	defer parseCache.Unlock()
+
+	if e, ok := parseCache.m[key]; ok {
+		log.Printf("[DEBUG] (template) using cached parse of %s", t.Source())
+		e.owners[t.cacheOwner] = struct{}{}
+		return e.tmpl, nil
+	}
+
+	tmpl, err := t.parseContents()
+	if err != nil {
+		return nil, err
+	}
+
+	parseCache.m[key] = &cacheEntry{
+		tmpl:   tmpl,
+		owners: map[*CacheOwner]struct{}{t.cacheOwner: struct{}{}},
+	}
+	return tmpl, nil
+}
+
+// parseContents parses the contents of the template.
+func (t *Template) parseContents() (*template.Template, error) {
+	start := time.Now()
+
+	// The functions are rebound on every execution, but they must exist at
+	// parse time so references to them can be resolved.
+	tmpl := template.New("")
+	tmpl.Delims(t.leftDelim, t.rightDelim)
+	tmpl.Funcs(funcMap(&funcMapInput{}))
+	tmpl.Funcs(pluginFuncMap(t.pluginFuncs))
+
+	tmpl, err := tmpl.Parse(t.contents)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse")
+	}
+	instrumentRanges(tmpl)
+
+	log.Printf("[DEBUG] (template) parsed %s in %s", t.Source(), time.Since(start))
+
+	return tmpl, nil
+}
+
+// Prune releases the parsed templates of this owner except those of the given
+// templates, and removes the ones no other owner uses from the cache. It is
+// called after a reload so templates that were removed or changed do not stay
+// in memory.
+func (o *CacheOwner) Prune(keep []*Template) {
+	keys := make(map[string]struct{}, len(keep))
+	for _, t := range keep {
+		keys[t.cacheKey()] = struct{}{}
+	}
+
+	parseCache.Lock()
+	defer parseCache.Unlock()
+
+	var pruned int
+	for key, e := range parseCache.m {
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		if _, ok := e.owners[o]; !ok {
+			continue
+		}
+		delete(e.owners, o)
+		if len(e.owners) == 0 {
+			delete(parseCache.m, key)
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		log.Printf("[DEBUG] (template) pruned %d parsed templates", pruned)
+	}
+}
+
+// Release releases all the parsed templates of this owner. It is called when
+// the owner stops.
+func (o *CacheOwner) Release() {
+	o.Prune(nil)
+}
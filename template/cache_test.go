@@ -0,0 +1,115 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestTemplate_parse(t *testing.T) {
+	owner := NewCacheOwner()
+	defer owner.Release()
+
+	a, err := NewTemplate(&NewTemplateInput{
+		Contents:   "test_parse {{ key \"foo\" }}",
+		CacheOwner: owner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewTemplate(&NewTemplateInput{
+		Contents:   "test_parse {{ key \"foo\" }}",
+		CacheOwner: owner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewTemplate(&NewTemplateInput{
+		Contents:   "test_parse {{ key \"foo\" }}",
+		LeftDelim:  "<<",
+		RightDelim: ">>",
+		CacheOwner: owner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewTemplate(&NewTemplateInput{Contents: "test_parse {{ key \"foo\" }}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pa, err := a.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := b.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := c.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd, err := d.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pa != pb {
+		t.Errorf("expected templates with the same contents to share a parse")
+	}
+	if pa == pc {
+		t.Errorf("expected templates with different delimiters to not share a parse")
+	}
+	if pa == pd {
+		t.Errorf("expected a template without an owner not to use the cache")
+	}
+}
+
+func TestCacheOwner_Prune(t *testing.T) {
+	ownerA, ownerB := NewCacheOwner(), NewCacheOwner()
+	defer ownerA.Release()
+	defer ownerB.Release()
+
+	newTemplate := func(contents string, owner *CacheOwner) *Template {
+		tmpl, err := NewTemplate(&NewTemplateInput{Contents: contents, CacheOwner: owner})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tmpl.parse(); err != nil {
+			t.Fatal(err)
+		}
+		return tmpl
+	}
+	cached := func(tmpl *Template) bool {
+		parseCache.Lock()
+		defer parseCache.Unlock()
+		_, ok := parseCache.m[tmpl.cacheKey()]
+		return ok
+	}
+
+	a := newTemplate("test_prune_cache_a", ownerA)
+	b := newTemplate("test_prune_cache_b", ownerA)
+	shared := newTemplate("test_prune_cache_shared", ownerA)
+	newTemplate("test_prune_cache_shared", ownerB)
+	c := newTemplate("test_prune_cache_c", ownerB)
+
+	// Pruning the templates of one owner does not evict the templates of
+	// the other owners.
+	ownerA.Prune([]*Template{a})
+	if !cached(a) {
+		t.Errorf("expected %s to be cached", a.Contents())
+	}
+	if cached(b) {
+		t.Errorf("expected %s to be pruned", b.Contents())
+	}
+	if !cached(shared) || !cached(c) {
+		t.Errorf("expected the templates of the other owner to be cached")
+	}
+
+	ownerB.Release()
+	if cached(shared) || cached(c) {
+		t.Errorf("expected the released templates to be pruned")
+	}
+	if !cached(a) {
+		t.Errorf("expected %s to be cached", a.Contents())
+	}
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecCapture runs the commands of the execCapture template function. Only the
+// allowed commands can be run, each with a timeout, and their output can be
+// reused for a while, so it is shared by all the executions of a process. A
+// nil ExecCapture allows no commands.
+type ExecCapture struct {
+	allowed  map[string]struct{}
+	cacheTTL time.Duration
+	timeout  time.Duration
+
+	// cache holds the output of the commands by their arguments, and is
+	// protected by lock.
+	cache map[string]execCaptureEntry
+	lock  sync.Mutex
+}
+
+// execCaptureEntry is the cached output of a command.
+type execCaptureEntry struct {
+	output  string
+	expires time.Time
+}
+
+// NewExecCaptureInput is used as input when creating an ExecCapture.
+type NewExecCaptureInput struct {
+	// Allowed are the commands which can be run, exactly as they are given to
+	// execCapture.
+	Allowed []string
+
+	// CacheTTL is how long the output of a command is reused for the same
+	// arguments. Zero runs the command every time.
+	CacheTTL time.Duration
+
+	// Timeout is the amount of time to wait for a command to return before it
+	// is killed. Zero does not limit it.
+	Timeout time.Duration
+}
+
+// NewExecCapture creates a new ExecCapture from the given input.
+func NewExecCapture(i *NewExecCaptureInput) *ExecCapture {
+	if i == nil {
+		i = &NewExecCaptureInput{}
+	}
+
+	allowed := make(map[string]struct{}, len(i.Allowed))
+	for _, name := range i.Allowed {
+		allowed[name] = struct{}{}
+	}
+
+	return &ExecCapture{
+		allowed:  allowed,
+		cacheTTL: i.CacheTTL,
+		timeout:  i.Timeout,
+		cache:    make(map[string]execCaptureEntry),
+	}
+}
+
+// Run runs the given allowed command with the given arguments, without a
+// shell, and returns its standard output with surrounding whitespace trimmed.
+// An error is returned if the command is not allowed, does not finish in time
+// or exits with a non-zero status.
+func (e *ExecCapture) Run(name string, args ...string) (string, error) {
+	if e == nil {
+		return "", fmt.Errorf("execCapture: %q is not allowed, no commands are allowed", name)
+	}
+	if _, ok := e.allowed[name]; !ok {
+		return "", fmt.Errorf("execCapture: %q is not allowed", name)
+	}
+
+	key := strings.Join(append([]string{name}, args...), "\x00")
+	if e.cacheTTL > 0 {
+		e.lock.Lock()
+		entry, ok := e.cache[key]
+		e.lock.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			return entry.output, nil
+		}
+	}
+
+	ctx := context.Background()
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("execCapture: %q did not finish in %s", name, e.timeout)
+		}
+		return "", fmt.Errorf("execCapture: %q: %s\n\nstderr:\n\n%s", name, err, stderr.Bytes())
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	if e.cacheTTL > 0 {
+		e.lock.Lock()
+		e.cache[key] = execCaptureEntry{
+			output:  output,
+			expires: time.Now().Add(e.cacheTTL),
+		}
+		e.lock.Unlock()
+	}
+	return output, nil
+}
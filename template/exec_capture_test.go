@@ -0,0 +1,69 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecCapture_Run(t *testing.T) {
+	t.Run("not_allowed", func(t *testing.T) {
+		e := NewExecCapture(&NewExecCaptureInput{
+			Allowed: []string{"echo"},
+		})
+		if _, err := e.Run("/bin/echo", "a"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		e := NewExecCapture(&NewExecCaptureInput{
+			Allowed: []string{"false"},
+		})
+		if _, err := e.Run("false"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		e := NewExecCapture(&NewExecCaptureInput{
+			Allowed: []string{"sleep"},
+			Timeout: 50 * time.Millisecond,
+		})
+		_, err := e.Run("sleep", "5")
+		if err == nil || !strings.Contains(err.Error(), "did not finish") {
+			t.Fatalf("expected a timeout, got %v", err)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "out")
+		if err := ioutil.WriteFile(path, []byte("before"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		e := NewExecCapture(&NewExecCaptureInput{
+			Allowed:  []string{"cat"},
+			CacheTTL: time.Minute,
+		})
+		if out, err := e.Run("cat", path); err != nil || out != "before" {
+			t.Fatalf("expected %q, got %q (%v)", "before", out, err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte("after"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := e.Run("cat", path); err != nil || out != "before" {
+			t.Fatalf("expected the cached %q, got %q (%v)", "before", out, err)
+		}
+	})
+}
@@ -2,13 +2,23 @@ package template
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"net"
 	"os"
 	"os/exec"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -16,7 +26,9 @@ import (
 
 	"github.com/burntsushi/toml"
 	dep "github.com/hashicorp/consul-template/dependency"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -24,6 +36,51 @@ import (
 // primarily for the tests to override times.
 var now = func() time.Time { return time.Now().UTC() }
 
+// aclSelfFunc returns or accumulates the dependency on the ACL token of Consul
+// Template.
+func aclSelfFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.ACLToken, error) {
+	return func(s ...string) (*dep.ACLToken, error) {
+		result := &dep.ACLToken{}
+
+		d, err := dep.NewACLTokenSelfQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(*dep.ACLToken), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// autopilotHealthFunc returns or accumulates autopilot health dependencies.
+func autopilotHealthFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.AutopilotHealth, error) {
+	return func(s ...string) (*dep.AutopilotHealth, error) {
+		result := &dep.AutopilotHealth{}
+
+		d, err := dep.NewOperatorAutopilotHealthQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(*dep.AutopilotHealth), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
 // datacentersFunc returns or accumulates datacenter dependencies.
 func datacentersFunc(b *Brain, used, missing *dep.Set) func() ([]string, error) {
 	return func() ([]string, error) {
@@ -46,6 +103,29 @@ func datacentersFunc(b *Brain, used, missing *dep.Set) func() ([]string, error)
 	}
 }
 
+// errorForFunc returns the error the most recent fetch of a dependency
+// returned, or an empty string if it has not failed. It must be given the
+// result of an API function, such as (key "foo"), and reports on the
+// dependency of that call. A failing dependency which is checked this way is
+// no longer treated as missing, so the template can render a fallback for it
+// instead of waiting for the data.
+func errorForFunc(b *Brain, used, missing *dep.Set) func(interface{}) string {
+	return func(interface{}) string {
+		d := used.Last()
+		if d == nil {
+			return ""
+		}
+
+		err := b.Error(d)
+		if err == nil {
+			return ""
+		}
+
+		missing.Remove(d)
+		return err.Error()
+	}
+}
+
 // executeTemplateFunc executes the given template in the context of the
 // parent. If an argument is specified, it will be used as the context instead.
 // This can be used for nested template definitions.
@@ -69,13 +149,18 @@ func executeTemplateFunc(t *template.Template) func(string, ...interface{}) (str
 	}
 }
 
-// fileFunc returns or accumulates file dependencies.
-func fileFunc(b *Brain, used, missing *dep.Set) func(string) (string, error) {
+// fileFunc returns or accumulates file dependencies. Files outside of the
+// sandbox, if any, are an error.
+func fileFunc(b *Brain, used, missing *dep.Set, sandbox string) func(string) (string, error) {
 	return func(s string) (string, error) {
 		if len(s) == 0 {
 			return "", nil
 		}
 
+		if err := pathInSandbox(sandbox, s); err != nil {
+			return "", fmt.Errorf("file: %s", err)
+		}
+
 		d, err := dep.NewFileQuery(s)
 		if err != nil {
 			return "", err
@@ -176,6 +261,57 @@ func keyWithDefaultFunc(b *Brain, used, missing *dep.Set) func(string, string) (
 	}
 }
 
+// kv2Func returns or accumulates secret dependencies read from a version 2 KV
+// secrets engine in Vault. New versions are held back by the secret
+// rotation, if any.
+func kv2Func(b *Brain, used, missing *dep.Set, rotation *SecretRotation, now time.Time) func(string) (*dep.Secret, error) {
+	return func(s string) (*dep.Secret, error) {
+		result := &dep.Secret{}
+
+		if len(s) == 0 {
+			return result, nil
+		}
+
+		d, err := dep.NewVaultKV2ReadQuery(s)
+		if err != nil {
+			return result, nil
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			result = rotation.Secret(s, value.(*dep.Secret), now)
+			return result, nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// licenseFunc returns or accumulates Consul Enterprise license dependencies.
+func licenseFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.License, error) {
+	return func(s ...string) (*dep.License, error) {
+		result := &dep.License{}
+
+		d, err := dep.NewOperatorLicenseQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(*dep.License), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
 // lsFunc returns or accumulates keyPrefix dependencies.
 func lsFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, error) {
 	return func(s string) ([]*dep.KeyPair, error) {
@@ -208,6 +344,14 @@ func lsFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, erro
 	}
 }
 
+// lsDiffFunc returns the top-level key pairs at the given prefix and their
+// changes since the template last rendered.
+func lsDiffFunc(b *Brain, id string, used, missing *dep.Set) func(string) (*KVDiff, error) {
+	return kvDiffFunc(b, id, used, missing, func(pair *dep.KeyPair) bool {
+		return pair.Key != "" && !strings.Contains(pair.Key, "/")
+	})
+}
+
 // nodeFunc returns or accumulates catalog node dependency.
 func nodeFunc(b *Brain, used, missing *dep.Set) func(...string) (*dep.CatalogNode, error) {
 	return func(s ...string) (*dep.CatalogNode, error) {
@@ -251,8 +395,54 @@ func nodesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Node, e
 	}
 }
 
-// secretFunc returns or accumulates secret dependencies from Vault.
-func secretFunc(b *Brain, used, missing *dep.Set) func(string) (*dep.Secret, error) {
+// peeringsFunc returns or accumulates cluster peering dependencies.
+func peeringsFunc(b *Brain, used, missing *dep.Set) func() ([]*dep.Peering, error) {
+	return func() ([]*dep.Peering, error) {
+		result := []*dep.Peering{}
+
+		d, err := dep.NewPeeringsQuery("")
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.Peering), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// raftConfigurationFunc returns or accumulates Raft configuration
+// dependencies.
+func raftConfigurationFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.RaftServer, error) {
+	return func(s ...string) ([]*dep.RaftServer, error) {
+		result := []*dep.RaftServer{}
+
+		d, err := dep.NewOperatorRaftConfigurationQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.RaftServer), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// secretFunc returns or accumulates secret dependencies from Vault. New
+// versions are held back by the secret rotation, if any.
+func secretFunc(b *Brain, used, missing *dep.Set, rotation *SecretRotation, now time.Time) func(string) (*dep.Secret, error) {
 	return func(s string) (*dep.Secret, error) {
 		result := &dep.Secret{}
 
@@ -268,7 +458,7 @@ func secretFunc(b *Brain, used, missing *dep.Set) func(string) (*dep.Secret, err
 		used.Add(d)
 
 		if value, ok := b.Recall(d); ok {
-			result = value.(*dep.Secret)
+			result = rotation.Secret(s, value.(*dep.Secret), now)
 			return result, nil
 		}
 
@@ -305,6 +495,45 @@ func secretsFunc(b *Brain, used, missing *dep.Set) func(string) ([]string, error
 	}
 }
 
+// pkiCertFunc returns or accumulates a certificate issued by a Vault PKI role.
+// The data sent to the role is given as "key=value" arguments. A new
+// certificate is only issued once the given fraction of the validity of the
+// current one has passed.
+func pkiCertFunc(b *Brain, used, missing *dep.Set, fraction float64) func(string, ...string) (*dep.VaultPKI, error) {
+	return func(path string, args ...string) (*dep.VaultPKI, error) {
+		result := &dep.VaultPKI{}
+
+		if len(path) == 0 {
+			return result, nil
+		}
+
+		data := make(map[string]interface{}, len(args))
+		for _, arg := range args {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("pkiCert: invalid argument %q, expected key=value", arg)
+			}
+			data[parts[0]] = parts[1]
+		}
+
+		d, err := dep.NewVaultPKIQuery(path, data, fraction)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			result = value.(*dep.VaultPKI)
+			return result, nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
 // serviceFunc returns or accumulates health service dependencies.
 func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.HealthService, error) {
 	return func(s ...string) ([]*dep.HealthService, error) {
@@ -314,6 +543,14 @@ func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Healt
 			return result, nil
 		}
 
+		// A cluster peer may be given as a separate argument, such as
+		// service "web" "peer=partner".
+		for i := 1; i < len(s); i++ {
+			if strings.HasPrefix(s[i], "peer=") {
+				s[i] = "?" + s[i]
+			}
+		}
+
 		d, err := dep.NewHealthServiceQuery(strings.Join(s, ""))
 		if err != nil {
 			return nil, err
@@ -331,6 +568,28 @@ func serviceFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Healt
 	}
 }
 
+// segmentsFunc returns or accumulates network segment dependencies.
+func segmentsFunc(b *Brain, used, missing *dep.Set) func(...string) ([]string, error) {
+	return func(s ...string) ([]string, error) {
+		result := []string{}
+
+		d, err := dep.NewOperatorSegmentsQuery(strings.Join(s, ""))
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]string), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
 // servicesFunc returns or accumulates catalog services dependencies.
 func servicesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.CatalogSnippet, error) {
 	return func(s ...string) ([]*dep.CatalogSnippet, error) {
@@ -353,6 +612,54 @@ func servicesFunc(b *Brain, used, missing *dep.Set) func(...string) ([]*dep.Cata
 	}
 }
 
+// nomadServiceFunc returns or accumulates Nomad service dependencies.
+func nomadServiceFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.NomadService, error) {
+	return func(s string) ([]*dep.NomadService, error) {
+		result := []*dep.NomadService{}
+
+		if len(s) == 0 {
+			return result, nil
+		}
+
+		d, err := dep.NewNomadServiceQuery(s)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.NomadService), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// nomadServicesFunc returns or accumulates Nomad services dependencies.
+func nomadServicesFunc(b *Brain, used, missing *dep.Set) func() ([]*dep.NomadServicesSnippet, error) {
+	return func() ([]*dep.NomadServicesSnippet, error) {
+		result := []*dep.NomadServicesSnippet{}
+
+		d, err := dep.NewNomadServicesQuery("")
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.NomadServicesSnippet), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
 // treeFunc returns or accumulates keyPrefix dependencies.
 func treeFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, error) {
 	return func(s string) ([]*dep.KeyPair, error) {
@@ -386,60 +693,185 @@ func treeFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, er
 	}
 }
 
-// byKey accepts a slice of KV pairs and returns a map of the top-level
-// key to all its subkeys. For example:
-//
-//		elasticsearch/a //=> "1"
-//		elasticsearch/b //=> "2"
-//		redis/a/b //=> "3"
-//
-// Passing the result from Consul through byTag would yield:
-//
-// 		map[string]map[string]string{
-//	  	"elasticsearch": &dep.KeyPair{"a": "1"}, &dep.KeyPair{"b": "2"},
-//			"redis": &dep.KeyPair{"a/b": "3"}
-//		}
-//
-// Note that the top-most key is stripped from the Key value. Keys that have no
-// prefix after stripping are removed from the list.
-func byKey(pairs []*dep.KeyPair) (map[string]map[string]*dep.KeyPair, error) {
-	m := make(map[string]map[string]*dep.KeyPair)
-	for _, pair := range pairs {
+// treeDiffFunc returns all key pairs at the given prefix and their changes
+// since the template last rendered.
+func treeDiffFunc(b *Brain, id string, used, missing *dep.Set) func(string) (*KVDiff, error) {
+	return kvDiffFunc(b, id, used, missing, func(pair *dep.KeyPair) bool {
 		parts := strings.Split(pair.Key, "/")
-		top := parts[0]
-		key := strings.Join(parts[1:], "/")
+		return parts[len(parts)-1] != ""
+	})
+}
 
-		if key == "" {
-			// Do not add a key if it has no prefix after stripping.
-			continue
-		}
+// KVDiff is the data at a KV prefix and its changes since the template last
+// rendered. Added and Changed hold current pairs, and Removed holds the pairs
+// as they were at the last render. Before the first render, every pair is
+// added.
+type KVDiff struct {
+	Current []*dep.KeyPair
+	Added   []*dep.KeyPair
+	Changed []*dep.KeyPair
+	Removed []*dep.KeyPair
+}
 
-		if _, ok := m[top]; !ok {
-			m[top] = make(map[string]*dep.KeyPair)
+// Empty returns true if nothing changed since the template last rendered.
+func (d *KVDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// kvDiffFunc returns the key pairs at the given prefix which match the filter,
+// and their changes since the template with the given ID last rendered. A pair
+// changed if its value or flags changed.
+func kvDiffFunc(b *Brain, id string, used, missing *dep.Set, filter func(*dep.KeyPair) bool) func(string) (*KVDiff, error) {
+	return func(s string) (*KVDiff, error) {
+		result := &KVDiff{}
+
+		if len(s) == 0 {
+			return result, nil
 		}
 
-		newPair := *pair
-		newPair.Key = key
-		m[top][key] = &newPair
-	}
+		d, err := dep.NewKVListQuery(s)
+		if err != nil {
+			return result, err
+		}
 
-	return m, nil
-}
+		used.Add(d)
 
-// byTag is a template func that takes the provided services and
-// produces a map based on Service tags.
-//
-// The map key is a string representing the service tag. The map value is a
-// slice of Services which have the tag assigned.
-func byTag(in interface{}) (map[string][]interface{}, error) {
-	m := make(map[string][]interface{})
+		value, ok := b.Recall(d)
+		if !ok {
+			missing.Add(d)
+			return result, nil
+		}
 
-	switch typed := in.(type) {
-	case nil:
-	case []*dep.CatalogSnippet:
-		for _, s := range typed {
-			for _, t := range s.Tags {
-				m[t] = append(m[t], s)
+		previous := make(map[string]*dep.KeyPair)
+		if data, ok := b.RecallRendered(id, d); ok {
+			for _, pair := range data.([]*dep.KeyPair) {
+				if filter(pair) {
+					previous[pair.Key] = pair
+				}
+			}
+		}
+
+		for _, pair := range value.([]*dep.KeyPair) {
+			if !filter(pair) {
+				continue
+			}
+			result.Current = append(result.Current, pair)
+
+			prev, ok := previous[pair.Key]
+			switch {
+			case !ok:
+				result.Added = append(result.Added, pair)
+			case prev.Value != pair.Value || prev.Flags != pair.Flags:
+				result.Changed = append(result.Changed, pair)
+			}
+			delete(previous, pair.Key)
+		}
+
+		// Keep the removed pairs in key order, like the other lists.
+		if len(previous) > 0 {
+			keys := make([]string, 0, len(previous))
+			for k := range previous {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				result.Removed = append(result.Removed, previous[k])
+			}
+		}
+
+		return result, nil
+	}
+}
+
+// treeMatchingFunc returns a slice of key pairs under the given prefix whose
+// keys, relative to the prefix, match the given regular expression. Changes to
+// other keys under the prefix do not re-render the template.
+func treeMatchingFunc(b *Brain, used, missing *dep.Set) func(string, string) ([]*dep.KeyPair, error) {
+	return func(s, pattern string) ([]*dep.KeyPair, error) {
+		result := []*dep.KeyPair{}
+
+		if len(s) == 0 {
+			return result, nil
+		}
+
+		d, err := dep.NewKVListMatchingQuery(s, pattern)
+		if err != nil {
+			return result, err
+		}
+
+		used.Add(d)
+
+		// Only return non-empty top-level keys
+		if value, ok := b.Recall(d); ok {
+			for _, pair := range value.([]*dep.KeyPair) {
+				parts := strings.Split(pair.Key, "/")
+				if parts[len(parts)-1] != "" {
+					result = append(result, pair)
+				}
+			}
+			return result, nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// byKey accepts a slice of KV pairs and returns a map of the top-level
+// key to all its subkeys. For example:
+//
+//		elasticsearch/a //=> "1"
+//		elasticsearch/b //=> "2"
+//		redis/a/b //=> "3"
+//
+// Passing the result from Consul through byTag would yield:
+//
+// 		map[string]map[string]string{
+//	  	"elasticsearch": &dep.KeyPair{"a": "1"}, &dep.KeyPair{"b": "2"},
+//			"redis": &dep.KeyPair{"a/b": "3"}
+//		}
+//
+// Note that the top-most key is stripped from the Key value. Keys that have no
+// prefix after stripping are removed from the list.
+func byKey(pairs []*dep.KeyPair) (map[string]map[string]*dep.KeyPair, error) {
+	m := make(map[string]map[string]*dep.KeyPair)
+	for _, pair := range pairs {
+		parts := strings.Split(pair.Key, "/")
+		top := parts[0]
+		key := strings.Join(parts[1:], "/")
+
+		if key == "" {
+			// Do not add a key if it has no prefix after stripping.
+			continue
+		}
+
+		if _, ok := m[top]; !ok {
+			m[top] = make(map[string]*dep.KeyPair)
+		}
+
+		newPair := *pair
+		newPair.Key = key
+		m[top][key] = &newPair
+	}
+
+	return m, nil
+}
+
+// byTag is a template func that takes the provided services and
+// produces a map based on Service tags.
+//
+// The map key is a string representing the service tag. The map value is a
+// slice of Services which have the tag assigned.
+func byTag(in interface{}) (map[string][]interface{}, error) {
+	m := make(map[string][]interface{})
+
+	switch typed := in.(type) {
+	case nil:
+	case []*dep.CatalogSnippet:
+		for _, s := range typed {
+			for _, t := range s.Tags {
+				m[t] = append(m[t], s)
 			}
 		}
 	case []*dep.CatalogService:
@@ -461,6 +893,211 @@ func byTag(in interface{}) (map[string][]interface{}, error) {
 	return m, nil
 }
 
+// groupBy is a template func that groups the elements of a list (or the values
+// of a map) by the value at the given dotted field path, for example:
+//
+//		{{ range $wan, $nodes := nodes | groupBy "TaggedAddresses.wan" }}
+//
+// The map key is the string form of the field value. Elements are kept in
+// their original order within each group.
+func groupBy(path string, in interface{}) (map[string][]interface{}, error) {
+	list, err := fieldPathElems(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "groupBy")
+	}
+
+	m := make(map[string][]interface{})
+	for _, elem := range list {
+		v, err := fieldPathValue(elem, path)
+		if err != nil {
+			return nil, errors.Wrap(err, "groupBy")
+		}
+		key := fieldPathString(v)
+		m[key] = append(m[key], elem)
+	}
+
+	return m, nil
+}
+
+// sortBy is a template func that sorts the elements of a list (or the values of
+// a map) by the value at the given dotted field path. Numbers are compared
+// numerically and everything else by its string form. The sort is stable.
+func sortBy(path string, in interface{}) ([]interface{}, error) {
+	list, err := fieldPathElems(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "sortBy")
+	}
+
+	sorted := make(byFieldPathValue, len(list))
+	for i, elem := range list {
+		sorted[i].elem = elem
+		if sorted[i].value, err = fieldPathValue(elem, path); err != nil {
+			return nil, errors.Wrap(err, "sortBy")
+		}
+	}
+	sort.Stable(sorted)
+
+	result := make([]interface{}, len(list))
+	for i, e := range sorted {
+		result[i] = e.elem
+	}
+	return result, nil
+}
+
+// uniqBy is a template func that returns the elements of a list (or the values
+// of a map), keeping only the first element for each distinct value at the
+// given dotted field path.
+func uniqBy(path string, in interface{}) ([]interface{}, error) {
+	list, err := fieldPathElems(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "uniqBy")
+	}
+
+	seen := make(map[string]struct{})
+	result := make([]interface{}, 0, len(list))
+	for _, elem := range list {
+		v, err := fieldPathValue(elem, path)
+		if err != nil {
+			return nil, errors.Wrap(err, "uniqBy")
+		}
+		key := fieldPathString(v)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, elem)
+	}
+
+	return result, nil
+}
+
+// fieldPathElems returns the elements of the given slice or array, or the
+// values of the given map ordered by key.
+func fieldPathElems(in interface{}) ([]interface{}, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(in)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			list[i] = v.Index(i).Interface()
+		}
+		return list, nil
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Sort(byMapKeyString(keys))
+		list := make([]interface{}, len(keys))
+		for i, k := range keys {
+			list[i] = v.MapIndex(k).Interface()
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("wrong argument type %T", in)
+	}
+}
+
+// fieldPathValue returns the value at the given dotted path, descending into
+// struct fields and map keys. Missing map keys and nil values along the path
+// result in nil, while unknown struct fields are an error.
+func fieldPathValue(in interface{}, path string) (interface{}, error) {
+	v := reflect.ValueOf(in)
+	if path == "" || path == "." {
+		return in, nil
+	}
+
+	for _, part := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f := v.FieldByName(part)
+			if !f.IsValid() {
+				return nil, fmt.Errorf("no field %q in %s", part, v.Type())
+			}
+			v = f
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("cannot index %s with %q", v.Type(), part)
+			}
+			v = v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return nil, nil
+			}
+		case reflect.Invalid:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("cannot get %q from %s", part, v.Type())
+		}
+	}
+
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, nil
+	}
+	return v.Interface(), nil
+}
+
+// fieldPathString returns the string form of a field value, using the empty
+// string for nil.
+func fieldPathString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// fieldPathLess compares two field values, numerically if both are numbers and
+// by their string form otherwise.
+func fieldPathLess(a, b interface{}) bool {
+	af, aok := fieldPathFloat(a)
+	bf, bok := fieldPathFloat(b)
+	if aok && bok {
+		return af < bf
+	}
+	return fieldPathString(a) < fieldPathString(b)
+}
+
+// byFieldPathValue is a sortable list of elements by their values at a field
+// path.
+type byFieldPathValue []struct {
+	elem, value interface{}
+}
+
+func (s byFieldPathValue) Len() int           { return len(s) }
+func (s byFieldPathValue) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byFieldPathValue) Less(i, j int) bool { return fieldPathLess(s[i].value, s[j].value) }
+
+// byMapKeyString is a sortable list of map keys by their string form.
+type byMapKeyString []reflect.Value
+
+func (s byMapKeyString) Len() int      { return len(s) }
+func (s byMapKeyString) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byMapKeyString) Less(i, j int) bool {
+	return fmt.Sprint(s[i].Interface()) < fmt.Sprint(s[j].Interface())
+}
+
+// fieldPathFloat returns the given value as a float64 if it is a number.
+func fieldPathFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
 // contains is a function that have reverse arguments of "in" and is designed to
 // be used as a pipe instead of a function:
 //
@@ -495,17 +1132,45 @@ func containsSomeFunc(ret_true, invert bool) func([]interface{}, interface{}) (b
 // real environment variables
 func envFunc(env []string) func(string) (string, error) {
 	return func(s string) (string, error) {
-		for _, e := range env {
-			split := strings.SplitN(e, "=", 2)
-			k, v := split[0], split[1]
-			if k == s {
-				return v, nil
-			}
+		return lookupEnv(env, s), nil
+	}
+}
+
+// envDefaultFunc returns a function which reads the given environment variable
+// like env, but returns the fallback if the variable is unset or empty.
+func envDefaultFunc(env []string) func(string, string) (string, error) {
+	return func(s, fallback string) (string, error) {
+		if v := lookupEnv(env, s); v != "" {
+			return v, nil
+		}
+		return fallback, nil
+	}
+}
+
+// mustEnvFunc returns a function which reads the given environment variable
+// like env, but fails the render if the variable is unset or empty.
+func mustEnvFunc(env []string) func(string) (string, error) {
+	return func(s string) (string, error) {
+		if v := lookupEnv(env, s); v != "" {
+			return v, nil
 		}
-		return os.Getenv(s), nil
+		return "", fmt.Errorf("mustEnv: environment variable %q is not set", s)
 	}
 }
 
+// lookupEnv returns the value of the given environment variable, preferring
+// the given custom environment over the environment of the process.
+func lookupEnv(env []string, s string) string {
+	for _, e := range env {
+		split := strings.SplitN(e, "=", 2)
+		k, v := split[0], split[1]
+		if k == s {
+			return v
+		}
+	}
+	return os.Getenv(s)
+}
+
 // explode is used to expand a list of keypairs into a deeply-nested hash.
 func explode(pairs []*dep.KeyPair) (map[string]interface{}, error) {
 	m := make(map[string]interface{})
@@ -541,6 +1206,113 @@ func explodeHelper(m map[string]interface{}, k, v, p string) error {
 	return nil
 }
 
+// mergeTreesDeleteMarker is the default value which deletes a key, or a whole
+// folder, from the layers mergeTrees merged before.
+const mergeTreesDeleteMarker = "__delete__"
+
+// mergeTrees deep-merges layers of KV pairs, such as defaults, environment and
+// host overrides, into a single deeply-nested hash like explode returns. Each
+// layer is the result of tree or ls, or a hash returned by explode. By default,
+// later layers take precedence over earlier ones; the leading option
+// "precedence=first" reverses this. A value equal to the delete marker, which
+// can be changed with the leading option "delete=<marker>", removes the key
+// from the layers with lower precedence.
+func mergeTrees(args ...interface{}) (map[string]interface{}, error) {
+	first := false
+	marker := mergeTreesDeleteMarker
+
+	// Leading string arguments are options.
+	for len(args) > 0 {
+		opt, ok := args[0].(string)
+		if !ok {
+			break
+		}
+		args = args[1:]
+
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("mergeTrees: invalid option %q", opt)
+		}
+		switch parts[0] {
+		case "precedence":
+			switch parts[1] {
+			case "first":
+				first = true
+			case "last":
+				first = false
+			default:
+				return nil, fmt.Errorf("mergeTrees: invalid precedence %q", parts[1])
+			}
+		case "delete":
+			if parts[1] == "" {
+				return nil, fmt.Errorf("mergeTrees: delete marker cannot be empty")
+			}
+			marker = parts[1]
+		default:
+			return nil, fmt.Errorf("mergeTrees: unknown option %q", parts[0])
+		}
+	}
+
+	layers := make([]map[string]interface{}, 0, len(args))
+	for i, arg := range args {
+		var layer map[string]interface{}
+		switch typed := arg.(type) {
+		case []*dep.KeyPair:
+			m, err := explode(typed)
+			if err != nil {
+				return nil, errors.Wrapf(err, "mergeTrees: layer %d", i)
+			}
+			layer = m
+		case map[string]interface{}:
+			layer = typed
+		case nil:
+			continue
+		default:
+			return nil, fmt.Errorf("mergeTrees: layer %d: unsupported type %T", i, arg)
+		}
+		layers = append(layers, layer)
+	}
+
+	// Merge from the lowest precedence to the highest.
+	if first {
+		for i, j := 0, len(layers)-1; i < j; i, j = i+1, j-1 {
+			layers[i], layers[j] = layers[j], layers[i]
+		}
+	}
+
+	result := make(map[string]interface{})
+	for _, layer := range layers {
+		mergeTreesHelper(result, layer, marker)
+	}
+	return result, nil
+}
+
+// mergeTreesHelper is a recursive helper for mergeTrees which merges src into
+// dst. Hashes are merged, any other value replaces the value in dst.
+func mergeTreesHelper(dst, src map[string]interface{}, marker string) {
+	for k, v := range src {
+		if s, ok := v.(string); ok && s == marker {
+			delete(dst, k)
+			continue
+		}
+
+		nest, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+
+		// Copy the hash instead of sharing it, so merging later layers never
+		// changes the layers given to mergeTrees.
+		existing, ok := dst[k].(map[string]interface{})
+		if !ok {
+			existing = make(map[string]interface{})
+			dst[k] = existing
+		}
+		mergeTreesHelper(existing, nest, marker)
+	}
+}
+
 // in searches for a given value in a given interface.
 func in(l, v interface{}) (bool, error) {
 	lv := reflect.ValueOf(l)
@@ -695,41 +1467,166 @@ func parseJSON(s string) (interface{}, error) {
 	return data, nil
 }
 
-// parseUint parses a string into a base 10 int
-func parseUint(s string) (uint64, error) {
+// The keys of the attributes and the text of an element in the maps used by
+// parseXML and toXML.
+const (
+	xmlAttrPrefix = "@"
+	xmlTextKey    = "#text"
+)
+
+// parseXML parses the given XML document into nested maps. The root element is
+// the only key of the returned map. Each element is a map of its attributes,
+// prefixed with "@", its child elements, and its text under "#text". Repeated
+// child elements are a list, and an element with only text is a string.
+// Comments, processing instructions and directives are ignored.
+func parseXML(s string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
 	if s == "" {
-		return 0, nil
+		return result, nil
 	}
 
-	result, err := strconv.ParseUint(s, 10, 64)
-	if err != nil {
-		return 0, errors.Wrap(err, "parseUint")
+	// The elements being parsed, innermost last.
+	type element struct {
+		name     string
+		children map[string]interface{}
+		text     bytes.Buffer
 	}
-	return result, nil
-}
+	var stack []*element
 
-// plugin executes a subprocess as the given command string. It is assumed the
-// resulting command returns JSON which is then parsed and returned as the
-// value for use in the template.
-func plugin(name string, args ...string) (string, error) {
-	if name == "" {
-		return "", nil
-	}
+	d := xml.NewDecoder(strings.NewReader(s))
+	for {
+		// Raw tokens keep the namespace prefixes of names as they are written.
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parseXML")
+		}
 
-	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{
+				name:     xmlName(t.Name),
+				children: make(map[string]interface{}),
+			}
+			for _, attr := range t.Attr {
+				e.children[xmlAttrPrefix+xmlName(attr.Name)] = attr.Value
+			}
+			stack = append(stack, e)
 
-	// Strip and trim each arg or else some plugins get confused with the newline
-	// characters
-	jsons := make([]string, 0, len(args))
-	for _, arg := range args {
-		if v := strings.TrimSpace(arg); v != "" {
-			jsons = append(jsons, v)
-		}
-	}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
 
-	cmd := exec.Command(name, jsons...)
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].name != xmlName(t.Name) {
+				return nil, fmt.Errorf("parseXML: unexpected end element </%s>", xmlName(t.Name))
+			}
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			var value interface{}
+			text := strings.TrimSpace(e.text.String())
+			if len(e.children) == 0 {
+				value = text
+			} else {
+				if text != "" {
+					e.children[xmlTextKey] = text
+				}
+				value = e.children
+			}
+
+			parent := result
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1].children
+			} else if len(result) > 0 {
+				return nil, fmt.Errorf("parseXML: multiple root elements")
+			}
+
+			switch existing := parent[e.name].(type) {
+			case nil:
+				parent[e.name] = value
+			case []interface{}:
+				parent[e.name] = append(existing, value)
+			default:
+				parent[e.name] = []interface{}{existing, value}
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("parseXML: unclosed element <%s>", stack[len(stack)-1].name)
+	}
+
+	return result, nil
+}
+
+// xmlName returns the name as written in the document, with its namespace
+// prefix if any.
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// parseUint parses a string into a base 10 int
+func parseUint(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "parseUint")
+	}
+	return result, nil
+}
+
+// pluginFunc returns the plugin function, which only executes commands inside
+// the sandbox, if any. Commands are looked up in the PATH first, and the
+// resolved path is executed.
+func pluginFunc(sandbox string) func(string, ...string) (string, error) {
+	return func(name string, args ...string) (string, error) {
+		if sandbox == "" || name == "" {
+			return plugin(name, args...)
+		}
+
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("plugin: %s", err)
+		}
+		if err := pathInSandbox(sandbox, path); err != nil {
+			return "", fmt.Errorf("plugin: %s", err)
+		}
+		return plugin(path, args...)
+	}
+}
+
+// plugin executes a subprocess as the given command string. It is assumed the
+// resulting command returns JSON which is then parsed and returned as the
+// value for use in the template.
+func plugin(name string, args ...string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+
+	// Strip and trim each arg or else some plugins get confused with the newline
+	// characters
+	jsons := make([]string, 0, len(args))
+	for _, arg := range args {
+		if v := strings.TrimSpace(arg); v != "" {
+			jsons = append(jsons, v)
+		}
+	}
+
+	cmd := exec.Command(name, jsons...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 	if err := cmd.Start(); err != nil {
 		return "", fmt.Errorf("exec %q: %s\n\nstdout:\n\n%s\n\nstderr:\n\n%s",
 			name, err, stdout.Bytes(), stderr.Bytes())
@@ -759,6 +1656,43 @@ func plugin(name string, args ...string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// alphaNum is the set of characters randAlphaNum chooses from.
+const alphaNum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// randAlphaNumFunc returns a random alphanumeric string of the given length.
+// The value is drawn from r, so it is stable for a given seed.
+func randAlphaNumFunc(r *rand.Rand) func(int) (string, error) {
+	return func(n int) (string, error) {
+		if n < 0 {
+			return "", fmt.Errorf("randAlphaNum: length must not be negative, got %d", n)
+		}
+
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphaNum[r.Intn(len(alphaNum))]
+		}
+		return string(b), nil
+	}
+}
+
+// randomChoiceFunc returns a random element of the given list. The element is
+// drawn from r, so it is stable for a given seed.
+func randomChoiceFunc(r *rand.Rand) func(interface{}) (interface{}, error) {
+	return func(list interface{}) (interface{}, error) {
+		v := reflect.ValueOf(list)
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice:
+		default:
+			return nil, fmt.Errorf("randomChoice: unsupported type %T", list)
+		}
+
+		if v.Len() == 0 {
+			return nil, errors.New("randomChoice: list is empty")
+		}
+		return v.Index(r.Intn(v.Len())).Interface(), nil
+	}
+}
+
 // replaceAll replaces all occurrences of a value in a string with the given
 // replacement value.
 func replaceAll(f, t, s string) (string, error) {
@@ -794,23 +1728,86 @@ func split(sep, s string) ([]string, error) {
 	return strings.Split(s, sep), nil
 }
 
-// timestamp returns the current UNIX timestamp in UTC. If an argument is
-// specified, it will be used to format the timestamp.
-func timestamp(s ...string) (string, error) {
-	switch len(s) {
-	case 0:
-		return now().Format(time.RFC3339), nil
-	case 1:
-		if s[0] == "unix" {
-			return strconv.FormatInt(now().Unix(), 10), nil
+// varFunc returns the value of the given user-defined variable. It is an error
+// to use a variable which is not defined.
+func varFunc(vars map[string]string) func(string) (string, error) {
+	return func(s string) (string, error) {
+		v, ok := vars[s]
+		if !ok {
+			return "", fmt.Errorf("var: %q is not defined", s)
+		}
+		return v, nil
+	}
+}
+
+// timestampFunc returns the UNIX timestamp of the given render time in UTC. If
+// an argument is specified, it will be used to format the timestamp.
+func timestampFunc(t time.Time) func(...string) (string, error) {
+	return func(s ...string) (string, error) {
+		switch len(s) {
+		case 0:
+			return t.Format(time.RFC3339), nil
+		case 1:
+			if s[0] == "unix" {
+				return strconv.FormatInt(t.Unix(), 10), nil
+			}
+			return t.Format(s[0]), nil
+		default:
+			return "", fmt.Errorf("timestamp: wrong number of arguments, expected 0 or 1"+
+				", but got %d", len(s))
 		}
-		return now().Format(s[0]), nil
+	}
+}
+
+// nowFunc returns the given render time, so every use of now in a render
+// returns the same time.
+func nowFunc(t time.Time) func() time.Time {
+	return func() time.Time {
+		return t
+	}
+}
+
+// parseDuration parses the given string as a duration, such as "1h30m".
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Wrap(err, "parseDuration")
+	}
+	return d, nil
+}
+
+// addDuration adds the given duration to the given time. The duration may be a
+// time.Duration or a string, such as "-1h", which is parsed as a duration.
+func addDuration(d interface{}, t time.Time) (time.Time, error) {
+	switch v := d.(type) {
+	case time.Duration:
+		return t.Add(v), nil
+	case string:
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "addDuration")
+		}
+		return t.Add(dur), nil
 	default:
-		return "", fmt.Errorf("timestamp: wrong number of arguments, expected 0 or 1"+
-			", but got %d", len(s))
+		return time.Time{}, fmt.Errorf("addDuration: unknown duration type %T", d)
 	}
 }
 
+// timestampFormat formats the given time with the given layout. As a special
+// case, the layout "unix" returns the UNIX timestamp in seconds.
+func timestampFormat(layout string, t time.Time) string {
+	if layout == "unix" {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(layout)
+}
+
+// unixNano returns the given time as the number of nanoseconds since the UNIX
+// epoch.
+func unixNano(t time.Time) int64 {
+	return t.UnixNano()
+}
+
 // toLower converts the given string (usually by a pipe) to lowercase.
 func toLower(s string) (string, error) {
 	return strings.ToLower(s), nil
@@ -868,6 +1865,169 @@ func toTOML(m map[string]interface{}) (string, error) {
 	return string(bytes.TrimSpace(result)), nil
 }
 
+// toXML converts the given structure into an indented XML document, the
+// inverse of parseXML. The map must have a single key, the root element. Keys
+// prefixed with "@" are attributes, "#text" is the text of the element and
+// lists are repeated elements. Attributes and child elements are written in
+// key order, so the output is stable.
+func toXML(m map[string]interface{}) (string, error) {
+	if len(m) != 1 {
+		return "", fmt.Errorf("toXML: expected a single root element, got %d", len(m))
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	for name, value := range m {
+		if err := encodeXMLElement(enc, name, value); err != nil {
+			return "", errors.Wrap(err, "toXML")
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return "", errors.Wrap(err, "toXML")
+	}
+	return buf.String(), nil
+}
+
+// encodeXMLElement writes the value as elements with the given name.
+func encodeXMLElement(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := encodeXMLElement(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var children []string
+		for _, k := range keys {
+			switch {
+			case strings.HasPrefix(k, xmlAttrPrefix):
+				start.Attr = append(start.Attr, xml.Attr{
+					Name:  xml.Name{Local: strings.TrimPrefix(k, xmlAttrPrefix)},
+					Value: xmlText(v[k]),
+				})
+			case k != xmlTextKey:
+				children = append(children, k)
+			}
+		}
+
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if text, ok := v[xmlTextKey]; ok {
+			if err := enc.EncodeToken(xml.CharData(xmlText(text))); err != nil {
+				return err
+			}
+		}
+		for _, k := range children {
+			if err := encodeXMLElement(enc, k, v[k]); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+
+	default:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if text := xmlText(v); text != "" {
+			if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+}
+
+// xmlText returns the text of a scalar value.
+func xmlText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// sha256sum returns the hex-encoded SHA-256 checksum of the given string.
+func sha256sum(s string) (string, error) {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// md5sum returns the hex-encoded MD5 checksum of the given string.
+func md5sum(s string) (string, error) {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hmacSHA256 returns the hex-encoded HMAC-SHA256 of the message using the
+// given key.
+func hmacSHA256(key, msg string) (string, error) {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// bcryptCacheSize is the number of passwords whose bcrypt hash is cached.
+const bcryptCacheSize = 1024
+
+// bcryptCache holds the bcrypt hash of the most recently hashed passwords,
+// keyed by the SHA-256 of the password. bcrypt uses a random salt, so without
+// the cache every render would produce different output.
+var bcryptCache, _ = lru.New(bcryptCacheSize)
+
+// bcryptFunc returns the bcrypt hash of the given string using the default
+// cost. The password is the last argument, so it can be piped. If it is given
+// a previous hash first which matches the password, that hash is returned, so
+// the output is stable across restarts too. Otherwise the hash is cached, so
+// the output is only stable between renders of the same process.
+func bcryptFunc(args ...string) (string, error) {
+	var previous, s string
+	switch len(args) {
+	case 1:
+		s = args[0]
+	case 2:
+		previous, s = args[0], args[1]
+	default:
+		return "", fmt.Errorf("bcrypt: wrong number of arguments, expected 1 or 2"+
+			", but got %d", len(args))
+	}
+
+	sum := sha256.Sum256([]byte(s))
+	key := hex.EncodeToString(sum[:])
+
+	if previous != "" && bcrypt.CompareHashAndPassword([]byte(previous), []byte(s)) == nil {
+		bcryptCache.Add(key, previous)
+		return previous, nil
+	}
+	if hash, ok := bcryptCache.Get(key); ok {
+		return hash.(string), nil
+	}
+
+	// Hashing takes a while by design, so it is done without holding the
+	// cache. If the same password was hashed meanwhile, that hash is kept.
+	hash, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "bcrypt")
+	}
+	if ok, _ := bcryptCache.ContainsOrAdd(key, string(hash)); ok {
+		if cached, ok := bcryptCache.Get(key); ok {
+			return cached.(string), nil
+		}
+	}
+	return string(hash), nil
+}
+
 // add returns the sum of a and b.
 func add(b, a interface{}) (interface{}, error) {
 	av := reflect.ValueOf(a)
@@ -1043,3 +2203,502 @@ func divide(b, a interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("divide: unknown type for %q (%T)", av, a)
 	}
 }
+
+// uuidv4Func returns a random (version 4) UUID. The UUID is drawn from r, so
+// it is stable for a given seed.
+func uuidv4Func(r *rand.Rand) func() (string, error) {
+	return func() (string, error) {
+		b := make([]byte, 16)
+		if _, err := r.Read(b); err != nil {
+			return "", errors.Wrap(err, "uuidv4")
+		}
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	}
+}
+
+// joinHostPort joins the given host and port into an address, bracketing IPv6
+// hosts, for example "[2001:db8::1]:8080". Brackets already around the host are
+// not doubled. The port can be a number or a string.
+func joinHostPort(host string, port interface{}) (string, error) {
+	var p string
+	switch typed := port.(type) {
+	case string:
+		p = typed
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		p = fmt.Sprint(typed)
+	default:
+		return "", fmt.Errorf("joinHostPort: wrong port type %T", port)
+	}
+	return net.JoinHostPort(unbracketHost(host), p), nil
+}
+
+// unbracketHost removes the brackets around an IPv6 host, if any.
+func unbracketHost(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// ipFamily returns the family of the given IP address, "v4" or "v6", or the
+// empty string if it is not an IP address. IPv6 addresses may be in brackets
+// or have a zone, and IPv4-mapped IPv6 addresses are "v4".
+func ipFamily(addr string) string {
+	host := unbracketHost(addr)
+	if i := strings.LastIndex(host, "%"); i != -1 {
+		host = host[:i]
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return "v4"
+	default:
+		return "v6"
+	}
+}
+
+// addressOf returns the address of an element of a list given to the address
+// family functions: the element itself if it is a string, otherwise its
+// ServiceAddress or, if that is empty or missing, its Address field.
+func addressOf(elem interface{}) string {
+	if s, ok := elem.(string); ok {
+		return s
+	}
+
+	v := reflect.ValueOf(elem)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for _, name := range []string{"ServiceAddress", "Address"} {
+		f := v.FieldByName(name)
+		if f.IsValid() && f.Kind() == reflect.String && f.String() != "" {
+			return f.String()
+		}
+	}
+	return ""
+}
+
+// filterByFamily returns the elements of the given list whose address is of
+// the given family, "v4" or "v6", for example:
+//
+//		{{ range service "web" | filterByFamily "v6" }}
+//
+// The list can hold strings or services and nodes, whose service address is
+// used if it is set and their address otherwise. Elements without an IP
+// address are left out.
+func filterByFamily(family string, in interface{}) ([]interface{}, error) {
+	if family != "v4" && family != "v6" {
+		return nil, fmt.Errorf("filterByFamily: family must be \"v4\" or \"v6\", got %q", family)
+	}
+
+	list, err := fieldPathElems(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "filterByFamily")
+	}
+
+	result := make([]interface{}, 0, len(list))
+	for _, elem := range list {
+		if ipFamily(addressOf(elem)) == family {
+			result = append(result, elem)
+		}
+	}
+	return result, nil
+}
+
+// inNetwork returns the elements of the given list whose address is within
+// one of the given networks, which are CIDRs separated by commas, for example:
+//
+//		{{ range service "web" | inNetwork "10.2.0.0/16,10.3.0.0/16" }}
+//
+// The list can hold strings or services and nodes, like filterByFamily.
+// Elements without an IP address are left out.
+func inNetwork(cidrs string, in interface{}) ([]interface{}, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(cidrs, ",") {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("inNetwork: invalid network %q", cidr)
+		}
+		networks = append(networks, network)
+	}
+
+	list, err := fieldPathElems(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "inNetwork")
+	}
+
+	result := make([]interface{}, 0, len(list))
+	for _, elem := range list {
+		ip := net.ParseIP(addressOf(elem))
+		if ip == nil {
+			continue
+		}
+		for _, network := range networks {
+			if network.Contains(ip) {
+				result = append(result, elem)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// preferAddressFunc returns a function which chooses an address from the given
+// list, such as the resolved IPs of a service or the tagged addresses of a
+// node. It returns the first address of the given preferred family, or the
+// first address if there is none or no family is preferred. The list can hold
+// strings or services and nodes, like filterByFamily, and maps are ordered by
+// their keys.
+func preferAddressFunc(family string) func(interface{}) (string, error) {
+	return func(in interface{}) (string, error) {
+		list, err := fieldPathElems(in)
+		if err != nil {
+			return "", errors.Wrap(err, "preferAddress")
+		}
+
+		var first string
+		for _, elem := range list {
+			addr := addressOf(elem)
+			if addr == "" {
+				continue
+			}
+			if family != "" && ipFamily(addr) == family {
+				return addr, nil
+			}
+			if first == "" {
+				first = addr
+			}
+		}
+		return first, nil
+	}
+}
+
+// deepGet is a template func that returns the value at the given path in a
+// nested structure, such as the result of parseJSON, for example:
+//
+//		{{ deepGet $pod "spec.containers.0.image" }}
+//		{{ deepGet $pod "/metadata/labels/app.kubernetes.io~1name" "unknown" }}
+//
+// A path starting with "/" is a JSON pointer, and any other path is dotted.
+// Numeric segments index lists. If any part of the path is missing, the
+// optional default is returned, or nil.
+func deepGet(in interface{}, path string, def ...interface{}) (interface{}, error) {
+	if len(def) > 1 {
+		return nil, fmt.Errorf("deepGet: wrong number of arguments, expected 2 or 3"+
+			", but got %d", len(def)+2)
+	}
+
+	parts, err := deepPath(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "deepGet")
+	}
+
+	v := reflect.ValueOf(in)
+	for _, part := range parts {
+		if v = deepIndex(v, part); !v.IsValid() {
+			break
+		}
+	}
+
+	if !v.IsValid() || !v.CanInterface() || deepIsNil(v) {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+		return nil, nil
+	}
+	return v.Interface(), nil
+}
+
+// deepSet is a template func that returns a copy of a nested structure, such
+// as the result of parseJSON, with the value at the given path replaced, for
+// example:
+//
+//		{{ $pod := deepSet $pod "spec.replicas" 3 }}
+//
+// Paths are the same as for deepGet. Missing maps along the path are created,
+// and a list index equal to the length of the list (or "-" in a JSON pointer)
+// appends to it. The given structure is not modified.
+func deepSet(in interface{}, path string, value interface{}) (interface{}, error) {
+	parts, err := deepPath(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "deepSet")
+	}
+
+	result, err := deepSetParts(in, parts, value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deepSet %q", path)
+	}
+	return result, nil
+}
+
+// deepSetParts returns a copy of the given structure with the value at the
+// given path segments replaced.
+func deepSetParts(in interface{}, parts []string, value interface{}) (interface{}, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+	part, rest := parts[0], parts[1:]
+
+	switch typed := in.(type) {
+	case nil:
+		if _, err := strconv.Atoi(part); err == nil || part == "-" {
+			return nil, fmt.Errorf("cannot create a list for %q", part)
+		}
+		v, err := deepSetParts(nil, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{part: v}, nil
+	case map[string]interface{}:
+		v, err := deepSetParts(typed[part], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(typed)+1)
+		for k, elem := range typed {
+			m[k] = elem
+		}
+		m[part] = v
+		return m, nil
+	case []interface{}:
+		i := len(typed)
+		if part != "-" {
+			var err error
+			if i, err = strconv.Atoi(part); err != nil || i < 0 || i > len(typed) {
+				return nil, fmt.Errorf("invalid index %q for a list of %d", part, len(typed))
+			}
+		}
+		var elem interface{}
+		if i < len(typed) {
+			elem = typed[i]
+		}
+		v, err := deepSetParts(elem, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		list := append([]interface{}{}, typed...)
+		if i == len(list) {
+			return append(list, v), nil
+		}
+		list[i] = v
+		return list, nil
+	default:
+		return nil, fmt.Errorf("cannot set %q in %T", part, in)
+	}
+}
+
+// deepPath splits the given JSON pointer or dotted path into its segments.
+func deepPath(path string) ([]string, error) {
+	if path == "" || path == "." {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return strings.Split(strings.TrimPrefix(path, "."), "."), nil
+	}
+
+	parts := strings.Split(path[1:], "/")
+	for i, part := range parts {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(part), "~") {
+			return nil, fmt.Errorf("invalid escape in JSON pointer %q", path)
+		}
+		parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+	}
+	return parts, nil
+}
+
+// deepIndex returns the value of the given path segment in the given value,
+// which is a map key, a list index or a struct field. The returned value is
+// invalid if the segment is missing.
+func deepIndex(v reflect.Value, part string) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}
+		}
+		return v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 0 || i >= v.Len() {
+			return reflect.Value{}
+		}
+		return v.Index(i)
+	case reflect.Struct:
+		return v.FieldByName(part)
+	default:
+		return reflect.Value{}
+	}
+}
+
+// deepIsNil returns true if the given value is a nil pointer, interface, map
+// or slice.
+func deepIsNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// consistentHash is a template func that assigns the given key to one of the
+// given number of buckets, numbered from 0, with jump consistent hashing, for
+// example:
+//
+//		{{ if eq (consistentHash "orders" (len (service "worker"))) 2 }}
+//
+// The same key always lands in the same bucket for a given number of buckets,
+// and when the number of buckets grows, only the keys which move to the new
+// buckets change.
+func consistentHash(key string, buckets interface{}) (int64, error) {
+	n, err := hashBuckets(buckets)
+	if err != nil {
+		return 0, errors.Wrap(err, "consistentHash")
+	}
+
+	// See "A Fast, Minimal Memory, Consistent Hash Algorithm" by Lamping and
+	// Veach.
+	h := hashKey(key)
+	var b, j int64 = -1, 0
+	for j < n {
+		b = j
+		h = h*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(1<<31) / float64((h>>33)+1)))
+	}
+	return b, nil
+}
+
+// hashBuckets returns the given number of buckets as an int64. It must be a
+// positive integer.
+func hashBuckets(buckets interface{}) (int64, error) {
+	var n int64
+	v := reflect.ValueOf(buckets)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > uint64(1<<62) {
+			return 0, fmt.Errorf("too many buckets: %d", v.Uint())
+		}
+		n = int64(v.Uint())
+	default:
+		return 0, fmt.Errorf("buckets must be an integer, got %T", buckets)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("buckets must be positive, got %d", n)
+	}
+	return n, nil
+}
+
+// hrw is a template func that chooses the node the given key belongs to from
+// the given list with highest random weight (rendezvous) hashing, for example:
+//
+//		{{ with hrw "orders" (service "worker") }}{{ .Node }}{{ end }}
+//
+// The list can hold strings, or services and nodes, which are identified by
+// their Node and ID fields together, or else by their Name field or address.
+// The same key always chooses the same node, and when a node is added or removed,
+// only the keys of that node change. It returns nil if the list is empty.
+func hrw(key string, nodes interface{}) (interface{}, error) {
+	list, err := fieldPathElems(nodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "hrw")
+	}
+
+	var best interface{}
+	var bestID string
+	var bestWeight uint64
+	for _, node := range list {
+		id := hrwNodeID(node)
+		if id == "" {
+			return nil, fmt.Errorf("hrw: cannot identify node %#v", node)
+		}
+
+		weight := hashMix(hashKey(id + "\x00" + key))
+		if best == nil || weight > bestWeight || (weight == bestWeight && id < bestID) {
+			best, bestID, bestWeight = node, id, weight
+		}
+	}
+	return best, nil
+}
+
+// hrwNodeID returns the identity of a node given to hrw: the node itself if it
+// is a string, otherwise its Node and ID fields, or else its Name field or its
+// address. Service IDs default to the service name and so repeat across nodes,
+// which is why they are combined with the node.
+func hrwNodeID(node interface{}) string {
+	if s, ok := node.(string); ok {
+		return s
+	}
+
+	v := reflect.ValueOf(node)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		var parts []string
+		for _, name := range []string{"Node", "ID"} {
+			if s := stringField(v, name); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "/")
+		}
+		if s := stringField(v, "Name"); s != "" {
+			return s
+		}
+	}
+	return addressOf(node)
+}
+
+// stringField returns the value of the named string field of the given struct,
+// or "" if it has no such field.
+func stringField(v reflect.Value, name string) string {
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// hashKey returns the 64-bit FNV-1a hash of the given key.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, key)
+	return h.Sum64()
+}
+
+// hashMix scrambles the bits of the given hash, so that hashes of similar
+// inputs are not ordered alike.
+func hashMix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
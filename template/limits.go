@@ -0,0 +1,132 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	// LimitOutputSize is the name of the limit on the size of the output.
+	LimitOutputSize = "max_output_size"
+
+	// LimitRangeIterations is the name of the limit on the range iterations.
+	LimitRangeIterations = "max_range_iterations"
+)
+
+// rangeIterationFunc is the name of the function which is called at the start
+// of every range iteration. It is not meant to be called by templates, so the
+// name is unlikely to collide with a user-defined name.
+const rangeIterationFunc = "_rangeIteration"
+
+// LimitError is the error returned when the execution of a template exceeds
+// one of its limits. The execution is stopped as soon as the limit is
+// exceeded, so no partial output is returned.
+type LimitError struct {
+	// Limit is the name of the limit which was exceeded.
+	Limit string
+
+	// Max is the configured maximum of the limit.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("template: exceeded %s of %d", e.Limit, e.Max)
+}
+
+// exceededLimit records the first limit an execution exceeded. The template
+// package turns the errors of writers and functions into plain strings on
+// older versions of Go, so the error is recorded where it happens instead of
+// being looked for in the error of the execution.
+type exceededLimit struct {
+	err *LimitError
+}
+
+// set records the given error if no limit was exceeded yet, and returns it.
+func (l *exceededLimit) set(err *LimitError) *LimitError {
+	if l.err == nil {
+		l.err = err
+	}
+	return err
+}
+
+// limitWriter is a buffer which fails writes once the output would be larger
+// than max. A max of 0 does not limit the output.
+type limitWriter struct {
+	bytes.Buffer
+	max      int
+	exceeded *exceededLimit
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.max > 0 && w.Len()+len(p) > w.max {
+		return 0, w.exceeded.set(&LimitError{Limit: LimitOutputSize, Max: w.max})
+	}
+	return w.Buffer.Write(p)
+}
+
+// rangeIterationCounterFunc returns the function called at the start of every
+// range iteration, which fails once more than max iterations were started. A
+// max of 0 does not limit the iterations.
+func rangeIterationCounterFunc(max int, exceeded *exceededLimit) func() (string, error) {
+	var count int
+	return func() (string, error) {
+		count++
+		if max > 0 && count > max {
+			return "", exceeded.set(&LimitError{Limit: LimitRangeIterations, Max: max})
+		}
+		return "", nil
+	}
+}
+
+// instrumentRanges inserts a call to the range iteration function at the start
+// of the body of every range action of the given template and of the templates
+// it defines. Go templates have no hook for range iterations, so this is what
+// allows nested ranges over large lists to be stopped.
+func instrumentRanges(tmpl *template.Template) {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			instrumentNode(t.Tree.Root)
+		}
+	}
+}
+
+func instrumentNode(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			instrumentNode(child)
+		}
+	case *parse.IfNode:
+		instrumentNode(n.List)
+		instrumentNode(n.ElseList)
+	case *parse.WithNode:
+		instrumentNode(n.List)
+		instrumentNode(n.ElseList)
+	case *parse.RangeNode:
+		instrumentNode(n.List)
+		instrumentNode(n.ElseList)
+
+		ident := parse.NewIdentifier(rangeIterationFunc).SetPos(n.Pos)
+		action := &parse.ActionNode{
+			NodeType: parse.NodeAction,
+			Pos:      n.Pos,
+			Line:     n.Line,
+			Pipe: &parse.PipeNode{
+				NodeType: parse.NodePipe,
+				Pos:      n.Pos,
+				Line:     n.Line,
+				Cmds: []*parse.CommandNode{{
+					NodeType: parse.NodeCommand,
+					Pos:      n.Pos,
+					Args:     []parse.Node{ident},
+				}},
+			},
+		}
+		n.List.Nodes = append([]parse.Node{action}, n.List.Nodes...)
+	}
+}
@@ -0,0 +1,68 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// PluginFunc is a template function provided by a template plugin. It is
+// called with the arguments given in the template, converted to strings.
+type PluginFunc func(args ...string) (string, error)
+
+// pluginFuncNameRe matches the names a template function may have.
+var pluginFuncNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validatePluginFuncs returns an error if one of the given plugin functions
+// cannot be called from a template or would replace a built-in function.
+func validatePluginFuncs(funcs map[string]PluginFunc) error {
+	builtin := funcMap(&funcMapInput{})
+	for name := range funcs {
+		if !pluginFuncNameRe.MatchString(name) {
+			return fmt.Errorf("template: invalid plugin function name %q", name)
+		}
+		if _, ok := builtin[name]; ok {
+			return fmt.Errorf("template: plugin function %q shadows a built-in function", name)
+		}
+	}
+	return nil
+}
+
+// pluginFuncMap returns the template functions which call the given plugin
+// functions. Arguments which are not strings are encoded as JSON.
+func pluginFuncMap(funcs map[string]PluginFunc) template.FuncMap {
+	m := make(template.FuncMap, len(funcs))
+	for name, f := range funcs {
+		f := f
+		m[name] = func(args ...interface{}) (string, error) {
+			s := make([]string, len(args))
+			for i, arg := range args {
+				switch v := arg.(type) {
+				case string:
+					s[i] = v
+				default:
+					b, err := json.Marshal(v)
+					if err != nil {
+						return "", err
+					}
+					s[i] = string(b)
+				}
+			}
+			return f(s...)
+		}
+	}
+	return m
+}
+
+// pluginFuncNames returns the sorted names of the given plugin functions.
+func pluginFuncNames(funcs map[string]PluginFunc) string {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
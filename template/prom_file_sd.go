@@ -0,0 +1,161 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/pkg/errors"
+)
+
+// promTargetGroup is a target group of a Prometheus file_sd file: the
+// addresses to scrape and the labels shared by all of them.
+type promTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// promFileSD converts the given lists of services into the JSON of a Prometheus
+// file_sd file, for example:
+//
+//	{{ promFileSD (service "web") (nomadService "api") }}
+//
+// The lists can hold the services returned by service, catalog.service and
+// nomadService, and the results of filters like filterByFamily. Each service
+// is a "host:port" target, with IPv6 hosts bracketed, labeled with its service
+// name, its node and its tags, which are joined with commas and surrounded by
+// commas like the tags of Prometheus' Consul service discovery. Services with
+// the same labels share a target group. Groups and their targets are sorted,
+// so the file only changes when the services do, and no services result in an
+// empty list rather than null.
+func promFileSD(services ...interface{}) (string, error) {
+	groups := make(map[string]*promTargetGroup)
+	for _, s := range services {
+		list, err := fieldPathElems(s)
+		if err != nil {
+			return "", errors.Wrap(err, "promFileSD")
+		}
+		for _, elem := range list {
+			target, labels, err := promTarget(elem)
+			if err != nil {
+				return "", errors.Wrap(err, "promFileSD")
+			}
+			key := promLabelsKey(labels)
+			g, ok := groups[key]
+			if !ok {
+				g = &promTargetGroup{Targets: []string{}, Labels: labels}
+				groups[key] = g
+			}
+			g.Targets = append(g.Targets, target)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]*promTargetGroup, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		sort.Strings(g.Targets)
+		g.Targets = uniqueSorted(g.Targets)
+		result = append(result, g)
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "promFileSD")
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// promTarget returns the target and the labels of the given service.
+func promTarget(elem interface{}) (string, map[string]string, error) {
+	var host string
+	var port int
+	labels := make(map[string]string)
+	switch s := elem.(type) {
+	case *dep.HealthService:
+		host, port = s.Address, s.Port
+		labels["service"] = s.Name
+		labels["node"] = s.Node
+		labels["tags"] = promTags(s.Tags)
+		labels["peer"] = s.Peer
+	case *dep.CatalogService:
+		host, port = s.ServiceAddress, s.ServicePort
+		if host == "" {
+			host = s.Address
+		}
+		labels["service"] = s.ServiceName
+		labels["node"] = s.Node
+		labels["tags"] = promTags(s.ServiceTags)
+	case *dep.NomadService:
+		host, port = s.Address, s.Port
+		labels["service"] = s.Name
+		labels["node"] = s.NodeID
+		labels["tags"] = promTags(s.Tags)
+		labels["namespace"] = s.Namespace
+		labels["datacenter"] = s.Datacenter
+		labels["nomad_job"] = s.JobID
+	default:
+		return "", nil, fmt.Errorf("wrong service type %T", elem)
+	}
+
+	if host == "" {
+		return "", nil, fmt.Errorf("service %q has no address", labels["service"])
+	}
+
+	// Empty labels are the same as missing ones to Prometheus.
+	for name, value := range labels {
+		if value == "" {
+			delete(labels, name)
+		}
+	}
+	return net.JoinHostPort(unbracketHost(host), strconv.Itoa(port)), labels, nil
+}
+
+// promTags joins the given tags with commas, surrounded by commas, so a tag
+// can be matched with a regular expression like ".*,primary,.*".
+func promTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.Join(tags, ",") + ","
+}
+
+// promLabelsKey returns a string which sorts and identifies the given labels,
+// starting with the service name.
+func promLabelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if name != "service" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString(labels["service"])
+	for _, name := range names {
+		fmt.Fprintf(&b, "\x00%s=%s", name, labels[name])
+	}
+	return b.String()
+}
+
+// uniqueSorted removes the duplicates of the given sorted list.
+func uniqueSorted(list []string) []string {
+	result := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package template
+
+import (
+	"testing"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestPromFileSD(t *testing.T) {
+	cases := []struct {
+		name     string
+		services []interface{}
+		exp      string
+		err      bool
+	}{
+		{
+			"empty",
+			[]interface{}{[]*dep.HealthService{}},
+			`[]`,
+			false,
+		},
+		{
+			"no_arguments",
+			nil,
+			`[]`,
+			false,
+		},
+		{
+			"health",
+			[]interface{}{[]*dep.HealthService{
+				&dep.HealthService{Name: "web", Node: "node2", Address: "10.0.0.2", Port: 8080},
+				&dep.HealthService{Name: "web", Node: "node1", Address: "10.0.0.1", Port: 8080,
+					Tags: dep.ServiceTags{"primary", "v2"}},
+			}},
+			`[
+  {
+    "targets": [
+      "10.0.0.1:8080"
+    ],
+    "labels": {
+      "node": "node1",
+      "service": "web",
+      "tags": ",primary,v2,"
+    }
+  },
+  {
+    "targets": [
+      "10.0.0.2:8080"
+    ],
+    "labels": {
+      "node": "node2",
+      "service": "web"
+    }
+  }
+]`,
+			false,
+		},
+		{
+			"grouped",
+			[]interface{}{[]*dep.HealthService{
+				&dep.HealthService{Name: "web", Node: "node1", Address: "10.0.0.1", Port: 9090},
+				&dep.HealthService{Name: "web", Node: "node1", Address: "10.0.0.1", Port: 8080},
+				&dep.HealthService{Name: "web", Node: "node1", Address: "10.0.0.1", Port: 8080},
+			}},
+			`[
+  {
+    "targets": [
+      "10.0.0.1:8080",
+      "10.0.0.1:9090"
+    ],
+    "labels": {
+      "node": "node1",
+      "service": "web"
+    }
+  }
+]`,
+			false,
+		},
+		{
+			"ipv6",
+			[]interface{}{[]*dep.HealthService{
+				&dep.HealthService{Name: "web", Address: "2001:db8::1", Port: 80},
+			}},
+			`[
+  {
+    "targets": [
+      "[2001:db8::1]:80"
+    ],
+    "labels": {
+      "service": "web"
+    }
+  }
+]`,
+			false,
+		},
+		{
+			"catalog",
+			[]interface{}{[]*dep.CatalogService{
+				&dep.CatalogService{ServiceName: "db", Node: "node1", Address: "10.0.0.1", ServicePort: 5432},
+			}},
+			`[
+  {
+    "targets": [
+      "10.0.0.1:5432"
+    ],
+    "labels": {
+      "node": "node1",
+      "service": "db"
+    }
+  }
+]`,
+			false,
+		},
+		{
+			"nomad",
+			[]interface{}{[]*dep.NomadService{
+				&dep.NomadService{Name: "api", Namespace: "default", NodeID: "n1", Datacenter: "dc1",
+					JobID: "api", Address: "10.0.0.3", Port: 25000},
+			}},
+			`[
+  {
+    "targets": [
+      "10.0.0.3:25000"
+    ],
+    "labels": {
+      "datacenter": "dc1",
+      "namespace": "default",
+      "node": "n1",
+      "nomad_job": "api",
+      "service": "api"
+    }
+  }
+]`,
+			false,
+		},
+		{
+			"several_services",
+			[]interface{}{
+				[]*dep.HealthService{&dep.HealthService{Name: "web", Address: "10.0.0.1", Port: 80}},
+				[]interface{}{&dep.HealthService{Name: "api", Address: "10.0.0.2", Port: 80}},
+			},
+			`[
+  {
+    "targets": [
+      "10.0.0.2:80"
+    ],
+    "labels": {
+      "service": "api"
+    }
+  },
+  {
+    "targets": [
+      "10.0.0.1:80"
+    ],
+    "labels": {
+      "service": "web"
+    }
+  }
+]`,
+			false,
+		},
+		{
+			"no_address",
+			[]interface{}{[]*dep.HealthService{&dep.HealthService{Name: "web", Port: 80}}},
+			"",
+			true,
+		},
+		{
+			"wrong_type",
+			[]interface{}{[]string{"10.0.0.1:80"}},
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			act, err := promFileSD(tc.services...)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if act != tc.exp {
+				t.Errorf("\nexp: %s\nact: %s", tc.exp, act)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pathInSandbox returns an error if the given path is outside of the sandbox
+// directory tree. Both are resolved to absolute paths with their symlinks
+// evaluated, so a symlink inside the sandbox cannot point outside of it. An
+// empty sandbox allows every path.
+func pathInSandbox(sandbox, path string) error {
+	if sandbox == "" {
+		return nil
+	}
+
+	root, err := resolvePath(sandbox)
+	if err != nil {
+		return fmt.Errorf("sandbox %q: %s", sandbox, err)
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return fmt.Errorf("%q: %s", path, err)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%q is outside of the sandbox %q", path, sandbox)
+	}
+	return nil
+}
+
+// resolvePath returns the given path as an absolute path with its symlinks
+// evaluated. The path may not exist yet, such as a file which is watched until
+// it is created, in which case the symlinks of its closest existing parent are
+// evaluated.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	parent := filepath.Dir(abs)
+	if parent == abs {
+		return abs, nil
+	}
+	resolvedParent, err := resolvePath(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(abs)), nil
+}
@@ -0,0 +1,219 @@
+package template
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+// secretRotationMargin is the part of the lease of a held secret after which a
+// new version is written outside of the rotation window, so the rendered
+// secret is replaced before it expires.
+const secretRotationMargin = 0.9
+
+// SecretRotation holds back new versions of the secrets read by the secret and
+// kv2 functions until their rotation window. The version a template was
+// rendered with keeps being rendered until then, unless its lease is about to
+// expire. It keeps the rendered versions, so it is shared by all the
+// executions of a template. A nil SecretRotation holds back nothing.
+type SecretRotation struct {
+	rules []*secretRotationRule
+
+	// secrets are the rendered versions by path, and are protected by lock.
+	secrets map[string]*rotatedSecret
+	lock    sync.Mutex
+}
+
+// SecretRotationRule is a rotation window for the secrets at the paths
+// matching Path, or all the secrets if it is empty. Window is the daily window
+// as "HH:MM-HH:MM" in local time.
+type SecretRotationRule struct {
+	Path   string
+	Window string
+}
+
+// secretRotationRule is a parsed SecretRotationRule. The window starts and ends
+// at the given minutes after midnight.
+type secretRotationRule struct {
+	path       string
+	start, end int
+}
+
+// rotatedSecret is the version of a secret which is rendered.
+type rotatedSecret struct {
+	secret *dep.Secret
+
+	// forceAt is when a new version is written even outside of the window,
+	// or zero if the secret has no lease.
+	forceAt time.Time
+
+	// next is when the new version which is held back is written, or zero if
+	// none is.
+	next time.Time
+}
+
+// NewSecretRotation creates a SecretRotation from the given rules. The first
+// rule matching the path of a secret applies to it. It returns nil if there
+// are no rules.
+func NewSecretRotation(rules []SecretRotationRule) (*SecretRotation, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	r := &SecretRotation{
+		secrets: make(map[string]*rotatedSecret),
+	}
+	for _, rule := range rules {
+		p := strings.Trim(rule.Path, "/")
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("secret_rotation: invalid path %q: %s", rule.Path, err)
+		}
+		start, end, err := parseRotationWindow(rule.Window)
+		if err != nil {
+			return nil, fmt.Errorf("secret_rotation: %s", err)
+		}
+		r.rules = append(r.rules, &secretRotationRule{
+			path:  p,
+			start: start,
+			end:   end,
+		})
+	}
+	return r, nil
+}
+
+// parseRotationWindow parses a window given as "HH:MM-HH:MM" into its start and
+// end in minutes after midnight.
+func parseRotationWindow(s string) (int, int, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid rotate_within %q, expected \"HH:MM-HH:MM\"", s)
+	}
+
+	var minutes [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid rotate_within %q, expected \"HH:MM-HH:MM\"", s)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	if minutes[0] == minutes[1] {
+		return 0, 0, fmt.Errorf("invalid rotate_within %q, the window is empty", s)
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// contains returns true if the given time is within the window.
+func (r *secretRotationRule) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if r.start < r.end {
+		return m >= r.start && m < r.end
+	}
+	// The window wraps around midnight.
+	return m >= r.start || m < r.end
+}
+
+// nextStart returns the next start of the window after the given time.
+func (r *secretRotationRule) nextStart(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), r.start/60, r.start%60, 0, 0, t.Location())
+	if !start.After(t) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start
+}
+
+// rule returns the first rule matching the given path, if any.
+func (r *SecretRotation) rule(p string) *secretRotationRule {
+	for _, rule := range r.rules {
+		if rule.path == "" {
+			return rule
+		}
+		if ok, _ := path.Match(rule.path, p); ok {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Secret returns the version of the secret at the given path to render at the
+// given time, given its latest version. A new version is returned within the
+// rotation window, or once the lease of the rendered version is about to
+// expire. Otherwise, the rendered version is returned.
+func (r *SecretRotation) Secret(p string, latest *dep.Secret, now time.Time) *dep.Secret {
+	if r == nil || latest == nil {
+		return latest
+	}
+
+	// The query options of a path, such as the version of a KV secret, are not
+	// matched.
+	rule := r.rule(strings.Trim(strings.SplitN(p, "?", 2)[0], "/"))
+	if rule == nil {
+		return latest
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	current, ok := r.secrets[p]
+	switch {
+	case !ok:
+		// The first version is always rendered.
+	case current.secret == latest:
+		return latest
+	case sameSecretVersion(current.secret, latest):
+		// A renewal extends the lease of the rendered version.
+	case rule.contains(now.Local()):
+		log.Printf("[INFO] (template) rotating secret %s within its window", p)
+	case !current.forceAt.IsZero() && !now.Before(current.forceAt):
+		log.Printf("[WARN] (template) rotating secret %s outside of its window, "+
+			"since its lease expires", p)
+	default:
+		current.next = rule.nextStart(now.Local())
+		if !current.forceAt.IsZero() && current.forceAt.Before(current.next) {
+			current.next = current.forceAt
+		}
+		return current.secret
+	}
+
+	rotated := &rotatedSecret{secret: latest}
+	if latest.LeaseDuration > 0 {
+		lease := time.Duration(float64(latest.LeaseDuration)*secretRotationMargin) * time.Second
+		rotated.forceAt = now.Add(lease)
+	}
+	r.secrets[p] = rotated
+	return latest
+}
+
+// Next returns the earliest time a new version which is held back is written,
+// or zero if none is.
+func (r *SecretRotation) Next() time.Time {
+	if r == nil {
+		return time.Time{}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var next time.Time
+	for _, s := range r.secrets {
+		if !s.next.IsZero() && (next.IsZero() || s.next.Before(next)) {
+			next = s.next
+		}
+	}
+	return next
+}
+
+// sameSecretVersion returns true if both secrets are the same version, which is
+// the case for a renewed lease, or for the same data if there is no lease.
+func sameSecretVersion(a, b *dep.Secret) bool {
+	if a.LeaseID != "" || b.LeaseID != "" {
+		return a.LeaseID == b.LeaseID
+	}
+	return reflect.DeepEqual(a.Data, b.Data)
+}
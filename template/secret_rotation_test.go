@@ -0,0 +1,260 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+func TestNewSecretRotation(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules []SecretRotationRule
+		err   bool
+	}{
+		{
+			"none",
+			nil,
+			false,
+		},
+		{
+			"valid",
+			[]SecretRotationRule{{Path: "database/creds/*", Window: "02:00-04:00"}},
+			false,
+		},
+		{
+			"wraps_midnight",
+			[]SecretRotationRule{{Window: "23:30 - 01:00"}},
+			false,
+		},
+		{
+			"missing_end",
+			[]SecretRotationRule{{Window: "02:00"}},
+			true,
+		},
+		{
+			"invalid_time",
+			[]SecretRotationRule{{Window: "02:00-25:00"}},
+			true,
+		},
+		{
+			"empty_window",
+			[]SecretRotationRule{{Window: "02:00-02:00"}},
+			true,
+		},
+		{
+			"invalid_path",
+			[]SecretRotationRule{{Path: "database/[", Window: "02:00-04:00"}},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSecretRotation(tc.rules)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestSecretRotationRule_contains(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 10, 16, hour, min, 0, 0, time.Local)
+	}
+
+	day := &secretRotationRule{start: 2 * 60, end: 4 * 60}
+	night := &secretRotationRule{start: 23*60 + 30, end: 60}
+
+	cases := []struct {
+		name string
+		rule *secretRotationRule
+		t    time.Time
+		exp  bool
+	}{
+		{"before", day, at(1, 59), false},
+		{"start", day, at(2, 0), true},
+		{"within", day, at(3, 59), true},
+		{"end", day, at(4, 0), false},
+		{"wrap_before", night, at(23, 29), false},
+		{"wrap_start", night, at(23, 30), true},
+		{"wrap_after_midnight", night, at(0, 30), true},
+		{"wrap_end", night, at(1, 0), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if act := tc.rule.contains(tc.t); act != tc.exp {
+				t.Errorf("expected %t, got %t", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestSecretRotation_Secret(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.Local)
+	}
+	secret := func(leaseID string, lease int, username string) *dep.Secret {
+		return &dep.Secret{
+			LeaseID:       leaseID,
+			LeaseDuration: lease,
+			Data:          map[string]interface{}{"username": username},
+		}
+	}
+
+	newRotation := func(t *testing.T) *SecretRotation {
+		r, err := NewSecretRotation([]SecretRotationRule{
+			{Path: "database/creds/*", Window: "02:00-04:00"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("held_until_window", func(t *testing.T) {
+		r := newRotation(t)
+		v1, v2 := secret("v1", 86400, "one"), secret("v2", 86400, "two")
+
+		if s := r.Secret("database/creds/app", v1, at(16, 10, 0)); s != v1 {
+			t.Fatalf("expected the first version, got %#v", s)
+		}
+		if s := r.Secret("database/creds/app", v2, at(16, 10, 30)); s != v1 {
+			t.Fatalf("expected the held version, got %#v", s)
+		}
+		if next, exp := r.Next(), at(17, 2, 0); !next.Equal(exp) {
+			t.Fatalf("expected next %s, got %s", exp, next)
+		}
+		if s := r.Secret("database/creds/app", v2, at(17, 2, 0)); s != v2 {
+			t.Fatalf("expected the new version, got %#v", s)
+		}
+		if next := r.Next(); !next.IsZero() {
+			t.Fatalf("expected no next, got %s", next)
+		}
+	})
+
+	t.Run("forced_on_expiry", func(t *testing.T) {
+		r := newRotation(t)
+		v1, v2 := secret("v1", 3600, "one"), secret("v2", 3600, "two")
+
+		r.Secret("database/creds/app", v1, at(16, 10, 0))
+		if s := r.Secret("database/creds/app", v2, at(16, 10, 30)); s != v1 {
+			t.Fatalf("expected the held version, got %#v", s)
+		}
+		if next, exp := r.Next(), at(16, 10, 54); !next.Equal(exp) {
+			t.Fatalf("expected next %s, got %s", exp, next)
+		}
+		if s := r.Secret("database/creds/app", v2, at(16, 10, 54)); s != v2 {
+			t.Fatalf("expected the new version, got %#v", s)
+		}
+	})
+
+	t.Run("renewal", func(t *testing.T) {
+		r := newRotation(t)
+		v1, renewed := secret("v1", 3600, "one"), secret("v1", 3600, "one")
+
+		r.Secret("database/creds/app", v1, at(16, 10, 0))
+		if s := r.Secret("database/creds/app", renewed, at(16, 10, 50)); s != renewed {
+			t.Fatalf("expected the renewed version, got %#v", s)
+		}
+
+		// The renewal extends the lease, so the new version is not forced at
+		// the end of the first one.
+		v2 := secret("v2", 3600, "two")
+		if s := r.Secret("database/creds/app", v2, at(16, 11, 0)); s != renewed {
+			t.Fatalf("expected the held version, got %#v", s)
+		}
+		if next, exp := r.Next(), at(16, 11, 44); !next.Equal(exp) {
+			t.Fatalf("expected next %s, got %s", exp, next)
+		}
+	})
+
+	t.Run("no_lease", func(t *testing.T) {
+		r := newRotation(t)
+		v1, v2 := secret("", 0, "one"), secret("", 0, "two")
+
+		r.Secret("database/creds/app", v1, at(16, 10, 0))
+		if s := r.Secret("database/creds/app", secret("", 0, "one"), at(16, 10, 30)); s.Data["username"] != "one" {
+			t.Fatalf("expected the same version, got %#v", s)
+		}
+		if s := r.Secret("database/creds/app", v2, at(16, 10, 30)); s.Data["username"] != "one" {
+			t.Fatalf("expected the held version, got %#v", s)
+		}
+		if next, exp := r.Next(), at(17, 2, 0); !next.Equal(exp) {
+			t.Fatalf("expected next %s, got %s", exp, next)
+		}
+	})
+
+	t.Run("not_matched", func(t *testing.T) {
+		r := newRotation(t)
+		v1, v2 := secret("v1", 86400, "one"), secret("v2", 86400, "two")
+
+		r.Secret("secret/app", v1, at(16, 10, 0))
+		if s := r.Secret("secret/app", v2, at(16, 10, 30)); s != v2 {
+			t.Fatalf("expected the new version, got %#v", s)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var r *SecretRotation
+		v1 := secret("v1", 86400, "one")
+		if s := r.Secret("database/creds/app", v1, at(16, 10, 0)); s != v1 {
+			t.Fatalf("expected the latest version, got %#v", s)
+		}
+		if next := r.Next(); !next.IsZero() {
+			t.Fatalf("expected no next, got %s", next)
+		}
+	})
+}
+
+func TestTemplate_Execute_secretRotation(t *testing.T) {
+	tmpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ with secret "database/creds/app" }}{{ .Data.username }}{{ end }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotation, err := NewSecretRotation([]SecretRotationRule{
+		{Path: "database/creds/app", Window: "02:00-04:00"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dep.NewVaultReadQuery("database/creds/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execute := func(username string, now time.Time) string {
+		brain := NewBrain()
+		brain.Remember(d, &dep.Secret{
+			LeaseID:       username,
+			LeaseDuration: 86400,
+			Data:          map[string]interface{}{"username": username},
+		})
+		result, err := tmpl.Execute(&ExecuteInput{
+			Brain:          brain,
+			Now:            now,
+			SecretRotation: rotation,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(result.Output)
+	}
+
+	if out := execute("one", time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)); out != "one" {
+		t.Fatalf("expected %q, got %q", "one", out)
+	}
+	if out := execute("two", time.Date(2026, 10, 16, 11, 0, 0, 0, time.Local)); out != "one" {
+		t.Fatalf("expected %q, got %q", "one", out)
+	}
+	if out := execute("two", time.Date(2026, 10, 17, 3, 0, 0, 0, time.Local)); out != "two" {
+		t.Fatalf("expected %q, got %q", "two", out)
+	}
+}
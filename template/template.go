@@ -1,17 +1,27 @@
 package template
 
 import (
-	"bytes"
 	"crypto/md5"
+	crand "crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
 	"io/ioutil"
+	"math/rand"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
 
 	dep "github.com/hashicorp/consul-template/dependency"
 )
 
+// processSeed seeds the random functions of executions that do not provide a
+// seed. It is chosen once, so generated values do not rotate across renders.
+var processSeed = NewSeed()
+
 var (
 	ErrTemplateContentsAndSource        = errors.New("template: cannot specify both 'source' and 'content'")
 	ErrTemplateMissingContentsAndSource = errors.New("template: must specify exactly one of 'source' or 'content'")
@@ -32,6 +42,24 @@ type Template struct {
 
 	// hexMD5 stores the hex version of the MD5
 	hexMD5 string
+
+	// strict makes references to missing map keys an execution error.
+	strict bool
+
+	// maxOutputSize and maxRangeIterations limit the execution. Zero values do
+	// not limit it.
+	maxOutputSize      int
+	maxRangeIterations int
+
+	// sandboxPath restricts the files the template reads and the plugins it
+	// executes to a directory tree. An empty value does not restrict them.
+	sandboxPath string
+
+	// pluginFuncs are the functions provided by the template plugins.
+	pluginFuncs map[string]PluginFunc
+
+	// cacheOwner owns the parse of the template in the parse cache, if any.
+	cacheOwner *CacheOwner
 }
 
 // NewTemplateInput is used as input when creating the template.
@@ -45,6 +73,31 @@ type NewTemplateInput struct {
 	// LeftDelim and RightDelim are the template delimiters.
 	LeftDelim  string
 	RightDelim string
+
+	// Strict makes references to missing map keys an execution error instead
+	// of rendering "<no value>".
+	Strict bool
+
+	// MaxOutputSize is the maximum size in bytes of the output and
+	// MaxRangeIterations is the maximum number of range iterations of a single
+	// execution. An execution which exceeds either is stopped with a
+	// *LimitError. Zero values do not limit the execution.
+	MaxOutputSize      int
+	MaxRangeIterations int
+
+	// SandboxPath is the directory tree the file function may read from and
+	// the plugin function may execute from. An empty value does not restrict
+	// them.
+	SandboxPath string
+
+	// PluginFuncs are the functions provided by the template plugins, callable
+	// from the template by name. They may not replace built-in functions.
+	PluginFuncs map[string]PluginFunc
+
+	// CacheOwner shares the parse of the template with the other templates
+	// with the same contents through the parse cache. Without an owner, the
+	// template is parsed on every execution.
+	CacheOwner *CacheOwner
 }
 
 // NewTemplate creates and parses a new Consul Template template at the given
@@ -68,6 +121,16 @@ func NewTemplate(i *NewTemplateInput) (*Template, error) {
 	t.contents = i.Contents
 	t.leftDelim = i.LeftDelim
 	t.rightDelim = i.RightDelim
+	t.strict = i.Strict
+	t.maxOutputSize = i.MaxOutputSize
+	t.maxRangeIterations = i.MaxRangeIterations
+	t.sandboxPath = i.SandboxPath
+	t.cacheOwner = i.CacheOwner
+
+	if err := validatePluginFuncs(i.PluginFuncs); err != nil {
+		return nil, err
+	}
+	t.pluginFuncs = i.PluginFuncs
 
 	if i.Source != "" {
 		contents, err := ioutil.ReadFile(i.Source)
@@ -84,6 +147,15 @@ func NewTemplate(i *NewTemplateInput) (*Template, error) {
 	return &t, nil
 }
 
+// NewSeed returns a new random seed for the random template functions.
+func NewSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
 // ID returns the identifier for this template.
 func (t *Template) ID() string {
 	return t.hexMD5
@@ -111,6 +183,46 @@ type ExecuteInput struct {
 	// Values specified here will take precedence over any values in the
 	// environment when using the `env` function.
 	Env []string
+
+	// ExecCapture runs the commands of the execCapture function. If nil, no
+	// commands are allowed.
+	ExecCapture *ExecCapture
+
+	// Input is the rendered output of another template. It is available to the
+	// template as .Input, parsed as JSON or YAML when it is an object or list.
+	Input []byte
+
+	// Now is the time of the render cycle. All time functions use it, so the
+	// time is consistent within a render. If zero, the current time is used.
+	Now time.Time
+
+	// PKIRenewFraction is the fraction of the validity of a certificate
+	// issued by the pkiCert function after which a new one is issued. If
+	// zero, the dependency's default is used.
+	PKIRenewFraction float64
+
+	// PreferFamily is the address family, "v4" or "v6", which the
+	// preferAddress function chooses first. If empty, it chooses the first
+	// address.
+	PreferFamily string
+
+	// SecretRotation holds back new versions of the secrets until their
+	// rotation window. If nil, the latest versions are rendered.
+	SecretRotation *SecretRotation
+
+	// Seed seeds the random functions. Executions with the same seed generate
+	// the same values. If zero, a seed chosen for the life of the process is
+	// combined with the template ID.
+	Seed int64
+
+	// Vars are the user-defined variables available through the var function.
+	Vars map[string]string
+}
+
+// executeData is the data the template is executed with.
+type executeData struct {
+	// Input is the parsed input, if any. See ExecuteInput.
+	Input interface{}
 }
 
 // ExecuteResult is the result of the template execution.
@@ -133,24 +245,67 @@ func (t *Template) Execute(i *ExecuteInput) (*ExecuteResult, error) {
 
 	var used, missing dep.Set
 
-	tmpl := template.New("")
-	tmpl.Delims(t.leftDelim, t.rightDelim)
-	tmpl.Funcs(funcMap(&funcMapInput{
-		t:       tmpl,
-		brain:   i.Brain,
-		env:     i.Env,
-		used:    &used,
-		missing: &missing,
-	}))
+	parsed, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
 
-	tmpl, err := tmpl.Parse(t.contents)
+	// Bind the functions for this execution to a copy of the parsed template so
+	// the cached template is never modified.
+	tmpl, err := parsed.Clone()
 	if err != nil {
-		return nil, errors.Wrap(err, "parse")
+		return nil, errors.Wrap(err, "clone")
+	}
+	if t.strict {
+		tmpl.Option("missingkey=error")
+	}
+
+	renderTime := i.Now
+	if renderTime.IsZero() {
+		renderTime = now()
 	}
 
+	seed := i.Seed
+	if seed == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(t.hexMD5))
+		seed = processSeed ^ int64(h.Sum64())
+	}
+
+	exceeded := &exceededLimit{}
+	tmpl.Funcs(funcMap(&funcMapInput{
+		t:                  tmpl,
+		id:                 t.hexMD5,
+		brain:              i.Brain,
+		env:                i.Env,
+		exceeded:           exceeded,
+		execCapture:        i.ExecCapture,
+		maxRangeIterations: t.maxRangeIterations,
+		now:                renderTime,
+		pkiRenewFraction:   i.PKIRenewFraction,
+		preferFamily:       i.PreferFamily,
+		rand:               rand.New(rand.NewSource(seed)),
+		sandboxPath:        t.sandboxPath,
+		secretRotation:     i.SecretRotation,
+		vars:               i.Vars,
+		used:               &used,
+		missing:            &missing,
+	}))
+	tmpl.Funcs(pluginFuncMap(t.pluginFuncs))
+
 	// Execute the template into the writer
-	var b bytes.Buffer
-	if err := tmpl.Execute(&b, nil); err != nil {
+	data := &executeData{}
+	if i.Input != nil {
+		data.Input = parseInput(i.Input)
+	}
+
+	b := &limitWriter{max: t.maxOutputSize, exceeded: exceeded}
+	if err := tmpl.Execute(b, data); err != nil {
+		// Surface exceeded limits as the cause, since the template package
+		// wraps errors returned by functions.
+		if exceeded.err != nil {
+			return nil, errors.Wrap(exceeded.err, "execute")
+		}
 		return nil, errors.Wrap(err, "execute")
 	}
 
@@ -161,13 +316,42 @@ func (t *Template) Execute(i *ExecuteInput) (*ExecuteResult, error) {
 	}, nil
 }
 
+// parseInput parses the given input as JSON or YAML if it is an object or a
+// list. Otherwise, the input is returned as a string.
+func parseInput(input []byte) interface{} {
+	var data interface{}
+	if err := json.Unmarshal(input, &data); err != nil {
+		if err := yaml.Unmarshal(input, &data); err != nil {
+			return string(input)
+		}
+	}
+
+	switch data.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		return data
+	default:
+		return string(input)
+	}
+}
+
 // funcMapInput is input to the funcMap, which builds the template functions.
 type funcMapInput struct {
-	t       *template.Template
-	brain   *Brain
-	env     []string
-	used    *dep.Set
-	missing *dep.Set
+	t                  *template.Template
+	id                 string
+	brain              *Brain
+	env                []string
+	exceeded           *exceededLimit
+	execCapture        *ExecCapture
+	maxRangeIterations int
+	now                time.Time
+	pkiRenewFraction   float64
+	preferFamily       string
+	rand               *rand.Rand
+	sandboxPath        string
+	secretRotation     *SecretRotation
+	vars               map[string]string
+	used               *dep.Set
+	missing            *dep.Set
 }
 
 // funcMap is the map of template functions to their respective functions.
@@ -176,19 +360,33 @@ func funcMap(i *funcMapInput) template.FuncMap {
 
 	return template.FuncMap{
 		// API functions
-		"datacenters":  datacentersFunc(i.brain, i.used, i.missing),
-		"file":         fileFunc(i.brain, i.used, i.missing),
-		"key":          keyFunc(i.brain, i.used, i.missing),
-		"keyExists":    keyExistsFunc(i.brain, i.used, i.missing),
-		"keyOrDefault": keyWithDefaultFunc(i.brain, i.used, i.missing),
-		"ls":           lsFunc(i.brain, i.used, i.missing),
-		"node":         nodeFunc(i.brain, i.used, i.missing),
-		"nodes":        nodesFunc(i.brain, i.used, i.missing),
-		"secret":       secretFunc(i.brain, i.used, i.missing),
-		"secrets":      secretsFunc(i.brain, i.used, i.missing),
-		"service":      serviceFunc(i.brain, i.used, i.missing),
-		"services":     servicesFunc(i.brain, i.used, i.missing),
-		"tree":         treeFunc(i.brain, i.used, i.missing),
+		"aclSelf":           aclSelfFunc(i.brain, i.used, i.missing),
+		"autopilotHealth":   autopilotHealthFunc(i.brain, i.used, i.missing),
+		"datacenters":       datacentersFunc(i.brain, i.used, i.missing),
+		"errorFor":          errorForFunc(i.brain, i.used, i.missing),
+		"file":              fileFunc(i.brain, i.used, i.missing, i.sandboxPath),
+		"key":               keyFunc(i.brain, i.used, i.missing),
+		"keyExists":         keyExistsFunc(i.brain, i.used, i.missing),
+		"keyOrDefault":      keyWithDefaultFunc(i.brain, i.used, i.missing),
+		"kv2":               kv2Func(i.brain, i.used, i.missing, i.secretRotation, i.now),
+		"license":           licenseFunc(i.brain, i.used, i.missing),
+		"ls":                lsFunc(i.brain, i.used, i.missing),
+		"lsDiff":            lsDiffFunc(i.brain, i.id, i.used, i.missing),
+		"node":              nodeFunc(i.brain, i.used, i.missing),
+		"nodes":             nodesFunc(i.brain, i.used, i.missing),
+		"nomadService":      nomadServiceFunc(i.brain, i.used, i.missing),
+		"nomadServices":     nomadServicesFunc(i.brain, i.used, i.missing),
+		"peerings":          peeringsFunc(i.brain, i.used, i.missing),
+		"pkiCert":           pkiCertFunc(i.brain, i.used, i.missing, i.pkiRenewFraction),
+		"raftConfiguration": raftConfigurationFunc(i.brain, i.used, i.missing),
+		"secret":            secretFunc(i.brain, i.used, i.missing, i.secretRotation, i.now),
+		"secrets":           secretsFunc(i.brain, i.used, i.missing),
+		"segments":          segmentsFunc(i.brain, i.used, i.missing),
+		"service":           serviceFunc(i.brain, i.used, i.missing),
+		"services":          servicesFunc(i.brain, i.used, i.missing),
+		"tree":              treeFunc(i.brain, i.used, i.missing),
+		"treeDiff":          treeDiffFunc(i.brain, i.id, i.used, i.missing),
+		"treeMatching":      treeMatchingFunc(i.brain, i.used, i.missing),
 
 		// Scratch
 		"scratch": func() *Scratch { return &scratch },
@@ -201,31 +399,67 @@ func funcMap(i *funcMapInput) template.FuncMap {
 		"containsAny":     containsSomeFunc(false, false),
 		"containsNone":    containsSomeFunc(true, false),
 		"containsNotall":  containsSomeFunc(false, true),
+		"deepGet":         deepGet,
+		"deepSet":         deepSet,
 		"env":             envFunc(i.env),
+		"envDefault":      envDefaultFunc(i.env),
+		"execCapture":     i.execCapture.Run,
 		"executeTemplate": executeTemplateFunc(i.t),
 		"explode":         explode,
+		"filterByFamily":  filterByFamily,
+		"groupBy":         groupBy,
 		"in":              in,
+		"inNetwork":       inNetwork,
+		"ipFamily":        ipFamily,
+		"joinHostPort":    joinHostPort,
 		"loop":            loop,
 		"join":            join,
+		"mergeTrees":      mergeTrees,
+		"mustEnv":         mustEnvFunc(i.env),
 		"trimSpace":       trimSpace,
 		"parseBool":       parseBool,
 		"parseFloat":      parseFloat,
 		"parseInt":        parseInt,
 		"parseJSON":       parseJSON,
 		"parseUint":       parseUint,
-		"plugin":          plugin,
+		"parseXML":        parseXML,
+		"plugin":          pluginFunc(i.sandboxPath),
+		"preferAddress":   preferAddressFunc(i.preferFamily),
+		"promFileSD":      promFileSD,
+		"randAlphaNum":    randAlphaNumFunc(i.rand),
+		"randomChoice":    randomChoiceFunc(i.rand),
 		"regexReplaceAll": regexReplaceAll,
 		"regexMatch":      regexMatch,
 		"replaceAll":      replaceAll,
-		"timestamp":       timestamp,
+		"sortBy":          sortBy,
+		"timestamp":       timestampFunc(i.now),
 		"toLower":         toLower,
 		"toJSON":          toJSON,
 		"toJSONPretty":    toJSONPretty,
 		"toTitle":         toTitle,
 		"toTOML":          toTOML,
 		"toUpper":         toUpper,
+		"toXML":           toXML,
 		"toYAML":          toYAML,
 		"split":           split,
+		"uniqBy":          uniqBy,
+		"uuidv4":          uuidv4Func(i.rand),
+		"var":             varFunc(i.vars),
+
+		// Hashing functions
+		"bcrypt":         bcryptFunc,
+		"consistentHash": consistentHash,
+		"hmacSHA256":     hmacSHA256,
+		"hrw":            hrw,
+		"md5sum":         md5sum,
+		"sha256sum":      sha256sum,
+
+		// Time functions
+		"addDuration":     addDuration,
+		"now":             nowFunc(i.now),
+		"parseDuration":   parseDuration,
+		"timestampFormat": timestampFormat,
+		"unixNano":        unixNano,
 
 		// Math functions
 		"add":      add,
@@ -233,6 +467,9 @@ func funcMap(i *funcMapInput) template.FuncMap {
 		"multiply": multiply,
 		"divide":   divide,
 
+		// Limits
+		rangeIterationFunc: rangeIterationCounterFunc(i.maxRangeIterations, i.exceeded),
+
 		// Deprecated functions
 		"key_or_default": keyWithDefaultFunc(i.brain, i.used, i.missing),
 	}
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
+
 	dep "github.com/hashicorp/consul-template/dependency"
 )
 
@@ -150,6 +154,50 @@ func TestTemplate_Execute(t *testing.T) {
 		},
 
 		// funcs
+		{
+			"func_aclSelf",
+			`{{ with aclSelf }}{{ if .Allows "key" "app/config" "read" }}config{{ end }}{{ if .Allows "key" "app/secret" "read" }}secret{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewACLTokenSelfQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.ACLToken{
+						Rules: []*dep.ACLRule{
+							&dep.ACLRule{Resource: "key", Segment: "app/secret", Access: "deny"},
+							&dep.ACLRule{Resource: "key", Segment: "app/", Prefix: true, Access: "read"},
+						},
+					})
+					return b
+				}(),
+			},
+			"config",
+			false,
+		},
+		{
+			"func_autopilotHealth",
+			`{{ with autopilotHealth }}{{ .Healthy }}{{ range .Servers }} {{ .Name }}:{{ .Healthy }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewOperatorAutopilotHealthQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.AutopilotHealth{
+						Servers: []*dep.AutopilotServerHealth{
+							&dep.AutopilotServerHealth{Name: "server1", Healthy: true},
+							&dep.AutopilotServerHealth{Name: "server2"},
+						},
+					})
+					return b
+				}(),
+			},
+			"false server1:true server2:false",
+			false,
+		},
 		{
 			"func_datacenters",
 			`{{ datacenters }}`,
@@ -167,6 +215,42 @@ func TestTemplate_Execute(t *testing.T) {
 			"[dc1 dc2]",
 			false,
 		},
+		{
+			"func_errorFor",
+			`{{ with errorFor (key "key") }}fallback: {{ . }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.RememberError(d, fmt.Errorf("connection refused"))
+					return b
+				}(),
+			},
+			"fallback: connection refused",
+			false,
+		},
+		{
+			"func_errorFor_no_error",
+			`{{ with errorFor (key "key") }}fallback{{ else }}{{ key "key" }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("key")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "5")
+					return b
+				}(),
+			},
+			"5",
+			false,
+		},
 		{
 			"func_file",
 			`{{ file "/path/to/file" }}`,
@@ -236,6 +320,26 @@ func TestTemplate_Execute(t *testing.T) {
 			"150 200",
 			false,
 		},
+		{
+			"func_license",
+			`{{ with license }}{{ if .Valid }}{{ range .Features }}{{ . }};{{ end }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewOperatorLicenseQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.License{
+						Valid:    true,
+						Features: []string{"Network Segments", "Redundancy Zones"},
+					})
+					return b
+				}(),
+			},
+			"Network Segments;Redundancy Zones;",
+			false,
+		},
 		{
 			"func_ls",
 			`{{ range ls "list" }}{{ .Key }}={{ .Value }}{{ end }}`,
@@ -301,6 +405,112 @@ func TestTemplate_Execute(t *testing.T) {
 			"node1node2",
 			false,
 		},
+		{
+			"func_nodes_segment",
+			`{{ range nodes "?segment=alpha" }}{{ .Node }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewCatalogNodesQuery("?segment=alpha")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.Node{
+						&dep.Node{Node: "node1"},
+					})
+					return b
+				}(),
+			},
+			"node1",
+			false,
+		},
+		{
+			"func_nomadService",
+			`{{ range nomadService "v2.api" }}{{ .Address }}:{{ .Port }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewNomadServiceQuery("v2.api")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.NomadService{
+						&dep.NomadService{
+							Name:    "api",
+							Address: "10.0.0.1",
+							Port:    8080,
+						},
+					})
+					return b
+				}(),
+			},
+			"10.0.0.1:8080",
+			false,
+		},
+		{
+			"func_nomadServices",
+			`{{ range nomadServices }}{{ .Name }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewNomadServicesQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.NomadServicesSnippet{
+						&dep.NomadServicesSnippet{
+							Name: "api",
+						},
+						&dep.NomadServicesSnippet{
+							Name: "web",
+						},
+					})
+					return b
+				}(),
+			},
+			"apiweb",
+			false,
+		},
+		{
+			"func_peerings",
+			`{{ range peerings }}{{ .Name }}:{{ .ImportedServices | join "," }};{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewPeeringsQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.Peering{
+						&dep.Peering{Name: "east", ImportedServices: []string{"db", "web"}},
+						&dep.Peering{Name: "west"},
+					})
+					return b
+				}(),
+			},
+			"east:db,web;west:;",
+			false,
+		},
+		{
+			"func_raftConfiguration",
+			`{{ range raftConfiguration "@dc1" }}{{ if .Leader }}{{ .Node }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewOperatorRaftConfigurationQuery("@dc1")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.RaftServer{
+						&dep.RaftServer{Node: "server1"},
+						&dep.RaftServer{Node: "server2", Leader: true},
+					})
+					return b
+				}(),
+			},
+			"server2",
+			false,
+		},
 		{
 			"func_secret",
 			`{{ with secret "secret/foo" }}{{ .Data.zip }}{{ end }}`,
@@ -323,6 +533,60 @@ func TestTemplate_Execute(t *testing.T) {
 			"zap",
 			false,
 		},
+		{
+			"func_pkiCert",
+			`{{ with pkiCert "pki/issue/web" "common_name=web.example.com" "ttl=72h" }}{{ .SerialNumber }} {{ .Cert }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewVaultPKIQuery("pki/issue/web", map[string]interface{}{
+						"common_name": "web.example.com",
+						"ttl":         "72h",
+					}, 0)
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.VaultPKI{
+						Cert:         "cert",
+						SerialNumber: "01",
+					})
+					return b
+				}(),
+			},
+			"01 cert",
+			false,
+		},
+		{
+			"func_pkiCert_invalid_argument",
+			`{{ pkiCert "pki/issue/web" "common_name" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"func_kv2",
+			`{{ with kv2 "secret/foo" }}{{ .Data.data.zip }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewVaultKV2ReadQuery("secret/foo")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, &dep.Secret{
+						Data: map[string]interface{}{
+							"data":     map[string]interface{}{"zip": "zap"},
+							"metadata": map[string]interface{}{"version": 1},
+						},
+					})
+					return b
+				}(),
+			},
+			"zap",
+			false,
+		},
 		{
 			"func_secrets",
 			`{{ range secrets "secret/" }}{{ . }}{{ end }}`,
@@ -340,6 +604,23 @@ func TestTemplate_Execute(t *testing.T) {
 			"barfoo",
 			false,
 		},
+		{
+			"func_segments",
+			`{{ range segments "@dc1" }}[{{ . }}]{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewOperatorSegmentsQuery("@dc1")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []string{"", "alpha"})
+					return b
+				}(),
+			},
+			"[][alpha]",
+			false,
+		},
 		{
 			"func_service",
 			`{{ range service "webapp" }}{{ .Address }}{{ end }}`,
@@ -366,6 +647,29 @@ func TestTemplate_Execute(t *testing.T) {
 			"1.2.3.45.6.7.8",
 			false,
 		},
+		{
+			"func_service_peer",
+			`{{ range service "webapp" "peer=partner" }}{{ .Address }}@{{ .Peer }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp?peer=partner")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "1.2.3.4",
+							Peer:    "partner",
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4@partner",
+			false,
+		},
 		{
 			"func_services",
 			`{{ range services }}{{ .Name }}{{ end }}`,
@@ -412,6 +716,26 @@ func TestTemplate_Execute(t *testing.T) {
 			"admin/port=1134maxconns=5minconns=2",
 			false,
 		},
+		{
+			"func_treeMatching",
+			`{{ range treeMatching "key" "conns$" }}{{ .Key }}={{ .Value }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListMatchingQuery("key", "conns$")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "maxconns", Value: "5"},
+						&dep.KeyPair{Key: "minconns", Value: "2"},
+					})
+					return b
+				}(),
+			},
+			"maxconns=5minconns=2",
+			false,
+		},
 
 		// scratch
 		{
@@ -509,8 +833,8 @@ func TestTemplate_Execute(t *testing.T) {
 			false,
 		},
 		{
-			"helper_contains",
-			`{{ range service "webapp" }}{{ if .Tags | contains "prod" }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_groupBy",
+			`{{ range $status, $services := service "webapp" | groupBy "Status" }}{{ $status }}:{{ range $services }}{{ .Address }},{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -520,49 +844,54 @@ func TestTemplate_Execute(t *testing.T) {
 					}
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
+							Node:    "node1",
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "staging"},
+							Status:  "passing",
+							Port:    10,
 						},
 						&dep.HealthService{
+							Node:    "node2",
 							Address: "5.6.7.8",
-							Tags:    []string{"staging"},
+							Status:  "warning",
+							Port:    9,
+						},
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "9.10.11.12",
+							Status:  "passing",
+							Port:    100,
 						},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.4",
+			"passing:1.2.3.4,9.10.11.12,warning:5.6.7.8,",
 			false,
 		},
 		{
-			"helper_containsAll",
-			`{{ $requiredTags := parseJSON "[\"prod\",\"us-realm\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_groupBy__map_path",
+			`{{ range $wan, $nodes := nodes | groupBy "TaggedAddresses.wan" }}{{ $wan }}:{{ range $nodes }}{{ .Node }},{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewHealthServiceQuery("webapp")
+					d, err := dep.NewCatalogNodesQuery("")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.HealthService{
-						&dep.HealthService{
-							Address: "1.2.3.4",
-							Tags:    []string{"prod", "us-realm"},
-						},
-						&dep.HealthService{
-							Address: "5.6.7.8",
-							Tags:    []string{"prod", "ca-realm"},
-						},
+					b.Remember(d, []*dep.Node{
+						&dep.Node{Node: "node1", TaggedAddresses: map[string]string{"wan": "1.1.1.1"}},
+						&dep.Node{Node: "node2", TaggedAddresses: map[string]string{"wan": "2.2.2.2"}},
+						&dep.Node{Node: "node3", TaggedAddresses: map[string]string{"wan": "1.1.1.1"}},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.4",
+			"1.1.1.1:node1,node3,2.2.2.2:node2,",
 			false,
 		},
 		{
-			"helper_containsAll__empty",
-			`{{ $requiredTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_groupBy__bad_field",
+			`{{ service "webapp" | groupBy "Nope" }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -572,23 +901,33 @@ func TestTemplate_Execute(t *testing.T) {
 					}
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
+							Node:    "node1",
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "us-realm"},
+							Status:  "passing",
+							Port:    10,
 						},
 						&dep.HealthService{
+							Node:    "node2",
 							Address: "5.6.7.8",
-							Tags:    []string{"prod", "ca-realm"},
+							Status:  "warning",
+							Port:    9,
 						},
-					})
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "9.10.11.12",
+							Status:  "passing",
+							Port:    100,
+						},
+					})
 					return b
 				}(),
 			},
-			"1.2.3.45.6.7.8",
-			false,
+			"",
+			true,
 		},
 		{
-			"helper_containsAny",
-			`{{ $acceptableTags := parseJSON "[\"v2\",\"v3\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_sortBy",
+			`{{ range service "webapp" | sortBy "Port" }}{{ .Port }},{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -598,23 +937,68 @@ func TestTemplate_Execute(t *testing.T) {
 					}
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
+							Node:    "node1",
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "v1"},
+							Status:  "passing",
+							Port:    10,
 						},
 						&dep.HealthService{
+							Node:    "node2",
 							Address: "5.6.7.8",
-							Tags:    []string{"prod", "v2"},
+							Status:  "warning",
+							Port:    9,
+						},
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "9.10.11.12",
+							Status:  "passing",
+							Port:    100,
 						},
 					})
 					return b
 				}(),
 			},
-			"5.6.7.8",
+			"9,10,100,",
 			false,
 		},
 		{
-			"helper_containsAny__empty",
-			`{{ $acceptableTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_sortBy__map",
+			`{{ range "{\"a\":{\"rack\":\"r2\"},\"b\":{\"rack\":\"r1\"}}" | parseJSON | sortBy "rack" }}{{ .rack }},{{ end }}`,
+			&ExecuteInput{},
+			"r1,r2,",
+			false,
+		},
+		{
+			"helper_uuidv4",
+			`{{ uuidv4 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+				Seed:  42,
+			},
+			"538c7f96-b164-4f1b-97bb-9f4bb472e89f",
+			false,
+		},
+		{
+			"helper_var",
+			`{{ var "region" }}`,
+			&ExecuteInput{
+				Vars: map[string]string{"region": "us-east-1"},
+			},
+			"us-east-1",
+			false,
+		},
+		{
+			"helper_var_undefined",
+			`{{ var "zone" }}`,
+			&ExecuteInput{
+				Vars: map[string]string{"region": "us-east-1"},
+			},
+			"",
+			true,
+		},
+		{
+			"helper_uniqBy",
+			`{{ range service "webapp" | uniqBy "Node" }}{{ .Address }},{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -624,23 +1008,33 @@ func TestTemplate_Execute(t *testing.T) {
 					}
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
+							Node:    "node1",
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "v1"},
+							Status:  "passing",
+							Port:    10,
 						},
 						&dep.HealthService{
+							Node:    "node2",
 							Address: "5.6.7.8",
-							Tags:    []string{"prod", "v2"},
+							Status:  "warning",
+							Port:    9,
+						},
+						&dep.HealthService{
+							Node:    "node1",
+							Address: "9.10.11.12",
+							Status:  "passing",
+							Port:    100,
 						},
 					})
 					return b
 				}(),
 			},
-			"",
+			"1.2.3.4,5.6.7.8,",
 			false,
 		},
 		{
-			"helper_containsNone",
-			`{{ $forbiddenTags := parseJSON "[\"devel\",\"staging\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_contains",
+			`{{ range service "webapp" }}{{ if .Tags | contains "prod" }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -651,11 +1045,11 @@ func TestTemplate_Execute(t *testing.T) {
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "v1"},
+							Tags:    []string{"prod", "staging"},
 						},
 						&dep.HealthService{
 							Address: "5.6.7.8",
-							Tags:    []string{"devel", "v2"},
+							Tags:    []string{"staging"},
 						},
 					})
 					return b
@@ -665,8 +1059,8 @@ func TestTemplate_Execute(t *testing.T) {
 			false,
 		},
 		{
-			"helper_containsNone__empty",
-			`{{ $forbiddenTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_containsAll",
+			`{{ $requiredTags := parseJSON "[\"prod\",\"us-realm\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -677,22 +1071,22 @@ func TestTemplate_Execute(t *testing.T) {
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
 							Address: "1.2.3.4",
-							Tags:    []string{"staging", "v1"},
+							Tags:    []string{"prod", "us-realm"},
 						},
 						&dep.HealthService{
 							Address: "5.6.7.8",
-							Tags:    []string{"devel", "v2"},
+							Tags:    []string{"prod", "ca-realm"},
 						},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.45.6.7.8",
+			"1.2.3.4",
 			false,
 		},
 		{
-			"helper_containsNotall",
-			`{{ $excludingTags := parseJSON "[\"es-v1\",\"es-v2\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNotall $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_containsAll__empty",
+			`{{ $requiredTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAll $requiredTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -703,22 +1097,22 @@ func TestTemplate_Execute(t *testing.T) {
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "es-v1"},
+							Tags:    []string{"prod", "us-realm"},
 						},
 						&dep.HealthService{
 							Address: "5.6.7.8",
-							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
+							Tags:    []string{"prod", "ca-realm"},
 						},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.4",
+			"1.2.3.45.6.7.8",
 			false,
 		},
 		{
-			"helper_containsNotall__empty",
-			`{{ $excludingTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNotall $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_containsAny",
+			`{{ $acceptableTags := parseJSON "[\"v2\",\"v3\"]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -729,106 +1123,126 @@ func TestTemplate_Execute(t *testing.T) {
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "es-v1"},
+							Tags:    []string{"prod", "v1"},
 						},
 						&dep.HealthService{
 							Address: "5.6.7.8",
-							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
+							Tags:    []string{"prod", "v2"},
 						},
 					})
 					return b
 				}(),
 			},
-			"",
+			"5.6.7.8",
 			false,
 		},
 		{
-			"helper_env",
-			`{{ env "CT_TEST" }}`,
+			"helper_containsAny__empty",
+			`{{ $acceptableTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsAny $acceptableTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
-					// Cheat and use the brain callback here to set the env.
-					if err := os.Setenv("CT_TEST", "1"); err != nil {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
 						t.Fatal(err)
 					}
-					return NewBrain()
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "v2"},
+						},
+					})
+					return b
 				}(),
 			},
-			"1",
-			false,
-		},
-		{
-			"helper_env__override",
-			`{{ env "CT_TEST" }}`,
-			&ExecuteInput{
-				Env: []string{
-					"CT_TEST=2",
-				},
-				Brain: NewBrain(),
-			},
-			"2",
+			"",
 			false,
 		},
 		{
-			"helper_executeTemplate",
-			`{{ define "custom" }}{{ key "foo" }}{{ end }}{{ executeTemplate "custom" }}`,
+			"helper_containsNone",
+			`{{ $forbiddenTags := parseJSON "[\"devel\",\"staging\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVGetQuery("foo")
+					d, err := dep.NewHealthServiceQuery("webapp")
 					if err != nil {
 						t.Fatal(err)
 					}
-					d.EnableBlocking()
-					b.Remember(d, "bar")
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"devel", "v2"},
+						},
+					})
 					return b
 				}(),
 			},
-			"bar",
+			"1.2.3.4",
 			false,
 		},
 		{
-			"helper_executeTemplate__dot",
-			`{{ define "custom" }}{{ key . }}{{ end }}{{ executeTemplate "custom" "foo" }}`,
+			"helper_containsNone__empty",
+			`{{ $forbiddenTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNone $forbiddenTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVGetQuery("foo")
+					d, err := dep.NewHealthServiceQuery("webapp")
 					if err != nil {
 						t.Fatal(err)
 					}
-					d.EnableBlocking()
-					b.Remember(d, "bar")
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"staging", "v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"devel", "v2"},
+						},
+					})
 					return b
 				}(),
 			},
-			"bar",
+			"1.2.3.45.6.7.8",
 			false,
 		},
 		{
-			"helper_explode",
-			`{{ range $k, $v := tree "list" | explode }}{{ $k }}{{ $v }}{{ end }}`,
+			"helper_containsNotall",
+			`{{ $excludingTags := parseJSON "[\"es-v1\",\"es-v2\"]" }}{{ range service "webapp" }}{{ if .Tags | containsNotall $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
-					d, err := dep.NewKVListQuery("list")
+					d, err := dep.NewHealthServiceQuery("webapp")
 					if err != nil {
 						t.Fatal(err)
 					}
-					b.Remember(d, []*dep.KeyPair{
-						&dep.KeyPair{Key: "", Value: ""},
-						&dep.KeyPair{Key: "foo/bar", Value: "a"},
-						&dep.KeyPair{Key: "zip/zap", Value: "b"},
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "es-v1"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
+						},
 					})
 					return b
 				}(),
 			},
-			"foomap[bar:a]zipmap[zap:b]",
+			"1.2.3.4",
 			false,
 		},
 		{
-			"helper_in",
-			`{{ range service "webapp" }}{{ if "prod" | in .Tags }}{{ .Address }}{{ end }}{{ end }}`,
+			"helper_containsNotall__empty",
+			`{{ $excludingTags := parseJSON "[]" }}{{ range service "webapp" }}{{ if .Tags | containsNotall $excludingTags }}{{ .Address }}{{ end }}{{ end }}`,
 			&ExecuteInput{
 				Brain: func() *Brain {
 					b := NewBrain()
@@ -839,105 +1253,879 @@ func TestTemplate_Execute(t *testing.T) {
 					b.Remember(d, []*dep.HealthService{
 						&dep.HealthService{
 							Address: "1.2.3.4",
-							Tags:    []string{"prod", "staging"},
+							Tags:    []string{"prod", "es-v1"},
 						},
 						&dep.HealthService{
 							Address: "5.6.7.8",
-							Tags:    []string{"staging"},
+							Tags:    []string{"prod", "hybrid", "es-v1", "es-v2"},
 						},
 					})
 					return b
 				}(),
 			},
-			"1.2.3.4",
+			"",
 			false,
 		},
 		{
-			"helper_loop",
-			`{{ range loop 3 }}1{{ end }}`,
+			"helper_deepGet",
+			`{{ $pod := parseJSON "{\"spec\":{\"containers\":[{\"image\":\"app:1.2\"}]},\"metadata\":{\"labels\":{\"app.kubernetes.io/name\":\"app\"}}}" }}` +
+				`{{ deepGet $pod "spec.containers.0.image" }},{{ deepGet $pod "/metadata/labels/app.kubernetes.io~1name" }},` +
+				`{{ deepGet $pod "spec.containers.1.image" "none" }},{{ deepGet $pod "spec.volumes.0.name" "none" }},{{ deepGet $pod "spec.containers.0.image.tag" "none" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"111",
+			"app:1.2,app,none,none,none",
 			false,
 		},
 		{
-			"helper_loop__i",
-			`{{ range $i := loop 3 }}{{ $i }}{{ end }}`,
+			"helper_deepGet__struct",
+			`{{ range service "webapp" }}{{ deepGet . "Tags.1" }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "es-v1"},
+						},
+					})
+					return b
+				}(),
 			},
-			"012",
+			"es-v1",
 			false,
 		},
 		{
-			"helper_join",
-			`{{ "a,b,c" | split "," | join ";" }}`,
+			"helper_deepGet__bad_pointer",
+			`{{ deepGet (parseJSON "{}") "/a~2" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"a;b;c",
-			false,
+			"",
+			true,
 		},
 		{
-			"helper_parseBool",
-			`{{ "true" | parseBool }}`,
+			"helper_deepSet",
+			`{{ $a := parseJSON "{\"spec\":{\"ports\":[80]}}" }}{{ $b := deepSet $a "spec.replicas" 3 }}{{ $b = deepSet $b "/spec/ports/-" 443 }}{{ $b = deepSet $b "spec.ports.0" 8080 }}` +
+				`{{ $b = deepSet $b "metadata.name" "app" }}{{ $b | toJSON }},{{ $a | toJSON }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"true",
+			`{"metadata":{"name":"app"},"spec":{"ports":[8080,443],"replicas":3}},{"spec":{"ports":[80]}}`,
 			false,
 		},
 		{
-			"helper_parseFloat",
-			`{{ "1.2" | parseFloat }}`,
+			"helper_deepSet__bad_index",
+			`{{ deepSet (parseJSON "{\"ports\":[80]}") "ports.5" 1 }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"1.2",
-			false,
+			"",
+			true,
 		},
 		{
-			"helper_parseInt",
-			`{{ "-1" | parseInt }}`,
+			"helper_sha256sum",
+			`{{ "hello" | sha256sum }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"-1",
+			"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
 			false,
 		},
 		{
-			"helper_parseJSON",
-			`{{ "{\"foo\": \"bar\"}" | parseJSON }}`,
+			"helper_consistentHash",
+			`{{ consistentHash "orders" 1 }},{{ consistentHash "orders" 10 }},{{ consistentHash "users" 10 }},{{ consistentHash "orders" (len (split "," "a,b,c")) }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"map[foo:bar]",
+			"0,7,3,0",
 			false,
 		},
 		{
-			"helper_parseUint",
-			`{{ "1" | parseUint }}`,
+			"helper_consistentHash__no_buckets",
+			`{{ consistentHash "orders" 0 }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"1",
-			false,
+			"",
+			true,
 		},
 		{
-			"helper_plugin",
-			`{{ "1" | plugin "echo" }}`,
+			"helper_hrw",
+			`{{ hrw "orders" (split "," "a,b,c") }},{{ hrw "users" (split "," "a,b,c") }},{{ hrw "orders" (split "," "c,a,b") }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"1",
+			"c,b,c",
 			false,
 		},
 		{
-			"helper_regexMatch",
-			`{{ "foo" | regexMatch "[a-z]+" }}`,
+			"helper_hrw__service",
+			`{{ range $shard := loop 4 }}{{ with hrw (print $shard) (service "webapp") }}{{ .Node }},{{ end }}{{ end }}`,
 			&ExecuteInput{
-				Brain: NewBrain(),
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{Node: "node1", ID: "webapp-1"},
+						&dep.HealthService{Node: "node2", ID: "webapp-2"},
+						&dep.HealthService{Node: "node3", ID: "webapp-3"},
+					})
+					return b
+				}(),
+			},
+			"node2,node3,node1,node3,",
+			false,
+		},
+		{
+			"helper_hrw__service_shared_id",
+			`{{ range $shard := loop 8 }}{{ with hrw (print $shard) (service "worker") }}{{ .Node }},{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("worker")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{Node: "node1", ID: "worker"},
+						&dep.HealthService{Node: "node2", ID: "worker"},
+						&dep.HealthService{Node: "node3", ID: "worker"},
+					})
+					return b
+				}(),
+			},
+			"node1,node3,node3,node3,node1,node2,node2,node3,",
+			false,
+		},
+		{
+			"helper_hrw__service_shared_id_order",
+			`{{ range $shard := loop 8 }}{{ with hrw (print $shard) (service "worker") }}{{ .Node }},{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("worker")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{Node: "node3", ID: "worker"},
+						&dep.HealthService{Node: "node1", ID: "worker"},
+						&dep.HealthService{Node: "node2", ID: "worker"},
+					})
+					return b
+				}(),
+			},
+			"node1,node3,node3,node3,node1,node2,node2,node3,",
+			false,
+		},
+		{
+			"helper_hrw__empty",
+			`{{ with hrw "orders" (service "webapp") }}{{ .Node }}{{ else }}none{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{})
+					return b
+				}(),
+			},
+			"none",
+			false,
+		},
+		{
+			"helper_md5sum",
+			`{{ "hello" | md5sum }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"5d41402abc4b2a76b9719d911017c592",
+			false,
+		},
+		{
+			"helper_hmacSHA256",
+			`{{ "hello" | hmacSHA256 "key" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b",
+			false,
+		},
+		{
+			"helper_bcrypt_stable",
+			`{{ $a := "hello" | bcrypt }}{{ $b := "hello" | bcrypt }}{{ eq $a $b }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"true",
+			false,
+		},
+		{
+			"helper_bcrypt_previous",
+			`{{ "hello" | bcrypt "$2a$04$T.M9tqJfLu.MesZeEfLWRe9gSTY3ZWVTEEOaddXRkBTJyCx4Mh.1C" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"$2a$04$T.M9tqJfLu.MesZeEfLWRe9gSTY3ZWVTEEOaddXRkBTJyCx4Mh.1C",
+			false,
+		},
+		{
+			"helper_bcrypt_previous_mismatch",
+			`{{ $a := "hello" | bcrypt "$2a$04$T.M9tqJfLu.MesZeEfLWRe9gSTY3ZWVTEEOaddXRkBTJyCx4Mh.1C" }}{{ $b := "world" | bcrypt "$2a$04$T.M9tqJfLu.MesZeEfLWRe9gSTY3ZWVTEEOaddXRkBTJyCx4Mh.1C" }}{{ eq $a $b }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"false",
+			false,
+		},
+		{
+			"input_string",
+			`{{ .Input }}`,
+			&ExecuteInput{
+				Input: []byte("hello"),
+			},
+			"hello",
+			false,
+		},
+		{
+			"input_json",
+			`{{ .Input.foo }}{{ range .Input.list }}{{ . }}{{ end }}`,
+			&ExecuteInput{
+				Input: []byte(`{"foo": "bar", "list": ["a", "b"]}`),
+			},
+			"barab",
+			false,
+		},
+		{
+			"input_yaml",
+			`{{ .Input.foo }}`,
+			&ExecuteInput{
+				Input: []byte("foo: bar\n"),
+			},
+			"bar",
+			false,
+		},
+		{
+			"helper_env",
+			`{{ env "CT_TEST" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					// Cheat and use the brain callback here to set the env.
+					if err := os.Setenv("CT_TEST", "1"); err != nil {
+						t.Fatal(err)
+					}
+					return NewBrain()
+				}(),
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_env__override",
+			`{{ env "CT_TEST" }}`,
+			&ExecuteInput{
+				Env: []string{
+					"CT_TEST=2",
+				},
+				Brain: NewBrain(),
+			},
+			"2",
+			false,
+		},
+		{
+			"helper_envDefault",
+			`{{ envDefault "CT_TEST_DEFAULT" "fallback" }} {{ envDefault "CT_TEST_EMPTY" "fallback" }} {{ envDefault "CT_TEST_SET" "fallback" }}`,
+			&ExecuteInput{
+				Env: []string{
+					"CT_TEST_EMPTY=",
+					"CT_TEST_SET=value",
+				},
+				Brain: NewBrain(),
+			},
+			"fallback fallback value",
+			false,
+		},
+		{
+			"helper_mustEnv",
+			`{{ mustEnv "CT_TEST_SET" }}`,
+			&ExecuteInput{
+				Env: []string{
+					"CT_TEST_SET=value",
+				},
+				Brain: NewBrain(),
+			},
+			"value",
+			false,
+		},
+		{
+			"helper_mustEnv__unset",
+			`{{ mustEnv "CT_TEST_UNSET" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_mustEnv__empty",
+			`{{ mustEnv "CT_TEST_EMPTY" }}`,
+			&ExecuteInput{
+				Env: []string{
+					"CT_TEST_EMPTY=",
+				},
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_execCapture",
+			`{{ execCapture "echo" "hello" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+				ExecCapture: NewExecCapture(&NewExecCaptureInput{
+					Allowed: []string{"echo"},
+				}),
+			},
+			"hello",
+			false,
+		},
+		{
+			"helper_execCapture_not_allowed",
+			`{{ execCapture "echo" "hello" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_executeTemplate",
+			`{{ define "custom" }}{{ key "foo" }}{{ end }}{{ executeTemplate "custom" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("foo")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "bar")
+					return b
+				}(),
+			},
+			"bar",
+			false,
+		},
+		{
+			"helper_executeTemplate__dot",
+			`{{ define "custom" }}{{ key . }}{{ end }}{{ executeTemplate "custom" "foo" }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVGetQuery("foo")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.EnableBlocking()
+					b.Remember(d, "bar")
+					return b
+				}(),
+			},
+			"bar",
+			false,
+		},
+		{
+			"helper_explode",
+			`{{ range $k, $v := tree "list" | explode }}{{ $k }}{{ $v }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVListQuery("list")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.KeyPair{
+						&dep.KeyPair{Key: "", Value: ""},
+						&dep.KeyPair{Key: "foo/bar", Value: "a"},
+						&dep.KeyPair{Key: "zip/zap", Value: "b"},
+					})
+					return b
+				}(),
+			},
+			"foomap[bar:a]zipmap[zap:b]",
+			false,
+		},
+		{
+			"helper_filterByFamily",
+			`{{ range service "webapp" | filterByFamily "v6" }}{{ joinHostPort .Address .Port }},{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{Address: "1.2.3.4", Port: 80},
+						&dep.HealthService{Address: "2001:db8::1", Port: 80},
+						&dep.HealthService{Address: "web.example.com", Port: 80},
+						&dep.HealthService{Address: "2001:db8::2", Port: 8080},
+					})
+					return b
+				}(),
+			},
+			"[2001:db8::1]:80,[2001:db8::2]:8080,",
+			false,
+		},
+		{
+			"helper_promFileSD",
+			`{{ promFileSD (service "webapp") }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{Name: "webapp", Address: "1.2.3.4", Port: 80},
+					})
+					return b
+				}(),
+			},
+			"[\n  {\n    \"targets\": [\n      \"1.2.3.4:80\"\n    ],\n    \"labels\": {\n      \"service\": \"webapp\"\n    }\n  }\n]",
+			false,
+		},
+		{
+			"helper_filterByFamily__bad_family",
+			`{{ service "webapp" | filterByFamily "ipv6" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_inNetwork",
+			`{{ range service "webapp" | inNetwork "10.2.0.0/16, 2001:db8::/32" }}{{ .Address }},{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{Address: "10.2.1.4"},
+						&dep.HealthService{Address: "10.3.1.4"},
+						&dep.HealthService{Address: "2001:db8::1"},
+						&dep.HealthService{Address: "web.example.com"},
+						&dep.HealthService{Address: "10.2.200.7"},
+					})
+					return b
+				}(),
+			},
+			"10.2.1.4,2001:db8::1,10.2.200.7,",
+			false,
+		},
+		{
+			"helper_inNetwork__bad_network",
+			`{{ service "webapp" | inNetwork "10.2.0.0" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_in",
+			`{{ range service "webapp" }}{{ if "prod" | in .Tags }}{{ .Address }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHealthServiceQuery("webapp")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.HealthService{
+						&dep.HealthService{
+							Address: "1.2.3.4",
+							Tags:    []string{"prod", "staging"},
+						},
+						&dep.HealthService{
+							Address: "5.6.7.8",
+							Tags:    []string{"staging"},
+						},
+					})
+					return b
+				}(),
+			},
+			"1.2.3.4",
+			false,
+		},
+		{
+			"helper_loop",
+			`{{ range loop 3 }}1{{ end }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"111",
+			false,
+		},
+		{
+			"helper_loop__i",
+			`{{ range $i := loop 3 }}{{ $i }}{{ end }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"012",
+			false,
+		},
+		{
+			"helper_join",
+			`{{ "a,b,c" | split "," | join ";" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"a;b;c",
+			false,
+		},
+		{
+			"helper_mergeTrees",
+			`{{ with mergeTrees (tree "defaults") (tree "env/prod") (tree "hosts/web1") }}{{ .db.host }}:{{ .db.port }} {{ .debug }} {{ .tls }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					layers := map[string][]*dep.KeyPair{
+						"defaults": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "localhost"},
+							&dep.KeyPair{Key: "db/port", Value: "5432"},
+							&dep.KeyPair{Key: "debug", Value: "false"},
+							&dep.KeyPair{Key: "tls/cert", Value: "default.pem"},
+						},
+						"env/prod": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "db.prod"},
+							&dep.KeyPair{Key: "tls", Value: "__delete__"},
+						},
+						"hosts/web1": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/port", Value: "6432"},
+							&dep.KeyPair{Key: "debug", Value: "true"},
+						},
+					}
+					for prefix, pairs := range layers {
+						d, err := dep.NewKVListQuery(prefix)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, pairs)
+					}
+					return b
+				}(),
+			},
+			"db.prod:6432 true <no value>",
+			false,
+		},
+		{
+			"helper_mergeTrees__first",
+			`{{ with mergeTrees "precedence=first" (tree "hosts/web1") (tree "env/prod") (tree "defaults") }}{{ .db.host }}:{{ .db.port }} {{ .debug }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					layers := map[string][]*dep.KeyPair{
+						"defaults": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "localhost"},
+							&dep.KeyPair{Key: "db/port", Value: "5432"},
+							&dep.KeyPair{Key: "debug", Value: "false"},
+							&dep.KeyPair{Key: "tls/cert", Value: "default.pem"},
+						},
+						"env/prod": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "db.prod"},
+							&dep.KeyPair{Key: "tls", Value: "__delete__"},
+						},
+						"hosts/web1": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/port", Value: "6432"},
+							&dep.KeyPair{Key: "debug", Value: "true"},
+						},
+					}
+					for prefix, pairs := range layers {
+						d, err := dep.NewKVListQuery(prefix)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, pairs)
+					}
+					return b
+				}(),
+			},
+			"db.prod:6432 true",
+			false,
+		},
+		{
+			"helper_mergeTrees__delete_marker",
+			`{{ with mergeTrees "delete=-" (tree "defaults") (tree "env/prod") }}{{ .tls }}{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					layers := map[string][]*dep.KeyPair{
+						"defaults": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "localhost"},
+							&dep.KeyPair{Key: "db/port", Value: "5432"},
+							&dep.KeyPair{Key: "debug", Value: "false"},
+							&dep.KeyPair{Key: "tls/cert", Value: "default.pem"},
+						},
+						"env/prod": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "db.prod"},
+							&dep.KeyPair{Key: "tls", Value: "__delete__"},
+						},
+						"hosts/web1": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/port", Value: "6432"},
+							&dep.KeyPair{Key: "debug", Value: "true"},
+						},
+					}
+					for prefix, pairs := range layers {
+						d, err := dep.NewKVListQuery(prefix)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, pairs)
+					}
+					return b
+				}(),
+			},
+			"__delete__",
+			false,
+		},
+		{
+			"helper_mergeTrees__explode",
+			`{{ $defaults := tree "defaults" | explode }}{{ with mergeTrees $defaults (tree "hosts/web1") }}{{ .db.port }}{{ end }} {{ $defaults.db.port }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					layers := map[string][]*dep.KeyPair{
+						"defaults": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "localhost"},
+							&dep.KeyPair{Key: "db/port", Value: "5432"},
+							&dep.KeyPair{Key: "debug", Value: "false"},
+							&dep.KeyPair{Key: "tls/cert", Value: "default.pem"},
+						},
+						"env/prod": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "db.prod"},
+							&dep.KeyPair{Key: "tls", Value: "__delete__"},
+						},
+						"hosts/web1": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/port", Value: "6432"},
+							&dep.KeyPair{Key: "debug", Value: "true"},
+						},
+					}
+					for prefix, pairs := range layers {
+						d, err := dep.NewKVListQuery(prefix)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, pairs)
+					}
+					return b
+				}(),
+			},
+			"6432 5432",
+			false,
+		},
+		{
+			"helper_mergeTrees__bad_option",
+			`{{ mergeTrees "precedence=middle" (tree "defaults") }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					layers := map[string][]*dep.KeyPair{
+						"defaults": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "localhost"},
+							&dep.KeyPair{Key: "db/port", Value: "5432"},
+							&dep.KeyPair{Key: "debug", Value: "false"},
+							&dep.KeyPair{Key: "tls/cert", Value: "default.pem"},
+						},
+						"env/prod": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/host", Value: "db.prod"},
+							&dep.KeyPair{Key: "tls", Value: "__delete__"},
+						},
+						"hosts/web1": []*dep.KeyPair{
+							&dep.KeyPair{Key: "db/port", Value: "6432"},
+							&dep.KeyPair{Key: "debug", Value: "true"},
+						},
+					}
+					for prefix, pairs := range layers {
+						d, err := dep.NewKVListQuery(prefix)
+						if err != nil {
+							t.Fatal(err)
+						}
+						b.Remember(d, pairs)
+					}
+					return b
+				}(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_parseBool",
+			`{{ "true" | parseBool }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"true",
+			false,
+		},
+		{
+			"helper_parseFloat",
+			`{{ "1.2" | parseFloat }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1.2",
+			false,
+		},
+		{
+			"helper_parseInt",
+			`{{ "-1" | parseInt }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"-1",
+			false,
+		},
+		{
+			"helper_parseJSON",
+			`{{ "{\"foo\": \"bar\"}" | parseJSON }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"map[foo:bar]",
+			false,
+		},
+		{
+			"helper_parseUint",
+			`{{ "1" | parseUint }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_parseXML",
+			`{{ with parseXML "<a x=\"1\"><b>2</b><b>3</b></a>" }}{{ index .a "@x" }}{{ range .a.b }}{{ . }}{{ end }}{{ end }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"123",
+			false,
+		},
+		{
+			"helper_parseXML_invalid",
+			`{{ parseXML "<a><b></a>" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"helper_ipFamily",
+			`{{ ipFamily "1.2.3.4" }},{{ ipFamily "[2001:db8::1]" }},{{ ipFamily "fe80::1%eth0" }},{{ ipFamily "::ffff:1.2.3.4" }},{{ ipFamily "example.com" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"v4,v6,v6,v4,",
+			false,
+		},
+		{
+			"helper_joinHostPort",
+			`{{ joinHostPort "1.2.3.4" 80 }},{{ joinHostPort "2001:db8::1" "443" }},{{ joinHostPort "[2001:db8::1]" 8080 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1.2.3.4:80,[2001:db8::1]:443,[2001:db8::1]:8080",
+			false,
+		},
+		{
+			"helper_plugin",
+			`{{ "1" | plugin "echo" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1",
+			false,
+		},
+		{
+			"helper_preferAddress",
+			`{{ range nodes }}{{ preferAddress .TaggedAddresses }},{{ end }}`,
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewCatalogNodesQuery("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.Node{
+						&dep.Node{Node: "node1", TaggedAddresses: map[string]string{
+							"lan_ipv4": "10.0.0.1",
+							"lan_ipv6": "2001:db8::1",
+						}},
+						&dep.Node{Node: "node2", TaggedAddresses: map[string]string{
+							"lan_ipv4": "10.0.0.2",
+						}},
+					})
+					return b
+				}(),
+				PreferFamily: "v6",
+			},
+			"2001:db8::1,10.0.0.2,",
+			false,
+		},
+		{
+			"helper_preferAddress__no_preference",
+			`{{ "2001:db8::1,10.0.0.1" | split "," | preferAddress }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"2001:db8::1",
+			false,
+		},
+		{
+			"helper_randAlphaNum",
+			`{{ randAlphaNum 12 }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+				Seed:  42,
+			},
+			"DL2inVnsqtz5",
+			false,
+		},
+		{
+			"helper_randomChoice",
+			`{{ "a,b,c" | split "," | randomChoice }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+				Seed:  42,
+			},
+			"c",
+			false,
+		},
+		{
+			"helper_regexMatch",
+			`{{ "foo" | regexMatch "[a-z]+" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
 			},
 			"true",
 			false,
@@ -966,25 +2154,90 @@ func TestTemplate_Execute(t *testing.T) {
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"[a b c]",
+			"[a b c]",
+			false,
+		},
+		{
+			"helper_timestamp",
+			`{{ timestamp }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-01T00:00:00Z",
+			false,
+		},
+		{
+			"helper_helper_timestamp__formatted",
+			`{{ timestamp "2006-01-02" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1970-01-01",
+			false,
+		},
+		{
+			"helper_timestamp__render_time",
+			`{{ timestamp "unix" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+				Now:   time.Unix(3600, 0).UTC(),
+			},
+			"3600",
+			false,
+		},
+		{
+			"time_now",
+			`{{ now | unixNano }} {{ now | unixNano }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+				Now:   time.Unix(1, 5).UTC(),
+			},
+			"1000000005 1000000005",
+			false,
+		},
+		{
+			"time_parseDuration",
+			`{{ parseDuration "1h30m" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"1h30m0s",
+			false,
+		},
+		{
+			"time_parseDuration__invalid",
+			`{{ parseDuration "nope" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"time_addDuration",
+			`{{ now | addDuration "1h" | timestampFormat "15:04" }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"01:00",
 			false,
 		},
 		{
-			"helper_timestamp",
-			`{{ timestamp }}`,
+			"time_addDuration__duration",
+			`{{ now | addDuration (parseDuration "-1h") | timestampFormat "2006-01-02T15:04" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"1970-01-01T00:00:00Z",
+			"1969-12-31T23:00",
 			false,
 		},
 		{
-			"helper_helper_timestamp__formatted",
-			`{{ timestamp "2006-01-02" }}`,
+			"time_timestampFormat__unix",
+			`{{ now | addDuration "90s" | timestampFormat "unix" }}`,
 			&ExecuteInput{
 				Brain: NewBrain(),
 			},
-			"1970-01-01",
+			"90",
 			false,
 		},
 		{
@@ -1032,6 +2285,15 @@ func TestTemplate_Execute(t *testing.T) {
 			"HI",
 			false,
 		},
+		{
+			"helper_toXML",
+			`{{ "<a x=\"1\"><b>2</b><b>3</b></a>" | parseXML | toXML }}`,
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"<a x=\"1\">\n  <b>2</b>\n  <b>3</b>\n</a>",
+			false,
+		},
 		{
 			"helper_toYAML",
 			`{{ "{\"foo\":\"bar\"}" | parseJSON | toYAML }}`,
@@ -1107,3 +2369,511 @@ func TestTemplate_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplate_Execute_errorForMissing(t *testing.T) {
+	d, err := dep.NewKVGetQuery("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EnableBlocking()
+
+	cases := []struct {
+		name    string
+		c       string
+		missing int
+	}{
+		{
+			"unchecked",
+			`{{ key "key" }}`,
+			1,
+		},
+		{
+			"checked",
+			`{{ with errorFor (key "key") }}fallback{{ end }}`,
+			0,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tmpl, err := NewTemplate(&NewTemplateInput{
+				Contents: tc.c,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := NewBrain()
+			b.RememberError(d, fmt.Errorf("connection refused"))
+
+			result, err := tmpl.Execute(&ExecuteInput{
+				Brain: b,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if l := result.Missing.Len(); l != tc.missing {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.missing, l)
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_strict(t *testing.T) {
+	cases := []struct {
+		name   string
+		c      string
+		strict bool
+		e      string
+		err    string
+	}{
+		{
+			"missing_key",
+			`{{ .Input.b }}`,
+			false,
+			"<no value>",
+			"",
+		},
+		{
+			"strict_missing_key",
+			`{{ .Input.b }}`,
+			true,
+			"",
+			`map has no entry for key "b"`,
+		},
+		{
+			"strict_nested_missing_key",
+			"a\n{{ .Input.c.d }}",
+			true,
+			"",
+			`:2:9: executing`,
+		},
+		{
+			"strict_present_key",
+			`{{ .Input.a }}`,
+			true,
+			"1",
+			"",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents: tc.c,
+				Strict:   tc.strict,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := tpl.Execute(&ExecuteInput{
+				Input: []byte(`{"a": 1}`),
+			})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal([]byte(tc.e), a.Output) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, string(a.Output))
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_pluginFuncs(t *testing.T) {
+	funcs := map[string]PluginFunc{
+		"concat": func(args ...string) (string, error) {
+			return strings.Join(args, "|"), nil
+		},
+		"oops": func(args ...string) (string, error) {
+			return "", errors.New("plugin failed")
+		},
+	}
+
+	cases := []struct {
+		name  string
+		c     string
+		funcs map[string]PluginFunc
+		e     string
+		err   string
+	}{
+		{
+			"strings",
+			`{{ concat "a" "b" }}`,
+			funcs,
+			"a|b",
+			"",
+		},
+		{
+			"json",
+			`{{ concat 1 true .Input }}`,
+			funcs,
+			`1|true|{"a":1}`,
+			"",
+		},
+		{
+			"error",
+			`{{ oops }}`,
+			funcs,
+			"",
+			"plugin failed",
+		},
+		{
+			"undefined",
+			`{{ concat "a" }}`,
+			nil,
+			"",
+			`function "concat" not defined`,
+		},
+		{
+			"shadows_builtin",
+			`{{ key "a" }}`,
+			map[string]PluginFunc{"key": funcs["concat"]},
+			"",
+			"shadows a built-in function",
+		},
+		{
+			"invalid_name",
+			`{{ "a" }}`,
+			map[string]PluginFunc{"my-func": funcs["concat"]},
+			"",
+			"invalid plugin function name",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents:    tc.c,
+				PluginFuncs: tc.funcs,
+			})
+			if err == nil {
+				var a *ExecuteResult
+				a, err = tpl.Execute(&ExecuteInput{
+					Input: []byte(`{"a": 1}`),
+				})
+				if err == nil && !bytes.Equal([]byte(tc.e), a.Output) {
+					t.Errorf("\nexp: %#v\nact: %#v", tc.e, string(a.Output))
+				}
+			}
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_sandbox(t *testing.T) {
+	sandbox, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sandbox)
+
+	outside, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	inside := filepath.Join(sandbox, "inside")
+	if err := ioutil.WriteFile(inside, []byte("inside"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(outside, "secret")
+	if err := ioutil.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(sandbox, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		c       string
+		sandbox string
+		err     string
+	}{
+		{
+			"no_sandbox",
+			fmt.Sprintf(`{{ file %q }}`, secret),
+			"",
+			"",
+		},
+		{
+			"inside",
+			fmt.Sprintf(`{{ file %q }}`, inside),
+			sandbox,
+			"",
+		},
+		{
+			"not_yet_created",
+			fmt.Sprintf(`{{ file %q }}`, filepath.Join(sandbox, "later", "file")),
+			sandbox,
+			"",
+		},
+		{
+			"outside",
+			fmt.Sprintf(`{{ file %q }}`, secret),
+			sandbox,
+			"is outside of the sandbox",
+		},
+		{
+			"traversal",
+			fmt.Sprintf(`{{ file %q }}`, sandbox+"/../"+filepath.Base(outside)+"/secret"),
+			sandbox,
+			"is outside of the sandbox",
+		},
+		{
+			"symlink",
+			fmt.Sprintf(`{{ file %q }}`, filepath.Join(sandbox, "link")),
+			sandbox,
+			"is outside of the sandbox",
+		},
+		{
+			"plugin_outside",
+			`{{ "1" | plugin "echo" }}`,
+			sandbox,
+			"plugin:",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents:    tc.c,
+				SandboxPath: tc.sandbox,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = tpl.Execute(&ExecuteInput{
+				Brain: NewBrain(),
+			})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_limits(t *testing.T) {
+	cases := []struct {
+		name               string
+		c                  string
+		maxOutputSize      int
+		maxRangeIterations int
+		e                  string
+		limit              string
+	}{
+		{
+			"unlimited",
+			`{{ range .Input }}{{ range $.Input }}{{ . }}{{ end }}{{ end }}`,
+			0,
+			0,
+			"123123123",
+			"",
+		},
+		{
+			"output_size_at_limit",
+			`{{ range .Input }}{{ . }}{{ end }}`,
+			3,
+			0,
+			"123",
+			"",
+		},
+		{
+			"output_size_exceeded",
+			`{{ range .Input }}{{ range $.Input }}{{ . }}{{ end }}{{ end }}`,
+			4,
+			0,
+			"",
+			LimitOutputSize,
+		},
+		{
+			"range_iterations_at_limit",
+			`{{ range .Input }}{{ range $.Input }}{{ end }}{{ end }}`,
+			0,
+			12,
+			"",
+			"",
+		},
+		{
+			"range_iterations_exceeded",
+			`{{ range .Input }}{{ range $.Input }}{{ end }}{{ end }}`,
+			0,
+			11,
+			"",
+			LimitRangeIterations,
+		},
+		{
+			"range_iterations_summed",
+			`{{ range .Input }}{{ end }}{{ range .Input }}{{ end }}`,
+			0,
+			5,
+			"",
+			LimitRangeIterations,
+		},
+		{
+			"range_iterations_defined_template",
+			`{{ define "t" }}{{ range . }}{{ end }}{{ end }}{{ template "t" .Input }}{{ template "t" .Input }}`,
+			0,
+			5,
+			"",
+			LimitRangeIterations,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tpl, err := NewTemplate(&NewTemplateInput{
+				Contents:           tc.c,
+				MaxOutputSize:      tc.maxOutputSize,
+				MaxRangeIterations: tc.maxRangeIterations,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := tpl.Execute(&ExecuteInput{
+				Input: []byte(`[1, 2, 3]`),
+			})
+			if tc.limit != "" {
+				lerr, ok := errors.Cause(err).(*LimitError)
+				if !ok {
+					t.Fatalf("expected *LimitError, got %v", err)
+				}
+				if lerr.Limit != tc.limit {
+					t.Errorf("\nexp: %#v\nact: %#v", tc.limit, lerr.Limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal([]byte(tc.e), a.Output) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.e, string(a.Output))
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_seed(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ uuidv4 }} {{ randAlphaNum 8 }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execute := func(seed int64) string {
+		a, err := tpl.Execute(&ExecuteInput{Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(a.Output)
+	}
+
+	t.Run("stable_without_seed", func(t *testing.T) {
+		if a, b := execute(0), execute(0); a != b {
+			t.Errorf("expected %q to equal %q", a, b)
+		}
+	})
+
+	t.Run("stable_with_seed", func(t *testing.T) {
+		if a, b := execute(1), execute(1); a != b {
+			t.Errorf("expected %q to equal %q", a, b)
+		}
+	})
+
+	t.Run("rotates_with_seed", func(t *testing.T) {
+		if a, b := execute(1), execute(2); a == b {
+			t.Errorf("expected %q to differ from %q", a, b)
+		}
+	})
+}
+
+func TestTemplate_Execute_kvDiff(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ with treeDiff "key" }}` +
+			`{{ range .Added }}+{{ .Key }}={{ .Value }} {{ end }}` +
+			`{{ range .Changed }}~{{ .Key }}={{ .Value }} {{ end }}` +
+			`{{ range .Removed }}-{{ .Key }}={{ .Value }} {{ end }}` +
+			`{{ len .Current }}{{ end }}` +
+			`{{ with lsDiff "key" }} ls:{{ range .Added }}+{{ .Key }}{{ end }}{{ end }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := dep.NewKVListQuery("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBrain()
+	execute := func(pairs ...*dep.KeyPair) string {
+		b.Remember(d, pairs)
+		result, err := tpl.Execute(&ExecuteInput{Brain: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.MarkRendered(tpl.ID(), result.Used.List())
+		return string(result.Output)
+	}
+
+	exp := "+admin/port=1134 +maxconns=5 2 ls:+maxconns"
+	act := execute(
+		&dep.KeyPair{Key: "admin/port", Value: "1134"},
+		&dep.KeyPair{Key: "maxconns", Value: "5"},
+	)
+	if act != exp {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	exp = "+minconns=2 ~maxconns=10 -admin/port=1134 2 ls:+minconns"
+	act = execute(
+		&dep.KeyPair{Key: "maxconns", Value: "10"},
+		&dep.KeyPair{Key: "minconns", Value: "2"},
+	)
+	if act != exp {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	// Without a new render, the changes are still relative to the last one.
+	exp = "~maxconns=11 2 ls:"
+	b.Remember(d, []*dep.KeyPair{
+		&dep.KeyPair{Key: "maxconns", Value: "11"},
+		&dep.KeyPair{Key: "minconns", Value: "2"},
+	})
+	if _, err := tpl.Execute(&ExecuteInput{Brain: b}); err != nil {
+		t.Fatal(err)
+	}
+	act = execute(
+		&dep.KeyPair{Key: "maxconns", Value: "11"},
+		&dep.KeyPair{Key: "minconns", Value: "2"},
+	)
+	if act != exp {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+}
@@ -118,3 +118,33 @@ func (d *TestDepRetry) String() string {
 }
 
 func (d *TestDepRetry) Stop() {}
+
+// TestDepDerived is a special dependency whose data changes on every fetch
+// while its index stays the same, like a dependency which resolves addresses.
+type TestDepDerived struct {
+	sync.Mutex
+	name    string
+	fetches int
+}
+
+func (d *TestDepDerived) Fetch(clients *dep.ClientSet, opts *dep.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	time.Sleep(10 * time.Millisecond)
+
+	d.Lock()
+	defer d.Unlock()
+
+	d.fetches++
+	data := fmt.Sprintf("this is fetch %d", d.fetches)
+	rm := &dep.ResponseMetadata{LastIndex: 1, Derived: true}
+	return data, rm, nil
+}
+
+func (d *TestDepDerived) CanShare() bool {
+	return true
+}
+
+func (d *TestDepDerived) String() string {
+	return fmt.Sprintf("test_dep_derived(%s)", d.name)
+}
+
+func (d *TestDepDerived) Stop() {}
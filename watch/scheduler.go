@@ -0,0 +1,102 @@
+package watch
+
+import "sync"
+
+// The priorities of the dependencies. When the number of requests which
+// establish watches is limited, waiting requests are made in the order of
+// their priority, and in the order they started waiting within a priority.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// fetchScheduler limits how many requests which establish watches, made
+// before a view has data or while it is retrying after an error, run at the
+// same time. A nil scheduler does not limit them.
+type fetchScheduler struct {
+	lock    sync.Mutex
+	free    int
+	waiting []*fetchWaiter
+}
+
+// fetchWaiter is a request waiting for its turn. ready is closed when it may
+// run.
+type fetchWaiter struct {
+	priority int
+	ready    chan struct{}
+}
+
+// newFetchScheduler creates a new scheduler which runs at most max requests at
+// the same time. It returns nil if max is 0 or less.
+func newFetchScheduler(max int) *fetchScheduler {
+	if max <= 0 {
+		return nil
+	}
+	return &fetchScheduler{free: max}
+}
+
+// acquire waits until a request of the given priority may run. The caller
+// must call release once the request returns. It returns false if stopCh was
+// closed before the request could run.
+func (s *fetchScheduler) acquire(priority int, stopCh <-chan struct{}) bool {
+	if s == nil {
+		return true
+	}
+
+	s.lock.Lock()
+	if s.free > 0 && len(s.waiting) == 0 {
+		s.free--
+		s.lock.Unlock()
+		return true
+	}
+
+	w := &fetchWaiter{priority: priority, ready: make(chan struct{})}
+	i := len(s.waiting)
+	for i > 0 && s.waiting[i-1].priority < priority {
+		i--
+	}
+	s.waiting = append(s.waiting, nil)
+	copy(s.waiting[i+1:], s.waiting[i:])
+	s.waiting[i] = w
+	s.lock.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-stopCh:
+	}
+
+	s.lock.Lock()
+	for i, other := range s.waiting {
+		if other == w {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			s.lock.Unlock()
+			return false
+		}
+	}
+	s.lock.Unlock()
+
+	// The turn was given to this request while it stopped, so pass it on.
+	s.release()
+	return false
+}
+
+// release ends a request, giving its turn to the waiting request with the
+// highest priority.
+func (s *fetchScheduler) release() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.waiting) == 0 {
+		s.free++
+		return
+	}
+	w := s.waiting[0]
+	s.waiting = s.waiting[1:]
+	close(w.ready)
+}
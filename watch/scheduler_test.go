@@ -0,0 +1,84 @@
+package watch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchScheduler_priority(t *testing.T) {
+	s := newFetchScheduler(1)
+	stopCh := make(chan struct{})
+
+	if !s.acquire(PriorityNormal, stopCh) {
+		t.Fatal("expected the first request to run")
+	}
+
+	orderCh := make(chan int, 3)
+	for i, priority := range []int{PriorityLow, PriorityHigh, PriorityNormal} {
+		go func(priority int) {
+			if s.acquire(priority, stopCh) {
+				orderCh <- priority
+			}
+		}(priority)
+
+		// Wait for the request to queue up, so the order is deterministic.
+		for {
+			s.lock.Lock()
+			n := len(s.waiting)
+			s.lock.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var order []int
+	for i := 0; i < 3; i++ {
+		s.release()
+		select {
+		case priority := <-orderCh:
+			order = append(order, priority)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	exp := []int{PriorityHigh, PriorityNormal, PriorityLow}
+	if !reflect.DeepEqual(exp, order) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, order)
+	}
+}
+
+func TestFetchScheduler_stop(t *testing.T) {
+	s := newFetchScheduler(1)
+	if !s.acquire(PriorityNormal, nil) {
+		t.Fatal("expected the first request to run")
+	}
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+	if s.acquire(PriorityHigh, stopCh) {
+		t.Fatal("expected a stopped request not to run")
+	}
+
+	// The stopped request does not hold a turn.
+	s.release()
+	if !s.acquire(PriorityNormal, nil) {
+		t.Fatal("expected the next request to run")
+	}
+}
+
+func TestFetchScheduler_unlimited(t *testing.T) {
+	var s *fetchScheduler
+	if s = newFetchScheduler(0); s != nil {
+		t.Fatal("expected no scheduler")
+	}
+	for i := 0; i < 3; i++ {
+		if !s.acquire(PriorityLow, nil) {
+			t.Fatal("expected the request to run")
+		}
+	}
+	s.release()
+}
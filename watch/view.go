@@ -1,10 +1,14 @@
 package watch
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"reflect"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dep "github.com/hashicorp/consul-template/dependency"
@@ -15,6 +19,19 @@ const (
 	defaultWaitTime = 60 * time.Second
 )
 
+var (
+	// requestIDPrefix is unique to this process, so request IDs do not repeat
+	// across restarts.
+	requestIDPrefix = newRequestIDPrefix()
+
+	// requestSeq is the sequence number of the last fetch request.
+	requestSeq uint64
+
+	// statusCodeRe matches the HTTP status code in the errors returned by the
+	// Consul and Vault API clients.
+	statusCodeRe = regexp.MustCompile(`(?:Unexpected response code|Code): (\d{3})`)
+)
+
 // View is a representation of a Dependency and the most recent data it has
 // received from Consul.
 type View struct {
@@ -34,6 +51,51 @@ type View struct {
 
 	// stopCh is used to stop polling on this View
 	stopCh chan struct{}
+
+	// paused is set while polling this view is paused, and resumeCh is closed
+	// when it is resumed. Both are protected by pauseLock.
+	pauseLock sync.Mutex
+	paused    bool
+	resumeCh  chan struct{}
+
+	// queued is set while the view is pending on the data channel under the
+	// coalesce backpressure policy, and cleared when its data is read.
+	queued uint32
+
+	// counters counts coalesced and dropped publishes. It is nil for views
+	// which are not created by a watcher.
+	counters *backpressureCounters
+
+	// fetchCounters counts fetch requests and the ones which failed. It is nil
+	// for views which are not created by a watcher.
+	fetchCounters *fetchCounters
+
+	// scheduler orders the requests which establish the watch by priority. It
+	// is nil for views which are not created by a watcher, or whose watcher
+	// does not limit these requests.
+	scheduler *fetchScheduler
+
+	// priority is the priority of the dependency, and is accessed atomically.
+	priority int32
+
+	// retrying is set by poll while the view retries after an error.
+	retrying bool
+}
+
+// ViewError is the error a View publishes when fetching its dependency fails.
+type ViewError struct {
+	// Dependency is the dependency that failed to fetch.
+	Dependency dep.Dependency
+
+	// Err is the error the fetch returned.
+	Err error
+
+	// RequestID identifies the failed fetch request in the trace logs.
+	RequestID string
+}
+
+func (e *ViewError) Error() string {
+	return e.Err.Error()
 }
 
 // NewView creates a new view object from the given Consul API client and
@@ -56,6 +118,8 @@ func NewView(config *WatcherConfig, d dep.Dependency) (*View, error) {
 
 // Data returns the most-recently-received data from Consul for this View.
 func (v *View) Data() interface{} {
+	atomic.StoreUint32(&v.queued, 0)
+
 	v.dataLock.RLock()
 	defer v.dataLock.RUnlock()
 	return v.data
@@ -65,6 +129,8 @@ func (v *View) Data() interface{} {
 // this view, along with the last index. This is atomic so you will get the
 // index that goes with the data you are fetching.
 func (v *View) DataAndLastIndex() (interface{}, uint64) {
+	atomic.StoreUint32(&v.queued, 0)
+
 	v.dataLock.RLock()
 	defer v.dataLock.RUnlock()
 	return v.data, v.lastIndex
@@ -87,12 +153,10 @@ func (v *View) poll(viewCh chan<- *View, errCh chan<- error) {
 			// Reset the retry to avoid exponentially incrementing retries when we
 			// have some successful requests
 			currentRetry = defaultRetry
+			v.retrying = false
 
 			log.Printf("[TRACE] (view) %s received data", v.Dependency)
-			select {
-			case <-v.stopCh:
-			case viewCh <- v:
-			}
+			v.publish(viewCh)
 
 			// If we are operating in once mode, do not loop - we received data at
 			// least once which is the API promise here.
@@ -100,15 +164,20 @@ func (v *View) poll(viewCh chan<- *View, errCh chan<- error) {
 				return
 			}
 		case err := <-fetchErrCh:
-			log.Printf("[ERR] (view) %s %s", v.Dependency, err)
+			verr, ok := err.(*ViewError)
+			if !ok {
+				verr = &ViewError{Dependency: v.Dependency, Err: err}
+			}
+			log.Printf("[ERR] (view) %s %s (request %s)", v.Dependency, verr.Err, verr.RequestID)
 
 			// Push the error back up to the watcher
 			select {
 			case <-v.stopCh:
-			case errCh <- err:
+			case errCh <- verr:
 			}
 
 			// Sleep and retry
+			v.retrying = true
 			if v.config.RetryFunc != nil {
 				currentRetry = v.config.RetryFunc(currentRetry)
 			}
@@ -122,6 +191,34 @@ func (v *View) poll(viewCh chan<- *View, errCh chan<- error) {
 	}
 }
 
+// publish sends this view on the given channel, applying the backpressure
+// policy of the watcher if the channel is full.
+func (v *View) publish(viewCh chan<- *View) {
+	switch v.config.Backpressure {
+	case BackpressureCoalesce:
+		// The data is read when the view is received, so a view which is still
+		// pending will deliver this update too.
+		if !atomic.CompareAndSwapUint32(&v.queued, 0, 1) {
+			v.counters.addCoalesced()
+			log.Printf("[TRACE] (view) %s coalesced with pending data", v.Dependency)
+			return
+		}
+	case BackpressureDrop:
+		select {
+		case viewCh <- v:
+		default:
+			v.counters.addDropped()
+			log.Printf("[DEBUG] (view) %s dropped data, the data channel is full", v.Dependency)
+		}
+		return
+	}
+
+	select {
+	case <-v.stopCh:
+	case viewCh <- v:
+	}
+}
+
 // fetch queries the Consul instance for the attached dependency. This API
 // promises that either data will be written to doneCh or an error will be
 // written to errCh. It is designed to be run in a goroutine that selects the
@@ -144,26 +241,61 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 		default:
 		}
 
+		// A paused view makes no requests until it is resumed.
+		if !v.waitResumed() {
+			return
+		}
+
+		id := newRequestID()
+		index := v.lastIndex
+		log.Printf("[TRACE] (view) %s request %s started (index %d, stale %t)",
+			v.Dependency, id, index, allowStale)
+
+		// Requests which establish the watch, before the view has data or while
+		// it retries after an error, wait for their turn so the dependencies with
+		// the highest priority are fetched first.
+		establishing := !v.receivedData || v.retrying
+		if establishing && !v.scheduler.acquire(v.Priority(), v.stopCh) {
+			return
+		}
+
+		start := time.Now()
 		data, rm, err := v.Dependency.Fetch(v.config.Clients, &dep.QueryOptions{
 			AllowStale: allowStale,
 			WaitTime:   defaultWaitTime,
-			WaitIndex:  v.lastIndex,
+			WaitIndex:  index,
 		})
+		duration := time.Since(start)
+		if establishing {
+			v.scheduler.release()
+		}
+		v.fetchCounters.addFetch(err != nil && err != dep.ErrStopped)
+
 		if err != nil {
 			if err == dep.ErrStopped {
-				log.Printf("[TRACE] (view) %s reported stop", v.Dependency)
+				log.Printf("[TRACE] (view) %s request %s reported stop after %s",
+					v.Dependency, id, duration)
 			} else {
-				errCh <- err
+				log.Printf("[TRACE] (view) %s request %s failed (status %s, duration %s, index %d)",
+					v.Dependency, id, fetchStatus(err), duration, index)
+				errCh <- &ViewError{Dependency: v.Dependency, Err: err, RequestID: id}
 			}
 			return
 		}
 
 		if rm == nil {
-			errCh <- fmt.Errorf("received nil response metadata - this is a bug " +
-				"and should be reported")
+			errCh <- &ViewError{
+				Dependency: v.Dependency,
+				Err: fmt.Errorf("received nil response metadata - this is a bug " +
+					"and should be reported"),
+				RequestID: id,
+			}
 			return
 		}
 
+		log.Printf("[TRACE] (view) %s request %s finished (status %s, duration %s, index %d -> %d)",
+			v.Dependency, id, fetchStatus(nil), duration, index, rm.LastIndex)
+
 		if allowStale && rm.LastContact > v.config.MaxStale {
 			allowStale = false
 			log.Printf("[TRACE] (view) %s stale data (last contact exceeded max_stale)", v.Dependency)
@@ -174,7 +306,9 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 			allowStale = true
 		}
 
-		if rm.LastIndex == v.lastIndex {
+		// Derived data may change while the index stays the same, so it is
+		// compared with the previous data below instead.
+		if rm.LastIndex == v.lastIndex && !rm.Derived {
 			log.Printf("[TRACE] (view) %s no new data (index was the same)", v.Dependency)
 			continue
 		}
@@ -209,8 +343,100 @@ func (v *View) fetch(doneCh chan<- struct{}, errCh chan<- error) {
 	}
 }
 
+// Priority returns the priority of the dependency of this view.
+func (v *View) Priority() int {
+	return int(atomic.LoadInt32(&v.priority))
+}
+
+// pause stops this view from making new requests until it is resumed. A
+// request in progress is not interrupted. It returns false if the view was
+// already paused.
+func (v *View) pause() bool {
+	v.pauseLock.Lock()
+	defer v.pauseLock.Unlock()
+
+	if v.paused {
+		return false
+	}
+	v.paused = true
+	v.resumeCh = make(chan struct{})
+	return true
+}
+
+// resume lets a paused view make requests again. It returns false if the view
+// was not paused.
+func (v *View) resume() bool {
+	v.pauseLock.Lock()
+	defer v.pauseLock.Unlock()
+
+	if !v.paused {
+		return false
+	}
+	v.paused = false
+	close(v.resumeCh)
+	return true
+}
+
+// isPaused returns true while this view is paused.
+func (v *View) isPaused() bool {
+	v.pauseLock.Lock()
+	defer v.pauseLock.Unlock()
+
+	return v.paused
+}
+
+// waitResumed blocks while this view is paused. It returns false if the view
+// was stopped in the meantime.
+func (v *View) waitResumed() bool {
+	v.pauseLock.Lock()
+	paused, resumeCh := v.paused, v.resumeCh
+	v.pauseLock.Unlock()
+
+	if !paused {
+		return true
+	}
+
+	log.Printf("[TRACE] (view) %s paused", v.Dependency)
+	select {
+	case <-resumeCh:
+		log.Printf("[TRACE] (view) %s resumed", v.Dependency)
+		return true
+	case <-v.stopCh:
+		return false
+	}
+}
+
 // stop halts polling of this view.
 func (v *View) stop() {
 	v.Dependency.Stop()
 	close(v.stopCh)
 }
+
+// newRequestID returns a new ID for a fetch request, which is unique within
+// this process.
+func newRequestID() string {
+	return fmt.Sprintf("%s-%d", requestIDPrefix, atomic.AddUint64(&requestSeq, 1))
+}
+
+// newRequestIDPrefix returns a random prefix for the request IDs of this
+// process.
+func newRequestIDPrefix() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
+
+// fetchStatus returns the status of a fetch request with the given error for
+// the trace logs: "ok" on success, the HTTP status code if the error has one,
+// or "error" otherwise.
+func fetchStatus(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if m := statusCodeRe.FindStringSubmatch(err.Error()); m != nil {
+		return m[1]
+	}
+	return "error"
+}
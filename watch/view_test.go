@@ -1,7 +1,9 @@
 package watch
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -91,6 +93,9 @@ func TestPoll_returnsErrCh(t *testing.T) {
 		if err.Error() != expected {
 			t.Errorf("expected %q to be %q", err.Error(), expected)
 		}
+		if verr, ok := err.(*ViewError); !ok || verr.Dependency != view.Dependency {
+			t.Errorf("expected a view error for %s, got %#v", view.Dependency, err)
+		}
 	case <-view.stopCh:
 		t.Errorf("poll received premature stop")
 	}
@@ -229,6 +234,31 @@ func TestFetch_savesView(t *testing.T) {
 	}
 }
 
+func TestFetch_derived(t *testing.T) {
+	view, err := NewView(defaultWatcherConfig, &TestDepDerived{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second fetch has the same index as the first one, but its data is
+	// still saved since it is derived.
+	for _, expected := range []string{"this is fetch 1", "this is fetch 2"} {
+		doneCh := make(chan struct{})
+		errCh := make(chan error)
+
+		go view.fetch(doneCh, errCh)
+
+		select {
+		case <-doneCh:
+			if !reflect.DeepEqual(view.Data(), expected) {
+				t.Errorf("expected %q to be %q", view.Data(), expected)
+			}
+		case err := <-errCh:
+			t.Fatalf("error while fetching: %s", err)
+		}
+	}
+}
+
 func TestFetch_returnsErrCh(t *testing.T) {
 	view, err := NewView(defaultWatcherConfig, &TestDepFetchError{})
 	if err != nil {
@@ -238,6 +268,8 @@ func TestFetch_returnsErrCh(t *testing.T) {
 	doneCh := make(chan struct{})
 	errCh := make(chan error)
 
+	view.fetchCounters = &fetchCounters{}
+
 	go view.fetch(doneCh, errCh)
 
 	select {
@@ -248,6 +280,61 @@ func TestFetch_returnsErrCh(t *testing.T) {
 		if err.Error() != expected {
 			t.Fatalf("expected error %q to be %q", err.Error(), expected)
 		}
+		verr, ok := err.(*ViewError)
+		if !ok || !strings.HasPrefix(verr.RequestID, requestIDPrefix+"-") {
+			t.Errorf("expected a view error with a request ID, got %#v", err)
+		}
+		if view.fetchCounters.fetches != 1 || view.fetchCounters.errors != 1 {
+			t.Errorf("expected 1 fetch and 1 error, got %d and %d",
+				view.fetchCounters.fetches, view.fetchCounters.errors)
+		}
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := newRequestID(), newRequestID()
+	if a == b {
+		t.Errorf("expected unique request IDs, got %q twice", a)
+	}
+	if !strings.HasPrefix(a, requestIDPrefix+"-") {
+		t.Errorf("expected %q to start with %q", a, requestIDPrefix)
+	}
+}
+
+func TestFetchStatus(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		e    string
+	}{
+		{
+			"ok",
+			nil,
+			"ok",
+		},
+		{
+			"consul",
+			fmt.Errorf("Unexpected response code: 403 (Permission denied)"),
+			"403",
+		},
+		{
+			"vault",
+			fmt.Errorf("Error making API request.\n\nURL: GET https://vault/v1/secret/foo\nCode: 503. Errors:\n\n* Vault is sealed"),
+			"503",
+		},
+		{
+			"other",
+			fmt.Errorf("dial tcp 127.0.0.1:8500: connection refused"),
+			"error",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := fetchStatus(tc.err); act != tc.e {
+				t.Errorf("\nexp: %q\nact: %q", tc.e, act)
+			}
+		})
 	}
 }
 
@@ -272,3 +359,122 @@ func TestStop_stopsPolling(t *testing.T) {
 		// Successfully stopped
 	}
 }
+
+func TestPoll_paused(t *testing.T) {
+	view, err := NewView(defaultWatcherConfig, &TestDep{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !view.pause() {
+		t.Fatal("expected the view to pause")
+	}
+	if view.pause() {
+		t.Error("expected a paused view not to pause again")
+	}
+
+	viewCh := make(chan *View)
+	errCh := make(chan error)
+
+	go view.poll(viewCh, errCh)
+	defer view.stop()
+
+	select {
+	case <-viewCh:
+		t.Fatal("expected no data while paused")
+	case err := <-errCh:
+		t.Fatal(err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !view.resume() {
+		t.Fatal("expected the view to resume")
+	}
+
+	select {
+	case <-viewCh:
+	case err := <-errCh:
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("expected data once resumed")
+	}
+}
+
+func TestView_publish(t *testing.T) {
+	cases := []struct {
+		name         string
+		backpressure string
+		bufferSize   int
+		pending      int
+		coalesced    uint64
+		dropped      uint64
+	}{
+		{
+			"block",
+			BackpressureBlock,
+			3,
+			3,
+			0,
+			0,
+		},
+		{
+			"coalesce",
+			BackpressureCoalesce,
+			3,
+			1,
+			2,
+			0,
+		},
+		{
+			"drop",
+			BackpressureDrop,
+			1,
+			1,
+			0,
+			2,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			view, err := NewView(&WatcherConfig{Backpressure: tc.backpressure}, &TestDep{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			view.counters = &backpressureCounters{}
+			defer view.stop()
+
+			viewCh := make(chan *View, tc.bufferSize)
+			for i := 0; i < 3; i++ {
+				view.publish(viewCh)
+			}
+
+			if len(viewCh) != tc.pending {
+				t.Errorf("expected %d pending, got %d", tc.pending, len(viewCh))
+			}
+			if view.counters.coalesced != tc.coalesced {
+				t.Errorf("expected %d coalesced, got %d", tc.coalesced, view.counters.coalesced)
+			}
+			if view.counters.dropped != tc.dropped {
+				t.Errorf("expected %d dropped, got %d", tc.dropped, view.counters.dropped)
+			}
+		})
+	}
+
+	t.Run("coalesce_after_read", func(t *testing.T) {
+		view, err := NewView(&WatcherConfig{Backpressure: BackpressureCoalesce}, &TestDep{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer view.stop()
+
+		viewCh := make(chan *View, 2)
+		view.publish(viewCh)
+		(<-viewCh).Data()
+		view.publish(viewCh)
+
+		if len(viewCh) != 1 {
+			t.Errorf("expected the view to be published again after its data was read")
+		}
+	})
+}
@@ -3,7 +3,9 @@ package watch
 import (
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dep "github.com/hashicorp/consul-template/dependency"
@@ -24,6 +26,56 @@ var DefaultRetryFunc RetryFunc = func(t time.Duration) time.Duration {
 // dataBufferSize is the default number of views to process in a batch.
 const dataBufferSize = 2048
 
+const (
+	// BackpressureBlock makes a view wait for room on the data channel before
+	// publishing its data. This is the default.
+	BackpressureBlock = "block"
+
+	// BackpressureCoalesce keeps at most one pending publish per view. Since a
+	// view always holds its latest data, an update which arrives while the view
+	// is still pending is coalesced into the pending one.
+	BackpressureCoalesce = "coalesce"
+
+	// BackpressureDrop drops the publish of a view when the data channel is
+	// full. The dropped data is only seen with the next update of the view.
+	BackpressureDrop = "drop"
+)
+
+// backpressureCounters counts the publishes which were coalesced or dropped.
+type backpressureCounters struct {
+	coalesced uint64
+	dropped   uint64
+}
+
+func (c *backpressureCounters) addCoalesced() {
+	if c != nil {
+		atomic.AddUint64(&c.coalesced, 1)
+	}
+}
+
+func (c *backpressureCounters) addDropped() {
+	if c != nil {
+		atomic.AddUint64(&c.dropped, 1)
+	}
+}
+
+// fetchCounters counts the fetch requests of the views and the ones which
+// failed.
+type fetchCounters struct {
+	fetches uint64
+	errors  uint64
+}
+
+func (c *fetchCounters) addFetch(failed bool) {
+	if c == nil {
+		return
+	}
+	atomic.AddUint64(&c.fetches, 1)
+	if failed {
+		atomic.AddUint64(&c.errors, 1)
+	}
+}
+
 // Watcher is a top-level manager for views that poll Consul for data.
 type Watcher struct {
 	sync.Mutex
@@ -40,6 +92,21 @@ type Watcher struct {
 	// depViewMap is a map of Templates to Views. Templates are keyed by
 	// their string.
 	depViewMap map[string]*View
+
+	// counters are shared with the views to count coalesced and dropped
+	// publishes.
+	counters *backpressureCounters
+
+	// fetchCounters are shared with the views to count fetch requests.
+	fetchCounters *fetchCounters
+
+	// scheduler is shared with the views to order the requests which establish
+	// their watches by priority.
+	scheduler *fetchScheduler
+
+	// priorities are the priorities of the dependencies, keyed by their string.
+	// Dependencies without one have the normal priority.
+	priorities map[string]int
 }
 
 // WatcherConfig is the configuration for a particular Watcher.
@@ -62,6 +129,20 @@ type WatcherConfig struct {
 	// RenewVault determines if the watcher should renew the Vault token as a
 	// background job.
 	RenewVault bool
+
+	// BufferSize is the capacity of the data channel. If zero, a default is
+	// used.
+	BufferSize int
+
+	// Backpressure is the policy applied when the data channel is full. It is
+	// one of the Backpressure constants; the default is BackpressureBlock.
+	Backpressure string
+
+	// MaxConcurrentFetches is the maximum number of requests which establish
+	// watches, made before a view has data or while it retries after an error,
+	// that run at the same time. Waiting requests run in the order of the
+	// priority of their dependency. If zero, they are not limited.
+	MaxConcurrentFetches int
 }
 
 // NewWatcher creates a new watcher using the given API client.
@@ -100,6 +181,10 @@ func (w *Watcher) Add(d dep.Dependency) (bool, error) {
 
 	log.Printf("[TRACE] (watcher) %s starting", d)
 
+	v.counters = w.counters
+	v.fetchCounters = w.fetchCounters
+	v.scheduler = w.scheduler
+	v.priority = int32(w.priorities[d.String()])
 	w.depViewMap[d.String()] = v
 	go v.poll(w.DataCh, w.ErrCh)
 
@@ -142,6 +227,7 @@ func (w *Watcher) Remove(d dep.Dependency) bool {
 		log.Printf("[TRACE] (watcher) actually removing %s", d)
 		view.stop()
 		delete(w.depViewMap, d.String())
+		delete(w.priorities, d.String())
 		return true
 	}
 
@@ -149,6 +235,98 @@ func (w *Watcher) Remove(d dep.Dependency) bool {
 	return false
 }
 
+// Prioritize sets the priority of the given dependency, which may be watched
+// already or not yet. A dependency shared by several templates keeps the
+// highest priority it was given, so a lower priority is ignored once one was
+// set.
+func (w *Watcher) Prioritize(d dep.Dependency, priority int) {
+	w.Lock()
+	defer w.Unlock()
+
+	if current, ok := w.priorities[d.String()]; ok && current >= priority {
+		return
+	}
+	w.priorities[d.String()] = priority
+
+	if view := w.depViewMap[d.String()]; view != nil {
+		atomic.StoreInt32(&view.priority, int32(priority))
+	}
+}
+
+// Pause stops polling the given dependency until it is resumed, keeping the
+// data it last received. A request in progress is not interrupted. If the
+// dependency is not being watched or is already paused, this function returns
+// false.
+func (w *Watcher) Pause(d dep.Dependency) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	view, ok := w.depViewMap[d.String()]
+	if !ok || view == nil || !view.pause() {
+		return false
+	}
+
+	log.Printf("[DEBUG] (watcher) paused %s", d)
+	return true
+}
+
+// Resume resumes polling the given paused dependency. If the dependency is not
+// being watched or is not paused, this function returns false.
+func (w *Watcher) Resume(d dep.Dependency) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	view, ok := w.depViewMap[d.String()]
+	if !ok || view == nil || !view.resume() {
+		return false
+	}
+
+	log.Printf("[DEBUG] (watcher) resumed %s", d)
+	return true
+}
+
+// Paused returns the sorted names of the dependencies which are paused.
+func (w *Watcher) Paused() []string {
+	w.Lock()
+	defer w.Unlock()
+
+	var paused []string
+	for key, view := range w.depViewMap {
+		if view != nil && view.isPaused() {
+			paused = append(paused, key)
+		}
+	}
+	sort.Strings(paused)
+	return paused
+}
+
+// Pending returns the number of views waiting on the data channel.
+func (w *Watcher) Pending() int {
+	return len(w.DataCh)
+}
+
+// Coalesced returns the number of publishes which were coalesced into a
+// pending publish of the same view.
+func (w *Watcher) Coalesced() uint64 {
+	return atomic.LoadUint64(&w.counters.coalesced)
+}
+
+// Dropped returns the number of publishes which were dropped because the data
+// channel was full.
+func (w *Watcher) Dropped() uint64 {
+	return atomic.LoadUint64(&w.counters.dropped)
+}
+
+// Fetches returns the number of fetch requests the views have made.
+func (w *Watcher) Fetches() uint64 {
+	return atomic.LoadUint64(&w.fetchCounters.fetches)
+}
+
+// FetchErrors returns the number of fetch requests which failed.
+func (w *Watcher) FetchErrors() uint64 {
+	return atomic.LoadUint64(&w.fetchCounters.errors)
+}
+
 // Size returns the number of views this watcher is watching.
 func (w *Watcher) Size() int {
 	w.Lock()
@@ -174,6 +352,7 @@ func (w *Watcher) Stop() {
 
 	// Reset the map to have no views
 	w.depViewMap = make(map[string]*View)
+	w.priorities = make(map[string]int)
 
 	// Close any idle TCP connections
 	w.config.Clients.Stop()
@@ -189,12 +368,29 @@ func (w *Watcher) init() error {
 		w.config.RetryFunc = DefaultRetryFunc
 	}
 
+	switch w.config.Backpressure {
+	case "":
+		w.config.Backpressure = BackpressureBlock
+	case BackpressureBlock, BackpressureCoalesce, BackpressureDrop:
+	default:
+		return fmt.Errorf("watcher: unknown backpressure %q", w.config.Backpressure)
+	}
+
+	bufferSize := w.config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = dataBufferSize
+	}
+
 	// Setup the channels
-	w.DataCh = make(chan *View, dataBufferSize)
+	w.DataCh = make(chan *View, bufferSize)
+	w.counters = &backpressureCounters{}
+	w.fetchCounters = &fetchCounters{}
+	w.scheduler = newFetchScheduler(w.config.MaxConcurrentFetches)
 	w.ErrCh = make(chan error)
 
 	// Setup our map of dependencies to views
 	w.depViewMap = make(map[string]*View)
+	w.priorities = make(map[string]int)
 
 	// Start a watcher for the Vault renew if that config was specified
 	if w.config.RenewVault {
@@ -75,6 +75,24 @@ func TestNewWatcher_values(t *testing.T) {
 	}
 }
 
+func TestNewWatcher_buffer(t *testing.T) {
+	w, err := NewWatcher(&WatcherConfig{
+		BufferSize:   16,
+		Backpressure: BackpressureCoalesce,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := cap(w.DataCh); size != 16 {
+		t.Errorf("expected DataCh to have %d buffer, but was %d", 16, size)
+	}
+
+	if _, err := NewWatcher(&WatcherConfig{Backpressure: "nope"}); err == nil {
+		t.Error("expected error for unknown backpressure")
+	}
+}
+
 func TestNewWatcher_renewVault(t *testing.T) {
 	clients := dep.NewClientSet()
 
@@ -242,6 +260,68 @@ func TestRemove_doesNotExist(t *testing.T) {
 	}
 }
 
+func TestPause(t *testing.T) {
+	w, err := NewWatcher(defaultWatcherConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	d := &TestDep{name: "paused"}
+	if _, err := w.Add(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Pause(&TestDep{name: "missing"}) {
+		t.Error("expected a dependency which is not watched not to pause")
+	}
+	if !w.Pause(d) {
+		t.Fatal("expected the dependency to pause")
+	}
+	if w.Pause(d) {
+		t.Error("expected a paused dependency not to pause again")
+	}
+
+	if exp, act := []string{d.String()}, w.Paused(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+	}
+
+	if !w.Resume(d) {
+		t.Fatal("expected the dependency to resume")
+	}
+	if w.Resume(d) {
+		t.Error("expected a running dependency not to resume")
+	}
+	if act := w.Paused(); len(act) != 0 {
+		t.Errorf("expected no paused dependencies, got %#v", act)
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	w, err := NewWatcher(defaultWatcherConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	d := &TestDep{name: "prioritized"}
+	w.Prioritize(d, PriorityLow)
+	if _, err := w.Add(d); err != nil {
+		t.Fatal(err)
+	}
+
+	view := w.depViewMap[d.String()]
+	if p := view.Priority(); p != PriorityLow {
+		t.Errorf("expected the priority given before the dependency was added, got %d", p)
+	}
+
+	w.Prioritize(d, PriorityHigh)
+	w.Prioritize(d, PriorityNormal)
+	if p := view.Priority(); p != PriorityHigh {
+		t.Errorf("expected the highest priority, got %d", p)
+	}
+}
+
 func TestSize_empty(t *testing.T) {
 	w, err := NewWatcher(defaultWatcherConfig)
 	if err != nil {